@@ -198,3 +198,32 @@ func (f FileURL) FlushData(ctx context.Context, fileSize int64, contentMd5 []byt
 		md5InBase64, nil, nil, nil, nil, nil, nil, nil,
 		nil, nil, &overrideHttpVerb, nil, nil, nil, nil)
 }
+
+// GetAccessControl returns the owner, group and permissions of the file, along with its other properties.
+// Note that Hierarchical Namespace must be enabled for the account.
+func (f FileURL) GetAccessControl(ctx context.Context) (*PathGetPropertiesResponse, error) {
+	upn := false // the IDs, rather than names, since they are what we can give back in SetAccessControl
+	return f.fileClient.GetProperties(ctx, f.fileSystemName, f.path, PathGetPropertiesActionGetAccessControl, &upn,
+		nil, nil, nil,
+		nil, nil, nil, nil, nil)
+}
+
+// SetAccessControl sets the owner, group and POSIX permissions of the file. Empty values are left unchanged.
+// Permissions may be symbolic (rwxr-x---) or in 4-digit octal (0750). Setting the owner needs super-user rights.
+func (f FileURL) SetAccessControl(ctx context.Context, owner, group, permissions string) (*PathUpdateResponse, error) {
+	optional := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+
+	// See the note about PATCH in AppendData
+	overrideHttpVerb := "PATCH"
+
+	return f.fileClient.Update(ctx, PathUpdateActionSetAccessControl, f.fileSystemName, f.path, nil,
+		nil, nil, nil, nil,
+		nil, nil, nil, nil, nil,
+		nil, nil, optional(owner), optional(group), optional(permissions), nil,
+		nil, nil, nil, nil, &overrideHttpVerb, nil, nil, nil, nil)
+}
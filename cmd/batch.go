@@ -0,0 +1,356 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The batch command runs several jobs at once, in this one process. Since the jobs share the process's transfer engine,
+// they share its connection pool, its bandwidth cap (cap-mbps and cap-ops) and its memory limit, which are divided
+// between them as they run, rather than each job assuming that it has the machine to itself.
+
+// batchJobPreparer cooks the flags that have just been parsed onto a job command, and returns the job,
+// ready to be started. Each job command registers one, in its init, since that's where its flags are bound
+type batchJobPreparer func(commandString string) (batchJob, error)
+
+var batchJobPreparers = make(map[*cobra.Command]batchJobPreparer)
+
+type batchJob struct {
+	commandString string
+	controller    common.WorkController // the job's progress is reported through this, once it has started
+	start         func() error
+}
+
+// readBatchFile reads the command lines of the jobs, one per line. Blank lines, and lines starting with #, are ignored
+func readBatchFile(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	commandLines := make([][]string, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // since SAS tokens make for long lines
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args, err := splitBatchLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if len(args) > 0 && args[0] == "azcopy" {
+			args = args[1:]
+		}
+		commandLines = append(commandLines, args)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(commandLines) == 0 {
+		return nil, errors.New("the batch file has no jobs in it")
+	}
+	return commandLines, nil
+}
+
+// splitBatchLine splits a command line into arguments, as a shell would: at spaces, except within single or double quotes.
+// Backslashes are left alone, since they're path separators on Windows
+func splitBatchLine(line string) ([]string, error) {
+	args := make([]string, 0)
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote %c", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// prepareBatchJob parses the flags of one job's command line, just as cobra would have, had the command been run on its own
+func prepareBatchJob(args []string) (batchJob, error) {
+	if len(args) == 0 {
+		return batchJob{}, errors.New("the command is empty")
+	}
+	cmd, flagsAndArgs, err := rootCmd.Find(args)
+	if err != nil {
+		return batchJob{}, err
+	}
+	prepare, ok := batchJobPreparers[cmd]
+	if !ok {
+		return batchJob{}, fmt.Errorf("%s can't be run in a batch. Only copy, sync and remove can", args[0])
+	}
+
+	// the flags still hold the values of the last job of the same kind
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		_ = f.Value.Set(f.DefValue)
+		f.Changed = false
+	})
+
+	// the flags of the whole process, like cap-mbps, were given to the batch command.
+	// A job can't have different ones, since it shares them with the other jobs
+	inherited := make(map[*pflag.Flag]bool)
+	cmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		inherited[f] = f.Changed
+		f.Changed = false
+	})
+	err = cmd.ParseFlags(flagsAndArgs)
+	var processFlags []string
+	for f, changed := range inherited {
+		if f.Changed {
+			processFlags = append(processFlags, "--"+f.Name)
+		}
+		f.Changed = changed
+	}
+	if err != nil {
+		return batchJob{}, err
+	}
+	if len(processFlags) > 0 {
+		return batchJob{}, fmt.Errorf("%s can't be given to a job in a batch, since they apply to all the jobs. Give them to the batch command instead",
+			strings.Join(processFlags, ", "))
+	}
+
+	if err = cmd.ValidateArgs(cmd.Flags().Args()); err != nil {
+		return batchJob{}, err
+	}
+	return prepare(strings.TrimSpace(copyHandlerUtil{}.constructCommandString(args)))
+}
+
+// jobBatch keeps track of the jobs as they end. Since each job would end the process when it ends, were it on its own,
+// the lifecycle manager is wrapped, so that only the end of the last job ends the process
+type jobBatch struct {
+	lcm common.LifecycleMgr // the real one
+
+	mu          sync.Mutex
+	total       int
+	succeeded   int
+	failedCount int
+	failed      []string                         // the command lines of the jobs that failed, where they're known
+	jobs        map[common.WorkController]string // the command lines of the jobs, by their controllers
+	reporting   map[common.WorkController]bool   // the controllers of the jobs whose progress is being reported
+}
+
+type batchSummary struct {
+	TotalJobs     int
+	JobsSucceeded int
+	JobsFailed    int
+	FailedJobs    []string
+}
+
+func newJobBatch(lcm common.LifecycleMgr, jobs []batchJob) *jobBatch {
+	b := &jobBatch{
+		lcm:       lcm,
+		total:     len(jobs),
+		failed:    make([]string, 0),
+		jobs:      make(map[common.WorkController]string),
+		reporting: make(map[common.WorkController]bool),
+	}
+	for _, job := range jobs {
+		b.jobs[job.controller] = job.commandString
+	}
+	return b
+}
+
+// startedReporting records that the job's progress is being reported, and returns its command line, if it's one of ours
+func (b *jobBatch) startedReporting(jc common.WorkController) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reporting[jc] = true
+	return b.jobs[jc]
+}
+
+func (b *jobBatch) isReporting(jc common.WorkController) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reporting[jc]
+}
+
+// jobEnded is called as each job ends, and ends the batch along with the last one
+func (b *jobBatch) jobEnded(commandString string, exitCode common.ExitCode) {
+	b.mu.Lock()
+	if exitCode == common.EExitCode.Success() {
+		b.succeeded++
+	} else {
+		b.failedCount++
+		if commandString != "" {
+			b.failed = append(b.failed, commandString)
+		}
+	}
+	done := b.succeeded+b.failedCount == b.total
+	summary := batchSummary{TotalJobs: b.total, JobsSucceeded: b.succeeded, JobsFailed: b.failedCount, FailedJobs: b.failed}
+	b.mu.Unlock()
+
+	if !done {
+		return
+	}
+	exitCode = common.EExitCode.Success()
+	if summary.JobsFailed > 0 {
+		exitCode = common.EExitCode.Error()
+	}
+	b.lcm.Exit(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			jsonOutput, err := json.Marshal(summary)
+			common.PanicIfErr(err)
+			return string(jsonOutput)
+		}
+		s := fmt.Sprintf("Batch complete: %d of %d jobs succeeded", summary.JobsSucceeded, summary.TotalJobs)
+		for _, c := range summary.FailedJobs {
+			s += "\nFailed: " + c
+		}
+		return s
+	}, exitCode)
+}
+
+// batchLifecycleMgr stands in for the lifecycle manager while the jobs run.
+// A job that ends before its progress is reported (e.g. a dry run) ends through its Exit
+type batchLifecycleMgr struct {
+	common.LifecycleMgr
+	batch         *jobBatch
+	commandString string // of the job, when it's known
+}
+
+func (m *batchLifecycleMgr) Exit(o common.OutputBuilder, applicationExitCode common.ExitCode) {
+	if applicationExitCode == common.EExitCode.NoExit() {
+		m.LifecycleMgr.Exit(o, applicationExitCode)
+		return
+	}
+	m.LifecycleMgr.Exit(o, common.EExitCode.NoExit())
+	m.batch.jobEnded(m.commandString, applicationExitCode)
+}
+
+func (m *batchLifecycleMgr) InitiateProgressReporting(jc common.WorkController) {
+	commandString := m.batch.startedReporting(jc)
+	m.LifecycleMgr.InitiateProgressReporting(&batchWorkController{WorkController: jc, batch: m.batch, commandString: commandString})
+}
+
+// batchWorkController passes each job the lifecycle manager that its progress is reported through, wrapped so that
+// the job ending doesn't end the process. A job with a followup (e.g. the cleanup after a move) exits with NoExit,
+// and it's the followup that ends
+type batchWorkController struct {
+	common.WorkController
+	batch         *jobBatch
+	commandString string
+}
+
+func (c *batchWorkController) ReportProgressOrExit(lcm common.LifecycleMgr) uint32 {
+	return c.WorkController.ReportProgressOrExit(&batchLifecycleMgr{LifecycleMgr: lcm, batch: c.batch, commandString: c.commandString})
+}
+
+func describeBatchGovernor(jobCount int) string {
+	s := fmt.Sprintf("Running %d jobs at once. They share this process's connections and memory limit", jobCount)
+	if cmdLineCapMegaBitsPerSecond > 0 {
+		s += fmt.Sprintf(", and its bandwidth cap of %v Mbps", cmdLineCapMegaBitsPerSecond)
+	}
+	return s + "."
+}
+
+func init() {
+	batchCmd := &cobra.Command{
+		Use:     "batch [file]",
+		Short:   batchCmdShortDescription,
+		Long:    batchCmdLongDescription,
+		Example: batchCmdExample,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			commandLines, err := readBatchFile(args[0])
+			if err != nil {
+				glcm.Error("cannot read the batch file: " + err.Error())
+				return
+			}
+
+			// every job is checked before any is started, so that a typo in the last doesn't leave the others half-done
+			jobs := make([]batchJob, len(commandLines))
+			for i, commandLine := range commandLines {
+				if jobs[i], err = prepareBatchJob(commandLine); err != nil {
+					glcm.Error(fmt.Sprintf("job %d (%s) is invalid: %s", i+1, strings.Join(commandLine, " "), err.Error()))
+					return
+				}
+			}
+
+			glcm.EnableInputWatcher()
+			if cancelFromStdin {
+				glcm.EnableCancelFromStdIn()
+			}
+			glcm.Info(describeBatchGovernor(len(jobs)))
+
+			batch := newJobBatch(glcm, jobs)
+			glcm = &batchLifecycleMgr{LifecycleMgr: glcm, batch: batch}
+			for _, job := range jobs {
+				go func(job batchJob) {
+					err := job.start()
+					if err == nil {
+						return
+					}
+					if batch.isReporting(job.controller) {
+						// the job can't finish, so there's nothing for it but to stop, as it would have stopped on its own
+						batch.lcm.Error("Cannot perform " + job.commandString + " due to error: " + err.Error())
+						return
+					}
+					batch.lcm.Warn("Cannot perform " + job.commandString + " due to error: " + err.Error())
+					batch.jobEnded(job.commandString, common.EExitCode.Error())
+				}(job)
+			}
+
+			glcm.SurrenderControl()
+		},
+	}
+	rootCmd.AddCommand(batchCmd)
+}
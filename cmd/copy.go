@@ -27,9 +27,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
+	"mime"
 	"net/url"
 	"os"
+	"path"
 	"runtime"
 	"strings"
 	"sync"
@@ -47,6 +50,9 @@ import (
 const pipingUploadParallelism = 5
 const pipingDefaultBlockSize = 8 * 1024 * 1024
 
+// crc64DownloadBlockSize is the largest range that the service will return the CRC64 of
+const crc64DownloadBlockSize = 4 * 1024 * 1024
+
 // For networking throughput in Mbps, (and only for networking), we divide by 1000*1000 (not 1024 * 1024) because
 // networking is traditionally done in base 10 units (not base 2).
 // E.g. "gigabit ethernet" means 10^9 bits/sec, not 2^30. So by using base 10 units
@@ -58,6 +64,36 @@ const base10Mega = 1000 * 1000
 
 const pipeLocation = "~pipe~"
 
+// stdioArgument is the argument that means stdin as the source, or stdout as the destination, as it does for many other tools
+const stdioArgument = "-"
+
+// inferStdioFromTo works out the FromTo of a copy with stdioArgument as its source (PipeBlob) or its destination
+// (BlobPipe), and checks it against the one given by --from-to, if any
+func inferStdioFromTo(src, dst string, userFromTo string) (common.FromTo, error) {
+	var fromTo common.FromTo
+	switch {
+	case src == stdioArgument && dst == stdioArgument:
+		return common.EFromTo.Unknown(), errors.New("fatal: stdin can't be copied to stdout. One of the arguments must be a blob URL")
+	case src == stdioArgument:
+		fromTo = common.EFromTo.PipeBlob()
+	case dst == stdioArgument:
+		fromTo = common.EFromTo.BlobPipe()
+	default:
+		return common.EFromTo.Unknown(), fmt.Errorf("neither argument is %s", stdioArgument)
+	}
+
+	if userFromTo != "" {
+		var parsed common.FromTo
+		if err := parsed.Parse(userFromTo); err != nil || parsed != fromTo {
+			if fromTo == common.EFromTo.PipeBlob() {
+				return common.EFromTo.Unknown(), fmt.Errorf("fatal: invalid from-to argument passed: %s. Stdin can only be uploaded to a blob (PipeBlob)", userFromTo)
+			}
+			return common.EFromTo.Unknown(), fmt.Errorf("fatal: invalid from-to argument passed: %s. Only a blob can be downloaded to Stdout (BlobPipe)", userFromTo)
+		}
+	}
+	return fromTo, nil
+}
+
 // represents the raw copy command input from the user
 type rawCopyCmdArgs struct {
 	// from arguments
@@ -75,32 +111,67 @@ type rawCopyCmdArgs struct {
 	includeFileAttributes string
 	excludeFileAttributes string
 	includeAfter          string
+	includeBefore         string
+	minSize               string
+	maxSize               string
 	legacyInclude         string // used only for warnings
 	legacyExclude         string // used only for warnings
 	listOfVersionIDs      string
+	includeSnapshots      bool
+	includeVersions       bool
+	includeDeleted        bool
 
 	// filters from flags
 	listOfFilesToCopy string
 	recursive         bool
 	followSymlinks    bool
+	preserveSymlinks  bool
+	skipSymlinks      bool
+	preserveHardlinks bool
 	autoDecompress    bool
+	compress          bool
+	pack              string
+	unpack            bool
+	vhd               bool
+	appendToBlob      bool
 	// forceWrite flag is used to define the User behavior
 	// to overwrite the existing blobs or not.
 	forceWrite      string
 	forceIfReadOnly bool
+	// skip files that are already at the destination with the same size and an up-to-date last modified time
+	skipUnchanged                 bool
+	skipUnchangedToleranceSeconds uint
+	// make the destination a mirror of the source, deleting its extra files
+	mirror bool
+	// what to do when two source files have the same destination
+	onDuplicateDestination string
+	// 'regex=>replacement' rules, separated by semicolons, that change each file's path at the destination
+	pathRewrites string
+	// list what would be transferred, without transferring anything
+	dryRun bool
+	// where to keep the job's plan
+	planLocation string
 
 	// options from flags
 	blockSizeMB              float64
 	metadata                 string
+	blobTags                 string
 	contentType              string
 	contentEncoding          string
 	contentDisposition       string
 	contentLanguage          string
 	cacheControl             string
 	noGuessMimeType          bool
+	contentTypeMap           string
+	sniffContentType         bool
+	headerRules              string
 	preserveLastModifiedTime bool
 	putMd5                   bool
 	md5ValidationOption      string
+	putCrc64                 bool
+	crc64ValidationOption    string
+	putSha256                bool
+	sha256ValidationOption   string
 	CheckLength              bool
 	deleteSnapshotsOption    string
 	// defines the type of the blob at the destination in case of upload / account to account copy
@@ -109,14 +180,34 @@ type rawCopyCmdArgs struct {
 	pageBlobTier  string
 	output        string // TODO: Is this unused now? replaced with param at root level?
 	logVerbosity  string
+	priority      string
+	activeHours   string
+	afterJob      string
+	// pattern=tier pairs, for the block blobs that get a tier other than blockBlobTier
+	blockBlobTierRules string
 	// list of blobTypes to exclude while enumerating the transfer
 	excludeBlobType string
+	// list of access tiers to include while enumerating the transfer
+	includeTier string
+	// what to do with archived source blobs, and how quickly to rehydrate them
+	archivedSource    string
+	rehydratePriority string
+	// key=value lists of the index tags and metadata that the blobs to include must have
+	includeTags     string
+	includeMetadata string
 	// Opt-in flag to persist SMB ACLs to Azure Files.
 	preserveSMBPermissions bool
 	preserveOwner          bool // works in conjunction with preserveSmbPermissions
 	// Opt-in flag to persist additional SMB properties to Azure Files. Named ...info instead of ...properties
 	// because the latter was similar enough to preserveSMBPermissions to induce user error
 	preserveSMBInfo bool
+	// Opt-in flag to persist the POSIX owner, group and mode of files to and from ADLS Gen2
+	preservePOSIXPermissions bool
+	// Opt-in flag to persist the creation and last write times of files, as SMB properties for Azure Files, or in
+	// metadata for Blob Storage
+	preserveInfo bool
+	// Opt-in flag to persist the extended attributes of files in blob metadata
+	preserveXattrs bool
 	// Flag to enable Window's special privileges
 	backupMode bool
 	// whether user wants to preserve full properties during service to service copy, the default value is true.
@@ -135,6 +226,12 @@ type rawCopyCmdArgs struct {
 	s2sSourceChangeValidation bool
 	// specify how user wants to handle invalid metadata.
 	s2sInvalidMetadataHandleOption string
+	// whether user wants to copy the index tags of source blobs during service to service copy.
+	s2sPreserveBlobTags bool
+	// how the metadata given by the user is combined with the source's during service to service copy.
+	s2sMetadataMerge string
+	// how the data of block blobs is copied during service to service copy.
+	s2sCopyMethod string
 
 	// internal override to enforce strip-top-dir
 	internalOverrideStripTopDir bool
@@ -171,6 +268,9 @@ func blockSizeInBytes(rawBlockSizeInMiB float64) (int64, error) {
 	if rawSizeInBytes > math.MaxInt64 {
 		return 0, errors.New("block size too big for int64")
 	}
+	if rawSizeInBytes > common.MaxBlockBlobBlockSize {
+		return 0, fmt.Errorf("block size cannot be greater than %dMiB", common.MaxBlockBlobBlockSize/(1024*1024))
+	}
 	const epsilon = 0.001 // arbitrarily using a tolerance of 1000th of a byte
 	_, frac := math.Modf(rawSizeInBytes)
 	isWholeNumber := frac < epsilon || frac > 1.0-epsilon // frac is very close to 0 or 1, so rawSizeInBytes is (very close to) an integer
@@ -233,6 +333,9 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		jobID: jobId,
 	}
 
+	// tokens like {yyyy} and {jobid} in the destination are expanded once, here, so all of the job goes to the same place
+	raw.dst = expandDestinationTemplate(raw.dst, jobId, time.Now())
+
 	fromTo, err := validateFromTo(raw.src, raw.dst, raw.fromTo) // TODO: src/dst
 	if err != nil {
 		return cooked, err
@@ -246,7 +349,8 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	}
 
 	// Check if source has a trailing wildcard on a URL
-	if fromTo.From().IsRemote() {
+	// (a plain HTTP(S) URL has no containers to search, and a * in it is just a character)
+	if fromTo.From().IsRemote() && fromTo.From() != common.ELocation.HTTP() {
 		tempSrc, cooked.stripTopDir, err = raw.stripTrailingWildcardOnRemoteSource(fromTo.From())
 
 		if err != nil {
@@ -274,7 +378,16 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 
 	cooked.fromTo = fromTo
 	cooked.recursive = raw.recursive
-	cooked.followSymlinks = raw.followSymlinks
+	if cooked.symlinkHandling, err = getSymlinkHandlingType(raw.followSymlinks, raw.preserveSymlinks, raw.skipSymlinks); err != nil {
+		return cooked, err
+	}
+	if err = validateSymlinkHandlingType(cooked.symlinkHandling, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	cooked.preserveHardlinks = raw.preserveHardlinks
+	if err = validatePreserveHardlinks(cooked.preserveHardlinks, cooked.fromTo); err != nil {
+		return cooked, err
+	}
 	cooked.forceIfReadOnly = raw.forceIfReadOnly
 	if err = validateForceIfReadOnly(cooked.forceIfReadOnly, cooked.fromTo); err != nil {
 		return cooked, err
@@ -285,6 +398,29 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	if err != nil {
 		return cooked, err
 	}
+	if raw.dryRun {
+		if fromTo.From() == common.ELocation.Pipe() || fromTo.To() == common.ELocation.Pipe() {
+			return cooked, errors.New("dry-run is not supported when piping")
+		}
+		cooked.dryRunPrinter = newDryRunPrinter()
+	}
+	if raw.planLocation != "" { // as for on-duplicate-destination
+		if err = cooked.planLocation.Parse(raw.planLocation); err != nil {
+			return cooked, fmt.Errorf("invalid plan-location value '%s'. Possible values are 'disk' and 'memory'", raw.planLocation)
+		}
+	}
+	if raw.onDuplicateDestination != "" { // commands that reuse the copy arguments leave it out, to get the default
+		err = cooked.duplicateDestinationPolicy.Parse(raw.onDuplicateDestination)
+		if err != nil {
+			return cooked, fmt.Errorf("invalid on-duplicate-destination value '%s'. Possible values are 'skip', 'fail' and 'lastWins'", raw.onDuplicateDestination)
+		}
+	}
+	if cooked.pathRewrites, err = parsePathRewriteRules(raw.parsePatterns(raw.pathRewrites)); err != nil {
+		return cooked, err
+	}
+	if len(cooked.pathRewrites) > 0 && cooked.preserveHardlinks {
+		return cooked, errPathRewriteWithHardlinks
+	}
 	allowAutoDecompress := fromTo == common.EFromTo.BlobLocal() || fromTo == common.EFromTo.FileLocal()
 	if raw.autoDecompress && !allowAutoDecompress {
 		return cooked, errors.New("automatic decompression is only supported for downloads from Blob and Azure Files") // as at Sept 2019, our ADLS Gen 2 Swagger does not include content-encoding for directory (path) listings so we can't support it there
@@ -297,6 +433,11 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	if fromTo.From() == common.ELocation.Local() && strings.Contains(cooked.source.ValueLocal(), "*") {
 		cooked.stripTopDir = true
 	}
+	// SFTP has no wildcards either, except that, as with remote sources, a trailing /* means the folder's contents, without the folder itself
+	if fromTo.From() == common.ELocation.SFTP() && strings.HasSuffix(cooked.source.Value, "/*") {
+		cooked.source.Value = strings.TrimSuffix(cooked.source.Value, "/*")
+		cooked.stripTopDir = true
+	}
 
 	cooked.blockSize, err = blockSizeInBytes(raw.blockSizeMB)
 	if err != nil {
@@ -309,6 +450,11 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		return cooked, err
 	}
 
+	cooked.uploadVHD = raw.vhd
+	if cooked.blobType, err = validateVHDUpload(cooked.uploadVHD, fromTo, cooked.blobType); err != nil {
+		return cooked, err
+	}
+
 	// If the given blobType is AppendBlob, block-size-mb should not be greater than
 	// 4MB.
 	if cookedSize, _ := blockSizeInBytes(raw.blockSizeMB); cooked.blobType == common.EBlobType.AppendBlob() && cookedSize > common.MaxAppendBlobBlockSize {
@@ -319,6 +465,17 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	if err != nil {
 		return cooked, err
 	}
+	if raw.blockBlobTierRules != "" {
+		if fromTo.To() != common.ELocation.Blob() {
+			return cooked, errors.New("block-blob-tier-rules is only supported when the destination is Blob Storage")
+		}
+		if cooked.blockBlobTierRules, err = common.ParseBlockBlobTierRules(raw.blockBlobTierRules); err != nil {
+			return cooked, fmt.Errorf("invalid block-blob-tier-rules: %w", err)
+		}
+		if len(cooked.blockBlobTierRules.ToString()) > ste.TierRulesMaxBytes {
+			return cooked, fmt.Errorf("block-blob-tier-rules is too long. It must fit in %d bytes", ste.TierRulesMaxBytes)
+		}
+	}
 	err = cooked.pageBlobTier.Parse(raw.pageBlobTier)
 	if err != nil {
 		return cooked, err
@@ -327,6 +484,20 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	if err != nil {
 		return cooked, err
 	}
+	err = cooked.priority.Parse(raw.priority)
+	if err != nil {
+		return cooked, err
+	}
+	err = cooked.activeHours.Parse(raw.activeHours)
+	if err != nil {
+		return cooked, err
+	}
+	if raw.afterJob != "" {
+		cooked.afterJob, err = common.ParseJobID(raw.afterJob)
+		if err != nil {
+			return cooked, fmt.Errorf("the job to run after, %s, is not a valid job ID: %v", raw.afterJob, err)
+		}
+	}
 
 	// Everything uses the new implementation of list-of-files now.
 	// This handles both list-of-files and include-path as a list enumerator.
@@ -427,14 +598,14 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		cooked.listOfFilesChannel = listChan
 	}
 
-	if raw.includeAfter != "" {
-		// must set chooseEarliest = true, so that if there's an ambiguous local date, the earliest will be returned
-		// (since that's safest for includeAfter.  Better to choose the earlier time and do more work, than the later one and fail to pick up a changed file
-		parsedIncludeAfter, err := includeAfterDateFilter{}.ParseISO8601(raw.includeAfter, true)
-		if err != nil {
-			return cooked, err
-		}
-		cooked.includeAfter = &parsedIncludeAfter
+	cooked.includeAfter, cooked.includeBefore, err = parseDateWindow(raw.includeAfter, raw.includeBefore)
+	if err != nil {
+		return cooked, err
+	}
+
+	cooked.minSize, cooked.maxSize, err = parseFileSizeRange(raw.minSize, raw.maxSize)
+	if err != nil {
+		return cooked, err
 	}
 
 	versionsChan := make(chan string)
@@ -476,7 +647,25 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		cooked.listOfVersionIDs = versionsChan
 	}
 
+	cooked.includeSnapshots = raw.includeSnapshots
+	if err = validateIncludeSnapshots(cooked.includeSnapshots, cooked.fromTo, cooked.source, raw.listOfVersionIDs != ""); err != nil {
+		return cooked, err
+	}
+	cooked.includeVersions = raw.includeVersions
+	if err = validateIncludeVersions(cooked.includeVersions, cooked.fromTo, cooked.source, cooked.forceWrite, raw.listOfVersionIDs != "", cooked.includeSnapshots); err != nil {
+		return cooked, err
+	}
+	cooked.includeDeleted = raw.includeDeleted
+	if err = validateIncludeDeleted(cooked.includeDeleted, cooked.fromTo, raw.listOfVersionIDs != ""); err != nil {
+		return cooked, err
+	}
+
 	cooked.metadata = raw.metadata
+	if raw.blobTags != "" {
+		if cooked.blobTags, err = parseBlobTags(raw.blobTags, cooked.fromTo); err != nil {
+			return cooked, err
+		}
+	}
 	cooked.contentType = raw.contentType
 	cooked.contentEncoding = raw.contentEncoding
 	cooked.contentLanguage = raw.contentLanguage
@@ -486,6 +675,12 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	cooked.preserveLastModifiedTime = raw.preserveLastModifiedTime
 	cooked.includeDirectoryStubs = raw.includeDirectoryStubs
 
+	cooked.skipUnchanged = raw.skipUnchanged
+	cooked.skipUnchangedTolerance = time.Duration(raw.skipUnchangedToleranceSeconds) * time.Second
+	if err = validateSkipUnchanged(cooked.skipUnchanged, cooked.fromTo, cooked.destination.Value); err != nil {
+		return cooked, err
+	}
+
 	// Make sure the given input is the one of the enums given by the blob SDK
 	err = cooked.deleteSnapshotsOption.Parse(raw.deleteSnapshotsOption)
 	if err != nil {
@@ -496,6 +691,54 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		cooked.noGuessMimeType = true // As specified in the help text, noGuessMimeType is inferred here.
 	}
 
+	cooked.compress = raw.compress
+	if err = validateCompress(cooked.compress, cooked.fromTo, cooked.contentEncoding, cooked.blobType); err != nil {
+		return cooked, err
+	}
+
+	if err = cooked.pack.Parse(raw.pack); err != nil {
+		return cooked, fmt.Errorf("invalid pack value '%s'. Possible values are 'none', 'tar' and 'tarGz'", raw.pack)
+	}
+	hasFilters := raw.include != "" || raw.exclude != "" || raw.includePath != "" || raw.excludePath != "" || raw.listOfFilesToCopy != "" ||
+		raw.includeAfter != "" || raw.includeBefore != "" || raw.minSize != "" || raw.maxSize != "" ||
+		raw.includeFileAttributes != "" || raw.excludeFileAttributes != ""
+	if err = validatePack(cooked.pack, cooked.fromTo, cooked.blobType, cooked.compress, hasFilters, cooked.symlinkHandling, cooked.preserveHardlinks); err != nil {
+		return cooked, err
+	}
+	cooked.unpack = raw.unpack
+	if err = validateUnpack(cooked.unpack, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+
+	cooked.appendToBlob = raw.appendToBlob
+	if err = validateAppendToBlob(cooked.appendToBlob, cooked.fromTo, cooked.blobType, cooked.forceWrite, cooked.compress); err != nil {
+		return cooked, err
+	}
+
+	cooked.mirror = raw.mirror
+	if err = cooked.validateMirror(raw.listOfFilesToCopy != "" || raw.includePath != ""); err != nil {
+		return cooked, err
+	}
+
+	cooked.sniffContentType = raw.sniffContentType
+	if err = validateContentTypeDetection(raw.contentTypeMap != "", cooked.sniffContentType, cooked.noGuessMimeType, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	if raw.contentTypeMap != "" {
+		if cooked.contentTypeMap, err = loadContentTypeMap(raw.contentTypeMap); err != nil {
+			return cooked, err
+		}
+	}
+
+	if raw.headerRules != "" {
+		if cooked.fromTo.From() != common.ELocation.Local() && cooked.fromTo.From() != common.ELocation.SFTP() {
+			return cooked, errors.New("header-rules only applies when uploading from the local file system or an SFTP server")
+		}
+		if cooked.headerRules, err = loadHeaderRules(raw.headerRules); err != nil {
+			return cooked, err
+		}
+	}
+
 	cooked.putMd5 = raw.putMd5
 	err = cooked.md5ValidationOption.Parse(raw.md5ValidationOption)
 	if err != nil {
@@ -503,6 +746,26 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	}
 	globalBlobFSMd5ValidationOption = cooked.md5ValidationOption // workaround, to avoid having to pass this all the way through the chain of methods in enumeration, just for one weird and (presumably) temporary workaround
 
+	cooked.putCrc64 = raw.putCrc64
+	cooked.crc64ValidationOption = common.EHashValidationOption.NoCheck() // unless given, as it isn't when other commands cook copy's arguments
+	if raw.crc64ValidationOption != "" {
+		if err = cooked.crc64ValidationOption.Parse(raw.crc64ValidationOption); err != nil {
+			return cooked, err
+		}
+	}
+	// the service only returns the CRC64 of ranges of up to 4 MiB, so unless told otherwise, download in chunks of that size
+	if cooked.crc64ValidationOption != common.EHashValidationOption.NoCheck() && cooked.blockSize == 0 {
+		cooked.blockSize = crc64DownloadBlockSize
+	}
+
+	cooked.putSha256 = raw.putSha256
+	cooked.sha256ValidationOption = common.EHashValidationOption.NoCheck()
+	if raw.sha256ValidationOption != "" {
+		if err = cooked.sha256ValidationOption.Parse(raw.sha256ValidationOption); err != nil {
+			return cooked, err
+		}
+	}
+
 	cooked.CheckLength = raw.CheckLength
 	// length of devnull will be 0, thus this will always fail unless downloading an empty file
 	if cooked.destination.Value == common.Dev_Null {
@@ -527,7 +790,21 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		return cooked, err
 	}
 
-	if err = crossValidateSymlinksAndPermissions(cooked.followSymlinks, cooked.preserveSMBPermissions.IsTruthy()); err != nil {
+	if err = crossValidateSymlinksAndPermissions(cooked.symlinkHandling == common.ESymlinkHandlingType.Follow(), cooked.preserveSMBPermissions.IsTruthy()); err != nil {
+		return cooked, err
+	}
+
+	if err = cooked.applyPreserveInfo(raw.preserveInfo); err != nil {
+		return cooked, err
+	}
+
+	cooked.preserveXattrs = raw.preserveXattrs
+	if err = validatePreserveXattrs(cooked.preserveXattrs, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+
+	cooked.preservePOSIXPermissions = raw.preservePOSIXPermissions
+	if err = validatePreservePOSIXPermissions(cooked.preservePOSIXPermissions, cooked.fromTo); err != nil {
 		return cooked, err
 	}
 
@@ -605,10 +882,24 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		if cooked.blobType != common.EBlobType.Detect() {
 			return cooked, fmt.Errorf("blob-type is not supported on Azure File")
 		}
+	case common.EFromTo.SFTPBlob(),
+		common.EFromTo.SFTPFile():
+		if cooked.preserveLastModifiedTime {
+			return cooked, fmt.Errorf("preserve-last-modified-time is not supported while copying from SFTP")
+		}
+		if cooked.fromTo.To() == common.ELocation.File() {
+			if cooked.blockBlobTier != common.EBlockBlobTier.None() ||
+				cooked.pageBlobTier != common.EPageBlobTier.None() {
+				return cooked, fmt.Errorf("blob-tier is not supported while uploading to Azure File")
+			}
+			if cooked.blobType != common.EBlobType.Detect() {
+				return cooked, fmt.Errorf("blob-type is not supported on Azure File")
+			}
+		}
 	case common.EFromTo.BlobLocal(),
 		common.EFromTo.FileLocal(),
 		common.EFromTo.BlobFSLocal():
-		if cooked.followSymlinks {
+		if cooked.symlinkHandling == common.ESymlinkHandlingType.Follow() {
 			return cooked, fmt.Errorf("follow-symlinks flag is not supported while downloading")
 		}
 		if cooked.blockBlobTier != common.EBlockBlobTier.None() ||
@@ -635,30 +926,34 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		}
 	case common.EFromTo.BlobFile(),
 		common.EFromTo.S3Blob(),
+		common.EFromTo.S3File(),
+		common.EFromTo.HTTPBlob(),
+		common.EFromTo.HTTPFile(),
 		common.EFromTo.BlobBlob(),
 		common.EFromTo.FileBlob(),
 		common.EFromTo.FileFile():
 		if cooked.preserveLastModifiedTime {
 			return cooked, fmt.Errorf("preserve-last-modified-time is not supported while copying from service to service")
 		}
-		if cooked.followSymlinks {
+		if cooked.symlinkHandling == common.ESymlinkHandlingType.Follow() {
 			return cooked, fmt.Errorf("follow-symlinks flag is not supported while copying from service to service")
 		}
 		// blob type is not supported if destination is not blob
 		if cooked.blobType != common.EBlobType.Detect() && cooked.fromTo.To() != common.ELocation.Blob() {
 			return cooked, fmt.Errorf("blob-type is not supported for the scenario (%s)", cooked.fromTo.String())
 		}
-		// Disabling blob tier override, when copying block -> block blob or page -> page blob, blob tier will be kept,
-		// For s3 and file, only hot block blob tier is supported.
-		if cooked.blockBlobTier != common.EBlockBlobTier.None() ||
-			cooked.pageBlobTier != common.EPageBlobTier.None() {
-			return cooked, fmt.Errorf("blob-tier is not supported while copying from service to service")
+		// Without a blob tier override, when copying block -> block blob or page -> page blob, blob tier will be kept,
+		// For s3 and file, only hot block blob tier is supported. The tier given by the user takes precedence over the source's
+		if (cooked.blockBlobTier != common.EBlockBlobTier.None() ||
+			cooked.pageBlobTier != common.EPageBlobTier.None()) && cooked.fromTo.To() != common.ELocation.Blob() {
+			return cooked, fmt.Errorf("blob-tier is only supported while copying from service to service when the destination is Blob Storage")
 		}
 		if cooked.noGuessMimeType {
 			return cooked, fmt.Errorf("no-guess-mime-type is not supported while copying from service to service")
 		}
-		if len(cooked.contentType) > 0 || len(cooked.contentEncoding) > 0 || len(cooked.contentLanguage) > 0 || len(cooked.contentDisposition) > 0 || len(cooked.cacheControl) > 0 || len(cooked.metadata) > 0 {
-			return cooked, fmt.Errorf("content-type, content-encoding, content-language, content-disposition, cache-control, or metadata is not supported while copying from service to service")
+		// metadata is allowed, since it can be merged with the source's (see s2s-metadata-merge)
+		if len(cooked.contentType) > 0 || len(cooked.contentEncoding) > 0 || len(cooked.contentLanguage) > 0 || len(cooked.contentDisposition) > 0 || len(cooked.cacheControl) > 0 {
+			return cooked, fmt.Errorf("content-type, content-encoding, content-language, content-disposition, or cache-control is not supported while copying from service to service")
 		}
 	}
 	if err = validatePutMd5(cooked.putMd5, cooked.fromTo); err != nil {
@@ -667,6 +962,15 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	if err = validateMd5Option(cooked.md5ValidationOption, cooked.fromTo); err != nil {
 		return cooked, err
 	}
+	if err = validateBlobHashOptions("crc64", cooked.putCrc64, cooked.crc64ValidationOption, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	if err = validateBlobHashOptions("sha256", cooked.putSha256, cooked.sha256ValidationOption, cooked.fromTo); err != nil {
+		return cooked, err
+	}
+	if err = validateManagedDiskDestination(cooked.destination.Value, cooked.fromTo, cooked.blobType, cooked.forceWrite, cooked.putMd5); err != nil {
+		return cooked, err
+	}
 
 	// Because of some of our defaults, these must live down here and can't be properly checked.
 	// TODO: Remove the above checks where they can't be done.
@@ -674,6 +978,19 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	cooked.s2sGetPropertiesInBackend = raw.s2sGetPropertiesInBackend
 	cooked.s2sPreserveAccessTier = raw.s2sPreserveAccessTier
 	cooked.s2sSourceChangeValidation = raw.s2sSourceChangeValidation
+	cooked.s2sPreserveBlobTags = raw.s2sPreserveBlobTags
+	if cooked.s2sPreserveBlobTags && cooked.fromTo != common.EFromTo.BlobBlob() {
+		return cooked, errors.New("s2s-preserve-blob-tags is only supported when copying from Blob Storage to Blob Storage")
+	}
+	if err = cooked.s2sMetadataMerge.Parse(raw.s2sMetadataMerge); err != nil {
+		return cooked, fmt.Errorf("invalid s2s-metadata-merge: %w", err)
+	}
+	if err = cooked.s2sCopyMethod.Parse(raw.s2sCopyMethod); err != nil {
+		return cooked, fmt.Errorf("invalid s2s-copy-method: %w", err)
+	}
+	if err = validateS2SCopyMethod(cooked.s2sCopyMethod, cooked.fromTo, cooked.blobType, cooked.s2sPreserveBlobTags || raw.blobTags != ""); err != nil {
+		return cooked, err
+	}
 
 	// If the user has provided some input with excludeBlobType flag, parse the input.
 	if len(raw.excludeBlobType) > 0 {
@@ -689,6 +1006,38 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 		}
 	}
 
+	if raw.includeTier != "" {
+		if fromTo.From() != common.ELocation.Blob() {
+			return cooked, errors.New("include-tier only applies when the source is Blob Storage, since only blobs have access tiers")
+		}
+		cooked.includeBlobTiers, err = parseBlobTiers(raw.includeTier)
+		if err != nil {
+			return cooked, fmt.Errorf("invalid include-tier: %w", err)
+		}
+	}
+
+	if err = cooked.archivedSource.Parse(raw.archivedSource); err != nil {
+		return cooked, fmt.Errorf("invalid archived-source: %w", err)
+	}
+	if err = cooked.rehydratePriority.Parse(raw.rehydratePriority); err != nil {
+		return cooked, fmt.Errorf("invalid rehydrate-priority: %w", err)
+	}
+	if err = validateArchivedSource(cooked.archivedSource, raw.rehydratePriority != "", fromTo); err != nil {
+		return cooked, err
+	}
+
+	if raw.includeTags != "" || raw.includeMetadata != "" {
+		if fromTo.From() != common.ELocation.Blob() {
+			return cooked, errors.New("include-tags and include-metadata only apply when the source is Blob Storage")
+		}
+		if cooked.includeBlobTags, err = parseKeyValueFilter(raw.includeTags, "include-tags"); err != nil {
+			return cooked, err
+		}
+		if cooked.includeMetadata, err = parseKeyValueFilter(raw.includeMetadata, "include-metadata"); err != nil {
+			return cooked, err
+		}
+	}
+
 	err = cooked.s2sInvalidMetadataHandleOption.Parse(raw.s2sInvalidMetadataHandleOption)
 	if err != nil {
 		return cooked, err
@@ -702,8 +1051,12 @@ func (raw rawCopyCmdArgs) cookWithId(jobId common.JobID) (cookedCopyCmdArgs, err
 	if (raw.includeFileAttributes != "" || raw.excludeFileAttributes != "") && fromTo.From() != common.ELocation.Local() {
 		return cooked, errors.New("cannot check file attributes on remote objects")
 	}
-	cooked.includeFileAttributes = raw.parsePatterns(raw.includeFileAttributes)
-	cooked.excludeFileAttributes = raw.parsePatterns(raw.excludeFileAttributes)
+	if cooked.includeFileAttributes, err = parseFileAttributes(raw.includeFileAttributes, "include-attributes"); err != nil {
+		return cooked, err
+	}
+	if cooked.excludeFileAttributes, err = parseFileAttributes(raw.excludeFileAttributes, "exclude-attributes"); err != nil {
+		return cooked, err
+	}
 
 	return cooked, nil
 }
@@ -729,8 +1082,10 @@ func (raw *rawCopyCmdArgs) setMandatoryDefaults() {
 	raw.pageBlobTier = common.EPageBlobTier.None().String()
 	raw.md5ValidationOption = common.DefaultHashValidationOption.String()
 	raw.s2sInvalidMetadataHandleOption = common.DefaultInvalidMetadataHandleOption.String()
+	raw.s2sMetadataMerge = common.EMetadataMergeOption.Overwrite().String()
 	raw.forceWrite = common.EOverwriteOption.True().String()
 	raw.preserveOwner = common.PreserveOwnerDefault
+	raw.priority = common.EJobPriority.Normal().String()
 }
 
 func validateForceIfReadOnly(toForce bool, fromTo common.FromTo) error {
@@ -773,6 +1128,364 @@ func validatePreserveOwner(preserve bool, fromTo common.FromTo) error {
 	return nil
 }
 
+func validateSkipUnchanged(skipUnchanged bool, fromTo common.FromTo, destination string) error {
+	if !skipUnchanged {
+		return nil
+	}
+	// Unknown covers deletions, which have no destination at all
+	if fromTo.To() == common.ELocation.Pipe() || fromTo.To() == common.ELocation.Unknown() || destination == common.Dev_Null {
+		return errors.New("skip-unchanged requires a destination that can be listed")
+	}
+	return nil
+}
+
+// getSymlinkHandlingType works out what to do with symlinks from the flags that ask for each way of handling them.
+// Symlinks are skipped unless one of the others is asked for
+func getSymlinkHandlingType(follow, preserve, skip bool) (common.SymlinkHandlingType, error) {
+	count := 0
+	result := common.ESymlinkHandlingType.Skip()
+	if follow {
+		count++
+		result = common.ESymlinkHandlingType.Follow()
+	}
+	if preserve {
+		count++
+		result = common.ESymlinkHandlingType.Preserve()
+	}
+	if skip {
+		count++
+	}
+	if count > 1 {
+		return result, errors.New("only one of --follow-symlinks, --preserve-symlinks and --skip-symlinks may be given")
+	}
+	return result, nil
+}
+
+// validateSymlinkHandlingType checks that the links can be preserved in this kind of transfer.
+// They're kept as blobs that hold the targets of the links, so that only works to and from Blob Storage
+func validateSymlinkHandlingType(symlinkHandling common.SymlinkHandlingType, fromTo common.FromTo) error {
+	if symlinkHandling == common.ESymlinkHandlingType.Preserve() &&
+		fromTo != common.EFromTo.LocalBlob() && fromTo != common.EFromTo.BlobLocal() {
+		return fmt.Errorf("preserve-symlinks is only supported when uploading from the local file system to Blob Storage, or downloading from Blob Storage to it")
+	}
+	return nil
+}
+
+// validatePreserveHardlinks checks that hard links can be kept in this kind of transfer. They're recorded in blob
+// metadata, and telling the links to a file apart needs the file's inode, which Windows doesn't give us
+func validatePreserveHardlinks(preserveHardlinks bool, fromTo common.FromTo) error {
+	if !preserveHardlinks {
+		return nil
+	}
+	if fromTo != common.EFromTo.LocalBlob() && fromTo != common.EFromTo.BlobLocal() {
+		return errors.New("preserve-hardlinks is only supported when uploading from the local file system to Blob Storage, or downloading from Blob Storage to it")
+	}
+	if fromTo.IsUpload() && runtime.GOOS == "windows" {
+		return errors.New("preserve-hardlinks is not supported when uploading from Windows")
+	}
+	return nil
+}
+
+// applyPreserveInfo turns --preserve-info into what keeps the times of files in this kind of transfer. Azure Files
+// has SMB properties for them, so there it's the same as --preserve-smb-info. Blob Storage doesn't, so there they are
+// kept in the metadata of each blob
+func (cca *cookedCopyCmdArgs) applyPreserveInfo(preserveInfo bool) error {
+	if !preserveInfo {
+		return nil
+	}
+
+	switch cca.fromTo {
+	case common.EFromTo.LocalFile(), common.EFromTo.FileLocal(), common.EFromTo.FileFile():
+		if err := validatePreserveSMBPropertyOption(true, cca.fromTo, &cca.forceWrite, "preserve-info"); err != nil {
+			return err
+		}
+		cca.preserveSMBInfo = true
+	case common.EFromTo.LocalBlob():
+		cca.preserveFileTimes = true
+	case common.EFromTo.BlobLocal():
+		cca.preserveFileTimes = true
+		cca.preserveLastModifiedTime = true // for blobs that weren't uploaded with --preserve-info
+	default:
+		return fmt.Errorf("preserve-info is not supported for the scenario (%s)", cca.fromTo.String())
+	}
+	return nil
+}
+
+// parseBlobTags reads the --blob-tags flag, which has the same key=value;key=value form as --metadata
+func parseBlobTags(s string, fromTo common.FromTo) (common.BlobTags, error) {
+	if fromTo.To() != common.ELocation.Blob() {
+		return nil, errors.New("blob-tags is only supported when the destination is Blob Storage")
+	}
+	pairs, err := parseKeyValueFilter(s, "blob-tags")
+	if err != nil {
+		return nil, err
+	}
+	tags := common.BlobTags(pairs)
+	if err = tags.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid blob-tags: %w", err)
+	}
+	return tags, nil
+}
+
+// validateCompress checks that --compress can be used. Files are only gzipped on upload to Blob Storage or Azure Files,
+// where the Content-Encoding header tells readers what was done to them. Page blobs are left out, since their size must
+// be a multiple of 512 bytes
+func validateCompress(compress bool, fromTo common.FromTo, contentEncoding string, blobType common.BlobType) error {
+	if !compress {
+		return nil
+	}
+	if fromTo != common.EFromTo.LocalBlob() && fromTo != common.EFromTo.LocalFile() {
+		return errors.New("compress is only supported when uploading from the local file system to Blob Storage or Azure Files")
+	}
+	if contentEncoding != "" {
+		return errors.New("compress can't be used with content-encoding, since compressed files get the content encoding gzip")
+	}
+	if blobType == common.EBlobType.PageBlob() {
+		return errors.New("compress can't be used to upload page blobs")
+	}
+	return nil
+}
+
+// validateVHDUpload checks that --vhd can be used, and gives the blob type to use. Disk images are uploaded as page
+// blobs, so that's the type unless the user asked for a different one, which is an error
+func validateVHDUpload(vhd bool, fromTo common.FromTo, blobType common.BlobType) (common.BlobType, error) {
+	if !vhd {
+		return blobType, nil
+	}
+	if fromTo != common.EFromTo.LocalBlob() {
+		return blobType, errors.New("vhd is only supported when uploading from the local file system to Blob Storage")
+	}
+	if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.PageBlob() {
+		return blobType, errors.New("vhd uploads disk images as page blobs, so it can't be used with any other blob-type")
+	}
+	return common.EBlobType.PageBlob(), nil
+}
+
+// validateIncludeSnapshots checks that --include-snapshots can be used. The source must be in Blob Storage, and must
+// be a blob, not a snapshot of one, or a container or directory
+func validateIncludeSnapshots(includeSnapshots bool, fromTo common.FromTo, source common.ResourceString, hasListOfVersions bool) error {
+	if !includeSnapshots {
+		return nil
+	}
+	if fromTo.From() != common.ELocation.Blob() {
+		return errors.New("include-snapshots is only supported when the source is Blob Storage")
+	}
+	if hasListOfVersions {
+		return errors.New("include-snapshots can't be used with list-of-versions")
+	}
+	if u, err := source.FullURL(); err == nil && azblob.NewBlobURLParts(*u).Snapshot != "" {
+		return errors.New("include-snapshots can't be used when the source is a snapshot. Remove the snapshot from the URL to copy the blob with all of its snapshots")
+	}
+	return nil
+}
+
+// validateIncludeVersions checks that --include-versions can be used. Each version is written over the one before it,
+// at a destination in Blob Storage that keeps versions too, so they must be allowed to overwrite. The source can't be
+// a particular snapshot or version, since all of the versions of its blobs are listed
+func validateIncludeVersions(includeVersions bool, fromTo common.FromTo, source common.ResourceString, forceWrite common.OverwriteOption, hasListOfVersions bool, includeSnapshots bool) error {
+	if !includeVersions {
+		return nil
+	}
+	if fromTo != common.EFromTo.BlobBlob() {
+		return errors.New("include-versions is only supported when copying from Blob Storage to Blob Storage")
+	}
+	if forceWrite != common.EOverwriteOption.True() {
+		return errors.New("include-versions writes each version over the one before it, so it can't be used with any overwrite setting other than true")
+	}
+	if hasListOfVersions {
+		return errors.New("include-versions can't be used with list-of-versions")
+	}
+	if includeSnapshots {
+		return errors.New("include-versions can't be used with include-snapshots")
+	}
+	if u, err := source.FullURL(); err == nil {
+		if parts := azblob.NewBlobURLParts(*u); parts.Snapshot != "" || parts.VersionID != "" {
+			return errors.New("include-versions can't be used when the source is a snapshot or version. Remove it from the URL to copy every version of the blob")
+		}
+	}
+	return nil
+}
+
+// validateIncludeDeleted checks that --include-deleted can be used. Only Blob Storage keeps soft-deleted blobs
+func validateIncludeDeleted(includeDeleted bool, fromTo common.FromTo, hasListOfVersions bool) error {
+	if !includeDeleted {
+		return nil
+	}
+	if fromTo.From() != common.ELocation.Blob() {
+		return errors.New("include-deleted is only supported when the source is Blob Storage")
+	}
+	if hasListOfVersions {
+		return errors.New("include-deleted can't be used with list-of-versions")
+	}
+	return nil
+}
+
+// validateS2SCopyMethod checks that the data of block blobs can be copied the way that the user asked. The service can
+// copy from any source, but AzCopy only reads blobs itself. The asynchronous Copy Blob copies the source's own
+// properties, and can't set index tags
+func validateS2SCopyMethod(method common.S2SCopyMethod, fromTo common.FromTo, blobType common.BlobType, hasBlobTags bool) error {
+	if method == common.ES2SCopyMethod.Auto() {
+		return nil
+	}
+	if !fromTo.IsS2S() || fromTo.To() != common.ELocation.Blob() {
+		return errors.New("s2s-copy-method is only supported for service to service copies to Blob Storage")
+	}
+	if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.BlockBlob() {
+		return errors.New("s2s-copy-method only applies to block blobs, so it can't be used with any other blob-type")
+	}
+	if method == common.ES2SCopyMethod.DownloadUpload() && fromTo.From() != common.ELocation.Blob() {
+		return errors.New("s2s-copy-method DownloadUpload is only supported when the source is Blob Storage")
+	}
+	if method == common.ES2SCopyMethod.CopyBlob() && fromTo.From() != common.ELocation.Blob() && fromTo.From() != common.ELocation.File() {
+		return errors.New("s2s-copy-method CopyBlob is only supported when the source is Blob Storage or Azure Files")
+	}
+	if method == common.ES2SCopyMethod.CopyBlob() && hasBlobTags {
+		return errors.New("s2s-copy-method CopyBlob can't set index tags, so it can't be used with blob-tags or s2s-preserve-blob-tags")
+	}
+	return nil
+}
+
+// validateAppendToBlob checks that --append can be used. It only adds to append blobs, and since it always writes to
+// existing blobs, it's pointless unless they may be overwritten. Compressed files aren't a continuation of the ones
+// uploaded before, so they can't be appended either
+func validateAppendToBlob(appendToBlob bool, fromTo common.FromTo, blobType common.BlobType, forceWrite common.OverwriteOption, compress bool) error {
+	if !appendToBlob {
+		return nil
+	}
+	if fromTo != common.EFromTo.LocalBlob() {
+		return errors.New("append is only supported when uploading from the local file system to Blob Storage")
+	}
+	if blobType != common.EBlobType.AppendBlob() {
+		return errors.New("append only adds to append blobs, so it must be used with blob-type AppendBlob")
+	}
+	if forceWrite != common.EOverwriteOption.True() {
+		return errors.New("append adds to the blobs that exist already, so it can't be used with any overwrite setting other than true")
+	}
+	if compress {
+		return errors.New("append can't be used with compress, since a compressed file isn't a continuation of the one uploaded before")
+	}
+	return nil
+}
+
+// validateContentTypeDetection checks that --content-type-map and --sniff-content-type can change anything. Content
+// types are only guessed when uploading, and not at all when the user gave one. Only local files can be sniffed, but the
+// map also applies to the files of an SFTP server
+func validateContentTypeDetection(hasContentTypeMap, sniffContentType, noGuessMimeType bool, fromTo common.FromTo) error {
+	if !hasContentTypeMap && !sniffContentType {
+		return nil
+	}
+	if noGuessMimeType {
+		return errors.New("content-type-map and sniff-content-type can't be used with no-guess-mime-type or content-type, since then the content type isn't guessed")
+	}
+	if hasContentTypeMap && fromTo.From() != common.ELocation.Local() && fromTo.From() != common.ELocation.SFTP() {
+		return errors.New("content-type-map only applies when uploading from the local file system or an SFTP server")
+	}
+	if sniffContentType && fromTo.From() != common.ELocation.Local() {
+		return errors.New("sniff-content-type only applies when uploading from the local file system")
+	}
+	return nil
+}
+
+// loadContentTypeMap reads the file given with --content-type-map. It holds a JSON object from file extensions to
+// content types, e.g. {".md": "text/markdown", "webmanifest": "application/manifest+json"}. Extensions are matched
+// regardless of case, and the leading dot is optional
+func loadContentTypeMap(path string) (common.ContentTypeMap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the content-type-map file: %w", err)
+	}
+
+	var entries map[string]string
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("the content-type-map file must hold a JSON object that maps file extensions to content types: %w", err)
+	}
+
+	contentTypeMap := common.ContentTypeMap{}
+	for extension, contentType := range entries {
+		extension = strings.ToLower(strings.TrimSpace(extension))
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		if extension == "." {
+			return nil, errors.New("the content-type-map file has an empty file extension")
+		}
+		if _, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, fmt.Errorf("'%s', given for %s in the content-type-map file, isn't a valid content type: %w", contentType, extension, err)
+		}
+		contentTypeMap[extension] = contentType
+	}
+
+	if len(contentTypeMap.ToString()) > ste.ContentTypeMapMaxBytes {
+		return nil, fmt.Errorf("the content-type-map file has too many entries. Once encoded, they must fit in %d bytes", ste.ContentTypeMapMaxBytes)
+	}
+	return contentTypeMap, nil
+}
+
+// loadHeaderRules reads the file given with --header-rules. It holds a JSON array of rules, each with a pattern and the
+// headers to set on the files that match it, e.g.
+// [{"pattern": "*.html", "cacheControl": "no-cache"}, {"pattern": "assets/*", "cacheControl": "max-age=31536000"}]
+func loadHeaderRules(rulesFile string) (common.HeaderRules, error) {
+	file, err := os.Open(rulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the header-rules file: %w", err)
+	}
+	defer file.Close()
+
+	var rules common.HeaderRules
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields() // so that a misspelt header isn't silently ignored
+	if err = decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("the header-rules file must hold a JSON array of rules, each with a pattern and the headers to set: %w", err)
+	}
+
+	for i, r := range rules {
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("rule %d of the header-rules file has no pattern", i+1)
+		}
+		if _, err = path.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("rule %d of the header-rules file has an invalid pattern '%s': %w", i+1, r.Pattern, err)
+		}
+		if r.CacheControl == "" && r.ContentEncoding == "" && r.ContentLanguage == "" && r.ContentDisposition == "" {
+			return nil, fmt.Errorf("rule %d of the header-rules file, for '%s', doesn't set any header", i+1, r.Pattern)
+		}
+	}
+
+	if len(rules.ToString()) > ste.HeaderRulesMaxBytes {
+		return nil, fmt.Errorf("the header-rules file has too many rules. Once encoded, they must fit in %d bytes", ste.HeaderRulesMaxBytes)
+	}
+	return rules, nil
+}
+
+// validatePreserveXattrs checks that extended attributes can be kept in this kind of transfer. Only Linux and macOS
+// have an API for them that we use
+func validatePreserveXattrs(preserveXattrs bool, fromTo common.FromTo) error {
+	if !preserveXattrs {
+		return nil
+	}
+	if fromTo != common.EFromTo.LocalBlob() && fromTo != common.EFromTo.BlobLocal() {
+		return errors.New("preserve-xattrs is only supported when uploading from the local file system to Blob Storage, or downloading from Blob Storage to it")
+	}
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		return errors.New("preserve-xattrs is only supported on Linux and macOS")
+	}
+	return nil
+}
+
+// validatePreservePOSIXPermissions checks that POSIX permissions can be kept in this kind of transfer. Only ADLS Gen2
+// (i.e. BlobFS) has them on the service side, and Windows doesn't have them locally
+func validatePreservePOSIXPermissions(preservePOSIXPermissions bool, fromTo common.FromTo) error {
+	if !preservePOSIXPermissions {
+		return nil
+	}
+	if fromTo != common.EFromTo.LocalBlobFS() && fromTo != common.EFromTo.BlobFSLocal() {
+		return errors.New("preserve-posix-permissions is only supported when uploading from the local file system to ADLS Gen2, or downloading from ADLS Gen2 to it")
+	}
+	if runtime.GOOS == "windows" {
+		return errors.New("preserve-posix-permissions is not supported on Windows")
+	}
+	return nil
+}
+
 func crossValidateSymlinksAndPermissions(followSymlinks, preservePermissions bool) error {
 	if followSymlinks && preservePermissions {
 		return errors.New("cannot follow symlinks when preserving permissions (since the correct permission inheritance behaviour for symlink targets is undefined)")
@@ -806,6 +1519,27 @@ func validatePutMd5(putMd5 bool, fromTo common.FromTo) error {
 	return nil
 }
 
+// validateManagedDiskDestination checks the options of copies to a managed disk's import URL. The disk is a single page
+// blob, which exists as soon as the disk does, so it must be overwritten. Its account doesn't keep MD5 hashes either
+func validateManagedDiskDestination(destination string, fromTo common.FromTo, blobType common.BlobType, forceWrite common.OverwriteOption, putMd5 bool) error {
+	if fromTo.To() != common.ELocation.Blob() {
+		return nil
+	}
+	if u, err := url.Parse(destination); err != nil || !ste.IsManagedDiskImportURL(*u) {
+		return nil
+	}
+	if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.PageBlob() {
+		return errors.New("managed disks can only be page blobs, so blob-type must be PageBlob (or not given) when copying to one")
+	}
+	if forceWrite != common.EOverwriteOption.True() {
+		return errors.New("the page blob of a managed disk always exists, so overwrite must be true when copying to one")
+	}
+	if putMd5 {
+		return errors.New("put-md5 can't be used when copying to a managed disk, since managed disks don't keep MD5 hashes")
+	}
+	return nil
+}
+
 func validateMd5Option(option common.HashValidationOption, fromTo common.FromTo) error {
 	hasMd5Validation := option != common.DefaultHashValidationOption
 	if hasMd5Validation && !fromTo.IsDownload() {
@@ -814,6 +1548,19 @@ func validateMd5Option(option common.HashValidationOption, fromTo common.FromTo)
 	return nil
 }
 
+// validateBlobHashOptions checks that the put-<hash> and check-<hash> options of a hash that only Blob Storage
+// supports (CRC64, which the service checks for each range, and SHA-256, which is kept in the blob's metadata)
+// are only used when uploading to, or downloading from, Blob Storage
+func validateBlobHashOptions(hashName string, put bool, option common.HashValidationOption, fromTo common.FromTo) error {
+	if put && fromTo != common.EFromTo.LocalBlob() {
+		return fmt.Errorf("put-%s is only supported when uploading to Blob Storage", hashName)
+	}
+	if option != common.EHashValidationOption.NoCheck() && fromTo != common.EFromTo.BlobLocal() {
+		return fmt.Errorf("check-%s is only supported when downloading from Blob Storage", hashName)
+	}
+	return nil
+}
+
 // represents the processed copy command input from the user
 type cookedCopyCmdArgs struct {
 	// from arguments
@@ -830,38 +1577,72 @@ type cookedCopyCmdArgs struct {
 	includeFileAttributes []string
 	excludeFileAttributes []string
 	includeAfter          *time.Time
+	includeBefore         *time.Time
+	minSize               *int64 // nil when there's no limit
+	maxSize               *int64
 
 	// list of version ids
 	listOfVersionIDs chan string
+	includeSnapshots bool
+	includeVersions  bool
+	includeDeleted   bool
 	// filters from flags
 	listOfFilesChannel chan string // Channels are nullable.
 	recursive          bool
 	stripTopDir        bool
-	followSymlinks     bool
+	symlinkHandling    common.SymlinkHandlingType
+	preserveHardlinks  bool
 	forceWrite         common.OverwriteOption // says whether we should try to overwrite
 	forceIfReadOnly    bool                   // says whether we should _force_ any overwrites (triggered by forceWrite) to work on Azure Files objects that are set to read-only
 	autoDecompress     bool
+	compress           bool
+	pack               common.PackFormat
+	unpack             bool
+	uploadVHD          bool
+	appendToBlob       bool
 
 	// options from flags
 	blockSize int64
 	// list of blobTypes to exclude while enumerating the transfer
-	excludeBlobType          []azblob.BlobType
+	excludeBlobType []azblob.BlobType
+	// list of access tiers to include while enumerating the transfer. Empty means all of them
+	includeBlobTiers []azblob.AccessTierType
+	// what to do with archived source blobs. archivedBlobSkipper is set by the enumerator when they are skipped,
+	// so that we can report how many were
+	archivedSource      common.ArchivedSourceOption
+	rehydratePriority   common.RehydratePriority
+	archivedBlobSkipper *archivedBlobSkipper
+	// the index tags and metadata that the blobs to include must have. The values may use wildcards
+	includeBlobTags          map[string]string
+	includeMetadata          map[string]string
 	blobType                 common.BlobType
 	blockBlobTier            common.BlockBlobTier
+	blockBlobTierRules       common.BlockBlobTierRules
 	pageBlobTier             common.PageBlobTier
 	metadata                 string
+	blobTags                 common.BlobTags
 	contentType              string
 	contentEncoding          string
 	contentLanguage          string
 	contentDisposition       string
 	cacheControl             string
 	noGuessMimeType          bool
+	contentTypeMap           common.ContentTypeMap
+	sniffContentType         bool
+	headerRules              common.HeaderRules
 	preserveLastModifiedTime bool
 	deleteSnapshotsOption    common.DeleteSnapshotsOption
 	putMd5                   bool
 	md5ValidationOption      common.HashValidationOption
+	putCrc64                 bool
+	crc64ValidationOption    common.HashValidationOption
+	putSha256                bool
+	sha256ValidationOption   common.HashValidationOption
 	CheckLength              bool
 	logVerbosity             common.LogLevel
+	priority                 common.JobPriority
+	activeHours              common.ActiveHours
+	afterJob                 common.JobID // the job that this one waits for, if any
 	// commandString hold the user given command which is logged to the Job log file
 	commandString string
 
@@ -881,6 +1662,9 @@ type cookedCopyCmdArgs struct {
 	// used to calculate job summary
 	jobStartTime time.Time
 
+	// only set if the progress bar was requested
+	progressBar *progressBar
+
 	// this flag is set by the enumerator
 	// it is useful to indicate whether we are simply waiting for the purpose of cancelling
 	isEnumerationComplete bool
@@ -889,6 +1673,12 @@ type cookedCopyCmdArgs struct {
 	preserveSMBPermissions common.PreservePermissionsOption
 	// Whether the user wants to preserve the SMB properties ...
 	preserveSMBInfo bool
+	// Whether the user wants to preserve the POSIX owner, group and mode of files moving to or from ADLS Gen2
+	preservePOSIXPermissions bool
+	// Whether the times of local files are kept in blob metadata on upload, and restored from it on download
+	preserveFileTimes bool
+	// Whether the extended attributes of local files are kept in blob metadata on upload, and restored from it on download
+	preserveXattrs bool
 
 	// Whether to enable Windows special privileges
 	backupMode bool
@@ -909,6 +1699,12 @@ type cookedCopyCmdArgs struct {
 	s2sSourceChangeValidation bool
 	// specify how user wants to handle invalid metadata.
 	s2sInvalidMetadataHandleOption common.InvalidMetadataHandleOption
+	// whether user wants to copy the index tags of source blobs during service to service copy.
+	s2sPreserveBlobTags bool
+	// how the metadata given by the user is combined with the source's during service to service copy.
+	s2sMetadataMerge common.MetadataMergeOption
+	// how the data of block blobs is copied during service to service copy.
+	s2sCopyMethod common.S2SCopyMethod
 
 	// followup/cleanup properties are NOT available on resume, and so should not be used for jobs that may be resumed
 	// TODO: consider find a way to enforce that, or else to allow them to be preserved. Initially, they are just for benchmark jobs, so not a problem immediately because those jobs can't be resumed, by design.
@@ -919,6 +1715,30 @@ type cookedCopyCmdArgs struct {
 
 	// whether to include blobs that have metadata 'hdi_isfolder = true'
 	includeDirectoryStubs bool
+
+	// whether to skip files that are unchanged at the destination, and how much later than the destination's
+	// last modified time the source's may be, while still being considered unchanged
+	skipUnchanged          bool
+	skipUnchangedTolerance time.Duration
+	// when set, the destination is made a mirror of the source, which skips the files that are the same at both ends,
+	// and deletes the extra ones
+	mirror bool
+	// set by the enumerator when skipUnchanged or mirror is on, so that we can report how many files were skipped
+	unchangedFileSkipper *unchangedFileSkipper
+
+	// set by the enumerator when preserveHardlinks is on, to keep track of the links it finds
+	hardlinks *hardlinkPreserver
+
+	// what to do when two source files have the same destination
+	duplicateDestinationPolicy common.DuplicateDestinationPolicy
+
+	// changes each file's path at the destination
+	pathRewrites pathRewriter
+
+	// set when only a dry run is wanted, in which case it prints the transfers instead of the job being started
+	dryRunPrinter *dryRunPrinter
+
+	planLocation common.PlanLocation
 }
 
 func (cca *cookedCopyCmdArgs) isRedirection() bool {
@@ -933,6 +1753,12 @@ func (cca *cookedCopyCmdArgs) isRedirection() bool {
 }
 
 func (cca *cookedCopyCmdArgs) process() error {
+	// wait before doing anything else, since the source may well be changed by the job we're waiting for
+	if !cca.afterJob.IsEmpty() {
+		if err := waitForPrecedingJob(cca.jobID, cca.afterJob, cca.commandString); err != nil {
+			return err
+		}
+	}
 
 	err := common.SetBackupMode(cca.backupMode, cca.fromTo)
 	if err != nil {
@@ -1092,7 +1918,13 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		ForceWrite:      cca.forceWrite,
 		ForceIfReadOnly: cca.forceIfReadOnly,
 		AutoDecompress:  cca.autoDecompress,
-		Priority:        common.EJobPriority.Normal(),
+		Compress:        cca.compress,
+		Pack:            cca.pack,
+		Unpack:          cca.unpack,
+		UploadVHD:       cca.uploadVHD,
+		AppendToBlob:    cca.appendToBlob,
+		Priority:        cca.priority,
+		ActiveHours:     cca.activeHours,
 		LogLevel:        cca.logVerbosity,
 		ExcludeBlobType: cca.excludeBlobType,
 		BlobAttributes: common.BlobTransferAttributes{
@@ -1104,16 +1936,26 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 			ContentDisposition:       cca.contentDisposition,
 			CacheControl:             cca.cacheControl,
 			BlockBlobTier:            cca.blockBlobTier,
+			BlockBlobTierRules:       cca.blockBlobTierRules.ToString(),
 			PageBlobTier:             cca.pageBlobTier,
 			Metadata:                 cca.metadata,
+			BlobTags:                 cca.blobTags.ToString(),
 			NoGuessMimeType:          cca.noGuessMimeType,
+			ContentTypeMap:           cca.contentTypeMap.ToString(),
+			HeaderRules:              cca.headerRules.ToString(),
+			SniffContentType:         cca.sniffContentType,
 			PreserveLastModifiedTime: cca.preserveLastModifiedTime,
 			PutMd5:                   cca.putMd5,
 			MD5ValidationOption:      cca.md5ValidationOption,
+			PutCrc64:                 cca.putCrc64,
+			Crc64ValidationOption:    cca.crc64ValidationOption,
+			PutSha256:                cca.putSha256,
+			Sha256ValidationOption:   cca.sha256ValidationOption,
 			DeleteSnapshotsOption:    cca.deleteSnapshotsOption,
 		},
 		CommandString:  cca.commandString,
 		CredentialInfo: cca.credentialInfo,
+		PlanLocation:   cca.planLocation,
 	}
 
 	from := cca.fromTo.From()
@@ -1150,6 +1992,12 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		common.EFromTo.FileFile(),
 		common.EFromTo.BlobFile(),
 		common.EFromTo.S3Blob(),
+		common.EFromTo.S3File(),
+		common.EFromTo.SFTPBlob(),
+		common.EFromTo.SFTPFile(),
+		common.EFromTo.LocalSFTP(),
+		common.EFromTo.HTTPBlob(),
+		common.EFromTo.HTTPFile(),
 		common.EFromTo.BenchmarkBlob(),
 		common.EFromTo.BenchmarkBlobFS(),
 		common.EFromTo.BenchmarkFile():
@@ -1181,7 +2029,21 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 		return fmt.Errorf("copy direction %v is not supported\n", cca.fromTo)
 	}
 
+	if err == nil && cca.dryRunPrinter != nil {
+		glcm.Exit(func(format common.OutputFormat) string {
+			return cca.dryRunPrinter.summary(cca.fromTo.To() == common.ELocation.Unknown())
+		}, common.EExitCode.Success())
+		return nil // explicitly, since in our tests Exit might be mocked away
+	}
+
 	if err != nil {
+		unchanged, archived := cca.unchangedFileSkipper.skippedCount(), cca.archivedBlobSkipper.skippedCount()
+		if err == NothingScheduledError && unchanged+archived > 0 {
+			// with skip-unchanged, or when skipping archived blobs, finding that nothing needs to be copied is a successful outcome
+			glcm.Exit(func(format common.OutputFormat) string {
+				return formatNothingScheduled(unchanged, archived, cca.dryRunPrinter != nil)
+			}, common.EExitCode.Success())
+		}
 		if err == NothingToRemoveError || err == NothingScheduledError {
 			return err // don't wrap it with anything that uses the word "error"
 		} else {
@@ -1197,17 +2059,14 @@ func (cca *cookedCopyCmdArgs) processCopyJobPartOrders() (err error) {
 // if blocking is specified to false, then another goroutine spawns and wait out the job
 func (cca *cookedCopyCmdArgs) waitUntilJobCompletion(blocking bool) {
 	// print initial message to indicate that the job is starting
-	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(),
-		fmt.Sprintf("%s%s%s.log",
-			azcopyLogPathFolder,
-			common.OS_PATH_SEPARATOR,
-			cca.jobID),
-		cca.isCleanupJob,
-		cca.cleanupJobMessage))
+	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), jobLogFilePath(cca.jobID), cca.isCleanupJob, cca.cleanupJobMessage))
 
 	// initialize the times necessary to track progress
 	cca.jobStartTime = time.Now()
 	cca.intervalStartTime = time.Now()
+	if showProgressBar {
+		cca.progressBar = newProgressBar(cca.jobStartTime)
+	}
 	cca.intervalBytesTransferred = 0
 
 	// hand over control to the lifecycle manager if blocking
@@ -1251,11 +2110,10 @@ func (cca *cookedCopyCmdArgs) hasFollowup() bool {
 
 func (cca *cookedCopyCmdArgs) launchFollowup(priorJobExitCode common.ExitCode) {
 	go func() {
-		glcm.AllowReinitiateProgressReporting()
 		cca.followupJobArgs.priorJobExitCode = &priorJobExitCode
 		err := cca.followupJobArgs.process()
 		if err == NothingToRemoveError {
-			glcm.Info("Cleanup completed (nothing needed to be deleted)")
+			glcm.Info(common.Localize(common.EMessageKey.CleanupNothingToDelete()))
 			glcm.Exit(nil, common.EExitCode.Success())
 		} else if err != nil {
 			glcm.Error("failed to perform followup/cleanup job due to error: " + err.Error())
@@ -1276,13 +2134,13 @@ func (cca *cookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 	// fetch a job status
 	var summary common.ListJobSummaryResponse
 	Rpc(common.ERpcCmd.ListJobSummary(), &cca.jobID, &summary)
-	glcmSwapOnce.Do(func() {
-		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &glcm)
-	})
+	Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &lcm)
 	summary.IsCleanupJob = cca.isCleanupJob // only FE knows this, so we can only set it here
+	summary.TransfersSkippedUnchanged = cca.unchangedFileSkipper.skippedCount()
+	summary.TransfersSkippedArchived = cca.archivedBlobSkipper.skippedCount()
 	cleanupStatusString := fmt.Sprintf("Cleanup %v/%v", summary.TransfersCompleted, summary.TotalTransfers)
 
-	jobDone := summary.JobStatus.IsJobDone()
+	jobDone := summary.JobStatus.HasStopped()
 	totalKnownCount = summary.TotalTransfers
 
 	// if json is not desired, and job is done, then we generate a special end message to conclude the job
@@ -1293,6 +2151,12 @@ func (cca *cookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 		if summary.TransfersFailed > 0 {
 			exitCode = common.EExitCode.Error()
 		}
+		if cca.hardlinks != nil && cca.fromTo.IsDownload() && summary.JobStatus != common.EJobStatus.Paused() &&
+			cca.hardlinks.createLinks(cca.forceWrite) > 0 {
+			exitCode = common.EExitCode.Error()
+		}
+		summary.FinalJobSummary = common.NewFinalJobSummary(summary, duration, exitCode)
+		summary.FinalJobSummary.LogFileLocation = jobLogFilePath(summary.JobID)
 
 		builder := func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
@@ -1302,20 +2166,7 @@ func (cca *cookedCopyCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			} else {
 				screenStats, logStats := formatExtraStats(cca.fromTo, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage)
 
-				output := fmt.Sprintf(
-					`
-
-Job %s summary
-Elapsed Time (Minutes): %v
-Number of File Transfers: %v
-Number of Folder Property Transfers: %v
-Total Number of Transfers: %v
-Number of Transfers Completed: %v
-Number of Transfers Failed: %v
-Number of Transfers Skipped: %v
-TotalBytesTransferred: %v
-Final Job Status: %v%s%s
-`,
+				output := common.Localize(common.EMessageKey.CopyJobSummary(),
 					summary.JobID.String(),
 					ste.ToFixed(duration.Minutes(), 4),
 					summary.FileTransfers,
@@ -1324,6 +2175,8 @@ Final Job Status: %v%s%s
 					summary.TransfersCompleted,
 					summary.TransfersFailed,
 					summary.TransfersSkipped,
+					cca.formatSkippedUnchanged(summary.TransfersSkippedUnchanged)+cca.formatSkippedArchived(summary.TransfersSkippedArchived)+
+						formatMd5Mismatches(summary.Md5Mismatches),
 					summary.TotalBytesTransferred,
 					summary.JobStatus,
 					screenStats,
@@ -1332,6 +2185,8 @@ Final Job Status: %v%s%s
 				// abbreviated output for cleanup jobs
 				if cca.isCleanupJob {
 					output = fmt.Sprintf("%s: %s)", cleanupStatusString, summary.JobStatus)
+				} else {
+					output += formatFinalJobSummary(summary.FinalJobSummary)
 				}
 
 				// log to job log
@@ -1343,7 +2198,12 @@ Final Job Status: %v%s%s
 			}
 		}
 
-		if cca.hasFollowup() {
+		jobPaused := summary.JobStatus == common.EJobStatus.Paused()
+		if jobPaused {
+			lcm.Info(common.Localize(common.EMessageKey.JobPaused(), summary.JobID, summary.JobID))
+		}
+
+		if cca.hasFollowup() && !jobPaused { // the followup must wait until the job is resumed and has finished
 			lcm.Exit(builder, common.EExitCode.NoExit()) // leave the app running to process the followup
 			cca.launchFollowup(exitCode)
 			lcm.SurrenderControl() // the followup job will run on its own goroutines
@@ -1364,7 +2224,7 @@ Final Job Status: %v%s%s
 		return common.Iffloat64(timeElapsed != 0, bytesInMb/timeElapsed, 0) * 8
 	}
 
-	glcm.Progress(func(format common.OutputFormat) string {
+	lcm.Progress(func(format common.OutputFormat) string {
 		if format == common.EOutputFormat.Json() {
 			jsonOutput, err := json.Marshal(summary)
 			common.PanicIfErr(err)
@@ -1374,6 +2234,9 @@ Final Job Status: %v%s%s
 			if cca.isCleanupJob {
 				return cleanupStatusString
 			}
+			if cca.progressBar != nil {
+				return cca.progressBar.render(summary, time.Now(), getTerminalWidth())
+			}
 
 			// if json is not needed, then we generate a message that goes nicely on the same line
 			// display a scanning keyword if the job is not completely ordered
@@ -1393,11 +2256,13 @@ Final Job Status: %v%s%s
 			isBenchmark := cca.fromTo.From() == common.ELocation.Benchmark()
 			perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, isBenchmark)
 
-			return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Skipped, %v Total%s, %s%s%s",
+			return fmt.Sprintf("%.1f %%%s, %v Done, %v Failed, %v Pending%s, %v Skipped, %v Total%s, %s%s%s",
 				summary.PercentComplete,
+				getByteProgressText(summary),
 				summary.TransfersCompleted,
 				summary.TransfersFailed,
 				summary.TotalTransfers-(summary.TransfersCompleted+summary.TransfersFailed+summary.TransfersSkipped),
+				getStalledText(summary)+getThrottledText(summary),
 				summary.TransfersSkipped, summary.TotalTransfers, scanningString, perfString, throughputString, diskString)
 		}
 	})
@@ -1405,6 +2270,63 @@ Final Job Status: %v%s%s
 	return
 }
 
+// files skipped by --skip-unchanged or --mirror never become transfers, so they get their own line in the summary
+func (cca *cookedCopyCmdArgs) formatSkippedUnchanged(count uint32) string {
+	if !cca.skipUnchanged && !cca.mirror {
+		return ""
+	}
+	return fmt.Sprintf("\nNumber of Files Skipped Because Unchanged: %v", count)
+}
+
+func (cca *cookedCopyCmdArgs) formatSkippedArchived(count uint32) string {
+	if cca.archivedSource != common.EArchivedSourceOption.Skip() {
+		return ""
+	}
+	return fmt.Sprintf("\nNumber of Blobs Skipped Because Archived: %v", count)
+}
+
+// explains why a copy that found files, but skipped them all, has nothing to transfer
+func formatNothingScheduled(unchanged, archived uint32, isDryRun bool) string {
+	var reason string
+	switch {
+	case archived == 0:
+		reason = fmt.Sprintf("all %v were unchanged at the destination", unchanged)
+	case unchanged == 0:
+		reason = fmt.Sprintf("all %v were in the archive tier", archived)
+	default:
+		reason = fmt.Sprintf("%v were unchanged at the destination and %v were in the archive tier", unchanged, archived)
+	}
+	if isDryRun {
+		return "Dry run complete. No files would be transferred, because " + reason + ". Nothing was changed."
+	}
+	return "No files were transferred, because " + reason
+}
+
+// at most this many of the files with MD5 mismatches are listed in the summary. The log has all of them
+const maxMd5MismatchesListed = 20
+
+// downloads whose data didn't match the MD5 stored at the source are listed, whether they failed or, with
+// check-md5=LogOnly, were kept, since the latter aren't shown anywhere else
+func formatMd5Mismatches(mismatches []common.TransferDetail) string {
+	if len(mismatches) == 0 {
+		return ""
+	}
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("\nNumber of Files with MD5 Mismatches: %v", len(mismatches)))
+	for i, m := range mismatches {
+		if i == maxMd5MismatchesListed {
+			b.WriteString(fmt.Sprintf("\n  ...and %v more. See the log for the others", len(mismatches)-maxMd5MismatchesListed))
+			break
+		}
+		outcome := "failed"
+		if m.TransferStatus == common.ETransferStatus.Success() {
+			outcome = "kept"
+		}
+		b.WriteString(fmt.Sprintf("\n  %s (%s)", m.Dst, outcome))
+	}
+	return b.String()
+}
+
 func formatPerfAdvice(advice []common.PerformanceAdvice) string {
 	if len(advice) == 0 {
 		return ""
@@ -1470,6 +2392,43 @@ func getPerfDisplayText(perfDiagnosticStrings []string, constraint common.PerfCo
 	return
 }
 
+// getByteProgressText shows how many bytes have been committed so far, so that progress remains visible
+// while a single very large file is in flight (when the file counts may not change for a long time)
+func getByteProgressText(summary common.ListJobSummaryResponse) string {
+	if summary.TotalBytesExpected == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s of %s)",
+		byteSizeToString(int64(summary.TotalBytesTransferred)),
+		byteSizeToString(int64(summary.TotalBytesExpected)))
+}
+
+// getStalledText shows how many times a transfer had to be restarted because it stopped making progress.
+// It's only shown once that has happened, since in most jobs it never will
+func getStalledText(summary common.ListJobSummaryResponse) string {
+	if summary.TransfersStalled == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%v Restarted After Stalling)", summary.TransfersStalled)
+}
+
+// getThrottledText shows how many requests the service has asked us to slow down, and so were retried after a delay.
+// Like the stalled count, it's only shown once it's happened
+func getThrottledText(summary common.ListJobSummaryResponse) string {
+	if summary.ThrottledRequests == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %v Throttled", summary.ThrottledRequests)
+}
+
+// formatFinalJobSummary gives the text output the same summary that the JSON output gets, on a single line,
+// so that scripts which use text output can check the job's outcome without parsing the rest
+func formatFinalJobSummary(summary *common.FinalJobSummary) string {
+	jsonOutput, err := json.Marshal(summary)
+	common.PanicIfErr(err)
+	return "Final Job Summary (JSON): " + string(jsonOutput) + "\n"
+}
+
 func shouldDisplayPerfStates() bool {
 	return glcm.GetEnvironmentVariable(common.EEnvironmentVariable.ShowPerfStates()) != ""
 }
@@ -1524,6 +2483,28 @@ func init() {
 					raw.src = args[0]
 					raw.dst = pipeLocation
 				}
+			} else if len(args) == 2 && (args[0] == stdioArgument || args[1] == stdioArgument) { // without the need for --from-to
+				fromTo, err := inferStdioFromTo(args[0], args[1], raw.fromTo)
+				if err != nil {
+					return err
+				}
+
+				if fromTo == common.EFromTo.PipeBlob() {
+					stdinPipeIn, err := isStdinPipeIn()
+					if err != nil {
+						return err
+					} else if !stdinPipeIn {
+						return errors.New("fatal: nothing is piped into Stdin, to be uploaded")
+					}
+
+					// stdin holds the data, so it can't also be watched for answers or for cancellation
+					raw.src = pipeLocation
+					raw.dst = args[1]
+				} else {
+					// stdout carries the data, so nothing else is printed to it. Errors still go to stderr
+					raw.src = args[0]
+					raw.dst = pipeLocation
+				}
 			} else if len(args) == 2 { // normal copy
 				raw.src = args[0]
 				raw.dst = args[1]
@@ -1556,10 +2537,30 @@ func init() {
 		},
 	}
 	rootCmd.AddCommand(cpCmd)
+	batchJobPreparers[cpCmd] = func(commandString string) (batchJob, error) {
+		cooked, err := raw.cook()
+		if err != nil {
+			return batchJob{}, err
+		}
+		if cooked.isRedirection() {
+			return batchJob{}, errors.New("piping can't be used in a batch, since the jobs would all share the one stdin and stdout")
+		}
+		cooked.commandString = commandString
+		return batchJob{commandString: commandString, controller: &cooked, start: cooked.process}, nil
+	}
 
 	// filters change which files get transferred
 	cpCmd.PersistentFlags().BoolVar(&raw.followSymlinks, "follow-symlinks", false, "Follow symbolic links when uploading from local file system.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveSymlinks, "preserve-symlinks", false, "Upload symbolic links as links, rather than following them. Each link becomes a blob that holds the target of the link, with the metadata '"+common.SymlinkMetadataKey+"=true'. "+
+		"When downloading, such blobs are turned back into symbolic links. Only supported between the local file system and Blob Storage.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveHardlinks, "preserve-hardlinks", false, "Keep hard links as links. When uploading, the content of a file with several hard links is uploaded once, and the other links become empty blobs, "+
+		"with the metadata '"+common.HardlinkMetadataKey+"' saying which blob has the content. When downloading, those blobs are turned back into hard links, after the other files have been downloaded. "+
+		"Only supported between the local file system and Blob Storage, and not for uploads from Windows.")
+	cpCmd.PersistentFlags().BoolVar(&raw.skipSymlinks, "skip-symlinks", false, "Leave symbolic links out of an upload. This is what happens when neither --follow-symlinks nor --preserve-symlinks is given.")
 	cpCmd.PersistentFlags().StringVar(&raw.includeAfter, common.IncludeAfterFlagName, "", "Include only those files modified on or after the given date/time. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone. As at AzCopy 10.5, this flag applies only to files, not folders, so folder properties won't be copied when using this flag with --preserve-smb-info or --preserve-smb-permissions.")
+	cpCmd.PersistentFlags().StringVar(&raw.includeBefore, common.IncludeBeforeFlagName, "", "Include only those files modified on or before the given date/time. The value is given in the same way as for --include-after, and the two may be used together to copy only the files changed in a window of time. This flag applies only to files, not folders.")
+	cpCmd.PersistentFlags().StringVar(&raw.minSize, "min-size", "", "Include only those files that are at least this big. The size is a number of bytes, optionally followed by a unit: KiB, MiB, GiB and TiB (or K, M, G and T) are powers of 1024, and KB, MB, GB and TB are powers of 1000. E.g. 500, 64KiB or 1.5GB. This flag applies only to files, not folders.")
+	cpCmd.PersistentFlags().StringVar(&raw.maxSize, "max-size", "", "Include only those files that are no bigger than this. The size is given in the same way as for --min-size. E.g. '--max-size 1GiB' skips the files over 1 GiB.")
 	cpCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only these files when copying. "+
 		"This option supports wildcard characters (*). Separate files by using a ';'.")
 	cpCmd.PersistentFlags().StringVar(&raw.includePath, "include-path", "", "Include only these paths when copying. "+
@@ -1569,44 +2570,142 @@ func init() {
 	// This flag is implemented only for Storage Explorer.
 	cpCmd.PersistentFlags().StringVar(&raw.listOfFilesToCopy, "list-of-files", "", "Defines the location of text file which has the list of only files to be copied.")
 	cpCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude these files when copying. This option supports wildcard characters (*)")
-	cpCmd.PersistentFlags().StringVar(&raw.forceWrite, "overwrite", "true", "Overwrite the conflicting files and blobs at the destination if this flag is set to true. (default 'true') Possible values include 'true', 'false', 'prompt', and 'ifSourceNewer'. For destinations that support folders, conflicting folder-level properties will be overwritten this flag is 'true' or if a positive response is provided to the prompt.")
-	cpCmd.PersistentFlags().BoolVar(&raw.autoDecompress, "decompress", false, "Automatically decompress files when downloading, if their content-encoding indicates that they are compressed. The supported content-encoding values are 'gzip' and 'deflate'. File extensions of '.gz'/'.gzip' or '.zz' aren't necessary, but will be removed if present.")
+	cpCmd.PersistentFlags().StringVar(&raw.forceWrite, "overwrite", "true", "Overwrite the conflicting files and blobs at the destination if this flag is set to true. (default 'true') Possible values include 'true', 'false', 'prompt', 'ifSourceNewer' and 'ifSizeDifferent'. Use 'ifSourceNewer' or 'ifSizeDifferent' when re-running a partially completed copy, to write only the files that are newer or that differ in size. With 'prompt', the answers 'Yes for all' and 'No for all' are remembered for the rest of the job. For destinations that support folders, conflicting folder-level properties will be overwritten this flag is 'true' or if a positive response is provided to the prompt.")
+	cpCmd.PersistentFlags().BoolVar(&raw.compress, "compress", false, "Compress files with gzip when uploading, and set their content-encoding to gzip, so that browsers and 'azcopy copy --decompress' decompress them. "+
+		"Files whose extensions show that they are compressed already (such as .zip, .gz, .jpg and .mp4), and files that gzip doesn't make smaller, are uploaded as they are. "+
+		"Each file is compressed to a temporary file before it's uploaded, so there must be space for it in the temporary directory. Progress is reported in terms of the uncompressed sizes.")
+	cpCmd.PersistentFlags().StringVar(&raw.pack, "pack", common.EPackFormat.None().String(), "Pack the files of each directory into one archive when uploading, which is much faster than uploading them one by one when there are many small files. "+
+		"Possible values are 'none' (the default), 'tar' and 'tarGz', which also compresses the archives with gzip. Each directory's archive is named "+common.EPackFormat.Tar().ArchiveName()+" (or "+common.EPackFormat.TarGz().ArchiveName()+"), and is uploaded into that directory. "+
+		"Only regular files are packed, and each archive is made in the temporary directory before it's uploaded. Use 'azcopy copy --unpack' to unpack the archives when downloading.")
+	cpCmd.PersistentFlags().BoolVar(&raw.unpack, "unpack", false, "Unpack the blobs whose names end with .tar, .tar.gz or .tgz when downloading, such as those uploaded with --pack. "+
+		"Each archive is unpacked into the directory it's downloaded to, and then removed. Files that exist already are only replaced if --overwrite is true.")
+	cpCmd.PersistentFlags().BoolVar(&raw.vhd, "vhd", false, "Upload disk images as page blobs. Each file must be a fixed-size VHD whose virtual size is a whole number of MiB, which is checked before it's uploaded. "+
+		"Only the pages that hold data are sent, so that mostly-empty disks upload quickly, while the VHD footer at the end of each file is always kept.")
+	cpCmd.PersistentFlags().BoolVar(&raw.appendToBlob, "append", false, "Upload only the data that was added to each file since it was last uploaded, by appending it to the existing blob. Must be used with '--blob-type AppendBlob'. "+
+		"Blobs that don't exist yet are created, and files that are shorter than their blobs fail, since they must have been truncated or replaced. Files should only ever be appended to, since the part of each file that's in its blob already isn't compared.")
+	cpCmd.PersistentFlags().BoolVar(&raw.autoDecompress, "decompress", false, "Automatically decompress files when downloading, if their content-encoding indicates that they are compressed. The supported content-encoding values are 'gzip' (or 'x-gzip') and 'deflate' (with or without the zlib wrapper). File extensions of '.gz'/'.gzip' or '.zz' aren't necessary, but will be removed if present, and '.tgz' becomes '.tar'.")
 	cpCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", false, "Look into sub-directories recursively when uploading from local file system.")
-	cpCmd.PersistentFlags().StringVar(&raw.fromTo, "from-to", "", "Optionally specifies the source destination combination. For Example: LocalBlob, BlobLocal, LocalBlobFS. Piping: BlobPipe, PipeBlob")
+	cpCmd.PersistentFlags().StringVar(&raw.fromTo, "from-to", "", "Optionally specifies the source destination combination. For Example: LocalBlob, BlobLocal, LocalBlobFS. Piping: BlobPipe, PipeBlob. "+
+		"SFTP: SFTPBlob, SFTPFile, and LocalSFTP, since only local files can be copied to an SFTP server")
+	cpCmd.PersistentFlags().StringVar(&raw.includeTier, "include-tier", "", "Include only the blobs in these access tiers when copying from Blob Storage, e.g. 'Archive' to migrate only the archived blobs, or 'Hot;Cool' to leave the archived blobs, which can't be read until they're rehydrated, alone. "+
+		"Separate the tiers by using a ';' or a ','. Blobs without an access tier, such as the page blobs of a standard account, are never included.")
+	cpCmd.PersistentFlags().StringVar(&raw.archivedSource, "archived-source", common.EArchivedSourceOption.Fail().String(), "What to do with source blobs in the archive tier, which can't be read until they're rehydrated. "+
+		"'Fail' lets their transfers fail, 'Skip' leaves them out and counts them in the job summary, and 'Rehydrate' starts moving them to the Hot tier and copies each one once it's back online, which can take hours. "+
+		"Rehydrating needs permission to set the tier of the source blobs.")
+	cpCmd.PersistentFlags().StringVar(&raw.rehydratePriority, "rehydrate-priority", "", "The priority of the rehydrations started by --archived-source=Rehydrate: 'Standard' (the default) or 'High', which is faster and more expensive.")
+	cpCmd.PersistentFlags().StringVar(&raw.includeTags, "include-tags", "", "Include only the blobs that have all these index tags when copying from Blob Storage, e.g. 'project=alpha;env=prod'. "+
+		"The values may use wildcard characters (*). When none do, and the credentials allow it, the blobs are found with the service's find-by-tags API, rather than by listing every blob. "+
+		"That API finds blobs by an index that is updated shortly after their tags change, so a blob whose tags were changed a moment ago may not be found.")
+	cpCmd.PersistentFlags().StringVar(&raw.includeMetadata, "include-metadata", "", "Include only the blobs that have all these metadata when copying from Blob Storage, e.g. 'owner=finance;reviewed=yes'. The names are case-insensitive, and the values may use wildcard characters (*).")
 	cpCmd.PersistentFlags().StringVar(&raw.excludeBlobType, "exclude-blob-type", "", "Optionally specifies the type of blob (BlockBlob/ PageBlob/ AppendBlob) to exclude when copying blobs from the container "+
 		"or the account. Use of this flag is not applicable for copying data from non azure-service to service. More than one blob should be separated by ';'. ")
 	// options change how the transfers are performed
-	cpCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage, and downloading from Azure Storage. The default value is automatically calculated based on file size. Decimal fractions are allowed (For example: 0.25).")
+	cpCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage, and downloading from Azure Storage. The default value is automatically calculated for each file, based on its size, so that no blob needs more than 50,000 blocks. Decimal fractions are allowed (For example: 0.25). The maximum is 4000.")
 	cpCmd.PersistentFlags().StringVar(&raw.logVerbosity, "log-level", "INFO", "Define the log verbosity for the log file, available levels: INFO(all requests/responses), WARNING(slow responses), ERROR(only failed requests), and NONE(no output logs). (default 'INFO').")
+	cpCmd.PersistentFlags().StringVar(&raw.priority, "priority", "Normal", "Run the job at this priority: Low, Normal or High (default 'Normal'). "+
+		"While jobs of different priorities are running in the same AzCopy process, each gets a share of its workers: 6 in 10 for High, 3 for Normal and 1 for Low. "+
+		"Priorities only apply within one process. Jobs started by separate AzCopy commands each have their own workers, and don't slow each other down, whatever their priorities.")
+	cpCmd.PersistentFlags().StringVar(&raw.activeHours, "active-hours", "", "Only transfer data during these hours of each day, in local time. For example, 22:00-06:00 runs overnight. "+
+		"Outside them, the job waits without using the network, and carries on where it left off when they begin again. (By default the job can run at any time.)")
+	cpCmd.PersistentFlags().StringVar(&raw.afterJob, "after-job", "", "Wait for the job with this job ID to complete successfully before starting. "+
+		"If it fails, or is cancelled, this job is not started. Use it to queue up the stages of a migration, each in its own command prompt. "+
+		"While it waits, 'azcopy jobs list' shows this job as Queued, and further jobs can be queued after it.")
 	cpCmd.PersistentFlags().StringVar(&raw.blobType, "blob-type", "Detect", "Defines the type of blob at the destination. This is used for uploading blobs and when copying between accounts (default 'Detect'). Valid values include 'Detect', 'BlockBlob', 'PageBlob', and 'AppendBlob'. "+
 		"When copying between accounts, a value of 'Detect' causes AzCopy to use the type of source blob to determine the type of the destination blob. When uploading a file, 'Detect' determines if the file is a VHD or a VHDX file based on the file extension. If the file is ether a VHD or VHDX file, AzCopy treats the file as a page blob.")
-	cpCmd.PersistentFlags().StringVar(&raw.blockBlobTier, "block-blob-tier", "None", "upload block blob to Azure Storage using this blob tier.")
+	cpCmd.PersistentFlags().StringVar(&raw.blockBlobTier, "block-blob-tier", "None", "upload block blob to Azure Storage using this blob tier. "+
+		"When copying from service to service, this takes precedence over the source's tier, which is otherwise kept.")
+	cpCmd.PersistentFlags().StringVar(&raw.blockBlobTierRules, "block-blob-tier-rules", "", "Set the tier of the block blobs whose paths match patterns, e.g. '*.bak=Archive;logs/*=Cool'. "+
+		"A pattern without a / is matched against the name of each file, and one with a / against its path relative to the source. "+
+		"These take precedence over --block-blob-tier, and where several patterns match a blob, the last one wins.")
 	cpCmd.PersistentFlags().StringVar(&raw.pageBlobTier, "page-blob-tier", "None", "Upload page blob to Azure Storage using this blob tier. (default 'None').")
-	cpCmd.PersistentFlags().StringVar(&raw.metadata, "metadata", "", "Upload to Azure Storage with these key-value pairs as metadata.")
+	cpCmd.PersistentFlags().StringVar(&raw.metadata, "metadata", "", "Upload to Azure Storage with these key-value pairs as metadata, e.g. 'source=ingest;original={relpath}'. "+
+		"In the values, {filename} is replaced by the name of each file, {relpath} by its path relative to the source, and {jobid} by the ID of the job. "+
+		"In service to service copies, this metadata is combined with the source's, as --s2s-metadata-merge says.")
+	cpCmd.PersistentFlags().StringVar(&raw.blobTags, "blob-tags", "", "Set these index tags on the blobs, in the same request that creates them, e.g. 'project=alpha;env=prod'. "+
+		"A blob can have at most 10 tags. Only supported when the destination is Blob Storage. In service to service copies, these replace the tags copied by --s2s-preserve-blob-tags.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentType, "content-type", "", "Specifies the content type of the file. Implies no-guess-mime-type. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentEncoding, "content-encoding", "", "Set the content-encoding header. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentDisposition, "content-disposition", "", "Set the content-disposition header. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.contentLanguage, "content-language", "", "Set the content-language header. Returned on download.")
 	cpCmd.PersistentFlags().StringVar(&raw.cacheControl, "cache-control", "", "Set the cache-control header. Returned on download.")
 	cpCmd.PersistentFlags().BoolVar(&raw.noGuessMimeType, "no-guess-mime-type", false, "Prevents AzCopy from detecting the content-type based on the extension or content of the file.")
+	cpCmd.PersistentFlags().StringVar(&raw.contentTypeMap, "content-type-map", "", "Path of a JSON file that maps file extensions to content types, e.g. {\".md\": \"text/markdown\"}. "+
+		"When uploading, these take precedence over the content types that AzCopy knows of for the extensions.")
+	cpCmd.PersistentFlags().StringVar(&raw.headerRules, "header-rules", "", "Path of a JSON file of rules that set headers on the uploaded files whose paths match patterns, "+
+		"e.g. [{\"pattern\": \"*.html\", \"cacheControl\": \"no-cache\"}]. Each rule may set cacheControl, contentEncoding, contentLanguage and contentDisposition. "+
+		"A pattern without a / is matched against the name of each file, and one with a / against its path relative to the source. "+
+		"The rules take precedence over --cache-control, --content-encoding, --content-language and --content-disposition, and later rules over earlier ones.")
+	cpCmd.PersistentFlags().BoolVar(&raw.sniffContentType, "sniff-content-type", false, "When uploading a file whose extension doesn't give its content type, detect the type from the first 512 bytes of the file. "+
+		"Without this, such files get the content type text/plain.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveLastModifiedTime, "preserve-last-modified-time", false, "Only available when destination is file system.")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveSMBPermissions, "preserve-smb-permissions", false, "False by default. Preserves SMB ACLs between aware resources (Windows and Azure Files). For downloads, you will also need the --backup flag to restore permissions where the new Owner will not be the user running AzCopy. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern).")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
 	cpCmd.PersistentFlags().BoolVar(&raw.preserveSMBInfo, "preserve-smb-info", false, "False by default. Preserves SMB property info (last write time, creation time, attribute bits) between SMB-aware resources (Windows and Azure Files). Only the attribute bits supported by Azure Files will be transferred; any others will be ignored. This flag applies to both files and folders, unless a file-only filter is specified (e.g. include-pattern). The info transferred for folders is the same as that for files, except for Last Write Time which is never preserved for folders.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveInfo, "preserve-info", false, "False by default. Preserves the creation and last write times of files. Between SMB-aware resources (Windows and Azure Files), this is the same as --preserve-smb-info. "+
+		"When uploading to Blob Storage, the times are kept in the metadata '"+common.CreationTimeMetadataKey+"' and '"+common.LastWriteTimeMetadataKey+"' of each blob, and downloading with this flag puts them back (falling back to the last modified time of blobs without them). "+
+		"Creation times are only recorded and restored on Windows.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preserveXattrs, "preserve-xattrs", false, "False by default. Preserves the extended attributes of files between the local file system and Blob Storage, on Linux and macOS. "+
+		"When uploading, they are kept in the metadata '"+common.XattrsMetadataKey+"' of each blob, and downloading with this flag puts them back. On Linux, only attributes in the 'user' namespace are preserved. "+
+		"Attributes that don't fit in the 4 KiB set aside for them in the blob's metadata are left out, with a warning in the log.")
+	cpCmd.PersistentFlags().BoolVar(&raw.preservePOSIXPermissions, "preserve-posix-permissions", false, "False by default. Preserves the POSIX owner, group and permissions of files between the local file system and ADLS Gen2 accounts (i.e. with a hierarchical namespace). "+
+		"On upload, the local user ID, group ID and mode are set on each destination path. On download, the mode is restored, and so are the owner and group if they are numeric IDs, which usually needs AzCopy to run as root. Not supported on Windows.")
+	cpCmd.PersistentFlags().BoolVar(&raw.skipUnchanged, "skip-unchanged", false, "False by default. Skip files that already exist at the destination with the same size, and a last modified time that is no older than the source's. The destination is listed once before the copy starts, to find such files. This check happens before, and independently of, --overwrite.")
+	cpCmd.PersistentFlags().UintVar(&raw.skipUnchangedToleranceSeconds, "skip-unchanged-tolerance", defaultSkipUnchangedToleranceSeconds, "Only used with --skip-unchanged. The number of seconds by which the source's last modified time may be later than the destination's, while still being considered unchanged.")
+	cpCmd.PersistentFlags().BoolVar(&raw.mirror, "mirror", false, "Make the destination a mirror of the source, as sync --mirror does: skip the files that are the same at both ends, copy the others over the destination's, and delete the destination files that aren't at the source. "+
+		"A file differs if its size does, if both ends have an MD5 hash of it and they differ, or, when both ends are remote, if its properties or metadata do. "+
+		"Without MD5 hashes at both ends (local files have none), a file of the same size is only taken to have changed if the source was modified after the destination, so a change made at the destination that keeps the file's size, or one made to the source before the destination was last written, isn't detected. Use sync --mirror --compare-hash=md5 to compare the content in those cases. "+
+		"Must be used with --recursive. The destination is listed once before the copy starts, and a report of how the two ends differed is printed when they have been compared.")
+	cpCmd.PersistentFlags().StringVar(&raw.onDuplicateDestination, "on-duplicate-destination", common.EDuplicateDestinationPolicy.Skip().String(), "What to do when two source files would be copied to the same destination, e.g. because of overlapping include-path entries, or names that differ only in case being copied to a case-insensitive destination. Possible values are 'skip' (the default), which copies the first one found, 'fail', which stops the job, and 'lastWins', which copies the last one found. With 'lastWins', no transfers start until the whole source has been listed.")
+	cpCmd.PersistentFlags().StringVar(&raw.pathRewrites, "path-rewrite", "", "Rules of the form 'regex=>replacement' that change where each file goes at the destination. "+
+		"Each rule is applied to the path of each file relative to the source, which uses forward slashes, in the same way as Go's regexp.ReplaceAllString, so the replacement may refer to the regex's groups as $1, $2 etc. "+
+		"Separate rules with semicolons, in which case they are applied in order. For example, '^.*/=>' flattens directories, '^logs/=>' strips a prefix, and '\\.csv$=>.old.csv' adds a suffix to the names of .csv files. "+
+		"Files that end up with the same path are handled as set by --on-duplicate-destination. Files that end up with an empty path, or a path outside the destination, are skipped. Can't be used with --preserve-hardlinks.")
+	cpCmd.PersistentFlags().BoolVar(&raw.dryRun, "dry-run", false, "List the files that would be copied, and their total size, without copying anything. The source is listed, and filtered, exactly as it would be for the copy. "+
+		"Unless --overwrite is true, each file is also looked up at the destination, to show which would be skipped or overwritten.")
+	cpCmd.PersistentFlags().StringVar(&raw.planLocation, planLocationFlagName, common.EPlanLocation.Disk().String(), "Where to keep the job's plan: 'disk' (the default), in the plan folder, so that the job can be listed, shown and resumed later, or 'memory', so that nothing is written to the plan folder. A plan kept in memory goes when AzCopy exits, so the job can't be resumed. Useful for small jobs on read-only or diskless machines.")
 	cpCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When overwriting an existing file on Windows or Azure Files, force the overwrite to work even if the existing file has its read-only attribute set")
 	cpCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
 	cpCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
-	cpCmd.PersistentFlags().StringVar(&raw.md5ValidationOption, "check-md5", common.DefaultHashValidationOption.String(), "Specifies how strictly MD5 hashes should be validated when downloading. Only available when downloading. Available options: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'FailIfDifferent')")
-	cpCmd.PersistentFlags().StringVar(&raw.includeFileAttributes, "include-attributes", "", "(Windows only) Include files whose attributes match the attribute list. For example: A;S;R")
-	cpCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. For example: A;S;R")
+	cpCmd.PersistentFlags().StringVar(&raw.md5ValidationOption, "check-md5", common.DefaultHashValidationOption.String(), "Specifies how strictly MD5 hashes should be validated when downloading. Only available when downloading. Available options: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'FailIfDifferent') "+
+		"The files whose data doesn't match the stored hash are listed in the job summary, including those that are kept with LogOnly.")
+	cpCmd.PersistentFlags().BoolVar(&raw.putCrc64, "put-crc64", false, "Send a CRC64 checksum with each block, so that Blob Storage rejects any block that was corrupted on the way, and save the CRC64 of each whole file in the '"+common.Crc64MetadataKey+"' metadata of the blob (block blobs only). "+
+		"CRC64 is much faster to compute than MD5, so suits fast links where computing MD5 hashes would limit throughput. Only available when uploading to Blob Storage.")
+	cpCmd.PersistentFlags().StringVar(&raw.crc64ValidationOption, "check-crc64", common.EHashValidationOption.NoCheck().String(), "Specifies how strictly CRC64 checksums should be validated when downloading from Blob Storage. Available options: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'NoCheck') "+
+		"Unless it's NoCheck, Blob Storage is asked for the CRC64 of each range that's read, and the data is checked against it, and the CRC64 of each whole file is checked against the one saved by --put-crc64. "+
+		"Blob Storage only gives the CRC64 of ranges of up to 4 MiB, so the block size defaults to 4 MiB when this is set.")
+	cpCmd.PersistentFlags().BoolVar(&raw.putSha256, "put-sha256", false, "Compute the SHA-256 digest of each file, and save it in hex in the '"+common.Sha256MetadataKey+"' metadata of the blob (block blobs only), for compliance regimes that require SHA-256 rather than MD5. "+
+		"The digest is computed in the background, alongside the upload. Only available when uploading to Blob Storage.")
+	cpCmd.PersistentFlags().StringVar(&raw.sha256ValidationOption, "check-sha256", common.EHashValidationOption.NoCheck().String(), "Specifies how strictly SHA-256 digests should be validated when downloading from Blob Storage. Available options: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'NoCheck') "+
+		"Unless it's NoCheck, the SHA-256 of each downloaded file is checked against the one saved by --put-sha256.")
+	cpCmd.PersistentFlags().StringVar(&raw.includeFileAttributes, "include-attributes", "", "(Windows only) Include files whose attributes match the attribute list. The attributes may be given by name or by letter, and separated by ';' or ','. For example: A;S;R or archive,system,readonly")
+	cpCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. The attributes may be given by name or by letter, and separated by ';' or ','. For example: hidden,system,temporary, to leave out files such as thumbs.db and desktop.ini. "+
+		"Only the attributes of files are checked, not those of folders, so use --exclude-path to leave out folders such as $RECYCLE.BIN.")
 	cpCmd.PersistentFlags().BoolVar(&raw.CheckLength, "check-length", true, "Check the length of a file on the destination after the transfer. If there is a mismatch between source and destination, the transfer is marked as failed.")
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sPreserveProperties, "s2s-preserve-properties", true, "Preserve full properties during service to service copy. "+
 		"For AWS S3 and Azure File non-single file source, the list operation doesn't return full properties of objects and files. To preserve full properties, AzCopy needs to send one additional request per object or file.")
+	cpCmd.PersistentFlags().StringVar(&raw.s2sMetadataMerge, "s2s-metadata-merge", common.EMetadataMergeOption.Overwrite().String(), "How the metadata given with --metadata is combined with the source's metadata during service to service copy. "+
+		"Available options: Overwrite (the given values replace the source's for the same keys), KeepSource (the source's values are kept for the same keys), Replace (the source's metadata is dropped). (default 'Overwrite')")
+	cpCmd.PersistentFlags().StringVar(&raw.s2sCopyMethod, "s2s-copy-method", common.ES2SCopyMethod.Auto().String(), "How the data of block blobs is copied during service to service copy to Blob Storage. "+
+		"Available options: PutBlockFromURL (the destination's service reads each block from the source; fastest, and nothing passes through AzCopy), "+
+		"CopyBlob (the service copies each blob in the background, with the asynchronous Copy Blob, which takes less of AzCopy's connections but may be slower, and copies the source's properties as they are), "+
+		"DownloadUpload (AzCopy reads each block from the source and sends it on, which works when the destination's service can't reach the source, but costs AzCopy's bandwidth and egress from the source), "+
+		"Auto (PutBlockFromURL, falling back to DownloadUpload for blob sources that the service can't read). The method used for each file is logged. (default 'Auto')")
+	cpCmd.PersistentFlags().BoolVar(&raw.s2sPreserveBlobTags, "s2s-preserve-blob-tags", false, "Copy the index tags of each blob during service to service copy between Blob Storage accounts. "+
+		"This needs an extra request per blob, and permission to read the tags of the source blobs (the 't' permission of a SAS).")
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sPreserveAccessTier, "s2s-preserve-access-tier", true, "Preserve access tier during service to service copy. "+
 		"Please refer to [Azure Blob storage: hot, cool, and archive access tiers](https://docs.microsoft.com/azure/storage/blobs/storage-blob-storage-tiers) to ensure destination storage account supports setting access tier. "+
 		"In the cases that setting access tier is not supported, please use s2sPreserveAccessTier=false to bypass copying access tier. (default true). ")
 	cpCmd.PersistentFlags().BoolVar(&raw.s2sSourceChangeValidation, "s2s-detect-source-changed", false, "Detect if the source file/blob changes while it is being read. (This parameter only applies to service to service copies, because the corresponding check is permanently enabled for uploads and downloads.)")
 	cpCmd.PersistentFlags().StringVar(&raw.s2sInvalidMetadataHandleOption, "s2s-handle-invalid-metadata", common.DefaultInvalidMetadataHandleOption.String(), "Specifies how invalid metadata keys are handled. Available options: ExcludeIfInvalid, FailIfInvalid, RenameIfInvalid. (default 'ExcludeIfInvalid').")
+	cpCmd.PersistentFlags().BoolVar(&raw.includeSnapshots, "include-snapshots", false, "Copy the snapshots of the blobs as well as the blobs. "+
+		"Each snapshot is copied to a blob or file of its own, named after its blob with the time of the snapshot added before the extension (e.g. report.2021-03-04T05-06-07.1234567Z.txt). "+
+		"To copy a single snapshot instead, give its URL, with the snapshot parameter, as the source.")
+	cpCmd.PersistentFlags().BoolVar(&raw.includeVersions, "include-versions", false, "Copy every version of the blobs, oldest first, to the same blobs at the destination, "+
+		"so that an account with versioning enabled gets the same history of versions as the source (e.g. for disaster recovery). Only supported between Blob Storage accounts, with overwrite set to true. "+
+		"To copy a single version instead, give its URL, with the versionid parameter, as the source.")
+	cpCmd.PersistentFlags().BoolVar(&raw.includeDeleted, "include-deleted", false, "Copy the blobs that have been soft-deleted, as well as the others, to recover them after they were deleted by mistake. "+
+		"Soft-deleted blobs can't be read, so each one is undeleted at the source, along with its snapshots, before it's copied. "+
+		"The source must be an account, container or directory; to recover a single blob, give its directory with include-path or include-pattern.")
 	cpCmd.PersistentFlags().StringVar(&raw.listOfVersionIDs, "list-of-versions", "", "Specifies a file where each version id is listed on a separate line. Ensure that the source must point to a single blob and all the version ids specified in the file using this flag must belong to the source blob only. AzCopy will download the specified versions in the destination folder provided.")
 	// s2sGetPropertiesInBackend is an optional flag for controlling whether S3 object's or Azure file's full properties are get during enumerating in frontend or
 	// right before transferring in ste(backend).
@@ -0,0 +1,71 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// validateArchivedSource checks the options for source blobs in the archive tier. Without them, the transfers of
+// archived blobs fail, since the service won't read them until they have been rehydrated
+func validateArchivedSource(option common.ArchivedSourceOption, hasRehydratePriority bool, fromTo common.FromTo) error {
+	if option != common.EArchivedSourceOption.Fail() && fromTo.From() != common.ELocation.Blob() {
+		return errors.New("archived-source only applies when the source is Blob Storage, since only blobs can be archived")
+	}
+	if hasRehydratePriority && option != common.EArchivedSourceOption.Rehydrate() {
+		return errors.New("rehydrate-priority can only be used with --archived-source=Rehydrate")
+	}
+	return nil
+}
+
+// archivedBlobSkipper leaves archived blobs out of the job, when copy is asked to skip them. It's done as the source
+// is listed, since the listing already tells us each blob's tier
+type archivedBlobSkipper struct {
+	atomicSkippedCount uint32
+}
+
+// skipIfArchived returns true if the transfer should not be scheduled, counting and logging the skip if so
+func (s *archivedBlobSkipper) skipIfArchived(source storedObject, srcRelativePath string) bool {
+	if source.entityType != common.EEntityType.File() || source.blobAccessTier != azblob.AccessTierArchive {
+		return false
+	}
+
+	atomic.AddUint32(&s.atomicSkippedCount, 1)
+	if ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(fmt.Sprintf("Skipping %s because it is in the archive tier", srcRelativePath), pipeline.LogWarning)
+	}
+	return true
+}
+
+func (s *archivedBlobSkipper) skippedCount() uint32 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadUint32(&s.atomicSkippedCount)
+}
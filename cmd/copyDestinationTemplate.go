@@ -0,0 +1,47 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// expandDestinationTemplate replaces the date, time and job ID tokens in the destination, e.g. so that each run
+// of a nightly backup lands in a directory of its own. The date and time are those of the start of the job, in
+// local time. Anything else in braces is left as it is
+func expandDestinationTemplate(destination string, jobID common.JobID, start time.Time) string {
+	if !strings.Contains(destination, "{") {
+		return destination
+	}
+
+	return strings.NewReplacer(
+		"{yyyy}", start.Format("2006"),
+		"{MM}", start.Format("01"),
+		"{dd}", start.Format("02"),
+		"{HH}", start.Format("15"),
+		"{mm}", start.Format("04"),
+		"{ss}", start.Format("05"),
+		"{jobid}", jobID.String(),
+	).Replace(destination)
+}
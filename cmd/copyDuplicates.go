@@ -0,0 +1,132 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// duplicateDestinationDetector notices when the source listing yields two files with the same destination, e.g.
+// because of overlapping --include-path entries, or because names that differ only in case are being copied to a
+// case-insensitive destination. Left alone, the two transfers would race to write the one destination.
+type duplicateDestinationDetector struct {
+	policy          common.DuplicateDestinationPolicy
+	caseInsensitive bool
+
+	// otherwise, each file in the source listing has a destination of its own, so there's nothing to track
+	duplicatesPossible bool
+
+	// the source of the file that is being copied to each destination
+	sourcesByDestination map[string]string
+
+	// with LastWins, a later file replaces an earlier one, so nothing can be scheduled until the whole source has
+	// been listed. Until then, the transfers are held here
+	held               []common.CopyTransfer
+	heldIndexByDestKey map[string]int
+
+	warnOnce sync.Once
+}
+
+// newDuplicateDestinationDetector is told whether the source listing may yield the same file more than once, as it may
+// when the files are listed by the user (with --include-path or --list-of-files)
+func newDuplicateDestinationDetector(policy common.DuplicateDestinationPolicy, caseInsensitive bool, overlappingSources bool) *duplicateDestinationDetector {
+	return &duplicateDestinationDetector{
+		policy:               policy,
+		caseInsensitive:      caseInsensitive,
+		duplicatesPossible:   caseInsensitive || overlappingSources,
+		sourcesByDestination: make(map[string]string),
+		heldIndexByDestKey:   make(map[string]int),
+	}
+}
+
+func (d *duplicateDestinationDetector) key(destination string) string {
+	if d.caseInsensitive {
+		return strings.ToLower(destination) // ToLower is enough here, for the same reasons as in ExclusiveStringMap
+	}
+	return destination
+}
+
+// add passes the transfer on to schedule, unless it's a duplicate that the policy says to leave out.
+// With LastWins, it holds the transfer back instead, until flush is called
+func (d *duplicateDestinationDetector) add(transfer common.CopyTransfer, schedule func(common.CopyTransfer) error) error {
+	if transfer.EntityType == common.EEntityType.Folder() {
+		return schedule(transfer) // properties of the same folder may be sent twice without harm
+	}
+	if !d.duplicatesPossible {
+		return schedule(transfer)
+	}
+
+	key := d.key(transfer.Destination)
+
+	if d.policy == common.EDuplicateDestinationPolicy.LastWins() {
+		if i, ok := d.heldIndexByDestKey[key]; ok {
+			d.logDuplicate(fmt.Sprintf("%s will be copied to %s, instead of %s", transfer.Source, transfer.Destination, d.held[i].Source))
+			d.held[i] = transfer
+			return nil
+		}
+		d.heldIndexByDestKey[key] = len(d.held)
+		d.held = append(d.held, transfer)
+		return nil
+	}
+
+	earlierSource, ok := d.sourcesByDestination[key]
+	if !ok {
+		d.sourcesByDestination[key] = transfer.Source
+		return schedule(transfer)
+	}
+
+	if d.policy == common.EDuplicateDestinationPolicy.Fail() {
+		return fmt.Errorf("both %s and %s would be copied to %s. Use --on-duplicate-destination to choose which is copied",
+			earlierSource, transfer.Source, transfer.Destination)
+	}
+	d.logDuplicate(fmt.Sprintf("Skipping %s, because %s is already being copied to %s", transfer.Source, earlierSource, transfer.Destination))
+	return nil
+}
+
+// flush schedules the transfers that were held back
+func (d *duplicateDestinationDetector) flush(schedule func(common.CopyTransfer) error) error {
+	held := d.held
+	d.held = nil
+	d.heldIndexByDestKey = make(map[string]int)
+	for _, transfer := range held {
+		if err := schedule(transfer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *duplicateDestinationDetector) logDuplicate(msg string) {
+	d.warnOnce.Do(func() {
+		glcm.Info("Some files in the source have the same destination as others, so only one of each will be copied. " +
+			"The log lists them.")
+	})
+	if ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(msg, pipeline.LogWarning)
+	}
+}
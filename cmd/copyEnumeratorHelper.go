@@ -22,7 +22,11 @@ func addTransfer(e *common.CopyJobPartOrderRequest, transfer common.CopyTransfer
 	// dispatch the transfers once the number reaches NumOfFilesPerDispatchJobPart
 	// we do this so that in the case of large transfer, the transfer engine can get started
 	// while the frontend is still gathering more transfers
-	if len(e.Transfers) == NumOfFilesPerDispatchJobPart {
+	if len(e.Transfers) == NumOfFilesPerDispatchJobPart && cca.dryRunPrinter != nil {
+		cca.dryRunPrinter.printPart(e)
+		e.Transfers = []common.CopyTransfer{}
+		e.PartNum++
+	} else if len(e.Transfers) == NumOfFilesPerDispatchJobPart {
 		shuffleTransfers(e.Transfers)
 		resp := common.CopyJobPartOrderResponse{}
 
@@ -56,14 +60,22 @@ func shuffleTransfers(transfers []common.CopyTransfer) {
 // we need to send a last part with isFinalPart set to true, along with whatever transfers that still haven't been sent
 // dispatchFinalPart sends a last part with isFinalPart set to true, along with whatever transfers that still haven't been sent.
 func dispatchFinalPart(e *common.CopyJobPartOrderRequest, cca *cookedCopyCmdArgs) error {
-	shuffleTransfers(e.Transfers)
 	e.IsFinalPart = true
+	if cca.dryRunPrinter != nil {
+		if resp := cca.dryRunPrinter.printPart(e); !resp.JobStarted {
+			return NothingScheduledError
+		}
+		cca.isEnumerationComplete = true
+		return nil
+	}
+
+	shuffleTransfers(e.Transfers)
 	var resp common.CopyJobPartOrderResponse
 	Rpc(common.ERpcCmd.CopyJobPartOrder(), (*common.CopyJobPartOrderRequest)(e), &resp)
 
 	if !resp.JobStarted {
 		// Output the log location and such
-		glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID), cca.isCleanupJob, cca.cleanupJobMessage))
+		glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), jobLogFilePath(cca.jobID), cca.isCleanupJob, cca.cleanupJobMessage))
 
 		if resp.ErrorMsg == common.ECopyJobPartOrderErrorType.NoTransfersScheduledErr() {
 			return NothingScheduledError
@@ -40,25 +40,50 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 
 	jobPartOrder.PreserveSMBPermissions = cca.preserveSMBPermissions
 	jobPartOrder.PreserveSMBInfo = cca.preserveSMBInfo
+	jobPartOrder.PreservePOSIXPermissions = cca.preservePOSIXPermissions
+	jobPartOrder.PreserveFileTimes = cca.preserveFileTimes
+	jobPartOrder.PreserveXattrs = cca.preserveXattrs
 
 	// Infer on download so that we get LMT and MD5 on files download
 	// On S2S transfers the following rules apply:
 	// If preserve properties is enabled, but get properties in backend is disabled, turn it on
 	// If source change validation is enabled on files to remote, turn it on (consider a separate flag entirely?)
 	getRemoteProperties := cca.forceWrite == common.EOverwriteOption.IfSourceNewer() ||
+		(cca.fromTo.From() == common.ELocation.File() && (cca.skipUnchanged || cca.mirror)) || // skip-unchanged and mirror need the source LMTs
 		(cca.fromTo.From() == common.ELocation.File() && !cca.fromTo.To().IsRemote()) || // If download, we still need LMT and MD5 from files.
-		(cca.fromTo.From() == common.ELocation.File() && cca.fromTo.To().IsRemote() && (cca.s2sSourceChangeValidation || cca.includeAfter != nil)) || // If S2S from File to *, and sourceChangeValidation is enabled, we get properties so that we have LMTs. Likewise if we are using includeAfter, which requires LMTs.
+		(cca.fromTo.From() == common.ELocation.File() && cca.fromTo.To().IsRemote() && (cca.s2sSourceChangeValidation || cca.includeAfter != nil || cca.includeBefore != nil)) || // If S2S from File to *, and sourceChangeValidation is enabled, we get properties so that we have LMTs. Likewise if we are using includeAfter or includeBefore, which require LMTs.
 		(cca.fromTo.From().IsRemote() && cca.fromTo.To().IsRemote() && cca.s2sPreserveProperties && !cca.s2sGetPropertiesInBackend) // If S2S and preserve properties AND get properties in backend is on, turn this off, as properties will be obtained in the backend.
 	jobPartOrder.S2SGetPropertiesInBackend = cca.s2sPreserveProperties && !getRemoteProperties && cca.s2sGetPropertiesInBackend // Infer GetProperties if GetPropertiesInBackend is enabled.
 	jobPartOrder.S2SSourceChangeValidation = cca.s2sSourceChangeValidation
 	jobPartOrder.DestLengthValidation = cca.CheckLength
 	jobPartOrder.S2SInvalidMetadataHandleOption = cca.s2sInvalidMetadataHandleOption
+	jobPartOrder.S2SPreserveBlobTags = cca.s2sPreserveBlobTags
+	jobPartOrder.S2SMetadataMerge = cca.s2sMetadataMerge
+	jobPartOrder.S2SCopyMethod = cca.s2sCopyMethod
+	jobPartOrder.ArchivedSource = cca.archivedSource
+	jobPartOrder.RehydratePriority = cca.rehydratePriority
+	jobPartOrder.IncludeVersions = cca.includeVersions
 
-	traverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, &cca.followSymlinks, cca.listOfFilesChannel, cca.recursive, getRemoteProperties, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs)
+	traverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, cca.symlinkHandling, cca.listOfFilesChannel, cca.recursive, getRemoteProperties, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs)
 
 	if err != nil {
 		return nil, err
 	}
+	if cca.includeSnapshots {
+		if err = enableSnapshotListing(traverser); err != nil {
+			return nil, err
+		}
+	}
+	if cca.includeVersions {
+		if err = enableVersionListing(traverser); err != nil {
+			return nil, err
+		}
+	}
+	if cca.includeDeleted {
+		if err = enableDeletedListing(traverser); err != nil {
+			return nil, err
+		}
+	}
 
 	// Ensure we're only copying from a directory with a trailing wildcard or recursive.
 	isSourceDir := traverser.isDirectory(true)
@@ -71,6 +96,9 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 	if cca.listOfVersionIDs != nil && (!(cca.fromTo == common.EFromTo.BlobLocal() || cca.fromTo == common.EFromTo.BlobTrash()) || isSourceDir || !isDestDir) {
 		log.Fatalf("Either source is not a blob or destination is not a local folder")
 	}
+	if cca.includeSnapshots && !isSourceDir && !isDestDir {
+		return nil, errors.New("the destination must be a directory or container when include-snapshots is used with a single blob, since each snapshot is copied to a file of its own")
+	}
 	srcLevel, err := determineLocationLevel(cca.source.Value, cca.fromTo.From(), true)
 
 	if err != nil {
@@ -105,6 +133,11 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 	// Create a S3 bucket resolver
 	// Giving it nothing to work with as new names will be added as we traverse.
 	var containerResolver = NewS3BucketNameToAzureResourcesResolver(nil)
+	if cca.dryRunPrinter != nil && cca.forceWrite != common.EOverwriteOption.True() {
+		cca.dryRunPrinter.overwrite = cca.forceWrite
+		cca.dryRunPrinter.findDestination = cca.dryRunDestinationFinder(ctx)
+	}
+
 	existingContainers := make(map[string]bool)
 	var logDstContainerCreateFailureOnce sync.Once
 	seenFailedContainers := make(map[string]bool) // Create map of already failed container conversions so we don't log a million items just for one container.
@@ -189,6 +222,25 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		}
 	}
 
+	if cca.mirror && srcLevel == ELocationLevel.Service() {
+		return nil, errors.New("mirror is not supported when the source is an entire account. Specify a container or directory instead")
+	}
+	if cca.skipUnchanged || cca.mirror {
+		if cca.unchangedFileSkipper, err = cca.initUnchangedFileSkipper(ctx, dstLevel); err != nil {
+			return nil, err
+		}
+	}
+	if cca.archivedSource == common.EArchivedSourceOption.Skip() {
+		cca.archivedBlobSkipper = &archivedBlobSkipper{}
+	}
+	if cca.preserveHardlinks {
+		cca.hardlinks = newHardlinkPreserver()
+	}
+	var packer *directoryPacker
+	if cca.pack != common.EPackFormat.None() {
+		packer = newDirectoryPacker(cca.pack)
+	}
+
 	filters := cca.initModularFilters()
 
 	// decide our folder transfer strategy
@@ -199,7 +251,18 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		ste.JobsAdmin.LogToJobLog(message, pipeline.LogInfo)
 	}
 
+	duplicates := newDuplicateDestinationDetector(cca.duplicateDestinationPolicy, common.IsCaseInsensitiveDestination(cca.fromTo, runtime.GOOS),
+		cca.listOfFilesChannel != nil || cca.listOfVersionIDs != nil)
+	schedule := func(transfer common.CopyTransfer) error {
+		return addTransfer(&jobPartOrder, transfer, cca)
+	}
+
 	processor := func(object storedObject) error {
+		// with --pack, the files aren't scheduled one by one. Each directory's are uploaded as one archive instead
+		if packer != nil {
+			return packer.add(object)
+		}
+
 		// Start by resolving the name and creating the container
 		if object.containerName != "" {
 			// set up the destination container name.
@@ -233,19 +296,68 @@ func (cca *cookedCopyCmdArgs) initEnumerator(jobPartOrder common.CopyJobPartOrde
 		srcRelPath := cca.makeEscapedRelativePath(true, isDestDir, object)
 		dstRelPath := cca.makeEscapedRelativePath(false, isDestDir, object)
 
+		// a file whose whole path is removed by the --path-rewrite rules has nowhere to go
+		if len(cca.pathRewrites) > 0 && object.entityType != common.EEntityType.Folder() && strings.HasSuffix(dstRelPath, "/") {
+			WarnStdoutAndJobLog(fmt.Sprintf("Skipping %s, as the path-rewrite rules leave it with an empty name, or a path outside the destination", srcRelPath))
+			return nil
+		}
+
+		// this runs before the transfer is scheduled, and so before the STE applies the overwrite option
+		if cca.unchangedFileSkipper != nil && cca.unchangedFileSkipper.skipIfUnchanged(object, cca.skipUnchangedLookupKey(dstRelPath)) {
+			if cca.dryRunPrinter != nil {
+				cca.dryRunPrinter.printUnchanged(joinDryRunPath(cca.source.Value, srcRelPath), joinDryRunPath(cca.destination.Value, dstRelPath))
+			}
+			return nil
+		}
+
+		if cca.archivedBlobSkipper != nil && cca.archivedBlobSkipper.skipIfArchived(object, srcRelPath) {
+			return nil
+		}
+
+		if cca.hardlinks != nil {
+			if cca.fromTo.IsUpload() {
+				cca.hardlinks.tagUploadedLink(&object, cca.source.ValueLocal())
+			} else if cca.hardlinks.recordDownloadedLink(object, cca.destination.ValueLocal(), dstRelPath) {
+				return nil // made after the job, once the file with the content is there
+			}
+		}
+
+		// blobs that hold preserved symlinks are turned back into links
+		if cca.symlinkHandling == common.ESymlinkHandlingType.Preserve() && cca.fromTo.IsDownload() &&
+			object.entityType == common.EEntityType.File() && object.Metadata[common.SymlinkMetadataKey] == "true" {
+			object.entityType = common.EEntityType.Symlink()
+		}
+
 		transfer, shouldSendToSte := object.ToNewCopyTransfer(
 			cca.autoDecompress && cca.fromTo.IsDownload(),
 			srcRelPath, dstRelPath,
-			cca.s2sPreserveAccessTier,
+			// when downloading, the source's tier is only kept so that the STE knows which blobs to rehydrate
+			cca.s2sPreserveAccessTier || cca.fromTo.IsDownload() && cca.archivedSource == common.EArchivedSourceOption.Rehydrate(),
 			jobPartOrder.Fpo,
 		)
 
 		if shouldSendToSte {
-			return addTransfer(&jobPartOrder, transfer, cca)
+			return duplicates.add(transfer, schedule)
 		}
 		return nil
 	}
 	finalizer := func() error {
+		if packer != nil {
+			if err := packer.schedule(cca, isDestDir, func(transfer common.CopyTransfer) error {
+				return duplicates.add(transfer, schedule)
+			}); err != nil {
+				return err
+			}
+		}
+		if err := duplicates.flush(schedule); err != nil {
+			return err
+		}
+		// as in sync, the deletions happen before the final part is dispatched, so that they're done before the job ends
+		if cca.mirror {
+			if err := cca.deleteExtraFiles(ctx, filters); err != nil {
+				return err
+			}
+		}
 		return dispatchFinalPart(&jobPartOrder, cca)
 	}
 
@@ -266,7 +378,7 @@ func (cca *cookedCopyCmdArgs) isDestDirectory(dst common.ResourceString, ctx *co
 		return false
 	}
 
-	rt, err := initResourceTraverser(dst, cca.fromTo.To(), ctx, &dstCredInfo, nil, nil, false, false, false, func(common.EntityType) {}, cca.listOfVersionIDs)
+	rt, err := initResourceTraverser(dst, cca.fromTo.To(), ctx, &dstCredInfo, common.ESymlinkHandlingType.Skip(), nil, false, false, false, func(common.EntityType) {}, cca.listOfVersionIDs)
 
 	if err != nil {
 		return false
@@ -279,9 +391,9 @@ func (cca *cookedCopyCmdArgs) isDestDirectory(dst common.ResourceString, ctx *co
 func (cca *cookedCopyCmdArgs) initModularFilters() []objectFilter {
 	filters := make([]objectFilter, 0) // same as []objectFilter{} under the hood
 
-	if cca.includeAfter != nil {
-		filters = append(filters, &includeAfterDateFilter{threshold: *cca.includeAfter})
-	}
+	filters = append(filters, buildDateFilters(cca.includeAfter, cca.includeBefore)...)
+
+	filters = append(filters, buildFileSizeFilters(cca.minSize, cca.maxSize)...)
 
 	if len(cca.includePatterns) != 0 {
 		filters = append(filters, &includeFilter{patterns: cca.includePatterns}) // TODO should this call buildIncludeFilters?
@@ -312,6 +424,10 @@ func (cca *cookedCopyCmdArgs) initModularFilters() []objectFilter {
 		filters = append(filters, &excludeBlobTypeFilter{blobTypes: excludeSet})
 	}
 
+	filters = append(filters, buildIncludeBlobTierFilters(cca.includeBlobTiers)...)
+	filters = append(filters, buildBlobTagsFilters(cca.includeBlobTags)...)
+	filters = append(filters, buildMetadataFilters(cca.includeMetadata)...)
+
 	if len(cca.includeFileAttributes) != 0 {
 		filters = append(filters, buildAttrFilters(cca.includeFileAttributes, cca.source.ValueLocal(), true)...)
 	}
@@ -336,6 +452,11 @@ func (cca *cookedCopyCmdArgs) createDstContainer(containerName string, dstWithSA
 	}
 	existingContainers[containerName] = true
 
+	if cca.dryRunPrinter != nil {
+		cca.dryRunPrinter.printContainerCreation(containerName)
+		return nil
+	}
+
 	dstCredInfo := common.CredentialInfo{}
 
 	if dstCredInfo, _, err = getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false); err != nil {
@@ -505,11 +626,12 @@ func (cca *cookedCopyCmdArgs) makeEscapedRelativePath(source bool, dstIsDir bool
 				// Our source points to a specific file (and so has no relative path)
 				// but our dest does not point to a specific file, it just points to a directory,
 				// and so relativePath needs the _name_ of the source.
+				// each version listed by --include-versions goes to the same blob, rather than to one of its own
 				processedVID := ""
-				if len(object.blobVersionID) > 0 {
+				if len(object.blobVersionID) > 0 && !cca.includeVersions {
 					processedVID = strings.ReplaceAll(object.blobVersionID, ":", "-") + "-"
 				}
-				relativePath += "/" + processedVID + object.name
+				relativePath += "/" + processedVID + cca.pathRewrites.rewrite(object.name)
 			} else {
 				relativePath = ""
 			}
@@ -523,7 +645,11 @@ func (cca *cookedCopyCmdArgs) makeEscapedRelativePath(source bool, dstIsDir bool
 	if object.isSourceRootFolder() {
 		relativePath = "" // otherwise we get "/" from the line below, and that breaks some clients, e.g. blobFS
 	} else {
-		relativePath = "/" + strings.Replace(object.relativePath, common.OS_PATH_SEPARATOR, common.AZCOPY_PATH_SEPARATOR_STRING, -1)
+		relativePath = strings.Replace(object.relativePath, common.OS_PATH_SEPARATOR, common.AZCOPY_PATH_SEPARATOR_STRING, -1)
+		if !source {
+			relativePath = cca.pathRewrites.rewrite(relativePath)
+		}
+		relativePath = "/" + relativePath
 	}
 
 	if common.IffString(source, object.containerName, object.dstContainerName) != "" {
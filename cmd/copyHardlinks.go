@@ -0,0 +1,195 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// hardlinkFileID identifies a file, rather than a name for it, so that all the hard links to one file have the same ID
+type hardlinkFileID struct {
+	device uint64
+	inode  uint64
+}
+
+// hardlinkPreserver lets copy keep hard links as links. On upload, only the first link found to each file carries
+// its content. The other links become empty blobs, whose metadata says where that first one is, relative to them.
+// On download, those blobs aren't transferred at all. Instead, once the job is over, they are re-created as links
+// to the file that was downloaded with the content.
+// As with followups, the links to re-create are known only to this process, so a resumed job won't make them.
+type hardlinkPreserver struct {
+	mu sync.Mutex
+
+	// the relative path of the first link found to each file, which is uploaded with the content
+	primaries map[hardlinkFileID]string
+
+	// the links to make when the download is over: full local path of the link -> full local path of the file it links to
+	linksToCreate map[string]string
+	// the links whose targets are outside the destination, which aren't made: full local path of the link -> its target
+	unsafeLinks map[string]string
+	createOnce  sync.Once
+}
+
+var errUnsafeHardlinkTarget = errors.New("the link's target would be outside the destination")
+
+func newHardlinkPreserver() *hardlinkPreserver {
+	return &hardlinkPreserver{primaries: map[hardlinkFileID]string{}, linksToCreate: map[string]string{}, unsafeLinks: map[string]string{}}
+}
+
+// tagUploadedLink checks whether the file is another link to one that was already found. If so, the object is
+// turned into an empty one, whose metadata records the relative path from it to the link that carries the content
+func (h *hardlinkPreserver) tagUploadedLink(object *storedObject, sourceRoot string) {
+	if object.entityType != common.EEntityType.File() || object.relativePath == "" {
+		return // a single file can't be a link to anything else in the upload
+	}
+
+	fileInfo, err := os.Lstat(common.GenerateFullPath(sourceRoot, object.relativePath))
+	if err != nil {
+		return // the upload will report the problem with the file
+	}
+	id, isLinked := getHardlinkFileID(fileInfo)
+	if !isLinked {
+		return
+	}
+
+	h.mu.Lock()
+	primary, seen := h.primaries[id]
+	if !seen {
+		h.primaries[id] = object.relativePath
+	}
+	h.mu.Unlock()
+	if !seen {
+		return
+	}
+
+	target := relativeLinkTarget(path.Dir(object.relativePath), primary)
+	metadata := common.Metadata{common.HardlinkMetadataKey: url.PathEscape(target)} // escaped, since metadata is sent as headers
+	for k, v := range object.Metadata {
+		metadata[k] = v
+	}
+	object.Metadata = metadata
+	object.size = 0
+}
+
+// recordDownloadedLink returns true if the blob holds a hard link, which is then re-created after the job,
+// instead of being downloaded. The link's target comes from the blob's metadata, so it's only made if the target
+// is inside the destination root. Otherwise, the link is counted as failed
+func (h *hardlinkPreserver) recordDownloadedLink(object storedObject, destinationRoot string, relativePath string) bool {
+	escapedTarget, ok := object.Metadata[common.HardlinkMetadataKey]
+	if !ok || object.entityType != common.EEntityType.File() {
+		return false
+	}
+	target, err := url.PathUnescape(escapedTarget)
+	if err != nil {
+		return false // not one of ours, so just download it
+	}
+
+	localPath := common.GenerateFullPath(destinationRoot, relativePath)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if resolved, ok := resolveLinkTarget(relativePath, target); ok {
+		h.linksToCreate[localPath] = filepath.Join(destinationRoot, resolved)
+	} else {
+		h.unsafeLinks[localPath] = target
+	}
+	return true
+}
+
+// resolveLinkTarget returns the target, which is relative to the link, as a path relative to the root, or false if it's
+// absolute, or outside the root
+func resolveLinkTarget(linkRelativePath, target string) (string, bool) {
+	target = filepath.FromSlash(target)
+	if target == "" || filepath.IsAbs(target) || filepath.VolumeName(target) != "" || strings.HasPrefix(target, string(filepath.Separator)) {
+		return "", false
+	}
+	resolved := filepath.Clean(filepath.Join(filepath.Dir(filepath.FromSlash(linkRelativePath)), target))
+	if resolved == "." || resolved == ".." || strings.HasPrefix(resolved, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return resolved, true
+}
+
+// createLinks makes the hard links that were left out of the download, and returns how many of them couldn't be made.
+// Links are only made to files that are there now, so when the file with the content failed to download, its links fail too
+func (h *hardlinkPreserver) createLinks(overwrite common.OverwriteOption) (failed int) {
+	h.createOnce.Do(func() {
+		for link, target := range h.unsafeLinks {
+			failed++
+			msg := fmt.Sprintf("Failed to create the hard link %s to %s: %s", link, target, errUnsafeHardlinkTarget)
+			glcm.Info(msg)
+			if ste.JobsAdmin != nil {
+				ste.JobsAdmin.LogToJobLog(msg, pipeline.LogError)
+			}
+		}
+		for link, target := range h.linksToCreate {
+			if err := createHardlink(link, target, overwrite); err != nil {
+				failed++
+				glcm.Info(fmt.Sprintf("Failed to create the hard link %s to %s: %s", link, target, err))
+				if ste.JobsAdmin != nil {
+					ste.JobsAdmin.LogToJobLog(fmt.Sprintf("Failed to create the hard link %s to %s: %s", link, target, err), pipeline.LogError)
+				}
+			}
+		}
+	})
+	return failed
+}
+
+func createHardlink(link, target string, overwrite common.OverwriteOption) error {
+	if _, err := os.Lstat(link); err == nil {
+		if overwrite != common.EOverwriteOption.True() {
+			return nil // like any file that's already there, and isn't to be overwritten
+		}
+		if err = os.Remove(link); err != nil {
+			return err
+		}
+	}
+	if err := common.CreateParentDirectoryIfNotExist(link, common.NewFolderCreationTracker(common.EFolderPropertiesOption.NoFolders())); err != nil {
+		return err
+	}
+	return os.Link(target, link)
+}
+
+// relativeLinkTarget returns the path to target, relative to the directory fromDir. Both are relative paths separated by /
+func relativeLinkTarget(fromDir, target string) string {
+	if fromDir == "." {
+		return target
+	}
+	fromParts := strings.Split(fromDir, "/")
+	targetParts := strings.Split(target, "/")
+
+	shared := 0
+	for shared < len(fromParts) && shared < len(targetParts)-1 && fromParts[shared] == targetParts[shared] {
+		shared++
+	}
+	return strings.Repeat("../", len(fromParts)-shared) + strings.Join(targetParts[shared:], "/")
+}
@@ -0,0 +1,148 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// With --mirror, copy makes the destination a mirror of the source, as sync --mirror does. It's built on the listing of
+// the destination made for --skip-unchanged: the files that are the same at both ends are skipped, the others are copied
+// over the destination's, and the destination files that no source file was copied to are deleted at the end
+
+// validateMirror checks that --mirror can be used. Extra files can only be deleted from the locations sync deletes from,
+// and since every destination file the copy doesn't reach is deleted, the source can't be narrowed down by anything
+// other than the filters, which are applied to the destination too
+func (cca *cookedCopyCmdArgs) validateMirror(hasPathList bool) error {
+	if !cca.mirror {
+		return nil
+	}
+	switch cca.fromTo.To() {
+	case common.ELocation.Local(), common.ELocation.Blob(), common.ELocation.File():
+	default:
+		return errors.New("mirror is only supported when the destination is the local file system, Blob Storage or Azure Files")
+	}
+	if cca.destination.Value == common.Dev_Null {
+		return errors.New("mirror requires a destination that can be listed")
+	}
+	if !cca.recursive {
+		return errors.New("mirror makes a directory or container a mirror of another, so it must be used with --recursive")
+	}
+	if cca.skipUnchanged {
+		return errors.New("mirror and skip-unchanged can't be used together, since a mirror already skips the files that are the same at both ends")
+	}
+	if cca.forceWrite != common.EOverwriteOption.True() {
+		return errors.New("mirror replaces every destination file that differs from the source, so it can't be used with any overwrite setting other than true")
+	}
+	if hasPathList {
+		return errors.New("mirror can't be used with list-of-files or include-path, since the destination files that aren't listed would be deleted")
+	}
+	if cca.includeAfter != nil || cca.includeBefore != nil {
+		return fmt.Errorf("mirror can't be used with %s or %s, since the destination files that weren't changed in the given time would be deleted",
+			common.IncludeAfterFlagName, common.IncludeBeforeFlagName)
+	}
+	if cca.pack != common.EPackFormat.None() || cca.appendToBlob {
+		return errors.New("mirror can't be used with pack or append, since neither makes each destination file a copy of its source file")
+	}
+	if cca.fromTo.From() == common.ELocation.Local() {
+		source := strings.TrimSuffix(strings.TrimSuffix(cca.source.ValueLocal(), "/*"), `\*`)
+		if strings.Contains(source, "*") {
+			return errors.New("mirror can't be used with wildcards in the source, other than a trailing /*, since the destination files the wildcards don't match would be deleted")
+		}
+	}
+	return nil
+}
+
+// mirrorRoot is the directory of the destination that the source's contents are copied to, relative to the destination,
+// with a trailing /. Only the files under it are the mirror's, so extra files are only deleted from there
+func (cca *cookedCopyCmdArgs) mirrorRoot() string {
+	if cca.stripTopDir {
+		return ""
+	}
+	root := cca.makeEscapedRelativePath(false, true, storedObject{entityType: common.EEntityType.Folder()})
+	return cca.skipUnchangedLookupKey(root) + common.AZCOPY_PATH_SEPARATOR_STRING
+}
+
+// deleteExtraFiles deletes the destination files under the mirror root that no source file was copied to, and reports
+// how the two ends differed. The filters are applied to them as they were to the source files, with their paths relative
+// to the root, so that the files the filters leave out of the copy are kept
+func (cca *cookedCopyCmdArgs) deleteExtraFiles(ctx context.Context, filters []objectFilter) error {
+	root := cca.mirrorRoot()
+	extraFiles := make([]storedObject, 0)
+	for relativePath, object := range cca.unchangedFileSkipper.destinationIndex.indexMap {
+		if object.entityType != common.EEntityType.File() || !strings.HasPrefix(relativePath, root) {
+			continue
+		}
+		underRoot := object
+		underRoot.relativePath = strings.TrimPrefix(relativePath, root)
+		if passedFilters(filters, underRoot) {
+			extraFiles = append(extraFiles, object)
+		}
+	}
+	sort.Slice(extraFiles, func(i, j int) bool { return extraFiles[i].relativePath < extraFiles[j].relativePath })
+
+	glcm.Info(cca.unchangedFileSkipper.mirror.report(uint64(len(extraFiles))))
+	if len(extraFiles) == 0 {
+		return nil
+	}
+
+	deleter, err := cca.newExtraFileDeleter(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete the extra files at the destination: %s", err)
+	}
+	for _, object := range extraFiles {
+		if err = deleter(object); err != nil {
+			WarnStdoutAndJobLog(fmt.Sprintf("Failed to delete the extra file %s: %s", object.relativePath, err))
+		}
+	}
+	return nil
+}
+
+// newExtraFileDeleter returns the deleter that sync uses for the destination. In a dry run, the files are listed instead
+func (cca *cookedCopyCmdArgs) newExtraFileDeleter(ctx context.Context) (objectProcessor, error) {
+	if cca.dryRunPrinter != nil {
+		return cca.dryRunPrinter.printDeletion, nil
+	}
+	if cca.fromTo.To() == common.ELocation.Local() {
+		deleter := localFileDeleter{rootPath: cca.destination.ValueLocal()}
+		return deleter.deleteFile, nil
+	}
+
+	rawURL, err := cca.destination.FullURL()
+	if err != nil {
+		return nil, err
+	}
+	dstCredInfo, _, err := getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false)
+	if err != nil {
+		return nil, err
+	}
+	p, err := initPipeline(ctx, cca.fromTo.To(), dstCredInfo)
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteResourceDeleter(rawURL, p, ctx, cca.fromTo.To()).delete, nil
+}
@@ -0,0 +1,130 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// directoryPacker collects the files listed for an upload with --pack by directory, so that the files of each
+// directory can be uploaded as one archive, rather than one by one. The STE makes each archive, from the files that
+// are in the directory when its transfer starts
+type directoryPacker struct {
+	format common.PackFormat
+
+	// the total size of the files listed in each directory, by its path relative to the source
+	sizes map[string]int64
+}
+
+func newDirectoryPacker(format common.PackFormat) *directoryPacker {
+	return &directoryPacker{format: format, sizes: make(map[string]int64)}
+}
+
+func (p *directoryPacker) add(object storedObject) error {
+	if object.isSingleSourceFile() {
+		return errors.New("pack is only supported when the source is a directory")
+	}
+	if object.entityType != common.EEntityType.File() {
+		return nil
+	}
+
+	dir := path.Dir(strings.Replace(object.relativePath, common.OS_PATH_SEPARATOR, common.AZCOPY_PATH_SEPARATOR_STRING, -1))
+	if dir == "." {
+		dir = ""
+	}
+	p.sizes[dir] += object.size
+	return nil
+}
+
+// schedule schedules the upload of an archive for each directory that has files, once the whole source has been listed
+func (p *directoryPacker) schedule(cca *cookedCopyCmdArgs, isDestDir bool, schedule func(common.CopyTransfer) error) error {
+	dirs := make([]string, 0, len(p.sizes))
+	for dir := range p.sizes {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		// the archive is checked for changes, after it's uploaded, against the directory's last modified time,
+		// which changes when files are added to the directory, or removed from it
+		fi, err := common.OSStat(common.GenerateFullPath(cca.source.ValueLocal(), dir))
+		if err != nil {
+			return err
+		}
+
+		folder := storedObject{relativePath: dir, entityType: common.EEntityType.Folder()}
+		archive := storedObject{
+			name:             p.format.ArchiveName(),
+			relativePath:     path.Join(dir, p.format.ArchiveName()),
+			entityType:       common.EEntityType.File(),
+			lastModifiedTime: fi.ModTime(),
+			size:             p.sizes[dir],
+		}
+
+		transfer, _ := archive.ToNewCopyTransfer(false,
+			cca.makeEscapedRelativePath(true, isDestDir, folder),
+			cca.makeEscapedRelativePath(false, isDestDir, archive),
+			false, common.EFolderPropertiesOption.NoFolders())
+		if err = schedule(transfer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePack checks that --pack can be used. Archives are only made on upload to Blob Storage, where a directory is
+// just a prefix of its files' names. Each directory is packed whole, so the source can't be filtered, and since only
+// regular files are packed, symlinks can't be followed or preserved
+func validatePack(pack common.PackFormat, fromTo common.FromTo, blobType common.BlobType, compress bool, hasFilters bool,
+	symlinkHandling common.SymlinkHandlingType, preserveHardlinks bool) error {
+	if pack == common.EPackFormat.None() {
+		return nil
+	}
+	if fromTo != common.EFromTo.LocalBlob() {
+		return errors.New("pack is only supported when uploading from the local file system to Blob Storage")
+	}
+	if blobType != common.EBlobType.Detect() && blobType != common.EBlobType.BlockBlob() {
+		return errors.New("pack uploads archives as block blobs, so it can't be used with any other blob-type")
+	}
+	if compress {
+		return errors.New("pack can't be used with compress. Use pack=TarGz to compress the archives")
+	}
+	if hasFilters {
+		return errors.New("pack can't be used with filters, such as include-pattern or include-path, since the files of each directory are packed whole")
+	}
+	if symlinkHandling != common.ESymlinkHandlingType.Skip() || preserveHardlinks {
+		return errors.New("pack only packs regular files, so it can't be used with follow-symlinks, preserve-symlinks or preserve-hardlinks")
+	}
+	return nil
+}
+
+// validateUnpack checks that --unpack can be used, which is only on download from Blob Storage
+func validateUnpack(unpack bool, fromTo common.FromTo) error {
+	if unpack && fromTo != common.EFromTo.BlobLocal() {
+		return errors.New("unpack is only supported when downloading from Blob Storage to the local file system")
+	}
+	return nil
+}
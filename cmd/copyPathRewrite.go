@@ -0,0 +1,95 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const pathRewriteSeparator = "=>"
+
+// pathRewriteRule replaces the parts of a destination path that match its pattern, in the same way as
+// regexp.ReplaceAllString, so the replacement may refer to the pattern's groups as $1, ${name} etc.
+type pathRewriteRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// pathRewriter holds the --path-rewrite rules, which change where each file goes at the destination,
+// e.g. to flatten directories or strip a prefix, without a staging copy of the files being made first
+type pathRewriter []pathRewriteRule
+
+// parsePathRewriteRules parses rules of the form 'regex=>replacement'
+func parsePathRewriteRules(rules []string) (pathRewriter, error) {
+	rewriter := make(pathRewriter, 0, len(rules))
+	for _, rule := range rules {
+		i := strings.Index(rule, pathRewriteSeparator)
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid path-rewrite rule '%s'. Rules must be of the form 'regex%sreplacement'", rule, pathRewriteSeparator)
+		}
+
+		pattern, err := regexp.Compile(rule[:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in path-rewrite rule '%s': %s", rule, err)
+		}
+
+		rewriter = append(rewriter, pathRewriteRule{pattern: pattern, replacement: rule[i+len(pathRewriteSeparator):]})
+	}
+	return rewriter, nil
+}
+
+var errPathRewriteWithHardlinks = errors.New("path-rewrite can't be used with preserve-hardlinks, since the links record where their targets were in the source")
+
+// rewrite applies each rule in turn to a relative path, which uses forward slashes whatever the OS.
+// Leading slashes left by the rules are dropped, since the result is always relative to the destination.
+// A result that would be outside the destination (e.g. with .. segments) is returned as empty, as is one
+// with no name left, and the file is skipped
+func (r pathRewriter) rewrite(relativePath string) string {
+	if len(r) == 0 {
+		return relativePath
+	}
+
+	for _, rule := range r {
+		relativePath = rule.pattern.ReplaceAllString(relativePath, rule.replacement)
+	}
+	relativePath = strings.TrimLeft(relativePath, "/")
+	if relativePath == "" || strings.HasSuffix(relativePath, "/") {
+		return relativePath // no name is left, so the file is skipped anyway
+	}
+
+	cleaned := path.Clean(relativePath)
+	if escapesPathRewriteRoot(cleaned) {
+		return ""
+	}
+	return cleaned
+}
+
+// escapesPathRewriteRoot says whether the clean relative path leads out of the destination. Backslashes count as
+// separators too, since they are on Windows
+func escapesPathRewriteRoot(cleaned string) bool {
+	asSlashes := path.Clean(strings.ReplaceAll(cleaned, `\`, "/"))
+	return asSlashes == ".." || strings.HasPrefix(asSlashes, "../") || strings.HasPrefix(asSlashes, "/") ||
+		(len(asSlashes) >= 2 && asSlashes[1] == ':') // a drive letter
+}
@@ -0,0 +1,153 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+const defaultSkipUnchangedToleranceSeconds = 2 // FAT file systems only store modification times to a 2 second resolution
+
+// unchangedFileSkipper lets copy skip files that are already present, and up to date, at the destination.
+// Unlike sync, it never deletes anything and never enumerates the destination a second time: the destination is
+// listed once, up front (which is much cheaper than a HEAD request per file), and each source file is then checked
+// against that listing before its transfer is scheduled.
+type unchangedFileSkipper struct {
+	destinationIndex *objectIndexer
+	mtimeTolerance   time.Duration
+
+	// with --mirror, the files that are the same at both ends are the unchanged ones, and the destination files that
+	// no source file is copied to are left in the index, to be deleted once the source has been enumerated
+	mirror *syncMirrorComparer
+
+	atomicSkippedCount uint32
+}
+
+// A source file is considered unchanged if the destination has the same size, and the destination is not older
+// than the source (allowing for the tolerance). We don't require the times to be equal, because when uploading
+// the destination's last modified time is the time of the upload, not the time the source was last changed.
+func (s *unchangedFileSkipper) isUnchanged(source storedObject, dstRelativePath string) bool {
+	if source.entityType != common.EEntityType.File() {
+		return false // folder properties are cheap to transfer, and don't have a meaningful size to compare
+	}
+
+	dest, present := s.destinationIndex.indexMap[dstRelativePath]
+	if !present || dest.entityType != common.EEntityType.File() {
+		return false
+	}
+	if s.mirror != nil {
+		return !s.mirror.isStale(source, dest)
+	}
+
+	return dest.size == source.size &&
+		!source.lastModifiedTime.After(dest.lastModifiedTime.Add(s.mtimeTolerance))
+}
+
+// skipIfUnchanged returns true if the transfer should not be scheduled, counting and logging the skip if so
+func (s *unchangedFileSkipper) skipIfUnchanged(source storedObject, dstRelativePath string) bool {
+	unchanged := s.isUnchanged(source, dstRelativePath)
+	if s.mirror != nil {
+		delete(s.destinationIndex.indexMap, dstRelativePath)
+		if !unchanged && source.entityType == common.EEntityType.File() {
+			s.mirror.transferCount++
+		}
+	}
+	if !unchanged {
+		return false
+	}
+
+	atomic.AddUint32(&s.atomicSkippedCount, 1)
+	if ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(fmt.Sprintf("Skipping %s because it is unchanged at the destination", dstRelativePath), pipeline.LogInfo)
+	}
+	return true
+}
+
+func (s *unchangedFileSkipper) skippedCount() uint32 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadUint32(&s.atomicSkippedCount)
+}
+
+// normalizes the escaped relative path computed for the destination of a transfer, so that it can be looked up
+// in an index of destination objects (whose relative paths are unescaped, and have no leading separator)
+func (cca *cookedCopyCmdArgs) skipUnchangedLookupKey(escapedDstRelPath string) string {
+	key := strings.TrimPrefix(escapedDstRelPath, common.AZCOPY_PATH_SEPARATOR_STRING)
+	if cca.fromTo.To().IsRemote() {
+		if unescaped, err := url.PathUnescape(key); err == nil {
+			key = unescaped
+		}
+	}
+	return key
+}
+
+// lists the destination once, to find what is already there
+func (cca *cookedCopyCmdArgs) initUnchangedFileSkipper(ctx context.Context, dstLevel LocationLevel) (*unchangedFileSkipper, error) {
+	if dstLevel == ELocationLevel.Service() {
+		return nil, fmt.Errorf("%s is not supported when the destination is an entire account. Specify a container or directory instead", common.IffString(cca.mirror, "mirror", "skip-unchanged"))
+	}
+
+	dstCredInfo, _, err := getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Azure Files listings don't include last modified times, so we need the properties of each file
+	getProperties := cca.fromTo.To() == common.ELocation.File()
+	traverser, err := initResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, common.ESymlinkHandlingType.Skip(), nil, true, getProperties, false, func(common.EntityType) {}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	index := newObjectIndexer()
+	err = traverser.traverse(noPreProccessor, func(object storedObject) error {
+		// local traversers report paths with the OS separator, but the paths we look up always use /
+		object.relativePath = strings.Replace(object.relativePath, common.OS_PATH_SEPARATOR, common.AZCOPY_PATH_SEPARATOR_STRING, -1)
+		return index.store(object)
+	}, nil)
+	if err != nil {
+		// most likely the destination doesn't exist yet, in which case nothing there can be unchanged.
+		// If it's something worse, the transfers themselves will fail and report it
+		WarnStdoutAndJobLog(fmt.Sprintf("Could not list the destination to find unchanged files, so all files will be transferred: %s", err))
+		index = newObjectIndexer()
+	}
+
+	skipper := &unchangedFileSkipper{
+		destinationIndex: index,
+		mtimeTolerance:   cca.skipUnchangedTolerance,
+	}
+	if cca.mirror {
+		// a copy has no conflicts to resolve, since the destination is always made to match the source
+		skipper.mirror = newMirrorComparer(cca.fromTo, common.ESyncConflictResolution.SourceWins(), nil)
+	}
+	return skipper, nil
+}
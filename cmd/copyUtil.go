@@ -95,7 +95,11 @@ func (util copyHandlerUtil) redactSigQueryParam(rawQuery string) (bool, string)
 // If any argument passed is an http Url and contains the signature, then the signature is redacted
 func (util copyHandlerUtil) ConstructCommandStringFromArgs() string {
 	// Get the os Args and strip away the first argument since it will be the path of Azcopy executable
-	args := os.Args[1:]
+	return util.constructCommandString(os.Args[1:])
+}
+
+// constructCommandString joins the arguments into a command string, with the signatures of any URLs redacted
+func (util copyHandlerUtil) constructCommandString(args []string) string {
 	if len(args) == 0 {
 		return ""
 	}
@@ -346,7 +346,7 @@ func checkAuthSafeForTarget(ct common.CredentialType, resource, extraSuffixesAAD
 			parts, err := common.NewS3URLParts(*u) // strip any leading bucket name from URL, to get an endpoint we can pass to s3utils
 			if err == nil {
 				u, err := url.Parse("https://" + parts.Endpoint)
-				ok = err == nil && s3utils.IsAmazonEndpoint(*u)
+				ok = err == nil && (s3utils.IsAmazonEndpoint(*u) || s3utils.IsGoogleEndpoint(*u))
 			}
 		}
 
@@ -398,10 +398,14 @@ func getCredentialTypeForLocation(ctx context.Context, location common.Location,
 func doGetCredentialTypeForLocation(ctx context.Context, location common.Location, resource, resourceSAS string, isSource bool, getForcedCredType func() common.CredentialType) (credType common.CredentialType, isPublic bool, err error) {
 	if resourceSAS != "" {
 		credType = common.ECredentialType.Anonymous()
-	} else if credType = getForcedCredType(); credType == common.ECredentialType.Unknown() || location == common.ELocation.S3() {
+	} else if credType = getForcedCredType(); credType == common.ECredentialType.Unknown() || location == common.ELocation.S3() || location == common.ELocation.HTTP() {
 		switch location {
-		case common.ELocation.Local(), common.ELocation.Benchmark():
+		case common.ELocation.Local(), common.ELocation.Benchmark(), common.ELocation.SFTP():
+			credType = common.ECredentialType.Anonymous() // SFTP logs in over SSH, with its own credentials
+		case common.ELocation.HTTP():
+			// the service reads a plain HTTP(S) URL as anyone could, with at most a token in its query string
 			credType = common.ECredentialType.Anonymous()
+			isPublic = true
 		case common.ELocation.Blob():
 			if credType, isPublic, err = getBlobCredentialType(ctx, resource, isSource, resourceSAS != ""); err != nil {
 				return common.ECredentialType.Unknown(), false, err
@@ -415,10 +419,15 @@ func doGetCredentialTypeForLocation(ctx context.Context, location common.Locatio
 				return common.ECredentialType.Unknown(), false, err
 			}
 		case common.ELocation.S3():
-			accessKeyID := glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AWSAccessKeyID())
-			secretAccessKey := glcm.GetEnvironmentVariable(common.EEnvironmentVariable.AWSSecretAccessKey())
-			if accessKeyID == "" || secretAccessKey == "" {
-				return common.ECredentialType.Unknown(), false, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables must be set before creating the S3 AccessKey credential")
+			endpoint := ""
+			if u, err := url.Parse(resource); err == nil {
+				if parts, err := common.NewS3URLParts(*u); err == nil {
+					endpoint = parts.Endpoint
+				}
+			}
+			accessKeyIDVar, secretAccessKeyVar := common.S3AccessKeyEnvironmentVariables(endpoint)
+			if glcm.GetEnvironmentVariable(accessKeyIDVar) == "" || glcm.GetEnvironmentVariable(secretAccessKeyVar) == "" {
+				return common.ECredentialType.Unknown(), false, fmt.Errorf("%s and %s environment variables must be set before creating the S3 AccessKey credential", accessKeyIDVar.Name, secretAccessKeyVar.Name)
 			}
 			credType = common.ECredentialType.S3AccessKey()
 		}
@@ -512,42 +521,74 @@ func createBlobPipeline(ctx context.Context, credInfo common.CredentialInfo) (pi
 
 const frontEndMaxIdleConnectionsPerHost = http.DefaultMaxIdleConnsPerHost
 
+// createHTTPPipeline creates a pipeline for the HEAD requests to plain HTTP(S) URLs. It's the Blob pipeline,
+// for its retries, but without the timeout parameter that only Azure Storage understands.
+func createHTTPPipeline(ctx context.Context, credInfo common.CredentialInfo) (pipeline.Pipeline, error) {
+	return ste.NewBlobPipeline(
+		azblob.NewAnonymousCredential(),
+		azblob.PipelineOptions{
+			Telemetry: azblob.TelemetryOptions{
+				Value: glcm.AddUserAgentPrefix(common.UserAgent),
+			},
+		},
+		ste.XferRetryOptions{
+			Policy:          0,
+			MaxTries:        ste.UploadMaxTries,
+			TryTimeout:      ste.UploadTryTimeout,
+			RetryDelay:      ste.UploadRetryDelay,
+			MaxRetryDelay:   ste.UploadMaxRetryDelay,
+			NoServerTimeout: true,
+		},
+		nil,
+		ste.NewAzcopyHTTPClient(frontEndMaxIdleConnectionsPerHost),
+		nil, // we don't gather network stats on the credential pipeline
+	), nil
+}
+
 func createBlobFSPipeline(ctx context.Context, credInfo common.CredentialInfo) (pipeline.Pipeline, error) {
 	credential := common.CreateBlobFSCredential(ctx, credInfo, common.CredentialOpOptions{
 		//LogInfo:  glcm.Info, //Comment out for debugging
 		LogError: glcm.Info,
 	})
 
-	return azbfs.NewPipeline(
+	return ste.NewBlobFSPipeline(
 		credential,
 		azbfs.PipelineOptions{
-			Retry: azbfs.RetryOptions{
-				Policy:        azbfs.RetryPolicyExponential,
-				MaxTries:      ste.UploadMaxTries,
-				TryTimeout:    ste.UploadTryTimeout,
-				RetryDelay:    ste.UploadRetryDelay,
-				MaxRetryDelay: ste.UploadMaxRetryDelay,
-			},
 			Telemetry: azbfs.TelemetryOptions{
 				Value: glcm.AddUserAgentPrefix(common.UserAgent),
 			},
-		}), nil
+		},
+		ste.XferRetryOptions{
+			Policy:        0,
+			MaxTries:      ste.UploadMaxTries,
+			TryTimeout:    ste.UploadTryTimeout,
+			RetryDelay:    ste.UploadRetryDelay,
+			MaxRetryDelay: ste.UploadMaxRetryDelay,
+		},
+		nil,
+		ste.NewAzcopyHTTPClient(frontEndMaxIdleConnectionsPerHost),
+		nil, // we don't gather network stats on the credential pipeline
+	), nil
 }
 
 // TODO note: ctx and credInfo are ignored at the moment because we only support SAS for Azure File
 func createFilePipeline(ctx context.Context, credInfo common.CredentialInfo) (pipeline.Pipeline, error) {
-	return azfile.NewPipeline(
+	return ste.NewFilePipeline(
 		azfile.NewAnonymousCredential(),
 		azfile.PipelineOptions{
-			Retry: azfile.RetryOptions{
-				Policy:        azfile.RetryPolicyExponential,
-				MaxTries:      ste.UploadMaxTries,
-				TryTimeout:    ste.UploadTryTimeout,
-				RetryDelay:    ste.UploadRetryDelay,
-				MaxRetryDelay: ste.UploadMaxRetryDelay,
-			},
 			Telemetry: azfile.TelemetryOptions{
 				Value: glcm.AddUserAgentPrefix(common.UserAgent),
 			},
-		}), nil
+		},
+		ste.XferRetryOptions{
+			Policy:        0,
+			MaxTries:      ste.UploadMaxTries,
+			TryTimeout:    ste.UploadTryTimeout,
+			RetryDelay:    ste.UploadRetryDelay,
+			MaxRetryDelay: ste.UploadMaxRetryDelay,
+		},
+		nil,
+		ste.NewAzcopyHTTPClient(frontEndMaxIdleConnectionsPerHost),
+		nil, // we don't gather network stats on the credential pipeline
+	), nil
 }
@@ -0,0 +1,218 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// dryRunPrinter stands in for the transfer engine when --dry-run is given. The source (and, for sync, the destination)
+// is enumerated and compared as usual, but the job parts that would have been sent to the engine are printed instead,
+// as are the deletions that sync would have made. Nothing is transferred or deleted, and no job is created.
+type dryRunPrinter struct {
+	mu            sync.Mutex
+	fileCount     uint64
+	folderCount   uint64
+	totalBytes    int64
+	deletionCount uint64
+	skippedCount  uint64
+	print         func(msg string)
+
+	// files that copy's skip-unchanged option found to be up to date at the destination. They never reach a job part
+	unchangedCount uint32
+
+	// unless existing files are always overwritten, copies are checked against what's at the destination, since the
+	// transfer engine would skip some of them
+	overwrite       common.OverwriteOption
+	findDestination func(destination string) (storedObject, bool)
+}
+
+func newDryRunPrinter() *dryRunPrinter {
+	return &dryRunPrinter{print: glcm.Info}
+}
+
+// printPart prints the transfers in a job part, and returns the response the transfer engine would have given
+func (p *dryRunPrinter) printPart(order *common.CopyJobPartOrderRequest) common.CopyJobPartOrderResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	isRemove := order.FromTo.To() == common.ELocation.Unknown() // i.e. to the "trash"
+	for _, t := range order.Transfers {
+		source := joinDryRunPath(order.SourceRoot.Value, t.Source)
+		what := "file"
+		if t.EntityType == common.EEntityType.Folder() {
+			what = "folder"
+			p.folderCount++
+		} else {
+			if t.EntityType == common.EEntityType.Symlink() {
+				what = "symlink"
+			}
+			p.fileCount++
+			p.totalBytes += t.SourceSize
+		}
+
+		if isRemove {
+			p.print(fmt.Sprintf("Would remove %s %s", what, source))
+			continue
+		}
+
+		destination := joinDryRunPath(order.DestinationRoot.Value, t.Destination)
+		verb := "copy"
+		if t.EntityType == common.EEntityType.File() {
+			verb = p.overwriteDecision(t, destination)
+		}
+		switch verb {
+		case "skip":
+			p.fileCount--
+			p.totalBytes -= t.SourceSize
+			p.skippedCount++
+			p.print(fmt.Sprintf("Would skip file %s, since %s already exists", source, destination))
+		case "ask":
+			p.print(fmt.Sprintf("Would ask whether to overwrite %s with file %s (%s)", destination, source, byteSizeToString(t.SourceSize)))
+		default:
+			p.print(fmt.Sprintf("Would %s %s %s to %s (%s)", verb, what, source, destination, byteSizeToString(t.SourceSize)))
+		}
+	}
+
+	if order.IsFinalPart && p.fileCount+p.folderCount+p.skippedCount == 0 {
+		return common.CopyJobPartOrderResponse{ErrorMsg: common.ECopyJobPartOrderErrorType.NoTransfersScheduledErr()}
+	}
+	return common.CopyJobPartOrderResponse{JobStarted: true}
+}
+
+// overwriteDecision makes the same decision about a file that already exists at the destination as the transfer
+// engine does, and returns "copy" if it doesn't exist, or "overwrite", "skip" or "ask" if it does
+func (p *dryRunPrinter) overwriteDecision(t common.CopyTransfer, destination string) string {
+	if p.findDestination == nil || p.overwrite == common.EOverwriteOption.True() {
+		return "copy"
+	}
+	existing, found := p.findDestination(destination)
+	if !found {
+		return "copy"
+	}
+
+	shouldOverwrite := false
+	switch p.overwrite {
+	case common.EOverwriteOption.Prompt():
+		return "ask"
+	case common.EOverwriteOption.IfSourceNewer():
+		shouldOverwrite = t.LastModifiedTime.After(existing.lastModifiedTime)
+	case common.EOverwriteOption.IfSizeDifferent():
+		shouldOverwrite = existing.size != t.SourceSize
+	}
+	if shouldOverwrite {
+		return "overwrite"
+	}
+	return "skip"
+}
+
+// printUnchanged prints a file that copy's skip-unchanged option found to be up to date at the destination, and so
+// would not be copied. Such files are skipped before any job part is made, so they're never seen by printPart
+func (p *dryRunPrinter) printUnchanged(source, destination string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unchangedCount++
+	p.print(fmt.Sprintf("Would skip (unchanged) file %s, since %s is up to date", source, destination))
+}
+
+// printDeletion prints the deletion of an extra file that sync found at the destination
+func (p *dryRunPrinter) printDeletion(object storedObject) error {
+	if object.entityType != common.EEntityType.File() {
+		return nil // sync doesn't delete folders
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deletionCount++
+	p.print("Would delete extra file " + object.relativePath)
+	return nil
+}
+
+// printContainerCreation prints the creation of a destination container
+func (p *dryRunPrinter) printContainerCreation(containerName string) {
+	p.print("Would create container " + containerName)
+}
+
+func (p *dryRunPrinter) summary(isRemove bool) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	verb := "copied"
+	if isRemove {
+		verb = "removed"
+	}
+	s := fmt.Sprintf("Dry run complete. %d files (%s) would be %s", p.fileCount, byteSizeToString(p.totalBytes), verb)
+	if p.folderCount > 0 {
+		s += fmt.Sprintf(", along with %d folders", p.folderCount)
+	}
+	if p.skippedCount > 0 {
+		s += fmt.Sprintf(", and %d files would be skipped, since they already exist", p.skippedCount)
+	}
+	if p.unchangedCount > 0 {
+		s += fmt.Sprintf(", and %d files would be skipped, since they are unchanged at the destination", p.unchangedCount)
+	}
+	if p.deletionCount > 0 {
+		s += fmt.Sprintf(", and %d extra files would be deleted from the destination", p.deletionCount)
+	}
+	return s + ". Nothing was changed."
+}
+
+// dryRunDestinationFinder looks up each file at the destination, with its properties, so that a dry run can tell which
+// copies the transfer engine would skip, or overwrite. It's one request per file, but nothing is copied in a dry run
+func (cca *cookedCopyCmdArgs) dryRunDestinationFinder(ctx context.Context) func(destination string) (storedObject, bool) {
+	dstCredInfo, _, err := getCredentialInfoForLocation(ctx, cca.fromTo.To(), cca.destination.Value, cca.destination.SAS, false)
+	if err != nil {
+		return nil
+	}
+
+	return func(destination string) (storedObject, bool) {
+		var existing storedObject
+		found := false
+		rt, err := initResourceTraverser(cca.destination.CloneWithValue(destination), cca.fromTo.To(), &ctx, &dstCredInfo,
+			common.ESymlinkHandlingType.Skip(), nil, false, true, false, func(common.EntityType) {}, nil)
+		if err != nil {
+			return existing, false
+		}
+		err = rt.traverse(noPreProccessor, func(object storedObject) error {
+			if object.entityType == common.EEntityType.File() && object.relativePath == "" { // not something below it
+				existing, found = object, true
+			}
+			return nil
+		}, nil)
+		return existing, found && err == nil
+	}
+}
+
+// paths in job parts are relative to the roots, so that they take less space in the plan files
+func joinDryRunPath(root, relativePath string) string {
+	if relativePath == "" {
+		return root
+	}
+	if root == "" {
+		return relativePath
+	}
+	return strings.TrimSuffix(root, common.AZCOPY_PATH_SEPARATOR_STRING) + common.AZCOPY_PATH_SEPARATOR_STRING +
+		strings.TrimPrefix(relativePath, common.AZCOPY_PATH_SEPARATOR_STRING)
+}
@@ -28,6 +28,11 @@ Copies source data to a destination location. The supported directions are:
   - Azure Files (SAS) -> Azure Files (SAS)
   - Azure Files (SAS) -> Azure Blob (SAS or OAuth authentication)
   - AWS S3 (Access Key) -> Azure Block Blob (SAS or OAuth authentication)
+  - AWS S3 (Access Key) -> Azure Files (SAS)
+  - Google Cloud Storage (HMAC Key only, not OAuth) -> Azure Block Blob (SAS or OAuth authentication) or Azure Files (SAS)
+  - SFTP (password or SSH key) -> Azure Blob (SAS or OAuth authentication) or Azure Files (SAS)
+  - local -> SFTP (password or SSH key)
+  - Any HTTP(S) URL (public or pre-signed) -> Azure Blob (SAS or OAuth authentication) or Azure Files (SAS)
 
 Please refer to the examples for more information.
 
@@ -43,6 +48,8 @@ The built-in lookup table is small, but on Unix, it is augmented by the local sy
 
 On Windows, MIME types are extracted from the registry. This feature can be turned off with the help of a flag. Please refer to the flag section.
 
+The destination may contain these tokens, which are replaced when the job starts: {yyyy}, {MM} and {dd} for the year, month and day, {HH}, {mm} and {ss} for the time (all in local time), and {jobid} for the ID of the job.
+
 ` + environmentVariableNotice
 
 const copyCmdExample = `Upload a single file by using OAuth authentication. If you have not yet logged into AzCopy, please run the azcopy login command before you run the following command.
@@ -65,6 +72,10 @@ Upload a single file by using OAuth and piping (block blobs only):
 
   - cat "/path/to/file.txt" | azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --from-to PipeBlob
 
+Upload the output of another command, such as a database dump, as it's produced, by giving - (for stdin) as the source. The length needn't be known in advance, since the data is staged as blocks, as it arrives:
+
+  - pg_dump [database] | azcopy cp - "https://[account].blob.core.windows.net/[container]/[path/to/blob]?[SAS]"
+
 Upload an entire directory by using a SAS token:
   
   - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true
@@ -79,6 +90,74 @@ Upload files and directories by using a SAS token and wildcard (*) characters:
 
   - azcopy cp "/path/*foo/*bar*" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true
 
+Upload an entire directory, but skip the files that are over 1 GiB:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --max-size=1GiB
+
+Back up a Windows user profile, leaving out hidden, system and temporary files such as thumbs.db and desktop.ini:
+
+  - azcopy cp "C:\Users\[user]" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --exclude-attributes=hidden,system,temporary
+
+Upload only the files in a directory that were changed in the first week of August 2020:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --include-after=2020-08-01 --include-before=2020-08-07T23:59:59
+
+Upload a directory that contains symbolic links, keeping the links as links, so that downloading it with --preserve-symlinks re-creates them:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --preserve-symlinks
+
+Back up a directory whose files are deduplicated with hard links, uploading the content of each file only once:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --preserve-hardlinks
+
+Upload a static website, with content types from a JSON file of your own (such as {".md": "text/markdown"}) for the extensions that AzCopy gets wrong, and with the types of files without a known extension detected from their content:
+
+  - azcopy cp "/path/to/site/*" "https://[account].blob.core.windows.net/$web?[SAS]" --recursive=true --content-type-map="/path/to/types.json" --sniff-content-type
+
+Upload a static website, letting browsers cache everything for an hour, except the HTML pages, which are always checked, and the files under assets, which are cached for a year. The rules are in a JSON file, such as [{"pattern": "*.html", "cacheControl": "no-cache"}, {"pattern": "assets/*", "cacheControl": "max-age=31536000"}]:
+
+  - azcopy cp "/path/to/site/*" "https://[account].blob.core.windows.net/$web?[SAS]" --recursive=true --cache-control="max-age=3600" --header-rules="/path/to/rules.json"
+
+Upload a directory of logs compressed with gzip, to cut the bytes sent and stored. Each blob gets the content encoding gzip, so downloading it with --decompress gives back the original file:
+
+  - azcopy cp "/path/to/logs" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --compress
+
+Upload backups straight into the archive tier, except for the small index files, which are kept in the cool tier so that they can be read without rehydrating them:
+
+  - azcopy cp "/path/to/backups" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --block-blob-tier=Archive --block-blob-tier-rules="*.idx=Cool"
+
+Upload a virtual machine's disk image as a page blob, checking that it's a fixed-size VHD and sending only the pages that hold data:
+
+  - azcopy cp "/path/to/disk.vhd" "https://[account].blob.core.windows.net/[container]/disk.vhd?[SAS]" --vhd
+
+Upload a VHD straight into a managed disk, using the URL given when the disk is opened for upload. Only the pages that hold data are sent:
+
+  - azcopy cp "/path/to/disk.vhd" "https://md-impexp-[id].[host].blob.storage.azure.net/[container]/abcd?[SAS]"
+
+Copy a managed disk to a storage account as a page blob, using the URL given when access to the disk is granted:
+
+  - azcopy cp "https://md-[id].[host].blob.storage.azure.net/[container]/abcd?[SAS]" "https://[account].blob.core.windows.net/[container]/disk.vhd?[SAS]"
+
+Ship a growing log file by running the same command repeatedly, each time appending only the new lines to the append blob:
+
+  - azcopy cp "/path/to/app.log" "https://[account].blob.core.windows.net/[container]/app.log?[SAS]" --blob-type AppendBlob --append
+
+Upload a directory, recording on each blob the path it had in the directory, and the job that uploaded it:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --metadata="original={relpath};job={jobid}"
+
+Upload a directory to an ADLS Gen2 account, keeping the owner, group and permissions of each file:
+
+  - azcopy cp "/path/to/dir" "https://[account].dfs.core.windows.net/[filesystem]/[path/to/directory]?[SAS]" --recursive=true --preserve-posix-permissions
+
+Migrate a directory from a Windows file server to an Azure file share, keeping the NTFS permissions (including those of folders, and whether they inherit from their parents) and the SMB properties of each file and folder:
+
+  - azcopy cp "C:\path\to\dir" "https://[account].file.core.windows.net/[share]/[path/to/directory]?[SAS]" --recursive=true --preserve-smb-permissions=true --preserve-smb-info=true
+
+Download the directory back to Windows with the same permissions. The --backup flag lets AzCopy set owners other than the user running it:
+
+  - azcopy cp "https://[account].file.core.windows.net/[share]/[path/to/directory]?[SAS]" "C:\path\to\dir" --recursive=true --preserve-smb-permissions=true --preserve-smb-info=true --backup
+
 Download a single file by using OAuth authentication. If you have not yet logged into AzCopy, please run the azcopy login command before you run the following command.
 
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" "/path/to/file.txt"
@@ -95,10 +174,26 @@ Download a single file by using OAuth and then piping the output to a file (bloc
   
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/blob]" --from-to BlobPipe > "/path/to/file.txt"
 
+Download a blob into another command, by giving - (for stdout) as the destination. Nothing else is written to stdout, and errors go to stderr:
+
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/archive.tar.gz]?[SAS]" - | tar xz
+
 Download an entire directory by using a SAS token:
   
   - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "/path/to/dir" --recursive=true
 
+Download an entire directory, checking each range and each whole file against the CRC64 checksums saved by an upload with --put-crc64:
+
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "/path/to/dir" --recursive=true --check-crc64=FailIfDifferent
+
+Download an entire directory, failing any file whose SHA-256 digest doesn't match the one saved by an upload with --put-sha256:
+
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "/path/to/dir" --recursive=true --check-sha256=FailIfDifferentOrMissing
+
+Download an entire directory, but leave out the archived blobs, which can't be read until they're rehydrated:
+
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "/path/to/dir" --recursive=true --include-tier="Hot;Cool"
+
 A note about using a wildcard character (*) in URLs:
 
 There's only two supported ways to use a wildcard character in a URL. 
@@ -121,6 +216,43 @@ Download all the versions of a blob from Azure Storage to local directory. Ensur
 
   - azcopy cp "https://[srcaccount].blob.core.windows.net/[containername]/[blobname]" "/path/to/dir" --list-of-versions="/another/path/to/dir/[versionidsFile]"
 
+Download a snapshot of a blob, by giving the snapshot's URL:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[containername]/[blobname]?snapshot=[snapshot]&[SAS]" "/path/to/file.txt"
+
+Back up a container with the snapshots of its blobs. Each snapshot is copied to a blob of its own, with the time of the snapshot in its name:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --recursive --include-snapshots
+
+Copy a particular version of a blob, by giving the version's URL:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[blobname]?versionid=[versionid]&[SAS]" "https://[destaccount].blob.core.windows.net/[container]/[blobname]?[SAS]"
+
+Replicate an account to another one with versioning enabled, for disaster recovery. Every version of each blob is copied, oldest first, so that the destination's versions follow the source's:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net?[SAS]" "https://[destaccount].blob.core.windows.net?[SAS]" --recursive --include-versions
+
+Recover a container's blobs after they were deleted by mistake, while soft delete was enabled. The soft-deleted blobs are undeleted, and copied along with the others:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]?[SAS]" "/path/to/dir" --recursive --include-deleted
+
+Re-run a copy that stopped part way through, writing only the files that are missing at the destination, or whose size there differs from the source's:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive --overwrite=ifSizeDifferent
+
+Download a container's blobs into a single directory, flattening the virtual directories they are in:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]?[SAS]" "/path/to/dir" --recursive --path-rewrite='^.*/=>'
+
+Back up a directory to a virtual directory named after the date, so that each night's run lands in one of its own:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/backups/{yyyy}/{MM}/{dd}?[SAS]" --recursive
+
+Upload a directory tree of many small files, packing the files of each directory into one gzipped tar archive, and download it again, unpacking the archives:
+
+  - azcopy cp "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive --pack=TarGz
+  - azcopy cp "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "/path/to/dir" --recursive --unpack
+
 Copy a single blob to another blob by using a SAS token.
 
   - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/blob]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/blob]?[SAS]"
@@ -129,10 +261,22 @@ Copy a single blob to another blob by using a SAS token and an OAuth token. You
 
   - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/blob]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/blob]"
 
+Copy a container from an account whose firewall keeps the destination's service out, by reading the data through AzCopy:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --recursive --s2s-copy-method=DownloadUpload
+
 Copy one blob virtual directory to another by using a SAS token:
 
   - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true
 
+Copy one blob virtual directory to another, adding metadata to each blob, but keeping the source's value of any key that the source blob already has:
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --metadata="migrated=true;owner=ops" --s2s-metadata-merge=KeepSource
+
+Copy a container that holds archived blobs as well as online ones, rehydrating the archived blobs with high priority and copying each one once it's back online (the source SAS must allow setting their tier):
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]?[SAS]" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --recursive=true --archived-source=Rehydrate --rehydrate-priority=High
+
 Copy all blob containers, directories, and blobs from storage account to another by using a SAS token:
 
   - azcopy cp "https://[srcaccount].blob.core.windows.net?[SAS]" "https://[destaccount].blob.core.windows.net?[SAS]" --recursive=true
@@ -158,6 +302,35 @@ Copy all buckets to Blob Storage from an Amazon Web Services (AWS) region by usi
 Copy a subset of buckets by using a wildcard symbol (*) in the bucket name. Like the previous examples, you'll need an access key and a SAS token. Make sure to set the environment variable AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for AWS S3 source.
 
   - azcopy cp "https://s3.amazonaws.com/[bucket*name]/" "https://[destaccount].blob.core.windows.net?[SAS]" --recursive=true
+
+Copy a folder from AWS S3 to an Azure file share, server to server. As for Blob Storage, set the environment variables AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for the AWS S3 source.
+
+  - azcopy cp "https://s3.amazonaws.com/[bucket]/[folder]" "https://[destaccount].file.core.windows.net/[share]/[path/to/folder]?[SAS]" --recursive=true
+
+Copy a bucket from Google Cloud Storage, through its S3-compatible XML API, by using an HMAC key and a SAS token. First, set the environment variables GOOGLE_HMAC_ACCESS_ID and GOOGLE_HMAC_SECRET for the Google Cloud Storage source. Use the URL of the bucket on storage.googleapis.com, or leave out the bucket name to copy all the buckets of the HMAC key's project (each to a container of the same name). Only HMAC keys are supported: Google's OAuth credentials, such as a service account's key file or application default credentials, can't be used, so create an HMAC key for the service account instead.
+
+  - azcopy cp "https://storage.googleapis.com/[bucket]" "https://[destaccount].blob.core.windows.net?[SAS]" --recursive=true
+
+Copy a folder from an SFTP server, by logging in with an SSH key (or with the password in AZCOPY_SFTP_PASSWORD). AzCopy reads the files through SFTP, and uploads them. The server's host key must be in ~/.ssh/known_hosts, or in the file named by AZCOPY_SFTP_KNOWN_HOSTS. The path isn't URL-encoded, and a path starting with /~/ is in the user's home directory.
+
+  - export AZCOPY_SFTP_KEY_FILE=~/.ssh/id_ed25519
+  - azcopy cp "sftp://[user]@[host]/[path/to/folder]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true
+
+Copy a local folder to an SFTP server, logging in as above. The directories are made on the server as they're needed. SFTP has nowhere to keep content types, metadata or hashes, so only the content of the files is copied.
+
+  - azcopy cp "/path/to/folder" "sftp://[user]@[host]/[path/to/directory]" --recursive=true
+
+Copy a file from any web server, such as a public dataset, server to server. The service reads the file range by range, retrying each range that fails, so the server must give the file's size and serve ranges of it. Any query string is kept like a SAS, out of the job's plan files, so give it again with --source-sas when you resume the job.
+
+  - azcopy cp "https://[host]/[path/to/file]" "https://[destaccount].blob.core.windows.net/[container]/[path/to/directory]?[SAS]"
+
+Copy several files from a web server, which can't be listed, by naming them (one per line, relative to the URL that ends in a slash) in a file.
+
+  - azcopy cp "https://[host]/[path/to/folder]/" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --list-of-files=[path/to/list.txt]
+
+Make a container a mirror of another, replacing every blob that differs in size, content, properties or metadata, and deleting the extra ones. A blob whose size is the same is only compared by content if both ends have an MD5 hash of it.
+
+  - azcopy cp "https://[srcaccount].blob.core.windows.net/[container]/*?[SAS]" "https://[destaccount].blob.core.windows.net/[container]?[SAS]" --recursive=true --mirror
 `
 
 // ===================================== ENV COMMAND ===================================== //
@@ -184,12 +357,122 @@ const showJobsCmdShortDescription = "Show detailed information for the given job
 const showJobsCmdLongDescription = `
 If you provide only a job ID, and not a flag, then this command returns the progress summary only.
 The byte counts and percent complete that appears when you run this command reflect only files that are completed in the job. They don't reflect partially completed files.
-If you set the with-status flag, then only the list of transfers associated with the given status appear.`
+If you set the with-status flag, then only the list of transfers associated with the given status appear.
+For failed transfers, the list includes the HTTP status, the error message and the number of retries of each one.
+Use the export flag to also write the list to a CSV or JSON file.
+
+To follow a job that runs unattended, e.g. from a monitoring tool, read the file [jobID].metrics.json, next to the job's log.
+While the job runs, it's replaced every 10 seconds with the latest progress, and at the end it holds the job's final state.`
 
 const resumeJobsCmdShortDescription = "Resume the existing job with the given job ID."
 
 const resumeJobsCmdLongDescription = `
-Resume the existing job with the given job ID.`
+Resume the existing job with the given job ID.
+
+A job that was paused, with the pause command, carries on from where it stopped, and failed transfers are tried again.
+Large files that were partly transferred are not started again from the beginning: blocks that had already been uploaded
+to block blobs are not sent again, and downloads carry on from where they had got to in the partially-downloaded file.
+
+SAS tokens are not saved with the job, so give them again with the source-sas and destination-sas flags. They don't have to be
+the ones that the job was started with, so a job whose SAS tokens have expired can be resumed with fresh ones. A job that uses
+OAuth authentication is resumed with the token from your most recent login; if that can't be refreshed, log in again first.`
+
+const pauseJobsCmdShortDescription = "Pause the running job with the given job ID."
+
+const pauseJobsCmdLongDescription = `
+Pause the running job with the given job ID, so that it can be carried on later with the resume command.
+
+Run it from another command prompt, while the job is running. The job stops soon afterwards, and the AzCopy command that was running it exits.
+Only jobs whose files have all been scheduled can be paused.`
+
+const pauseJobsCmdExample = "azcopy jobs pause [jobID]"
+
+const retryJobsCmdShortDescription = "Retry the failed transfers of the job with the given job ID, as a new job."
+
+const retryJobsCmdLongDescription = `
+Retry the failed transfers of the job with the given job ID, as a new job, with the same options.
+
+The source isn't listed again and compared with the destination, so this is much quicker than running the original
+command again, when only a few of a large number of transfers have failed. The original job is left as it is, with its
+failures. The job must have finished (or been paused or cancelled); a job whose AzCopy process was stopped should be resumed instead.
+
+As with the resume command, SAS tokens are not saved with the job, so give them again with the source-sas and destination-sas flags.`
+
+const retryJobsCmdExample = `Retry the failed transfers of an upload to blob storage:
+
+  - azcopy jobs retry [jobID] --destination-sas "[SAS]"`
+
+const exportJobsCmdShortDescription = "Export the job with the given job ID to a file, so that it can be imported, and carried on, on another machine."
+
+const exportJobsCmdLongDescription = `
+Export the job with the given job ID to a file, so that it can be imported, and carried on, by AzCopy on another machine.
+For example, when the machine that a long migration is running on has to be decommissioned before the migration is over.
+
+The file is JSON. It holds the job's options, all its transfers, and how far each one has got. Transfers that had finished
+aren't done again once the job has been imported. Pause or cancel the job first, or wait for its AzCopy process to stop,
+so that its progress is up to date, and so that the job isn't carried on in both places.
+
+SAS tokens are not saved with the job, so they are not in the file either. The paths of everything the job transfers are,
+so keep the file as safe as the plan files it's made from.`
+
+const exportJobsCmdExample = "  azcopy jobs export e52247de-0323-b14d-4cc8-76e0be2e2d44 migration.json"
+
+const importJobsCmdShortDescription = "Import a job that was exported with the export command, so that it can be resumed here."
+
+const importJobsCmdLongDescription = `
+Import a job that was exported with the export command, so that it can be resumed here, with the resume command.
+The job keeps its job ID, and the transfers that had finished stay finished.
+
+If the job's source or destination is somewhere else on this machine (e.g. a local folder that has been copied over to
+a different path), give the new location with the source or destination flag. Give it in the same form that the export
+command showed.
+
+The job must be imported by the same version of AzCopy that exported it.`
+
+const importJobsCmdExample = `Import a job, and carry on uploading it to blob storage:
+
+  - azcopy jobs import migration.json
+  - azcopy jobs resume e52247de-0323-b14d-4cc8-76e0be2e2d44 --destination-sas "[SAS]"
+
+Import a job that uploads a local folder, which is at a different path on this machine:
+
+  - azcopy jobs import migration.json --source /mnt/data/photos`
+
+const setJobsCmdShortDescription = "Change the settings of the running job with the given job ID."
+
+const setJobsCmdLongDescription = `
+Change the settings of the running job with the given job ID, without stopping it.
+
+Run it from another command prompt, while the job is running. At present, the only setting that can be changed is
+the throughput cap (cap-mbps). For example, lower the cap of a job that is saturating a shared network link, and raise it
+again later. The cap applies to the whole of the AzCopy command that is running the job.`
+
+const setJobsCmdExample = `Cap the throughput of a running job at 50 megabits per second:
+
+  - azcopy jobs set [jobID] --cap-mbps 50
+
+Remove the cap again:
+
+  - azcopy jobs set [jobID] --cap-mbps 0`
+
+const cancelTransfersJobsCmdShortDescription = "Cancel some of the transfers of the running job with the given job ID, and let the rest carry on."
+
+const cancelTransfersJobsCmdLongDescription = `
+Cancel some of the transfers of the running job with the given job ID, without stopping the rest of the job.
+
+Run it from another command prompt, while the job is running. Give the sources of the transfers to cancel, either in full
+or relative to the source of the job (i.e. as they appear in the destination). For remote sources, the SAS can be left out.
+A download that is cancelled part way through is deleted; an upload leaves nothing behind in the destination.
+
+The cancelled transfers are counted as skipped, and they stay cancelled if the job is paused and then resumed.`
+
+const cancelTransfersJobsCmdExample = `Cancel the upload of one large file, in a job that uploads the folder /data:
+
+  - azcopy jobs cancel-transfers [jobID] videos/huge.mp4
+
+Or give the source in full:
+
+  - azcopy jobs cancel-transfers [jobID] /data/videos/huge.mp4`
 
 const removeJobsCmdShortDescription = "Remove all files associated with the given job ID."
 
@@ -200,12 +483,29 @@ Note that you can customize the location where log and plan files are saved. See
 
 const removeJobsCmdExample = "  azcopy jobs rm e52247de-0323-b14d-4cc8-76e0be2e2d44"
 
-const cleanJobsCmdShortDescription = "Remove all log and plan files for all jobs"
+const cleanJobsCmdShortDescription = "Remove the log and plan files of all jobs, or of only the jobs that match the given flags"
 
 const cleanJobsCmdLongDescription = `
+Remove the log and plan files of jobs. By default, the files of all jobs are removed.
+
+To stop the files building up on a machine that runs AzCopy regularly, remove only some of the jobs:
+use older-than to remove only old jobs, completed-only to keep any job that might still need to be resumed, and
+keep-last to always keep the most recent jobs. A job is only removed if it matches all the flags that are given.
+Use dry-run to see which jobs would be removed, before removing them.
+
 Note that you can customize the location where log and plan files are saved. See the env command to learn more.`
 
-const cleanJobsCmdExample = "  azcopy jobs clean --with-status=completed"
+const cleanJobsCmdExample = `Remove the jobs with the status Completed:
+
+  - azcopy jobs clean --with-status=completed
+
+Remove the jobs that ran to the end more than 30 days ago, but always keep the 10 most recent jobs:
+
+  - azcopy jobs clean --older-than=30 --completed-only --keep-last=10
+
+See which jobs that would remove, without removing them:
+
+  - azcopy jobs clean --older-than=30 --completed-only --keep-last=10 --dry-run`
 
 // ===================================== LIST COMMAND ===================================== //
 const listCmdShortDescription = "List the entities in a given resource"
@@ -304,6 +604,14 @@ Remove an entire virtual directory but exclude certain blobs from the scope (For
 
    - azcopy rm "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --exclude-pattern="foo*;*bar"
 
+Remove only the blobs that are tagged as belonging to a finished project (the service finds them by their tags, rather than every blob being listed):
+
+   - azcopy rm "https://[account].blob.core.windows.net/[container]?[SAS]" --recursive=true --include-tags="project=alpha;status=done"
+
+Remove only the tiny blobs, of 1 KiB or less, from a virtual directory:
+
+   - azcopy rm "https://[account].blob.core.windows.net/[container]/[path/to/directory]?[SAS]" --recursive=true --max-size=1KiB
+
 Remove specified version ids of a blob from Azure Storage. Ensure that source is a valid blob and versionidsfile which takes in a path to the file where each version is written on a separate line. All the specified versions will be removed from Azure Storage.
 
   - azcopy rm "https://[srcaccount].blob.core.windows.net/[containername]/[blobname]" "/path/to/dir" --list-of-versions="/path/to/dir/[versionidsfile]"
@@ -400,6 +708,26 @@ Sync an Azure File directory (same syntax as Blob):
 
    - azcopy sync "https://[account].file.core.windows.net/[share]/[path/to/dir]?[SAS]" "https://[account].file.core.windows.net/[share]/[path/to/dir]" --recursive=true
 
+See which files a sync would copy, and which extra files it would delete from the destination, without changing anything:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --delete-destination=true --dry-run
+
+Delete the extra files from the destination, but stop without deleting anything if more than 1 in 10 of its files would go (for example, when syncing from the wrong directory):
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --delete-destination=true --delete-destination-threshold=10
+
+Sync files restored from a backup, whose last-modified times can't be trusted, by comparing the MD5 hashes of their content instead:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]" --compare-hash=md5 --put-md5
+
+Keep a local directory and a container in sync both ways, so that changes made at either end are copied to the other, and files deleted at either end are deleted at the other:
+
+   - azcopy sync "/path/to/dir" "https://[account].blob.core.windows.net/[container]/[path/to/virtual/dir]?[SAS]" --two-way --delete-destination=true --conflict-resolution=NewestWins
+
+Make a container a mirror of another, replacing every blob that differs in size, content, properties or metadata, and deleting the extra ones:
+
+   - azcopy sync "https://[account].blob.core.windows.net/[container]?[SAS]" "https://[account].blob.core.windows.net/[container]?[SAS]" --mirror
+
 Note: if include and exclude flags are used together, only files matching the include patterns are used, but those matching the exclude patterns are ignored.
 `
 
@@ -474,3 +802,110 @@ Run an upload that does not delete the transferred files. (These files can then
 
    - azcopy bench "https://[account].blob.core.windows.net/[container]?<SAS>" --file-count 100 --delete-test-data=false
 `
+
+// ===================================== SERVE COMMAND ===================================== //
+const serveCmdShortDescription = "Run a server, on the local machine, that other programs can use to submit and manage jobs"
+
+const serveCmdLongDescription = `
+Run a server that lets other programs, such as graphical front ends and scripts, submit and manage jobs over HTTP.
+It only listens on the local machine, and each request must send the server's token in the header 'Authorization: Bearer <token>'.
+
+Each job runs in its own AzCopy process, just as if it had been started from the command line, so it can also be managed
+with the jobs commands. The responses are JSON, with the same content as the JSON output of the matching command:
+
+  - GET  /jobs                 lists the jobs, like "jobs list"
+  - POST /jobs                 starts a job, e.g. {"Args": ["copy", "/data", "https://[account].blob.core.windows.net/[container]?[SAS]", "--recursive"]},
+                               and responds as soon as it has started, with its job ID
+  - GET  /jobs/[jobID]         shows the job's progress, like "jobs show"
+  - POST /jobs/[jobID]/pause   pauses the job, like "jobs pause"
+  - POST /jobs/[jobID]/resume  resumes the job, like "jobs resume"
+  - POST /jobs/[jobID]/retry   retries the job's failed transfers, as a new job, like "jobs retry"
+  - POST /jobs/[jobID]/cancel  cancels the job
+  - POST /jobs/[jobID]/cancel-transfers
+                               cancels some of the job's transfers, e.g. {"Sources": ["videos/huge.mp4"]}, like "jobs cancel-transfers"`
+
+const serveCmdExample = `Run the server on port 8085:
+
+  - azcopy serve --port 8085
+
+Then, for example, list the jobs:
+
+  - curl -H "Authorization: Bearer [token]" http://127.0.0.1:8085/jobs`
+
+// ===================================== TEMPLATES COMMAND ===================================== //
+const templatesCmdShortDescription = "Sub-commands related to managing job templates"
+
+const templatesCmdLongDescription = `
+Sub-commands related to managing job templates.
+
+A job template is a saved copy, sync or remove command, for a job that is run again and again. Parts of the command that
+change from one run to the next, such as a folder name or a SAS token, can be left as placeholders, written as {{name}}.
+Run a template with the run command, giving a value for each placeholder.
+
+Templates are saved in the templates folder of the AzCopy folder, where only you can read them. Even so, it's better to
+leave SAS tokens as placeholders than to save them in templates.`
+
+const templatesCmdExample = `  - azcopy templates save nightly -- sync "/data/{{folder}}" "https://[account].blob.core.windows.net/backup/{{folder}}?{{sas}}" --recursive
+  - azcopy run nightly --param folder=projects --param sas="[SAS]"`
+
+const saveTemplatesCmdShortDescription = "Save a command as a job template, with the given name"
+
+const saveTemplatesCmdLongDescription = `
+Save a copy, sync or remove command as a job template, with the given name. Give the command after '--', just as it
+would be given to AzCopy, but with {{name}} placeholders for anything that is to be given when the template is run.`
+
+const saveTemplatesCmdExample = `Save a sync of one of the folders under /data:
+
+  - azcopy templates save nightly -- sync "/data/{{folder}}" "https://[account].blob.core.windows.net/backup/{{folder}}?{{sas}}" --recursive
+
+Replace it, with one that also deletes files that are no longer in the source:
+
+  - azcopy templates save nightly --force -- sync "/data/{{folder}}" "https://[account].blob.core.windows.net/backup/{{folder}}?{{sas}}" --recursive --delete-destination true`
+
+const listTemplatesCmdShortDescription = "List the job templates, with their commands and parameters"
+
+const removeTemplatesCmdShortDescription = "Remove the job template with the given name"
+
+// ===================================== RUN COMMAND ===================================== //
+const runCmdShortDescription = "Run the job template with the given name"
+
+const runCmdLongDescription = `
+Run the job template with the given name, filling in its placeholders with the values given by the param flags.
+Each of the template's placeholders must be given a value.
+
+Any other flags are added to the end of the template's command, so they can add to the saved flags, or override them
+(e.g. --dry-run, to see what the job would do, or --output-type json). The job then runs exactly as if the command had
+been typed in full.`
+
+const runCmdExample = `Run the template that was saved as "nightly", for the projects folder:
+
+  - azcopy run nightly --param folder=projects --param sas="[SAS]"
+
+See what it would do, without doing it:
+
+  - azcopy run nightly --param folder=projects --param sas="[SAS]" --dry-run`
+
+// ===================================== BATCH COMMAND ===================================== //
+const batchCmdShortDescription = "Run several copy, sync and remove jobs at once, in this one process"
+
+const batchCmdLongDescription = `
+Run several copy, sync and remove jobs at once, in this one process. The file gives the jobs' commands, one per line,
+just as they would be given to AzCopy (with or without "azcopy" at the start). Blank lines, and lines starting with #,
+are ignored. Arguments that contain spaces can be quoted with single or double quotes.
+
+The jobs share the process's connections, memory limit and bandwidth cap, which are divided between them as they run,
+instead of each job assuming that it has the machine to itself, as separate AzCopy processes would. So flags that
+govern the whole process, such as cap-mbps, cap-ops and memory-limit-gb, are given to the batch command, not to the jobs.
+Use each job's priority flag to choose which jobs get the most of what's shared.
+
+Every job is checked before any is started. The progress of all the jobs is shown together, and each job's summary is
+shown as it ends. The batch ends when the last job does, and it succeeds only if every job succeeded.`
+
+const batchCmdExample = `Given a file, jobs.txt, with the lines:
+
+  copy "/data/photos" "https://[account].blob.core.windows.net/photos?[SAS]" --recursive
+  sync "/data/documents" "https://[account].blob.core.windows.net/documents?[SAS]" --priority High
+
+Run both jobs, at up to 500 megabits per second between them:
+
+  - azcopy batch jobs.txt --cap-mbps 500`
@@ -0,0 +1,98 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// how often we look at the status of the job that a new job is waiting for
+const precedingJobCheckInterval = 5 * time.Second
+
+// waitForPrecedingJob holds back a job that was queued, with --after-job, behind another one, until that one has
+// completed successfully. The other job is usually being run by another AzCopy process, so we watch its status
+// in the job's plan files. If it fails, or is cancelled, or its process goes away, the new job doesn't start at all.
+// While it waits, the new job is recorded as queued, so that "jobs list" and "jobs show" can see it, and further
+// jobs can be queued behind it
+func waitForPrecedingJob(jobID common.JobID, precedingJobID common.JobID, commandString string) error {
+	setStatus := func(status common.JobStatus) error {
+		var resp common.UpdateQueuedJobResponse
+		Rpc(common.ERpcCmd.UpdateQueuedJob(), common.UpdateQueuedJobRequest{
+			JobID: jobID, AfterJob: precedingJobID, CommandString: commandString, JobStatus: status}, &resp)
+		if resp.ErrorMsg != "" {
+			return errors.New(resp.ErrorMsg)
+		}
+		return nil
+	}
+
+	if err := setStatus(common.EJobStatus.Queued()); err != nil {
+		return fmt.Errorf("cannot queue job %v: %v", jobID, err)
+	}
+	glcm.Info(fmt.Sprintf("Job %v is queued, and will start once job %v has completed", jobID, precedingJobID))
+
+	if err := waitForJobToComplete(precedingJobID); err != nil {
+		_ = setStatus(common.EJobStatus.Failed()) // so that the jobs queued behind this one don't start either
+		return err
+	}
+	// the job stays in progress, as far as its queue file is concerned, until its first plan file replaces it
+	return setStatus(common.EJobStatus.InProgress())
+}
+
+func waitForJobToComplete(precedingJobID common.JobID) error {
+	for {
+		var resp common.GetJobStatusResponse
+		Rpc(common.ERpcCmd.GetJobStatus(), precedingJobID, &resp)
+		if resp.ErrorMsg != "" {
+			return fmt.Errorf("cannot wait for job %v: %s", precedingJobID, resp.ErrorMsg)
+		}
+
+		done, err := precedingJobOutcome(precedingJobID, resp.JobStatus, resp.Abandoned)
+		if done || err != nil {
+			return err
+		}
+		time.Sleep(precedingJobCheckInterval)
+	}
+}
+
+// precedingJobOutcome returns whether the job that we're waiting for has completed successfully, or an error if it
+// has ended without doing so. A paused job isn't over, since it can still be resumed, so we carry on waiting for it.
+// But a job that's still in progress, or queued, with no process running it, would never end, so we give up on it
+func precedingJobOutcome(precedingJobID common.JobID, status common.JobStatus, abandoned bool) (done bool, err error) {
+	switch status {
+	case common.EJobStatus.Completed(), common.EJobStatus.CompletedWithSkipped():
+		return true, nil
+	case common.EJobStatus.InProgress(), common.EJobStatus.Queued(), common.EJobStatus.Cancelling():
+		if abandoned {
+			return false, fmt.Errorf("job %v, which this job was to start after, has status %v, but no AzCopy process is running it any more, "+
+				"so this job has not been started. Resume that job with 'azcopy jobs resume', then run this one again", precedingJobID, status)
+		}
+		return false, nil
+	case common.EJobStatus.Paused():
+		return false, nil
+	default:
+		return false, fmt.Errorf("job %v, which this job was to start after, ended with status %v, so this job has not been started",
+			precedingJobID, status)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func init() {
+	// cancel some of the transfers of a running job, and let the rest carry on
+	jobsCancelTransfersCmd := &cobra.Command{
+		Use:     "cancel-transfers [jobID] [source]...",
+		Short:   cancelTransfersJobsCmdShortDescription,
+		Long:    cancelTransfersJobsCmdLongDescription,
+		Example: cancelTransfersJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("cancel-transfers command requires the JobID, and the source of at least one transfer")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			jobID, err := common.ParseJobID(args[0])
+			if err != nil {
+				glcm.Error(fmt.Sprintf("error parsing the jobId %s. Failed with error %s", args[0], err.Error()))
+				return
+			}
+
+			var resp common.CancelTransfersResponse
+			Rpc(common.ERpcCmd.CancelTransfers(), common.CancelTransfersRequest{JobID: jobID, Sources: args[1:]}, &resp)
+			if resp.ErrorMsg != "" {
+				glcm.Error(resp.ErrorMsg)
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(resp)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+				return fmt.Sprintf("%d transfers of job %s will be cancelled. The job picks up the cancellations within a few seconds",
+					resp.TransfersCancelled, jobID)
+			}, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsCancelTransfersCmd)
+}
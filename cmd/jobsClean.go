@@ -23,7 +23,9 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -32,7 +34,11 @@ import (
 
 func init() {
 	type JobsCleanReq struct {
-		withStatus string
+		withStatus    string
+		olderThanDays int
+		completedOnly bool
+		keepLast      int
+		dryRun        bool
 	}
 
 	commandLineInput := JobsCleanReq{}
@@ -48,6 +54,12 @@ func init() {
 			if len(args) != 0 {
 				return errors.New("clean command does not accept arguments")
 			}
+			if commandLineInput.olderThanDays < 0 {
+				return errors.New("older-than must not be negative")
+			}
+			if commandLineInput.keepLast < 0 {
+				return errors.New("keep-last must not be negative")
+			}
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
@@ -57,15 +69,26 @@ func init() {
 				glcm.Error(fmt.Sprintf("Failed to parse --with-status due to error: %s.", err))
 			}
 
-			err = handleCleanJobsCommand(withStatus)
+			options := jobsCleanOptions{
+				withStatus:    withStatus,
+				olderThan:     time.Duration(commandLineInput.olderThanDays) * 24 * time.Hour,
+				completedOnly: commandLineInput.completedOnly,
+				keepLast:      commandLineInput.keepLast,
+				dryRun:        commandLineInput.dryRun,
+			}
+			err = handleCleanJobsCommand(options)
 			if err == nil {
-				if withStatus == common.EJobStatus.All() {
+				if options.dryRun {
+					glcm.Exit(func(format common.OutputFormat) string {
+						return "Dry run complete. No files were removed."
+					}, common.EExitCode.Success())
+				} else if options.isUnfiltered() {
 					glcm.Exit(func(format common.OutputFormat) string {
 						return fmt.Sprintf("Successfully removed all jobs.")
 					}, common.EExitCode.Success())
 				} else {
 					glcm.Exit(func(format common.OutputFormat) string {
-						return fmt.Sprintf("Successfully removed jobs with status: %s.", withStatus)
+						return fmt.Sprintf("Successfully removed the selected jobs.")
 					}, common.EExitCode.Success())
 				}
 			} else {
@@ -80,10 +103,33 @@ func init() {
 	jobsCleanCmd.PersistentFlags().StringVar(&commandLineInput.withStatus, "with-status", "All",
 		"only remove the jobs with this status, available values: All, Cancelled, Failed, Completed"+
 			" CompletedWithErrors, CompletedWithSkipped, CompletedWithErrorsAndSkipped")
+	jobsCleanCmd.PersistentFlags().IntVar(&commandLineInput.olderThanDays, "older-than", 0,
+		"only remove the jobs that started more than this many days ago. 0 (the default) means jobs of any age")
+	jobsCleanCmd.PersistentFlags().BoolVar(&commandLineInput.completedOnly, "completed-only", false,
+		"only remove the jobs that ran to the end (with or without errors). Jobs that are in progress, paused, cancelled or failed are kept, so that they can still be resumed")
+	jobsCleanCmd.PersistentFlags().IntVar(&commandLineInput.keepLast, "keep-last", 0,
+		"always keep this many of the most recently started jobs, whatever the other flags say. 0 (the default) means none are kept")
+	jobsCleanCmd.PersistentFlags().BoolVar(&commandLineInput.dryRun, "dry-run", false,
+		"list the jobs that would be removed, without removing anything")
+}
+
+// jobsCleanOptions says which jobs "jobs clean" removes. A job must match all of them to be removed
+type jobsCleanOptions struct {
+	withStatus    common.JobStatus
+	olderThan     time.Duration // zero means any age
+	completedOnly bool
+	keepLast      int // the number of most recent jobs to keep
+	dryRun        bool
+}
+
+// isUnfiltered is true if every job is to be removed, in which case the files can simply be deleted without
+// looking inside them
+func (o jobsCleanOptions) isUnfiltered() bool {
+	return o.withStatus == common.EJobStatus.All() && o.olderThan == 0 && !o.completedOnly && o.keepLast == 0
 }
 
-func handleCleanJobsCommand(givenStatus common.JobStatus) error {
-	if givenStatus == common.EJobStatus.All() {
+func handleCleanJobsCommand(options jobsCleanOptions) error {
+	if options.isUnfiltered() && !options.dryRun {
 		numFilesDeleted, err := blindDeleteAllJobFiles()
 		glcm.Info(fmt.Sprintf("Removed %v files.", numFilesDeleted))
 		return err
@@ -97,20 +143,62 @@ func handleCleanJobsCommand(givenStatus common.JobStatus) error {
 		return errors.New("failed to query the list of jobs")
 	}
 
-	for _, job := range resp.JobIDDetails {
-		// delete all jobs matching the givenStatus
-		if job.JobStatus == givenStatus {
-			glcm.Info(fmt.Sprintf("Removing files for job %s", job.JobId))
-			err := handleRemoveSingleJob(job.JobId)
-			if err != nil {
-				return err
-			}
+	for _, job := range selectJobsToClean(resp.JobIDDetails, options, time.Now()) {
+		if options.dryRun {
+			glcm.Info(fmt.Sprintf("Would remove files for job %s (status %s, started %s)",
+				job.JobId, job.JobStatus, time.Unix(0, job.StartTime).Format(time.RFC3339)))
+			continue
+		}
+
+		glcm.Info(fmt.Sprintf("Removing files for job %s", job.JobId))
+		err := handleRemoveSingleJob(job.JobId)
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// selectJobsToClean returns the jobs that the options say should be removed
+func selectJobsToClean(jobs []common.JobIDDetails, options jobsCleanOptions, now time.Time) []common.JobIDDetails {
+	// the most recent jobs come first, so that the ones to keep are at the start
+	sorted := make([]common.JobIDDetails, len(jobs))
+	copy(sorted, jobs)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartTime > sorted[j].StartTime })
+
+	selected := make([]common.JobIDDetails, 0)
+	for i, job := range sorted {
+		if i < options.keepLast {
+			continue
+		}
+		if options.withStatus != common.EJobStatus.All() && job.JobStatus != options.withStatus {
+			continue
+		}
+		if options.completedOnly && !isCompletedJobStatus(job.JobStatus) {
+			continue
+		}
+		if options.olderThan > 0 && now.Sub(time.Unix(0, job.StartTime)) <= options.olderThan {
+			continue
+		}
+		selected = append(selected, job)
+	}
+	return selected
+}
+
+// isCompletedJobStatus is true for jobs that ran to the end, even if some of their transfers failed or were skipped
+func isCompletedJobStatus(status common.JobStatus) bool {
+	switch status {
+	case common.EJobStatus.Completed(),
+		common.EJobStatus.CompletedWithErrors(),
+		common.EJobStatus.CompletedWithSkipped(),
+		common.EJobStatus.CompletedWithErrorsAndSkipped():
+		return true
+	default:
+		return false
+	}
+}
+
 func blindDeleteAllJobFiles() (int, error) {
 	// get rid of the job plan files
 	numPlanFilesRemoved, err := removeFilesWithPredicate(azcopyJobPlanFolder, func(s string) bool {
@@ -125,7 +213,7 @@ func blindDeleteAllJobFiles() (int, error) {
 
 	// get rid of the logs
 	numLogFilesRemoved, err := removeFilesWithPredicate(azcopyLogPathFolder, func(s string) bool {
-		if strings.HasSuffix(s, ".log") {
+		if strings.HasSuffix(s, ".log") || strings.HasSuffix(s, ".metrics.json") {
 			return true
 		}
 		return false
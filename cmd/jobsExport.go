@@ -0,0 +1,103 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// exportJob writes the job's bundle to a new file. The owner alone can read it, since it lists everything the job transfers
+func exportJob(jobID common.JobID, path string) (common.JobBundle, error) {
+	var resp common.ExportJobResponse
+	Rpc(common.ERpcCmd.ExportJob(), jobID, &resp)
+	if resp.ErrorMsg != "" {
+		return resp.Bundle, errors.New(resp.ErrorMsg)
+	}
+
+	content, err := json.MarshalIndent(resp.Bundle, "", "  ")
+	if err != nil {
+		return resp.Bundle, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return resp.Bundle, fmt.Errorf("%s already exists. Remove it, or give another file", path)
+	} else if err != nil {
+		return resp.Bundle, err
+	}
+	if _, err = file.Write(content); err != nil {
+		file.Close()
+		return resp.Bundle, err
+	}
+	return resp.Bundle, file.Close()
+}
+
+// describeJobBundle summarizes the job in a bundle, without its parts
+func describeJobBundle(b common.JobBundle) string {
+	return fmt.Sprintf("Job %v (%s), from %s to %s\nStatus: %v\nTransfers: %d, of which %d completed and %d failed\nCommand: %s",
+		b.JobID, b.FromTo, b.Source, b.Destination, b.JobStatus, b.TotalTransfers, b.TransfersCompleted, b.TransfersFailed, b.CommandString)
+}
+
+func init() {
+	// write a job to a file, so that it can be carried on by AzCopy on another machine
+	jobsExportCmd := &cobra.Command{
+		Use:     "export [jobID] [file]",
+		Short:   exportJobsCmdShortDescription,
+		Long:    exportJobsCmdLongDescription,
+		Example: exportJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("export job command requires the JobID, and the file to export the job to")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			jobID, err := common.ParseJobID(args[0])
+			if err != nil {
+				glcm.Error(fmt.Sprintf("error parsing the jobId %s. Failed with error %s", args[0], err.Error()))
+				return
+			}
+
+			bundle, err := exportJob(jobID, args[1])
+			if err != nil {
+				glcm.Error("cannot export the job: " + err.Error())
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				bundle.Parts = nil // they're in the file, and they're not for reading
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(bundle)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+				return fmt.Sprintf("Exported to %s:\n%s", args[1], describeJobBundle(bundle))
+			}, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsExportCmd)
+}
@@ -0,0 +1,124 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type importJobCmdArgs struct {
+	source      string
+	destination string
+}
+
+// newRoot puts a new source or destination of an imported job in the form that plans keep them in, without any SAS
+func (importJobCmdArgs) newRoot(raw string, location common.Location) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	if location == common.ELocation.Local() {
+		abs, err := filepath.Abs(raw)
+		if err != nil {
+			return "", err
+		}
+		raw = abs
+	}
+	resource, err := SplitResourceString(raw, location)
+	return resource.Value, err
+}
+
+// importJob reads the bundle from the file, and imports its job
+func (args importJobCmdArgs) importJob(path string) (common.JobBundle, common.ImportJobResponse, error) {
+	var req common.ImportJobRequest
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return req.Bundle, common.ImportJobResponse{}, err
+	}
+	if err = json.Unmarshal(content, &req.Bundle); err != nil {
+		return req.Bundle, common.ImportJobResponse{}, fmt.Errorf("%s isn't an exported job: %w", path, err)
+	}
+
+	var fromTo common.FromTo
+	if err = fromTo.Parse(req.Bundle.FromTo); err != nil {
+		return req.Bundle, common.ImportJobResponse{}, fmt.Errorf("%s isn't an exported job: %w", path, err)
+	}
+	if req.Source, err = args.newRoot(args.source, fromTo.From()); err != nil {
+		return req.Bundle, common.ImportJobResponse{}, fmt.Errorf("invalid source: %w", err)
+	}
+	if req.Destination, err = args.newRoot(args.destination, fromTo.To()); err != nil {
+		return req.Bundle, common.ImportJobResponse{}, fmt.Errorf("invalid destination: %w", err)
+	}
+
+	var resp common.ImportJobResponse
+	Rpc(common.ERpcCmd.ImportJob(), &req, &resp)
+	if resp.ErrorMsg != "" {
+		return req.Bundle, resp, errors.New(resp.ErrorMsg)
+	}
+	return req.Bundle, resp, nil
+}
+
+func init() {
+	importCmdArgs := importJobCmdArgs{}
+
+	// import a job that was exported on another machine, so that it can be resumed here
+	jobsImportCmd := &cobra.Command{
+		Use:     "import [file]",
+		Short:   importJobsCmdShortDescription,
+		Long:    importJobsCmdLongDescription,
+		Example: importJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("import job command requires the file that the job was exported to")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			bundle, resp, err := importCmdArgs.importJob(args[0])
+			if err != nil {
+				glcm.Error("cannot import the job: " + err.Error())
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(resp)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+				return fmt.Sprintf("Imported job %v, with %d transfers left to do. Carry it on with: azcopy jobs resume %v",
+					bundle.JobID, resp.TransfersLeft, bundle.JobID)
+			}, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsImportCmd)
+	jobsImportCmd.PersistentFlags().StringVar(&importCmdArgs.source, "source", "", "The new location of the job's source, "+
+		"if it's somewhere else on this machine.")
+	jobsImportCmd.PersistentFlags().StringVar(&importCmdArgs.destination, "destination", "", "The new location of the job's destination, "+
+		"if it's somewhere else on this machine.")
+}
@@ -25,11 +25,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-azcopy/ste"
+	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/spf13/cobra"
 )
 
@@ -49,6 +51,9 @@ type resumeJobController struct {
 
 	// used to calculate job summary
 	jobStartTime time.Time
+
+	// only set if the progress bar was requested
+	progressBar *progressBar
 }
 
 // wraps call to lifecycle manager to wait for the job to complete
@@ -56,11 +61,14 @@ type resumeJobController struct {
 // if blocking is specified to false, then another goroutine spawns and wait out the job
 func (cca *resumeJobController) waitUntilJobCompletion(blocking bool) {
 	// print initial message to indicate that the job is starting
-	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID), false, ""))
+	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), jobLogFilePath(cca.jobID), false, ""))
 
 	// initialize the times necessary to track progress
 	cca.jobStartTime = time.Now()
 	cca.intervalStartTime = time.Now()
+	if showProgressBar {
+		cca.progressBar = newProgressBar(cca.jobStartTime)
+	}
 	cca.intervalBytesTransferred = 0
 
 	// hand over control to the lifecycle manager if blocking
@@ -85,10 +93,8 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 	// fetch a job status
 	var summary common.ListJobSummaryResponse
 	Rpc(common.ERpcCmd.ListJobSummary(), &cca.jobID, &summary)
-	glcmSwapOnce.Do(func() {
-		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &glcm)
-	})
-	jobDone := summary.JobStatus.IsJobDone()
+	Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &lcm)
+	jobDone := summary.JobStatus.HasStopped()
 	totalKnownCount = summary.TotalTransfers
 
 	// if json is not desired, and job is done, then we generate a special end message to conclude the job
@@ -99,6 +105,11 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 		if summary.TransfersFailed > 0 {
 			exitCode = common.EExitCode.Error()
 		}
+		summary.FinalJobSummary = common.NewFinalJobSummary(summary, duration, exitCode)
+		summary.FinalJobSummary.LogFileLocation = jobLogFilePath(summary.JobID)
+		if summary.JobStatus == common.EJobStatus.Paused() {
+			lcm.Info(common.Localize(common.EMessageKey.JobPaused(), summary.JobID, summary.JobID))
+		}
 
 		lcm.Exit(func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
@@ -106,8 +117,7 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 				common.PanicIfErr(err)
 				return string(jsonOutput)
 			}
-			return fmt.Sprintf(
-				"\n\nJob %s summary\nElapsed Time (Minutes): %v\nNumber of File Transfers: %v\nNumber of Folder Property Transfers: %v\nTotal Number Of Transfers: %v\nNumber of Transfers Completed: %v\nNumber of Transfers Failed: %v\nNumber of Transfers Skipped: %v\nTotalBytesTransferred: %v\nFinal Job Status: %v\n",
+			return common.Localize(common.EMessageKey.ResumeJobSummary(),
 				summary.JobID.String(),
 				ste.ToFixed(duration.Minutes(), 4),
 				summary.FileTransfers,
@@ -117,7 +127,7 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 				summary.TransfersFailed,
 				summary.TransfersSkipped,
 				summary.TotalBytesTransferred,
-				summary.JobStatus)
+				summary.JobStatus) + formatFinalJobSummary(summary.FinalJobSummary)
 		}, exitCode)
 	}
 
@@ -133,12 +143,15 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 		return common.Iffloat64(timeElapsed != 0, bytesInMb/timeElapsed, 0) * 8
 	}
 
-	glcm.Progress(func(format common.OutputFormat) string {
+	lcm.Progress(func(format common.OutputFormat) string {
 		if format == common.EOutputFormat.Json() {
 			jsonOutput, err := json.Marshal(summary)
 			common.PanicIfErr(err)
 			return string(jsonOutput)
 		}
+		if cca.progressBar != nil {
+			return cca.progressBar.render(summary, time.Now(), getTerminalWidth())
+		}
 		// if json is not needed, then we generate a message that goes nicely on the same line
 		// display a scanning keyword if the job is not completely ordered
 		var scanningString = " (scanning...)"
@@ -156,11 +169,13 @@ func (cca *resumeJobController) ReportProgressOrExit(lcm common.LifecycleMgr) (t
 		// indicate whether constrained by disk or not
 		perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, false)
 
-		return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Skipped, %v Total%s, %s%s%s",
+		return fmt.Sprintf("%.1f %%%s, %v Done, %v Failed, %v Pending%s, %v Skipped, %v Total%s, %s%s%s",
 			summary.PercentComplete,
+			getByteProgressText(summary),
 			summary.TransfersCompleted,
 			summary.TransfersFailed,
 			summary.TotalTransfers-(summary.TransfersCompleted+summary.TransfersFailed+summary.TransfersSkipped),
+			getStalledText(summary)+getThrottledText(summary),
 			summary.TransfersSkipped, summary.TotalTransfers, scanningString, perfString, throughputString, diskString)
 	})
 	return
@@ -201,8 +216,10 @@ func init() {
 	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.excludeTransfer, "exclude", "", "Filter: exclude these failed transfer(s) when resuming the job. "+
 		"Files should be separated by ';'.")
 	// oauth options
-	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.SourceSAS, "source-sas", "", "Source SAS token of the source for a given Job ID.")
-	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.DestinationSAS, "destination-sas", "", "destination SAS token of the destination for a given Job ID.")
+	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.SourceSAS, "source-sas", "", "Source SAS token of the source for a given Job ID. "+
+		"SAS tokens are not saved with the job, so give one whenever the source needs it, e.g. a new one if the original has expired.")
+	resumeCmd.PersistentFlags().StringVar(&resumeCmdArgs.DestinationSAS, "destination-sas", "", "destination SAS token of the destination for a given Job ID. "+
+		"SAS tokens are not saved with the job, so give one whenever the destination needs it, e.g. a new one if the original has expired.")
 }
 
 type resumeCmdArgs struct {
@@ -272,6 +289,15 @@ func (rca resumeCmdArgs) process() error {
 		return errors.New("resuming benchmark jobs is not supported")
 	}
 
+	// SAS tokens aren't kept in the plan files, so a resumed job uses the ones given here. One that has already
+	// expired would just make every remaining transfer fail with a 403, so catch that now
+	if err = validateResumeSAS("source-sas", rca.SourceSAS, time.Now()); err != nil {
+		return err
+	}
+	if err = validateResumeSAS("destination-sas", rca.DestinationSAS, time.Now()); err != nil {
+		return err
+	}
+
 	ctx := context.TODO()
 	// Initialize credential info.
 	credentialInfo := common.CredentialInfo{}
@@ -287,8 +313,10 @@ func (rca resumeCmdArgs) process() error {
 	} else if credentialInfo.CredentialType == common.ECredentialType.OAuthToken() {
 		uotm := GetUserOAuthTokenManagerInstance()
 		// Get token from env var or cache.
-		if tokenInfo, err := uotm.GetTokenInfo(ctx); err != nil {
-			return err
+		tokenInfo, err := uotm.GetTokenInfo(ctx)
+		if err != nil {
+			// the token the job was started with can't be used any more (e.g. its refresh token has expired too)
+			return fmt.Errorf("cannot get a fresh OAuth token for the job: %v. Log in again, with azcopy login, then resume the job", err)
 		}
 		credentialInfo.OAuthTokenInfo = *tokenInfo
 	}
@@ -315,3 +343,21 @@ func (rca resumeCmdArgs) process() error {
 
 	return nil
 }
+
+// validateResumeSAS returns an error if the SAS given with the named flag has already expired
+func validateResumeSAS(flagName string, sas string, now time.Time) error {
+	if sas == "" {
+		return nil
+	}
+	u, err := url.Parse("https://resume/?" + strings.TrimPrefix(sas, "?"))
+	if err != nil {
+		return fmt.Errorf("the SAS given with --%s is not valid: %v", flagName, err)
+	}
+	parts := azblob.NewBlobURLParts(*u)
+	expiry := parts.SAS.ExpiryTime()
+	if !expiry.IsZero() && !expiry.After(now) {
+		return fmt.Errorf("the SAS given with --%s expired at %v. Generate a new SAS, and give it with --%s to resume the job",
+			flagName, expiry.Local().Format(time.RFC1123), flagName)
+	}
+	return nil
+}
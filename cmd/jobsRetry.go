@@ -0,0 +1,76 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func init() {
+	retryCmdArgs := resumeCmdArgs{}
+
+	// run the failed transfers of a job again, as a new job
+	jobsRetryCmd := &cobra.Command{
+		Use:     "retry [jobID]",
+		Short:   retryJobsCmdShortDescription,
+		Long:    retryJobsCmdLongDescription,
+		Example: retryJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("retry job command requires the JobID")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			jobID, err := common.ParseJobID(args[0])
+			if err != nil {
+				glcm.Error(fmt.Sprintf("error parsing the jobId %s. Failed with error %s", args[0], err.Error()))
+				return
+			}
+
+			var resp common.RetryJobResponse
+			Rpc(common.ERpcCmd.RetryJob(), jobID, &resp)
+			if resp.ErrorMsg != "" {
+				glcm.Error(resp.ErrorMsg)
+				return
+			}
+			glcm.Info(fmt.Sprintf("Retrying the %d failed transfers of job %v, as the new job %v", resp.TransfersToRetry, jobID, resp.JobID))
+
+			// the new job is complete, apart from its credentials, so it's run just like a job that was stopped part way through
+			retryCmdArgs.jobID = resp.JobID.String()
+			if err = retryCmdArgs.process(); err != nil {
+				glcm.Error(fmt.Sprintf("failed to perform retry command due to error: %s", err.Error()))
+			}
+			glcm.Exit(nil, common.EExitCode.Success())
+		},
+	}
+
+	jobsCmd.AddCommand(jobsRetryCmd)
+	jobsRetryCmd.PersistentFlags().StringVar(&retryCmdArgs.SourceSAS, "source-sas", "", "Source SAS token of the source for the job. "+
+		"SAS tokens are not saved with the job, so give one whenever the source needs it.")
+	jobsRetryCmd.PersistentFlags().StringVar(&retryCmdArgs.DestinationSAS, "destination-sas", "", "Destination SAS token of the destination for the job. "+
+		"SAS tokens are not saved with the job, so give one whenever the destination needs it.")
+}
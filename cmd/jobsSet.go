@@ -0,0 +1,72 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+func init() {
+	// change the settings of a running job
+	jobsSetCmd := &cobra.Command{
+		Use:     "set [jobID]",
+		Short:   setJobsCmdShortDescription,
+		Long:    setJobsCmdLongDescription,
+		Example: setJobsCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("set job command requires the JobID")
+			}
+			if !cmd.Flags().Changed("cap-mbps") {
+				return errors.New("set job command requires the new setting, e.g. --cap-mbps")
+			}
+			if cmdLineCapMegaBitsPerSecond < 0 {
+				return errors.New("cap-mbps must not be negative")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			jobID, err := common.ParseJobID(args[0])
+			if err != nil {
+				glcm.Error(fmt.Sprintf("error parsing the jobId %s. Failed with error %s", args[0], err.Error()))
+				return
+			}
+
+			var resp common.SetJobSettingsResponse
+			Rpc(common.ERpcCmd.SetJobSettings(), common.SetJobSettingsRequest{JobID: jobID, CapMbps: cmdLineCapMegaBitsPerSecond}, &resp)
+			if resp.ErrorMsg != "" {
+				glcm.Error(resp.ErrorMsg)
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return fmt.Sprintf("The new settings have been sent to job %s. The job picks them up within a few seconds", jobID)
+			}, common.EExitCode.Success())
+		},
+	}
+
+	// the new cap is given with the cap-mbps flag that applies to all commands
+	jobsCmd.AddCommand(jobsSetCmd)
+}
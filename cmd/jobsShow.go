@@ -23,8 +23,12 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
+	"encoding/csv"
 	"encoding/json"
 
 	"github.com/Azure/azure-storage-azcopy/common"
@@ -34,6 +38,7 @@ import (
 type ListReq struct {
 	JobID    common.JobID
 	OfStatus string
+	Export   string
 }
 
 func init() {
@@ -61,7 +66,7 @@ func init() {
 			listRequest.JobID = commandLineInput.JobID
 			listRequest.OfStatus = commandLineInput.OfStatus
 
-			err := HandleShowCommand(listRequest)
+			err := HandleShowCommand(listRequest, commandLineInput.Export)
 			if err == nil {
 				glcm.Exit(nil, common.EExitCode.Success())
 			} else {
@@ -74,12 +79,22 @@ func init() {
 
 	// filters
 	shJob.PersistentFlags().StringVar(&commandLineInput.OfStatus, "with-status", "", "Only list the transfers of job with this status, available values: Started, Success, Failed.")
+	shJob.PersistentFlags().StringVar(&commandLineInput.Export, "export", "", "Also write the listed transfers to this file. "+
+		"The file name must end in .csv or .json, which sets the file's format. Can only be used with the with-status flag.")
 }
 
 // handles the list command
 // dispatches the list order to the transfer engine
-func HandleShowCommand(listRequest common.ListRequest) error {
+func HandleShowCommand(listRequest common.ListRequest, exportPath string) error {
 	rpcCmd := common.ERpcCmd.None()
+	if listRequest.OfStatus == "" && exportPath != "" {
+		return errors.New("the export flag can only be used with the with-status flag")
+	}
+	if exportPath != "" {
+		if _, err := exportFormatOf(exportPath); err != nil {
+			return err
+		}
+	}
 	if listRequest.OfStatus == "" {
 		resp := common.ListJobSummaryResponse{}
 		rpcCmd = common.ERpcCmd.ListJobSummary()
@@ -97,11 +112,56 @@ func HandleShowCommand(listRequest common.ListRequest) error {
 		resp := common.ListJobTransfersResponse{}
 		rpcCmd = common.ERpcCmd.ListJobTransfers()
 		Rpc(rpcCmd, lsRequest, &resp)
+		if exportPath != "" && resp.ErrorMsg == "" {
+			if err := exportJobTransfers(exportPath, resp); err != nil {
+				return fmt.Errorf("cannot export the transfers to %s: %v", exportPath, err)
+			}
+		}
 		PrintJobTransfers(resp)
 	}
 	return nil
 }
 
+// exportFormatOf says which format the transfers should be exported in, going by the file's extension
+func exportFormatOf(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv", ".json":
+		return ext[1:], nil
+	default:
+		return "", fmt.Errorf("cannot export to %s, because the file name must end in .csv or .json", path)
+	}
+}
+
+// exportJobTransfers writes the listed transfers to a file, so that (for example) the failures can be worked through
+// in a spreadsheet, or used by a script to decide what to re-run
+func exportJobTransfers(path string, listTransfersResponse common.ListJobTransfersResponse) error {
+	format, err := exportFormatOf(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if format == "json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listTransfersResponse.Details)
+	}
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"Source", "Destination", "IsFolderProperties", "TransferStatus", "ErrorCode", "ErrorMessage", "RetryCount"})
+	for _, d := range listTransfersResponse.Details {
+		_ = writer.Write([]string{d.Src, d.Dst, strconv.FormatBool(d.IsFolderProperties), d.TransferStatus.String(),
+			strconv.Itoa(int(d.ErrorCode)), d.ErrorMessage, strconv.Itoa(int(d.RetryCount))})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
 // PrintJobTransfers prints the response of listOrder command when list Order command requested the list of specific transfer of an existing job
 func PrintJobTransfers(listTransfersResponse common.ListJobTransfersResponse) {
 	if listTransfersResponse.ErrorMsg != "" {
@@ -124,6 +184,13 @@ func PrintJobTransfers(listTransfersResponse common.ListJobTransfersResponse) {
 			}
 			sb.WriteString("transfer--> source: " + listTransfersResponse.Details[index].Src + folderChar + " destination: " +
 				listTransfersResponse.Details[index].Dst + folderChar + " status " + listTransfersResponse.Details[index].TransferStatus.String() + "\n")
+			if d := listTransfersResponse.Details[index]; d.TransferStatus <= common.ETransferStatus.Failed() {
+				sb.WriteString(fmt.Sprintf("    HTTP status: %d, retries: %d", d.ErrorCode, d.RetryCount))
+				if d.ErrorMessage != "" {
+					sb.WriteString(", error: " + d.ErrorMessage)
+				}
+				sb.WriteString("\n")
+			}
 		}
 
 		return sb.String()
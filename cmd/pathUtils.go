@@ -55,6 +55,18 @@ func determineLocationLevel(location string, locationType common.Location, sourc
 		}
 	case common.ELocation.Benchmark():
 		return ELocationLevel.Object(), nil // we always benchmark to a subfolder, not the container root
+	case common.ELocation.SFTP():
+		// as in local, there's no such thing as a service, and a path with a trailing slash is a folder
+		if strings.HasSuffix(location, "/") {
+			return ELocationLevel.Container(), nil
+		}
+		return ELocationLevel.Object(), nil
+	case common.ELocation.HTTP():
+		// a web server can't be listed, so a URL with a trailing slash is only a base for --list-of-files
+		if strings.HasSuffix(location, "/") {
+			return ELocationLevel.Container(), nil
+		}
+		return ELocationLevel.Object(), nil
 
 	case common.ELocation.Blob(),
 		common.ELocation.File(),
@@ -103,6 +115,10 @@ func GetResourceRoot(resource string, location common.Location) (resourceBase st
 		return resource, nil
 	case common.ELocation.Local():
 		return cleanLocalPath(getPathBeforeFirstWildcard(resource)), nil
+	case common.ELocation.SFTP():
+		return resource, nil // SFTP paths have no wildcards
+	case common.ELocation.HTTP():
+		return resource, nil // nor do plain HTTP(S) URLs
 
 	//noinspection GoNilness
 	case common.ELocation.Blob():
@@ -152,7 +168,9 @@ func GetResourceRoot(resource string, location common.Location) (resourceBase st
 	// noinspection GoNilness
 	case common.ELocation.S3():
 		s3URLParts, err := common.NewS3URLParts(*resourceURL)
-		common.PanicIfErr(err)
+		if err != nil {
+			return resource, err
+		}
 
 		if s3URLParts.BucketName == "" || strings.Contains(s3URLParts.BucketName, "*") {
 			if s3URLParts.ObjectKey != "" {
@@ -204,7 +222,23 @@ func splitAuthTokenFromResource(resource string, location common.Location) (reso
 
 		*baseURL = common.URLExtension{URL: *baseURL}.URLWithPlusDecodedInPath()
 		return baseURL.String(), "", nil
+	case common.ELocation.HTTP():
+		// we can't tell which parameters of the query authorize the request (e.g. a pre-signed URL's signature),
+		// so, to keep them out of the plan files, we treat the whole query as the token, just like a SAS
+		var baseURL *url.URL
+		baseURL, err = url.Parse(resource)
+
+		if err != nil {
+			return resource, "", err
+		}
+
+		resourceToken = baseURL.RawQuery
+		baseURL.RawQuery = ""
+		baseURL.ForceQuery = false
+		resourceBase = baseURL.String()
+		return
 	case common.ELocation.Benchmark(), // cover for benchmark as we generate data for that
+		common.ELocation.SFTP(),    // cover for SFTP as its credentials come from the environment
 		common.ELocation.Unknown(): // cover for unknown as we treat that as garbage
 		// Local and S3 don't feature URL-embedded tokens
 		return resource, "", nil
@@ -69,10 +69,15 @@ func HandlePauseCommand(jobIdString string) {
 	if err != nil {
 		// If parsing gives an error, hence it is not a valid JobId format
 		glcm.Error("invalid jobId string passed. Failed while parsing string to jobId")
+		return
 	}
 
 	var pauseJobResponse common.CancelPauseResumeResponse
 	Rpc(common.ERpcCmd.PauseJob(), jobID, &pauseJobResponse)
+	if !pauseJobResponse.CancelledPauseResumed {
+		glcm.Error(pauseJobResponse.ErrorMsg)
+		return
+	}
 	glcm.Exit(func(format common.OutputFormat) string {
 		return "Job " + jobID.String() + " paused successfully"
 	}, common.EExitCode.Success())
@@ -0,0 +1,152 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// the ETA is based on the throughput over this long, so that it follows changes in speed without jumping about
+const progressBarEtaWindow = 30 * time.Second
+
+const defaultTerminalWidth = 80
+
+// progressBar renders the progress of a job as a bar, with throughput and an estimate of the time remaining, e.g.
+//
+//	[=========>          ]  47.5% | 12/30 files | 85.3 MB/s (avg 70.1 MB/s) | ETA 1m20s
+type progressBar struct {
+	jobStartTime time.Time
+	samples      []progressSample // oldest first, covering the ETA window
+}
+
+type progressSample struct {
+	at    time.Time
+	bytes uint64
+}
+
+func newProgressBar(jobStartTime time.Time) *progressBar {
+	return &progressBar{jobStartTime: jobStartTime}
+}
+
+// record remembers how many bytes were done at the given time, and forgets samples that are too old to matter
+func (p *progressBar) record(now time.Time, bytes uint64) {
+	p.samples = append(p.samples, progressSample{at: now, bytes: bytes})
+
+	// keep one sample from before the window, so there's always a full window to measure over
+	for len(p.samples) > 2 && now.Sub(p.samples[1].at) >= progressBarEtaWindow {
+		p.samples = p.samples[1:]
+	}
+}
+
+// bytes per second between the two samples
+func bytesPerSecond(from, to progressSample) float64 {
+	elapsed := to.at.Sub(from.at).Seconds()
+	if elapsed <= 0 || to.bytes < from.bytes {
+		return 0
+	}
+	return float64(to.bytes-from.bytes) / elapsed
+}
+
+func formatMegabytesPerSecond(bytesPerSec float64) string {
+	return fmt.Sprintf("%.1f MB/s", bytesPerSec/base10Mega)
+}
+
+func (p *progressBar) render(summary common.ListJobSummaryResponse, now time.Time, width int) string {
+	p.record(now, summary.TotalBytesTransferred)
+
+	var instantRate, windowRate float64
+	if n := len(p.samples); n >= 2 {
+		instantRate = bytesPerSecond(p.samples[n-2], p.samples[n-1])
+		windowRate = bytesPerSecond(p.samples[0], p.samples[n-1])
+	}
+	averageRate := bytesPerSecond(progressSample{at: p.jobStartTime}, p.samples[len(p.samples)-1])
+
+	// until scanning is complete, we don't know how much there is left to do
+	eta := "ETA --"
+	if summary.CompleteJobOrdered && summary.TotalBytesExpected >= summary.TotalBytesTransferred && windowRate > 0 {
+		remaining := time.Duration(float64(summary.TotalBytesExpected-summary.TotalBytesTransferred) / windowRate * float64(time.Second))
+		eta = "ETA " + remaining.Round(time.Second).String()
+	}
+
+	stats := fmt.Sprintf(" %5.1f%% | %v/%v files | %s (avg %s) | %s",
+		summary.PercentComplete,
+		summary.TransfersCompleted+summary.TransfersFailed+summary.TransfersSkipped,
+		summary.TotalTransfers,
+		formatMegabytesPerSecond(instantRate),
+		formatMegabytesPerSecond(averageRate),
+		eta)
+	if !summary.CompleteJobOrdered {
+		stats += " (scanning...)"
+	}
+	if summary.TransfersFailed > 0 {
+		stats += fmt.Sprintf(" | %v failed", summary.TransfersFailed)
+	}
+	if summary.TransfersStalled > 0 {
+		stats += fmt.Sprintf(" | %v stalled", summary.TransfersStalled)
+	}
+	if summary.ThrottledRequests > 0 {
+		stats += fmt.Sprintf(" | %v throttled", summary.ThrottledRequests)
+	}
+
+	// the bar gets whatever room is left, but the line must never wrap, since it's rewritten in place.
+	// One column is left spare, because some terminals wrap when the last column is written
+	const minBarWidth = 10
+	barWidth := width - 1 - len(stats) - 2
+	if barWidth < minBarWidth {
+		barWidth = minBarWidth
+	}
+	line := renderBar(float64(summary.PercentComplete), barWidth) + stats
+	if width > 1 && len(line) > width-1 {
+		line = line[:width-1]
+	}
+	return line
+}
+
+func renderBar(percent float64, width int) string {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+
+	filled := int(percent / 100 * float64(width))
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+	return "[" + bar + "]"
+}
+
+// the width of the terminal that stdout goes to, or a sensible default if it doesn't go to a terminal
+func getTerminalWidth() int {
+	width, _, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return defaultTerminalWidth
+	}
+	return width
+}
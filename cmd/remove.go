@@ -85,15 +85,32 @@ func init() {
 		},
 	}
 	rootCmd.AddCommand(deleteCmd)
+	batchJobPreparers[deleteCmd] = func(commandString string) (batchJob, error) {
+		cooked, err := raw.cook()
+		if err != nil {
+			return batchJob{}, err
+		}
+		cooked.commandString = commandString
+		return batchJob{commandString: commandString, controller: &cooked, start: cooked.process}, nil
+	}
 
 	deleteCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", false, "Look into sub-directories recursively when syncing between directories.")
 	deleteCmd.PersistentFlags().StringVar(&raw.logVerbosity, "log-level", "INFO", "Define the log verbosity for the log file. Available levels include: INFO(all requests/responses), WARNING(slow responses), ERROR(only failed requests), and NONE(no output logs). (default 'INFO')")
 	deleteCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
 	deleteCmd.PersistentFlags().StringVar(&raw.includePath, "include-path", "", "Include only these paths when removing. "+
 		"This option does not support wildcard characters (*). Checks relative path prefix. For example: myFolder;myFolder/subDirName/file.pdf")
+	deleteCmd.PersistentFlags().StringVar(&raw.includeAfter, common.IncludeAfterFlagName, "", "Remove only the files modified on or after the given date/time. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone.")
+	deleteCmd.PersistentFlags().StringVar(&raw.includeBefore, common.IncludeBeforeFlagName, "", "Remove only the files modified on or before the given date/time. The value is given in the same way as for --include-after.")
+	deleteCmd.PersistentFlags().StringVar(&raw.includeTier, "include-tier", "", "Remove only the blobs in these access tiers, e.g. 'Hot;Cool' to leave the archived blobs alone. Separate the tiers by using a ';' or a ','.")
+	deleteCmd.PersistentFlags().StringVar(&raw.includeTags, "include-tags", "", "Remove only the blobs that have all these index tags, e.g. 'project=alpha;env=prod'. The values may use wildcard characters (*). When none do, and the credentials allow it, the blobs are found with the service's find-by-tags API, rather than by listing every blob.")
+	deleteCmd.PersistentFlags().StringVar(&raw.includeMetadata, "include-metadata", "", "Remove only the blobs that have all these metadata, e.g. 'owner=finance;reviewed=yes'. The names are case-insensitive, and the values may use wildcard characters (*).")
+	deleteCmd.PersistentFlags().StringVar(&raw.minSize, "min-size", "", "Remove only the files that are at least this big. The size is a number of bytes, optionally followed by a unit: KiB, MiB, GiB and TiB (or K, M, G and T) are powers of 1024, and KB, MB, GB and TB are powers of 1000. E.g. 500, 64KiB or 1.5GB.")
+	deleteCmd.PersistentFlags().StringVar(&raw.maxSize, "max-size", "", "Remove only the files that are no bigger than this. The size is given in the same way as for --min-size. E.g. '--max-size 1KiB' removes only the tiny files.")
 	deleteCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
 	deleteCmd.PersistentFlags().StringVar(&raw.excludePath, "exclude-path", "", "Exclude these paths when removing. "+
 		"This option does not support wildcard characters (*). Checks relative path prefix. For example: myFolder;myFolder/subDirName/file.pdf")
+	deleteCmd.PersistentFlags().BoolVar(&raw.dryRun, "dry-run", false, "List the files that would be removed, without removing anything.")
+	deleteCmd.PersistentFlags().StringVar(&raw.planLocation, planLocationFlagName, common.EPlanLocation.Disk().String(), "Where to keep the job's plan: 'disk' (the default), in the plan folder, so that the job can be listed, shown and resumed later, or 'memory', so that nothing is written to the plan folder. A plan kept in memory goes when AzCopy exits, so the job can't be resumed. Useful for small jobs on read-only or diskless machines.")
 	deleteCmd.PersistentFlags().BoolVar(&raw.forceIfReadOnly, "force-if-read-only", false, "When deleting an Azure Files file or folder, force the deletion to work even if the existing object is has its read-only attribute set")
 	deleteCmd.PersistentFlags().StringVar(&raw.listOfFilesToCopy, "list-of-files", "", "Defines the location of a file which contains the list of files and directories to be deleted. The relative paths should be delimited by line breaks, and the paths should NOT be URL-encoded.")
 	deleteCmd.PersistentFlags().StringVar(&raw.deleteSnapshotsOption, "delete-snapshots", "", "By default, the delete operation fails if a blob has snapshots. Specify 'include' to remove the root blob and all its snapshots; alternatively specify 'only' to remove only the snapshots but keep the root blob.")
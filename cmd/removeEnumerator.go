@@ -45,9 +45,12 @@ func newRemoveEnumerator(cca *cookedCopyCmdArgs) (enumerator *copyEnumerator, er
 
 	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
 
+	// Azure Files listings have no last modified times, so they must be fetched if the files are filtered by them
+	getProperties := cca.fromTo.From() == common.ELocation.File() && (cca.includeAfter != nil || cca.includeBefore != nil)
+
 	// Include-path is handled by ListOfFilesChannel.
-	sourceTraverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &cca.credentialInfo, nil,
-		cca.listOfFilesChannel, cca.recursive, false, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs)
+	sourceTraverser, err = initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &cca.credentialInfo, common.ESymlinkHandlingType.Skip(),
+		cca.listOfFilesChannel, cca.recursive, getProperties, cca.includeDirectoryStubs, func(common.EntityType) {}, cca.listOfVersionIDs)
 
 	// report failure to create traverser
 	if err != nil {
@@ -61,6 +64,11 @@ func newRemoveEnumerator(cca *cookedCopyCmdArgs) (enumerator *copyEnumerator, er
 	// set up the filters in the right order
 	filters := append(includeFilters, excludeFilters...)
 	filters = append(filters, excludePathFilters...)
+	filters = append(filters, buildFileSizeFilters(cca.minSize, cca.maxSize)...)
+	filters = append(filters, buildDateFilters(cca.includeAfter, cca.includeBefore)...)
+	filters = append(filters, buildIncludeBlobTierFilters(cca.includeBlobTiers)...)
+	filters = append(filters, buildBlobTagsFilters(cca.includeBlobTags)...)
+	filters = append(filters, buildMetadataFilters(cca.includeMetadata)...)
 
 	// decide our folder transfer strategy
 	// (Must enumerate folders when deleting from a folder-aware location. Can't do folder deletion just based on file
@@ -116,6 +124,11 @@ func removeBfsResources(cca *cookedCopyCmdArgs) (err error) {
 		return errors.New("pattern matches are not supported in this command")
 	}
 
+	// the service does the (recursive) deletion, so we never list what's being removed
+	if cca.dryRunPrinter != nil {
+		return errors.New("dry-run is not supported for this destination. Use the blob endpoint (blob.core.windows.net) of the account instead")
+	}
+
 	// create bfs pipeline
 	p, err := createBlobFSPipeline(ctx, cca.credentialInfo)
 	if err != nil {
@@ -38,10 +38,11 @@ func newRemoveTransferProcessor(cca *cookedCopyCmdArgs, numOfTransfersPerPart in
 		// flags
 		LogLevel:       cca.logVerbosity,
 		BlobAttributes: common.BlobTransferAttributes{DeleteSnapshotsOption: cca.deleteSnapshotsOption},
+		PlanLocation:   cca.planLocation,
 	}
 
 	reportFirstPart := func(jobStarted bool) {
-		if jobStarted {
+		if jobStarted && cca.dryRunPrinter == nil {
 			cca.waitUntilJobCompletion(false)
 		}
 	}
@@ -49,6 +50,8 @@ func newRemoveTransferProcessor(cca *cookedCopyCmdArgs, numOfTransfersPerPart in
 
 	// note that the source and destination, along with the template are given to the generic processor's constructor
 	// this means that given an object with a relative path, this processor already knows how to schedule the right kind of transfers
-	return newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
+	processor := newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
 		reportFirstPart, reportFinalPart, false)
+	processor.dryRunPrinter = cca.dryRunPrinter
+	return processor
 }
@@ -23,13 +23,16 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Azure/azure-pipeline-go/pipeline"
+	"io/ioutil"
 	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/Azure/azure-storage-azcopy/common"
@@ -40,12 +43,34 @@ import (
 
 var azcopyAppPathFolder string
 var azcopyLogPathFolder string
+var logLocation string
 var azcopyJobPlanFolder string
 var azcopyMaxFileAndSocketHandles int
 var outputFormatRaw string
 var cancelFromStdin bool
 var azcopyOutputFormat common.OutputFormat
+var outputVerbosityRaw string
+var azcopyOutputVerbosity common.OutputVerbosity
+var progressEndpoint string
+var progressDisplayRaw string
+var errorOutputRaw string
+var outputOverflowRaw string
+var colorModeRaw string
+var colorThemeRaw string
+var statusIntervalSeconds float64
+var promptTimeoutSeconds uint
+var promptAnswers []string
+var promptAnswersFile string
+var outputFilePath string
+var useSystemLog bool
+var transferEvents bool
+var notifyURL string
+var quietOutput bool
+var outputLocale string
+var showProgressBar bool
 var cmdLineCapMegaBitsPerSecond float64
+var cmdLineCapOpsPerSecond int
+var cmdLineMemoryLimitGB float64
 var azcopyAwaitContinue bool
 var azcopyAwaitAllowOpenFiles bool
 
@@ -70,12 +95,90 @@ var rootCmd = &cobra.Command{
 
 		timeAtPrestart := time.Now()
 
+		if needsPlanFolder(cmd) {
+			if err := os.Mkdir(azcopyJobPlanFolder, os.ModeDir|os.ModePerm); err != nil && !os.IsExist(err) {
+				glcm.ReportError(common.NewAzCopyError(common.EErrorCode.FileSystem(), "create the plan file folder", err))
+			}
+		}
+
 		err := azcopyOutputFormat.Parse(outputFormatRaw)
 		glcm.SetOutputFormat(azcopyOutputFormat)
 		if err != nil {
 			return err
 		}
 
+		err = azcopyOutputVerbosity.Parse(outputVerbosityRaw)
+		if err != nil {
+			return fmt.Errorf("invalid verbosity '%s'. The choices include: debug, info, warning, error", outputVerbosityRaw)
+		}
+		glcm.SetOutputVerbosity(azcopyOutputVerbosity)
+		glcm.SetQuiet(quietOutput)
+
+		var progressDisplay common.ProgressDisplay
+		if err := progressDisplay.Parse(progressDisplayRaw); err != nil {
+			return fmt.Errorf("invalid progress-display '%s'. The choices include: auto, inplace, lines", progressDisplayRaw)
+		}
+		glcm.SetProgressDisplay(progressDisplay)
+
+		var errorOutput common.ErrorOutput
+		if err := errorOutput.Parse(errorOutputRaw); err != nil {
+			return fmt.Errorf("invalid error-output '%s'. The choices include: stderr, stdout", errorOutputRaw)
+		}
+		glcm.SetErrorOutput(errorOutput)
+
+		var overflowPolicy common.QueueOverflowPolicy
+		if err := overflowPolicy.Parse(outputOverflowRaw); err != nil {
+			return fmt.Errorf("invalid output-overflow '%s'. The choices include: block, coalesce, dropoldest, grow", outputOverflowRaw)
+		}
+		glcm.SetQueueOverflowPolicy(overflowPolicy)
+
+		if err := setColors(); err != nil {
+			return err
+		}
+
+		if err := setStatusInterval(); err != nil {
+			return err
+		}
+		if err := setLogLocation(); err != nil {
+			return err
+		}
+		glcm.SetPromptTimeout(time.Duration(promptTimeoutSeconds) * time.Second)
+		if err := setScriptedAnswers(); err != nil {
+			return err
+		}
+
+		if err := common.SetLocale(outputLocale); err != nil {
+			glcm.Warn(err.Error()) // the messages are still understandable, so this is no reason to stop
+		}
+
+		if outputFilePath != "" {
+			if err := glcm.SetOutputFile(outputFilePath); err != nil {
+				return err
+			}
+		}
+
+		if useSystemLog {
+			if err := glcm.EnableSystemLog(); err != nil {
+				return err
+			}
+		}
+
+		if transferEvents {
+			glcm.EnableTransferEvents()
+		}
+
+		if notifyURL != "" {
+			if err := glcm.SetNotifyURL(notifyURL); err != nil {
+				return err
+			}
+		}
+
+		if progressEndpoint != "" {
+			if err := glcm.EnableProgressEndpoint(progressEndpoint); err != nil {
+				return err
+			}
+		}
+
 		// warn Windows users re quoting (since our docs all use single quotes, but CMD needs double)
 		// Single ones just come through as part of the args, in CMD.
 		// Ideally, for usability, we'd ideally have this info come back in the result of url.Parse. But that's hard to
@@ -98,7 +201,24 @@ var rootCmd = &cobra.Command{
 
 		// startup of the STE happens here, so that the startup can access the values of command line parameters that are defined for "root" command
 		concurrencySettings := ste.NewConcurrencySettings(azcopyMaxFileAndSocketHandles, preferToAutoTuneGRs)
-		err = ste.MainSTE(concurrencySettings, float64(cmdLineCapMegaBitsPerSecond), azcopyJobPlanFolder, azcopyLogPathFolder, providePerformanceAdvice)
+		retrySettings, err := ste.NewRetrySettings()
+		if err != nil {
+			return err
+		}
+		checkpointInterval, err := ste.NewCheckpointInterval()
+		if err != nil {
+			return err
+		}
+		if err = ste.ValidatePlanEncryption(); err != nil {
+			return err
+		}
+		if cmdLineCapOpsPerSecond < 0 {
+			return errors.New("cap-ops cannot be negative")
+		}
+		if cmdLineMemoryLimitGB < 0 {
+			return errors.New("memory-limit-gb cannot be negative")
+		}
+		err = ste.MainSTE(concurrencySettings, retrySettings, checkpointInterval, float64(cmdLineCapMegaBitsPerSecond), cmdLineCapOpsPerSecond, cmdLineMemoryLimitGB, azcopyJobPlanFolder, azcopyLogPathFolder, providePerformanceAdvice)
 		if err != nil {
 			return err
 		}
@@ -125,9 +245,120 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+const planLocationFlagName = "plan-location"
+
+// needsPlanFolder says whether the command uses the plan folder. Only a job that keeps its plan in memory doesn't,
+// and it can then be run where the folder can't be created, e.g. on a read-only file system
+func needsPlanFolder(cmd *cobra.Command) bool {
+	f := cmd.Flags().Lookup(planLocationFlagName)
+	if f == nil {
+		return true
+	}
+	var location common.PlanLocation
+	return location.Parse(f.Value.String()) != nil || location != common.EPlanLocation.Memory()
+}
+
+// answers from the file come first, so that the ones on the command line override them
+func setScriptedAnswers() error {
+	var entries []string
+	if promptAnswersFile != "" {
+		content, err := ioutil.ReadFile(promptAnswersFile)
+		if err != nil {
+			return fmt.Errorf("cannot read prompt-answers-file: %w", err)
+		}
+		entries = strings.Split(string(content), "\n") // any \r is trimmed off in parsing
+	}
+	entries = append(entries, promptAnswers...)
+
+	answers, err := common.ParseScriptedAnswers(entries)
+	if err != nil {
+		return err
+	}
+	glcm.SetScriptedAnswers(answers)
+	return nil
+}
+
+// the flag takes precedence over the environment variable
+func setColors() error {
+	var colorMode common.ColorMode
+	if err := colorMode.Parse(colorModeRaw); err != nil {
+		return fmt.Errorf("invalid color '%s'. The choices include: auto, always, never", colorModeRaw)
+	}
+
+	themeRaw := colorThemeRaw
+	if themeRaw == "" {
+		themeRaw = glcm.GetEnvironmentVariable(common.EEnvironmentVariable.ColorTheme())
+	}
+	theme, err := common.ParseColorTheme(themeRaw)
+	if err != nil {
+		return fmt.Errorf("invalid color-theme: %w", err)
+	}
+
+	glcm.SetColors(colorMode, theme)
+	return nil
+}
+
+// minStatusInterval is the shortest interval allowed, since fetching the progress much more often would only load the transfer engine
+const minStatusInterval = 100 * time.Millisecond
+
+func setStatusInterval() error {
+	interval, err := statusInterval()
+	if err != nil {
+		return err
+	}
+	glcm.SetProgressInterval(interval)
+	return nil
+}
+
+// statusInterval returns how often to report progress, as given by the flag or the environment variable.
+// Zero means neither was given, so the default applies
+func statusInterval() (time.Duration, error) {
+	seconds := statusIntervalSeconds
+	if seconds == 0 {
+		if envValue := glcm.GetEnvironmentVariable(common.EEnvironmentVariable.StatusInterval()); envValue != "" {
+			var err error
+			seconds, err = strconv.ParseFloat(envValue, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s' for %s. It must be a number of seconds", envValue, common.EEnvironmentVariable.StatusInterval().Name)
+			}
+		}
+	}
+
+	if seconds < 0 {
+		return 0, errors.New("the status interval cannot be negative")
+	}
+	interval := time.Duration(seconds * float64(time.Second))
+	if interval != 0 && interval < minStatusInterval {
+		interval = minStatusInterval
+	}
+	return interval, nil
+}
+
+// setLogLocation moves the log files to the folder given by --log-location, if there is one.
+// It takes precedence over the environment variable, which main has already applied
+func setLogLocation() error {
+	if logLocation == "" {
+		return nil
+	}
+	folder, err := filepath.Abs(logLocation) // so that the path we print still works from another directory
+	if err != nil {
+		return fmt.Errorf("invalid log-location '%s': %w", logLocation, err)
+	}
+	if err := os.MkdirAll(folder, os.ModeDir|os.ModePerm); err != nil {
+		return common.NewAzCopyError(common.EErrorCode.FileSystem(), "create the log folder", err)
+	}
+	azcopyLogPathFolder = folder
+	return nil
+}
+
+// jobLogFilePath is where the job's log goes. Each job has its own, named by its ID, so that jobs run at the same
+// time, or one after another, don't write into each other's logs
+func jobLogFilePath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, jobID)
+}
+
 // hold a pointer to the global lifecycle controller so that commands could output messages and exit properly
 var glcm = common.GetLifecycleMgr()
-var glcmSwapOnce = &sync.Once{}
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
@@ -137,6 +368,18 @@ func Execute(azsAppPathFolder, logPathFolder string, jobPlanFolder string, maxFi
 	azcopyJobPlanFolder = jobPlanFolder
 	azcopyMaxFileAndSocketHandles = maxFileAndSocketHandles
 
+	// an embedder may have replaced the lifecycle manager since our package was initialized
+	glcm = common.GetLifecycleMgr()
+
+	// a job template is run by running the command line that it expands to
+	if args := os.Args[1:]; isRunCommand(args) {
+		expanded, err := expandRunCommand(args[1:])
+		if err != nil {
+			glcm.Error(err.Error())
+		}
+		rootCmd.SetArgs(expanded)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		glcm.Error(err.Error())
 	} else {
@@ -157,7 +400,50 @@ func init() {
 	rootCmd.SetUsageTemplate(strings.Replace((&cobra.Command{}).UsageTemplate(), "Global Flags", "Flags Applying to All Commands", -1))
 
 	rootCmd.PersistentFlags().Float64Var(&cmdLineCapMegaBitsPerSecond, "cap-mbps", 0, "Caps the transfer rate, in megabits per second. Moment-by-moment throughput might vary slightly from the cap. If this option is set to zero, or it is omitted, the throughput isn't capped.")
-	rootCmd.PersistentFlags().StringVar(&outputFormatRaw, "output-type", "text", "Format of the command's output. The choices include: text, json. The default value is 'text'.")
+	rootCmd.PersistentFlags().IntVar(&cmdLineCapOpsPerSecond, "cap-ops", 0, "Caps the number of requests sent to the service per second, including those for listing, and retries. "+
+		"Use this for an account that is close to its request rate limit, so that AzCopy doesn't get throttled (e.g. when copying many small files). If this option is set to zero, or it is omitted, the rate isn't capped.")
+	rootCmd.PersistentFlags().Float64Var(&cmdLineMemoryLimitGB, "memory-limit-gb", 0, "Hard limit, in GB, on the memory that AzCopy uses for buffering data between network and disk. May include a decimal point, e.g. 0.5. "+
+		"When the limit is reached, AzCopy waits for buffers to be freed before it processes more data. Use this when AzCopy runs in a container or small VM with little memory. "+
+		"It takes precedence over the "+common.EEnvironmentVariable.BufferGB().Name+" environment variable, which is a softer limit. If this option is set to zero, or it is omitted, there is no hard limit.")
+	rootCmd.PersistentFlags().StringVar(&outputFormatRaw, "output-type", "text", "Format of the command's output. The choices include: text, json. The default value is 'text'. With json, each message is written on its own line as a JSON object, containing the message type, a timestamp and the message content.")
+	rootCmd.PersistentFlags().StringVar(&outputVerbosityRaw, "verbosity", "info", "Define the least severe messages to show in the command's output: debug, info, warning or error. The default value is 'info'. Errors that stop the command are always shown. This does not affect the log file; use log-level for that.")
+	rootCmd.PersistentFlags().StringVar(&progressDisplayRaw, "progress-display", "auto", "How to show progress with text output: 'inplace' rewrites a single line, 'lines' prints a separate line every 30 seconds (or every status-interval, if set), "+
+		"and 'auto' (the default) chooses 'inplace' if the output is a terminal and 'lines' if it is redirected to a file or pipe.")
+	rootCmd.PersistentFlags().StringVar(&errorOutputRaw, "error-output", "stderr", "Where to print errors and warnings with text output: 'stderr' (the default), so they can be kept apart from the progress and summaries on stdout, "+
+		"or 'stdout', to print everything together. With json output, everything is printed to stdout, as a single stream of messages.")
+	rootCmd.PersistentFlags().StringVar(&colorModeRaw, "color", "auto", "Whether to color errors (red), warnings (yellow) and the summary of a successful job (green), with text output: "+
+		"'auto' (the default) colors them if the output is a terminal and the NO_COLOR environment variable isn't set, 'always' colors them regardless, and 'never' doesn't.")
+	rootCmd.PersistentFlags().StringVar(&colorThemeRaw, "color-theme", "", "Change the colors, e.g. for a light background, as comma-separated ANSI color numbers, e.g. 'error=91,warning=35,success=none'. "+
+		"Colors that aren't mentioned keep their defaults. Can also be set with the "+common.EEnvironmentVariable.ColorTheme().Name+" environment variable.")
+	rootCmd.PersistentFlags().StringVar(&outputOverflowRaw, "output-overflow", "block", "What to do when output can't keep up with the messages for it, e.g. with a slow terminal: "+
+		"'block' (the default) holds up the transfers until there's room, 'coalesce' skips progress reports that are already out of date, "+
+		"'dropoldest' holds the messages, but drops the oldest informational ones once there are too many, and 'grow' holds as many as it takes.")
+	rootCmd.PersistentFlags().Float64Var(&statusIntervalSeconds, "status-interval", 0, "How often, in seconds, to fetch and report the job's progress. "+
+		"Larger values reduce the cost of polling in very large jobs. The default is every 2 seconds, reducing to every 2 minutes for jobs of more than a million files. The shortest interval is 0.1 seconds. "+
+		"Can also be set with the "+common.EEnvironmentVariable.StatusInterval().Name+" environment variable.")
+	rootCmd.PersistentFlags().UintVar(&promptTimeoutSeconds, "prompt-timeout", 0, "Stop waiting for an answer to a question (e.g. whether to overwrite a file) after this many seconds, "+
+		"and take the safe choice instead (e.g. don't overwrite). Useful for unattended runs, which would otherwise wait forever. The default is 0, meaning wait forever.")
+	rootCmd.PersistentFlags().StringSliceVar(&promptAnswers, "prompt-answers", nil, "Answer questions ahead of time, instead of waiting for someone to type an answer, e.g. 'Overwrite=n,DeleteDestination=a'. "+
+		"The types of question are Cancel, Overwrite and DeleteDestination, and the answers are those that would be typed, e.g. y, n, a (yes for all) or l (no for all).")
+	rootCmd.PersistentFlags().StringVar(&promptAnswersFile, "prompt-answers-file", "", "Read answers to questions from this file, with one answer per line in the same form as for prompt-answers. "+
+		"Lines starting with # are ignored. Answers given with prompt-answers take precedence.")
+	rootCmd.PersistentFlags().BoolVar(&quietOutput, "quiet", false, "Only output warnings, errors, questions and the final summary. Informational messages and progress updates are not shown. Useful for scheduled jobs.")
+	rootCmd.PersistentFlags().BoolVar(&showProgressBar, "progress-bar", false, "With text output, show progress as a bar with the percentage complete, current and average throughput in MB/s, files done, and an estimate of the time remaining. "+
+		"The bar is sized to fit the width of the terminal.")
+	rootCmd.PersistentFlags().StringVar(&outputLocale, "locale", "", "The locale of the language to show messages such as job summaries in, e.g. en-US. "+
+		"If there are no messages for the locale, those for another locale of the same language are used, or else the default of "+common.DefaultLocale+".")
+	rootCmd.PersistentFlags().StringVar(&logLocation, "log-location", "", "The folder to write the log files to. Each job's log is in its own file there, named by the job ID, "+
+		"and its path is shown as the job starts and in its summary. The folder is created if need be. Takes precedence over the "+common.EEnvironmentVariable.LogLocation().Name+" environment variable. "+
+		"Give the same location to the jobs commands (e.g. jobs clean) so that they find the logs.")
+	rootCmd.PersistentFlags().StringVar(&outputFilePath, "output-file", "", "Also append the command's output to this file, with a timestamp on each line. Progress updates are left out. Unlike redirecting the output, this doesn't affect what is shown on screen.")
+	rootCmd.PersistentFlags().BoolVar(&useSystemLog, "system-log", false, "Also write the summary of each job, and any error that stops the command, to syslog on Linux and macOS, or to the Windows Event Log, with the source '"+common.SystemLogSource+"'. "+
+		"Useful for monitoring scheduled runs across many machines.")
+	rootCmd.PersistentFlags().BoolVar(&transferEvents, "transfer-events", false, "With --output-type=json, also output a TransferEvent message as each file starts, and as it completes, fails or is skipped, "+
+		"with its source, destination, bytes transferred and elapsed time. Useful for auditing exactly which files were moved.")
+	rootCmd.PersistentFlags().StringVar(&notifyURL, "notify-url", "", "When each job ends, whether it succeeded or not, POST a JSON notification containing the job summary to this URL. "+
+		"Failed attempts are retried, for up to 5 seconds in all, so that AzCopy isn't held up exiting. Set the "+common.EEnvironmentVariable.NotifySecret().Name+" environment variable to have each notification signed, with HMAC-SHA256.")
+	rootCmd.PersistentFlags().StringVar(&progressEndpoint, "progress-endpoint", "", "Also publish the job's progress to local applications that connect to this endpoint. "+
+		"On Linux and macOS it is the path of a Unix domain socket; on Windows it is the name of a named pipe. Each event is written as one line of JSON, in the same format as --output-type=json.")
 
 	rootCmd.PersistentFlags().StringVar(&cmdLineExtraSuffixesAAD, trustedSuffixesNameAAD, "", "Specifies additional domain suffixes where Azure Active Directory login tokens may be sent.  The default is '"+
 		trustedSuffixesAAD+"'. Any listed here are added to the default. For security, you should only put Microsoft Azure domains here. Separate multiple entries with semi-colons.")
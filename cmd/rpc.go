@@ -44,7 +44,8 @@ func inprocSend(rpcCmd common.RpcCmd, requestData interface{}, responseData inte
 		*(responseData.(*common.CopyJobPartOrderResponse)) = ste.ExecuteNewCopyJobPartOrder(*requestData.(*common.CopyJobPartOrderRequest))
 
 	case common.ERpcCmd.GetJobLCMWrapper():
-		*(responseData.(*common.LifecycleMgr)) = ste.GetJobLCMWrapper(*requestData.(*common.JobID))
+		lcm := responseData.(*common.LifecycleMgr)
+		*lcm = ste.GetJobLCMWrapper(*requestData.(*common.JobID), *lcm)
 
 	case common.ERpcCmd.ListJobs():
 		*(responseData.(*common.ListJobsResponse)) = ste.ListJobs(requestData.(common.JobStatus))
@@ -56,7 +57,7 @@ func inprocSend(rpcCmd common.RpcCmd, requestData interface{}, responseData inte
 		*(responseData.(*common.ListJobTransfersResponse)) = ste.ListJobTransfers(requestData.(common.ListJobTransfersRequest))
 
 	case common.ERpcCmd.PauseJob():
-		responseData = ste.CancelPauseJobOrder(requestData.(common.JobID), common.EJobStatus.Paused())
+		*(responseData.(*common.CancelPauseResumeResponse)) = ste.CancelPauseJobOrder(requestData.(common.JobID), common.EJobStatus.Paused())
 
 	case common.ERpcCmd.CancelJob():
 		*(responseData.(*common.CancelPauseResumeResponse)) = ste.CancelPauseJobOrder(requestData.(common.JobID), common.EJobStatus.Cancelling())
@@ -67,6 +68,27 @@ func inprocSend(rpcCmd common.RpcCmd, requestData interface{}, responseData inte
 	case common.ERpcCmd.GetJobFromTo():
 		*(responseData.(*common.GetJobFromToResponse)) = ste.GetJobFromTo(*requestData.(*common.GetJobFromToRequest))
 
+	case common.ERpcCmd.SetJobSettings():
+		*(responseData.(*common.SetJobSettingsResponse)) = ste.SetJobSettings(requestData.(common.SetJobSettingsRequest))
+
+	case common.ERpcCmd.CancelTransfers():
+		*(responseData.(*common.CancelTransfersResponse)) = ste.CancelTransfers(requestData.(common.CancelTransfersRequest))
+
+	case common.ERpcCmd.GetJobStatus():
+		*(responseData.(*common.GetJobStatusResponse)) = ste.GetJobStatus(requestData.(common.JobID))
+
+	case common.ERpcCmd.UpdateQueuedJob():
+		*(responseData.(*common.UpdateQueuedJobResponse)) = ste.UpdateQueuedJob(requestData.(common.UpdateQueuedJobRequest))
+
+	case common.ERpcCmd.RetryJob():
+		*(responseData.(*common.RetryJobResponse)) = ste.RetryJob(requestData.(common.JobID))
+
+	case common.ERpcCmd.ExportJob():
+		*(responseData.(*common.ExportJobResponse)) = ste.ExportJob(requestData.(common.JobID))
+
+	case common.ERpcCmd.ImportJob():
+		*(responseData.(*common.ImportJobResponse)) = ste.ImportJob(*requestData.(*common.ImportJobRequest))
+
 	default:
 		panic(fmt.Errorf("Unrecognized RpcCmd: %q", rpcCmd.String()))
 	}
@@ -114,7 +136,9 @@ func (httpClient *HTTPClient) send(rpcCmd common.RpcCmd, requestData interface{}
 		return fmt.Errorf("error reading response for the request")
 	}
 	err = json.Unmarshal(responseJson, responseData)
-	common.PanicIfErr(err)
+	if err != nil {
+		return fmt.Errorf("error parsing response for the request: %w", err)
+	}
 	return nil
 }
 
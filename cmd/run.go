@@ -0,0 +1,97 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const runParamFlag = "--param"
+
+// The run command isn't really run by cobra. Before cobra sees the command line, Execute swaps "run [template] ..." for
+// the command line that the template expands to, so the job runs exactly as if that had been typed. That's also why the
+// flags are parsed here, by hand: any flags that aren't --param are added to the end of the template's command line
+var runCmd = &cobra.Command{
+	Use:     "run [template]",
+	Short:   runCmdShortDescription,
+	Long:    runCmdLongDescription,
+	Example: runCmdExample,
+	Args: func(cmd *cobra.Command, args []string) error {
+		return errors.New("run command requires the name of a template")
+	},
+	Run: func(cmd *cobra.Command, args []string) {},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.PersistentFlags().StringArray("param", nil, "The value of one of the template's placeholders, as name=value. Give the flag once for each placeholder.")
+}
+
+// isRunCommand says whether Execute should expand the command line. Requests for help are left to cobra
+func isRunCommand(args []string) bool {
+	return len(args) >= 2 && args[0] == runCmd.Name() && !strings.HasPrefix(args[1], "-")
+}
+
+// expandRunCommand turns the arguments of "run", i.e. [template] [flags...], into the command line that the template expands to
+func expandRunCommand(args []string) ([]string, error) {
+	t, err := loadJobTemplate(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	extra := make([]string, 0)
+	for i := 1; i < len(args); i++ {
+		var param string
+		switch {
+		case args[i] == runParamFlag:
+			if i+1 >= len(args) {
+				return nil, errors.New("--param needs a value, as name=value")
+			}
+			i++
+			param = args[i]
+		case strings.HasPrefix(args[i], runParamFlag+"="):
+			param = strings.TrimPrefix(args[i], runParamFlag+"=")
+		default:
+			extra = append(extra, args[i])
+			continue
+		}
+
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("'%s' is not a valid --param. Give it as name=value", param)
+		}
+		if _, ok := values[kv[0]]; ok {
+			return nil, fmt.Errorf("more than one value was given for %s", kv[0])
+		}
+		values[kv[0]] = kv[1]
+	}
+
+	expanded, err := t.expand(values)
+	if err != nil {
+		return nil, err
+	}
+	return append(expanded, extra...), nil
+}
@@ -38,6 +38,7 @@ import (
 // 2. Each label in the bucket name must start with a lowercase letter or number.
 // 3. The bucket name cannot contain underscores, end with a dash or period, have consecutive periods, or use dashes adjacent to periods.
 // 4. The bucket name cannot be formatted as an IP address (198.51.100.24).
+// Google Cloud Storage's are much the same, except that they may contain underscores, which are replaced like periods.
 // Two common cases need be solved are:
 // 1. bucket name with period. In this case, AzCopy try to replace period with hyphen.
 // e.g. bucket.with.period -> bucket-with-period
@@ -99,8 +100,8 @@ func (s3Resolver *S3BucketNameToAzureResourcesResolver) ResolveName(bucketName s
 }
 
 func (s3Resolver *S3BucketNameToAzureResourcesResolver) resolveNewBucketNameInternal(orgBucketName string) {
-	// Check if the bucket name contains periods or consecutive hyphens, and try to resolve them.
-	hasPeriod := strings.Contains(orgBucketName, ".")
+	// Check if the bucket name contains periods (or underscores) or consecutive hyphens, and try to resolve them.
+	hasPeriod := strings.ContainsAny(orgBucketName, "._")
 	hasConsecutiveHyphen := strings.Contains(orgBucketName, "--")
 
 	if !hasPeriod && !hasConsecutiveHyphen {
@@ -111,10 +112,11 @@ func (s3Resolver *S3BucketNameToAzureResourcesResolver) resolveNewBucketNameInte
 	// Init resolved name as original bucket name
 	resolvedName := orgBucketName
 
-	// 1. Try to replace period with hyphen.
-	// Note: there should be no '.' adjacent to '-'.
+	// 1. Try to replace period (and underscore) with hyphen.
+	// Note: there should be no '.' adjacent to '-', but in a Google bucket name an '_' may be, so look again for consecutive hyphens
 	if hasPeriod {
-		resolvedName = strings.Replace(orgBucketName, ".", "-", -1)
+		resolvedName = strings.NewReplacer(".", "-", "_", "-").Replace(orgBucketName)
+		hasConsecutiveHyphen = strings.Contains(resolvedName, "--")
 	}
 
 	// 2. Try to replace consecutive hyphen with -[number]-.
@@ -62,6 +62,12 @@ func (s *s3NameResolverTestSuite) TestS3BucketNameToAzureResourceResolverSingleB
 	resolvedName, err = r.ResolveName("0123456789--01234567890123456789012345678901234567890123456789")
 	c.Assert(err, chk.IsNil)
 	c.Assert(resolvedName, chk.Equals, "0123456789-2-01234567890123456789012345678901234567890123456789")
+
+	// Google Cloud Storage allows underscores, which are replaced like periods
+	r = NewS3BucketNameToAzureResourcesResolver([]string{"google_bucket_-name"})
+	resolvedName, err = r.ResolveName("google_bucket_-name")
+	c.Assert(err, chk.IsNil)
+	c.Assert(resolvedName, chk.Equals, "google-bucket-2-name")
 }
 
 func (s *s3NameResolverTestSuite) TestS3BucketNameToAzureResourceResolverMultipleBucketNames(c *chk.C) {
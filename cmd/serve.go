@@ -0,0 +1,333 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The control server lets other programs (e.g. a GUI) submit and manage jobs over HTTP, on the local machine only.
+// Each request is carried out by running AzCopy itself, with JSON output, just as such a program would otherwise do.
+// So every job runs in its own process, exactly as it would from the command line, and the server holds no job state.
+// That's also what allows the jobs to be managed from the command line, e.g. with "jobs list", at the same time.
+
+// the message types, as they appear in JSON output
+const (
+	jsonMessageTypeInit     = "Init"
+	jsonMessageTypeError    = "Error"
+	jsonMessageTypeEndOfJob = "EndOfJob"
+)
+
+// the commands that can be submitted as jobs
+var controlServerJobCommands = map[string]bool{"copy": true, "cp": true, "sync": true, "remove": true, "rm": true}
+
+type controlServer struct {
+	token string
+
+	// run runs AzCopy with the given arguments, to completion, and returns its output
+	run func(args []string) ([]common.JsonOutputTemplate, error)
+
+	// start runs AzCopy with the given arguments, and returns its output as it is written. The channel is closed when AzCopy exits
+	start func(args []string) (<-chan common.JsonOutputTemplate, error)
+}
+
+type controlSubmitRequest struct {
+	Args []string // the command line, without "azcopy", e.g. ["copy", "/data", "https://...", "--recursive"]
+}
+
+type controlCancelTransfersRequest struct {
+	Sources []string // the sources of the transfers to cancel, as for "jobs cancel-transfers"
+}
+
+type controlErrorResponse struct {
+	Error string
+}
+
+func newControlServer(token string) *controlServer {
+	return &controlServer{token: token, run: runAzCopyForControl, start: startAzCopyForControl}
+}
+
+func (s *controlServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+s.token)) != 1 {
+		writeControlError(w, http.StatusUnauthorized, "the Authorization header must contain the server's token, as 'Bearer <token>'")
+		return
+	}
+
+	// the paths are /jobs, /jobs/<jobID> and /jobs/<jobID>/<action>
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if segments[0] != "jobs" || len(segments) > 3 {
+		writeControlError(w, http.StatusNotFound, "unknown path "+r.URL.Path)
+		return
+	}
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			s.respondWithOutput(w, []string{"jobs", "list"})
+		case http.MethodPost:
+			s.submitJob(w, r)
+		default:
+			writeControlError(w, http.StatusMethodNotAllowed, "use GET to list jobs, or POST to submit one")
+		}
+		return
+	}
+
+	jobID, err := common.ParseJobID(segments[1])
+	if err != nil {
+		writeControlError(w, http.StatusBadRequest, fmt.Sprintf("%s is not a valid job ID", segments[1]))
+		return
+	}
+	if len(segments) == 2 {
+		if r.Method != http.MethodGet {
+			writeControlError(w, http.StatusMethodNotAllowed, "use GET to show a job")
+			return
+		}
+		s.respondWithOutput(w, []string{"jobs", "show", jobID.String()})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeControlError(w, http.StatusMethodNotAllowed, "use POST to "+segments[2]+" a job")
+		return
+	}
+	switch segments[2] {
+	case "pause":
+		s.respondWithOutput(w, []string{"jobs", "pause", jobID.String()})
+	case "resume":
+		s.submitArgs(w, []string{"jobs", "resume", jobID.String()})
+	case "retry":
+		s.submitArgs(w, []string{"jobs", "retry", jobID.String()})
+	case "cancel":
+		s.respondWithOutput(w, []string{"cancel", jobID.String()})
+	case "cancel-transfers":
+		var req controlCancelTransfersRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Sources) == 0 {
+			writeControlError(w, http.StatusBadRequest, "the request must be a JSON object, with the sources of the transfers in Sources")
+			return
+		}
+		// the sources follow "--", so that none of them can be taken for a flag
+		s.respondWithOutput(w, append([]string{"jobs", "cancel-transfers", jobID.String(), "--"}, req.Sources...))
+	default:
+		writeControlError(w, http.StatusNotFound, "unknown action "+segments[2])
+	}
+}
+
+// respondWithOutput runs a command that completes quickly, and responds with its result
+func (s *controlServer) respondWithOutput(w http.ResponseWriter, args []string) {
+	messages, err := s.run(args)
+	if err != nil {
+		writeControlError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		switch messages[i].MessageType {
+		case jsonMessageTypeError:
+			writeControlError(w, http.StatusBadRequest, messages[i].MessageContent)
+			return
+		case jsonMessageTypeEndOfJob:
+			writeControlResult(w, http.StatusOK, messages[i])
+			return
+		}
+	}
+	writeControlError(w, http.StatusInternalServerError, "AzCopy exited without a result")
+}
+
+func (s *controlServer) submitJob(w http.ResponseWriter, r *http.Request) {
+	var req controlSubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeControlError(w, http.StatusBadRequest, "the request must be a JSON object, with the command line in Args: "+err.Error())
+		return
+	}
+	if len(req.Args) == 0 || !controlServerJobCommands[req.Args[0]] {
+		writeControlError(w, http.StatusBadRequest, "only copy, sync and remove commands can be submitted")
+		return
+	}
+	s.submitArgs(w, req.Args)
+}
+
+// submitArgs starts a command that runs a job, and responds as soon as the job has started, with its job ID.
+// The job carries on running after that, in its own process
+func (s *controlServer) submitArgs(w http.ResponseWriter, args []string) {
+	messages, err := s.start(args)
+	if err != nil {
+		writeControlError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for m := range messages {
+		switch m.MessageType {
+		case jsonMessageTypeInit:
+			writeControlResult(w, http.StatusAccepted, m)
+		case jsonMessageTypeError:
+			writeControlError(w, http.StatusBadRequest, m.MessageContent)
+		default:
+			continue
+		}
+		// the rest of the output must still be read, or the job would stall once its stdout is full
+		go func() {
+			for range messages {
+			}
+		}()
+		return
+	}
+	writeControlError(w, http.StatusInternalServerError, "AzCopy exited without starting a job")
+}
+
+// writeControlResult responds with the content of the message, as JSON
+func writeControlResult(w http.ResponseWriter, status int, m common.JsonOutputTemplate) {
+	var body interface{} = struct{ Message string }{m.MessageContent}
+	if len(m.Payload) > 0 {
+		body = m.Payload
+	}
+	writeControlJSON(w, status, body)
+}
+
+func writeControlError(w http.ResponseWriter, status int, msg string) {
+	writeControlJSON(w, status, controlErrorResponse{Error: msg})
+}
+
+func writeControlJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func controlCommand(args []string) (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	// the JSON output type goes last, so that it takes precedence. Stdin isn't connected, so nothing waits for answers
+	cmd := exec.Command(exe, append(append([]string{}, args...), "--output-type", "json")...)
+	cmd.Stderr = os.Stderr
+	return cmd, nil
+}
+
+func runAzCopyForControl(args []string) ([]common.JsonOutputTemplate, error) {
+	messages, err := startAzCopyForControl(args)
+	if err != nil {
+		return nil, err
+	}
+	var result []common.JsonOutputTemplate
+	for m := range messages {
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+func startAzCopyForControl(args []string) (<-chan common.JsonOutputTemplate, error) {
+	cmd, err := controlCommand(args)
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	messages := make(chan common.JsonOutputTemplate)
+	go func() {
+		defer close(messages)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 64*1024*1024) // the output of jobs show can be large
+		for scanner.Scan() {
+			var m common.JsonOutputTemplate
+			if json.Unmarshal(scanner.Bytes(), &m) == nil {
+				messages <- m
+			}
+		}
+		_ = cmd.Wait() // the exit code is already reported in the output
+	}()
+	return messages, nil
+}
+
+func newControlToken() (string, error) {
+	if token := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.ControlToken()); token != "" {
+		return token, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func init() {
+	port := 0
+
+	serveCmd := &cobra.Command{
+		Use:     "serve",
+		Short:   serveCmdShortDescription,
+		Long:    serveCmdLongDescription,
+		Example: serveCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("serve command does not take any arguments")
+			}
+			if port < 0 || port > 65535 {
+				return fmt.Errorf("%d is not a valid port", port)
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			token, err := newControlToken()
+			if err != nil {
+				glcm.Error("cannot create the server's token: " + err.Error())
+				return
+			}
+			// only listen on the loopback interface, since jobs run with the credentials of the user running the server
+			listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			if err != nil {
+				glcm.Error("cannot start the control server: " + err.Error())
+				return
+			}
+
+			address := "http://" + listener.Addr().String()
+			glcm.Info("The control server is listening on " + address)
+			if common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.ControlToken()) == "" {
+				glcm.Info("Send this token, in the header 'Authorization: Bearer <token>', with each request: " + token)
+			}
+
+			err = http.Serve(listener, newControlServer(token))
+			glcm.Error("the control server has stopped: " + err.Error())
+		},
+	}
+
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.PersistentFlags().IntVar(&port, "port", 0, "Listen on this port of the local machine. (By default any free port is used, and shown when the server starts.)")
+}
@@ -23,6 +23,7 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync/atomic"
@@ -44,11 +45,18 @@ type rawSyncCmdArgs struct {
 	// options from flags
 	blockSizeMB           float64
 	logVerbosity          string
+	priority              string
+	activeHours           string
+	afterJob              string
 	include               string
 	exclude               string
 	excludePath           string
 	includeFileAttributes string
 	excludeFileAttributes string
+	includeAfter          string
+	includeBefore         string
+	minSize               string
+	maxSize               string
 	legacyInclude         string // for warning messages only
 	legacyExclude         string // for warning messages only
 
@@ -63,10 +71,24 @@ type rawSyncCmdArgs struct {
 	// which do not exists at source. With this flag turned on/off, users will not be asked for permission.
 	// otherwise the user is prompted to make a decision
 	deleteDestination string
+	// the most of the destination, as a percentage of its files, that may be deleted. 0 means there's no limit
+	deleteDestinationThreshold uint
+	// what to compare to find the files that have changed: last-modified times (none), or md5 hashes
+	compareHash string
+	// copy the changes made at either end to the other, rather than only those made at the source
+	twoWay             bool
+	conflictResolution string
+	// make the destination the same as the source, however it differs
+	mirror bool
 
 	s2sPreserveAccessTier bool
 
 	forceIfReadOnly bool
+
+	// list what would be copied and deleted, without changing anything
+	dryRun bool
+	// where to keep the job's plan
+	planLocation string
 }
 
 func (raw *rawSyncCmdArgs) parsePatterns(pattern string) (cookedPatterns []string) {
@@ -110,15 +132,23 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		return cooked, fmt.Errorf("Unable to infer the source '%s' / destination '%s'. ", raw.src, raw.dst)
 	} else if cooked.fromTo == common.EFromTo.LocalBlob() {
 		cooked.destination, err = SplitResourceString(raw.dst, cooked.fromTo.To())
-		common.PanicIfErr(err)
+		if err != nil {
+			return cooked, err
+		}
 	} else if cooked.fromTo == common.EFromTo.BlobLocal() {
 		cooked.source, err = SplitResourceString(raw.src, cooked.fromTo.From())
-		common.PanicIfErr(err)
+		if err != nil {
+			return cooked, err
+		}
 	} else if cooked.fromTo == common.EFromTo.BlobBlob() || cooked.fromTo == common.EFromTo.FileFile() {
 		cooked.destination, err = SplitResourceString(raw.dst, cooked.fromTo.To())
-		common.PanicIfErr(err)
+		if err != nil {
+			return cooked, err
+		}
 		cooked.source, err = SplitResourceString(raw.src, cooked.fromTo.From())
-		common.PanicIfErr(err)
+		if err != nil {
+			return cooked, err
+		}
 	} else {
 		return cooked, fmt.Errorf("source '%s' / destination '%s' combination '%s' not supported for sync command ", raw.src, raw.dst, cooked.fromTo)
 	}
@@ -174,6 +204,45 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	if err != nil {
 		return cooked, err
 	}
+	if raw.deleteDestinationThreshold > 100 {
+		return cooked, fmt.Errorf("the delete-destination-threshold is a percentage, so it can't be more than 100")
+	}
+	cooked.deleteDestinationThreshold = raw.deleteDestinationThreshold
+
+	if err = cooked.compareHash.Parse(raw.compareHash); err != nil {
+		return cooked, fmt.Errorf("invalid compare-hash value '%s'. Possible values are 'none' and 'md5'", raw.compareHash)
+	}
+
+	cooked.twoWay = raw.twoWay
+	cooked.mirror = raw.mirror
+	if cooked.twoWay && cooked.mirror {
+		return cooked, errors.New("two-way and mirror can't be used together, since a mirror only copies from the source")
+	}
+	if cooked.twoWay && cooked.compareHash != common.ESyncHashType.None() {
+		return cooked, errors.New("compare-hash can't be used with two-way, which finds the changes at each end by comparing it with how it was at the end of the last sync")
+	}
+
+	// the newest wins the conflicts of a two-way sync, unless the user says otherwise, and the source wins those of a mirror, since it's what the destination mirrors
+	cooked.conflictResolution = common.ESyncConflictResolution.NewestWins()
+	if cooked.mirror {
+		cooked.conflictResolution = common.ESyncConflictResolution.SourceWins()
+	}
+	if raw.conflictResolution != "" {
+		if !cooked.twoWay && !cooked.mirror {
+			return cooked, errors.New("conflict-resolution only applies to two-way and mirror syncs")
+		}
+		if err = cooked.conflictResolution.Parse(raw.conflictResolution); err != nil {
+			return cooked, fmt.Errorf("invalid conflict-resolution value '%s'. Possible values are 'NewestWins', 'SourceWins' and 'RenameBoth'", raw.conflictResolution)
+		}
+		if cooked.mirror && cooked.conflictResolution == common.ESyncConflictResolution.RenameBoth() {
+			return cooked, errors.New("RenameBoth only applies to two-way syncs. A mirror's conflicts are resolved by NewestWins or SourceWins")
+		}
+	}
+
+	// a mirror has no extra files. They are still asked about, if the user wants to be asked
+	if cooked.mirror && cooked.deleteDestination == common.EDeleteDestination.False() {
+		cooked.deleteDestination = common.EDeleteDestination.True()
+	}
 
 	// warn on legacy filters
 	if raw.legacyInclude != "" || raw.legacyExclude != "" {
@@ -186,13 +255,48 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	cooked.excludePaths = raw.parsePatterns(raw.excludePath)
 
 	// parse the attribute filter patterns
-	cooked.includeFileAttributes = raw.parsePatterns(raw.includeFileAttributes)
-	cooked.excludeFileAttributes = raw.parsePatterns(raw.excludeFileAttributes)
+	if cooked.includeFileAttributes, err = parseFileAttributes(raw.includeFileAttributes, "include-attributes"); err != nil {
+		return cooked, err
+	}
+	if cooked.excludeFileAttributes, err = parseFileAttributes(raw.excludeFileAttributes, "exclude-attributes"); err != nil {
+		return cooked, err
+	}
+
+	cooked.minSize, cooked.maxSize, err = parseFileSizeRange(raw.minSize, raw.maxSize)
+	if err != nil {
+		return cooked, err
+	}
+
+	// the destination's last modified times say when files were synced to it, not when they were changed, so only
+	// the source is filtered by them. All the destination's other files would then look like extra files, though
+	cooked.includeAfter, cooked.includeBefore, err = parseDateWindow(raw.includeAfter, raw.includeBefore)
+	if err != nil {
+		return cooked, err
+	}
+	if (cooked.includeAfter != nil || cooked.includeBefore != nil) &&
+		(cooked.deleteDestination != common.EDeleteDestination.False() || cooked.twoWay || cooked.mirror) {
+		return cooked, fmt.Errorf("%s and %s can't be used with delete-destination, two-way or mirror, since the destination's files that weren't changed in the given time would be deleted",
+			common.IncludeAfterFlagName, common.IncludeBeforeFlagName)
+	}
 
 	err = cooked.logVerbosity.Parse(raw.logVerbosity)
 	if err != nil {
 		return cooked, err
 	}
+	err = cooked.priority.Parse(raw.priority)
+	if err != nil {
+		return cooked, err
+	}
+	err = cooked.activeHours.Parse(raw.activeHours)
+	if err != nil {
+		return cooked, err
+	}
+	if raw.afterJob != "" {
+		cooked.afterJob, err = common.ParseJobID(raw.afterJob)
+		if err != nil {
+			return cooked, fmt.Errorf("the job to run after, %s, is not a valid job ID: %v", raw.afterJob, err)
+		}
+	}
 
 	if err = validatePreserveSMBPropertyOption(raw.preserveSMBPermissions, cooked.fromTo, nil, "preserve-smb-permissions"); err != nil {
 		return cooked, err
@@ -208,8 +312,16 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		return cooked, err
 	}
 
+	// a two-way sync copies both ways, so these are checked against the way that they apply to. Each job only uses those that apply to it
+	uploadFromTo, downloadFromTo := cooked.fromTo, cooked.fromTo
+	if cooked.twoWay && cooked.fromTo.IsDownload() {
+		uploadFromTo = cooked.fromTo.Reversed()
+	} else if cooked.twoWay && cooked.fromTo.IsUpload() {
+		downloadFromTo = cooked.fromTo.Reversed()
+	}
+
 	cooked.putMd5 = raw.putMd5
-	if err = validatePutMd5(cooked.putMd5, cooked.fromTo); err != nil {
+	if err = validatePutMd5(cooked.putMd5, uploadFromTo); err != nil {
 		return cooked, err
 	}
 
@@ -217,7 +329,7 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 	if err != nil {
 		return cooked, err
 	}
-	if err = validateMd5Option(cooked.md5ValidationOption, cooked.fromTo); err != nil {
+	if err = validateMd5Option(cooked.md5ValidationOption, downloadFromTo); err != nil {
 		return cooked, err
 	}
 
@@ -225,6 +337,16 @@ func (raw *rawSyncCmdArgs) cook() (cookedSyncCmdArgs, error) {
 		cooked.preserveAccessTier = raw.s2sPreserveAccessTier
 	}
 
+	if raw.dryRun {
+		cooked.dryRunPrinter = newDryRunPrinter()
+	}
+
+	if raw.planLocation != "" {
+		if err = cooked.planLocation.Parse(raw.planLocation); err != nil {
+			return cooked, fmt.Errorf("invalid plan-location value '%s'. Possible values are 'disk' and 'memory'", raw.planLocation)
+		}
+	}
+
 	return cooked, nil
 }
 
@@ -259,6 +381,10 @@ type cookedSyncCmdArgs struct {
 	excludePaths          []string
 	includeFileAttributes []string
 	excludeFileAttributes []string
+	includeAfter          *time.Time // only the source's files are filtered by their times
+	includeBefore         *time.Time
+	minSize               *int64 // nil when there's no limit
+	maxSize               *int64
 
 	// options
 	preserveSMBPermissions common.PreservePermissionsOption
@@ -267,6 +393,9 @@ type cookedSyncCmdArgs struct {
 	md5ValidationOption    common.HashValidationOption
 	blockSize              int64
 	logVerbosity           common.LogLevel
+	priority               common.JobPriority
+	activeHours            common.ActiveHours
+	afterJob               common.JobID // the job that this one waits for, if any
 	forceIfReadOnly        bool
 	backupMode             bool
 
@@ -286,6 +415,9 @@ type cookedSyncCmdArgs struct {
 	// used to calculate job summary
 	jobStartTime time.Time
 
+	// only set if the progress bar was requested
+	progressBar *progressBar
+
 	// this flag is set by the enumerator
 	// it is useful to indicate whether we are simply waiting for the purpose of cancelling
 	// this is set to true once the final part has been dispatched
@@ -295,8 +427,22 @@ type cookedSyncCmdArgs struct {
 	// which do not exists at source. With this flag turned on/off, users will not be asked for permission.
 	// otherwise the user is prompted to make a decision
 	deleteDestination common.DeleteDestination
+	// the sync fails, without deleting anything, if more than this percentage of the files at the destination would be deleted
+	deleteDestinationThreshold uint
+	// when set, files at both ends are compared by the hash of their content, instead of their last-modified times
+	compareHash common.SyncHashType
+	// when set, the changes made at either end since the last sync are copied to the other
+	twoWay             bool
+	conflictResolution common.SyncConflictResolution
+	// when set, every destination file that differs from the source is replaced, and the extra ones are deleted
+	mirror bool
 
 	preserveAccessTier bool
+
+	// set when only a dry run is wanted, in which case it prints the transfers and deletions instead of them being done
+	dryRunPrinter *dryRunPrinter
+
+	planLocation common.PlanLocation
 }
 
 func (cca *cookedSyncCmdArgs) incrementDeletionCount() {
@@ -332,11 +478,14 @@ func (cca *cookedSyncCmdArgs) scanningComplete() bool {
 // if blocking is specified to false, then another goroutine spawns and wait out the job
 func (cca *cookedSyncCmdArgs) waitUntilJobCompletion(blocking bool) {
 	// print initial message to indicate that the job is starting
-	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), fmt.Sprintf("%s%s%s.log", azcopyLogPathFolder, common.OS_PATH_SEPARATOR, cca.jobID), false, ""))
+	glcm.Init(common.GetStandardInitOutputBuilder(cca.jobID.String(), jobLogFilePath(cca.jobID), false, ""))
 
 	// initialize the times necessary to track progress
 	cca.jobStartTime = time.Now()
 	cca.intervalStartTime = time.Now()
+	if showProgressBar {
+		cca.progressBar = newProgressBar(cca.jobStartTime)
+	}
 	cca.intervalBytesTransferred = 0
 
 	// hand over control to the lifecycle manager if blocking
@@ -424,7 +573,7 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 	if cca.firstPartOrdered() {
 		Rpc(common.ERpcCmd.ListJobSummary(), &cca.jobID, &summary)
 		Rpc(common.ERpcCmd.GetJobLCMWrapper(), &cca.jobID, &lcm)
-		jobDone = summary.JobStatus.IsJobDone()
+		jobDone = summary.JobStatus.HasStopped()
 		totalKnownCount = summary.TotalTransfers
 
 		// compute the average throughput for the last time interval
@@ -449,6 +598,11 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 		if summary.TransfersFailed > 0 {
 			exitCode = common.EExitCode.Error()
 		}
+		summary.FinalJobSummary = common.NewFinalJobSummary(summary, duration, exitCode)
+		summary.FinalJobSummary.LogFileLocation = jobLogFilePath(summary.JobID)
+		if summary.JobStatus == common.EJobStatus.Paused() {
+			lcm.Info(common.Localize(common.EMessageKey.JobPaused(), summary.JobID, summary.JobID))
+		}
 
 		lcm.Exit(func(format common.OutputFormat) string {
 			if format == common.EOutputFormat.Json() {
@@ -456,22 +610,7 @@ func (cca *cookedSyncCmdArgs) ReportProgressOrExit(lcm common.LifecycleMgr) (tot
 			}
 			screenStats, logStats := formatExtraStats(cca.fromTo, summary.AverageIOPS, summary.AverageE2EMilliseconds, summary.NetworkErrorPercentage, summary.ServerBusyPercentage)
 
-			output := fmt.Sprintf(
-				`
-Job %s Summary
-Files Scanned at Source: %v
-Files Scanned at Destination: %v
-Elapsed Time (Minutes): %v
-Number of Copy Transfers for Files: %v
-Number of Copy Transfers for Folder Properties: %v 
-Total Number Of Copy Transfers: %v
-Number of Copy Transfers Completed: %v
-Number of Copy Transfers Failed: %v
-Number of Deletions at Destination: %v
-Total Number of Bytes Transferred: %v
-Total Number of Bytes Enumerated: %v
-Final Job Status: %v%s%s
-`,
+			output := common.Localize(common.EMessageKey.SyncJobSummary(),
 				summary.JobID.String(),
 				atomic.LoadUint64(&cca.atomicSourceFilesScanned),
 				atomic.LoadUint64(&cca.atomicDestinationFilesScanned),
@@ -487,6 +626,7 @@ Final Job Status: %v%s%s
 				summary.JobStatus,
 				screenStats,
 				formatPerfAdvice(summary.PerformanceAdvice))
+			output += formatFinalJobSummary(summary.FinalJobSummary)
 
 			jobMan, exists := ste.JobsAdmin.JobMgr(summary.JobID)
 			if exists {
@@ -501,29 +641,28 @@ Final Job Status: %v%s%s
 		if format == common.EOutputFormat.Json() {
 			return cca.getJsonOfSyncJobSummary(summary)
 		}
+		if cca.progressBar != nil {
+			return cca.progressBar.render(summary, time.Now(), getTerminalWidth())
+		}
 
 		// indicate whether constrained by disk or not
 		perfString, diskString := getPerfDisplayText(summary.PerfStrings, summary.PerfConstraint, duration, false)
 
-		return fmt.Sprintf("%.1f %%, %v Done, %v Failed, %v Pending, %v Total%s, 2-sec Throughput (Mb/s): %v%s",
+		return fmt.Sprintf("%.1f %%%s, %v Done, %v Failed, %v Pending%s, %v Total%s, 2-sec Throughput (Mb/s): %v%s",
 			summary.PercentComplete,
+			getByteProgressText(summary),
 			summary.TransfersCompleted,
 			summary.TransfersFailed,
 			summary.TotalTransfers-summary.TransfersCompleted-summary.TransfersFailed,
+			getStalledText(summary)+getThrottledText(summary),
 			summary.TotalTransfers, perfString, ste.ToFixed(throughput, 4), diskString)
 	})
 
 	return
 }
 
-func (cca *cookedSyncCmdArgs) process() (err error) {
-	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
-
-	err = common.SetBackupMode(cca.backupMode, cca.fromTo)
-	if err != nil {
-		return err
-	}
-
+// initCredentialInfo works out the credential that the job is given, which is the destination's, unless only the source needs OAuth
+func (cca *cookedSyncCmdArgs) initCredentialInfo(ctx context.Context) (err error) {
 	// Verifies credential type and initializes credential info.
 	// Note that this is for the destination.
 	cca.credentialInfo, _, err = getCredentialInfoForLocation(ctx, cca.fromTo.To(),
@@ -555,12 +694,47 @@ func (cca *cookedSyncCmdArgs) process() (err error) {
 			cca.credentialInfo.OAuthTokenInfo = *tokenInfo
 		}
 	}
+	return nil
+}
+
+func (cca *cookedSyncCmdArgs) process() (err error) {
+	ctx := context.WithValue(context.TODO(), ste.ServiceAPIVersionOverride, ste.DefaultServiceApiVersion)
+
+	// wait before doing anything else, since the source may well be changed by the job we're waiting for
+	if !cca.afterJob.IsEmpty() {
+		if err = waitForPrecedingJob(cca.jobID, cca.afterJob, cca.commandString); err != nil {
+			return err
+		}
+	}
+
+	err = common.SetBackupMode(cca.backupMode, cca.fromTo)
+	if err != nil {
+		return err
+	}
+
+	if err = cca.initCredentialInfo(ctx); err != nil {
+		return err
+	}
+
+	if cca.twoWay {
+		return cca.processTwoWay(ctx)
+	}
 
 	enumerator, err := cca.initEnumerator(ctx)
 	if err != nil {
 		return err
 	}
 
+	if cca.dryRunPrinter != nil {
+		if err = enumerator.enumerate(); err != nil {
+			return err
+		}
+		glcm.Exit(func(format common.OutputFormat) string {
+			return cca.dryRunPrinter.summary(false)
+		}, common.EExitCode.Success())
+		return nil
+	}
+
 	// trigger the progress reporting
 	cca.waitUntilJobCompletion(false)
 
@@ -610,6 +784,14 @@ func init() {
 	}
 
 	rootCmd.AddCommand(syncCmd)
+	batchJobPreparers[syncCmd] = func(commandString string) (batchJob, error) {
+		cooked, err := raw.cook()
+		if err != nil {
+			return batchJob{}, err
+		}
+		cooked.commandString = commandString
+		return batchJob{commandString: commandString, controller: &cooked, start: cooked.process}, nil
+	}
 	syncCmd.PersistentFlags().BoolVar(&raw.recursive, "recursive", true, "True by default, look into sub-directories recursively when syncing between directories. (default true).")
 
 	// TODO: enable for copy with IfSourceNewer
@@ -622,16 +804,48 @@ func init() {
 	//syncCmd.PersistentFlags().BoolVar(&raw.preserveOwner, common.PreserveOwnerFlagName, common.PreserveOwnerDefault, "Only has an effect in downloads, and only when --preserve-smb-permissions is used. If true (the default), the file Owner and Group are preserved in downloads. If set to false, --preserve-smb-permissions will still preserve ACLs but Owner and Group will be based on the user running AzCopy")
 	//syncCmd.PersistentFlags().BoolVar(&raw.backupMode, common.BackupModeFlagName, false, "Activates Windows' SeBackupPrivilege for uploads, or SeRestorePrivilege for downloads, to allow AzCopy to see read all files, regardless of their file system permissions, and to restore all permissions. Requires that the account running AzCopy already has these permissions (e.g. has Administrator rights or is a member of the 'Backup Operators' group). All this flag does is activate privileges that the account already has")
 
-	syncCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage or downloading from Azure Storage. Default is automatically calculated based on file size. Decimal fractions are allowed (For example: 0.25).")
+	syncCmd.PersistentFlags().Float64Var(&raw.blockSizeMB, "block-size-mb", 0, "Use this block size (specified in MiB) when uploading to Azure Storage or downloading from Azure Storage. Default is automatically calculated for each file, based on its size, so that no blob needs more than 50,000 blocks. Decimal fractions are allowed (For example: 0.25). The maximum is 4000.")
 	syncCmd.PersistentFlags().StringVar(&raw.include, "include-pattern", "", "Include only files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
+	syncCmd.PersistentFlags().StringVar(&raw.includeAfter, common.IncludeAfterFlagName, "", "Include only the source files modified on or after the given date/time, e.g. to sync just the files changed since the last sync, without comparing everything. The value should be in ISO8601 format. If no timezone is specified, the value is assumed to be in the local timezone of the machine running AzCopy. E.g. '2020-08-19T15:04:00Z' for a UTC time, or '2020-08-19' for midnight (00:00) in the local timezone. Can't be used with --delete-destination, --two-way or --mirror.")
+	syncCmd.PersistentFlags().StringVar(&raw.includeBefore, common.IncludeBeforeFlagName, "", "Include only the source files modified on or before the given date/time. The value is given in the same way as for --include-after.")
+	syncCmd.PersistentFlags().StringVar(&raw.minSize, "min-size", "", "Include only files that are at least this big. The size is a number of bytes, optionally followed by a unit: KiB, MiB, GiB and TiB (or K, M, G and T) are powers of 1024, and KB, MB, GB and TB are powers of 1000. E.g. 500, 64KiB or 1.5GB. As with the other filters, files outside the range are ignored at both the source and the destination, so they are neither copied nor deleted.")
+	syncCmd.PersistentFlags().StringVar(&raw.maxSize, "max-size", "", "Include only files that are no bigger than this. The size is given in the same way as for --min-size.")
 	syncCmd.PersistentFlags().StringVar(&raw.exclude, "exclude-pattern", "", "Exclude files where the name matches the pattern list. For example: *.jpg;*.pdf;exactName")
 	syncCmd.PersistentFlags().StringVar(&raw.excludePath, "exclude-path", "", "Exclude these paths when comparing the source against the destination. "+
 		"This option does not support wildcard characters (*). Checks relative path prefix(For example: myFolder;myFolder/subDirName/file.pdf).")
-	syncCmd.PersistentFlags().StringVar(&raw.includeFileAttributes, "include-attributes", "", "(Windows only) Include only files whose attributes match the attribute list. For example: A;S;R")
-	syncCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. For example: A;S;R")
+	syncCmd.PersistentFlags().StringVar(&raw.includeFileAttributes, "include-attributes", "", "(Windows only) Include only files whose attributes match the attribute list. The attributes may be given by name or by letter, and separated by ';' or ','. For example: A;S;R or archive,system,readonly")
+	syncCmd.PersistentFlags().StringVar(&raw.excludeFileAttributes, "exclude-attributes", "", "(Windows only) Exclude files whose attributes match the attribute list. The attributes may be given by name or by letter, and separated by ';' or ','. For example: hidden,system,temporary, to leave out files such as thumbs.db and desktop.ini. "+
+		"Only the attributes of files are checked, not those of folders, so use --exclude-path to leave out folders such as $RECYCLE.BIN.")
 	syncCmd.PersistentFlags().StringVar(&raw.logVerbosity, "log-level", "INFO", "Define the log verbosity for the log file, available levels: INFO(all requests and responses), WARNING(slow responses), ERROR(only failed requests), and NONE(no output logs). (default INFO).")
+	syncCmd.PersistentFlags().StringVar(&raw.priority, "priority", "Normal", "Run the job at this priority: Low, Normal or High (default Normal). "+
+		"While jobs of different priorities are running in the same AzCopy process, each gets a share of its workers: 6 in 10 for High, 3 for Normal and 1 for Low. "+
+		"Priorities only apply within one process. Jobs started by separate AzCopy commands each have their own workers, and don't slow each other down, whatever their priorities.")
+	syncCmd.PersistentFlags().StringVar(&raw.activeHours, "active-hours", "", "Only transfer data during these hours of each day, in local time. For example, 22:00-06:00 runs overnight. "+
+		"Outside them, the job waits without using the network, and carries on where it left off when they begin again. (By default the job can run at any time.)")
+	syncCmd.PersistentFlags().StringVar(&raw.afterJob, "after-job", "", "Wait for the job with this job ID to complete successfully before starting. "+
+		"If it fails, or is cancelled, this job is not started. Use it to queue up the stages of a migration, each in its own command prompt. "+
+		"While it waits, 'azcopy jobs list' shows this job as Queued, and further jobs can be queued after it.")
 	syncCmd.PersistentFlags().StringVar(&raw.deleteDestination, "delete-destination", "false", "Defines whether to delete extra files from the destination that are not present at the source. Could be set to true, false, or prompt. "+
 		"If set to prompt, the user will be asked a question before scheduling files and blobs for deletion. (default 'false').")
+	syncCmd.PersistentFlags().UintVar(&raw.deleteDestinationThreshold, "delete-destination-threshold", 0, "Stop the sync, without deleting anything, if more than this percentage of the files at the destination would be deleted. "+
+		"A guard against syncing from the wrong or an empty source. For example, 10 stops the sync if more than one file in ten would go. (By default there's no limit.)")
+	syncCmd.PersistentFlags().StringVar(&raw.compareHash, "compare-hash", common.ESyncHashType.None().String(), "Compare files by the hash of their content, instead of their last-modified times, to find those that have changed: 'none' (the default) or 'md5'. "+
+		"For when the times can't be trusted, e.g. files restored from a backup, or on a mounted share. Local files are hashed as needed, and their hashes are kept in the AzCopy folder, so that files that haven't changed aren't read again by the next sync. "+
+		"Remote files are compared by the MD5 hash stored with them, so upload with --put-md5. Files without one are compared by their last-modified times.")
+	syncCmd.PersistentFlags().BoolVar(&raw.twoWay, "two-way", false, "Copy the changes made at either end since the last sync to the other end, instead of making the destination like the source. "+
+		"How both ends were at the end of each sync is kept in the AzCopy folder, to tell which end a file has changed at. A file deleted at one end is deleted at the other if delete-destination is true (or prompt), and copied back otherwise. "+
+		"On the first two-way sync of a pair, files that are at both ends with the same size are taken to be the same.")
+	syncCmd.PersistentFlags().StringVar(&raw.conflictResolution, "conflict-resolution", "", "What a two-way sync does with a file that has changed at both ends: "+
+		"NewestWins (the default) copies the one modified last over the other, SourceWins copies the source's over the destination's, "+
+		"and RenameBoth overwrites neither, but keeps both versions at both ends, with '.source-conflict' or '.destination-conflict' added to their names, and reports the file as in conflict until it changes again. A file changed at one end and deleted at the other is always copied back. "+
+		"For a mirror, it's what's done with a destination file that differs from the source, and was modified after it: SourceWins (the default for a mirror) overwrites it, and NewestWins keeps it.")
+	syncCmd.PersistentFlags().BoolVar(&raw.mirror, "mirror", false, "Make the destination a mirror of the source: replace every destination file that differs from the source in size, content (where both have an MD5 hash, or with --compare-hash), "+
+		"or, when both are remote, in properties or metadata, as well as those the source is newer than, and delete the extra files (unless delete-destination is prompt). "+
+		"Without hashes at both ends, a file of the same size is only taken to have changed if the source was modified after the destination, so a change made at the destination that keeps the file's size, or one made to the source before the destination was last written, is only detected with --compare-hash. "+
+		"A report of how the two ends differed is printed when they have been compared.")
+	syncCmd.PersistentFlags().BoolVar(&raw.dryRun, "dry-run", false, "List the files that would be copied, and the extra files that would be deleted from the destination (if delete-destination is true or prompt), without changing anything. "+
+		"The source and destination are compared exactly as they would be for the sync.")
+	syncCmd.PersistentFlags().StringVar(&raw.planLocation, planLocationFlagName, common.EPlanLocation.Disk().String(), "Where to keep the job's plan: 'disk' (the default), in the plan folder, so that the job can be listed, shown and resumed later, or 'memory', so that nothing is written to the plan folder. A plan kept in memory goes when AzCopy exits, so the job can't be resumed. Useful for small jobs on read-only or diskless machines.")
 	syncCmd.PersistentFlags().BoolVar(&raw.putMd5, "put-md5", false, "Create an MD5 hash of each file, and save the hash as the Content-MD5 property of the destination blob or file. (By default the hash is NOT created.) Only available when uploading.")
 	syncCmd.PersistentFlags().StringVar(&raw.md5ValidationOption, "check-md5", common.DefaultHashValidationOption.String(), "Specifies how strictly MD5 hashes should be validated when downloading. This option is only available when downloading. Available values include: NoCheck, LogOnly, FailIfDifferent, FailIfDifferentOrMissing. (default 'FailIfDifferent').")
 	syncCmd.PersistentFlags().BoolVar(&raw.s2sPreserveAccessTier, "s2s-preserve-access-tier", true, "Preserve access tier during service to service copy. "+
@@ -32,10 +32,18 @@ type syncDestinationComparator struct {
 
 	// storing the source objects
 	sourceIndex *objectIndexer
+
+	// decides whether the destination object is out of date
+	isStale func(sourceObject, destinationObject storedObject) bool
 }
 
 func newSyncDestinationComparator(i *objectIndexer, copyScheduler, cleaner objectProcessor) *syncDestinationComparator {
-	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner}
+	return &syncDestinationComparator{sourceIndex: i, copyTransferScheduler: copyScheduler, destinationCleaner: cleaner, isStale: isSourceMoreRecent}
+}
+
+// by default, the destination is out of date when the source was modified more recently
+func isSourceMoreRecent(sourceObject, destinationObject storedObject) bool {
+	return sourceObject.isMoreRecentThan(destinationObject)
 }
 
 // it will only schedule transfers for destination objects that are present in the indexer but stale compared to the entry in the map
@@ -50,7 +58,7 @@ func (f *syncDestinationComparator) processIfNecessary(destinationObject storedO
 	if present {
 		defer delete(f.sourceIndex.indexMap, destinationObject.relativePath)
 
-		if sourceObjectInMap.isMoreRecentThan(destinationObject) {
+		if f.isStale(sourceObjectInMap, destinationObject) {
 			err := f.copyTransferScheduler(sourceObjectInMap)
 			if err != nil {
 				return err
@@ -74,10 +82,13 @@ type syncSourceComparator struct {
 
 	// storing the destination objects
 	destinationIndex *objectIndexer
+
+	// decides whether the destination object is out of date
+	isStale func(sourceObject, destinationObject storedObject) bool
 }
 
 func newSyncSourceComparator(i *objectIndexer, copyScheduler objectProcessor) *syncSourceComparator {
-	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler}
+	return &syncSourceComparator{destinationIndex: i, copyTransferScheduler: copyScheduler, isStale: isSourceMoreRecent}
 }
 
 // it will only transfer source items that are:
@@ -92,7 +103,7 @@ func (f *syncSourceComparator) processIfNecessary(sourceObject storedObject) err
 		defer delete(f.destinationIndex.indexMap, sourceObject.relativePath)
 
 		// if destination is stale, schedule source for transfer
-		if sourceObject.isMoreRecentThan(destinationObjectInMap) {
+		if f.isStale(sourceObject, destinationObjectInMap) {
 			return f.copyTransferScheduler(sourceObject)
 		}
 		// skip if source is more recent
@@ -58,7 +58,7 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	// TODO: enable symlink support in a future release after evaluating the implications
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
-	sourceTraverser, err := initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, nil, nil, cca.recursive, true, false, func(entityType common.EntityType) {
+	sourceTraverser, err := initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &srcCredInfo, common.ESymlinkHandlingType.Skip(), nil, cca.recursive, true, false, func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicSourceFilesScanned, 1)
 		}
@@ -78,7 +78,7 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	// TODO: enable symlink support in a future release after evaluating the implications
 	// GetProperties is enabled by default as sync supports both upload and download.
 	// This property only supports Files and S3 at the moment, but provided that Files sync is coming soon, enable to avoid stepping on Files sync work
-	destinationTraverser, err := initResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, nil, nil, cca.recursive, true, false, func(entityType common.EntityType) {
+	destinationTraverser, err := initResourceTraverser(cca.destination, cca.fromTo.To(), &ctx, &dstCredInfo, common.ESymlinkHandlingType.Skip(), nil, cca.recursive, true, false, func(entityType common.EntityType) {
 		if entityType == common.EEntityType.File() {
 			atomic.AddUint64(&cca.atomicDestinationFilesScanned, 1)
 		}
@@ -96,27 +96,18 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	// Note: includeFilters and includeAttrFilters are ANDed
 	// They must both pass to get the file included
 	// Same rule applies to excludeFilters and excludeAttrFilters
-	filters := buildIncludeFilters(cca.includePatterns)
-	if cca.fromTo.From() == common.ELocation.Local() {
-		includeAttrFilters := buildAttrFilters(cca.includeFileAttributes, cca.source.ValueLocal(), true)
-		filters = append(filters, includeAttrFilters...)
-	}
-
-	filters = append(filters, buildExcludeFilters(cca.excludePatterns, false)...)
-	filters = append(filters, buildExcludeFilters(cca.excludePaths, true)...)
-	if cca.fromTo.From() == common.ELocation.Local() {
-		excludeAttrFilters := buildAttrFilters(cca.excludeFileAttributes, cca.source.ValueLocal(), false)
-		filters = append(filters, excludeAttrFilters...)
-	}
+	filters := cca.buildFilters()
 	// after making all filters, log any search prefix computed from them
 	if ste.JobsAdmin != nil {
 		if prefixFilter := filterSet(filters).GetEnumerationPreFilter(cca.recursive); prefixFilter != "" {
 			ste.JobsAdmin.LogToJobLog("Search prefix, which may be used to optimize scanning, is: "+prefixFilter, pipeline.LogInfo) // "May be used" because we don't know here which enumerators will use it
 		}
 	}
+	// the date filters apply only to the source
+	sourceFilters := append(buildDateFilters(cca.includeAfter, cca.includeBefore), filters...)
 
 	// decide our folder transfer strategy
-	fpo, folderMessage := newFolderPropertyOption(cca.fromTo, cca.recursive, true, filters, cca.preserveSMBInfo, cca.preserveSMBPermissions.IsTruthy()) // sync always acts like stripTopDir=true
+	fpo, folderMessage := newFolderPropertyOption(cca.fromTo, cca.recursive, true, sourceFilters, cca.preserveSMBInfo, cca.preserveSMBPermissions.IsTruthy()) // sync always acts like stripTopDir=true
 	glcm.Info(folderMessage)
 	if ste.JobsAdmin != nil {
 		ste.JobsAdmin.LogToJobLog(folderMessage, pipeline.LogInfo)
@@ -129,6 +120,21 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 	var comparator objectProcessor
 	var finalize func() error
 
+	var hashComparer *syncHashComparer
+	var isStale func(sourceObject, destinationObject storedObject) bool
+	if cca.compareHash == common.ESyncHashType.MD5() {
+		hashComparer = newSyncHashComparer(cca)
+		isStale = hashComparer.isStale
+	}
+
+	scheduleCopyTransfer := transferScheduler.scheduleCopyTransfer
+	var mirror *syncMirrorComparer
+	if cca.mirror {
+		mirror = newSyncMirrorComparer(cca, hashComparer)
+		isStale = mirror.isStale
+		scheduleCopyTransfer = mirror.countTransfers(scheduleCopyTransfer)
+	}
+
 	switch cca.fromTo {
 	case common.EFromTo.LocalBlob():
 		// upload implies transferring from a local disk to a remote resource
@@ -139,18 +145,35 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 		if err != nil {
 			return nil, fmt.Errorf("unable to instantiate destination cleaner due to: %s", err.Error())
 		}
-		destCleanerFunc := newFpoAwareProcessor(fpo, destinationCleaner.removeImmediately)
+		pendingDeletions := newPendingDeletions(cca)
+		destCleanerFunc := newFpoAwareProcessor(fpo, pendingDeletions.add)
 
-		// when uploading, we can delete remote objects immediately, because as we traverse the remote location
+		// when uploading, we know which remote objects to delete as soon as we see them, because as we traverse the remote location
 		// we ALREADY have available a complete map of everything that exists locally
-		// so as soon as we see a remote destination object we can know whether it exists in the local source
-		comparator = newSyncDestinationComparator(indexer, transferScheduler.scheduleCopyTransfer, destCleanerFunc).processIfNecessary
+		// they are only deleted once the traversal is done though, so that the deletions can be weighed against the whole destination
+		destinationComparator := newSyncDestinationComparator(indexer, scheduleCopyTransfer, destCleanerFunc)
+		if isStale != nil {
+			destinationComparator.isStale = isStale
+		}
+		comparator = destinationComparator.processIfNecessary
 		finalize = func() error {
+			if hashComparer != nil {
+				hashComparer.saveCache()
+			}
+
+			err = pendingDeletions.deleteAll(destinationCleaner.removeImmediately)
+			if err != nil {
+				return err
+			}
+
 			// schedule every local file that doesn't exist at the destination
-			err = indexer.traverse(transferScheduler.scheduleCopyTransfer, filters)
+			err = indexer.traverse(scheduleCopyTransfer, filters)
 			if err != nil {
 				return err
 			}
+			if mirror != nil {
+				glcm.Info(mirror.report(pendingDeletions.fileCount))
+			}
 
 			jobInitiated, err := transferScheduler.dispatchFinalPart()
 			// sync cleanly exits if nothing is scheduled.
@@ -163,32 +186,48 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 			return nil
 		}
 
-		return newSyncEnumerator(sourceTraverser, destinationTraverser, indexer, filters, comparator, finalize), nil
+		return newSyncEnumerator(sourceTraverser, destinationTraverser, indexer, sourceFilters, filters, comparator, finalize), nil
 	default:
 		// in all other cases (download and S2S), the destination is scanned/indexed first
 		// then the source is scanned and filtered based on what the destination contains
-		comparator = newSyncSourceComparator(indexer, transferScheduler.scheduleCopyTransfer).processIfNecessary
+		sourceComparator := newSyncSourceComparator(indexer, scheduleCopyTransfer)
+		if isStale != nil {
+			sourceComparator.isStale = isStale
+		}
+		comparator = sourceComparator.processIfNecessary
 
 		finalize = func() error {
+			if hashComparer != nil {
+				hashComparer.saveCache()
+			}
+
 			// remove the extra files at the destination that were not present at the source
 			// we can only know what needs to be deleted when we have FINISHED traversing the remote source
 			// since only then can we know which local files definitely don't exist remotely
-			var deleteScheduler objectProcessor
+			var deleter *interactiveDeleteProcessor
 			switch cca.fromTo.To() {
 			case common.ELocation.Blob(), common.ELocation.File():
-				deleter, err := newSyncDeleteProcessor(cca)
+				deleter, err = newSyncDeleteProcessor(cca)
 				if err != nil {
 					return err
 				}
-				deleteScheduler = newFpoAwareProcessor(fpo, deleter.removeImmediately)
 			default:
-				deleteScheduler = newFpoAwareProcessor(fpo, newSyncLocalDeleteProcessor(cca).removeImmediately)
+				deleter = newSyncLocalDeleteProcessor(cca)
+			}
+
+			pendingDeletions := newPendingDeletions(cca)
+			err = indexer.traverse(newFpoAwareProcessor(fpo, pendingDeletions.add), nil)
+			if err != nil {
+				return err
 			}
 
-			err = indexer.traverse(deleteScheduler, nil)
+			err = pendingDeletions.deleteAll(deleter.removeImmediately)
 			if err != nil {
 				return err
 			}
+			if mirror != nil {
+				glcm.Info(mirror.report(pendingDeletions.fileCount))
+			}
 
 			// let the deletions happen first
 			// otherwise if the final part is executed too quickly, we might quit before deletions could finish
@@ -203,11 +242,32 @@ func (cca *cookedSyncCmdArgs) initEnumerator(ctx context.Context) (enumerator *s
 			return nil
 		}
 
-		return newSyncEnumerator(destinationTraverser, sourceTraverser, indexer, filters, comparator, finalize), nil
+		return newSyncEnumerator(destinationTraverser, sourceTraverser, indexer, filters, sourceFilters, comparator, finalize), nil
 	}
 }
 
+// buildFilters makes the filters for the source, in the right order
+func (cca *cookedSyncCmdArgs) buildFilters() []objectFilter {
+	filters := buildIncludeFilters(cca.includePatterns)
+	if cca.fromTo.From() == common.ELocation.Local() {
+		includeAttrFilters := buildAttrFilters(cca.includeFileAttributes, cca.source.ValueLocal(), true)
+		filters = append(filters, includeAttrFilters...)
+	}
+
+	filters = append(filters, buildExcludeFilters(cca.excludePatterns, false)...)
+	filters = append(filters, buildExcludeFilters(cca.excludePaths, true)...)
+	if cca.fromTo.From() == common.ELocation.Local() {
+		excludeAttrFilters := buildAttrFilters(cca.excludeFileAttributes, cca.source.ValueLocal(), false)
+		filters = append(filters, excludeAttrFilters...)
+	}
+	filters = append(filters, buildFileSizeFilters(cca.minSize, cca.maxSize)...)
+	return filters
+}
+
 func quitIfInSync(transferJobInitiated, anyDestinationFileDeleted bool, cca *cookedSyncCmdArgs) {
+	if cca.dryRunPrinter != nil {
+		return // there's no job to wait for, so the outcome is reported as soon as the enumeration is done
+	}
 	if !transferJobInitiated && !anyDestinationFileDeleted {
 		cca.reportScanningProgress(glcm, 0)
 		glcm.Exit(func(format common.OutputFormat) string {
@@ -0,0 +1,201 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// syncHashComparer compares files by the MD5 hashes of their content, rather than their last-modified times,
+// for when the times can't be trusted (e.g. files restored from a backup, or on a mounted share).
+// Local files have no stored hash, so they are hashed as they are compared, and the hashes are cached,
+// so that the files that haven't changed since the last sync aren't read again
+type syncHashComparer struct {
+	// the local end of the sync, if there is one
+	localRoot     string
+	localIsSource bool
+	cache         *localHashCache
+
+	fallbackWarning sync.Once
+}
+
+func newSyncHashComparer(cca *cookedSyncCmdArgs) *syncHashComparer {
+	h := &syncHashComparer{}
+	switch {
+	case cca.fromTo.From() == common.ELocation.Local():
+		h.localRoot, h.localIsSource = cca.source.ValueLocal(), true
+	case cca.fromTo.To() == common.ELocation.Local():
+		h.localRoot = cca.destination.ValueLocal()
+	}
+	if h.localRoot != "" && azcopyAppPathFolder != "" {
+		h.cache = loadLocalHashCache(filepath.Join(azcopyAppPathFolder, "hashes"), h.localRoot)
+	}
+	return h
+}
+
+// isStale reports whether the destination has different content from the source.
+// Files that can't be hashed on both ends are compared by their last-modified times, as they would be without a hash
+func (h *syncHashComparer) isStale(sourceObject, destinationObject storedObject) bool {
+	if sourceObject.entityType != common.EEntityType.File() {
+		return sourceObject.isMoreRecentThan(destinationObject)
+	}
+	if sourceObject.size != destinationObject.size {
+		return true // no need to read anything to tell these apart
+	}
+
+	sourceHash, destinationHash := h.hashOf(sourceObject, h.localIsSource), h.hashOf(destinationObject, !h.localIsSource)
+	if len(sourceHash) == 0 || len(destinationHash) == 0 {
+		h.fallbackWarning.Do(func() {
+			glcm.Warn("Some files have no MD5 hash to compare, so they were compared by their last-modified times instead. " +
+				"Upload with --put-md5 to store the hashes of the files with them.")
+		})
+		return sourceObject.isMoreRecentThan(destinationObject)
+	}
+	return !bytes.Equal(sourceHash, destinationHash)
+}
+
+func (h *syncHashComparer) hashOf(object storedObject, isLocal bool) []byte {
+	if !isLocal || h.localRoot == "" {
+		return object.md5
+	}
+
+	if hash := h.cache.get(object); hash != nil {
+		return hash
+	}
+	hash, err := hashLocalFile(common.GenerateFullPath(h.localRoot, object.relativePath))
+	if err != nil {
+		glcm.Info(fmt.Sprintf("Cannot hash %s, so it is compared by its last-modified time: %s", object.relativePath, err))
+		return nil
+	}
+	h.cache.put(object, hash)
+	return hash
+}
+
+// saveCache keeps the hashes for the next sync. Failing to do that only costs the next sync some reading, so it's not an error
+func (h *syncHashComparer) saveCache() {
+	if err := h.cache.save(); err != nil {
+		glcm.Info("Cannot save the hashes of the local files for the next sync: " + err.Error())
+	}
+}
+
+func hashLocalFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hasher := md5.New()
+	if _, err = io.Copy(hasher, f); err != nil {
+		return nil, err
+	}
+	return hasher.Sum(nil), nil
+}
+
+// localHashCacheEntry is the hash of a local file, which is good for as long as its size and last-modified time stay the same
+type localHashCacheEntry struct {
+	Size         int64
+	LastModified time.Time
+	MD5          []byte
+}
+
+// localHashCache holds the hashes of the files under one local directory, keyed by their relative paths.
+// Only the hashes used by a sync are saved, so that those of files that have gone don't pile up.
+// A nil cache holds nothing
+type localHashCache struct {
+	path    string
+	entries map[string]localHashCacheEntry
+	used    map[string]localHashCacheEntry
+}
+
+// loadLocalHashCache loads the cache of the directory localRoot from the folder. A missing or unreadable cache starts out empty
+func loadLocalHashCache(folder, localRoot string) *localHashCache {
+	if absRoot, err := filepath.Abs(localRoot); err == nil {
+		localRoot = absRoot
+	}
+	name := sha256.Sum256([]byte(localRoot))
+	c := &localHashCache{
+		path:    filepath.Join(folder, hex.EncodeToString(name[:16])+".json"),
+		entries: make(map[string]localHashCacheEntry),
+		used:    make(map[string]localHashCacheEntry),
+	}
+
+	if content, err := ioutil.ReadFile(c.path); err == nil {
+		if json.Unmarshal(content, &c.entries) != nil {
+			c.entries = make(map[string]localHashCacheEntry)
+		}
+	}
+	return c
+}
+
+func (c *localHashCache) get(object storedObject) []byte {
+	if c == nil {
+		return nil
+	}
+	entry, ok := c.entries[object.relativePath]
+	if !ok || entry.Size != object.size || !entry.LastModified.Equal(object.lastModifiedTime) {
+		return nil
+	}
+	c.used[object.relativePath] = entry
+	return entry.MD5
+}
+
+func (c *localHashCache) put(object storedObject, hash []byte) {
+	if c == nil {
+		return
+	}
+	entry := localHashCacheEntry{Size: object.size, LastModified: object.lastModifiedTime, MD5: hash}
+	c.entries[object.relativePath] = entry
+	c.used[object.relativePath] = entry
+}
+
+func (c *localHashCache) save() error {
+	if c == nil {
+		return nil
+	}
+	content, err := json.Marshal(c.used)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), os.ModeDir|os.ModePerm); err != nil {
+		return err
+	}
+
+	// write it aside first, so that a sync that's cut short can't leave half a cache behind
+	tempPath := c.path + ".tmp"
+	if err = ioutil.WriteFile(tempPath, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, c.path)
+}
@@ -0,0 +1,164 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
+)
+
+// syncMirrorComparer makes the destination a mirror of the source. A destination file is replaced whenever it differs
+// from the source (not only when the source is newer), and the differences are counted up, for the reconciliation report
+type syncMirrorComparer struct {
+	// the properties and metadata are only compared when both ends are remote, since local files have none
+	compareProperties bool
+	// set when the content is to be compared by hash
+	hashComparer *syncHashComparer
+	resolution   common.SyncConflictResolution
+
+	// for the report
+	transferCount     uint64
+	sameCount         uint64
+	differenceCounts  map[string]uint64
+	overwrittenCount  uint64
+	keptNewerCount    uint64
+	replacedFileCount uint64
+}
+
+func newSyncMirrorComparer(cca *cookedSyncCmdArgs, hashComparer *syncHashComparer) *syncMirrorComparer {
+	return newMirrorComparer(cca.fromTo, cca.conflictResolution, hashComparer)
+}
+
+// newMirrorComparer is also used by copy --mirror, which has no conflict resolution or hash comparison options of its own
+func newMirrorComparer(fromTo common.FromTo, resolution common.SyncConflictResolution, hashComparer *syncHashComparer) *syncMirrorComparer {
+	return &syncMirrorComparer{
+		compareProperties: fromTo.IsS2S(),
+		hashComparer:      hashComparer,
+		resolution:        resolution,
+		differenceCounts:  make(map[string]uint64),
+	}
+}
+
+// isStale reports whether the destination file has to be replaced to mirror the source.
+// One that was modified after the source, and differs from it, is a conflict, which the source wins, unless the newest is to
+func (m *syncMirrorComparer) isStale(sourceObject, destinationObject storedObject) bool {
+	if sourceObject.entityType != common.EEntityType.File() {
+		return sourceObject.isMoreRecentThan(destinationObject)
+	}
+
+	difference := m.differenceOf(sourceObject, destinationObject)
+	if difference == "" {
+		m.sameCount++
+		return false
+	}
+
+	if destinationObject.isMoreRecentThan(sourceObject) {
+		if m.resolution == common.ESyncConflictResolution.NewestWins() {
+			m.keptNewerCount++
+			glcm.Info(fmt.Sprintf("Conflict: %s differs in %s, and was modified at the destination after the source. Kept, since the newest wins.", sourceObject.relativePath, difference))
+			return false
+		}
+		m.overwrittenCount++
+		glcm.Info(fmt.Sprintf("Conflict: %s differs in %s, and was modified at the destination after the source. Overwritten, since the source wins.", sourceObject.relativePath, difference))
+	}
+
+	m.replacedFileCount++
+	m.differenceCounts[difference]++
+	if ste.JobsAdmin != nil {
+		ste.JobsAdmin.LogToJobLog(fmt.Sprintf("Mirror: %s differs in %s, so it is replaced", sourceObject.relativePath, difference), pipeline.LogInfo)
+	}
+	return true
+}
+
+// differenceOf names the first way in which the destination file differs from the source, or returns "" if it doesn't
+func (m *syncMirrorComparer) differenceOf(sourceObject, destinationObject storedObject) string {
+	if sourceObject.size != destinationObject.size {
+		return "size"
+	}
+
+	var sourceHash, destinationHash []byte
+	if m.hashComparer != nil {
+		sourceHash, destinationHash = m.hashComparer.hashOf(sourceObject, m.hashComparer.localIsSource), m.hashComparer.hashOf(destinationObject, !m.hashComparer.localIsSource)
+	} else {
+		sourceHash, destinationHash = sourceObject.md5, destinationObject.md5
+	}
+	if len(sourceHash) > 0 && len(destinationHash) > 0 {
+		if !bytes.Equal(sourceHash, destinationHash) {
+			return "content"
+		}
+	} else if sourceObject.isMoreRecentThan(destinationObject) {
+		// without hashes, a source modified after the destination is taken to have changed, as it is by sync
+		return "last-modified time"
+	}
+
+	if m.compareProperties {
+		if sourceObject.contentType != destinationObject.contentType ||
+			sourceObject.contentEncoding != destinationObject.contentEncoding ||
+			sourceObject.contentLanguage != destinationObject.contentLanguage ||
+			sourceObject.contentDisposition != destinationObject.contentDisposition ||
+			sourceObject.cacheControl != destinationObject.cacheControl {
+			return "properties"
+		}
+		if (len(sourceObject.Metadata) > 0 || len(destinationObject.Metadata) > 0) && !reflect.DeepEqual(sourceObject.Metadata, destinationObject.Metadata) {
+			return "metadata"
+		}
+	}
+	return ""
+}
+
+// countTransfers wraps the transfer scheduler, to count the files scheduled
+func (m *syncMirrorComparer) countTransfers(scheduleCopyTransfer objectProcessor) objectProcessor {
+	return func(object storedObject) error {
+		if object.entityType == common.EEntityType.File() {
+			m.transferCount++
+		}
+		return scheduleCopyTransfer(object)
+	}
+}
+
+// report sums up how the destination was made a mirror of the source
+func (m *syncMirrorComparer) report(extraFileCount uint64) string {
+	differences := make([]string, 0, len(m.differenceCounts))
+	for _, difference := range []string{"size", "content", "last-modified time", "properties", "metadata"} {
+		if count := m.differenceCounts[difference]; count > 0 {
+			differences = append(differences, fmt.Sprintf("%d in %s", count, difference))
+		}
+	}
+
+	s := fmt.Sprintf("Mirror report: %d files were the same at both ends, %d were missing from the destination, and %d differed",
+		m.sameCount, m.transferCount-m.replacedFileCount, m.replacedFileCount)
+	if len(differences) > 0 {
+		s += " (" + strings.Join(differences, ", ") + ")"
+	}
+	s += fmt.Sprintf(". %d extra files were at the destination.", extraFileCount)
+	if m.overwrittenCount+m.keptNewerCount > 0 {
+		s += fmt.Sprintf(" %d files had been modified at the destination after the source: %d were overwritten, and %d kept.",
+			m.overwrittenCount+m.keptNewerCount, m.overwrittenCount, m.keptNewerCount)
+	}
+	return s
+}
@@ -26,6 +26,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"sync/atomic"
 
 	"github.com/Azure/azure-storage-file-go/azfile"
 
@@ -55,12 +56,15 @@ func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int,
 		ForceWrite:                     common.EOverwriteOption.True(), // once we decide to transfer for a sync operation, we overwrite the destination regardless
 		ForceIfReadOnly:                cca.forceIfReadOnly,
 		LogLevel:                       cca.logVerbosity,
+		Priority:                       cca.priority,
+		ActiveHours:                    cca.activeHours,
 		PreserveSMBPermissions:         cca.preserveSMBPermissions,
 		PreserveSMBInfo:                cca.preserveSMBInfo,
 		S2SSourceChangeValidation:      true,
 		DestLengthValidation:           true,
 		S2SGetPropertiesInBackend:      true,
 		S2SInvalidMetadataHandleOption: common.EInvalidMetadataHandleOption.RenameIfInvalid(),
+		PlanLocation:                   cca.planLocation,
 	}
 
 	reportFirstPart := func(jobStarted bool) { cca.setFirstPartOrdered() } // for compatibility with the way sync has always worked, we don't check jobStarted here
@@ -68,8 +72,10 @@ func newSyncTransferProcessor(cca *cookedSyncCmdArgs, numOfTransfersPerPart int,
 
 	// note that the source and destination, along with the template are given to the generic processor's constructor
 	// this means that given an object with a relative path, this processor already knows how to schedule the right kind of transfers
-	return newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
+	processor := newCopyTransferProcessor(copyJobTemplate, numOfTransfersPerPart, cca.source, cca.destination,
 		reportFirstPart, reportFinalPart, cca.preserveAccessTier)
+	processor.dryRunPrinter = cca.dryRunPrinter
+	return processor
 }
 
 // base for delete processors targeting different resources
@@ -116,7 +122,7 @@ func (d *interactiveDeleteProcessor) removeImmediately(object storedObject) (err
 }
 
 func (d *interactiveDeleteProcessor) promptForConfirmation(object storedObject) (shouldDelete bool, keepPrompting bool) {
-	answer := glcm.Prompt(fmt.Sprintf("The %s '%s' does not exist at the source. "+
+	answer := glcm.PromptForChoice(fmt.Sprintf("The %s '%s' does not exist at the source. "+
 		"Do you wish to delete it from the destination(%s)?",
 		d.objectTypeToDisplay, object.relativePath, d.objectLocationToDisplay),
 		common.PromptDetails{
@@ -162,7 +168,94 @@ func newInteractiveDeleteProcessor(deleter objectProcessor, deleteDestination co
 	}
 }
 
+// pendingDeletions holds back the deletion of the extra files found at the destination until all of them are known,
+// so that they can be summarized, and weighed against the deletion threshold, before the first one goes
+type pendingDeletions struct {
+	cca       *cookedSyncCmdArgs
+	objects   []storedObject
+	fileCount uint64
+}
+
+func newPendingDeletions(cca *cookedSyncCmdArgs) *pendingDeletions {
+	return &pendingDeletions{cca: cca}
+}
+
+func (p *pendingDeletions) add(object storedObject) error {
+	if p.cca.deleteDestination == common.EDeleteDestination.False() {
+		return nil // nothing is going to be deleted, so there's no need to hold on to it
+	}
+	p.objects = append(p.objects, object)
+	if object.entityType == common.EEntityType.File() {
+		p.fileCount++
+	}
+	return nil
+}
+
+// deleteAll summarizes the pending deletions, and hands them to the deleter, unless they'd take more of the destination than the threshold allows
+func (p *pendingDeletions) deleteAll(deleter objectProcessor) error {
+	if p.fileCount == 0 {
+		return nil
+	}
+
+	err := p.checkThreshold()
+	if err != nil {
+		return err
+	}
+	destinationFileCount := atomic.LoadUint64(&p.cca.atomicDestinationFilesScanned)
+
+	summary := fmt.Sprintf("%d of the %d files at the destination are not at the source.", p.fileCount, destinationFileCount)
+	switch {
+	case p.cca.dryRunPrinter != nil:
+		summary += " They would be deleted."
+	case p.cca.deleteDestination == common.EDeleteDestination.Prompt():
+		summary += " You will be asked before they are deleted."
+	default:
+		summary += " They will be deleted."
+	}
+	glcm.Info(summary)
+
+	for _, object := range p.objects {
+		if err = deleter(object); err != nil {
+			return err
+		}
+	}
+	p.objects = nil
+	return nil
+}
+
+func (p *pendingDeletions) checkThreshold() error {
+	return checkDeletionThreshold(p.fileCount, atomic.LoadUint64(&p.cca.atomicDestinationFilesScanned), p.cca.deleteDestinationThreshold)
+}
+
+// checkDeletionThreshold fails when more than thresholdPercent of the files at the destination would be deleted.
+// A threshold of 0 means there's no limit
+func checkDeletionThreshold(deletionCount, destinationFileCount uint64, thresholdPercent uint) error {
+	if thresholdPercent == 0 || destinationFileCount == 0 {
+		return nil
+	}
+
+	if deletionCount*100 > uint64(thresholdPercent)*destinationFileCount {
+		return fmt.Errorf("%d of the %d files at the destination (%d%%) are not at the source, which is more than the --delete-destination-threshold of %d%%. "+
+			"Nothing was deleted. Check that the source is the right one, or raise the threshold if that many deletions are intended",
+			deletionCount, destinationFileCount, deletionCount*100/destinationFileCount, thresholdPercent)
+	}
+	return nil
+}
+
+// in a dry run, the extra files are listed instead of being deleted. If the user would have been asked about
+// deleting them, they are listed without asking
+func newDryRunDeleteProcessor(cca *cookedSyncCmdArgs) *interactiveDeleteProcessor {
+	deleteDestination := cca.deleteDestination
+	if deleteDestination == common.EDeleteDestination.Prompt() {
+		deleteDestination = common.EDeleteDestination.True()
+	}
+	return newInteractiveDeleteProcessor(cca.dryRunPrinter.printDeletion, deleteDestination, "file", cca.destination, nil)
+}
+
 func newSyncLocalDeleteProcessor(cca *cookedSyncCmdArgs) *interactiveDeleteProcessor {
+	if cca.dryRunPrinter != nil {
+		return newDryRunDeleteProcessor(cca)
+	}
 	localDeleter := localFileDeleter{rootPath: cca.destination.ValueLocal()}
 	return newInteractiveDeleteProcessor(localDeleter.deleteFile, cca.deleteDestination, "local file", cca.destination, cca.incrementDeletionCount)
 }
@@ -199,6 +292,10 @@ func (l *localFileDeleter) deleteFile(object storedObject) error {
 }
 
 func newSyncDeleteProcessor(cca *cookedSyncCmdArgs) (*interactiveDeleteProcessor, error) {
+	if cca.dryRunPrinter != nil {
+		return newDryRunDeleteProcessor(cca), nil
+	}
+
 	rawURL, err := cca.destination.FullURL()
 	if err != nil {
 		return nil, err
@@ -65,7 +65,7 @@ func newLocalTraverserForSync(cca *cookedSyncCmdArgs, isSource bool) (*localTrav
 	// TODO: Implement this flag (followSymlinks).
 	// It's extra work and would require testing at the moment, hence why I didn't do it.
 	// Though in hindsight, copy is already getting this testing so, your choice.
-	traverser := newLocalTraverser(fullPath, cca.recursive, false, incrementEnumerationCounter)
+	traverser := newLocalTraverser(fullPath, cca.recursive, common.ESymlinkHandlingType.Skip(), incrementEnumerationCounter)
 
 	return traverser, nil
 }
@@ -0,0 +1,578 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// A two-way sync copies the changes made at either end since the last sync to the other end.
+// To tell which end a file has changed at, how both ends were at the end of the last sync is kept, in the sync state.
+// The copies each way are made by a job of their own, and the two jobs run side by side, like the jobs of a batch.
+// Only files are synced both ways, since sync doesn't delete folders.
+
+// twoWaySyncFileVersion is how a file was at one end, at the end of the last sync
+type twoWaySyncFileVersion struct {
+	Size         int64
+	LastModified time.Time
+}
+
+func (v twoWaySyncFileVersion) matches(object storedObject) bool {
+	return v.Size == object.size && v.LastModified.Equal(object.lastModifiedTime)
+}
+
+type twoWaySyncStateEntry struct {
+	Source      twoWaySyncFileVersion
+	Destination twoWaySyncFileVersion
+
+	// the file is different at each end, since it changed at both, and both versions were kept under their conflict names.
+	// It stays in conflict until it changes again
+	Conflict bool `json:",omitempty"`
+}
+
+// twoWaySyncState holds the files that were at both ends at the end of the last two-way sync between them, by their relative paths
+type twoWaySyncState struct {
+	path  string
+	Files map[string]twoWaySyncStateEntry
+}
+
+// loadTwoWaySyncState loads the state of the two-way sync between the source and destination from the folder.
+// There is none before the first sync
+func loadTwoWaySyncState(folder string, source, destination common.ResourceString, fromTo common.FromTo) (*twoWaySyncState, error) {
+	name := sha256.Sum256([]byte(twoWaySyncRoot(source, fromTo.From()) + "\n" + twoWaySyncRoot(destination, fromTo.To())))
+	s := &twoWaySyncState{
+		path:  filepath.Join(folder, hex.EncodeToString(name[:16])+".json"),
+		Files: make(map[string]twoWaySyncStateEntry),
+	}
+
+	content, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(content, &s.Files); err != nil {
+		// starting over would turn every file changed since the last sync into a conflict, so it's for the user to decide
+		return nil, fmt.Errorf("the state of the last two-way sync, in %s, can't be read: %v. Delete it to sync as if for the first time", s.path, err)
+	}
+	return s, nil
+}
+
+// the same directory is the same pair, however it was given
+func twoWaySyncRoot(resource common.ResourceString, location common.Location) string {
+	if location == common.ELocation.Local() {
+		if absRoot, err := filepath.Abs(resource.ValueLocal()); err == nil {
+			return absRoot
+		}
+	}
+	return resource.Value // without the SAS, since that changes from one sync to the next
+}
+
+// record replaces the state with the files that are at both ends now, of which those in conflict are different at each end
+func (s *twoWaySyncState) record(sourceFiles, destinationFiles map[string]storedObject, conflicts []string) {
+	inConflict := make(map[string]bool, len(conflicts))
+	for _, relativePath := range conflicts {
+		inConflict[relativePath] = true
+	}
+
+	s.Files = make(map[string]twoWaySyncStateEntry)
+	for relativePath, sourceObject := range sourceFiles {
+		if destinationObject, present := destinationFiles[relativePath]; present {
+			s.Files[relativePath] = twoWaySyncStateEntry{
+				Source:      twoWaySyncFileVersion{Size: sourceObject.size, LastModified: sourceObject.lastModifiedTime},
+				Destination: twoWaySyncFileVersion{Size: destinationObject.size, LastModified: destinationObject.lastModifiedTime},
+				Conflict:    inConflict[relativePath],
+			}
+		}
+	}
+}
+
+func (s *twoWaySyncState) save() error {
+	content, err := json.Marshal(s.Files)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), os.ModeDir|os.ModePerm); err != nil {
+		return err
+	}
+
+	// write it aside first, so that a sync that's cut short can't leave half a state behind
+	tempPath := s.path + ".tmp"
+	if err = ioutil.WriteFile(tempPath, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, s.path)
+}
+
+// twoWaySyncPlan is what a two-way sync does to bring both ends up to date
+type twoWaySyncPlan struct {
+	toDestination       []storedObject // the source files to copy to the destination
+	toSource            []storedObject // the destination files to copy to the source
+	deleteAtSource      []storedObject
+	deleteAtDestination []storedObject
+
+	// the relative paths of the files that changed at both ends
+	conflicts []string
+
+	// the relative paths of the files in conflict whose versions are both kept, under their conflict names, rather than
+	// one replacing the other: those that have just been renamed, and those still in conflict from an earlier sync
+	renamed         []string
+	stillInConflict []string
+}
+
+// unresolved is the files that are left different at each end, which are saved as being in conflict
+func (p twoWaySyncPlan) unresolved() []string {
+	return append(append([]string{}, p.renamed...), p.stillInConflict...)
+}
+
+// planTwoWaySync compares the files at each end with how they were at the end of the last sync, to find those that have changed since.
+// A file that has changed at one end is copied to the other, and one that has gone from one end is deleted from the other,
+// or copied back, if deletions aren't to be propagated. A file that has changed at both ends is a conflict, which is resolved as the user asked
+func planTwoWaySync(sourceFiles, destinationFiles map[string]storedObject, state *twoWaySyncState,
+	resolution common.SyncConflictResolution, propagateDeletions bool) twoWaySyncPlan {
+
+	relativePaths := make([]string, 0, len(sourceFiles)+len(destinationFiles))
+	for relativePath := range sourceFiles {
+		relativePaths = append(relativePaths, relativePath)
+	}
+	for relativePath := range destinationFiles {
+		if _, present := sourceFiles[relativePath]; !present {
+			relativePaths = append(relativePaths, relativePath)
+		}
+	}
+	sort.Strings(relativePaths) // so that the plan, and what's printed about it, comes out the same each time
+
+	plan := twoWaySyncPlan{}
+	for _, relativePath := range relativePaths {
+		sourceObject, atSource := sourceFiles[relativePath]
+		destinationObject, atDestination := destinationFiles[relativePath]
+		last, synced := state.Files[relativePath]
+
+		switch {
+		case atSource && atDestination && !synced:
+			// at both ends for the first time, so there's nothing to tell which is the newer version, if they differ
+			if !isSameFileForTwoWaySync(sourceObject, destinationObject) {
+				plan.resolveConflict(sourceObject, destinationObject, resolution)
+			}
+		case atSource && atDestination:
+			changedAtSource, changedAtDestination := !last.Source.matches(sourceObject), !last.Destination.matches(destinationObject)
+			if last.Conflict && !changedAtSource && !changedAtDestination {
+				// both versions were kept last time, and neither has changed since, so there's nothing new to copy
+				plan.stillInConflict = append(plan.stillInConflict, relativePath)
+			} else if changedAtSource && changedAtDestination {
+				plan.resolveConflict(sourceObject, destinationObject, resolution)
+			} else if changedAtSource {
+				plan.toDestination = append(plan.toDestination, sourceObject)
+			} else if changedAtDestination {
+				plan.toSource = append(plan.toSource, destinationObject)
+			}
+		case atSource && synced && propagateDeletions && last.Source.matches(sourceObject):
+			plan.deleteAtSource = append(plan.deleteAtSource, sourceObject)
+		case atSource:
+			// new at the source, or deleted from the destination after it changed at the source, in which case it's copied back, so that no change is lost
+			plan.toDestination = append(plan.toDestination, sourceObject)
+		case synced && propagateDeletions && last.Destination.matches(destinationObject):
+			plan.deleteAtDestination = append(plan.deleteAtDestination, destinationObject)
+		default:
+			plan.toSource = append(plan.toSource, destinationObject)
+		}
+	}
+	return plan
+}
+
+// without the state of a last sync, files of the same size (and the same hash, where both ends have one) are taken to be the same
+func isSameFileForTwoWaySync(sourceObject, destinationObject storedObject) bool {
+	if sourceObject.size != destinationObject.size {
+		return false
+	}
+	if len(sourceObject.md5) > 0 && len(destinationObject.md5) > 0 {
+		return string(sourceObject.md5) == string(destinationObject.md5)
+	}
+	return true
+}
+
+func (p *twoWaySyncPlan) resolveConflict(sourceObject, destinationObject storedObject, resolution common.SyncConflictResolution) {
+	p.conflicts = append(p.conflicts, sourceObject.relativePath)
+
+	switch resolution {
+	case common.ESyncConflictResolution.SourceWins():
+		p.toDestination = append(p.toDestination, sourceObject)
+	case common.ESyncConflictResolution.RenameBoth():
+		p.renamed = append(p.renamed, sourceObject.relativePath)
+		sourceObject.dstRelativePath = twoWaySyncConflictName(sourceObject.relativePath, "source")
+		destinationObject.dstRelativePath = twoWaySyncConflictName(destinationObject.relativePath, "destination")
+		p.toDestination = append(p.toDestination, sourceObject)
+		p.toSource = append(p.toSource, destinationObject)
+	default:
+		// the source wins a tie, since it's the one the user named first
+		if destinationObject.isMoreRecentThan(sourceObject) {
+			p.toSource = append(p.toSource, destinationObject)
+		} else {
+			p.toDestination = append(p.toDestination, sourceObject)
+		}
+	}
+}
+
+// twoWaySyncConflictName is the name a conflicting file is copied to the other end under, e.g. dir/report.source-conflict.docx
+func twoWaySyncConflictName(relativePath, from string) string {
+	ext := path.Ext(relativePath)
+	return strings.TrimSuffix(relativePath, ext) + "." + from + "-conflict" + ext
+}
+
+// reversedForTwoWaySync is the job that copies the other way, from the destination to the source
+func (cca *cookedSyncCmdArgs) reversedForTwoWaySync() *cookedSyncCmdArgs {
+	reversed := cca.newJobForTwoWaySync(cca.destination, cca.source, cca.fromTo.Reversed())
+
+	// the options for uploads and downloads were given for whichever way they apply to
+	for _, c := range []*cookedSyncCmdArgs{cca, reversed} {
+		c.putMd5 = c.putMd5 && c.fromTo.IsUpload()
+		if !c.fromTo.IsDownload() {
+			c.md5ValidationOption = common.DefaultHashValidationOption
+		}
+	}
+	return reversed
+}
+
+// againForTwoWaySync is a new job that copies the same way as this one, with the same options, for the copies that
+// can only be made once this one has finished
+func (cca *cookedSyncCmdArgs) againForTwoWaySync() *cookedSyncCmdArgs {
+	return cca.newJobForTwoWaySync(cca.source, cca.destination, cca.fromTo)
+}
+
+func (cca *cookedSyncCmdArgs) newJobForTwoWaySync(source, destination common.ResourceString, fromTo common.FromTo) *cookedSyncCmdArgs {
+	return &cookedSyncCmdArgs{
+		source:                     source,
+		destination:                destination,
+		fromTo:                     fromTo,
+		recursive:                  cca.recursive,
+		followSymlinks:             cca.followSymlinks,
+		includePatterns:            cca.includePatterns,
+		excludePatterns:            cca.excludePatterns,
+		excludePaths:               cca.excludePaths,
+		includeFileAttributes:      cca.includeFileAttributes,
+		excludeFileAttributes:      cca.excludeFileAttributes,
+		preserveSMBPermissions:     cca.preserveSMBPermissions,
+		preserveSMBInfo:            cca.preserveSMBInfo,
+		putMd5:                     cca.putMd5,
+		md5ValidationOption:        cca.md5ValidationOption,
+		blockSize:                  cca.blockSize,
+		logVerbosity:               cca.logVerbosity,
+		priority:                   cca.priority,
+		activeHours:                cca.activeHours,
+		forceIfReadOnly:            cca.forceIfReadOnly,
+		backupMode:                 cca.backupMode,
+		commandString:              cca.commandString,
+		jobID:                      common.NewJobID(),
+		deleteDestination:          cca.deleteDestination,
+		deleteDestinationThreshold: cca.deleteDestinationThreshold,
+		twoWay:                     true,
+		conflictResolution:         cca.conflictResolution,
+		preserveAccessTier:         cca.preserveAccessTier,
+		dryRunPrinter:              cca.dryRunPrinter,
+		planLocation:               cca.planLocation,
+	}
+}
+
+// listFilesForTwoWaySync lists the files at the source, as the filters allow
+func (cca *cookedSyncCmdArgs) listFilesForTwoWaySync(ctx context.Context) (map[string]storedObject, error) {
+	credInfo, _, err := getCredentialInfoForLocation(ctx, cca.fromTo.From(), cca.source.Value, cca.source.SAS, true)
+	if err != nil {
+		return nil, err
+	}
+	traverser, err := initResourceTraverser(cca.source, cca.fromTo.From(), &ctx, &credInfo, common.ESymlinkHandlingType.Skip(), nil, cca.recursive, true, false, func(common.EntityType) {}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]storedObject)
+	err = traverser.traverse(noPreProccessor, func(object storedObject) error {
+		if object.entityType == common.EEntityType.File() {
+			files[object.relativePath] = object
+		}
+		return nil
+	}, cca.buildFilters())
+	return files, err
+}
+
+// listBothEndsForTwoWaySync lists the files at the source (of the forward job) and at the destination (the source of the reversed one)
+func listBothEndsForTwoWaySync(ctx context.Context, forward, reversed *cookedSyncCmdArgs) (sourceFiles, destinationFiles map[string]storedObject, err error) {
+	if sourceFiles, err = forward.listFilesForTwoWaySync(ctx); err != nil {
+		return nil, nil, err
+	}
+	if destinationFiles, err = reversed.listFilesForTwoWaySync(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	forward.atomicSourceFilesScanned, forward.atomicDestinationFilesScanned = uint64(len(sourceFiles)), uint64(len(destinationFiles))
+	reversed.atomicSourceFilesScanned, reversed.atomicDestinationFilesScanned = uint64(len(destinationFiles)), uint64(len(sourceFiles))
+	return sourceFiles, destinationFiles, nil
+}
+
+// pendingDeletionsForTwoWaySync holds the deletions from the destination, which are checked against the threshold before any is made
+func (cca *cookedSyncCmdArgs) pendingDeletionsForTwoWaySync(objects []storedObject) *pendingDeletions {
+	pending := newPendingDeletions(cca)
+	for _, object := range objects {
+		_ = pending.add(object)
+	}
+	return pending
+}
+
+func (cca *cookedSyncCmdArgs) deleteForTwoWaySync(pending *pendingDeletions) error {
+	if pending.fileCount == 0 {
+		return nil
+	}
+	var deleter *interactiveDeleteProcessor
+	var err error
+	if cca.fromTo.To() == common.ELocation.Local() {
+		deleter = newSyncLocalDeleteProcessor(cca)
+	} else if deleter, err = newSyncDeleteProcessor(cca); err != nil {
+		return err
+	}
+	return pending.deleteAll(deleter.removeImmediately)
+}
+
+// copyForTwoWaySync starts the job that copies the files from the source to the destination
+func (cca *cookedSyncCmdArgs) copyForTwoWaySync(objects []storedObject) error {
+	fpo, _ := newFolderPropertyOption(cca.fromTo, cca.recursive, true, cca.buildFilters(), cca.preserveSMBInfo, cca.preserveSMBPermissions.IsTruthy())
+	transferScheduler := newSyncTransferProcessor(cca, NumOfFilesPerDispatchJobPart, fpo)
+	if cca.dryRunPrinter == nil {
+		cca.waitUntilJobCompletion(false)
+	}
+
+	for _, object := range objects {
+		if err := transferScheduler.scheduleCopyTransfer(object); err != nil {
+			return err
+		}
+	}
+	if _, err := transferScheduler.dispatchFinalPart(); err != nil {
+		return err
+	}
+	cca.setScanningComplete()
+	return nil
+}
+
+func (cca *cookedSyncCmdArgs) processTwoWay(ctx context.Context) error {
+	reversed := cca.reversedForTwoWaySync()
+	if err := reversed.initCredentialInfo(ctx); err != nil {
+		return err
+	}
+
+	state, err := loadTwoWaySyncState(filepath.Join(azcopyAppPathFolder, "syncstate"), cca.source, cca.destination, cca.fromTo)
+	if err != nil {
+		return err
+	}
+	sourceFiles, destinationFiles, err := listBothEndsForTwoWaySync(ctx, cca, reversed)
+	if err != nil {
+		return err
+	}
+
+	plan := planTwoWaySync(sourceFiles, destinationFiles, state, cca.conflictResolution, cca.deleteDestination != common.EDeleteDestination.False())
+	for _, relativePath := range plan.conflicts {
+		if cca.conflictResolution == common.ESyncConflictResolution.RenameBoth() {
+			glcm.Info(fmt.Sprintf("Conflict: %s has changed at both ends since the last sync. Both versions are kept, at both ends, as %s and %s.",
+				relativePath, twoWaySyncConflictName(relativePath, "source"), twoWaySyncConflictName(relativePath, "destination")))
+		} else {
+			glcm.Info(fmt.Sprintf("Conflict: %s has changed at both ends since the last sync. Resolved by %s.", relativePath, cca.conflictResolution))
+		}
+	}
+	for _, relativePath := range plan.stillInConflict {
+		glcm.Info(fmt.Sprintf("Conflict: %s is still different at each end. Change it at one end to resolve the conflict.", relativePath))
+	}
+	unresolved := plan.unresolved()
+
+	// both ends are checked against the threshold before anything is deleted from either
+	deleteAtDestination, deleteAtSource := cca.pendingDeletionsForTwoWaySync(plan.deleteAtDestination), reversed.pendingDeletionsForTwoWaySync(plan.deleteAtSource)
+	for _, pending := range []*pendingDeletions{deleteAtDestination, deleteAtSource} {
+		if err = pending.checkThreshold(); err != nil {
+			return err
+		}
+	}
+	if err = cca.deleteForTwoWaySync(deleteAtDestination); err != nil {
+		return err
+	}
+	if err = reversed.deleteForTwoWaySync(deleteAtSource); err != nil {
+		return err
+	}
+
+	if cca.dryRunPrinter != nil {
+		for _, direction := range []struct {
+			cca     *cookedSyncCmdArgs
+			objects []storedObject
+		}{{cca, plan.toDestination}, {reversed, plan.toSource}} {
+			if err = direction.cca.copyForTwoWaySync(direction.objects); err != nil && err != NothingScheduledError {
+				return err
+			}
+		}
+		glcm.Exit(func(format common.OutputFormat) string {
+			return cca.dryRunPrinter.summary(false)
+		}, common.EExitCode.Success())
+		return nil
+	}
+
+	// the state is only saved when every change has been copied, since the next sync can't tell the files that were copied from those that weren't
+	saveState := func() error {
+		sourceFiles, destinationFiles, err := listBothEndsForTwoWaySync(ctx, cca, reversed)
+		if err != nil {
+			return err
+		}
+		state.record(sourceFiles, destinationFiles, unresolved)
+		return state.save()
+	}
+
+	jobs := twoWaySyncJobs(cca, reversed, plan.toDestination, plan.toSource)
+	if len(jobs) == 0 {
+		if err = saveState(); err != nil {
+			return fmt.Errorf("cannot save the state of the sync: %w", err)
+		}
+		cca.reportScanningProgress(glcm, 0)
+		glcm.Exit(func(format common.OutputFormat) string {
+			if len(unresolved) > 0 {
+				return fmt.Sprintf("The source and destination are in sync, except for %d files in conflict.", len(unresolved))
+			} else if cca.getDeletionCount()+reversed.getDeletionCount() > 0 {
+				return "The source and destination are now in sync."
+			}
+			return "The source and destination are already in sync."
+		}, common.EExitCode.Success())
+		return nil
+	}
+
+	lcm := &twoWaySyncLifecycleMgr{LifecycleMgr: glcm, saveState: saveState, unresolved: len(unresolved)}
+	if len(plan.renamed) > 0 {
+		// the jobs copy each end's version of a file in conflict to the other end, under its conflict name. Copying it
+		// under that name at its own end has to wait until they have finished, since each job only copies one way
+		lcm.followUp = func(lcm *twoWaySyncLifecycleMgr) (bool, error) {
+			return cca.startConflictCopiesForTwoWaySync(ctx, reversed, plan.renamed, lcm)
+		}
+	}
+	return startTwoWaySyncJobs(lcm, jobs)
+}
+
+func twoWaySyncJobs(forward, reversed *cookedSyncCmdArgs, toDestination, toSource []storedObject) []batchJob {
+	jobs := make([]batchJob, 0, 2)
+	if len(toDestination) > 0 {
+		jobs = append(jobs, batchJob{commandString: forward.commandString, controller: forward, start: func() error { return forward.copyForTwoWaySync(toDestination) }})
+	}
+	if len(toSource) > 0 {
+		jobs = append(jobs, batchJob{commandString: reversed.commandString, controller: reversed, start: func() error { return reversed.copyForTwoWaySync(toSource) }})
+	}
+	return jobs
+}
+
+// startTwoWaySyncJobs starts the jobs, which end like those of a batch, so that the process ends, and the state is saved,
+// when both have
+func startTwoWaySyncJobs(lcm *twoWaySyncLifecycleMgr, jobs []batchJob) error {
+	batch := newJobBatch(lcm, jobs)
+	glcm = &batchLifecycleMgr{LifecycleMgr: lcm.LifecycleMgr, batch: batch}
+	for _, job := range jobs {
+		if err := job.start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startConflictCopiesForTwoWaySync copies the files that were just copied to each end under their conflict names back
+// to the other end, so that both ends have both versions of each file in conflict. It returns false if there was nothing to copy
+func (cca *cookedSyncCmdArgs) startConflictCopiesForTwoWaySync(ctx context.Context, reversed *cookedSyncCmdArgs, renamed []string,
+	lcm *twoWaySyncLifecycleMgr) (started bool, err error) {
+
+	sourceFiles, destinationFiles, err := listBothEndsForTwoWaySync(ctx, cca, reversed)
+	if err != nil {
+		return false, err
+	}
+	var toDestination, toSource []storedObject
+	for _, relativePath := range renamed {
+		if object, present := sourceFiles[twoWaySyncConflictName(relativePath, "destination")]; present {
+			toDestination = append(toDestination, object)
+		}
+		if object, present := destinationFiles[twoWaySyncConflictName(relativePath, "source")]; present {
+			toSource = append(toSource, object)
+		}
+	}
+
+	forwardAgain, reversedAgain := cca.againForTwoWaySync(), reversed.againForTwoWaySync()
+	for _, c := range []*cookedSyncCmdArgs{forwardAgain, reversedAgain} {
+		if err = c.initCredentialInfo(ctx); err != nil {
+			return false, err
+		}
+	}
+	jobs := twoWaySyncJobs(forwardAgain, reversedAgain, toDestination, toSource)
+	if len(jobs) == 0 {
+		return false, nil
+	}
+	return true, startTwoWaySyncJobs(&twoWaySyncLifecycleMgr{LifecycleMgr: lcm.LifecycleMgr, saveState: lcm.saveState, unresolved: lcm.unresolved}, jobs)
+}
+
+// twoWaySyncLifecycleMgr saves the state of the sync when both its jobs have succeeded, before the process ends,
+// unless there's a followup, whose jobs end the process instead
+type twoWaySyncLifecycleMgr struct {
+	common.LifecycleMgr
+	saveState  func() error
+	unresolved int // how many files are left in conflict
+	followUp   func(lcm *twoWaySyncLifecycleMgr) (started bool, err error)
+}
+
+func (m *twoWaySyncLifecycleMgr) Exit(o common.OutputBuilder, applicationExitCode common.ExitCode) {
+	if applicationExitCode == common.EExitCode.NoExit() {
+		m.LifecycleMgr.Exit(o, applicationExitCode)
+		return
+	}
+
+	message := "Two-way sync complete. The source and destination are now in sync."
+	if m.unresolved > 0 {
+		message = fmt.Sprintf("Two-way sync complete, except for %d files that changed at both ends. Both versions of each are kept at both ends, "+
+			"under their conflict names, and the files stay in conflict until one end changes again.", m.unresolved)
+	}
+	if applicationExitCode != common.EExitCode.Success() {
+		message = "Some files could not be copied, so the state of the sync was not saved. Run the sync again to retry them."
+	} else if err := m.saveState(); err != nil {
+		message = "All the files were copied, but the state of the sync could not be saved: " + err.Error()
+		applicationExitCode = common.EExitCode.Error()
+	} else if m.followUp != nil {
+		// the state saved so far holds the files in conflict, so the next sync won't copy them again if the followup fails
+		started, err := m.followUp(m)
+		if err != nil {
+			message = "The files in conflict could not be copied under their conflict names to both ends: " + err.Error() + ". Run the sync again to retry them."
+			applicationExitCode = common.EExitCode.Error()
+		} else if started {
+			return
+		}
+	}
+	m.LifecycleMgr.Exit(func(format common.OutputFormat) string {
+		if format == common.EOutputFormat.Json() {
+			return o(format)
+		}
+		return message
+	}, applicationExitCode)
+}
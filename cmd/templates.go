@@ -0,0 +1,291 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// A job template is a saved command line, for a job that is run again and again (e.g. a nightly sync), in which
+// parts that change from run to run can be left as {{placeholders}}. "azcopy run" fills them in, and runs the command.
+// Each template is kept in its own file, in the templates folder of the AzCopy folder
+
+const templateFileExtension = ".json"
+
+// the owner alone can read the files, since the command lines might include SAS tokens
+const templateFilePerm = 0600
+
+var templatePlaceholderRegex = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_-]+)\s*\}\}`)
+
+var templateNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+type jobTemplate struct {
+	Name string
+	Args []string // the command line, without "azcopy", e.g. ["sync", "/data/{{folder}}", "https://...", "--recursive"]
+}
+
+// Parameters lists the names of the placeholders, in the order they first appear
+func (t jobTemplate) Parameters() []string {
+	params := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, arg := range t.Args {
+		for _, match := range templatePlaceholderRegex.FindAllStringSubmatch(arg, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				params = append(params, match[1])
+			}
+		}
+	}
+	return params
+}
+
+// expand fills in the placeholders. Every placeholder must be given a value, and every value must be for a placeholder,
+// so that a misspelt parameter doesn't leave the job running with the wrong paths
+func (t jobTemplate) expand(values map[string]string) ([]string, error) {
+	params := t.Parameters()
+	missing := make([]string, 0)
+	for _, p := range params {
+		if _, ok := values[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("template %s needs a value for %s. Give them with --param, e.g. --param %s=value",
+			t.Name, strings.Join(missing, ", "), missing[0])
+	}
+	isParam := make(map[string]bool)
+	for _, p := range params {
+		isParam[p] = true
+	}
+	for name := range values {
+		if !isParam[name] {
+			return nil, fmt.Errorf("template %s has no parameter named %s. Its parameters are: %s", t.Name, name, strings.Join(params, ", "))
+		}
+	}
+
+	expanded := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		expanded[i] = templatePlaceholderRegex.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			return values[templatePlaceholderRegex.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+	return expanded, nil
+}
+
+func templatesFolder() string {
+	return filepath.Join(azcopyAppPathFolder, "templates")
+}
+
+func templatePath(name string) string {
+	return filepath.Join(templatesFolder(), name+templateFileExtension)
+}
+
+func validateTemplateName(name string) error {
+	if !templateNameRegex.MatchString(name) {
+		return fmt.Errorf("'%s' is not a valid template name. Use letters, digits, '-', '_' and '.', starting with a letter or digit", name)
+	}
+	return nil
+}
+
+func saveJobTemplate(t jobTemplate, overwrite bool) error {
+	if err := validateTemplateName(t.Name); err != nil {
+		return err
+	}
+	if len(t.Args) == 0 || !controlServerJobCommands[t.Args[0]] {
+		return errors.New("only copy, sync and remove commands can be saved as templates")
+	}
+	if _, err := os.Stat(templatePath(t.Name)); err == nil && !overwrite {
+		return fmt.Errorf("template %s already exists. Use --force to replace it", t.Name)
+	}
+
+	content, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(templatesFolder(), os.ModeDir|os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(templatePath(t.Name), content, templateFilePerm)
+}
+
+func loadJobTemplate(name string) (jobTemplate, error) {
+	var t jobTemplate
+	if err := validateTemplateName(name); err != nil {
+		return t, err
+	}
+	content, err := ioutil.ReadFile(templatePath(name))
+	if os.IsNotExist(err) {
+		return t, fmt.Errorf("there is no template named %s. The templates command lists them", name)
+	} else if err != nil {
+		return t, err
+	}
+	if err = json.Unmarshal(content, &t); err != nil {
+		return t, fmt.Errorf("template %s is damaged: %w", name, err)
+	}
+	t.Name = name // the file name is what counts, in case the file has been renamed
+	return t, nil
+}
+
+func listJobTemplates() ([]jobTemplate, error) {
+	entries, err := ioutil.ReadDir(templatesFolder())
+	if os.IsNotExist(err) {
+		return []jobTemplate{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	templates := make([]jobTemplate, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), templateFileExtension) {
+			continue
+		}
+		t, err := loadJobTemplate(strings.TrimSuffix(e.Name(), templateFileExtension))
+		if err != nil {
+			glcm.Warn(err.Error())
+			continue
+		}
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// describeJobTemplate shows the template as a command line
+func describeJobTemplate(t jobTemplate) string {
+	quoted := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		quoted[i] = arg
+		if strings.ContainsAny(arg, " \t\"'") {
+			quoted[i] = fmt.Sprintf("%q", arg)
+		}
+	}
+	s := t.Name + ": azcopy " + strings.Join(quoted, " ")
+	if params := t.Parameters(); len(params) > 0 {
+		s += "\n  Parameters: " + strings.Join(params, ", ")
+	}
+	return s
+}
+
+// templates command is used to encapsulate the sub-commands that manage job templates
+var templatesCmd = &cobra.Command{
+	Use:     "templates",
+	Aliases: []string{"template"},
+	Short:   templatesCmdShortDescription,
+	Long:    templatesCmdLongDescription,
+	Example: templatesCmdExample,
+}
+
+func init() {
+	rootCmd.AddCommand(templatesCmd)
+
+	overwrite := false
+	templatesSaveCmd := &cobra.Command{
+		Use:     "save [name] -- [command]...",
+		Short:   saveTemplatesCmdShortDescription,
+		Long:    saveTemplatesCmdLongDescription,
+		Example: saveTemplatesCmdExample,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 || cmd.ArgsLenAtDash() != 1 {
+				return errors.New("save template command requires the name of the template, then '--', then the command, " +
+					"e.g. azcopy templates save nightly -- sync /data/{{folder}} [destination] --recursive")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			t := jobTemplate{Name: args[0], Args: args[1:]}
+			if err := saveJobTemplate(t, overwrite); err != nil {
+				glcm.Error("cannot save the template: " + err.Error())
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(t)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+				return "Saved template " + describeJobTemplate(t)
+			}, common.EExitCode.Success())
+		},
+	}
+	templatesSaveCmd.PersistentFlags().BoolVar(&overwrite, "force", false, "Replace the template, if there's already one with the same name.")
+	templatesCmd.AddCommand(templatesSaveCmd)
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   listTemplatesCmdShortDescription,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			templates, err := listJobTemplates()
+			if err != nil {
+				glcm.Error("cannot list the templates: " + err.Error())
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				if format == common.EOutputFormat.Json() {
+					jsonOutput, err := json.Marshal(templates)
+					common.PanicIfErr(err)
+					return string(jsonOutput)
+				}
+				if len(templates) == 0 {
+					return "There are no templates. Save one with the templates save command."
+				}
+				descriptions := make([]string, len(templates))
+				for i, t := range templates {
+					descriptions[i] = describeJobTemplate(t)
+				}
+				return strings.Join(descriptions, "\n")
+			}, common.EExitCode.Success())
+		},
+	})
+
+	templatesCmd.AddCommand(&cobra.Command{
+		Use:     "remove [name]",
+		Aliases: []string{"rm"},
+		Short:   removeTemplatesCmdShortDescription,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if _, err := loadJobTemplate(args[0]); err != nil {
+				glcm.Error(err.Error())
+				return
+			}
+			if err := os.Remove(templatePath(args[0])); err != nil {
+				glcm.Error("cannot remove the template: " + err.Error())
+				return
+			}
+			glcm.Exit(func(format common.OutputFormat) string {
+				return "Removed template " + args[0]
+			}, common.EExitCode.Success())
+		},
+	})
+}
@@ -100,6 +100,18 @@ func inferFromTo(src, dst string) common.FromTo {
 		return common.EFromTo.FileFile()
 	case srcLocation == common.ELocation.S3() && dstLocation == common.ELocation.Blob():
 		return common.EFromTo.S3Blob()
+	case srcLocation == common.ELocation.S3() && dstLocation == common.ELocation.File():
+		return common.EFromTo.S3File()
+	case srcLocation == common.ELocation.SFTP() && dstLocation == common.ELocation.Blob():
+		return common.EFromTo.SFTPBlob()
+	case srcLocation == common.ELocation.SFTP() && dstLocation == common.ELocation.File():
+		return common.EFromTo.SFTPFile()
+	case srcLocation == common.ELocation.Local() && dstLocation == common.ELocation.SFTP():
+		return common.EFromTo.LocalSFTP()
+	case srcLocation == common.ELocation.HTTP() && dstLocation == common.ELocation.Blob():
+		return common.EFromTo.HTTPBlob()
+	case srcLocation == common.ELocation.HTTP() && dstLocation == common.ELocation.File():
+		return common.EFromTo.HTTPFile()
 	case srcLocation == common.ELocation.Benchmark() && dstLocation == common.ELocation.Blob():
 		return common.EFromTo.BenchmarkBlob()
 	case srcLocation == common.ELocation.Benchmark() && dstLocation == common.ELocation.File():
@@ -108,6 +120,11 @@ func inferFromTo(src, dst string) common.FromTo {
 		return common.EFromTo.BenchmarkBlobFS()
 	}
 
+	if dstLocation == common.ELocation.SFTP() {
+		glcm.Info("Only local files can be copied to SFTP servers. To copy from Azure Storage to an SFTP server, download the files first.")
+		return common.EFromTo.Unknown()
+	}
+
 	glcm.Info("The parameters you supplied were " +
 		"Source: '" + common.URLStringExtension(src).RedactSecretQueryParamForLogging() + "' of type " + srcLocation.String() +
 		", and Destination: '" + common.URLStringExtension(dst).RedactSecretQueryParamForLogging() + "' of type " + dstLocation.String())
@@ -124,6 +141,9 @@ func inferArgumentLocation(arg string) common.Location {
 	if arg == pipeLocation {
 		return common.ELocation.Pipe()
 	}
+	if common.IsSFTPURL(arg) {
+		return common.ELocation.SFTP()
+	}
 	if startsWith(arg, "http") {
 		// Let's try to parse the argument as a URL
 		u, err := url.Parse(arg)
@@ -148,6 +168,9 @@ func inferArgumentLocation(arg string) common.Location {
 			if common.IsS3URL(*u) {
 				return common.ELocation.S3()
 			}
+
+			// any other web server can still be a source, as a plain HTTP(S) URL
+			return common.ELocation.HTTP()
 		}
 	}
 
@@ -0,0 +1,64 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the names that Windows file attributes may be given by, as well as by the letters that buildAttrFilters knows them by
+var fileAttributeNames = map[string]string{
+	"readonly":          "R",
+	"read-only":         "R",
+	"archive":           "A",
+	"system":            "S",
+	"hidden":            "H",
+	"compressed":        "C",
+	"normal":            "N",
+	"encrypted":         "E",
+	"temporary":         "T",
+	"offline":           "O",
+	"notindexed":        "I",
+	"notcontentindexed": "I",
+}
+
+// parseFileAttributes parses a list of Windows file attributes, such as "hidden,system" or "H;S", into their letters
+func parseFileAttributes(s string, flagName string) ([]string, error) {
+	letters := make([]string, 0)
+	for _, attribute := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' }) {
+		attribute = strings.TrimSpace(attribute)
+		if attribute == "" {
+			continue
+		}
+
+		if letter, ok := fileAttributeNames[strings.ToLower(attribute)]; ok {
+			letters = append(letters, letter)
+		} else if len(attribute) == 1 && strings.Contains("RASHCNETOI", strings.ToUpper(attribute)) {
+			letters = append(letters, strings.ToUpper(attribute))
+		} else {
+			return nil, fmt.Errorf("'%s' given to %s is not a file attribute. The attributes are ReadOnly (R), Archive (A), System (S), Hidden (H), "+
+				"Compressed (C), Normal (N), Encrypted (E), Temporary (T), Offline (O) and NotIndexed (I)", attribute, flagName)
+		}
+	}
+
+	return letters, nil
+}
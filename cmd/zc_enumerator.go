@@ -76,11 +76,19 @@ type storedObject struct {
 	containerName string
 	// destination container name. Included in the processor after resolving container names.
 	dstContainerName string
+	// destination path, relative to the destination root, when it isn't the same as relativePath.
+	// Only set by two-way sync, for the copies it makes of conflicting files.
+	dstRelativePath string
 	// access tier, only included by blob traverser.
 	blobAccessTier azblob.AccessTierType
+	// index tags, only included by the blob traverser, and only when a filter needs them.
+	blobTags map[string]string
 	// metadata, included in S2S transfers
-	Metadata      common.Metadata
-	blobVersionID string
+	Metadata       common.Metadata
+	blobVersionID  string
+	blobSnapshotID string
+	// whether the blob is soft-deleted, only included by the blob traverser, and only when deleted blobs are listed
+	blobDeleted bool
 }
 
 const (
@@ -106,7 +114,7 @@ func (s *storedObject) isSourceRootFolder() bool {
 // do not pass through that routine.  So we need to make the filtering available in a separate function
 // so that the sync deletion code path(s) can access it.
 func (s *storedObject) isCompatibleWithFpo(fpo common.FolderPropertyOption) bool {
-	if s.entityType == common.EEntityType.File() {
+	if s.entityType == common.EEntityType.File() || s.entityType == common.EEntityType.Symlink() {
 		return true
 	} else if s.entityType == common.EEntityType.Folder() {
 		switch fpo {
@@ -150,6 +158,9 @@ func (s *storedObject) ToNewCopyTransfer(
 	if steWillAutoDecompress {
 		Destination = stripCompressionExtension(Destination, s.contentEncoding)
 	}
+	if s.blobSnapshotID != "" {
+		Destination = snapshotDestination(Destination, s.blobSnapshotID)
+	}
 
 	t := common.CopyTransfer{
 		Source:             Source,
@@ -166,6 +177,8 @@ func (s *storedObject) ToNewCopyTransfer(
 		Metadata:           s.Metadata,
 		BlobType:           s.blobType,
 		BlobVersionID:      s.blobVersionID,
+		BlobSnapshotID:     s.blobSnapshotID,
+		BlobDeleted:        s.blobDeleted,
 		// set this below, conditionally: BlobTier
 	}
 
@@ -192,6 +205,9 @@ func stripCompressionExtension(dest string, contentEncoding string) string {
 	if stripGzip || stripZlib {
 		return strings.TrimSuffix(dest, filepath.Ext(dest))
 	}
+	if ct == common.ECompressionType.GZip() && ext == ".tgz" {
+		return strings.TrimSuffix(dest, filepath.Ext(dest)) + ".tar" // a gzipped tar file, which is left as a tar file
+	}
 	return dest
 }
 
@@ -291,10 +307,10 @@ type enumerationCounterFunc func(entityType common.EntityType)
 
 // source, location, recursive, and incrementEnumerationCounter are always required.
 // ctx, pipeline are only required for remote resources.
-// followSymlinks is only required for local resources (defaults to false)
+// symlinkHandling only matters for local resources (and SFTP, which can only follow them or skip them)
 // errorOnDirWOutRecursive is used by copy.
 func initResourceTraverser(resource common.ResourceString, location common.Location, ctx *context.Context, credential *common.CredentialInfo,
-	followSymlinks *bool, listOfFilesChannel chan string, recursive, getProperties, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc, listOfVersionIds chan string) (resourceTraverser, error) {
+	symlinkHandling common.SymlinkHandlingType, listOfFilesChannel chan string, recursive, getProperties, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc, listOfVersionIds chan string) (resourceTraverser, error) {
 	var output resourceTraverser
 	var p *pipeline.Pipeline
 
@@ -314,11 +330,6 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 		p = &tmppipe
 	}
 
-	toFollow := false
-	if followSymlinks != nil {
-		toFollow = *followSymlinks
-	}
-
 	// Feed list of files channel into new list traverser
 	if listOfFilesChannel != nil {
 		if location.IsLocal() {
@@ -331,7 +342,7 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 			}
 		}
 
-		output = newListTraverser(resource, location, credential, ctx, recursive, toFollow, getProperties, listOfFilesChannel, includeDirectoryStubs, incrementEnumerationCounter)
+		output = newListTraverser(resource, location, credential, ctx, recursive, symlinkHandling, getProperties, listOfFilesChannel, includeDirectoryStubs, incrementEnumerationCounter)
 		return output, nil
 	}
 
@@ -358,9 +369,9 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 			}()
 
 			baseResource := resource.CloneWithValue(cleanLocalPath(basePath))
-			output = newListTraverser(baseResource, location, nil, nil, recursive, toFollow, getProperties, globChan, includeDirectoryStubs, incrementEnumerationCounter)
+			output = newListTraverser(baseResource, location, nil, nil, recursive, symlinkHandling, getProperties, globChan, includeDirectoryStubs, incrementEnumerationCounter)
 		} else {
-			output = newLocalTraverser(resource.ValueLocal(), recursive, toFollow, incrementEnumerationCounter)
+			output = newLocalTraverser(resource.ValueLocal(), recursive, symlinkHandling, incrementEnumerationCounter)
 		}
 	case common.ELocation.Benchmark():
 		ben, err := newBenchmarkTraverser(resource.Value, incrementEnumerationCounter)
@@ -481,6 +492,25 @@ func initResourceTraverser(resource common.ResourceString, location common.Locat
 				return nil, err
 			}
 		}
+	case common.ELocation.SFTP():
+		sftp, err := newSFTPTraverser(resource.Value, recursive, symlinkHandling == common.ESymlinkHandlingType.Follow(), incrementEnumerationCounter)
+		if err != nil {
+			return nil, err
+		}
+		output = sftp
+	case common.ELocation.HTTP():
+		resourceURL, err := resource.FullURL()
+		if err != nil {
+			return nil, err
+		}
+
+		recommendHttpsIfNecessary(*resourceURL)
+
+		if ctx == nil || p == nil {
+			return nil, errors.New("a valid context must be supplied to create an HTTP traverser")
+		}
+
+		output = newHTTPTraverser(resourceURL, *p, *ctx, incrementEnumerationCounter)
 	default:
 		return nil, errors.New("could not choose a traverser from currently available traversers")
 	}
@@ -534,6 +564,12 @@ type preFilterProvider interface {
 	getEnumerationPreFilter() string
 }
 
+// findByTagsProvider is implemented by the filters that the service's find-by-tags API can apply, so that the
+// blobs that pass them can be found without listing every blob
+type findByTagsProvider interface {
+	getFindByTagsQuery(containerName string) string
+}
+
 // -------------------------------------- Generic Enumerators -------------------------------------- \\
 // the following enumerators must be instantiated with configurations
 // they define the work flow in the most generic terms
@@ -547,8 +583,10 @@ type syncEnumerator struct {
 	// the results from the primary traverser would be stored here
 	objectIndexer *objectIndexer
 
-	// general filters apply to both the primary and secondary traverser
-	filters []objectFilter
+	// general filters apply to both the primary and secondary traverser,
+	// but each may also have filters of its own, such as those that apply only to the source
+	primaryFilters   []objectFilter
+	secondaryFilters []objectFilter
 
 	// the processor that apply only to the secondary traverser
 	// it processes objects as scanning happens
@@ -560,12 +598,13 @@ type syncEnumerator struct {
 }
 
 func newSyncEnumerator(primaryTraverser, secondaryTraverser resourceTraverser, indexer *objectIndexer,
-	filters []objectFilter, comparator objectProcessor, finalize func() error) *syncEnumerator {
+	primaryFilters, secondaryFilters []objectFilter, comparator objectProcessor, finalize func() error) *syncEnumerator {
 	return &syncEnumerator{
 		primaryTraverser:   primaryTraverser,
 		secondaryTraverser: secondaryTraverser,
 		objectIndexer:      indexer,
-		filters:            filters,
+		primaryFilters:     primaryFilters,
+		secondaryFilters:   secondaryFilters,
 		objectComparator:   comparator,
 		finalize:           finalize,
 	}
@@ -573,7 +612,7 @@ func newSyncEnumerator(primaryTraverser, secondaryTraverser resourceTraverser, i
 
 func (e *syncEnumerator) enumerate() (err error) {
 	// enumerate the primary resource and build lookup map
-	err = e.primaryTraverser.traverse(noPreProccessor, e.objectIndexer.store, e.filters)
+	err = e.primaryTraverser.traverse(noPreProccessor, e.objectIndexer.store, e.primaryFilters)
 	if err != nil {
 		return
 	}
@@ -582,7 +621,7 @@ func (e *syncEnumerator) enumerate() (err error) {
 	// they will be passed to the object comparator
 	// which can process given objects based on what's already indexed
 	// note: transferring can start while scanning is ongoing
-	err = e.secondaryTraverser.traverse(noPreProccessor, e.objectComparator, e.filters)
+	err = e.secondaryTraverser.traverse(noPreProccessor, e.objectComparator, e.secondaryFilters)
 	if err != nil {
 		return
 	}
@@ -619,7 +658,7 @@ func newCopyEnumerator(traverser resourceTraverser, filters []objectFilter, obje
 }
 
 func WarnStdoutAndJobLog(toLog string) {
-	glcm.Info(toLog)
+	glcm.Warn(toLog)
 	if ste.JobsAdmin != nil {
 		ste.JobsAdmin.LogToJobLog(toLog, pipeline.LogWarning)
 	}
@@ -646,7 +685,7 @@ func passedFilters(filters []objectFilter, storedObject storedObject) bool {
 				glcm.Error(msg)
 			}
 
-			if filter.appliesOnlyToFiles() && storedObject.entityType != common.EEntityType.File() {
+			if filter.appliesOnlyToFiles() && storedObject.entityType == common.EEntityType.Folder() {
 				// don't pass folders to filters that only know how to deal with files
 				// As at Feb 2020, we have separate logic to weed out folder properties (and not even send them)
 				// if any filter applies only to files... but that logic runs after this point, so we need this
@@ -23,6 +23,8 @@ package cmd
 import (
 	"fmt"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -58,6 +60,68 @@ func (f *excludeBlobTypeFilter) doesPass(object storedObject) bool {
 	return false
 }
 
+// includeBlobTierFilter includes only the blobs in the given access tiers. It is used to pick out the archived blobs,
+// or to leave them alone, since they can't be read without first being rehydrated
+type includeBlobTierFilter struct {
+	tiers map[azblob.AccessTierType]bool
+}
+
+func (f *includeBlobTierFilter) doesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *includeBlobTierFilter) appliesOnlyToFiles() bool {
+	return true // folders have no tier
+}
+
+func (f *includeBlobTierFilter) doesPass(object storedObject) bool {
+	return f.tiers[object.blobAccessTier]
+}
+
+func buildIncludeBlobTierFilters(tiers []azblob.AccessTierType) []objectFilter {
+	if len(tiers) == 0 {
+		return []objectFilter{}
+	}
+
+	tierSet := make(map[azblob.AccessTierType]bool)
+	for _, tier := range tiers {
+		tierSet[tier] = true
+	}
+
+	return []objectFilter{&includeBlobTierFilter{tiers: tierSet}}
+}
+
+// parseBlobTiers parses a list of access tiers, such as "hot;cool" or "Archive", into the names the service uses for them
+func parseBlobTiers(s string) ([]azblob.AccessTierType, error) {
+	tiers := make([]azblob.AccessTierType, 0)
+	validTiers := make([]string, 0)
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' }) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, tier := range azblob.PossibleAccessTierTypeValues() {
+			if tier != azblob.AccessTierNone && strings.EqualFold(name, string(tier)) {
+				tiers = append(tiers, tier)
+				found = true
+				break
+			}
+		}
+		if !found {
+			for _, tier := range azblob.PossibleAccessTierTypeValues() {
+				if tier != azblob.AccessTierNone {
+					validTiers = append(validTiers, string(tier))
+				}
+			}
+			return nil, fmt.Errorf("'%s' is not an access tier. The access tiers are %s", name, strings.Join(validTiers, ", "))
+		}
+	}
+
+	return tiers, nil
+}
+
 type excludeFilter struct {
 	pattern     string
 	targetsPath bool
@@ -228,6 +292,27 @@ func (fs filterSet) GetEnumerationPreFilter(recursive bool) string {
 	return prefix
 }
 
+// needsBlobTags says whether any of the filters looks at index tags, which blob traversers only get when asked to
+func (fs filterSet) needsBlobTags() bool {
+	for _, f := range fs {
+		if _, ok := f.(*blobTagsFilter); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getFindByTagsQuery returns a query for the service's find-by-tags API that finds the blobs in the container that
+// may pass the filterSet, or "" if there's no such query
+func (fs filterSet) getFindByTagsQuery(containerName string) string {
+	for _, f := range fs {
+		if provider, ok := f.(findByTagsProvider); ok {
+			return provider.getFindByTagsQuery(containerName)
+		}
+	}
+	return ""
+}
+
 ////////
 
 // includeAfterDateFilter includes files with Last Modified Times >= the specified threshold
@@ -259,6 +344,73 @@ func (f *includeAfterDateFilter) doesPass(storedObject storedObject) bool {
 		storedObject.lastModifiedTime.Equal(f.threshold) // >= is easier for users to understand than >
 }
 
+// includeBeforeDateFilter includes files with Last Modified Times <= the specified threshold
+// Together with includeAfterDateFilter, it selects the files changed in a window of time
+type includeBeforeDateFilter struct {
+	threshold time.Time
+}
+
+func (f *includeBeforeDateFilter) doesSupportThisOS() (msg string, supported bool) {
+	msg = ""
+	supported = true
+	return
+}
+
+func (f *includeBeforeDateFilter) appliesOnlyToFiles() bool {
+	return true // for the same reason as includeAfterDateFilter
+}
+
+func (f *includeBeforeDateFilter) doesPass(storedObject storedObject) bool {
+	zeroTime := time.Time{}
+	if storedObject.lastModifiedTime == zeroTime {
+		panic("cannot use includeBeforeDateFilter on an object for which no Last Modified Time has been retrieved")
+	}
+
+	return storedObject.lastModifiedTime.Before(f.threshold) ||
+		storedObject.lastModifiedTime.Equal(f.threshold) // <=, to match the >= of includeAfterDateFilter
+}
+
+func buildDateFilters(includeAfter, includeBefore *time.Time) []objectFilter {
+	filters := make([]objectFilter, 0)
+	if includeAfter != nil {
+		filters = append(filters, &includeAfterDateFilter{threshold: *includeAfter})
+	}
+	if includeBefore != nil {
+		filters = append(filters, &includeBeforeDateFilter{threshold: *includeBefore})
+	}
+
+	return filters
+}
+
+// parseDateWindow parses the values of the include-after and include-before flags. A nil result means the window is open at that end
+func parseDateWindow(includeAfter, includeBefore string) (after *time.Time, before *time.Time, err error) {
+	if includeAfter != "" {
+		// must set chooseEarliest = true, so that if there's an ambiguous local date, the earliest will be returned
+		// (since that's safest for includeAfter.  Better to choose the earlier time and do more work, than the later one and fail to pick up a changed file
+		parsed, err := includeAfterDateFilter{}.ParseISO8601(includeAfter, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		after = &parsed
+	}
+
+	if includeBefore != "" {
+		// and likewise, the latest is safest for includeBefore
+		parsed, err := includeAfterDateFilter{}.ParseISO8601(includeBefore, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		before = &parsed
+	}
+
+	if after != nil && before != nil && after.After(*before) {
+		return nil, nil, fmt.Errorf("the time given to %s can't be later than the one given to %s, since then no file could be included",
+			common.IncludeAfterFlagName, common.IncludeBeforeFlagName)
+	}
+
+	return after, before, nil
+}
+
 // ParseISO8601 parses ISO 8601 dates. This routine is needed because GoLang's time.Parse* routines require all expected
 // elements to be present.  I.e. you can't specify just a date, and have the time default to 00:00. But ISO 8601 requires
 // that and, for usability, that's what we want.  (So that users can omit the whole time, or at least the seconds portion of it, if they wish)
@@ -314,3 +466,217 @@ func (_ includeAfterDateFilter) ParseISO8601(s string, chooseEarliest bool) (tim
 func (_ includeAfterDateFilter) FormatAsUTC(t time.Time) string {
 	return t.UTC().Format(time.RFC3339)
 }
+
+////////
+
+// fileSizeFilter includes only files whose size is within the given range. Either end of the range may be left open
+type fileSizeFilter struct {
+	minSize *int64
+	maxSize *int64
+}
+
+func (f *fileSizeFilter) doesSupportThisOS() (msg string, supported bool) {
+	msg = ""
+	supported = true
+	return
+}
+
+func (f *fileSizeFilter) appliesOnlyToFiles() bool {
+	return true // folders have no size of their own
+}
+
+func (f *fileSizeFilter) doesPass(storedObject storedObject) bool {
+	if f.minSize != nil && storedObject.size < *f.minSize {
+		return false
+	}
+	if f.maxSize != nil && storedObject.size > *f.maxSize {
+		return false
+	}
+
+	return true
+}
+
+func buildFileSizeFilters(minSize, maxSize *int64) []objectFilter {
+	if minSize == nil && maxSize == nil {
+		return []objectFilter{}
+	}
+
+	return []objectFilter{&fileSizeFilter{minSize: minSize, maxSize: maxSize}}
+}
+
+// the multipliers of the units that file sizes may be given in. The single letters are binary units,
+// as they are everywhere else in AzCopy, and KB, MB etc. are decimal ones
+var fileSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"kb":  1e3,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"mb":  1e6,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"gb":  1e9,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+	"tb":  1e12,
+}
+
+// parseFileSize parses a file size given by the user, such as 500, 64KiB, 1.5G or 2 GB, into a number of bytes
+func parseFileSize(s string, flagName string) (int64, error) {
+	s = strings.TrimSpace(s)
+	numberEnd := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if numberEnd == -1 {
+		numberEnd = len(s)
+	}
+
+	number, err := strconv.ParseFloat(s[:numberEnd], 64)
+	multiplier, knownUnit := fileSizeUnits[strings.ToLower(strings.TrimSpace(s[numberEnd:]))]
+	if err != nil || !knownUnit {
+		return 0, fmt.Errorf("%s must be a number of bytes, optionally followed by a unit such as KiB, MiB, GiB or TiB (or KB, MB, GB or TB for powers of 1000). E.g. 500, 64KiB or 1.5GB. '%s' is not", flagName, s)
+	}
+
+	return int64(number * multiplier), nil
+}
+
+// parseFileSizeRange parses the values of the min-size and max-size flags. A nil result means there's no limit
+func parseFileSizeRange(minSize, maxSize string) (min *int64, max *int64, err error) {
+	parse := func(s string, flagName string) (*int64, error) {
+		if s == "" {
+			return nil, nil
+		}
+		size, err := parseFileSize(s, flagName)
+		if err != nil {
+			return nil, err
+		}
+		return &size, nil
+	}
+
+	if min, err = parse(minSize, "min-size"); err != nil {
+		return nil, nil, err
+	}
+	if max, err = parse(maxSize, "max-size"); err != nil {
+		return nil, nil, err
+	}
+	if min != nil && max != nil && *min > *max {
+		return nil, nil, fmt.Errorf("min-size (%d bytes) can't be more than max-size (%d bytes)", *min, *max)
+	}
+
+	return min, max, nil
+}
+
+////////
+
+// blobTagsFilter includes only the blobs that have all the given index tags. The values may use wildcards (*)
+type blobTagsFilter struct {
+	tags map[string]string
+}
+
+func (f *blobTagsFilter) doesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *blobTagsFilter) appliesOnlyToFiles() bool {
+	return true // folders have no tags
+}
+
+func (f *blobTagsFilter) doesPass(storedObject storedObject) bool {
+	// tag names are case-sensitive
+	return keyValuesMatch(f.tags, storedObject.blobTags, func(key string, values map[string]string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	})
+}
+
+// getFindByTagsQuery returns the where expression that finds the blobs with these tags in the container. The API
+// can only find tags with exact values, so there's no query when any of the values has a wildcard
+func (f *blobTagsFilter) getFindByTagsQuery(containerName string) string {
+	conditions := []string{fmt.Sprintf("@container='%s'", containerName)}
+	for _, key := range sortedKeys(f.tags) {
+		if strings.ContainsAny(f.tags[key], "*?[\\'") || strings.Contains(key, "\"") {
+			return ""
+		}
+		conditions = append(conditions, fmt.Sprintf("\"%s\"='%s'", key, f.tags[key]))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// metadataFilter includes only the files that have all the given metadata. The values may use wildcards (*)
+type metadataFilter struct {
+	metadata map[string]string
+}
+
+func (f *metadataFilter) doesSupportThisOS() (msg string, supported bool) {
+	return "", true
+}
+
+func (f *metadataFilter) appliesOnlyToFiles() bool {
+	return true
+}
+
+func (f *metadataFilter) doesPass(storedObject storedObject) bool {
+	// metadata names are case-insensitive
+	return keyValuesMatch(f.metadata, storedObject.Metadata, func(key string, values map[string]string) (string, bool) {
+		for k, v := range values {
+			if strings.EqualFold(k, key) {
+				return v, true
+			}
+		}
+		return "", false
+	})
+}
+
+// keyValuesMatch says whether the values have all the keys of the patterns, with values that match the patterns' values
+func keyValuesMatch(patterns map[string]string, values map[string]string, lookup func(key string, values map[string]string) (string, bool)) bool {
+	for key, pattern := range patterns {
+		value, ok := lookup(key, values)
+		if !ok {
+			return false
+		}
+		if matched, err := path.Match(pattern, value); err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func buildBlobTagsFilters(tags map[string]string) []objectFilter {
+	if len(tags) == 0 {
+		return []objectFilter{}
+	}
+	return []objectFilter{&blobTagsFilter{tags: tags}}
+}
+
+func buildMetadataFilters(metadata map[string]string) []objectFilter {
+	if len(metadata) == 0 {
+		return []objectFilter{}
+	}
+	return []objectFilter{&metadataFilter{metadata: metadata}}
+}
+
+// parseKeyValueFilter parses a list of key=value pairs, such as "project=alpha;env=prod", all of which must match
+func parseKeyValueFilter(s string, flagName string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		keyAndValue := strings.SplitN(pair, "=", 2)
+		key := strings.TrimSpace(keyAndValue[0])
+		if len(keyAndValue) != 2 || key == "" {
+			return nil, fmt.Errorf("%s must be a list of key=value pairs, separated by ';'. E.g. project=alpha;env=prod. '%s' is not a key=value pair", flagName, pair)
+		}
+		pairs[key] = keyAndValue[1]
+	}
+	return pairs, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -12,7 +12,8 @@ import (
 func initPipeline(ctx context.Context, location common.Location, credential common.CredentialInfo) (p pipeline.Pipeline, err error) {
 	switch location {
 	case common.ELocation.Local(),
-		common.ELocation.Benchmark():
+		common.ELocation.Benchmark(),
+		common.ELocation.SFTP():
 		// Gracefully return
 		return nil, nil
 	case common.ELocation.Blob():
@@ -21,6 +22,8 @@ func initPipeline(ctx context.Context, location common.Location, credential comm
 		p, err = createFilePipeline(ctx, credential)
 	case common.ELocation.BlobFS():
 		p, err = createBlobFSPipeline(ctx, credential)
+	case common.ELocation.HTTP():
+		p, err = createHTTPPipeline(ctx, credential)
 	case common.ELocation.S3():
 		// Gracefully return because pipelines aren't used for S3
 		return nil, nil
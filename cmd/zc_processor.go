@@ -43,6 +43,9 @@ type copyTransferProcessor struct {
 
 	preserveAccessTier     bool
 	folderPropertiesOption common.FolderPropertyOption
+
+	// set in a dry run, so that the parts are printed instead of being sent to the transfer engine
+	dryRunPrinter *dryRunPrinter
 }
 
 func newCopyTransferProcessor(copyJobTemplate *common.CopyJobPartOrderRequest, numOfTransfersPerPart int,
@@ -65,7 +68,11 @@ func (s *copyTransferProcessor) scheduleCopyTransfer(storedObject storedObject)
 	// Escape paths on destinations where the characters are invalid
 	// And re-encode them where the characters are valid.
 	srcRelativePath := pathEncodeRules(storedObject.relativePath, s.copyJobTemplate.FromTo, true)
-	dstRelativePath := pathEncodeRules(storedObject.relativePath, s.copyJobTemplate.FromTo, false)
+	dstRelativePath := storedObject.relativePath
+	if storedObject.dstRelativePath != "" {
+		dstRelativePath = storedObject.dstRelativePath
+	}
+	dstRelativePath = pathEncodeRules(dstRelativePath, s.copyJobTemplate.FromTo, false)
 
 	copyTransfer, shouldSendToSte := storedObject.ToNewCopyTransfer(
 		false, // sync has no --decompress option
@@ -129,7 +136,11 @@ func (s *copyTransferProcessor) dispatchFinalPart() (copyJobInitiated bool, err
 // only test the response on the final dispatch to help diagnose root cause of test failures from 0 transfers
 func (s *copyTransferProcessor) sendPartToSte() common.CopyJobPartOrderResponse {
 	var resp common.CopyJobPartOrderResponse
-	Rpc(common.ERpcCmd.CopyJobPartOrder(), s.copyJobTemplate, &resp)
+	if s.dryRunPrinter != nil {
+		resp = s.dryRunPrinter.printPart(s.copyJobTemplate)
+	} else {
+		Rpc(common.ERpcCmd.CopyJobPartOrder(), s.copyJobTemplate, &resp)
+	}
 
 	// if the current part order sent to ste is 0, then alert the progress reporting routine
 	if s.copyJobTemplate.PartNum == 0 && s.reportFirstPartDispatched != nil {
@@ -32,6 +32,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-azcopy/ste"
 )
 
 // allow us to iterate through a path pointing to the blob endpoint
@@ -44,6 +45,15 @@ type blobTraverser struct {
 	// whether to include blobs that have metadata 'hdi_isfolder = true'
 	includeDirectoryStubs bool
 
+	// whether to include the snapshots of blobs
+	includeSnapshots bool
+
+	// whether to include every version of each blob, rather than the current ones
+	includeVersions bool
+
+	// whether to include blobs that have been soft-deleted
+	includeDeleted bool
+
 	// a generic function to notify that a new stored object has been enumerated
 	incrementEnumerationCounter enumerationCounterFunc
 }
@@ -95,6 +105,9 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 	blobUrlParts := azblob.NewBlobURLParts(*t.rawURL)
 	util := copyHandlerUtil{}
 
+	// index tags are only got when something needs them, since they cost more to get
+	needsBlobTags := filterSet(filters).needsBlobTags()
+
 	// check if the url points to a single blob
 	blobProperties, isBlob, isDirStub, propErr := t.getPropertiesIfSingleBlob()
 
@@ -107,6 +120,11 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 		}
 	}
 
+	// a managed disk's URL is for the single blob of the disk, and its account can't be listed, so there's nothing else to try
+	if propErr != nil && ste.IsManagedDiskURL(*t.rawURL) {
+		return fmt.Errorf("cannot get the properties of the managed disk, whose access URL may have expired or been revoked: %s", propErr)
+	}
+
 	// schedule the blob in two cases:
 	// 	1. either we are targeting a single blob and the URL wasn't explicitly pointed to a virtual dir
 	//	2. either we are scanning recursively with includeDirectoryStubs set to true,
@@ -118,6 +136,12 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 			panic("isBlob should never be set if getting properties is an error")
 		}
 
+		// the current version is listed along with the others, after them
+		if isBlob && t.includeVersions {
+			containerURL := azblob.NewContainerURL(util.getContainerUrl(blobUrlParts), t.p)
+			return t.traverseHistoryOfBlob(containerURL, blobUrlParts.BlobName, blobUrlParts.ContainerName, preprocessor, processor, filters)
+		}
+
 		storedObject := newStoredObject(
 			preprocessor,
 			getObjectNameOnly(strings.TrimSuffix(blobUrlParts.BlobName, common.AZCOPY_PATH_SEPARATOR_STRING)),
@@ -130,6 +154,14 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 			common.FromAzBlobMetadataToCommonMetadata(blobProperties.NewMetadata()), // .NewMetadata() seems odd to call, but it does actually retrieve the metadata from the blob properties.
 			blobUrlParts.ContainerName,
 		)
+		if needsBlobTags {
+			blobURL := azblob.NewBlobURLParts(*t.rawURL)
+			blobURL.BlobName = strings.TrimSuffix(blobURL.BlobName, common.AZCOPY_PATH_SEPARATOR_STRING)
+			storedObject.blobTags, err = getBlobTags(t.ctx, t.p, blobURL.URL())
+			if err != nil {
+				return fmt.Errorf("cannot get the tags of the blob due to reason %s", err)
+			}
+		}
 
 		if t.incrementEnumerationCounter != nil {
 			t.incrementEnumerationCounter(common.EEntityType.File())
@@ -139,6 +171,10 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 		_, err = getProcessingError(err)
 
 		// short-circuit if we don't have anything else to scan
+		if isBlob && err == nil && t.includeSnapshots {
+			containerURL := azblob.NewContainerURL(util.getContainerUrl(blobUrlParts), t.p)
+			return t.traverseHistoryOfBlob(containerURL, blobUrlParts.BlobName, blobUrlParts.ContainerName, preprocessor, processor, filters)
+		}
 		if isBlob || err != nil {
 			return err
 		}
@@ -162,13 +198,25 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 	// as a performance optimization, get an extra prefix to do pre-filtering. It's typically the start portion of a blob name.
 	extraSearchPrefix := filterSet(filters).GetEnumerationPreFilter(t.recursive)
 
+	if t.includeSnapshots || t.includeVersions {
+		return t.traverseWithHistory(containerURL, searchPrefix, extraSearchPrefix, blobUrlParts.ContainerName, preprocessor, processor, filters)
+	}
+
+	// when the blobs are filtered by their tags, ask the service to find the ones with those tags, if it can,
+	// rather than list them all. It doesn't find soft-deleted blobs, though
+	if query := filterSet(filters).getFindByTagsQuery(blobUrlParts.ContainerName); query != "" && !t.includeDeleted {
+		if found, err := t.traverseBlobsFoundByTags(query, searchPrefix, preprocessor, processor, filters); found {
+			return err
+		}
+	}
+
 	// Define how to enumerate its contents
 	// This func must be thread safe/goroutine safe
 	enumerateOneDir := func(dir parallel.Directory, enqueueDir func(parallel.Directory), enqueueOutput func(parallel.DirectoryEntry, error)) error {
 		currentDirPath := dir.(string)
 		for marker := (azblob.Marker{}); marker.NotDone(); {
 			lResp, err := containerURL.ListBlobsHierarchySegment(t.ctx, marker, "/", azblob.ListBlobsSegmentOptions{Prefix: currentDirPath,
-				Details: azblob.BlobListingDetails{Metadata: true}})
+				Details: azblob.BlobListingDetails{Metadata: true, Tags: needsBlobTags, Deleted: t.includeDeleted}})
 			if err != nil {
 				return fmt.Errorf("cannot list files due to reason %s", err)
 			}
@@ -188,20 +236,7 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 				}
 
 				relativePath := strings.TrimPrefix(blobInfo.Name, searchPrefix)
-				adapter := blobPropertiesAdapter{blobInfo.Properties}
-				storedObject := newStoredObject(
-					preprocessor,
-					getObjectNameOnly(blobInfo.Name),
-					relativePath,
-					common.EEntityType.File(),
-					blobInfo.Properties.LastModified,
-					*blobInfo.Properties.ContentLength,
-					adapter,
-					adapter, // adapter satisfies both interfaces
-					common.FromAzBlobMetadataToCommonMetadata(blobInfo.Metadata),
-					blobUrlParts.ContainerName,
-				)
-				enqueueOutput(storedObject, nil)
+				enqueueOutput(t.listedStoredObject(preprocessor, blobInfo, relativePath, blobUrlParts.ContainerName, needsBlobTags), nil)
 			}
 
 			marker = lResp.NextMarker
@@ -236,6 +271,32 @@ func (t *blobTraverser) traverse(preprocessor objectMorpher, processor objectPro
 	return
 }
 
+// listedStoredObject makes the stored object of a blob, or a snapshot or version of one, that was found by listing its container
+func (t *blobTraverser) listedStoredObject(preprocessor objectMorpher, blobInfo azblob.BlobItemInternal, relativePath string, containerName string, needsBlobTags bool) storedObject {
+	adapter := blobPropertiesAdapter{blobInfo.Properties}
+	storedObject := newStoredObject(
+		preprocessor,
+		getObjectNameOnly(blobInfo.Name),
+		relativePath,
+		common.EEntityType.File(),
+		blobInfo.Properties.LastModified,
+		*blobInfo.Properties.ContentLength,
+		adapter,
+		adapter, // adapter satisfies both interfaces
+		common.FromAzBlobMetadataToCommonMetadata(blobInfo.Metadata),
+		containerName,
+	)
+	if needsBlobTags {
+		storedObject.blobTags = blobTagsToMap(blobInfo.BlobTags)
+	}
+	storedObject.blobSnapshotID = blobInfo.Snapshot
+	storedObject.blobDeleted = blobInfo.Deleted
+	if t.includeVersions && blobInfo.VersionID != nil {
+		storedObject.blobVersionID = *blobInfo.VersionID
+	}
+	return storedObject
+}
+
 func newBlobTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, recursive, includeDirectoryStubs bool,
 	incrementEnumerationCounter enumerationCounterFunc) (t *blobTraverser) {
 	t = &blobTraverser{rawURL: rawURL, p: p, ctx: ctx, recursive: recursive, includeDirectoryStubs: includeDirectoryStubs,
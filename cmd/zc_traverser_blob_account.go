@@ -37,6 +37,9 @@ type blobAccountTraverser struct {
 	containerPattern      string
 	cachedContainers      []string
 	includeDirectoryStubs bool
+	includeSnapshots      bool
+	includeVersions       bool
+	includeDeleted        bool
 
 	// a generic function to notify that a new stored object has been enumerated
 	incrementEnumerationCounter enumerationCounterFunc
@@ -96,6 +99,9 @@ func (t *blobAccountTraverser) traverse(preprocessor objectMorpher, processor ob
 	for _, v := range cList {
 		containerURL := t.accountURL.NewContainerURL(v).URL()
 		containerTraverser := newBlobTraverser(&containerURL, t.p, t.ctx, true, t.includeDirectoryStubs, t.incrementEnumerationCounter)
+		containerTraverser.includeSnapshots = t.includeSnapshots
+		containerTraverser.includeVersions = t.includeVersions
+		containerTraverser.includeDeleted = t.includeDeleted
 
 		preprocessorForThisChild := preprocessor.FollowedBy(newContainerDecorator(v))
 
@@ -0,0 +1,130 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The service only lists snapshots and versions in flat listings, so when they are included, the blobs are listed flat
+// rather than crawled virtual directory by virtual directory, and a single blob's snapshots or versions are found by
+// listing with its name. Versions are listed oldest first, which is the order in which they must be copied.
+
+// enableSnapshotListing makes a traverser of the source list the snapshots of blobs, as well as the blobs
+func enableSnapshotListing(traverser resourceTraverser) error {
+	switch t := traverser.(type) {
+	case *blobTraverser:
+		t.includeSnapshots = true
+	case *blobAccountTraverser:
+		t.includeSnapshots = true
+	default:
+		return errors.New("include-snapshots can only be used when the source is a container, directory or blob in Blob Storage")
+	}
+	return nil
+}
+
+// enableVersionListing makes a traverser of the source list every version of each blob, rather than the current ones
+func enableVersionListing(traverser resourceTraverser) error {
+	switch t := traverser.(type) {
+	case *blobTraverser:
+		t.includeVersions = true
+	case *blobAccountTraverser:
+		t.includeVersions = true
+	default:
+		return errors.New("include-versions can only be used when the source is an account, container, directory or blob in Blob Storage")
+	}
+	return nil
+}
+
+// snapshotDestination gives the destination path of a blob's snapshot, which is the blob's path with the snapshot's
+// time added to the name, before the extension, so that the snapshots don't overwrite the blob or each other. Colons
+// aren't allowed in the names of local files on Windows, so they are replaced
+func snapshotDestination(destination string, snapshotID string) string {
+	ext := path.Ext(destination)
+	if ext == path.Base(destination) {
+		ext = "" // a name like .profile has no extension
+	}
+	return strings.TrimSuffix(destination, ext) + "." + strings.ReplaceAll(snapshotID, ":", "-") + ext
+}
+
+// listBlobsWithHistory calls process with each blob whose name starts with prefix, and with each of their snapshots or
+// versions, if they are included
+func (t *blobTraverser) listBlobsWithHistory(containerURL azblob.ContainerURL, prefix string, needsBlobTags bool, process func(azblob.BlobItemInternal) error) error {
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		lResp, err := containerURL.ListBlobsFlatSegment(t.ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: prefix,
+			Details: azblob.BlobListingDetails{Metadata: true, Tags: needsBlobTags, Snapshots: t.includeSnapshots, Versions: t.includeVersions, Deleted: t.includeDeleted}})
+		if err != nil {
+			return fmt.Errorf("cannot list files due to reason %s", err)
+		}
+
+		for _, blobInfo := range lResp.Segment.BlobItems {
+			if err = process(blobInfo); err != nil {
+				return err
+			}
+		}
+		marker = lResp.NextMarker
+	}
+	return nil
+}
+
+// traverseHistoryOfBlob processes the snapshots of a single blob, which has been processed itself already, or all of
+// its versions, including the current one
+func (t *blobTraverser) traverseHistoryOfBlob(containerURL azblob.ContainerURL, blobName string, containerName string, preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	needsBlobTags := filterSet(filters).needsBlobTags()
+	return t.listBlobsWithHistory(containerURL, blobName, needsBlobTags, func(blobInfo azblob.BlobItemInternal) error {
+		if blobInfo.Name != blobName || t.includeSnapshots && blobInfo.Snapshot == "" {
+			return nil // the blob itself, or another blob whose name starts with the same characters
+		}
+		return t.processListedBlob(t.listedStoredObject(preprocessor, blobInfo, "", containerName, needsBlobTags), processor, filters)
+	})
+}
+
+// traverseWithHistory processes the blobs under the search prefix, and their snapshots or versions
+func (t *blobTraverser) traverseWithHistory(containerURL azblob.ContainerURL, searchPrefix string, extraSearchPrefix string, containerName string, preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	needsBlobTags := filterSet(filters).needsBlobTags()
+	return t.listBlobsWithHistory(containerURL, searchPrefix+extraSearchPrefix, needsBlobTags, func(blobInfo azblob.BlobItemInternal) error {
+		relativePath := strings.TrimPrefix(blobInfo.Name, searchPrefix)
+		if !t.recursive && strings.Contains(relativePath, common.AZCOPY_PATH_SEPARATOR_STRING) {
+			return nil
+		}
+		if gCopyUtil.doesBlobRepresentAFolder(blobInfo.Metadata) && !(t.includeDirectoryStubs && t.recursive) {
+			return nil
+		}
+		return t.processListedBlob(t.listedStoredObject(preprocessor, blobInfo, relativePath, containerName, needsBlobTags), processor, filters)
+	})
+}
+
+func (t *blobTraverser) processListedBlob(object storedObject, processor objectProcessor, filters []objectFilter) error {
+	if t.incrementEnumerationCounter != nil {
+		t.incrementEnumerationCounter(common.EEntityType.File())
+	}
+
+	err := processIfPassedFilters(filters, object, processor)
+	_, err = getProcessingError(err)
+	return err
+}
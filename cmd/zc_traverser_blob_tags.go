@@ -0,0 +1,191 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The blob SDK can list blobs with their index tags, but it has no public methods for getting the tags of one blob,
+// or for the find-by-tags API, so the requests for those are made here.
+
+// getBlobTags gets the index tags of a blob
+func getBlobTags(ctx context.Context, p pipeline.Pipeline, blobURL url.URL) (map[string]string, error) {
+	query := blobURL.Query()
+	query.Set("comp", "tags")
+	blobURL.RawQuery = query.Encode()
+
+	var tags azblob.BlobTags
+	if err := getBlobServiceXML(ctx, p, blobURL, &tags); err != nil {
+		return nil, err
+	}
+
+	return blobTagsToMap(&tags), nil
+}
+
+// findBlobsByTags gets one segment of the blobs in the account whose index tags match the where expression
+func findBlobsByTags(ctx context.Context, p pipeline.Pipeline, serviceURL url.URL, where string, marker *string) (*azblob.FilterBlobSegment, error) {
+	query := serviceURL.Query()
+	query.Set("comp", "blobs")
+	query.Set("where", where)
+	if marker != nil && *marker != "" {
+		query.Set("marker", *marker)
+	}
+	serviceURL.RawQuery = query.Encode()
+
+	segment := &azblob.FilterBlobSegment{}
+	if err := getBlobServiceXML(ctx, p, serviceURL, segment); err != nil {
+		return nil, err
+	}
+
+	return segment, nil
+}
+
+func getBlobServiceXML(ctx context.Context, p pipeline.Pipeline, u url.URL, result interface{}) error {
+	request, err := pipeline.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("x-ms-version", azblob.ServiceVersion)
+
+	response, err := p.Do(ctx, nil, request)
+	if err != nil {
+		return err
+	}
+	r := response.Response()
+	body, err := ioutil.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if r.StatusCode != http.StatusOK {
+		// leave out the query, since it may hold a SAS
+		status := r.Status
+		if code := r.Header.Get("x-ms-error-code"); code != "" {
+			status += " (" + code + ")"
+		}
+		return fmt.Errorf("%s %s://%s%s failed: %s", http.MethodGet, u.Scheme, u.Host, u.Path, status)
+	}
+
+	return xml.Unmarshal(body, result)
+}
+
+func blobTagsToMap(tags *azblob.BlobTags) map[string]string {
+	result := make(map[string]string)
+	if tags != nil {
+		for _, tag := range tags.BlobTagSet {
+			result[tag.Key] = tag.Value
+		}
+	}
+	return result
+}
+
+// traverseBlobsFoundByTags gives the processor the blobs that the find-by-tags API finds with the query, rather than
+// listing every blob and checking its tags. The API finds blobs throughout the container, so those outside the traversed
+// virtual directory are left out here. found is false if the API couldn't be used at all, e.g. because the SAS doesn't
+// give permission for it, in which case the blobs must be listed instead
+func (t *blobTraverser) traverseBlobsFoundByTags(query string, searchPrefix string, preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (found bool, err error) {
+	blobUrlParts := azblob.NewBlobURLParts(*t.rawURL)
+	containerURL := azblob.NewContainerURL(copyHandlerUtil{}.getContainerUrl(blobUrlParts), t.p)
+
+	serviceURLParts := blobUrlParts
+	serviceURLParts.ContainerName = ""
+	serviceURLParts.BlobName = ""
+	serviceURLParts.Snapshot = ""
+	serviceURLParts.VersionID = ""
+	serviceURL := serviceURLParts.URL()
+
+	for marker := (*string)(nil); !found || (marker != nil && *marker != ""); {
+		segment, err := findBlobsByTags(t.ctx, t.p, serviceURL, query, marker)
+		if err != nil {
+			if !found {
+				glcm.Info(fmt.Sprintf("The blobs couldn't be found by their tags (%s), so all the blobs will be listed, and their tags checked, instead.", err))
+				return false, nil
+			}
+			return true, fmt.Errorf("cannot find blobs by their tags due to reason %s", err)
+		}
+		found = true
+		marker = segment.NextMarker
+
+		for _, item := range segment.Blobs {
+			if item.ContainerName != blobUrlParts.ContainerName || !strings.HasPrefix(item.Name, searchPrefix) {
+				continue
+			}
+			relativePath := strings.TrimPrefix(item.Name, searchPrefix)
+			if !t.recursive && strings.Contains(relativePath, common.AZCOPY_PATH_SEPARATOR_STRING) {
+				continue
+			}
+
+			blobURL := containerURL.NewBlobURL(item.Name)
+			props, err := blobURL.GetProperties(t.ctx, azblob.BlobAccessConditions{})
+			if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+				continue // the blob was deleted after it was found
+			} else if err != nil {
+				return true, fmt.Errorf("cannot get the properties of %s due to reason %s", item.Name, err)
+			}
+			if gCopyUtil.doesBlobRepresentAFolder(props.NewMetadata()) && !(t.includeDirectoryStubs && t.recursive) {
+				continue
+			}
+
+			storedObject := newStoredObject(
+				preprocessor,
+				getObjectNameOnly(item.Name),
+				relativePath,
+				common.EEntityType.File(),
+				props.LastModified(),
+				props.ContentLength(),
+				props,
+				blobPropertiesResponseAdapter{props},
+				common.FromAzBlobMetadataToCommonMetadata(props.NewMetadata()),
+				blobUrlParts.ContainerName,
+			)
+			// the tags are got again, since they may have changed after the index was updated
+			storedObject.blobTags, err = getBlobTags(t.ctx, t.p, blobURL.URL())
+			if err != nil {
+				return true, fmt.Errorf("cannot get the tags of %s due to reason %s", item.Name, err)
+			}
+
+			if t.incrementEnumerationCounter != nil {
+				t.incrementEnumerationCounter(common.EEntityType.File())
+			}
+
+			processErr := processIfPassedFilters(filters, storedObject, processor)
+			_, processErr = getProcessingError(processErr)
+			if processErr != nil {
+				return true, processErr
+			}
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,90 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// httpTraverser "enumerates" a single file at a plain HTTP(S) URL. Web servers can't be listed,
+// so several files are copied by naming them, relative to a base URL, with --list-of-files
+type httpTraverser struct {
+	rawURL *url.URL // with the query string, if any, since the HEAD request may need it
+	p      pipeline.Pipeline
+	ctx    context.Context
+
+	// A generic function to notify that a new stored object has been enumerated
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+func newHTTPTraverser(rawURL *url.URL, p pipeline.Pipeline, ctx context.Context, incrementEnumerationCounter enumerationCounterFunc) *httpTraverser {
+	return &httpTraverser{rawURL: rawURL, p: p, ctx: ctx, incrementEnumerationCounter: incrementEnumerationCounter}
+}
+
+func (t *httpTraverser) isDirectory(bool) bool {
+	return t.rawURL.Path == "" || strings.HasSuffix(t.rawURL.Path, "/")
+}
+
+func (t *httpTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	if t.isDirectory(true) {
+		return errors.New("a web server can't be listed. To copy several files from under a plain HTTP(S) URL, name them with --list-of-files")
+	}
+
+	props, err := common.GetHTTPResourceProperties(t.ctx, t.p, *t.rawURL)
+	if err != nil {
+		return err
+	}
+
+	// the service needs the size, to read the file range by range
+	size := props.ContentLength()
+	if size < 0 {
+		return fmt.Errorf("the server didn't give the size of %s://%s%s, so it can't be copied", t.rawURL.Scheme, t.rawURL.Host, t.rawURL.Path)
+	}
+
+	if t.incrementEnumerationCounter != nil {
+		t.incrementEnumerationCounter(common.EEntityType.File())
+	}
+
+	storedObject := newStoredObject(
+		preprocessor,
+		path.Base(t.rawURL.Path),
+		"",
+		common.EEntityType.File(),
+		props.LastModified(),
+		size,
+		props,
+		noBlobProps,
+		common.Metadata{}, // a web server has no metadata of its own to give
+		"")
+
+	err = processIfPassedFilters(filters, storedObject, processor)
+	_, err = getProcessingError(err)
+	return err
+}
@@ -88,23 +88,23 @@ func (l *listTraverser) traverse(preprocessor objectMorpher, processor objectPro
 }
 
 func newListTraverser(parent common.ResourceString, parentType common.Location, credential *common.CredentialInfo, ctx *context.Context,
-	recursive, followSymlinks, getProperties bool, listChan chan string, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc) resourceTraverser {
+	recursive bool, symlinkHandling common.SymlinkHandlingType, getProperties bool, listChan chan string, includeDirectoryStubs bool, incrementEnumerationCounter enumerationCounterFunc) resourceTraverser {
 	var traverserGenerator childTraverserGenerator
 
 	traverserGenerator = func(relativeChildPath string) (resourceTraverser, error) {
 		source := parent.Clone()
-		if parentType != common.ELocation.Local() {
+		if parentType != common.ELocation.Local() && parentType != common.ELocation.SFTP() {
 			// assume child path is not URL-encoded yet, this is consistent with the behavior of previous implementation
 			childURL, _ := url.Parse(parent.Value)
 			childURL.Path = common.GenerateFullPath(childURL.Path, relativeChildPath)
 			source.Value = childURL.String()
 		} else {
-			// is local, only generate the full path
+			// is local (or SFTP, whose paths aren't URL-encoded), only generate the full path
 			source.Value = common.GenerateFullPath(parent.ValueLocal(), relativeChildPath)
 		}
 
 		// Construct a traverser that goes through the child
-		traverser, err := initResourceTraverser(source, parentType, ctx, credential, &followSymlinks, nil, recursive, getProperties, includeDirectoryStubs, incrementEnumerationCounter, nil)
+		traverser, err := initResourceTraverser(source, parentType, ctx, credential, symlinkHandling, nil, recursive, getProperties, includeDirectoryStubs, incrementEnumerationCounter, nil)
 		if err != nil {
 			return nil, err
 		}
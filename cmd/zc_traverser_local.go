@@ -33,9 +33,9 @@ import (
 )
 
 type localTraverser struct {
-	fullPath       string
-	recursive      bool
-	followSymlinks bool
+	fullPath        string
+	recursive       bool
+	symlinkHandling common.SymlinkHandlingType
 
 	// a generic function to notify that a new stored object has been enumerated
 	incrementEnumerationCounter enumerationCounterFunc
@@ -148,11 +148,17 @@ func (s symlinkTargetFileInfo) Name() string {
 	return s.name // override the name
 }
 
+// symlinkObjectSize is the size of the object that preserves a symlink, which holds the target of the link
+func symlinkObjectSize(linkPath string) (int64, error) {
+	target, err := os.Readlink(linkPath)
+	return int64(len(target)), err
+}
+
 // WalkWithSymlinks is a symlinks-aware, parallelized, version of filePath.Walk.
 // Separate this from the traverser for two purposes:
 // 1) Cleaner code
 // 2) Easier to test individually than to test the entire traverser.
-func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlinks bool) (err error) {
+func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, symlinkHandling common.SymlinkHandlingType) (err error) {
 
 	// We want to re-queue symlinks up in their evaluated form because filepath.Walk doesn't evaluate them for us.
 	// So, what is the plan of attack?
@@ -174,7 +180,7 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 	// do NOT put fullPath: true into the map at this time, because we want to match the semantics of filepath.Walk, where the walkfunc is called for the root
 	// When following symlinks, our current implementation tracks folders and files.  Which may consume GB's of RAM when there are 10s of millions of files.
 	var seenPaths seenPathsRecorder = &nullSeenPathsRecorder{} // uses no RAM
-	if followSymlinks {
+	if symlinkHandling == common.ESymlinkHandlingType.Follow() {
 		seenPaths = &realSeenPathsRecorder{make(map[string]struct{})} // have to use the RAM if we are dealing with symlinks, to prevent cycles
 	}
 
@@ -195,7 +201,12 @@ func WalkWithSymlinks(fullPath string, walkFunc filepath.WalkFunc, followSymlink
 			computedRelativePath = strings.TrimPrefix(computedRelativePath, common.AZCOPY_PATH_SEPARATOR_STRING)
 
 			if fileInfo.Mode()&os.ModeSymlink != 0 {
-				if !followSymlinks {
+				if symlinkHandling == common.ESymlinkHandlingType.Preserve() {
+					// hand over the link itself, rather than what it points to
+					_, err := getProcessingError(walkFunc(common.GenerateFullPath(fullPath, computedRelativePath), fileInfo, fileError))
+					return err
+				}
+				if symlinkHandling != common.ESymlinkHandlingType.Follow() {
 					return nil // skip it
 				}
 				result, err := UnfurlSymlinks(filePath)
@@ -338,6 +349,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 				}
 
 				var entityType common.EntityType
+				size := fileInfo.Size()
 				if fileInfo.IsDir() {
 					entityType = common.EEntityType.Folder()
 				} else {
@@ -345,9 +357,19 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 				}
 
 				relPath := strings.TrimPrefix(strings.TrimPrefix(cleanLocalPath(filePath), cleanLocalPath(t.fullPath)), common.DeterminePathSeparator(t.fullPath))
-				if !t.followSymlinks && fileInfo.Mode()&os.ModeSymlink != 0 {
-					WarnStdoutAndJobLog(fmt.Sprintf("Skipping over symlink at %s because --follow-symlinks is false", common.GenerateFullPath(t.fullPath, relPath)))
-					return nil
+				if fileInfo.Mode()&os.ModeSymlink != 0 {
+					if t.symlinkHandling != common.ESymlinkHandlingType.Preserve() {
+						WarnStdoutAndJobLog(fmt.Sprintf("Skipping over symlink at %s because --follow-symlinks is false", common.GenerateFullPath(t.fullPath, relPath)))
+						return nil
+					}
+
+					entityType = common.EEntityType.Symlink()
+					linkSize, err := symlinkObjectSize(filePath)
+					if err != nil {
+						WarnStdoutAndJobLog(fmt.Sprintf("Failed to read symlink %s: %s", filePath, err))
+						return nil
+					}
+					size = linkSize
 				}
 
 				if t.incrementEnumerationCounter != nil {
@@ -362,7 +384,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 						strings.ReplaceAll(relPath, common.DeterminePathSeparator(t.fullPath), common.AZCOPY_PATH_SEPARATOR_STRING), // Consolidate relative paths to the azcopy path separator for sync
 						entityType,
 						fileInfo.ModTime(), // get this for both files and folders, since sync needs it for both.
-						fileInfo.Size(),
+						size,
 						noContentProps, // Local MD5s are computed in the STE, and other props don't apply to local files
 						noBlobProps,
 						noMetdata,
@@ -372,7 +394,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 			}
 
 			// note: Walk includes root, so no need here to separately create storedObject for root (as we do for other folder-aware sources)
-			return WalkWithSymlinks(t.fullPath, processFile, t.followSymlinks)
+			return WalkWithSymlinks(t.fullPath, processFile, t.symlinkHandling)
 		} else {
 			// if recursive is off, we only need to scan the files immediately under the fullPath
 			// We don't transfer any directory properties here, not even the root. (Because the root's
@@ -387,8 +409,15 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 			for _, singleFile := range files {
 				// This won't change. It's purely to hand info off to STE about where the symlink lives.
 				relativePath := singleFile.Name()
+				entityType := common.EEntityType.File()
+				size := singleFile.Size()
 				if singleFile.Mode()&os.ModeSymlink != 0 {
-					if !t.followSymlinks {
+					if t.symlinkHandling == common.ESymlinkHandlingType.Preserve() {
+						entityType = common.EEntityType.Symlink()
+						if size, err = symlinkObjectSize(common.GenerateFullPath(t.fullPath, singleFile.Name())); err != nil {
+							return err
+						}
+					} else if t.symlinkHandling != common.ESymlinkHandlingType.Follow() {
 						continue
 					} else {
 						// Because this only goes one layer deep, we can just append the filename to fullPath and resolve with it.
@@ -413,6 +442,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 						if err != nil {
 							return err
 						}
+						size = singleFile.Size()
 					}
 				}
 
@@ -422,7 +452,7 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 				}
 
 				if t.incrementEnumerationCounter != nil {
-					t.incrementEnumerationCounter(common.EEntityType.File())
+					t.incrementEnumerationCounter(entityType)
 				}
 
 				err := processIfPassedFilters(filters,
@@ -430,9 +460,9 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 						preprocessor,
 						singleFile.Name(),
 						strings.ReplaceAll(relativePath, common.DeterminePathSeparator(t.fullPath), common.AZCOPY_PATH_SEPARATOR_STRING), // Consolidate relative paths to the azcopy path separator for sync
-						common.EEntityType.File(), // TODO: add code path for folders
+						entityType, // TODO: add code path for folders
 						singleFile.ModTime(),
-						singleFile.Size(),
+						size,
 						noContentProps, // Local MD5s are computed in the STE, and other props don't apply to local files
 						noBlobProps,
 						noMetdata,
@@ -450,11 +480,11 @@ func (t *localTraverser) traverse(preprocessor objectMorpher, processor objectPr
 	return
 }
 
-func newLocalTraverser(fullPath string, recursive bool, followSymlinks bool, incrementEnumerationCounter enumerationCounterFunc) *localTraverser {
+func newLocalTraverser(fullPath string, recursive bool, symlinkHandling common.SymlinkHandlingType, incrementEnumerationCounter enumerationCounterFunc) *localTraverser {
 	traverser := localTraverser{
 		fullPath:                    cleanLocalPath(fullPath),
 		recursive:                   recursive,
-		followSymlinks:              followSymlinks,
+		symlinkHandling:             symlinkHandling,
 		incrementEnumerationCounter: incrementEnumerationCounter}
 	return &traverser
 }
@@ -0,0 +1,164 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// sftpTraverser enumerates a file, or a directory tree, on an SFTP server
+type sftpTraverser struct {
+	sftpURLParts   common.SFTPURLParts
+	sftpClient     *common.SFTPClient
+	recursive      bool
+	followSymlinks bool
+
+	// A generic function to notify that a new stored object has been enumerated
+	incrementEnumerationCounter enumerationCounterFunc
+}
+
+// the traversers share their connections, since a list of files makes one traverser for each file
+var sftpClientFactory = common.NewSFTPClientFactory()
+
+func newSFTPTraverser(source string, recursive bool, followSymlinks bool, incrementEnumerationCounter enumerationCounterFunc) (t *sftpTraverser, err error) {
+	t = &sftpTraverser{recursive: recursive, followSymlinks: followSymlinks, incrementEnumerationCounter: incrementEnumerationCounter}
+
+	t.sftpURLParts, err = common.NewSFTPURLParts(source)
+	if err != nil {
+		return nil, err
+	}
+
+	t.sftpClient, err = sftpClientFactory.GetSFTPClient(t.sftpURLParts)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *sftpTraverser) isDirectory(bool) bool {
+	if strings.HasSuffix(t.sftpURLParts.Path, "/") {
+		return true
+	}
+
+	info, err := t.sftpClient.Stat(t.sftpURLParts.Path)
+	return err == nil && info.IsDir()
+}
+
+func (t *sftpTraverser) traverse(preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) (err error) {
+	info, err := t.sftpClient.Stat(t.sftpURLParts.Path)
+	if err != nil {
+		return fmt.Errorf("cannot scan the path %s: %w", t.sftpURLParts.Path, err)
+	}
+
+	if !info.IsDir() {
+		return t.processFile(info, "", preprocessor, processor, filters)
+	}
+	return t.traverseDirectory(t.sftpURLParts.Path, "", preprocessor, processor, filters)
+}
+
+func (t *sftpTraverser) traverseDirectory(dir, relativeDir string, preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	entries, err := t.sftpClient.ReadDir(dir)
+	if err != nil {
+		if relativeDir == "" {
+			return err
+		}
+		// as for local folders, one that can't be read is skipped, rather than failing the whole job
+		WarnStdoutAndJobLog(fmt.Sprintf("Accessing %s failed with error: %s", dir, err))
+		return nil
+	}
+
+	for _, entry := range entries {
+		entryPath := path.Join(dir, entry.Name())
+		relativePath := path.Join(relativeDir, entry.Name())
+
+		if entry.Mode()&os.ModeSymlink != 0 {
+			if !t.followSymlinks {
+				continue
+			}
+			target, err := t.sftpClient.Stat(entryPath)
+			if err != nil {
+				WarnStdoutAndJobLog(fmt.Sprintf("Failed to resolve symlink %s: %s", entryPath, err))
+				continue
+			}
+			if target.IsDir() {
+				// since SFTP doesn't tell us where a link leads, there'd be no way to spot loops
+				WarnStdoutAndJobLog(fmt.Sprintf("Symlinks to directories are not supported on SFTP sources, so will ignore %s", entryPath))
+				continue
+			}
+			entry = sftpRenamedFileInfo{target, entry.Name()}
+		}
+
+		if entry.IsDir() {
+			if t.recursive {
+				if err = t.traverseDirectory(entryPath, relativePath, preprocessor, processor, filters); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !entry.Mode().IsRegular() {
+			continue // sockets, devices and pipes have no content to copy
+		}
+
+		if err = t.processFile(entry, relativePath, preprocessor, processor, filters); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *sftpTraverser) processFile(info os.FileInfo, relativePath string, preprocessor objectMorpher, processor objectProcessor, filters []objectFilter) error {
+	if t.incrementEnumerationCounter != nil {
+		t.incrementEnumerationCounter(common.EEntityType.File())
+	}
+
+	err := processIfPassedFilters(filters,
+		newStoredObject(
+			preprocessor,
+			info.Name(),
+			relativePath,
+			common.EEntityType.File(),
+			info.ModTime(),
+			info.Size(),
+			noContentProps, // as for local files, MD5s are computed in the STE
+			noBlobProps,
+			noMetdata,
+			"", // SFTP has no such thing as containers
+		),
+		processor)
+	_, err = getProcessingError(err)
+	return err
+}
+
+// sftpRenamedFileInfo is the info of the target of a symlink, under the name of the link
+type sftpRenamedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i sftpRenamedFileInfo) Name() string {
+	return i.name
+}
@@ -0,0 +1,126 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type batchSuite struct{}
+
+var _ = chk.Suite(&batchSuite{})
+
+func (s *batchSuite) TestSplitBatchLine(c *chk.C) {
+	args, err := splitBatchLine(`copy "/data/my photos" 'https://a.blob.core.windows.net/c?sv=1&sig=2'  --recursive`)
+	c.Assert(err, chk.IsNil)
+	c.Assert(args, chk.DeepEquals, []string{"copy", "/data/my photos", "https://a.blob.core.windows.net/c?sv=1&sig=2", "--recursive"})
+
+	args, err = splitBatchLine(`copy C:\data\x "" --include-pattern=*.jpg`)
+	c.Assert(err, chk.IsNil)
+	c.Assert(args, chk.DeepEquals, []string{"copy", `C:\data\x`, "", "--include-pattern=*.jpg"})
+
+	_, err = splitBatchLine(`copy "/data/x /dst`)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *batchSuite) TestReadBatchFile(c *chk.C) {
+	dir, err := ioutil.TempDir("", "batchtest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "jobs.txt")
+	content := "# nightly\n\ncopy /a /b --recursive\n  azcopy remove 'https://a.blob.core.windows.net/c/x'\n"
+	c.Assert(ioutil.WriteFile(path, []byte(content), 0600), chk.IsNil)
+
+	commandLines, err := readBatchFile(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(commandLines, chk.DeepEquals, [][]string{
+		{"copy", "/a", "/b", "--recursive"},
+		{"remove", "https://a.blob.core.windows.net/c/x"},
+	})
+
+	c.Assert(ioutil.WriteFile(path, []byte("# nothing\n"), 0600), chk.IsNil)
+	_, err = readBatchFile(path)
+	c.Assert(err, chk.ErrorMatches, ".*no jobs.*")
+}
+
+func (s *batchSuite) TestPrepareBatchJob(c *chk.C) {
+	dir, err := ioutil.TempDir("", "batchtest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	dst := "https://a.blob.core.windows.net/c?sv=1&sig=secret"
+	job, err := prepareBatchJob([]string{"copy", dir, dst, "--recursive"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(job.controller.(*cookedCopyCmdArgs).recursive, chk.Equals, true)
+	c.Assert(job.commandString, chk.Not(chk.Matches), ".*secret.*")
+
+	// each job starts from the defaults, not from the flags of the job before
+	job, err = prepareBatchJob([]string{"cp", dir, dst})
+	c.Assert(err, chk.IsNil)
+	c.Assert(job.controller.(*cookedCopyCmdArgs).recursive, chk.Equals, false)
+
+	_, err = prepareBatchJob([]string{"copy", dir, dst, "--cap-mbps", "10"})
+	c.Assert(err, chk.ErrorMatches, ".*--cap-mbps can't be given to a job in a batch.*")
+	_, err = prepareBatchJob([]string{"list", dst})
+	c.Assert(err, chk.ErrorMatches, ".*can't be run in a batch.*")
+	_, err = prepareBatchJob([]string{"copy", dir})
+	c.Assert(err, chk.NotNil)
+}
+
+// batchRecordingLifecycleManager records the exit codes, and counts the exits that would have ended the process
+type batchRecordingLifecycleManager struct {
+	mockedLifecycleManager
+	exitCodes []common.ExitCode
+}
+
+func (m *batchRecordingLifecycleManager) Exit(o common.OutputBuilder, e common.ExitCode) {
+	m.exitCodes = append(m.exitCodes, e)
+}
+
+func (s *batchSuite) TestOnlyTheLastJobEndsTheBatch(c *chk.C) {
+	lcm := &batchRecordingLifecycleManager{}
+	first, second := &cookedCopyCmdArgs{}, &cookedSyncCmdArgs{}
+	batch := newJobBatch(lcm, []batchJob{{commandString: "copy", controller: first}, {commandString: "sync", controller: second}})
+	wrapped := &batchLifecycleMgr{LifecycleMgr: lcm, batch: batch}
+
+	// a job with a followup leaves the process running, as it always has
+	wrapped.Exit(nil, common.EExitCode.NoExit())
+	c.Assert(lcm.exitCodes, chk.DeepEquals, []common.ExitCode{common.EExitCode.NoExit()})
+
+	// the first job to end doesn't end the process
+	wrapped.InitiateProgressReporting(first)
+	c.Assert(batch.isReporting(first), chk.Equals, true)
+	c.Assert(batch.isReporting(second), chk.Equals, false)
+	(&batchLifecycleMgr{LifecycleMgr: lcm, batch: batch, commandString: "copy"}).Exit(nil, common.EExitCode.Error())
+	c.Assert(lcm.exitCodes[len(lcm.exitCodes)-1], chk.Equals, common.EExitCode.NoExit())
+
+	// the last one does, and the batch fails, since one of its jobs did
+	batch.jobEnded("sync", common.EExitCode.Success())
+	c.Assert(lcm.exitCodes[len(lcm.exitCodes)-1], chk.Equals, common.EExitCode.Error())
+	c.Assert(batch.failed, chk.DeepEquals, []string{"copy"})
+}
@@ -39,7 +39,9 @@ func (s *genericFilterSuite) TestConversions(c *chk.C) {
 		{1, 1024 * 1024, ""},
 		{0.25, 256 * 1024, ""},
 		{0.000030517578125, 32, ""}, // 32 bytes, extremely small case
+		{4000, 4000 * 1024 * 1024, ""},
 		{-1, 0, "negative block size not allowed"},
+		{4001, 0, "block size cannot be greater than 4000MiB"},
 		{0.333, 0, "while fractional numbers of MiB are allowed as the block size, the fraction must result to a whole number of bytes. 0.333000000000 MiB resolves to 349175.808 bytes"},
 	}
 
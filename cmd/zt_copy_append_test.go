@@ -0,0 +1,47 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyAppendSuite struct{}
+
+var _ = chk.Suite(&copyAppendSuite{})
+
+func (s *copyAppendSuite) TestValidateAppendToBlob(c *chk.C) {
+	appendBlob := common.EBlobType.AppendBlob()
+	overwrite := common.EOverwriteOption.True()
+
+	c.Assert(validateAppendToBlob(false, common.EFromTo.BlobBlob(), common.EBlobType.Detect(), common.EOverwriteOption.False(), true), chk.IsNil)
+	c.Assert(validateAppendToBlob(true, common.EFromTo.LocalBlob(), appendBlob, overwrite, false), chk.IsNil)
+
+	err := validateAppendToBlob(true, common.EFromTo.BlobBlob(), appendBlob, overwrite, false)
+	c.Assert(err, chk.ErrorMatches, "append is only supported when uploading from the local file system to Blob Storage")
+	err = validateAppendToBlob(true, common.EFromTo.LocalBlob(), common.EBlobType.Detect(), overwrite, false)
+	c.Assert(err, chk.ErrorMatches, "append only adds to append blobs.*")
+	err = validateAppendToBlob(true, common.EFromTo.LocalBlob(), appendBlob, common.EOverwriteOption.IfSourceNewer(), false)
+	c.Assert(err, chk.ErrorMatches, "append adds to the blobs that exist already.*")
+	err = validateAppendToBlob(true, common.EFromTo.LocalBlob(), appendBlob, overwrite, true)
+	c.Assert(err, chk.ErrorMatches, "append can't be used with compress.*")
+}
@@ -0,0 +1,57 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyBlobHashesSuite struct{}
+
+var _ = chk.Suite(&copyBlobHashesSuite{})
+
+func (s *copyBlobHashesSuite) TestBlobHashOptionsOnlyApplyToBlobStorage(c *chk.C) {
+	noCheck, failIfDifferent := common.EHashValidationOption.NoCheck(), common.EHashValidationOption.FailIfDifferent()
+
+	for _, hashName := range []string{"crc64", "sha256"} {
+		for _, t := range []struct {
+			put    bool
+			option common.HashValidationOption
+			fromTo common.FromTo
+			err    string
+		}{
+			{true, noCheck, common.EFromTo.LocalBlob(), ""},
+			{false, failIfDifferent, common.EFromTo.BlobLocal(), ""},
+			{false, noCheck, common.EFromTo.LocalFile(), ""},
+			{true, noCheck, common.EFromTo.LocalFile(), "put-" + hashName + " is only supported when uploading to Blob Storage"},
+			{true, noCheck, common.EFromTo.BlobBlob(), "put-" + hashName + " .*"},
+			{false, failIfDifferent, common.EFromTo.FileLocal(), "check-" + hashName + " is only supported when downloading from Blob Storage"},
+		} {
+			err := validateBlobHashOptions(hashName, t.put, t.option, t.fromTo)
+			if t.err == "" {
+				c.Check(err, chk.IsNil, chk.Commentf("%s %v", hashName, t))
+			} else {
+				c.Check(err, chk.ErrorMatches, t.err, chk.Commentf("%s %v", hashName, t))
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyBlobTagsSuite struct{}
+
+var _ = chk.Suite(&copyBlobTagsSuite{})
+
+func (s *copyBlobTagsSuite) TestParseBlobTags(c *chk.C) {
+	tags, err := parseBlobTags("project=alpha;env=prod", common.EFromTo.LocalBlob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(tags, chk.DeepEquals, common.BlobTags{"project": "alpha", "env": "prod"})
+
+	_, err = parseBlobTags("project=alpha", common.EFromTo.LocalFile())
+	c.Assert(err, chk.NotNil)
+	_, err = parseBlobTags("project", common.EFromTo.LocalBlob())
+	c.Assert(err, chk.NotNil)
+	_, err = parseBlobTags("project=a&b", common.EFromTo.BlobBlob())
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,39 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyCompressSuite struct{}
+
+var _ = chk.Suite(&copyCompressSuite{})
+
+func (s *copyCompressSuite) TestValidateCompress(c *chk.C) {
+	c.Assert(validateCompress(false, common.EFromTo.BlobLocal(), "", common.EBlobType.Detect()), chk.IsNil)
+	c.Assert(validateCompress(true, common.EFromTo.LocalBlob(), "", common.EBlobType.Detect()), chk.IsNil)
+	c.Assert(validateCompress(true, common.EFromTo.LocalFile(), "", common.EBlobType.Detect()), chk.IsNil)
+	c.Assert(validateCompress(true, common.EFromTo.BlobBlob(), "", common.EBlobType.Detect()), chk.ErrorMatches, "compress is only supported when uploading.*")
+	c.Assert(validateCompress(true, common.EFromTo.LocalBlob(), "br", common.EBlobType.Detect()), chk.ErrorMatches, "compress can't be used with content-encoding.*")
+	c.Assert(validateCompress(true, common.EFromTo.LocalBlob(), "", common.EBlobType.PageBlob()), chk.ErrorMatches, "compress can't be used to upload page blobs")
+}
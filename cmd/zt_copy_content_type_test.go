@@ -0,0 +1,63 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyContentTypeSuite struct{}
+
+var _ = chk.Suite(&copyContentTypeSuite{})
+
+func (s *copyContentTypeSuite) TestLoadContentTypeMap(c *chk.C) {
+	dir, err := ioutil.TempDir("", "contenttypemap")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "types.json")
+	c.Assert(ioutil.WriteFile(path, []byte(`{".MD": "text/markdown", "webmanifest": "application/manifest+json"}`), 0644), chk.IsNil)
+	contentTypeMap, err := loadContentTypeMap(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(contentTypeMap, chk.DeepEquals, common.ContentTypeMap{".md": "text/markdown", ".webmanifest": "application/manifest+json"})
+
+	c.Assert(ioutil.WriteFile(path, []byte(`{".md": "not a type"}`), 0644), chk.IsNil)
+	_, err = loadContentTypeMap(path)
+	c.Assert(err, chk.ErrorMatches, ".*isn't a valid content type.*")
+
+	c.Assert(ioutil.WriteFile(path, []byte(`[".md"]`), 0644), chk.IsNil)
+	_, err = loadContentTypeMap(path)
+	c.Assert(err, chk.ErrorMatches, "the content-type-map file must hold a JSON object.*")
+}
+
+func (s *copyContentTypeSuite) TestValidateContentTypeDetection(c *chk.C) {
+	c.Assert(validateContentTypeDetection(false, false, true, common.EFromTo.BlobBlob()), chk.IsNil)
+	c.Assert(validateContentTypeDetection(true, true, false, common.EFromTo.LocalBlob()), chk.IsNil)
+	c.Assert(validateContentTypeDetection(true, false, false, common.EFromTo.SFTPBlob()), chk.IsNil)
+	c.Assert(validateContentTypeDetection(false, true, false, common.EFromTo.SFTPBlob()), chk.NotNil)
+	c.Assert(validateContentTypeDetection(true, false, false, common.EFromTo.BlobBlob()), chk.NotNil)
+	c.Assert(validateContentTypeDetection(true, false, true, common.EFromTo.LocalBlob()), chk.NotNil)
+}
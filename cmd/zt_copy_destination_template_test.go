@@ -0,0 +1,55 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyDestinationTemplateSuite struct{}
+
+var _ = chk.Suite(&copyDestinationTemplateSuite{})
+
+func (s *copyDestinationTemplateSuite) TestExpandDestinationTemplate(c *chk.C) {
+	jobID := common.NewJobID()
+	start := time.Date(2021, time.March, 4, 5, 6, 7, 0, time.Local)
+
+	c.Assert(expandDestinationTemplate("https://account.blob.core.windows.net/container/backups/{yyyy}/{MM}/{dd}?sv=x", jobID, start),
+		chk.Equals, "https://account.blob.core.windows.net/container/backups/2021/03/04?sv=x")
+	c.Assert(expandDestinationTemplate("/backups/{yyyy}{MM}{dd}-{HH}{mm}{ss}/{jobid}", jobID, start),
+		chk.Equals, "/backups/20210304-050607/"+jobID.String())
+
+	// anything else is left alone
+	c.Assert(expandDestinationTemplate("/backups/{other}/{YYYY}", jobID, start), chk.Equals, "/backups/{other}/{YYYY}")
+	c.Assert(expandDestinationTemplate("/backups", jobID, start), chk.Equals, "/backups")
+}
+
+func (s *copyDestinationTemplateSuite) TestDestinationIsExpandedWhenCooked(c *chk.C) {
+	raw := getDefaultRawCopyInput("/src/dir", "https://account.blob.core.windows.net/container/{jobid}")
+	jobID := common.NewJobID()
+
+	cooked, err := raw.cookWithId(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.destination.Value, chk.Equals, "https://account.blob.core.windows.net/container/"+jobID.String())
+}
@@ -0,0 +1,96 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyDuplicatesSuite struct{}
+
+var _ = chk.Suite(&copyDuplicatesSuite{})
+
+func duplicateTestTransfers() []common.CopyTransfer {
+	file := common.EEntityType.File()
+	return []common.CopyTransfer{
+		{Source: "/a/x.txt", Destination: "/x.txt", EntityType: file},
+		{Source: "/b/y.txt", Destination: "/y.txt", EntityType: file},
+		{Source: "/b/x.txt", Destination: "/x.txt", EntityType: file},
+		{Source: "/c/X.TXT", Destination: "/X.TXT", EntityType: file},
+	}
+}
+
+func runDuplicateDetector(d *duplicateDestinationDetector) (scheduled []string, err error) {
+	schedule := func(t common.CopyTransfer) error {
+		scheduled = append(scheduled, t.Source)
+		return nil
+	}
+	for _, t := range duplicateTestTransfers() {
+		if err = d.add(t, schedule); err != nil {
+			return scheduled, err
+		}
+	}
+	err = d.flush(schedule)
+	return scheduled, err
+}
+
+func (s *copyDuplicatesSuite) TestDuplicateDestinationSkip(c *chk.C) {
+	scheduled, err := runDuplicateDetector(newDuplicateDestinationDetector(common.EDuplicateDestinationPolicy.Skip(), false, true))
+	c.Assert(err, chk.IsNil)
+	c.Assert(scheduled, chk.DeepEquals, []string{"/a/x.txt", "/b/y.txt", "/c/X.TXT"})
+}
+
+func (s *copyDuplicatesSuite) TestDuplicateDestinationSkipCaseInsensitive(c *chk.C) {
+	scheduled, err := runDuplicateDetector(newDuplicateDestinationDetector(common.EDuplicateDestinationPolicy.Skip(), true, true))
+	c.Assert(err, chk.IsNil)
+	c.Assert(scheduled, chk.DeepEquals, []string{"/a/x.txt", "/b/y.txt"})
+}
+
+func (s *copyDuplicatesSuite) TestDuplicateDestinationFail(c *chk.C) {
+	scheduled, err := runDuplicateDetector(newDuplicateDestinationDetector(common.EDuplicateDestinationPolicy.Fail(), false, true))
+	c.Assert(err, chk.ErrorMatches, "both /a/x.txt and /b/x.txt would be copied to /x.txt.*")
+	c.Assert(scheduled, chk.DeepEquals, []string{"/a/x.txt", "/b/y.txt"})
+}
+
+func (s *copyDuplicatesSuite) TestDuplicateDestinationLastWins(c *chk.C) {
+	scheduled, err := runDuplicateDetector(newDuplicateDestinationDetector(common.EDuplicateDestinationPolicy.LastWins(), true, true))
+	c.Assert(err, chk.IsNil)
+	c.Assert(scheduled, chk.DeepEquals, []string{"/c/X.TXT", "/b/y.txt"}) // each destination keeps its place in the order
+}
+
+func (s *copyDuplicatesSuite) TestNothingIsTrackedWhenDuplicatesAreImpossible(c *chk.C) {
+	d := newDuplicateDestinationDetector(common.EDuplicateDestinationPolicy.Skip(), false, false)
+	scheduled, err := runDuplicateDetector(d)
+	c.Assert(err, chk.IsNil)
+	c.Assert(scheduled, chk.HasLen, len(duplicateTestTransfers())) // a listing couldn't really yield these
+	c.Assert(d.sourcesByDestination, chk.HasLen, 0)
+}
+
+func (s *copyDuplicatesSuite) TestDuplicateDestinationIgnoresFolders(c *chk.C) {
+	d := newDuplicateDestinationDetector(common.EDuplicateDestinationPolicy.Fail(), false, true)
+	folder := common.CopyTransfer{Source: "/a", Destination: "/a", EntityType: common.EEntityType.Folder()}
+	count := 0
+	schedule := func(common.CopyTransfer) error { count++; return nil }
+
+	c.Assert(d.add(folder, schedule), chk.IsNil)
+	c.Assert(d.add(folder, schedule), chk.IsNil)
+	c.Assert(count, chk.Equals, 2)
+}
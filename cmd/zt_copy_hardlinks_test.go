@@ -0,0 +1,136 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyHardlinksSuite struct{}
+
+var _ = chk.Suite(&copyHardlinksSuite{})
+
+func (s *copyHardlinksSuite) TestRelativeLinkTarget(c *chk.C) {
+	c.Assert(relativeLinkTarget(".", "a/f"), chk.Equals, "a/f")
+	c.Assert(relativeLinkTarget("a", "a/f"), chk.Equals, "f")
+	c.Assert(relativeLinkTarget("a", "f"), chk.Equals, "../f")
+	c.Assert(relativeLinkTarget("a/b", "a/c/f"), chk.Equals, "../c/f")
+	c.Assert(relativeLinkTarget("a/b", "a/b/c/f"), chk.Equals, "c/f")
+}
+
+func (s *copyHardlinksSuite) TestHardlinksRoundTrip(c *chk.C) {
+	if runtime.GOOS == "windows" {
+		c.Skip("hard links aren't detected on Windows")
+	}
+	src, err := ioutil.TempDir("", "hardlinktest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(src)
+
+	c.Assert(os.MkdirAll(filepath.Join(src, "a", "b"), 0700), chk.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(src, "a", "f"), []byte("content"), 0600), chk.IsNil)
+	c.Assert(os.Link(filepath.Join(src, "a", "f"), filepath.Join(src, "a", "b", "link")), chk.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(src, "single"), []byte("content"), 0600), chk.IsNil)
+
+	// on upload, the first link carries the content, and the second points at it
+	uploader := newHardlinkPreserver()
+	objects := []storedObject{
+		{relativePath: "a/f", entityType: common.EEntityType.File(), size: 7},
+		{relativePath: "a/b/link", entityType: common.EEntityType.File(), size: 7},
+		{relativePath: "single", entityType: common.EEntityType.File(), size: 7},
+	}
+	for i := range objects {
+		uploader.tagUploadedLink(&objects[i], src)
+	}
+	c.Assert(objects[0].size, chk.Equals, int64(7))
+	c.Assert(objects[0].Metadata, chk.IsNil)
+	c.Assert(objects[1].size, chk.Equals, int64(0))
+	c.Assert(objects[1].Metadata, chk.DeepEquals, common.Metadata{common.HardlinkMetadataKey: "..%2Ff"})
+	c.Assert(objects[2].Metadata, chk.IsNil)
+
+	// on download, the link is left out, and made once the file with the content is there
+	dst, err := ioutil.TempDir("", "hardlinktest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dst)
+
+	downloader := newHardlinkPreserver()
+	c.Assert(downloader.recordDownloadedLink(objects[0], dst, "a/f"), chk.Equals, false)
+	c.Assert(downloader.recordDownloadedLink(objects[1], dst, "a/b/link"), chk.Equals, true)
+
+	c.Assert(os.MkdirAll(filepath.Join(dst, "a"), 0700), chk.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dst, "a", "f"), []byte("content"), 0600), chk.IsNil)
+	c.Assert(downloader.createLinks(common.EOverwriteOption.True()), chk.Equals, 0)
+
+	fileInfo, err := os.Stat(filepath.Join(dst, "a", "f"))
+	c.Assert(err, chk.IsNil)
+	linkInfo, err := os.Stat(filepath.Join(dst, "a", "b", "link"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(os.SameFile(fileInfo, linkInfo), chk.Equals, true)
+}
+
+func (s *copyHardlinksSuite) TestLinkTargetsOutsideTheDestinationAreRejected(c *chk.C) {
+	resolved, ok := resolveLinkTarget("a/b/link", "../f")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(resolved, chk.Equals, filepath.Join("a", "f"))
+
+	for _, target := range []string{"../../../etc/passwd", "../../..", "/etc/passwd", "", "../.."} {
+		_, ok = resolveLinkTarget("a/b/link", target)
+		c.Check(ok, chk.Equals, false, chk.Commentf(target))
+	}
+
+	// such a link isn't downloaded, nor made, and counts as failed. The file that's there already is left alone
+	dst, err := ioutil.TempDir("", "hardlinktest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dst)
+	c.Assert(ioutil.WriteFile(filepath.Join(dst, "existing"), []byte("mine"), 0600), chk.IsNil)
+
+	downloader := newHardlinkPreserver()
+	evil := storedObject{relativePath: "existing", entityType: common.EEntityType.File(),
+		Metadata: common.Metadata{common.HardlinkMetadataKey: "..%2F..%2F..%2F..%2Fetc%2Fpasswd"}}
+	c.Assert(downloader.recordDownloadedLink(evil, dst, "existing"), chk.Equals, true)
+	c.Assert(downloader.linksToCreate, chk.HasLen, 0)
+	c.Assert(downloader.createLinks(common.EOverwriteOption.True()), chk.Equals, 1)
+
+	content, err := ioutil.ReadFile(filepath.Join(dst, "existing"))
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(content), chk.Equals, "mine")
+}
+
+func (s *copyHardlinksSuite) TestValidatePreserveHardlinks(c *chk.C) {
+	c.Assert(validatePreserveHardlinks(false, common.EFromTo.LocalFile()), chk.IsNil)
+	c.Assert(validatePreserveHardlinks(true, common.EFromTo.BlobLocal()), chk.IsNil)
+	c.Assert(validatePreserveHardlinks(true, common.EFromTo.LocalFile()), chk.NotNil)
+}
+
+func (s *copyHardlinksSuite) TestValidatePreservePOSIXPermissions(c *chk.C) {
+	c.Assert(validatePreservePOSIXPermissions(false, common.EFromTo.LocalBlob()), chk.IsNil)
+	c.Assert(validatePreservePOSIXPermissions(true, common.EFromTo.LocalBlob()), chk.NotNil)
+	c.Assert(validatePreservePOSIXPermissions(true, common.EFromTo.BlobFSTrash()), chk.NotNil)
+	if runtime.GOOS != "windows" {
+		c.Assert(validatePreservePOSIXPermissions(true, common.EFromTo.LocalBlobFS()), chk.IsNil)
+		c.Assert(validatePreservePOSIXPermissions(true, common.EFromTo.BlobFSLocal()), chk.IsNil)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyHeaderRulesSuite struct{}
+
+var _ = chk.Suite(&copyHeaderRulesSuite{})
+
+func (s *copyHeaderRulesSuite) TestLoadHeaderRules(c *chk.C) {
+	dir, err := ioutil.TempDir("", "headerrules")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.json")
+	c.Assert(ioutil.WriteFile(path, []byte(`[{"pattern": "*.html", "cacheControl": "no-cache"}, {"pattern": "assets/*", "cacheControl": "max-age=31536000"}]`), 0644), chk.IsNil)
+	rules, err := loadHeaderRules(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(rules, chk.DeepEquals, common.HeaderRules{
+		{Pattern: "*.html", CacheControl: "no-cache"},
+		{Pattern: "assets/*", CacheControl: "max-age=31536000"},
+	})
+
+	c.Assert(ioutil.WriteFile(path, []byte(`[{"pattern": "*.md", "contentType": "text/markdown"}]`), 0644), chk.IsNil)
+	_, err = loadHeaderRules(path)
+	c.Assert(err, chk.ErrorMatches, "the header-rules file must hold a JSON array.*unknown field.*")
+
+	c.Assert(ioutil.WriteFile(path, []byte(`[{"pattern": "[", "cacheControl": "no-cache"}]`), 0644), chk.IsNil)
+	_, err = loadHeaderRules(path)
+	c.Assert(err, chk.ErrorMatches, "rule 1 of the header-rules file has an invalid pattern.*")
+
+	c.Assert(ioutil.WriteFile(path, []byte(`[{"pattern": "*.css"}]`), 0644), chk.IsNil)
+	_, err = loadHeaderRules(path)
+	c.Assert(err, chk.ErrorMatches, ".*doesn't set any header")
+}
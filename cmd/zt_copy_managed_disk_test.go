@@ -0,0 +1,47 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyManagedDiskSuite struct{}
+
+var _ = chk.Suite(&copyManagedDiskSuite{})
+
+func (s *copyManagedDiskSuite) TestValidateManagedDiskDestination(c *chk.C) {
+	disk := "https://md-impexp-t0abc.z1.blob.storage.azure.net/xyz/abcd"
+	overwrite := common.EOverwriteOption.True()
+
+	// ordinary blobs, and disks with the options they support, are fine
+	c.Assert(validateManagedDiskDestination("https://account.blob.core.windows.net/c/disk.vhd", common.EFromTo.LocalBlob(), common.EBlobType.BlockBlob(), common.EOverwriteOption.False(), true), chk.IsNil)
+	c.Assert(validateManagedDiskDestination(disk, common.EFromTo.LocalBlob(), common.EBlobType.Detect(), overwrite, false), chk.IsNil)
+	c.Assert(validateManagedDiskDestination(disk, common.EFromTo.BlobBlob(), common.EBlobType.PageBlob(), overwrite, false), chk.IsNil)
+
+	err := validateManagedDiskDestination(disk, common.EFromTo.LocalBlob(), common.EBlobType.BlockBlob(), overwrite, false)
+	c.Assert(err, chk.ErrorMatches, "managed disks can only be page blobs.*")
+	err = validateManagedDiskDestination(disk, common.EFromTo.LocalBlob(), common.EBlobType.Detect(), common.EOverwriteOption.False(), false)
+	c.Assert(err, chk.ErrorMatches, "the page blob of a managed disk always exists.*")
+	err = validateManagedDiskDestination(disk, common.EFromTo.LocalBlob(), common.EBlobType.Detect(), overwrite, true)
+	c.Assert(err, chk.ErrorMatches, "put-md5 can't be used when copying to a managed disk.*")
+}
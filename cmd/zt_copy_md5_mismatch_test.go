@@ -0,0 +1,51 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type md5MismatchSummarySuite struct{}
+
+var _ = chk.Suite(&md5MismatchSummarySuite{})
+
+func (s *md5MismatchSummarySuite) TestMismatchesAreListedInSummary(c *chk.C) {
+	c.Assert(formatMd5Mismatches(nil), chk.Equals, "")
+
+	text := formatMd5Mismatches([]common.TransferDetail{
+		{Dst: "/d/a", TransferStatus: common.ETransferStatus.Success()},
+		{Dst: "/d/b", TransferStatus: common.ETransferStatus.Failed()},
+	})
+	c.Assert(text, chk.Equals, "\nNumber of Files with MD5 Mismatches: 2\n  /d/a (kept)\n  /d/b (failed)")
+
+	many := make([]common.TransferDetail, maxMd5MismatchesListed+3)
+	for i := range many {
+		many[i] = common.TransferDetail{Dst: fmt.Sprintf("/d/%d", i), TransferStatus: common.ETransferStatus.Failed()}
+	}
+	text = formatMd5Mismatches(many)
+	c.Assert(strings.Count(text, " (failed)"), chk.Equals, maxMd5MismatchesListed)
+	c.Assert(strings.HasSuffix(text, "...and 3 more. See the log for the others"), chk.Equals, true)
+}
@@ -0,0 +1,165 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyMirrorSuite struct{}
+
+var _ = chk.Suite(&copyMirrorSuite{})
+
+func (s *copyMirrorSuite) TestValidateMirror(c *chk.C) {
+	valid := func() *cookedCopyCmdArgs {
+		return &cookedCopyCmdArgs{
+			mirror:      true,
+			fromTo:      common.EFromTo.LocalBlob(),
+			source:      common.ResourceString{Value: "/data/dir"},
+			destination: common.ResourceString{Value: "https://account.blob.core.windows.net/container"},
+			recursive:   true,
+			forceWrite:  common.EOverwriteOption.True(),
+		}
+	}
+	c.Assert(valid().validateMirror(false), chk.IsNil)
+
+	cca := valid()
+	cca.source.Value = "/data/dir/*"
+	c.Assert(cca.validateMirror(false), chk.IsNil)
+	cca.source.Value = "/data/dir/*.txt"
+	c.Assert(cca.validateMirror(false), chk.ErrorMatches, "mirror can't be used with wildcards in the source.*")
+
+	cca = valid()
+	cca.fromTo = common.EFromTo.LocalBlobFS()
+	c.Assert(cca.validateMirror(false), chk.ErrorMatches, "mirror is only supported when the destination is the local file system, Blob Storage or Azure Files")
+
+	cca = valid()
+	cca.recursive = false
+	c.Assert(cca.validateMirror(false), chk.ErrorMatches, ".*must be used with --recursive")
+
+	cca = valid()
+	cca.skipUnchanged = true
+	c.Assert(cca.validateMirror(false), chk.ErrorMatches, "mirror and skip-unchanged can't be used together.*")
+
+	cca = valid()
+	cca.forceWrite = common.EOverwriteOption.IfSourceNewer()
+	c.Assert(cca.validateMirror(false), chk.ErrorMatches, ".*can't be used with any overwrite setting other than true")
+
+	c.Assert(valid().validateMirror(true), chk.ErrorMatches, "mirror can't be used with list-of-files or include-path.*")
+
+	cca = valid()
+	after := time.Now()
+	cca.includeAfter = &after
+	c.Assert(cca.validateMirror(false), chk.ErrorMatches, "mirror can't be used with include-after or include-before.*")
+
+	cca = valid()
+	cca.pack = common.EPackFormat.Tar()
+	c.Assert(cca.validateMirror(false), chk.ErrorMatches, "mirror can't be used with pack or append.*")
+}
+
+func (s *copyMirrorSuite) TestCookMirror(c *chk.C) {
+	raw := getDefaultCopyRawInput(os.TempDir(), "https://account.blob.core.windows.net/container?sv=1&sig=2")
+	raw.recursive = true
+	raw.mirror = true
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.mirror, chk.Equals, true)
+
+	raw.forceWrite = common.EOverwriteOption.False().String()
+	_, err = raw.cook()
+	c.Assert(err, chk.ErrorMatches, "mirror replaces every destination file that differs from the source.*")
+}
+
+func (s *copyMirrorSuite) TestMirrorSkipsTheFilesThatAreTheSame(c *chk.C) {
+	then, now := time.Now().Add(-time.Hour), time.Now()
+	indexer := newObjectIndexer()
+	for _, name := range []string{"dir/same.txt", "dir/resized.txt", "dir/extra.txt"} {
+		c.Assert(indexer.store(twoWayTestFile(name, 10, now)), chk.IsNil)
+	}
+	skipper := &unchangedFileSkipper{destinationIndex: indexer, mirror: newMirrorComparer(common.EFromTo.LocalBlob(), common.ESyncConflictResolution.SourceWins(), nil)}
+
+	c.Assert(skipper.skipIfUnchanged(twoWayTestFile("same.txt", 10, then), "dir/same.txt"), chk.Equals, true)
+	c.Assert(skipper.skipIfUnchanged(twoWayTestFile("resized.txt", 11, now.Add(time.Minute)), "dir/resized.txt"), chk.Equals, false)
+	c.Assert(skipper.skipIfUnchanged(twoWayTestFile("new.txt", 10, then), "dir/new.txt"), chk.Equals, false)
+	c.Assert(skipper.skippedCount(), chk.Equals, uint32(1))
+
+	// what's left is the files no source file was copied to
+	c.Assert(indexer.indexMap, chk.HasLen, 1)
+	c.Assert(indexer.indexMap["dir/extra.txt"].relativePath, chk.Equals, "dir/extra.txt")
+	c.Assert(skipper.mirror.report(1), chk.Equals, "Mirror report: 1 files were the same at both ends, 1 were missing from the destination, and 1 differed (1 in size). "+
+		"1 extra files were at the destination.")
+}
+
+func (s *copyMirrorSuite) TestMirrorRoot(c *chk.C) {
+	cca := cookedCopyCmdArgs{fromTo: common.EFromTo.BlobBlob(), source: common.ResourceString{Value: "https://account.blob.core.windows.net/container/dir"}}
+	c.Assert(cca.mirrorRoot(), chk.Equals, "dir/")
+
+	cca.stripTopDir = true
+	c.Assert(cca.mirrorRoot(), chk.Equals, "")
+}
+
+func (s *copyMirrorSuite) TestOnlyTheExtraFilesUnderTheRootAreDeleted(c *chk.C) {
+	dir, err := ioutil.TempDir("", "copymirror")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	indexer := newObjectIndexer()
+	for _, name := range []string{"dir/extra.txt", "dir/sub/extra.txt", "dir/excluded.log", "other/outside.txt"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		c.Assert(os.MkdirAll(filepath.Dir(path), os.ModePerm), chk.IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte("x"), 0644), chk.IsNil)
+		file := twoWayTestFile(name, 1, time.Now())
+		file.name = filepath.Base(path)
+		c.Assert(indexer.store(file), chk.IsNil)
+	}
+	c.Assert(indexer.store(storedObject{name: "dir", relativePath: "dir", entityType: common.EEntityType.Folder()}), chk.IsNil)
+
+	cca := &cookedCopyCmdArgs{
+		fromTo:      common.EFromTo.BlobLocal(),
+		source:      common.ResourceString{Value: "https://account.blob.core.windows.net/container/dir"},
+		destination: common.ResourceString{Value: dir},
+		unchangedFileSkipper: &unchangedFileSkipper{
+			destinationIndex: indexer,
+			mirror:           newMirrorComparer(common.EFromTo.BlobLocal(), common.ESyncConflictResolution.SourceWins(), nil),
+		},
+	}
+	// the files the filters leave out of the copy are kept, as are the ones outside the directory the source is copied to
+	filters := []objectFilter{&excludeFilter{pattern: "*.log"}}
+
+	printer, printed := newRecordingDryRunPrinter()
+	cca.dryRunPrinter = printer
+	c.Assert(cca.deleteExtraFiles(context.Background(), filters), chk.IsNil)
+	c.Assert(*printed, chk.DeepEquals, []string{"Would delete extra file dir/extra.txt", "Would delete extra file dir/sub/extra.txt"})
+
+	cca.dryRunPrinter = nil
+	c.Assert(cca.deleteExtraFiles(context.Background(), filters), chk.IsNil)
+	for name, exists := range map[string]bool{"dir/extra.txt": false, "dir/sub/extra.txt": false, "dir/excluded.log": true, "other/outside.txt": true} {
+		_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		c.Assert(err == nil, chk.Equals, exists, chk.Commentf(name))
+	}
+}
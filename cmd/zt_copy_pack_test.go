@@ -0,0 +1,108 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyPackSuite struct{}
+
+var _ = chk.Suite(&copyPackSuite{})
+
+func (s *copyPackSuite) TestEachDirectoryIsUploadedAsOneArchive(c *chk.C) {
+	src, err := ioutil.TempDir("", "packtest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(src)
+	c.Assert(os.MkdirAll(filepath.Join(src, "a", "b"), 0700), chk.IsNil)
+
+	cca := cookedCopyCmdArgs{
+		source:      common.ResourceString{Value: src},
+		destination: common.ResourceString{Value: "https://account.blob.core.windows.net/container"},
+		fromTo:      common.EFromTo.LocalBlob(),
+		stripTopDir: true,
+	}
+	packer := newDirectoryPacker(common.EPackFormat.TarGz())
+	for _, object := range []storedObject{
+		{name: "x", relativePath: "x", entityType: common.EEntityType.File(), size: 1},
+		{name: "y", relativePath: "a/b/y", entityType: common.EEntityType.File(), size: 2},
+		{name: "z", relativePath: "a/b/z", entityType: common.EEntityType.File(), size: 3},
+		{name: "b", relativePath: "a/b", entityType: common.EEntityType.Folder()},
+	} {
+		c.Assert(packer.add(object), chk.IsNil)
+	}
+
+	var transfers []common.CopyTransfer
+	c.Assert(packer.schedule(&cca, true, func(t common.CopyTransfer) error {
+		transfers = append(transfers, t)
+		return nil
+	}), chk.IsNil)
+
+	c.Assert(transfers, chk.HasLen, 2)
+	c.Assert(transfers[0].Source, chk.Equals, "")
+	c.Assert(transfers[0].Destination, chk.Equals, "/azcopy-packed.tar.gz")
+	c.Assert(transfers[0].SourceSize, chk.Equals, int64(1))
+	c.Assert(transfers[1].Source, chk.Equals, "/a/b")
+	c.Assert(transfers[1].Destination, chk.Equals, "/a/b/azcopy-packed.tar.gz")
+	c.Assert(transfers[1].SourceSize, chk.Equals, int64(5))
+	c.Assert(transfers[1].EntityType, chk.Equals, common.EEntityType.File())
+	c.Assert(transfers[1].LastModifiedTime.IsZero(), chk.Equals, false)
+
+	// a single file has no directory to pack
+	c.Assert(packer.add(storedObject{name: "x", entityType: common.EEntityType.File()}), chk.ErrorMatches, "pack is only supported when the source is a directory")
+}
+
+func (s *copyPackSuite) TestValidatePack(c *chk.C) {
+	none, tar := common.EPackFormat.None(), common.EPackFormat.Tar()
+	skip := common.ESymlinkHandlingType.Skip()
+	detect := common.EBlobType.Detect()
+
+	c.Assert(validatePack(none, common.EFromTo.BlobLocal(), common.EBlobType.PageBlob(), true, true, common.ESymlinkHandlingType.Follow(), true), chk.IsNil)
+	c.Assert(validatePack(tar, common.EFromTo.LocalBlob(), detect, false, false, skip, false), chk.IsNil)
+	c.Assert(validatePack(tar, common.EFromTo.LocalBlob(), common.EBlobType.BlockBlob(), false, false, skip, false), chk.IsNil)
+
+	c.Assert(validatePack(tar, common.EFromTo.LocalFile(), detect, false, false, skip, false), chk.ErrorMatches, "pack is only supported when uploading.*")
+	c.Assert(validatePack(tar, common.EFromTo.LocalBlob(), common.EBlobType.PageBlob(), false, false, skip, false), chk.ErrorMatches, "pack uploads archives as block blobs.*")
+	c.Assert(validatePack(tar, common.EFromTo.LocalBlob(), detect, true, false, skip, false), chk.ErrorMatches, "pack can't be used with compress.*")
+	c.Assert(validatePack(tar, common.EFromTo.LocalBlob(), detect, false, true, skip, false), chk.ErrorMatches, "pack can't be used with filters.*")
+	c.Assert(validatePack(tar, common.EFromTo.LocalBlob(), detect, false, false, common.ESymlinkHandlingType.Follow(), false), chk.ErrorMatches, "pack only packs regular files.*")
+	c.Assert(validatePack(tar, common.EFromTo.LocalBlob(), detect, false, false, skip, true), chk.ErrorMatches, "pack only packs regular files.*")
+}
+
+func (s *copyPackSuite) TestValidateUnpack(c *chk.C) {
+	c.Assert(validateUnpack(false, common.EFromTo.LocalBlob()), chk.IsNil)
+	c.Assert(validateUnpack(true, common.EFromTo.BlobLocal()), chk.IsNil)
+	c.Assert(validateUnpack(true, common.EFromTo.FileLocal()), chk.ErrorMatches, "unpack is only supported when downloading.*")
+}
+
+func (s *copyPackSuite) TestParsePackFormat(c *chk.C) {
+	var p common.PackFormat
+	c.Assert(p.Parse("tarGz"), chk.IsNil)
+	c.Assert(p, chk.Equals, common.EPackFormat.TarGz())
+	c.Assert(p.Parse(""), chk.IsNil)
+	c.Assert(p, chk.Equals, common.EPackFormat.None())
+	c.Assert(p.Parse("zip"), chk.NotNil)
+}
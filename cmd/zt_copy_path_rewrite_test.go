@@ -0,0 +1,116 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyPathRewriteSuite struct{}
+
+var _ = chk.Suite(&copyPathRewriteSuite{})
+
+func (s *copyPathRewriteSuite) TestParsePathRewriteRules(c *chk.C) {
+	r, err := parsePathRewriteRules(nil)
+	c.Assert(err, chk.IsNil)
+	c.Assert(r, chk.HasLen, 0)
+
+	_, err = parsePathRewriteRules([]string{"no separator"})
+	c.Assert(err, chk.ErrorMatches, "invalid path-rewrite rule 'no separator'.*")
+	_, err = parsePathRewriteRules([]string{"=>x"})
+	c.Assert(err, chk.ErrorMatches, "invalid path-rewrite rule '=>x'.*")
+	_, err = parsePathRewriteRules([]string{"(=>x"})
+	c.Assert(err, chk.ErrorMatches, "invalid regex in path-rewrite rule.*")
+}
+
+func (s *copyPathRewriteSuite) TestRewrite(c *chk.C) {
+	flatten, err := parsePathRewriteRules([]string{"^.*/=>"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(flatten.rewrite("a/b/c.txt"), chk.Equals, "c.txt")
+	c.Assert(flatten.rewrite("c.txt"), chk.Equals, "c.txt")
+
+	// rules are applied in order, and may refer to groups
+	r, err := parsePathRewriteRules([]string{"^logs/=>", `^(\d{4})-(\d{2})/=>$1/$2/`, `\.csv$=>.old.csv`})
+	c.Assert(err, chk.IsNil)
+	c.Assert(r.rewrite("logs/2020-06/x.csv"), chk.Equals, "2020/06/x.old.csv")
+	c.Assert(r.rewrite("other/x.txt"), chk.Equals, "other/x.txt")
+
+	// the result is always relative
+	r, err = parsePathRewriteRules([]string{"^=>/"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(r.rewrite("x"), chk.Equals, "x")
+
+	// and never leads out of the destination
+	r, err = parsePathRewriteRules([]string{"^=>../../"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(r.rewrite("x"), chk.Equals, "")
+	r, err = parsePathRewriteRules([]string{`^a/=>a/../..\\`})
+	c.Assert(err, chk.IsNil)
+	c.Assert(r.rewrite("a/x"), chk.Equals, "")
+	r, err = parsePathRewriteRules([]string{"^=>C:/"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(r.rewrite("x"), chk.Equals, "")
+	r, err = parsePathRewriteRules([]string{"^a/=>a/./b/../"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(r.rewrite("a/x"), chk.Equals, "a/x")
+
+	var none pathRewriter
+	c.Assert(none.rewrite("/a/b"), chk.Equals, "/a/b")
+}
+
+func (s *copyPathRewriteSuite) TestPathRewriteCantBeUsedWithHardlinks(c *chk.C) {
+	raw := getDefaultCopyRawInput("https://account.blob.core.windows.net/container/dir", "/tmp/dst")
+	raw.fromTo = common.EFromTo.BlobLocal().String()
+	raw.recursive = true
+	raw.pathRewrites = "^dir/=>"
+	raw.preserveHardlinks = true
+	_, err := raw.cook()
+	c.Assert(err, chk.Equals, errPathRewriteWithHardlinks)
+
+	raw.preserveHardlinks = false
+	_, err = raw.cook()
+	c.Assert(err, chk.IsNil)
+}
+
+func (s *copyPathRewriteSuite) TestOnlyDestinationPathIsRewritten(c *chk.C) {
+	rewriter, err := parsePathRewriteRules([]string{"^.*/=>"})
+	c.Assert(err, chk.IsNil)
+	cca := cookedCopyCmdArgs{
+		source:       common.ResourceString{Value: "/src/dir"},
+		destination:  common.ResourceString{Value: "https://account.blob.core.windows.net/container"},
+		fromTo:       common.EFromTo.LocalBlob(),
+		stripTopDir:  true,
+		pathRewrites: rewriter,
+	}
+
+	object := storedObject{name: "c.txt", relativePath: "a/b/c.txt", entityType: common.EEntityType.File()}
+	c.Assert(cca.makeEscapedRelativePath(true, true, object), chk.Equals, "/a/b/c.txt")
+	c.Assert(cca.makeEscapedRelativePath(false, true, object), chk.Equals, "/c.txt")
+
+	// a single file is only renamed when it goes into a directory
+	rename, err := parsePathRewriteRules([]string{"^c=>d"})
+	c.Assert(err, chk.IsNil)
+	cca.pathRewrites = rename
+	single := storedObject{name: "c.txt", relativePath: "", entityType: common.EEntityType.File()}
+	c.Assert(cca.makeEscapedRelativePath(false, true, single), chk.Equals, "/d.txt")
+	c.Assert(cca.makeEscapedRelativePath(false, false, single), chk.Equals, "")
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"runtime"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyPreserveInfoSuite struct{}
+
+var _ = chk.Suite(&copyPreserveInfoSuite{})
+
+func (s *copyPreserveInfoSuite) TestPreserveInfoKeepsTimesInBlobMetadata(c *chk.C) {
+	upload := cookedCopyCmdArgs{fromTo: common.EFromTo.LocalBlob()}
+	c.Assert(upload.applyPreserveInfo(true), chk.IsNil)
+	c.Assert(upload.preserveFileTimes, chk.Equals, true)
+	c.Assert(upload.preserveSMBInfo, chk.Equals, false)
+
+	download := cookedCopyCmdArgs{fromTo: common.EFromTo.BlobLocal()}
+	c.Assert(download.applyPreserveInfo(true), chk.IsNil)
+	c.Assert(download.preserveFileTimes, chk.Equals, true)
+	c.Assert(download.preserveLastModifiedTime, chk.Equals, true)
+
+	off := cookedCopyCmdArgs{fromTo: common.EFromTo.BlobLocal()}
+	c.Assert(off.applyPreserveInfo(false), chk.IsNil)
+	c.Assert(off.preserveFileTimes, chk.Equals, false)
+}
+
+func (s *copyPreserveInfoSuite) TestPreserveInfoUsesSMBPropertiesForAzureFiles(c *chk.C) {
+	s2s := cookedCopyCmdArgs{fromTo: common.EFromTo.FileFile()}
+	c.Assert(s2s.applyPreserveInfo(true), chk.IsNil)
+	c.Assert(s2s.preserveSMBInfo, chk.Equals, true)
+	c.Assert(s2s.preserveFileTimes, chk.Equals, false)
+
+	upload := cookedCopyCmdArgs{fromTo: common.EFromTo.LocalFile()}
+	err := upload.applyPreserveInfo(true)
+	if runtime.GOOS == "windows" {
+		c.Assert(err, chk.IsNil)
+		c.Assert(upload.preserveSMBInfo, chk.Equals, true)
+	} else {
+		c.Assert(err, chk.NotNil)
+	}
+}
+
+func (s *copyPreserveInfoSuite) TestPreserveInfoRejectsOtherScenarios(c *chk.C) {
+	cca := cookedCopyCmdArgs{fromTo: common.EFromTo.S3Blob()}
+	c.Assert(cca.applyPreserveInfo(true), chk.NotNil)
+}
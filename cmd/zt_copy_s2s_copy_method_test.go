@@ -0,0 +1,51 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyS2SCopyMethodSuite struct{}
+
+var _ = chk.Suite(&copyS2SCopyMethodSuite{})
+
+func (s *copyS2SCopyMethodSuite) TestValidateS2SCopyMethod(c *chk.C) {
+	detect := common.EBlobType.Detect()
+	c.Assert(validateS2SCopyMethod(common.ES2SCopyMethod.Auto(), common.EFromTo.LocalBlob(), common.EBlobType.PageBlob(), true), chk.IsNil)
+	c.Assert(validateS2SCopyMethod(common.ES2SCopyMethod.PutBlockFromURL(), common.EFromTo.S3Blob(), detect, true), chk.IsNil)
+	c.Assert(validateS2SCopyMethod(common.ES2SCopyMethod.CopyBlob(), common.EFromTo.FileBlob(), common.EBlobType.BlockBlob(), false), chk.IsNil)
+	c.Assert(validateS2SCopyMethod(common.ES2SCopyMethod.DownloadUpload(), common.EFromTo.BlobBlob(), detect, true), chk.IsNil)
+
+	err := validateS2SCopyMethod(common.ES2SCopyMethod.CopyBlob(), common.EFromTo.LocalBlob(), detect, false)
+	c.Assert(err, chk.ErrorMatches, "s2s-copy-method is only supported for service to service copies to Blob Storage")
+	err = validateS2SCopyMethod(common.ES2SCopyMethod.PutBlockFromURL(), common.EFromTo.BlobFile(), detect, false)
+	c.Assert(err, chk.ErrorMatches, "s2s-copy-method is only supported for service to service copies to Blob Storage")
+	err = validateS2SCopyMethod(common.ES2SCopyMethod.CopyBlob(), common.EFromTo.BlobBlob(), common.EBlobType.AppendBlob(), false)
+	c.Assert(err, chk.ErrorMatches, "s2s-copy-method only applies to block blobs.*")
+	err = validateS2SCopyMethod(common.ES2SCopyMethod.DownloadUpload(), common.EFromTo.FileBlob(), detect, false)
+	c.Assert(err, chk.ErrorMatches, "s2s-copy-method DownloadUpload is only supported when the source is Blob Storage")
+	err = validateS2SCopyMethod(common.ES2SCopyMethod.CopyBlob(), common.EFromTo.S3Blob(), detect, false)
+	c.Assert(err, chk.ErrorMatches, "s2s-copy-method CopyBlob is only supported when the source is Blob Storage or Azure Files")
+	err = validateS2SCopyMethod(common.ES2SCopyMethod.CopyBlob(), common.EFromTo.BlobBlob(), detect, true)
+	c.Assert(err, chk.ErrorMatches, "s2s-copy-method CopyBlob can't set index tags.*")
+}
@@ -72,6 +72,7 @@ func getDefaultRawCopyInput(src, dst string) rawCopyCmdArgs {
 		dst:                            dst,
 		recursive:                      true,
 		logVerbosity:                   defaultLogVerbosityForCopy,
+		priority:                       common.EJobPriority.Normal().String(),
 		output:                         defaultOutputFormatForCopy,
 		blobType:                       defaultBlobTypeForCopy,
 		blockBlobTier:                  defaultBlockBlobTierForCopy,
@@ -82,6 +83,7 @@ func getDefaultRawCopyInput(src, dst string) rawCopyCmdArgs {
 		s2sPreserveProperties:          defaultS2SPreserveProperties,
 		s2sSourceChangeValidation:      defaultS2SSourceChangeValidation,
 		s2sInvalidMetadataHandleOption: defaultS2SInvalideMetadataHandleOption.String(),
+		s2sMetadataMerge:               common.EMetadataMergeOption.Overwrite().String(),
 		forceWrite:                     common.EOverwriteOption.True().String(),
 		preserveOwner:                  common.PreserveOwnerDefault,
 	}
@@ -0,0 +1,79 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copySkipUnchangedSuite struct{}
+
+var _ = chk.Suite(&copySkipUnchangedSuite{})
+
+func (s *copySkipUnchangedSuite) TestUnchangedFileSkipper(c *chk.C) {
+	now := time.Now()
+	indexer := newObjectIndexer()
+	c.Assert(indexer.store(storedObject{name: "a.txt", relativePath: "dir/a.txt", entityType: common.EEntityType.File(), size: 10, lastModifiedTime: now}), chk.IsNil)
+	skipper := &unchangedFileSkipper{destinationIndex: indexer, mtimeTolerance: 2 * time.Second}
+
+	cases := []struct {
+		desc     string
+		source   storedObject
+		dstPath  string
+		expected bool
+	}{
+		{"same size and time", storedObject{size: 10, lastModifiedTime: now}, "dir/a.txt", true},
+		{"destination newer", storedObject{size: 10, lastModifiedTime: now.Add(-time.Hour)}, "dir/a.txt", true},
+		{"source newer within tolerance", storedObject{size: 10, lastModifiedTime: now.Add(time.Second)}, "dir/a.txt", true},
+		{"source newer beyond tolerance", storedObject{size: 10, lastModifiedTime: now.Add(time.Minute)}, "dir/a.txt", false},
+		{"size differs", storedObject{size: 11, lastModifiedTime: now}, "dir/a.txt", false},
+		{"not at destination", storedObject{size: 10, lastModifiedTime: now}, "dir/b.txt", false},
+	}
+
+	for _, cs := range cases {
+		cs.source.entityType = common.EEntityType.File()
+		c.Assert(skipper.skipIfUnchanged(cs.source, cs.dstPath), chk.Equals, cs.expected, chk.Commentf(cs.desc))
+	}
+
+	// only the skips are counted
+	c.Assert(skipper.skippedCount(), chk.Equals, uint32(3))
+
+	// folders are never skipped, since there's nothing meaningful to compare
+	folder := storedObject{entityType: common.EEntityType.Folder(), relativePath: "dir"}
+	c.Assert(indexer.store(folder), chk.IsNil)
+	c.Assert(skipper.isUnchanged(folder, "dir"), chk.Equals, false)
+
+	// and when the feature is off, there is no skipper at all
+	var noSkipper *unchangedFileSkipper
+	c.Assert(noSkipper.skippedCount(), chk.Equals, uint32(0))
+}
+
+func (s *copySkipUnchangedSuite) TestSkipUnchangedLookupKey(c *chk.C) {
+	cca := cookedCopyCmdArgs{fromTo: common.EFromTo.LocalBlob()}
+	c.Assert(cca.skipUnchangedLookupKey("/dir/with%20space.txt"), chk.Equals, "dir/with space.txt")
+	c.Assert(cca.skipUnchangedLookupKey(""), chk.Equals, "")
+
+	cca = cookedCopyCmdArgs{fromTo: common.EFromTo.BlobLocal()}
+	c.Assert(cca.skipUnchangedLookupKey("/dir/100%25.txt"), chk.Equals, "dir/100%25.txt") // local paths are not url-escaped
+}
@@ -0,0 +1,59 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type copyStdioSuite struct{}
+
+var _ = chk.Suite(&copyStdioSuite{})
+
+func (s *copyStdioSuite) TestFromToIsInferredFromStdioArgument(c *chk.C) {
+	blobURL := "https://acct.blob.core.windows.net/c/blob"
+
+	fromTo, err := inferStdioFromTo(stdioArgument, blobURL, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.PipeBlob())
+
+	fromTo, err = inferStdioFromTo(blobURL, stdioArgument, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.BlobPipe())
+
+	_, err = inferStdioFromTo(stdioArgument, stdioArgument, "")
+	c.Assert(err, chk.ErrorMatches, ".*stdin can't be copied to stdout.*")
+}
+
+func (s *copyStdioSuite) TestFromToMustAgreeWithStdioArgument(c *chk.C) {
+	blobURL := "https://acct.blob.core.windows.net/c/blob"
+
+	fromTo, err := inferStdioFromTo(stdioArgument, blobURL, "PipeBlob")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo, chk.Equals, common.EFromTo.PipeBlob())
+
+	_, err = inferStdioFromTo(stdioArgument, blobURL, "BlobPipe")
+	c.Assert(err, chk.ErrorMatches, ".*Stdin can only be uploaded to a blob.*")
+
+	_, err = inferStdioFromTo(blobURL, stdioArgument, "LocalBlob")
+	c.Assert(err, chk.ErrorMatches, ".*Only a blob can be downloaded to Stdout.*")
+}
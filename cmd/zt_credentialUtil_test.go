@@ -55,10 +55,13 @@ func (s *credentialUtilSuite) TestCheckAuthSafeForTarget(c *chk.C) {
 		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "http://s3.eu-central-1.amazonaws.com", "", true},
 		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "http://s3.cn-north-1.amazonaws.com.cn", "", true},
 		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "http://s3.amazonaws.com", "", true},
+		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "https://storage.googleapis.com/bucket", "", true},
+		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "https://bucket.storage.googleapis.com", "", true},
 
 		// These should fail (they are not storage)
 		{common.ECredentialType.OAuthToken(), common.ELocation.Blob(), "http://somethingelseinazure.windows.net", "", false},
 		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "http://somethingelseinaws.amazonaws.com", "", false},
+		{common.ECredentialType.S3AccessKey(), common.ELocation.S3(), "https://storage.googleapis.com.example.com/bucket", "", false},
 
 		// As should these (they are nothing to do with the expected URLs)
 		{common.ECredentialType.OAuthToken(), common.ELocation.Blob(), "http://abc.example.com", "", false},
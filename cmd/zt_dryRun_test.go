@@ -0,0 +1,174 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type dryRunSuite struct{}
+
+var _ = chk.Suite(&dryRunSuite{})
+
+func newRecordingDryRunPrinter() (*dryRunPrinter, *[]string) {
+	var printed []string
+	p := newDryRunPrinter()
+	p.print = func(msg string) { printed = append(printed, msg) }
+	return p, &printed
+}
+
+func (s *dryRunSuite) TestDryRunPrintsCopies(c *chk.C) {
+	p, printed := newRecordingDryRunPrinter()
+	order := &common.CopyJobPartOrderRequest{
+		FromTo:          common.EFromTo.LocalBlob(),
+		SourceRoot:      common.ResourceString{Value: "/data"},
+		DestinationRoot: common.ResourceString{Value: "https://acct.blob.core.windows.net/c/"},
+		Transfers: []common.CopyTransfer{
+			{Source: "/a.txt", Destination: "/a.txt", EntityType: common.EEntityType.File(), SourceSize: 1024},
+			{Source: "/sub/b.txt", Destination: "/sub/b.txt", EntityType: common.EEntityType.File(), SourceSize: 1024},
+		},
+	}
+
+	resp := p.printPart(order)
+	c.Assert(resp.JobStarted, chk.Equals, true)
+	c.Assert(*printed, chk.DeepEquals, []string{
+		"Would copy file /data/a.txt to https://acct.blob.core.windows.net/c/a.txt (1.00 KiB)",
+		"Would copy file /data/sub/b.txt to https://acct.blob.core.windows.net/c/sub/b.txt (1.00 KiB)",
+	})
+
+	c.Assert(p.printDeletion(storedObject{relativePath: "old.txt", entityType: common.EEntityType.File()}), chk.IsNil)
+	c.Assert(p.printDeletion(storedObject{relativePath: "olddir", entityType: common.EEntityType.Folder()}), chk.IsNil)
+	c.Assert((*printed)[2], chk.Equals, "Would delete extra file old.txt")
+	c.Assert(*printed, chk.HasLen, 3)
+
+	c.Assert(p.summary(false), chk.Equals,
+		"Dry run complete. 2 files (2.00 KiB) would be copied, and 1 extra files would be deleted from the destination. Nothing was changed.")
+}
+
+func (s *dryRunSuite) TestDryRunPrintsRemovals(c *chk.C) {
+	p, printed := newRecordingDryRunPrinter()
+	order := &common.CopyJobPartOrderRequest{
+		FromTo:      common.EFromTo.BlobTrash(),
+		SourceRoot:  common.ResourceString{Value: "https://acct.blob.core.windows.net/c"},
+		IsFinalPart: true,
+		Transfers: []common.CopyTransfer{
+			{Source: "dir", EntityType: common.EEntityType.Folder()},
+			{Source: "dir/x", EntityType: common.EEntityType.File(), SourceSize: 10},
+		},
+	}
+
+	c.Assert(p.printPart(order).JobStarted, chk.Equals, true)
+	c.Assert(*printed, chk.DeepEquals, []string{
+		"Would remove folder https://acct.blob.core.windows.net/c/dir",
+		"Would remove file https://acct.blob.core.windows.net/c/dir/x",
+	})
+	c.Assert(p.summary(true), chk.Equals, "Dry run complete. 1 files (10.00 B) would be removed, along with 1 folders. Nothing was changed.")
+}
+
+func (s *dryRunSuite) TestDryRunAppliesTheOverwriteOption(c *chk.C) {
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+	existing := map[string]storedObject{
+		"/dst/same":  {size: 10, lastModifiedTime: older},
+		"/dst/newer": {size: 10, lastModifiedTime: older},
+		"/dst/sized": {size: 20, lastModifiedTime: newer},
+	}
+	order := &common.CopyJobPartOrderRequest{
+		FromTo:          common.EFromTo.BlobLocal(),
+		SourceRoot:      common.ResourceString{Value: "/src"},
+		DestinationRoot: common.ResourceString{Value: "/dst"},
+		Transfers: []common.CopyTransfer{
+			{Source: "new", Destination: "new", EntityType: common.EEntityType.File(), SourceSize: 10, LastModifiedTime: newer},
+			{Source: "same", Destination: "same", EntityType: common.EEntityType.File(), SourceSize: 10, LastModifiedTime: older},
+			{Source: "newer", Destination: "newer", EntityType: common.EEntityType.File(), SourceSize: 10, LastModifiedTime: newer},
+			{Source: "sized", Destination: "sized", EntityType: common.EEntityType.File(), SourceSize: 10, LastModifiedTime: older},
+		},
+	}
+	dryRun := func(overwrite common.OverwriteOption) (*dryRunPrinter, []string) {
+		p, printed := newRecordingDryRunPrinter()
+		p.overwrite = overwrite
+		p.findDestination = func(destination string) (storedObject, bool) {
+			o, ok := existing[destination]
+			return o, ok
+		}
+		p.printPart(order)
+		return p, *printed
+	}
+
+	p, printed := dryRun(common.EOverwriteOption.False())
+	c.Assert(printed, chk.DeepEquals, []string{
+		"Would copy file /src/new to /dst/new (10.00 B)",
+		"Would skip file /src/same, since /dst/same already exists",
+		"Would skip file /src/newer, since /dst/newer already exists",
+		"Would skip file /src/sized, since /dst/sized already exists",
+	})
+	c.Assert(p.summary(false), chk.Equals,
+		"Dry run complete. 1 files (10.00 B) would be copied, and 3 files would be skipped, since they already exist. Nothing was changed.")
+
+	_, printed = dryRun(common.EOverwriteOption.IfSourceNewer())
+	c.Assert(printed[1:], chk.DeepEquals, []string{
+		"Would skip file /src/same, since /dst/same already exists",
+		"Would overwrite file /src/newer to /dst/newer (10.00 B)",
+		"Would skip file /src/sized, since /dst/sized already exists",
+	})
+
+	_, printed = dryRun(common.EOverwriteOption.IfSizeDifferent())
+	c.Assert(printed[1:], chk.DeepEquals, []string{
+		"Would skip file /src/same, since /dst/same already exists",
+		"Would skip file /src/newer, since /dst/newer already exists",
+		"Would overwrite file /src/sized to /dst/sized (10.00 B)",
+	})
+
+	_, printed = dryRun(common.EOverwriteOption.Prompt())
+	c.Assert(printed[1], chk.Equals, "Would ask whether to overwrite /dst/same with file /src/same (10.00 B)")
+}
+
+func (s *dryRunSuite) TestDryRunCountsUnchangedFiles(c *chk.C) {
+	p, printed := newRecordingDryRunPrinter()
+	p.printUnchanged("/src/b", "/dst/b")
+	p.printUnchanged("/src/c", "/dst/c")
+	p.printPart(&common.CopyJobPartOrderRequest{FromTo: common.EFromTo.LocalBlob(), IsFinalPart: true,
+		Transfers: []common.CopyTransfer{{Source: "/a", Destination: "/a", SourceSize: 10, EntityType: common.EEntityType.File()}}})
+
+	c.Assert((*printed)[:2], chk.DeepEquals, []string{
+		"Would skip (unchanged) file /src/b, since /dst/b is up to date",
+		"Would skip (unchanged) file /src/c, since /dst/c is up to date",
+	})
+
+	c.Assert(p.summary(false), chk.Equals,
+		"Dry run complete. 1 files (10.00 B) would be copied, and 2 files would be skipped, since they are unchanged at the destination. Nothing was changed.")
+}
+
+func (s *dryRunSuite) TestDryRunWithEverythingUnchanged(c *chk.C) {
+	c.Assert(formatNothingScheduled(3, 0, true), chk.Equals,
+		"Dry run complete. No files would be transferred, because all 3 were unchanged at the destination. Nothing was changed.")
+	c.Assert(formatNothingScheduled(3, 0, false), chk.Equals, "No files were transferred, because all 3 were unchanged at the destination")
+}
+
+func (s *dryRunSuite) TestDryRunWithNothingToDo(c *chk.C) {
+	p, _ := newRecordingDryRunPrinter()
+	resp := p.printPart(&common.CopyJobPartOrderRequest{FromTo: common.EFromTo.LocalBlob(), IsFinalPart: true})
+	c.Assert(resp.JobStarted, chk.Equals, false)
+	c.Assert(resp.ErrorMsg, chk.Equals, common.ECopyJobPartOrderErrorType.NoTransfersScheduledErr())
+}
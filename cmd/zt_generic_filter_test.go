@@ -23,7 +23,11 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
 	chk "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
 	"strings"
 	"time"
 )
@@ -181,3 +185,259 @@ func (_ *genericFilterSuite) findAmbiguousTime() (string, time.Time, time.Time,
 
 	return "", time.Time{}, time.Time{}, noAmbiguousHourError
 }
+
+func (s *genericFilterSuite) TestFileSizeFilter(c *chk.C) {
+	minSize, maxSize, err := parseFileSizeRange("1KiB", "1MB")
+	c.Assert(err, chk.IsNil)
+	filters := buildFileSizeFilters(minSize, maxSize)
+
+	for _, size := range []int64{1024, 5000, 1000000} {
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(filters, storedObject{name: "f", size: size, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.IsNil)
+		c.Assert(len(dummyProcessor.record), chk.Equals, 1)
+	}
+
+	for _, size := range []int64{0, 1023, 1000001} {
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(filters, storedObject{name: "f", size: size, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.Equals, ignoredError)
+		c.Assert(len(dummyProcessor.record), chk.Equals, 0)
+	}
+
+	// folders have no size, so they aren't filtered by it
+	dummyProcessor := &dummyProcessor{}
+	err = processIfPassedFilters(filters, storedObject{name: "d", entityType: common.EEntityType.Folder()}, dummyProcessor.process)
+	c.Assert(err, chk.IsNil)
+
+	// either end of the range may be left open
+	minSize, maxSize, err = parseFileSizeRange("", "10")
+	c.Assert(err, chk.IsNil)
+	c.Assert(minSize, chk.IsNil)
+	c.Assert(*maxSize, chk.Equals, int64(10))
+	c.Assert(buildFileSizeFilters(nil, nil), chk.HasLen, 0)
+
+	_, _, err = parseFileSizeRange("2G", "1G")
+	c.Assert(err, chk.ErrorMatches, "min-size .* can't be more than max-size .*")
+}
+
+func (s *genericFilterSuite) TestParseFileSize(c *chk.C) {
+	expectedSizes := map[string]int64{
+		"0":       0,
+		"500":     500,
+		"500B":    500,
+		"64k":     64 * 1024,
+		"64KiB":   64 * 1024,
+		"64KB":    64000,
+		"1.5G":    1536 * 1024 * 1024,
+		"2 GB":    2000000000,
+		"1TiB":    1024 * 1024 * 1024 * 1024,
+		" 3 mib ": 3 * 1024 * 1024,
+	}
+	for s, expected := range expectedSizes {
+		size, err := parseFileSize(s, "max-size")
+		c.Assert(err, chk.IsNil, chk.Commentf(s))
+		c.Assert(size, chk.Equals, expected, chk.Commentf(s))
+	}
+
+	for _, s := range []string{"", "GB", "-1K", "1.2.3M", "12 parsecs", "1PB"} {
+		_, err := parseFileSize(s, "max-size")
+		c.Assert(err, chk.ErrorMatches, "max-size must be a number of bytes.*", chk.Commentf(s))
+	}
+}
+
+func (s *genericFilterSuite) TestDateWindow(c *chk.C) {
+	after, before, err := parseDateWindow("2020-08-01T00:00:00Z", "2020-08-31T00:00:00Z")
+	c.Assert(err, chk.IsNil)
+	filters := buildDateFilters(after, before)
+	c.Assert(filters, chk.HasLen, 2)
+
+	for _, lmt := range []string{"2020-08-01T00:00:00Z", "2020-08-15T12:00:00Z", "2020-08-31T00:00:00Z"} {
+		t, _ := time.Parse(time.RFC3339, lmt)
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(filters, storedObject{name: "f", lastModifiedTime: t, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.IsNil, chk.Commentf(lmt))
+	}
+
+	for _, lmt := range []string{"2020-07-31T23:59:59Z", "2020-08-31T00:00:01Z"} {
+		t, _ := time.Parse(time.RFC3339, lmt)
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(filters, storedObject{name: "f", lastModifiedTime: t, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.Equals, ignoredError, chk.Commentf(lmt))
+	}
+
+	// either end of the window may be left open
+	after, before, err = parseDateWindow("", "2020-08-31")
+	c.Assert(err, chk.IsNil)
+	c.Assert(after, chk.IsNil)
+	c.Assert(before, chk.NotNil)
+	c.Assert(buildDateFilters(nil, nil), chk.HasLen, 0)
+
+	_, _, err = parseDateWindow("2020-09-01", "2020-08-31")
+	c.Assert(err, chk.ErrorMatches, ".*can't be later than.*")
+	_, _, err = parseDateWindow("", "last Tuesday")
+	c.Assert(err, chk.ErrorMatches, "could not parse date/time 'last Tuesday'.*")
+}
+
+func (s *genericFilterSuite) TestSyncDateWindowIsOnlyForCopyingChanges(c *chk.C) {
+	dir, err := ioutil.TempDir("", "datewindow")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	raw := getDefaultSyncRawInput(dir, "https://account.blob.core.windows.net/container?sv=1&sig=2")
+	raw.includeAfter = "2020-08-01"
+
+	// deleting the destination's files that aren't in the window would delete nearly everything
+	_, err = raw.cook()
+	c.Assert(err, chk.ErrorMatches, ".*can't be used with delete-destination.*")
+
+	raw.deleteDestination = common.EDeleteDestination.False().String()
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.includeAfter, chk.NotNil)
+	c.Assert(cooked.includeBefore, chk.IsNil)
+}
+
+func (s *genericFilterSuite) TestIncludeBlobTierFilter(c *chk.C) {
+	tiers, err := parseBlobTiers("hot, Cool;")
+	c.Assert(err, chk.IsNil)
+	c.Assert(tiers, chk.DeepEquals, []azblob.AccessTierType{azblob.AccessTierHot, azblob.AccessTierCool})
+	filters := buildIncludeBlobTierFilters(tiers)
+
+	for _, tier := range []azblob.AccessTierType{azblob.AccessTierHot, azblob.AccessTierCool} {
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(filters, storedObject{name: "b", blobAccessTier: tier, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.IsNil)
+	}
+
+	// archived blobs, and blobs with no tier at all, are left out
+	for _, tier := range []azblob.AccessTierType{azblob.AccessTierArchive, azblob.AccessTierNone} {
+		dummyProcessor := &dummyProcessor{}
+		err := processIfPassedFilters(filters, storedObject{name: "b", blobAccessTier: tier, entityType: common.EEntityType.File()}, dummyProcessor.process)
+		c.Assert(err, chk.Equals, ignoredError)
+	}
+
+	c.Assert(buildIncludeBlobTierFilters(nil), chk.HasLen, 0)
+	_, err = parseBlobTiers("hot;lukewarm")
+	c.Assert(err, chk.ErrorMatches, "'lukewarm' is not an access tier. The access tiers are .*Archive.*")
+}
+
+func (s *genericFilterSuite) TestIncludeTierNeedsBlobSource(c *chk.C) {
+	dir, err := ioutil.TempDir("", "includetier")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	raw := getDefaultCopyRawInput(dir, "https://account.blob.core.windows.net/container?sv=1&sig=2")
+	raw.includeTier = "Archive"
+	_, err = raw.cook()
+	c.Assert(err, chk.ErrorMatches, "include-tier only applies when the source is Blob Storage.*")
+
+	raw = getDefaultCopyRawInput("https://account.blob.core.windows.net/container?sv=1&sig=2", dir)
+	raw.recursive = true
+	raw.includeTier = "Archive"
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.includeBlobTiers, chk.DeepEquals, []azblob.AccessTierType{azblob.AccessTierArchive})
+}
+
+func (s *genericFilterSuite) TestTagsAndMetadataFilters(c *chk.C) {
+	tags, err := parseKeyValueFilter("project=alpha;env=prod*", "include-tags")
+	c.Assert(err, chk.IsNil)
+	c.Assert(tags, chk.DeepEquals, map[string]string{"project": "alpha", "env": "prod*"})
+	filters := buildBlobTagsFilters(tags)
+	c.Assert(filterSet(filters).needsBlobTags(), chk.Equals, true)
+
+	passes := func(object storedObject) bool {
+		object.entityType = common.EEntityType.File()
+		return processIfPassedFilters(filters, object, (&dummyProcessor{}).process) == nil
+	}
+	c.Assert(passes(storedObject{blobTags: map[string]string{"project": "alpha", "env": "production", "x": "y"}}), chk.Equals, true)
+	c.Assert(passes(storedObject{blobTags: map[string]string{"project": "alpha"}}), chk.Equals, false)
+	c.Assert(passes(storedObject{blobTags: map[string]string{"Project": "alpha", "env": "prod"}}), chk.Equals, false) // tag names are case-sensitive
+
+	// metadata names aren't
+	filters = buildMetadataFilters(map[string]string{"owner": "finance"})
+	c.Assert(filterSet(filters).needsBlobTags(), chk.Equals, false)
+	c.Assert(passes(storedObject{Metadata: common.Metadata{"Owner": "finance"}}), chk.Equals, true)
+	c.Assert(passes(storedObject{Metadata: common.Metadata{"owner": "legal"}}), chk.Equals, false)
+	c.Assert(passes(storedObject{}), chk.Equals, false)
+
+	_, err = parseKeyValueFilter("project=alpha;env", "include-tags")
+	c.Assert(err, chk.ErrorMatches, "include-tags must be a list of key=value pairs.*'env' is not a key=value pair")
+}
+
+func (s *genericFilterSuite) TestFindByTagsQuery(c *chk.C) {
+	filters := buildBlobTagsFilters(map[string]string{"project": "alpha", "env": "prod"})
+	c.Assert(filterSet(filters).getFindByTagsQuery("images"), chk.Equals, `@container='images' AND "env"='prod' AND "project"='alpha'`)
+
+	// the service can only find exact values
+	filters = buildBlobTagsFilters(map[string]string{"project": "alpha", "env": "prod*"})
+	c.Assert(filterSet(filters).getFindByTagsQuery("images"), chk.Equals, "")
+	c.Assert(filterSet(buildIncludeFilters([]string{"*.jpg"})).getFindByTagsQuery("images"), chk.Equals, "")
+}
+
+func (s *genericFilterSuite) TestParseFileAttributes(c *chk.C) {
+	letters, err := parseFileAttributes("hidden,System; temporary;R;a", "exclude-attributes")
+	c.Assert(err, chk.IsNil)
+	c.Assert(letters, chk.DeepEquals, []string{"H", "S", "T", "R", "A"})
+
+	letters, err = parseFileAttributes("", "exclude-attributes")
+	c.Assert(err, chk.IsNil)
+	c.Assert(letters, chk.HasLen, 0)
+
+	_, err = parseFileAttributes("hidden,sparkly", "exclude-attributes")
+	c.Assert(err, chk.ErrorMatches, "'sparkly' given to exclude-attributes is not a file attribute.*")
+	_, err = parseFileAttributes("X", "include-attributes")
+	c.Assert(err, chk.ErrorMatches, "'X' given to include-attributes is not a file attribute.*")
+}
+
+func (s *genericFilterSuite) TestBlobTierForServiceToServiceCopy(c *chk.C) {
+	raw := getDefaultCopyRawInput("https://account.blob.core.windows.net/container?sv=1&sig=2", "https://other.blob.core.windows.net/container?sv=1&sig=2")
+	raw.blockBlobTier = "Archive"
+	raw.blockBlobTierRules = "*.log=Cool"
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.blockBlobTier, chk.Equals, common.EBlockBlobTier.Archive())
+	c.Assert(cooked.blockBlobTierRules.ToString(), chk.Equals, "*.log=Cool")
+
+	raw = getDefaultCopyRawInput("https://account.blob.core.windows.net/container?sv=1&sig=2", "https://other.file.core.windows.net/share?sv=1&sig=2")
+	raw.blockBlobTier = "Archive"
+	_, err = raw.cook()
+	c.Assert(err, chk.ErrorMatches, "blob-tier is only supported while copying from service to service when the destination is Blob Storage")
+}
+
+func (s *genericFilterSuite) TestArchivedSourceOptions(c *chk.C) {
+	dir, err := ioutil.TempDir("", "archivedsource")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	raw := getDefaultCopyRawInput("https://account.blob.core.windows.net/container?sv=1&sig=2", dir)
+	raw.recursive = true
+	raw.archivedSource = "rehydrate"
+	raw.rehydratePriority = "high"
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.archivedSource, chk.Equals, common.EArchivedSourceOption.Rehydrate())
+	c.Assert(cooked.rehydratePriority, chk.Equals, common.ERehydratePriority.High())
+
+	// the priority is only for rehydrating
+	raw.archivedSource = "Skip"
+	_, err = raw.cook()
+	c.Assert(err, chk.ErrorMatches, "rehydrate-priority can only be used with --archived-source=Rehydrate")
+
+	raw = getDefaultCopyRawInput(dir, "https://account.blob.core.windows.net/container?sv=1&sig=2")
+	raw.archivedSource = "Skip"
+	_, err = raw.cook()
+	c.Assert(err, chk.ErrorMatches, "archived-source only applies when the source is Blob Storage.*")
+}
+
+func (s *genericFilterSuite) TestArchivedBlobSkipper(c *chk.C) {
+	skipper := &archivedBlobSkipper{}
+	c.Assert(skipper.skipIfArchived(storedObject{name: "a", blobAccessTier: azblob.AccessTierArchive, entityType: common.EEntityType.File()}, "a"), chk.Equals, true)
+	c.Assert(skipper.skipIfArchived(storedObject{name: "b", blobAccessTier: azblob.AccessTierCool, entityType: common.EEntityType.File()}, "b"), chk.Equals, false)
+	c.Assert(skipper.skipIfArchived(storedObject{name: "c", entityType: common.EEntityType.File()}, "c"), chk.Equals, false)
+	c.Assert(skipper.skippedCount(), chk.Equals, uint32(1))
+
+	var noSkipper *archivedBlobSkipper
+	c.Assert(noSkipper.skippedCount(), chk.Equals, uint32(0))
+}
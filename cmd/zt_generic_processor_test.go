@@ -147,3 +147,12 @@ func (s *genericProcessorSuite) TestCopyTransferProcessorSingleFile(c *chk.C) {
 	// assert the right transfers were scheduled
 	validateCopyTransfersAreScheduled(c, false, false, "", "", []string{""}, mockedRPC)
 }
+
+func (s *genericProcessorSuite) TestStripCompressionExtension(c *chk.C) {
+	c.Assert(stripCompressionExtension("/dir/app.log.gz", "gzip"), chk.Equals, "/dir/app.log")
+	c.Assert(stripCompressionExtension("/dir/app.log.gz", "x-gzip"), chk.Equals, "/dir/app.log")
+	c.Assert(stripCompressionExtension("/dir/backup.tgz", "gzip"), chk.Equals, "/dir/backup.tar")
+	c.Assert(stripCompressionExtension("/dir/data.zz", "deflate"), chk.Equals, "/dir/data")
+	c.Assert(stripCompressionExtension("/dir/index.html", "gzip"), chk.Equals, "/dir/index.html")
+	c.Assert(stripCompressionExtension("/dir/app.log.gz", ""), chk.Equals, "/dir/app.log.gz")
+}
@@ -202,7 +202,7 @@ func (s *genericTraverserSuite) TestWalkWithSymlinks_ToFolder(c *chk.C) {
 		fileCount++
 		return nil
 	},
-		true), chk.IsNil)
+		common.ESymlinkHandlingType.Follow()), chk.IsNil)
 
 	// 3 files live in base, 3 files live in symlink
 	c.Assert(fileCount, chk.Equals, 6)
@@ -242,7 +242,7 @@ func (s *genericTraverserSuite) TestWalkWithSymlinks_ToFile(c *chk.C) {
 		}
 		return nil
 	},
-		true), chk.IsNil)
+		common.ESymlinkHandlingType.Follow()), chk.IsNil)
 
 	// 1 file is in base, 2 are pointed to by a symlink (the fact that both point to the same file is does NOT prevent us
 	// processing them both. For efficiency of dedupe algorithm, we only dedupe directories, not files).
@@ -272,7 +272,7 @@ func (s *genericTraverserSuite) TestWalkWithSymlinksBreakLoop(c *chk.C) {
 		fileCount++
 		return nil
 	},
-		true), chk.IsNil)
+		common.ESymlinkHandlingType.Follow()), chk.IsNil)
 
 	c.Assert(fileCount, chk.Equals, 3)
 }
@@ -302,7 +302,7 @@ func (s *genericTraverserSuite) TestWalkWithSymlinksDedupe(c *chk.C) {
 		fileCount++
 		return nil
 	},
-		true), chk.IsNil)
+		common.ESymlinkHandlingType.Follow()), chk.IsNil)
 
 	c.Assert(fileCount, chk.Equals, 6)
 }
@@ -333,7 +333,7 @@ func (s *genericTraverserSuite) TestWalkWithSymlinksMultitarget(c *chk.C) {
 		fileCount++
 		return nil
 	},
-		true), chk.IsNil)
+		common.ESymlinkHandlingType.Follow()), chk.IsNil)
 
 	// 3 files live in base, 3 files live in first symlink, second & third symlink is ignored.
 	c.Assert(fileCount, chk.Equals, 6)
@@ -366,12 +366,55 @@ func (s *genericTraverserSuite) TestWalkWithSymlinksToParentAndChild(c *chk.C) {
 		fileCount++
 		return nil
 	},
-		true), chk.IsNil)
+		common.ESymlinkHandlingType.Follow()), chk.IsNil)
 
 	// 6 files total live under toroot. tochild should be ignored (or if tochild was traversed first, child will be ignored on toroot).
 	c.Assert(fileCount, chk.Equals, 6)
 }
 
+// with --preserve-symlinks, links are enumerated as links, whose size is the length of their target
+func (s *genericTraverserSuite) TestLocalTraverserPreservesSymlinks(c *chk.C) {
+	tmpDir := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(tmpDir)
+	scenarioHelper{}.generateLocalFilesFromList(c, tmpDir, []string{"target.txt"})
+	trySymlink("target.txt", filepath.Join(tmpDir, "link"), c)
+
+	for _, recursive := range []bool{true, false} {
+		found := map[string]storedObject{}
+		processor := func(o storedObject) error {
+			found[o.relativePath] = o
+			return nil
+		}
+
+		c.Assert(newLocalTraverser(tmpDir, recursive, common.ESymlinkHandlingType.Preserve(), nil).traverse(noPreProccessor, processor, nil), chk.IsNil)
+		c.Assert(found["link"].entityType, chk.Equals, common.EEntityType.Symlink())
+		c.Assert(found["link"].size, chk.Equals, int64(len("target.txt")))
+		c.Assert(found["target.txt"].entityType, chk.Equals, common.EEntityType.File())
+
+		found = map[string]storedObject{}
+		c.Assert(newLocalTraverser(tmpDir, recursive, common.ESymlinkHandlingType.Skip(), nil).traverse(noPreProccessor, processor, nil), chk.IsNil)
+		_, sawLink := found["link"]
+		c.Assert(sawLink, chk.Equals, false)
+		c.Assert(found["target.txt"].entityType, chk.Equals, common.EEntityType.File())
+	}
+}
+
+func (s *genericTraverserSuite) TestSymlinkHandlingFlags(c *chk.C) {
+	handling, err := getSymlinkHandlingType(false, false, false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(handling, chk.Equals, common.ESymlinkHandlingType.Skip())
+
+	handling, err = getSymlinkHandlingType(false, true, false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(handling, chk.Equals, common.ESymlinkHandlingType.Preserve())
+
+	_, err = getSymlinkHandlingType(true, false, true)
+	c.Assert(err, chk.NotNil)
+
+	c.Assert(validateSymlinkHandlingType(common.ESymlinkHandlingType.Preserve(), common.EFromTo.BlobLocal()), chk.IsNil)
+	c.Assert(validateSymlinkHandlingType(common.ESymlinkHandlingType.Preserve(), common.EFromTo.LocalFile()), chk.NotNil)
+}
+
 // validate traversing a single Blob, a single Azure File, and a single local file
 // compare that the traversers get consistent results
 func (s *genericTraverserSuite) TestTraverserWithSingleObject(c *chk.C) {
@@ -409,7 +452,7 @@ func (s *genericTraverserSuite) TestTraverserWithSingleObject(c *chk.C) {
 		scenarioHelper{}.generateLocalFilesFromList(c, dstDirName, blobList)
 
 		// construct a local traverser
-		localTraverser := newLocalTraverser(filepath.Join(dstDirName, dstFileName), false, false, func(common.EntityType) {})
+		localTraverser := newLocalTraverser(filepath.Join(dstDirName, dstFileName), false, common.ESymlinkHandlingType.Skip(), func(common.EntityType) {})
 
 		// invoke the local traversal with a dummy processor
 		localDummyProcessor := dummyProcessor{}
@@ -542,7 +585,7 @@ func (s *genericTraverserSuite) TestTraverserContainerAndLocalDirectory(c *chk.C
 	// test two scenarios, either recursive or not
 	for _, isRecursiveOn := range []bool{true, false} {
 		// construct a local traverser
-		localTraverser := newLocalTraverser(dstDirName, isRecursiveOn, false, func(common.EntityType) {})
+		localTraverser := newLocalTraverser(dstDirName, isRecursiveOn, common.ESymlinkHandlingType.Skip(), func(common.EntityType) {})
 
 		// invoke the local traversal with an indexer
 		// so that the results are indexed for easy validation
@@ -682,7 +725,7 @@ func (s *genericTraverserSuite) TestTraverserWithVirtualAndLocalDirectory(c *chk
 	// test two scenarios, either recursive or not
 	for _, isRecursiveOn := range []bool{true, false} {
 		// construct a local traverser
-		localTraverser := newLocalTraverser(filepath.Join(dstDirName, virDirName), isRecursiveOn, false, func(common.EntityType) {})
+		localTraverser := newLocalTraverser(filepath.Join(dstDirName, virDirName), isRecursiveOn, common.ESymlinkHandlingType.Skip(), func(common.EntityType) {})
 
 		// invoke the local traversal with an indexer
 		// so that the results are indexed for easy validation
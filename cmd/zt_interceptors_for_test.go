@@ -21,8 +21,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/Azure/azure-storage-azcopy/common"
 )
@@ -55,6 +57,14 @@ func (i *interceptor) intercept(cmd common.RpcCmd, request interface{}, response
 	case common.ERpcCmd.ResumeJob():
 	case common.ERpcCmd.GetJobFromTo():
 		fallthrough
+	case common.ERpcCmd.SetJobSettings():
+		fallthrough
+	case common.ERpcCmd.GetJobStatus():
+		fallthrough
+	case common.ERpcCmd.UpdateQueuedJob():
+		fallthrough
+	case common.ERpcCmd.RetryJob():
+		fallthrough
 	default:
 		panic("RPC mock not implemented")
 	}
@@ -93,9 +103,21 @@ func (m *mockedLifecycleManager) Info(msg string) {
 	default:
 	}
 }
+func (m *mockedLifecycleManager) Warn(msg string) {
+	m.Info(msg) // tests look for warnings alongside the info messages
+}
+func (*mockedLifecycleManager) Debug(string) {}
 func (*mockedLifecycleManager) Prompt(message string, details common.PromptDetails) common.ResponseOption {
 	return common.EResponseOption.Default()
 }
+func (*mockedLifecycleManager) PromptWithTimeout(message string, details common.PromptDetails, timeout time.Duration, defaultAnswer common.ResponseOption) common.ResponseOption {
+	return defaultAnswer
+}
+func (*mockedLifecycleManager) PromptForChoice(message string, details common.PromptDetails) common.ResponseOption {
+	return common.EResponseOption.Default()
+}
+func (*mockedLifecycleManager) SetPromptTimeout(time.Duration)                  {}
+func (*mockedLifecycleManager) SetScriptedAnswers(map[common.PromptType]string) {}
 func (m *mockedLifecycleManager) Exit(o common.OutputBuilder, e common.ExitCode) {
 	select {
 	case m.exitLog <- o(common.EOutputFormat.Text()):
@@ -108,8 +130,16 @@ func (m *mockedLifecycleManager) Error(msg string) {
 	default:
 	}
 }
-func (*mockedLifecycleManager) SurrenderControl()                               {}
-func (mockedLifecycleManager) AllowReinitiateProgressReporting()                {}
+func (m *mockedLifecycleManager) ReportError(err error) {
+	m.Error(common.DescribeError(err))
+}
+func (*mockedLifecycleManager) SurrenderControl() {}
+func (*mockedLifecycleManager) ReturnOnExit()     {}
+func (*mockedLifecycleManager) Run(work func()) common.ExitCode {
+	work()
+	return common.EExitCode.Success()
+}
+func (*mockedLifecycleManager) Context() context.Context                        { return context.Background() }
 func (*mockedLifecycleManager) InitiateProgressReporting(common.WorkController) {}
 func (*mockedLifecycleManager) ClearEnvironmentVariable(env common.EnvironmentVariable) {
 	_ = os.Setenv(env.Name, "")
@@ -121,12 +151,30 @@ func (*mockedLifecycleManager) GetEnvironmentVariable(env common.EnvironmentVari
 	}
 	return value
 }
-func (*mockedLifecycleManager) SetOutputFormat(common.OutputFormat) {}
-func (*mockedLifecycleManager) EnableInputWatcher()                 {}
-func (*mockedLifecycleManager) EnableCancelFromStdIn()              {}
+func (*mockedLifecycleManager) SetOutputFormat(common.OutputFormat)       {}
+func (*mockedLifecycleManager) SetOutputVerbosity(common.OutputVerbosity) {}
+func (*mockedLifecycleManager) EnableProgressEndpoint(string) error       { return nil }
+func (*mockedLifecycleManager) RegisterCleanupHook(func())                {}
+func (*mockedLifecycleManager) SetProgressDisplay(common.ProgressDisplay) {}
+func (*mockedLifecycleManager) SetErrorOutput(common.ErrorOutput)         {}
+func (*mockedLifecycleManager) SetProgressInterval(time.Duration)         {}
+func (*mockedLifecycleManager) SetOutputFile(string) error                { return nil }
+func (*mockedLifecycleManager) EnableSystemLog() error                    { return nil }
+func (*mockedLifecycleManager) SetNotifyURL(string) error                 { return nil }
+func (*mockedLifecycleManager) SetQuiet(bool)                             {}
+func (*mockedLifecycleManager) EnableInputWatcher()                       {}
+func (*mockedLifecycleManager) EnableCancelFromStdIn()                    {}
 func (*mockedLifecycleManager) AddUserAgentPrefix(userAgent string) string {
 	return userAgent
 }
+func (*mockedLifecycleManager) SetQueueOverflowPolicy(common.QueueOverflowPolicy) {}
+func (*mockedLifecycleManager) SetColors(common.ColorMode, common.ColorTheme)     {}
+func (*mockedLifecycleManager) EnableTransferEvents()                             {}
+func (*mockedLifecycleManager) TransferEventsEnabled() bool                       { return false }
+func (*mockedLifecycleManager) TransferEvent(common.TransferEvent)                {}
+func (*mockedLifecycleManager) OutputQueueStats() common.OutputQueueStats {
+	return common.OutputQueueStats{}
+}
 
 func (*mockedLifecycleManager) E2EAwaitContinue() {
 	// not implemented in mocked version
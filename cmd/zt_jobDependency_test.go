@@ -0,0 +1,73 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobDependencySuite struct{}
+
+var _ = chk.Suite(&jobDependencySuite{})
+
+func (s *jobDependencySuite) TestPrecedingJobOutcome(c *chk.C) {
+	jobID := common.NewJobID()
+
+	for _, status := range []common.JobStatus{common.EJobStatus.Completed(), common.EJobStatus.CompletedWithSkipped()} {
+		done, err := precedingJobOutcome(jobID, status, false)
+		c.Assert(done, chk.Equals, true)
+		c.Assert(err, chk.IsNil)
+	}
+
+	// still running, or able to carry on running
+	for _, status := range []common.JobStatus{common.EJobStatus.InProgress(), common.EJobStatus.Queued(), common.EJobStatus.Paused(), common.EJobStatus.Cancelling()} {
+		done, err := precedingJobOutcome(jobID, status, false)
+		c.Assert(done, chk.Equals, false)
+		c.Assert(err, chk.IsNil)
+	}
+
+	for _, status := range []common.JobStatus{common.EJobStatus.Failed(), common.EJobStatus.Cancelled(),
+		common.EJobStatus.CompletedWithErrors(), common.EJobStatus.CompletedWithErrorsAndSkipped()} {
+		done, err := precedingJobOutcome(jobID, status, false)
+		c.Assert(done, chk.Equals, false)
+		c.Assert(err, chk.NotNil)
+	}
+}
+
+func (s *jobDependencySuite) TestAbandonedPrecedingJob(c *chk.C) {
+	jobID := common.NewJobID()
+
+	// a job that's meant to be running, or queued, but has no process, would never end
+	for _, status := range []common.JobStatus{common.EJobStatus.InProgress(), common.EJobStatus.Queued(), common.EJobStatus.Cancelling()} {
+		done, err := precedingJobOutcome(jobID, status, true)
+		c.Assert(done, chk.Equals, false)
+		c.Assert(err, chk.ErrorMatches, ".*no AzCopy process is running it.*")
+	}
+
+	// whereas a paused one can still be resumed, and a completed one needs no process
+	done, err := precedingJobOutcome(jobID, common.EJobStatus.Paused(), true)
+	c.Assert(done, chk.Equals, false)
+	c.Assert(err, chk.IsNil)
+	done, err = precedingJobOutcome(jobID, common.EJobStatus.Completed(), true)
+	c.Assert(done, chk.Equals, true)
+	c.Assert(err, chk.IsNil)
+}
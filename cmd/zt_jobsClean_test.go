@@ -0,0 +1,69 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobsCleanSuite struct{}
+
+var _ = chk.Suite(&jobsCleanSuite{})
+
+func (s *jobsCleanSuite) TestSelectJobsToClean(c *chk.C) {
+	now := time.Now()
+	daysAgo := func(days int) int64 { return now.Add(-time.Duration(days) * 24 * time.Hour).UnixNano() }
+	recentCompleted := common.JobIDDetails{JobId: common.NewJobID(), StartTime: daysAgo(1), JobStatus: common.EJobStatus.Completed()}
+	oldCompleted := common.JobIDDetails{JobId: common.NewJobID(), StartTime: daysAgo(40), JobStatus: common.EJobStatus.CompletedWithErrors()}
+	oldCancelled := common.JobIDDetails{JobId: common.NewJobID(), StartTime: daysAgo(50), JobStatus: common.EJobStatus.Cancelled()}
+	oldestCompleted := common.JobIDDetails{JobId: common.NewJobID(), StartTime: daysAgo(60), JobStatus: common.EJobStatus.Completed()}
+	jobs := []common.JobIDDetails{oldCompleted, recentCompleted, oldestCompleted, oldCancelled}
+	all := common.EJobStatus.All()
+
+	// each option on its own
+	c.Assert(selectJobsToClean(jobs, jobsCleanOptions{withStatus: all, olderThan: 30 * 24 * time.Hour}, now),
+		chk.DeepEquals, []common.JobIDDetails{oldCompleted, oldCancelled, oldestCompleted})
+	c.Assert(selectJobsToClean(jobs, jobsCleanOptions{withStatus: all, completedOnly: true}, now),
+		chk.DeepEquals, []common.JobIDDetails{recentCompleted, oldCompleted, oldestCompleted})
+	c.Assert(selectJobsToClean(jobs, jobsCleanOptions{withStatus: all, keepLast: 2}, now),
+		chk.DeepEquals, []common.JobIDDetails{oldCancelled, oldestCompleted})
+	c.Assert(selectJobsToClean(jobs, jobsCleanOptions{withStatus: common.EJobStatus.Completed()}, now),
+		chk.DeepEquals, []common.JobIDDetails{recentCompleted, oldestCompleted})
+
+	// together, a job must match them all. The kept jobs are the most recent of ALL jobs, not just of the matching ones
+	c.Assert(selectJobsToClean(jobs, jobsCleanOptions{withStatus: all, olderThan: 30 * 24 * time.Hour, completedOnly: true, keepLast: 2}, now),
+		chk.DeepEquals, []common.JobIDDetails{oldestCompleted})
+
+	// keeping more jobs than there are means nothing is removed
+	c.Assert(selectJobsToClean(jobs, jobsCleanOptions{withStatus: all, keepLast: 10}, now), chk.HasLen, 0)
+}
+
+func (s *jobsCleanSuite) TestUnfilteredOnlyWithoutRetentionOptions(c *chk.C) {
+	c.Assert(jobsCleanOptions{withStatus: common.EJobStatus.All()}.isUnfiltered(), chk.Equals, true)
+	c.Assert(jobsCleanOptions{withStatus: common.EJobStatus.All(), dryRun: true}.isUnfiltered(), chk.Equals, true)
+	c.Assert(jobsCleanOptions{withStatus: common.EJobStatus.Completed()}.isUnfiltered(), chk.Equals, false)
+	c.Assert(jobsCleanOptions{withStatus: common.EJobStatus.All(), olderThan: time.Hour}.isUnfiltered(), chk.Equals, false)
+	c.Assert(jobsCleanOptions{withStatus: common.EJobStatus.All(), completedOnly: true}.isUnfiltered(), chk.Equals, false)
+	c.Assert(jobsCleanOptions{withStatus: common.EJobStatus.All(), keepLast: 1}.isUnfiltered(), chk.Equals, false)
+}
@@ -0,0 +1,48 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type jobsImportSuite struct{}
+
+var _ = chk.Suite(&jobsImportSuite{})
+
+func (s *jobsImportSuite) TestNewRootHasNoSAS(c *chk.C) {
+	args := importJobCmdArgs{}
+	root, err := args.newRoot("https://a.blob.core.windows.net/c/dir?sv=1&sig=secret", common.ELocation.Blob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(root, chk.Equals, "https://a.blob.core.windows.net/c/dir")
+
+	root, err = args.newRoot("data", common.ELocation.Local())
+	c.Assert(err, chk.IsNil)
+	c.Assert(filepath.IsAbs(root), chk.Equals, true)
+
+	root, err = args.newRoot("", common.ELocation.Local())
+	c.Assert(err, chk.IsNil)
+	c.Assert(root, chk.Equals, "") // the root is left as it is
+}
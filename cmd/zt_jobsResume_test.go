@@ -0,0 +1,47 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type jobsResumeSuite struct{}
+
+var _ = chk.Suite(&jobsResumeSuite{})
+
+func (s *jobsResumeSuite) TestValidateResumeSAS(c *chk.C) {
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	// no SAS, or one without an expiry, can't be checked
+	c.Assert(validateResumeSAS("source-sas", "", now), chk.IsNil)
+	c.Assert(validateResumeSAS("source-sas", "sv=2019-12-12&sig=abc", now), chk.IsNil)
+
+	// the leading '?' is optional
+	c.Assert(validateResumeSAS("source-sas", "?sv=2019-12-12&se=2020-09-02T00%3A00%3A00Z&sig=abc", now), chk.IsNil)
+	c.Assert(validateResumeSAS("source-sas", "sv=2019-12-12&se=2020-09-02&sig=abc", now), chk.IsNil)
+
+	err := validateResumeSAS("destination-sas", "sv=2019-12-12&se=2020-09-01T11%3A59%3A00Z&sig=abc", now)
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.Error(), chk.Matches, ".*--destination-sas expired.*")
+}
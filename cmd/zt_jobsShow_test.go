@@ -0,0 +1,90 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobsShowSuite struct{}
+
+var _ = chk.Suite(&jobsShowSuite{})
+
+func failedTransfersForTest() common.ListJobTransfersResponse {
+	return common.ListJobTransfersResponse{
+		JobID: common.NewJobID(),
+		Details: []common.TransferDetail{
+			{Src: "/a.txt", Dst: "https://acct.blob.core.windows.net/c/a.txt", TransferStatus: common.ETransferStatus.Failed(),
+				ErrorCode: 403, ErrorMessage: "AuthorizationFailure, \"quoted\"", RetryCount: 0},
+			{Src: "/b.txt", Dst: "https://acct.blob.core.windows.net/c/b.txt", TransferStatus: common.ETransferStatus.Failed(),
+				ErrorCode: 503, ErrorMessage: "ServerBusy", RetryCount: 20},
+		},
+	}
+}
+
+func (s *jobsShowSuite) TestExportFailedTransfersAsCSV(c *chk.C) {
+	dir, err := ioutil.TempDir("", "jobsshow")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "failed.csv")
+
+	c.Assert(exportJobTransfers(path, failedTransfersForTest()), chk.IsNil)
+
+	file, err := os.Open(path)
+	c.Assert(err, chk.IsNil)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	c.Assert(err, chk.IsNil)
+	c.Assert(records, chk.HasLen, 3)
+	c.Assert(records[0][5], chk.Equals, "ErrorMessage")
+	c.Assert(records[1][4:], chk.DeepEquals, []string{"403", "AuthorizationFailure, \"quoted\"", "0"})
+	c.Assert(records[2][3:], chk.DeepEquals, []string{"Failed", "503", "ServerBusy", "20"})
+}
+
+func (s *jobsShowSuite) TestExportFailedTransfersAsJSON(c *chk.C) {
+	dir, err := ioutil.TempDir("", "jobsshow")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "failed.JSON")
+
+	expected := failedTransfersForTest()
+	c.Assert(exportJobTransfers(path, expected), chk.IsNil)
+
+	content, err := ioutil.ReadFile(path)
+	c.Assert(err, chk.IsNil)
+	var details []common.TransferDetail
+	c.Assert(json.Unmarshal(content, &details), chk.IsNil)
+	c.Assert(details, chk.DeepEquals, expected.Details)
+}
+
+func (s *jobsShowSuite) TestExportNeedsKnownExtension(c *chk.C) {
+	_, err := exportFormatOf("failed.txt")
+	c.Assert(err, chk.NotNil)
+	_, err = exportFormatOf("failed")
+	c.Assert(err, chk.NotNil)
+}
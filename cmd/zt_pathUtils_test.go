@@ -58,6 +58,22 @@ func (s *pathUtilsSuite) TestStripQueryFromSaslessUrl(c *chk.C) {
 	}
 }
 
+func (s *pathUtilsSuite) TestSplitHTTPResourceKeepsTheQueryAsToken(c *chk.C) {
+	// the whole query of a plain HTTP(S) URL may authorize the request, so none of it is to be persisted
+	rs, err := SplitResourceString("https://example.com/sets/a.csv?X-Amz-Signature=abc&v=2", common.ELocation.HTTP())
+	c.Assert(err, chk.IsNil)
+	c.Assert(rs.Value, chk.Equals, "https://example.com/sets/a.csv")
+	c.Assert(rs.SAS, chk.Equals, "X-Amz-Signature=abc&v=2")
+	c.Assert(rs.ExtraQuery, chk.Equals, "")
+
+	level, err := determineLocationLevel(rs.Value, common.ELocation.HTTP(), true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(level, chk.Equals, ELocationLevel.Object())
+	level, err = determineLocationLevel("https://example.com/sets/", common.ELocation.HTTP(), true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(level, chk.Equals, ELocationLevel.Container())
+}
+
 func (s *pathUtilsSuite) TestToReversedString(c *chk.C) {
 	t := &benchmarkTraverser{}
 	c.Assert("1", chk.Equals, t.toReversedString(1))
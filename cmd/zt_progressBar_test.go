@@ -0,0 +1,100 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type progressBarSuite struct{}
+
+var _ = chk.Suite(&progressBarSuite{})
+
+func (s *progressBarSuite) TestProgressBarRatesAndEta(c *chk.C) {
+	start := time.Now()
+	bar := newProgressBar(start)
+	summary := common.ListJobSummaryResponse{CompleteJobOrdered: true, TotalTransfers: 10, TotalBytesExpected: 100 * base10Mega}
+
+	bar.render(summary, start, 200)
+
+	// 20 MB in 10 seconds, then 20 MB in 2: the window rate is 40 MB / 12 s, so 60 MB remaining should take 18 s
+	summary.TotalBytesTransferred = 20 * base10Mega
+	bar.render(summary, start.Add(10*time.Second), 200)
+	summary.TotalBytesTransferred = 40 * base10Mega
+	summary.TransfersCompleted = 4
+	summary.PercentComplete = 40
+	line := bar.render(summary, start.Add(12*time.Second), 200)
+
+	c.Assert(strings.Contains(line, " 40.0% "), chk.Equals, true, chk.Commentf(line))
+	c.Assert(strings.Contains(line, "4/10 files"), chk.Equals, true, chk.Commentf(line))
+	c.Assert(strings.Contains(line, "10.0 MB/s (avg 3.3 MB/s)"), chk.Equals, true, chk.Commentf(line))
+	c.Assert(strings.Contains(line, "ETA 18s"), chk.Equals, true, chk.Commentf(line))
+
+	// only the last part of the job counts toward the ETA, once it's long enough
+	bar.render(summary, start.Add(time.Minute), 200)
+	c.Assert(len(bar.samples) <= 3, chk.Equals, true)
+}
+
+func (s *progressBarSuite) TestProgressBarNoEtaWhileScanning(c *chk.C) {
+	start := time.Now()
+	bar := newProgressBar(start)
+	summary := common.ListJobSummaryResponse{TotalTransfers: 10, TotalBytesExpected: 100 * base10Mega}
+
+	bar.render(summary, start, 200)
+	summary.TotalBytesTransferred = 20 * base10Mega
+	line := bar.render(summary, start.Add(time.Second), 200)
+
+	// more files may yet be found, so the remaining time isn't known
+	c.Assert(strings.Contains(line, "ETA --"), chk.Equals, true, chk.Commentf(line))
+	c.Assert(strings.Contains(line, "(scanning...)"), chk.Equals, true, chk.Commentf(line))
+}
+
+func (s *progressBarSuite) TestProgressBarShowsThrottling(c *chk.C) {
+	summary := common.ListJobSummaryResponse{CompleteJobOrdered: true, TotalTransfers: 10}
+	line := newProgressBar(time.Now()).render(summary, time.Now(), 200)
+	c.Assert(strings.Contains(line, "throttled"), chk.Equals, false, chk.Commentf(line))
+
+	summary.ThrottledRequests = 42
+	line = newProgressBar(time.Now()).render(summary, time.Now(), 200)
+	c.Assert(strings.Contains(line, "| 42 throttled"), chk.Equals, true, chk.Commentf(line))
+	c.Assert(getThrottledText(summary), chk.Equals, ", 42 Throttled")
+}
+
+func (s *progressBarSuite) TestProgressBarFitsTerminal(c *chk.C) {
+	summary := common.ListJobSummaryResponse{CompleteJobOrdered: true, PercentComplete: 50, TotalTransfers: 10}
+
+	for _, width := range []int{120, 80, 40} {
+		line := newProgressBar(time.Now()).render(summary, time.Now(), width)
+		c.Assert(len(line) < width, chk.Equals, true, chk.Commentf("width %d: %q", width, line))
+	}
+
+	// the bar takes up the spare room
+	line := newProgressBar(time.Now()).render(summary, time.Now(), 120)
+	c.Assert(len(line), chk.Equals, 119)
+
+	c.Assert(renderBar(50, 10), chk.Equals, "[=====>    ]")
+	c.Assert(renderBar(100, 4), chk.Equals, "[====]")
+	c.Assert(renderBar(0, 4), chk.Equals, "[>   ]")
+}
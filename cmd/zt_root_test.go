@@ -0,0 +1,116 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type rootCmdSuite struct{}
+
+var _ = chk.Suite(&rootCmdSuite{})
+
+func (s *rootCmdSuite) TestSetStatusInterval(c *chk.C) {
+	envName := common.EEnvironmentVariable.StatusInterval().Name
+	defer func() {
+		statusIntervalSeconds = 0
+		_ = os.Unsetenv(envName)
+		_ = setStatusInterval() // back to the default
+	}()
+
+	// with neither the flag nor the environment variable, the default applies
+	_ = os.Unsetenv(envName)
+	interval, err := statusInterval()
+	c.Assert(err, chk.IsNil)
+	c.Assert(interval, chk.Equals, time.Duration(0))
+
+	// the environment variable is used when the flag is not given
+	c.Assert(os.Setenv(envName, "30"), chk.IsNil)
+	interval, err = statusInterval()
+	c.Assert(err, chk.IsNil)
+	c.Assert(interval, chk.Equals, 30*time.Second)
+
+	c.Assert(os.Setenv(envName, "soon"), chk.IsNil)
+	c.Assert(setStatusInterval(), chk.NotNil)
+
+	// but the flag wins when it is
+	statusIntervalSeconds = 0.5
+	interval, err = statusInterval()
+	c.Assert(err, chk.IsNil)
+	c.Assert(interval, chk.Equals, 500*time.Millisecond)
+	c.Assert(setStatusInterval(), chk.IsNil)
+
+	// intervals that are too short are lengthened
+	statusIntervalSeconds = 0.001
+	interval, err = statusInterval()
+	c.Assert(err, chk.IsNil)
+	c.Assert(interval, chk.Equals, minStatusInterval)
+
+	statusIntervalSeconds = -1
+	c.Assert(setStatusInterval(), chk.NotNil)
+}
+
+func (s *rootCmdSuite) TestNeedsPlanFolder(c *chk.C) {
+	syncCmd, _, err := rootCmd.Find([]string{"sync"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(syncCmd.ParseFlags([]string{}), chk.IsNil)
+	c.Assert(needsPlanFolder(syncCmd), chk.Equals, true)
+
+	c.Assert(syncCmd.ParseFlags([]string{"--" + planLocationFlagName, "Memory"}), chk.IsNil)
+	flag := syncCmd.Flags().Lookup(planLocationFlagName)
+	defer func() { _ = flag.Value.Set(flag.DefValue) }()
+	c.Assert(needsPlanFolder(syncCmd), chk.Equals, false)
+
+	// commands that don't run jobs need the folder regardless, e.g. to list the jobs
+	c.Assert(needsPlanFolder(jobsCmd), chk.Equals, true)
+}
+
+func (s *rootCmdSuite) TestSetLogLocation(c *chk.C) {
+	dir, err := ioutil.TempDir("", "logLocation")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	defer func(folder string) {
+		azcopyLogPathFolder = folder
+		logLocation = ""
+	}(azcopyLogPathFolder)
+
+	// without the flag, the folder from the environment (or the default) is kept
+	azcopyLogPathFolder = dir
+	c.Assert(setLogLocation(), chk.IsNil)
+	c.Assert(azcopyLogPathFolder, chk.Equals, dir)
+
+	// with it, the logs go to that folder, which is created if need be
+	logLocation = filepath.Join(dir, "job-logs")
+	c.Assert(setLogLocation(), chk.IsNil)
+	c.Assert(azcopyLogPathFolder, chk.Equals, logLocation)
+	info, err := os.Stat(logLocation)
+	c.Assert(err, chk.IsNil)
+	c.Assert(info.IsDir(), chk.Equals, true)
+
+	jobID := common.NewJobID()
+	c.Assert(jobLogFilePath(jobID), chk.Equals, filepath.Join(logLocation, jobID.String()+".log"))
+}
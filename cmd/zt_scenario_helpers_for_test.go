@@ -692,7 +692,7 @@ func validateCopyTransfersAreScheduled(c *chk.C, isSrcEncoded bool, isDstEncoded
 			if runtime.GOOS == "windows" {
 				// Decode unsafe dst characters on windows
 				pathParts := strings.Split(dstRelativeFilePath, "/")
-				invalidChars := `<>\/:"|?*` + string(0x00)
+				invalidChars := `<>\/:"|?*` + string(rune(0x00))
 
 				for _, c := range strings.Split(invalidChars, "") {
 					for k, p := range pathParts {
@@ -750,8 +750,10 @@ func getDefaultSyncRawInput(src, dst string) rawSyncCmdArgs {
 		dst:                 dst,
 		recursive:           true,
 		logVerbosity:        defaultLogVerbosityForSync,
+		priority:            common.EJobPriority.Normal().String(),
 		deleteDestination:   deleteDestination.String(),
 		md5ValidationOption: common.DefaultHashValidationOption.String(),
+		compareHash:         common.ESyncHashType.None().String(),
 	}
 }
 
@@ -760,11 +762,13 @@ func getDefaultCopyRawInput(src string, dst string) rawCopyCmdArgs {
 		src:                            src,
 		dst:                            dst,
 		logVerbosity:                   defaultLogVerbosityForSync,
+		priority:                       common.EJobPriority.Normal().String(),
 		blobType:                       common.EBlobType.Detect().String(),
 		blockBlobTier:                  common.EBlockBlobTier.None().String(),
 		pageBlobTier:                   common.EPageBlobTier.None().String(),
 		md5ValidationOption:            common.DefaultHashValidationOption.String(),
 		s2sInvalidMetadataHandleOption: defaultS2SInvalideMetadataHandleOption.String(),
+		s2sMetadataMerge:               common.EMetadataMergeOption.Overwrite().String(),
 		forceWrite:                     common.EOverwriteOption.True().String(),
 		preserveOwner:                  common.PreserveOwnerDefault,
 	}
@@ -784,11 +788,13 @@ func getDefaultRemoveRawInput(src string) rawCopyCmdArgs {
 		src:                            src,
 		fromTo:                         fromTo.String(),
 		logVerbosity:                   defaultLogVerbosityForSync,
+		priority:                       common.EJobPriority.Normal().String(),
 		blobType:                       common.EBlobType.Detect().String(),
 		blockBlobTier:                  common.EBlockBlobTier.None().String(),
 		pageBlobTier:                   common.EPageBlobTier.None().String(),
 		md5ValidationOption:            common.DefaultHashValidationOption.String(),
 		s2sInvalidMetadataHandleOption: defaultS2SInvalideMetadataHandleOption.String(),
+		s2sMetadataMerge:               common.EMetadataMergeOption.Overwrite().String(),
 		forceWrite:                     common.EOverwriteOption.True().String(),
 		preserveOwner:                  common.PreserveOwnerDefault,
 		includeDirectoryStubs:          true,
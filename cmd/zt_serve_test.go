@@ -0,0 +1,153 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type serveSuite struct{}
+
+var _ = chk.Suite(&serveSuite{})
+
+// newTestControlServer returns a server that records the commands it runs, and answers them with the given output
+func newTestControlServer(output ...common.JsonOutputTemplate) (*controlServer, *[][]string) {
+	var commands [][]string
+	s := &controlServer{token: "secret"}
+	s.run = func(args []string) ([]common.JsonOutputTemplate, error) {
+		commands = append(commands, args)
+		return output, nil
+	}
+	s.start = func(args []string) (<-chan common.JsonOutputTemplate, error) {
+		commands = append(commands, args)
+		messages := make(chan common.JsonOutputTemplate, len(output))
+		for _, m := range output {
+			messages <- m
+		}
+		close(messages)
+		return messages, nil
+	}
+	return s, &commands
+}
+
+func controlRequest(s *controlServer, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func (s *serveSuite) TestControlServerRequiresToken(c *chk.C) {
+	server, commands := newTestControlServer()
+
+	r := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	c.Assert(w.Code, chk.Equals, http.StatusUnauthorized)
+	c.Assert(*commands, chk.HasLen, 0)
+}
+
+func (s *serveSuite) TestControlServerRunsJobCommands(c *chk.C) {
+	jobID := common.NewJobID()
+	endOfJob := common.JsonOutputTemplate{MessageType: jsonMessageTypeEndOfJob, Payload: json.RawMessage(`{"JobID":"x"}`)}
+
+	cases := []struct {
+		method, path string
+		expected     []string
+	}{
+		{http.MethodGet, "/jobs", []string{"jobs", "list"}},
+		{http.MethodGet, "/jobs/" + jobID.String(), []string{"jobs", "show", jobID.String()}},
+		{http.MethodPost, "/jobs/" + jobID.String() + "/pause", []string{"jobs", "pause", jobID.String()}},
+		{http.MethodPost, "/jobs/" + jobID.String() + "/cancel", []string{"cancel", jobID.String()}},
+	}
+	for _, x := range cases {
+		server, commands := newTestControlServer(endOfJob)
+		w := controlRequest(server, x.method, x.path, "")
+
+		c.Assert(w.Code, chk.Equals, http.StatusOK)
+		c.Assert(strings.TrimSpace(w.Body.String()), chk.Equals, `{"JobID":"x"}`)
+		c.Assert(*commands, chk.DeepEquals, [][]string{x.expected})
+	}
+}
+
+func (s *serveSuite) TestControlServerReportsErrors(c *chk.C) {
+	server, _ := newTestControlServer(
+		common.JsonOutputTemplate{MessageType: "Info", MessageContent: "working"},
+		common.JsonOutputTemplate{MessageType: jsonMessageTypeError, MessageContent: "no such job"})
+
+	w := controlRequest(server, http.MethodGet, "/jobs/"+common.NewJobID().String(), "")
+	c.Assert(w.Code, chk.Equals, http.StatusBadRequest)
+
+	var resp controlErrorResponse
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &resp), chk.IsNil)
+	c.Assert(resp.Error, chk.Equals, "no such job")
+}
+
+func (s *serveSuite) TestControlServerRejectsBadRequests(c *chk.C) {
+	server, commands := newTestControlServer()
+
+	c.Assert(controlRequest(server, http.MethodGet, "/jobs/not-a-job-id", "").Code, chk.Equals, http.StatusBadRequest)
+	c.Assert(controlRequest(server, http.MethodGet, "/other", "").Code, chk.Equals, http.StatusNotFound)
+	c.Assert(controlRequest(server, http.MethodDelete, "/jobs", "").Code, chk.Equals, http.StatusMethodNotAllowed)
+	c.Assert(controlRequest(server, http.MethodPost, "/jobs/"+common.NewJobID().String()+"/explode", "").Code, chk.Equals, http.StatusNotFound)
+
+	// cancelling transfers needs the sources of the transfers
+	c.Assert(controlRequest(server, http.MethodPost, "/jobs/"+common.NewJobID().String()+"/cancel-transfers", `{"Sources": []}`).Code, chk.Equals, http.StatusBadRequest)
+
+	// only commands that run jobs can be submitted
+	c.Assert(controlRequest(server, http.MethodPost, "/jobs", `{"Args": ["login"]}`).Code, chk.Equals, http.StatusBadRequest)
+	c.Assert(controlRequest(server, http.MethodPost, "/jobs", `not json`).Code, chk.Equals, http.StatusBadRequest)
+	c.Assert(*commands, chk.HasLen, 0)
+}
+
+func (s *serveSuite) TestControlServerCancelsTransfers(c *chk.C) {
+	jobID := common.NewJobID()
+	server, commands := newTestControlServer(common.JsonOutputTemplate{MessageType: jsonMessageTypeEndOfJob, MessageContent: "done"})
+
+	w := controlRequest(server, http.MethodPost, "/jobs/"+jobID.String()+"/cancel-transfers", `{"Sources": ["videos/huge.mp4", "-odd-name"]}`)
+	c.Assert(w.Code, chk.Equals, http.StatusOK)
+	c.Assert(*commands, chk.DeepEquals, [][]string{{"jobs", "cancel-transfers", jobID.String(), "--", "videos/huge.mp4", "-odd-name"}})
+}
+
+func (s *serveSuite) TestControlServerSubmitsJob(c *chk.C) {
+	server, commands := newTestControlServer(
+		common.JsonOutputTemplate{MessageType: jsonMessageTypeInit, Payload: json.RawMessage(`{"JobID":"y"}`)},
+		common.JsonOutputTemplate{MessageType: "Progress"})
+
+	w := controlRequest(server, http.MethodPost, "/jobs", `{"Args": ["copy", "/data", "/elsewhere", "--recursive"]}`)
+	c.Assert(w.Code, chk.Equals, http.StatusAccepted)
+	c.Assert(strings.TrimSpace(w.Body.String()), chk.Equals, `{"JobID":"y"}`)
+	c.Assert(*commands, chk.DeepEquals, [][]string{{"copy", "/data", "/elsewhere", "--recursive"}})
+
+	// a job that fails before it starts
+	server, _ = newTestControlServer(common.JsonOutputTemplate{MessageType: jsonMessageTypeError, MessageContent: "bad source"})
+	w = controlRequest(server, http.MethodPost, "/jobs", `{"Args": ["sync", "/data", "/elsewhere"]}`)
+	c.Assert(w.Code, chk.Equals, http.StatusBadRequest)
+}
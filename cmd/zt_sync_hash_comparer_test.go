@@ -0,0 +1,93 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"crypto/md5"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncHashComparerSuite struct{}
+
+var _ = chk.Suite(&syncHashComparerSuite{})
+
+func (s *syncHashComparerSuite) TestComparesLocalFilesByContent(c *chk.C) {
+	srcDir, err := ioutil.TempDir("", "hashcomparer")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(srcDir)
+	content := []byte("same content")
+	c.Assert(ioutil.WriteFile(filepath.Join(srcDir, "a.txt"), content, 0600), chk.IsNil)
+
+	h := &syncHashComparer{localRoot: srcDir, localIsSource: true}
+	sameHash := md5.Sum(content)
+	otherHash := md5.Sum([]byte("othr content"))
+	now := time.Now()
+	source := storedObject{relativePath: "a.txt", entityType: common.EEntityType.File(), size: int64(len(content)), lastModifiedTime: now}
+
+	// a newer source with the same content isn't transferred, and one with different content is, however old it is
+	c.Assert(h.isStale(source, storedObject{relativePath: "a.txt", size: source.size, md5: sameHash[:], lastModifiedTime: now.Add(-time.Hour)}), chk.Equals, false)
+	c.Assert(h.isStale(source, storedObject{relativePath: "a.txt", size: source.size, md5: otherHash[:], lastModifiedTime: now.Add(time.Hour)}), chk.Equals, true)
+
+	// without a hash at the destination, the times decide
+	c.Assert(h.isStale(source, storedObject{relativePath: "a.txt", size: source.size, lastModifiedTime: now.Add(-time.Hour)}), chk.Equals, true)
+	c.Assert(h.isStale(source, storedObject{relativePath: "a.txt", size: source.size, lastModifiedTime: now.Add(time.Hour)}), chk.Equals, false)
+}
+
+func (s *syncHashComparerSuite) TestLocalHashCache(c *chk.C) {
+	cacheDir, err := ioutil.TempDir("", "hashcache")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(cacheDir)
+
+	now := time.Now()
+	kept := storedObject{relativePath: "kept.txt", size: 1, lastModifiedTime: now}
+	gone := storedObject{relativePath: "gone.txt", size: 1, lastModifiedTime: now}
+	cache := loadLocalHashCache(cacheDir, "/data")
+	cache.put(kept, []byte{1})
+	cache.put(gone, []byte{2})
+	c.Assert(cache.save(), chk.IsNil)
+
+	// the next sync finds the hash, as long as the file hasn't changed
+	cache = loadLocalHashCache(cacheDir, "/data")
+	c.Assert(cache.get(kept), chk.DeepEquals, []byte{1})
+	changed := kept
+	changed.lastModifiedTime = now.Add(time.Second)
+	c.Assert(cache.get(changed), chk.IsNil)
+	c.Assert(cache.save(), chk.IsNil)
+
+	// only the hashes that were used are kept, and each directory has a cache of its own
+	cache = loadLocalHashCache(cacheDir, "/data")
+	c.Assert(cache.get(gone), chk.IsNil)
+	c.Assert(cache.get(kept), chk.DeepEquals, []byte{1})
+	c.Assert(loadLocalHashCache(cacheDir, "/other").get(kept), chk.IsNil)
+
+	// a nil cache holds nothing
+	var none *localHashCache
+	none.put(kept, []byte{1})
+	c.Assert(none.get(kept), chk.IsNil)
+	c.Assert(none.save(), chk.IsNil)
+}
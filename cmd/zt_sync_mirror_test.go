@@ -0,0 +1,92 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncMirrorSuite struct{}
+
+var _ = chk.Suite(&syncMirrorSuite{})
+
+func (s *syncMirrorSuite) TestDifferences(c *chk.C) {
+	then, now := time.Now().Add(-time.Hour), time.Now()
+	m := newSyncMirrorComparer(&cookedSyncCmdArgs{fromTo: common.EFromTo.BlobBlob(), conflictResolution: common.ESyncConflictResolution.SourceWins()}, nil)
+	source := twoWayTestFile("a", 1, then)
+	source.md5 = []byte{1}
+	source.contentType = "text/plain"
+	source.Metadata = common.Metadata{"owner": "x"}
+
+	destination := source
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "")
+
+	destination.size = 2
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "size")
+
+	destination = source
+	destination.md5 = []byte{2}
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "content")
+
+	// without hashes, only a newer source is taken to have changed
+	source.md5, destination.md5 = nil, nil
+	destination.lastModifiedTime = then.Add(-time.Minute)
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "last-modified time")
+	destination.lastModifiedTime = now
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "")
+
+	destination.contentType = "application/octet-stream"
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "properties")
+
+	destination = source
+	destination.Metadata = common.Metadata{"owner": "y"}
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "metadata")
+
+	// local files have no properties or metadata to compare
+	m = newSyncMirrorComparer(&cookedSyncCmdArgs{fromTo: common.EFromTo.LocalBlob()}, nil)
+	c.Assert(m.differenceOf(source, destination), chk.Equals, "")
+}
+
+func (s *syncMirrorSuite) TestConflictsAndReport(c *chk.C) {
+	then, now := time.Now().Add(-time.Hour), time.Now()
+	source := twoWayTestFile("a", 1, then)
+	newerDestination := twoWayTestFile("a", 2, now)
+
+	m := newSyncMirrorComparer(&cookedSyncCmdArgs{fromTo: common.EFromTo.BlobLocal(), conflictResolution: common.ESyncConflictResolution.SourceWins()}, nil)
+	c.Assert(m.isStale(source, newerDestination), chk.Equals, true)
+	c.Assert(m.isStale(source, twoWayTestFile("a", 1, now)), chk.Equals, false)
+
+	scheduled := 0
+	schedule := m.countTransfers(func(storedObject) error { scheduled++; return nil })
+	c.Assert(schedule(source), chk.IsNil) // the one that differed
+	c.Assert(schedule(twoWayTestFile("new", 1, now)), chk.IsNil)
+	c.Assert(scheduled, chk.Equals, 2)
+	c.Assert(m.report(3), chk.Equals, "Mirror report: 1 files were the same at both ends, 1 were missing from the destination, and 1 differed (1 in size). "+
+		"3 extra files were at the destination. 1 files had been modified at the destination after the source: 1 were overwritten, and 0 kept.")
+
+	m = newSyncMirrorComparer(&cookedSyncCmdArgs{fromTo: common.EFromTo.BlobLocal(), conflictResolution: common.ESyncConflictResolution.NewestWins()}, nil)
+	c.Assert(m.isStale(source, newerDestination), chk.Equals, false)
+	c.Assert(m.keptNewerCount, chk.Equals, uint64(1))
+}
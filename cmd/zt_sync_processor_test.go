@@ -134,3 +134,45 @@ func (s *syncProcessorSuite) TestFileDeleter(c *chk.C) {
 	_, err = fileURL.GetProperties(context.Background())
 	c.Assert(err, chk.NotNil)
 }
+
+func (s *syncProcessorSuite) TestPendingDeletionsRespectThreshold(c *chk.C) {
+	dstDirName := scenarioHelper{}.generateLocalDirectory(c)
+	defer os.RemoveAll(dstDirName)
+	extraFiles := []string{"extra1.txt", "extra2.txt"}
+	scenarioHelper{}.generateLocalFilesFromList(c, dstDirName, extraFiles)
+
+	cca := &cookedSyncCmdArgs{
+		destination:                   newLocalRes(dstDirName),
+		deleteDestination:             common.EDeleteDestination.True(),
+		deleteDestinationThreshold:    50,
+		atomicDestinationFilesScanned: 3,
+	}
+	deleter := newSyncLocalDeleteProcessor(cca)
+
+	// 2 of 3 files is more than half of the destination, so nothing goes
+	pending := newPendingDeletions(cca)
+	for _, name := range extraFiles {
+		c.Assert(pending.add(storedObject{relativePath: name, entityType: common.EEntityType.File()}), chk.IsNil)
+	}
+	c.Assert(pending.deleteAll(deleter.removeImmediately), chk.ErrorMatches, ".*2 of the 3 files.*more than the --delete-destination-threshold of 50%.*")
+	for _, name := range extraFiles {
+		_, err := os.Stat(filepath.Join(dstDirName, name))
+		c.Assert(err, chk.IsNil)
+	}
+
+	// with the threshold raised, they are deleted
+	cca.deleteDestinationThreshold = 70
+	c.Assert(pending.deleteAll(deleter.removeImmediately), chk.IsNil)
+	for _, name := range extraFiles {
+		_, err := os.Stat(filepath.Join(dstDirName, name))
+		c.Assert(err, chk.NotNil)
+	}
+	c.Assert(cca.getDeletionCount(), chk.Equals, uint32(2))
+}
+
+func (s *syncProcessorSuite) TestCheckDeletionThreshold(c *chk.C) {
+	c.Assert(checkDeletionThreshold(100, 100, 0), chk.IsNil)
+	c.Assert(checkDeletionThreshold(10, 100, 10), chk.IsNil)
+	c.Assert(checkDeletionThreshold(11, 100, 10), chk.NotNil)
+	c.Assert(checkDeletionThreshold(0, 0, 10), chk.IsNil)
+}
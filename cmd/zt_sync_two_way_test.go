@@ -0,0 +1,221 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type syncTwoWaySuite struct{}
+
+var _ = chk.Suite(&syncTwoWaySuite{})
+
+func twoWayTestFile(relativePath string, size int64, lastModified time.Time) storedObject {
+	return storedObject{name: relativePath, relativePath: relativePath, entityType: common.EEntityType.File(), size: size, lastModifiedTime: lastModified}
+}
+
+func relativePathsOf(objects []storedObject) []string {
+	paths := make([]string, 0)
+	for _, object := range objects {
+		paths = append(paths, object.relativePath)
+	}
+	return paths
+}
+
+func (s *syncTwoWaySuite) TestPlanCopiesTheChangesAtEachEnd(c *chk.C) {
+	then, now := time.Now().Add(-time.Hour), time.Now()
+	state := &twoWaySyncState{Files: make(map[string]twoWaySyncStateEntry)}
+	state.record(map[string]storedObject{
+		"same":                 twoWayTestFile("same", 1, then),
+		"changedAtSource":      twoWayTestFile("changedAtSource", 1, then),
+		"changedAtDestination": twoWayTestFile("changedAtDestination", 1, then),
+		"goneFromSource":       twoWayTestFile("goneFromSource", 1, then),
+		"goneFromDestination":  twoWayTestFile("goneFromDestination", 1, then),
+	}, map[string]storedObject{
+		"same":                 twoWayTestFile("same", 1, then),
+		"changedAtSource":      twoWayTestFile("changedAtSource", 1, then),
+		"changedAtDestination": twoWayTestFile("changedAtDestination", 1, then),
+		"goneFromSource":       twoWayTestFile("goneFromSource", 1, then),
+		"goneFromDestination":  twoWayTestFile("goneFromDestination", 1, then),
+	}, nil)
+
+	sourceFiles := map[string]storedObject{
+		"same":                 twoWayTestFile("same", 1, then),
+		"changedAtSource":      twoWayTestFile("changedAtSource", 2, now),
+		"changedAtDestination": twoWayTestFile("changedAtDestination", 1, then),
+		"goneFromDestination":  twoWayTestFile("goneFromDestination", 1, then),
+		"newAtSource":          twoWayTestFile("newAtSource", 1, now),
+	}
+	destinationFiles := map[string]storedObject{
+		"same":                 twoWayTestFile("same", 1, then),
+		"changedAtSource":      twoWayTestFile("changedAtSource", 1, then),
+		"changedAtDestination": twoWayTestFile("changedAtDestination", 1, now),
+		"goneFromSource":       twoWayTestFile("goneFromSource", 1, then),
+		"newAtDestination":     twoWayTestFile("newAtDestination", 1, now),
+	}
+
+	plan := planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.NewestWins(), true)
+	c.Assert(relativePathsOf(plan.toDestination), chk.DeepEquals, []string{"changedAtSource", "newAtSource"})
+	c.Assert(relativePathsOf(plan.toSource), chk.DeepEquals, []string{"changedAtDestination", "newAtDestination"})
+	c.Assert(relativePathsOf(plan.deleteAtSource), chk.DeepEquals, []string{"goneFromDestination"})
+	c.Assert(relativePathsOf(plan.deleteAtDestination), chk.DeepEquals, []string{"goneFromSource"})
+	c.Assert(plan.conflicts, chk.HasLen, 0)
+
+	// without deletions, what's gone from one end is copied back from the other
+	plan = planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.NewestWins(), false)
+	c.Assert(relativePathsOf(plan.toDestination), chk.DeepEquals, []string{"changedAtSource", "goneFromDestination", "newAtSource"})
+	c.Assert(relativePathsOf(plan.toSource), chk.DeepEquals, []string{"changedAtDestination", "goneFromSource", "newAtDestination"})
+	c.Assert(plan.deleteAtSource, chk.HasLen, 0)
+	c.Assert(plan.deleteAtDestination, chk.HasLen, 0)
+
+	// a file changed at one end isn't deleted because it has gone from the other
+	sourceFiles["goneFromDestination"] = twoWayTestFile("goneFromDestination", 3, now)
+	plan = planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.NewestWins(), true)
+	c.Assert(relativePathsOf(plan.toDestination), chk.DeepEquals, []string{"changedAtSource", "goneFromDestination", "newAtSource"})
+	c.Assert(plan.deleteAtSource, chk.HasLen, 0)
+}
+
+func (s *syncTwoWaySuite) TestPlanResolvesConflicts(c *chk.C) {
+	then, now := time.Now().Add(-time.Hour), time.Now()
+	state := &twoWaySyncState{Files: map[string]twoWaySyncStateEntry{
+		"dir/report.docx": {Source: twoWaySyncFileVersion{Size: 1, LastModified: then}, Destination: twoWaySyncFileVersion{Size: 1, LastModified: then}},
+	}}
+	sourceFiles := map[string]storedObject{"dir/report.docx": twoWayTestFile("dir/report.docx", 2, now.Add(-time.Minute))}
+	destinationFiles := map[string]storedObject{"dir/report.docx": twoWayTestFile("dir/report.docx", 3, now)}
+
+	plan := planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.NewestWins(), true)
+	c.Assert(plan.conflicts, chk.DeepEquals, []string{"dir/report.docx"})
+	c.Assert(plan.toDestination, chk.HasLen, 0)
+	c.Assert(relativePathsOf(plan.toSource), chk.DeepEquals, []string{"dir/report.docx"})
+
+	plan = planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.SourceWins(), true)
+	c.Assert(relativePathsOf(plan.toDestination), chk.DeepEquals, []string{"dir/report.docx"})
+	c.Assert(plan.toSource, chk.HasLen, 0)
+
+	plan = planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.RenameBoth(), true)
+	c.Assert(plan.toDestination, chk.HasLen, 1)
+	c.Assert(plan.toDestination[0].dstRelativePath, chk.Equals, "dir/report.source-conflict.docx")
+	c.Assert(plan.toSource, chk.HasLen, 1)
+	c.Assert(plan.toSource[0].dstRelativePath, chk.Equals, "dir/report.destination-conflict.docx")
+	c.Assert(plan.renamed, chk.DeepEquals, []string{"dir/report.docx"})
+
+	// on the first sync, files of the same size are taken to be the same, and others are conflicts
+	noState := &twoWaySyncState{Files: make(map[string]twoWaySyncStateEntry)}
+	plan = planTwoWaySync(sourceFiles, map[string]storedObject{"dir/report.docx": twoWayTestFile("dir/report.docx", 2, now)}, noState,
+		common.ESyncConflictResolution.NewestWins(), true)
+	c.Assert(plan.toDestination, chk.HasLen, 0)
+	c.Assert(plan.toSource, chk.HasLen, 0)
+	plan = planTwoWaySync(sourceFiles, destinationFiles, noState, common.ESyncConflictResolution.NewestWins(), true)
+	c.Assert(plan.conflicts, chk.HasLen, 1)
+}
+
+func (s *syncTwoWaySuite) TestRenamedConflictStaysInConflict(c *chk.C) {
+	then, now := time.Now().Add(-time.Hour), time.Now()
+	sourceFiles := map[string]storedObject{"report.docx": twoWayTestFile("report.docx", 2, then)}
+	destinationFiles := map[string]storedObject{"report.docx": twoWayTestFile("report.docx", 3, then)}
+	state := &twoWaySyncState{Files: make(map[string]twoWaySyncStateEntry)}
+
+	plan := planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.RenameBoth(), true)
+	c.Assert(plan.unresolved(), chk.DeepEquals, []string{"report.docx"})
+	state.record(sourceFiles, destinationFiles, plan.unresolved())
+	c.Assert(state.Files["report.docx"].Conflict, chk.Equals, true)
+
+	// the next sync reports it again, rather than taking the two versions to be in sync, but doesn't copy them again
+	plan = planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.RenameBoth(), true)
+	c.Assert(plan.stillInConflict, chk.DeepEquals, []string{"report.docx"})
+	c.Assert(plan.toDestination, chk.HasLen, 0)
+	c.Assert(plan.toSource, chk.HasLen, 0)
+
+	// until it changes at one end, which resolves the conflict
+	sourceFiles["report.docx"] = twoWayTestFile("report.docx", 4, now)
+	plan = planTwoWaySync(sourceFiles, destinationFiles, state, common.ESyncConflictResolution.RenameBoth(), true)
+	c.Assert(plan.unresolved(), chk.HasLen, 0)
+	c.Assert(relativePathsOf(plan.toDestination), chk.DeepEquals, []string{"report.docx"})
+	c.Assert(plan.toDestination[0].dstRelativePath, chk.Equals, "")
+}
+
+func (s *syncTwoWaySuite) TestAgainCopiesTheSameWay(c *chk.C) {
+	forward := &cookedSyncCmdArgs{
+		source:      common.ResourceString{Value: "/data"},
+		destination: common.ResourceString{Value: "https://a.blob.core.windows.net/c"},
+		fromTo:      common.EFromTo.LocalBlob(),
+		putMd5:      true,
+		jobID:       common.NewJobID(),
+	}
+	reversed := forward.reversedForTwoWaySync()
+	for _, original := range []*cookedSyncCmdArgs{forward, reversed} {
+		again := original.againForTwoWaySync()
+		c.Assert(again.fromTo, chk.Equals, original.fromTo)
+		c.Assert(again.source, chk.Equals, original.source)
+		c.Assert(again.putMd5, chk.Equals, original.putMd5)
+		c.Assert(again.jobID, chk.Not(chk.Equals), original.jobID)
+	}
+}
+
+func (s *syncTwoWaySuite) TestStateIsKeptPerPair(c *chk.C) {
+	folder, err := ioutil.TempDir("", "syncstate")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(folder)
+
+	source := common.ResourceString{Value: "https://a.blob.core.windows.net/c", SAS: "sig=1"}
+	destination := common.ResourceString{Value: "https://b.blob.core.windows.net/c"}
+	state, err := loadTwoWaySyncState(folder, source, destination, common.EFromTo.BlobBlob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(state.Files, chk.HasLen, 0)
+
+	now := time.Now()
+	state.record(map[string]storedObject{"a": twoWayTestFile("a", 1, now), "onlyAtSource": twoWayTestFile("onlyAtSource", 1, now)},
+		map[string]storedObject{"a": twoWayTestFile("a", 1, now)}, nil)
+	c.Assert(state.save(), chk.IsNil)
+
+	// the SAS doesn't matter, but which end is which does
+	source.SAS = "sig=2"
+	state, err = loadTwoWaySyncState(folder, source, destination, common.EFromTo.BlobBlob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(state.Files, chk.HasLen, 1)
+	c.Assert(state.Files["a"].Source.matches(twoWayTestFile("a", 1, now)), chk.Equals, true)
+	state, err = loadTwoWaySyncState(folder, destination, source, common.EFromTo.BlobBlob())
+	c.Assert(err, chk.IsNil)
+	c.Assert(state.Files, chk.HasLen, 0)
+}
+
+func (s *syncTwoWaySuite) TestReversedJobCopiesTheOtherWay(c *chk.C) {
+	forward := &cookedSyncCmdArgs{
+		source:      common.ResourceString{Value: "/data"},
+		destination: common.ResourceString{Value: "https://a.blob.core.windows.net/c"},
+		fromTo:      common.EFromTo.LocalBlob(),
+		putMd5:      true,
+		jobID:       common.NewJobID(),
+	}
+	reversed := forward.reversedForTwoWaySync()
+	c.Assert(reversed.fromTo, chk.Equals, common.EFromTo.BlobLocal())
+	c.Assert(reversed.source, chk.Equals, forward.destination)
+	c.Assert(reversed.destination, chk.Equals, forward.source)
+	c.Assert(reversed.jobID, chk.Not(chk.Equals), forward.jobID)
+	c.Assert(forward.putMd5, chk.Equals, true)
+	c.Assert(reversed.putMd5, chk.Equals, false)
+}
@@ -0,0 +1,127 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+
+	chk "gopkg.in/check.v1"
+)
+
+type templatesSuite struct{}
+
+var _ = chk.Suite(&templatesSuite{})
+
+// withTemplatesFolder points the AzCopy folder, and so the templates folder, at a fresh temp folder
+func (s *templatesSuite) withTemplatesFolder(c *chk.C, test func()) {
+	dir, err := ioutil.TempDir("", "templatestest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	old := azcopyAppPathFolder
+	azcopyAppPathFolder = dir
+	defer func() { azcopyAppPathFolder = old }()
+	test()
+}
+
+func (s *templatesSuite) TestExpand(c *chk.C) {
+	t := jobTemplate{Name: "nightly", Args: []string{"sync", "/data/{{folder}}", "https://a.blob.core.windows.net/b/{{ folder }}?{{sas}}", "--recursive"}}
+	c.Assert(t.Parameters(), chk.DeepEquals, []string{"folder", "sas"})
+
+	expanded, err := t.expand(map[string]string{"folder": "projects", "sas": "sv=1&sig=2"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(expanded, chk.DeepEquals, []string{"sync", "/data/projects", "https://a.blob.core.windows.net/b/projects?sv=1&sig=2", "--recursive"})
+	c.Assert(t.Args[1], chk.Equals, "/data/{{folder}}") // the template itself is unchanged
+
+	_, err = t.expand(map[string]string{"folder": "projects"})
+	c.Assert(err, chk.ErrorMatches, ".*needs a value for sas.*")
+	_, err = t.expand(map[string]string{"folder": "projects", "sas": "x", "fodler": "y"})
+	c.Assert(err, chk.ErrorMatches, ".*has no parameter named fodler.*")
+}
+
+func (s *templatesSuite) TestSaveLoadAndList(c *chk.C) {
+	s.withTemplatesFolder(c, func() {
+		templates, err := listJobTemplates()
+		c.Assert(err, chk.IsNil)
+		c.Assert(templates, chk.HasLen, 0) // not even the folder exists yet
+
+		b := jobTemplate{Name: "b", Args: []string{"copy", "/src/{{x}}", "/dst"}}
+		a := jobTemplate{Name: "a", Args: []string{"remove", "https://a.blob.core.windows.net/c/{{x}}"}}
+		c.Assert(saveJobTemplate(b, false), chk.IsNil)
+		c.Assert(saveJobTemplate(a, false), chk.IsNil)
+
+		loaded, err := loadJobTemplate("b")
+		c.Assert(err, chk.IsNil)
+		c.Assert(loaded, chk.DeepEquals, b)
+
+		// only with overwrite can a template be replaced
+		b2 := jobTemplate{Name: "b", Args: []string{"copy", "/other", "/dst"}}
+		c.Assert(saveJobTemplate(b2, false), chk.NotNil)
+		c.Assert(saveJobTemplate(b2, true), chk.IsNil)
+
+		templates, err = listJobTemplates()
+		c.Assert(err, chk.IsNil)
+		c.Assert(templates, chk.DeepEquals, []jobTemplate{a, b2})
+
+		_, err = loadJobTemplate("c")
+		c.Assert(err, chk.NotNil)
+	})
+}
+
+func (s *templatesSuite) TestSaveRejectsBadTemplates(c *chk.C) {
+	s.withTemplatesFolder(c, func() {
+		c.Assert(saveJobTemplate(jobTemplate{Name: "../escape", Args: []string{"copy", "a", "b"}}, false), chk.NotNil)
+		c.Assert(saveJobTemplate(jobTemplate{Name: "", Args: []string{"copy", "a", "b"}}, false), chk.NotNil)
+		c.Assert(saveJobTemplate(jobTemplate{Name: "login", Args: []string{"login"}}, false), chk.NotNil)
+		_, err := loadJobTemplate("../escape")
+		c.Assert(err, chk.NotNil)
+	})
+}
+
+func (s *templatesSuite) TestRunCommandLine(c *chk.C) {
+	c.Assert(isRunCommand([]string{"run", "nightly"}), chk.Equals, true)
+	c.Assert(isRunCommand([]string{"run", "--help"}), chk.Equals, false)
+	c.Assert(isRunCommand([]string{"run"}), chk.Equals, false)
+	c.Assert(isRunCommand([]string{"copy", "a", "b"}), chk.Equals, false)
+
+	s.withTemplatesFolder(c, func() {
+		c.Assert(saveJobTemplate(jobTemplate{Name: "nightly", Args: []string{"sync", "/data/{{folder}}", "/backup/{{folder}}", "--recursive"}}, false), chk.IsNil)
+
+		args, err := expandRunCommand([]string{"nightly", "--param", "folder=a=b", "--dry-run", "--output-type", "json"})
+		c.Assert(err, chk.IsNil)
+		c.Assert(args, chk.DeepEquals, []string{"sync", "/data/a=b", "/backup/a=b", "--recursive", "--dry-run", "--output-type", "json"})
+
+		args, err = expandRunCommand([]string{"nightly", "--param=folder=x"})
+		c.Assert(err, chk.IsNil)
+		c.Assert(args, chk.DeepEquals, []string{"sync", "/data/x", "/backup/x", "--recursive"})
+
+		for _, bad := range [][]string{
+			{"nightly", "--param"},
+			{"nightly", "--param", "folder"},
+			{"nightly", "--param", "folder=x", "--param", "folder=y"},
+			{"nightly"},
+			{"weekly", "--param", "folder=x"},
+		} {
+			_, err = expandRunCommand(bad)
+			c.Assert(err, chk.NotNil, chk.Commentf("%v", bad))
+		}
+	})
+}
@@ -0,0 +1,275 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type blobSnapshotsTraverserSuite struct{}
+
+var _ = chk.Suite(&blobSnapshotsTraverserSuite{})
+
+// fakeSnapshotBlobService serves just enough of the Blob service for a traverser to list the blobs of one container
+// with their snapshots or versions, or the ones that are soft-deleted. Like the real service, it doesn't list snapshots
+// or versions in hierarchical listings, and lists versions oldest first, the last being the current one
+type fakeSnapshotBlobService struct {
+	blobs     []string
+	snapshots map[string][]string
+	versions  map[string][]string
+	deleted   map[string]bool
+}
+
+func (f *fakeSnapshotBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lmt := time.Date(2020, 5, 4, 3, 2, 1, 0, time.UTC).Format(http.TimeFormat)
+
+	switch {
+	case r.URL.Path == "/account/container" && query.Get("comp") == "list":
+		if (strings.Contains(query.Get("include"), "snapshots") || strings.Contains(query.Get("include"), "versions")) && query.Get("delimiter") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body := "<EnumerationResults><Blobs>"
+		for _, name := range f.blobs {
+			if !strings.HasPrefix(name, query.Get("prefix")) || f.deleted[name] && !strings.Contains(query.Get("include"), "deleted") {
+				continue
+			}
+			if f.deleted[name] {
+				body += fmt.Sprintf("<Blob><Name>%s</Name><Deleted>true</Deleted><Properties><Last-Modified>%s</Last-Modified><Content-Length>5</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>", name, lmt)
+				continue
+			}
+			for _, snapshot := range f.snapshots[name] {
+				body += fmt.Sprintf("<Blob><Name>%s</Name><Snapshot>%s</Snapshot><Properties><Last-Modified>%s</Last-Modified><Content-Length>5</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>", name, snapshot, lmt)
+			}
+			if versions := f.versions[name]; len(versions) > 0 && strings.Contains(query.Get("include"), "versions") {
+				for i, version := range versions {
+					body += fmt.Sprintf("<Blob><Name>%s</Name><VersionId>%s</VersionId><IsCurrentVersion>%t</IsCurrentVersion><Properties><Last-Modified>%s</Last-Modified><Content-Length>5</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>", name, version, i == len(versions)-1, lmt)
+				}
+				continue
+			}
+			body += fmt.Sprintf("<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified><Content-Length>5</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>", name, lmt)
+		}
+		body += "</Blobs><NextMarker/></EnumerationResults>"
+		_, _ = w.Write([]byte(body))
+	case strings.HasPrefix(r.URL.Path, "/account/container/"):
+		name := strings.TrimPrefix(r.URL.Path, "/account/container/")
+		for _, blob := range f.blobs {
+			if blob == name && !f.deleted[blob] {
+				w.Header().Set("Content-Length", "5")
+				w.Header().Set("Last-Modified", lmt)
+				w.Header().Set("x-ms-blob-type", "BlockBlob")
+				return
+			}
+		}
+		w.Header().Set("x-ms-error-code", "BlobNotFound")
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// traverse gives the relative paths (or names, for single blobs) of the objects found, with the IDs of the snapshots
+func (s *blobSnapshotsTraverserSuite) traverse(c *chk.C, path string, recursive bool) []string {
+	service := &fakeSnapshotBlobService{
+		blobs: []string{"dir/a.txt", "dir/a.txt.bak", "dir/sub/b.txt"},
+		snapshots: map[string][]string{
+			"dir/a.txt":     {"2021-01-01T00:00:00.0000000Z", "2021-02-01T00:00:00.0000000Z"},
+			"dir/sub/b.txt": {"2021-03-01T00:00:00.0000000Z"},
+		},
+	}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/account/container/" + path)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	processor := &dummyProcessor{}
+	traverser := newBlobTraverser(u, p, context.Background(), recursive, false, nil)
+	c.Assert(enableSnapshotListing(traverser), chk.IsNil)
+	c.Assert(traverser.traverse(noPreProccessor, processor.process, nil), chk.IsNil)
+
+	found := make([]string, 0)
+	for _, object := range processor.record {
+		found = append(found, strings.TrimSuffix(object.relativePath+"@"+object.blobSnapshotID, "@"))
+	}
+	sort.Strings(found)
+	return found
+}
+
+func (s *blobSnapshotsTraverserSuite) TestSnapshotsAreListedWithTheirBlobs(c *chk.C) {
+	c.Assert(s.traverse(c, "dir/", true), chk.DeepEquals, []string{
+		"a.txt", "a.txt.bak",
+		"a.txt@2021-01-01T00:00:00.0000000Z", "a.txt@2021-02-01T00:00:00.0000000Z",
+		"sub/b.txt", "sub/b.txt@2021-03-01T00:00:00.0000000Z",
+	})
+	c.Assert(s.traverse(c, "dir/", false), chk.DeepEquals, []string{
+		"a.txt", "a.txt.bak",
+		"a.txt@2021-01-01T00:00:00.0000000Z", "a.txt@2021-02-01T00:00:00.0000000Z",
+	})
+}
+
+func (s *blobSnapshotsTraverserSuite) TestSnapshotsOfSingleBlob(c *chk.C) {
+	// only the snapshots of the blob itself, not of others whose names start the same way
+	c.Assert(s.traverse(c, "dir/a.txt", false), chk.DeepEquals, []string{
+		"", "@2021-01-01T00:00:00.0000000Z", "@2021-02-01T00:00:00.0000000Z",
+	})
+}
+
+func (s *blobSnapshotsTraverserSuite) TestSnapshotDestination(c *chk.C) {
+	snapshot := "2021-03-04T05:06:07.1234567Z"
+	c.Assert(snapshotDestination("/dir/report.txt", snapshot), chk.Equals, "/dir/report.2021-03-04T05-06-07.1234567Z.txt")
+	c.Assert(snapshotDestination("/dir.v1/report", snapshot), chk.Equals, "/dir.v1/report.2021-03-04T05-06-07.1234567Z")
+	c.Assert(snapshotDestination("/dir/.profile", snapshot), chk.Equals, "/dir/.profile.2021-03-04T05-06-07.1234567Z")
+
+	object := storedObject{name: "report.txt", relativePath: "dir/report.txt", entityType: common.EEntityType.File(), blobSnapshotID: snapshot}
+	transfer, _ := object.ToNewCopyTransfer(false, "/dir/report.txt", "/dir/report.txt", false, common.EFolderPropertiesOption.NoFolders())
+	c.Assert(transfer.Source, chk.Equals, "/dir/report.txt")
+	c.Assert(transfer.Destination, chk.Equals, "/dir/report.2021-03-04T05-06-07.1234567Z.txt")
+	c.Assert(transfer.BlobSnapshotID, chk.Equals, snapshot)
+}
+
+func (s *blobSnapshotsTraverserSuite) TestValidateIncludeSnapshots(c *chk.C) {
+	container := common.ResourceString{Value: "https://account.blob.core.windows.net/container"}
+	snapshot := common.ResourceString{Value: "https://account.blob.core.windows.net/container/a.txt", ExtraQuery: "snapshot=2021-01-01T00:00:00.0000000Z"}
+
+	c.Assert(validateIncludeSnapshots(false, common.EFromTo.LocalBlob(), container, true), chk.IsNil)
+	c.Assert(validateIncludeSnapshots(true, common.EFromTo.BlobLocal(), container, false), chk.IsNil)
+	c.Assert(validateIncludeSnapshots(true, common.EFromTo.BlobBlob(), container, false), chk.IsNil)
+
+	c.Assert(validateIncludeSnapshots(true, common.EFromTo.FileBlob(), container, false), chk.ErrorMatches, "include-snapshots is only supported when the source is Blob Storage")
+	c.Assert(validateIncludeSnapshots(true, common.EFromTo.BlobLocal(), container, true), chk.ErrorMatches, "include-snapshots can't be used with list-of-versions")
+	c.Assert(validateIncludeSnapshots(true, common.EFromTo.BlobLocal(), snapshot, false), chk.ErrorMatches, "include-snapshots can't be used when the source is a snapshot.*")
+}
+
+// traverseVersions gives the relative paths (or names, for single blobs) of the objects found, with the IDs of the
+// versions, in the order in which they were found
+func (s *blobSnapshotsTraverserSuite) traverseVersions(c *chk.C, path string) []string {
+	service := &fakeSnapshotBlobService{
+		blobs: []string{"dir/a.txt", "dir/a.txt.bak", "dir/b.txt"},
+		versions: map[string][]string{
+			"dir/a.txt":     {"2021-01-01T00:00:00.0000000Z", "2021-02-01T00:00:00.0000000Z", "2021-03-01T00:00:00.0000000Z"},
+			"dir/a.txt.bak": {"2021-01-15T00:00:00.0000000Z"},
+		},
+	}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/account/container/" + path)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	processor := &dummyProcessor{}
+	traverser := newBlobTraverser(u, p, context.Background(), true, false, nil)
+	c.Assert(enableVersionListing(traverser), chk.IsNil)
+	c.Assert(traverser.traverse(noPreProccessor, processor.process, nil), chk.IsNil)
+
+	found := make([]string, 0)
+	for _, object := range processor.record {
+		found = append(found, strings.TrimSuffix(object.relativePath+"@"+object.blobVersionID, "@"))
+	}
+	return found
+}
+
+func (s *blobSnapshotsTraverserSuite) TestVersionsAreListedInOrder(c *chk.C) {
+	// a blob that was never versioned has no version ID, and is copied as it is
+	c.Assert(s.traverseVersions(c, "dir/"), chk.DeepEquals, []string{
+		"a.txt@2021-01-01T00:00:00.0000000Z", "a.txt@2021-02-01T00:00:00.0000000Z", "a.txt@2021-03-01T00:00:00.0000000Z",
+		"a.txt.bak@2021-01-15T00:00:00.0000000Z",
+		"b.txt",
+	})
+}
+
+func (s *blobSnapshotsTraverserSuite) TestVersionsOfSingleBlob(c *chk.C) {
+	// the current version is only found once, by the listing
+	c.Assert(s.traverseVersions(c, "dir/a.txt"), chk.DeepEquals, []string{
+		"@2021-01-01T00:00:00.0000000Z", "@2021-02-01T00:00:00.0000000Z", "@2021-03-01T00:00:00.0000000Z",
+	})
+}
+
+func (s *blobSnapshotsTraverserSuite) TestValidateIncludeVersions(c *chk.C) {
+	container := common.ResourceString{Value: "https://account.blob.core.windows.net/container"}
+	version := common.ResourceString{Value: "https://account.blob.core.windows.net/container/a.txt", ExtraQuery: "versionid=2021-01-01T00:00:00.0000000Z"}
+	overwrite := common.EOverwriteOption.True()
+
+	c.Assert(validateIncludeVersions(false, common.EFromTo.LocalBlob(), container, common.EOverwriteOption.False(), true, true), chk.IsNil)
+	c.Assert(validateIncludeVersions(true, common.EFromTo.BlobBlob(), container, overwrite, false, false), chk.IsNil)
+
+	c.Assert(validateIncludeVersions(true, common.EFromTo.BlobLocal(), container, overwrite, false, false), chk.ErrorMatches, "include-versions is only supported when copying from Blob Storage to Blob Storage")
+	c.Assert(validateIncludeVersions(true, common.EFromTo.BlobBlob(), container, common.EOverwriteOption.IfSourceNewer(), false, false), chk.ErrorMatches, "include-versions writes each version over the one before it.*")
+	c.Assert(validateIncludeVersions(true, common.EFromTo.BlobBlob(), container, overwrite, true, false), chk.ErrorMatches, "include-versions can't be used with list-of-versions")
+	c.Assert(validateIncludeVersions(true, common.EFromTo.BlobBlob(), container, overwrite, false, true), chk.ErrorMatches, "include-versions can't be used with include-snapshots")
+	c.Assert(validateIncludeVersions(true, common.EFromTo.BlobBlob(), version, overwrite, false, false), chk.ErrorMatches, "include-versions can't be used when the source is a snapshot or version.*")
+}
+
+func (s *blobSnapshotsTraverserSuite) TestDeletedBlobsAreListed(c *chk.C) {
+	service := &fakeSnapshotBlobService{
+		blobs:   []string{"dir/a.txt", "dir/b.txt"},
+		deleted: map[string]bool{"dir/b.txt": true},
+	}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/account/container/dir/")
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	traverse := func(includeDeleted bool) map[string]bool {
+		processor := &dummyProcessor{}
+		traverser := newBlobTraverser(u, p, context.Background(), true, false, nil)
+		if includeDeleted {
+			c.Assert(enableDeletedListing(traverser), chk.IsNil)
+		}
+		c.Assert(traverser.traverse(noPreProccessor, processor.process, nil), chk.IsNil)
+
+		found := make(map[string]bool)
+		for _, object := range processor.record {
+			found[object.relativePath] = object.blobDeleted
+		}
+		return found
+	}
+
+	c.Assert(traverse(false), chk.DeepEquals, map[string]bool{"a.txt": false})
+	c.Assert(traverse(true), chk.DeepEquals, map[string]bool{"a.txt": false, "b.txt": true})
+
+	// the transfer says that the source must be undeleted before it's read
+	object := storedObject{name: "b.txt", relativePath: "b.txt", entityType: common.EEntityType.File(), blobDeleted: true}
+	transfer, _ := object.ToNewCopyTransfer(false, "/b.txt", "/b.txt", false, common.EFolderPropertiesOption.NoFolders())
+	c.Assert(transfer.BlobDeleted, chk.Equals, true)
+
+	c.Assert(enableDeletedListing(&localTraverser{}), chk.NotNil)
+}
+
+func (s *blobSnapshotsTraverserSuite) TestValidateIncludeDeleted(c *chk.C) {
+	c.Assert(validateIncludeDeleted(false, common.EFromTo.LocalBlob(), true), chk.IsNil)
+	c.Assert(validateIncludeDeleted(true, common.EFromTo.BlobLocal(), false), chk.IsNil)
+	c.Assert(validateIncludeDeleted(true, common.EFromTo.BlobBlob(), false), chk.IsNil)
+
+	c.Assert(validateIncludeDeleted(true, common.EFromTo.FileBlob(), false), chk.ErrorMatches, "include-deleted is only supported when the source is Blob Storage")
+	c.Assert(validateIncludeDeleted(true, common.EFromTo.BlobLocal(), true), chk.ErrorMatches, "include-deleted can't be used with list-of-versions")
+}
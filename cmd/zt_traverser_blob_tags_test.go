@@ -0,0 +1,166 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+)
+
+type blobTagsTraverserSuite struct{}
+
+var _ = chk.Suite(&blobTagsTraverserSuite{})
+
+// fakeTaggedBlobService serves just enough of the Blob service for a traverser to find, list and get the tags of the blobs
+// in one container. Every blob is tagged project=alpha, except those named in untagged
+type fakeTaggedBlobService struct {
+	blobs           []string
+	untagged        map[string]bool
+	findIsForbidden bool
+	wheres          []string
+	listings        int
+}
+
+func (f *fakeTaggedBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	lmt := time.Date(2020, 5, 4, 3, 2, 1, 0, time.UTC).Format(http.TimeFormat)
+	tagsXML := func(name string) string {
+		if f.untagged[name] {
+			return "<Tags><TagSet></TagSet></Tags>"
+		}
+		return "<Tags><TagSet><Tag><Key>project</Key><Value>alpha</Value></Tag></TagSet></Tags>"
+	}
+
+	switch {
+	case r.URL.Path == "/account" && query.Get("comp") == "blobs":
+		f.wheres = append(f.wheres, query.Get("where"))
+		if f.findIsForbidden {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body := "<EnumerationResults><Blobs>"
+		for _, name := range f.blobs {
+			if !f.untagged[name] {
+				body += fmt.Sprintf("<Blob><Name>%s</Name><ContainerName>container</ContainerName><TagValue>alpha</TagValue></Blob>", name)
+			}
+		}
+		body += "<Blob><Name>dir/x.txt</Name><ContainerName>another</ContainerName><TagValue>alpha</TagValue></Blob></Blobs><NextMarker/></EnumerationResults>"
+		_, _ = w.Write([]byte(body))
+	case r.URL.Path == "/account/container" && query.Get("comp") == "list":
+		f.listings++
+		prefix := query.Get("prefix")
+		body := "<EnumerationResults><Blobs>"
+		subdirs := map[string]bool{}
+		for _, name := range f.blobs {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if i := strings.Index(name[len(prefix):], "/"); i >= 0 {
+				subdirs[name[:len(prefix)+i+1]] = true
+				continue
+			}
+			tags := ""
+			if strings.Contains(query.Get("include"), "tags") {
+				tags = tagsXML(name)
+			}
+			body += fmt.Sprintf("<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified><Content-Length>5</Content-Length><BlobType>BlockBlob</BlobType></Properties>%s</Blob>", name, lmt, tags)
+		}
+		for subdir := range subdirs {
+			body += fmt.Sprintf("<BlobPrefix><Name>%s</Name></BlobPrefix>", subdir)
+		}
+		body += "</Blobs><NextMarker/></EnumerationResults>"
+		_, _ = w.Write([]byte(body))
+	case strings.HasPrefix(r.URL.Path, "/account/container/"):
+		name := strings.TrimPrefix(r.URL.Path, "/account/container/")
+		found := false
+		for _, blob := range f.blobs {
+			found = found || blob == name
+		}
+		if !found {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if query.Get("comp") == "tags" {
+			_, _ = w.Write([]byte(tagsXML(name)))
+			return
+		}
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("Last-Modified", lmt)
+		w.Header().Set("x-ms-blob-type", "BlockBlob")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (s *blobTagsTraverserSuite) traverse(c *chk.C, service *fakeTaggedBlobService, recursive bool, filters []objectFilter) []string {
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/account/container/dir/")
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{})
+	processor := &dummyProcessor{}
+	err := newBlobTraverser(u, p, context.Background(), recursive, false, nil).traverse(noPreProccessor, processor.process, filters)
+	c.Assert(err, chk.IsNil)
+
+	found := make([]string, 0)
+	for _, object := range processor.record {
+		c.Assert(object.blobTags, chk.NotNil)
+		c.Assert(object.size, chk.Equals, int64(5))
+		found = append(found, object.relativePath)
+	}
+	sort.Strings(found)
+	return found
+}
+
+func (s *blobTagsTraverserSuite) TestTaggedBlobsAreFoundByTheService(c *chk.C) {
+	service := &fakeTaggedBlobService{blobs: []string{"dir/a.txt", "dir/b.txt", "dir/sub/c.txt", "other/d.txt"}, untagged: map[string]bool{"dir/b.txt": true}}
+	filters := buildBlobTagsFilters(map[string]string{"project": "alpha"})
+
+	c.Assert(s.traverse(c, service, true, filters), chk.DeepEquals, []string{"a.txt", "sub/c.txt"})
+	c.Assert(service.wheres, chk.DeepEquals, []string{`@container='container' AND "project"='alpha'`})
+	c.Assert(service.listings, chk.Equals, 0)
+
+	c.Assert(s.traverse(c, service, false, filters), chk.DeepEquals, []string{"a.txt"})
+}
+
+func (s *blobTagsTraverserSuite) TestTaggedBlobsAreListedWhenTheServiceCantFindThem(c *chk.C) {
+	service := &fakeTaggedBlobService{blobs: []string{"dir/a.txt", "dir/b.txt", "dir/sub/c.txt"}, untagged: map[string]bool{"dir/b.txt": true}, findIsForbidden: true}
+	filters := buildBlobTagsFilters(map[string]string{"project": "alpha"})
+
+	c.Assert(s.traverse(c, service, true, filters), chk.DeepEquals, []string{"a.txt", "sub/c.txt"})
+	c.Assert(service.wheres, chk.HasLen, 1)
+	c.Assert(service.listings, chk.Not(chk.Equals), 0)
+
+	// the service can't find tags by wildcards, so those blobs are always listed
+	service = &fakeTaggedBlobService{blobs: []string{"dir/a.txt", "dir/b.txt"}, untagged: map[string]bool{"dir/b.txt": true}}
+	c.Assert(s.traverse(c, service, true, buildBlobTagsFilters(map[string]string{"project": "al*"})), chk.DeepEquals, []string{"a.txt"})
+	c.Assert(service.wheres, chk.HasLen, 0)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type httpTraverserSuite struct{}
+
+var _ = chk.Suite(&httpTraverserSuite{})
+
+func (s *httpTraverserSuite) TestHTTPTraverserGetsThePropertiesFromAHeadRequest(c *chk.C) {
+	lmt := time.Date(2020, 5, 4, 3, 2, 1, 0, time.UTC)
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, chk.Equals, http.MethodHead)
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Path != "/sets/a b.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", "1234")
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Last-Modified", lmt.Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	p, err := createHTTPPipeline(ctx, common.CredentialInfo{CredentialType: common.ECredentialType.Anonymous()})
+	c.Assert(err, chk.IsNil)
+
+	u, _ := url.Parse(server.URL + "/sets/a%20b.csv?sig=abc")
+	var found []storedObject
+	err = newHTTPTraverser(u, p, ctx, nil).traverse(noPreProccessor, func(o storedObject) error {
+		found = append(found, o)
+		return nil
+	}, nil)
+	c.Assert(err, chk.IsNil)
+	c.Assert(found, chk.HasLen, 1)
+	c.Assert(found[0].name, chk.Equals, "a b.csv")
+	c.Assert(found[0].relativePath, chk.Equals, "")
+	c.Assert(found[0].size, chk.Equals, int64(1234))
+	c.Assert(found[0].contentType, chk.Equals, "text/csv")
+	c.Assert(found[0].lastModifiedTime.Equal(lmt), chk.Equals, true)
+
+	// the query goes to the server as it was given, without Azure Storage's timeout parameter
+	c.Assert(queries, chk.DeepEquals, []string{"sig=abc"})
+
+	u, _ = url.Parse(server.URL + "/sets/missing.csv")
+	err = newHTTPTraverser(u, p, ctx, nil).traverse(noPreProccessor, func(storedObject) error { return nil }, nil)
+	c.Assert(err, chk.ErrorMatches, ".*404.*")
+
+	// a web server can't be listed
+	u, _ = url.Parse(server.URL + "/sets/")
+	t := newHTTPTraverser(u, p, ctx, nil)
+	c.Assert(t.isDirectory(true), chk.Equals, true)
+	c.Assert(t.traverse(noPreProccessor, func(storedObject) error { return nil }, nil), chk.ErrorMatches, ".*--list-of-files.*")
+}
@@ -0,0 +1,91 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type validatorsSuite struct{}
+
+var _ = chk.Suite(&validatorsSuite{})
+
+func (s *validatorsSuite) TestInferFromToForS3Sources(c *chk.C) {
+	src := "https://s3.amazonaws.com/bucket/folder"
+	c.Assert(inferFromTo(src, "https://account.blob.core.windows.net/container?sv=1&sig=2"), chk.Equals, common.EFromTo.S3Blob())
+	c.Assert(inferFromTo(src, "https://account.file.core.windows.net/share/dir?sv=1&sig=2"), chk.Equals, common.EFromTo.S3File())
+
+	fromTo, err := validateFromTo(src, "https://account.file.core.windows.net/share", "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fromTo.From(), chk.Equals, common.ELocation.S3())
+	c.Assert(fromTo.To(), chk.Equals, common.ELocation.File())
+	c.Assert(fromTo.IsS2S(), chk.Equals, true)
+
+	// Google Cloud Storage is copied from through its S3-compatible API
+	c.Assert(inferFromTo("https://storage.googleapis.com/bucket/folder", "https://account.blob.core.windows.net/container?sv=1&sig=2"), chk.Equals, common.EFromTo.S3Blob())
+}
+
+func (s *validatorsSuite) TestInferFromToForSFTPSources(c *chk.C) {
+	src := "sftp://user@files.contoso.com/data"
+	c.Assert(inferFromTo(src, "https://account.blob.core.windows.net/container?sv=1&sig=2"), chk.Equals, common.EFromTo.SFTPBlob())
+	c.Assert(inferFromTo(src, "https://account.file.core.windows.net/share/dir?sv=1&sig=2"), chk.Equals, common.EFromTo.SFTPFile())
+
+	// AzCopy reads the files itself, so it's an upload, not a service to service copy
+	fromTo := common.EFromTo.SFTPBlob()
+	c.Assert(fromTo.IsUpload(), chk.Equals, true)
+	c.Assert(fromTo.IsS2S(), chk.Equals, false)
+
+	// only local files can be copied to an SFTP server, which, as with SFTP sources, AzCopy writes itself
+	c.Assert(inferFromTo("/data", src), chk.Equals, common.EFromTo.LocalSFTP())
+	fromTo = common.EFromTo.LocalSFTP()
+	c.Assert(fromTo.IsUpload() || fromTo.IsDownload() || fromTo.IsS2S(), chk.Equals, false)
+	c.Assert(inferFromTo("https://account.blob.core.windows.net/container?sv=1&sig=2", src), chk.Equals, common.EFromTo.Unknown())
+}
+
+func (s *validatorsSuite) TestCopyToSFTP(c *chk.C) {
+	raw := getDefaultCopyRawInput(os.TempDir(), "sftp://user@files.contoso.com/data")
+	raw.recursive = true
+	cooked, err := raw.cook()
+	c.Assert(err, chk.IsNil)
+	c.Assert(cooked.fromTo, chk.Equals, common.EFromTo.LocalSFTP())
+
+	raw = getDefaultCopyRawInput("https://account.blob.core.windows.net/container?sv=1&sig=2", "sftp://user@files.contoso.com/data")
+	_, err = raw.cook()
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *validatorsSuite) TestInferFromToForHTTPSources(c *chk.C) {
+	src := "https://data.example.org/sets/2020/a.csv"
+	c.Assert(inferArgumentLocation(src), chk.Equals, common.ELocation.HTTP())
+	c.Assert(inferFromTo(src, "https://account.blob.core.windows.net/container?sv=1&sig=2"), chk.Equals, common.EFromTo.HTTPBlob())
+	c.Assert(inferFromTo(src, "https://account.file.core.windows.net/share/dir?sv=1&sig=2"), chk.Equals, common.EFromTo.HTTPFile())
+
+	// Azure Storage and S3 URLs are still recognized as such
+	c.Assert(inferArgumentLocation("https://account.blob.core.windows.net/container"), chk.Equals, common.ELocation.Blob())
+	c.Assert(inferArgumentLocation("https://s3.amazonaws.com/bucket"), chk.Equals, common.ELocation.S3())
+
+	// the service reads the source itself, so it's a service to service copy
+	fromTo := common.EFromTo.HTTPBlob()
+	c.Assert(fromTo.IsS2S(), chk.Equals, true)
+}
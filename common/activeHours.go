@@ -0,0 +1,104 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ActiveHours is the daily window, in local time, during which a job may use the network, e.g. 22:00-06:00.
+// A window whose end is before its start runs over midnight. It's saved in the job plan (so it's a fixed size),
+// and the zero value means the job is always active
+type ActiveHours struct {
+	IsSet       bool
+	StartMinute uint16 // minutes after local midnight
+	EndMinute   uint16
+}
+
+// Parse reads a window in the form HH:MM-HH:MM. An empty string means always active
+func (a *ActiveHours) Parse(s string) error {
+	*a = ActiveHours{}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return fmt.Errorf("the active hours '%s' must be given as a start and end time, in the form HH:MM-HH:MM", s)
+	}
+	start, err := parseMinuteOfDay(parts[0])
+	if err != nil {
+		return err
+	}
+	end, err := parseMinuteOfDay(parts[1])
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("the active hours '%s' start and end at the same time. To run at any time, leave them out", s)
+	}
+
+	*a = ActiveHours{IsSet: true, StartMinute: start, EndMinute: end}
+	return nil
+}
+
+func parseMinuteOfDay(s string) (uint16, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a valid time of day. Use the 24-hour form HH:MM, e.g. 06:00 or 22:30", strings.TrimSpace(s))
+	}
+	return uint16(t.Hour()*60 + t.Minute()), nil
+}
+
+func (a ActiveHours) String() string {
+	if !a.IsSet {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", a.StartMinute/60, a.StartMinute%60, a.EndMinute/60, a.EndMinute%60)
+}
+
+// IsActiveAt returns whether t, taken in its own location, is inside the window
+func (a ActiveHours) IsActiveAt(t time.Time) bool {
+	if !a.IsSet {
+		return true
+	}
+	minute := uint16(t.Hour()*60 + t.Minute())
+	if a.StartMinute < a.EndMinute {
+		return minute >= a.StartMinute && minute < a.EndMinute
+	}
+	// the window runs over midnight
+	return minute >= a.StartMinute || minute < a.EndMinute
+}
+
+// NextStart returns when the window next opens, after t. If t is inside the window, that's t itself
+func (a ActiveHours) NextStart(t time.Time) time.Time {
+	if a.IsActiveAt(t) {
+		return t
+	}
+	start := time.Date(t.Year(), t.Month(), t.Day(), int(a.StartMinute/60), int(a.StartMinute%60), 0, 0, t.Location())
+	if !start.After(t) {
+		start = time.Date(t.Year(), t.Month(), t.Day()+1, int(a.StartMinute/60), int(a.StartMinute%60), 0, 0, t.Location())
+	}
+	return start
+}
@@ -0,0 +1,89 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/JeffreyRichter/enum/enum"
+)
+
+// ErrorCode says what kind of thing went wrong, so that errors which aren't AzCopy's fault (e.g. a full disk)
+// can be reported with advice on fixing them, rather than as a stack trace
+var EErrorCode = ErrorCode(0)
+
+type ErrorCode uint8
+
+func (ErrorCode) Unknown() ErrorCode    { return ErrorCode(0) }
+func (ErrorCode) Input() ErrorCode      { return ErrorCode(1) } // reading answers and commands from stdin
+func (ErrorCode) LogFile() ErrorCode    { return ErrorCode(2) } // opening, writing or closing a log file
+func (ErrorCode) FileSystem() ErrorCode { return ErrorCode(3) } // AzCopy's own folders, e.g. for logs and plan files
+
+func (ec ErrorCode) String() string {
+	return enum.StringInt(ec, reflect.TypeOf(ec))
+}
+
+// the advice that's given with each code
+var errorCodeHints = map[ErrorCode]MessageKey{
+	EErrorCode.Input():      EMessageKey.ErrorHintInput(),
+	EErrorCode.LogFile():    EMessageKey.ErrorHintLogFile(),
+	EErrorCode.FileSystem(): EMessageKey.ErrorHintFileSystem(),
+}
+
+// AzCopyError wraps an error with what AzCopy was doing when it happened
+type AzCopyError struct {
+	Code ErrorCode
+	Op   string // what we were trying to do, e.g. "open the log file"
+	Err  error
+}
+
+func NewAzCopyError(code ErrorCode, op string, err error) *AzCopyError {
+	return &AzCopyError{Code: code, Op: op, Err: err}
+}
+
+func (e *AzCopyError) Error() string {
+	return fmt.Sprintf("cannot %s: %v", e.Op, e.Err)
+}
+
+func (e *AzCopyError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCodeOf returns the code of the first AzCopyError in err's chain, or Unknown if there isn't one
+func ErrorCodeOf(err error) ErrorCode {
+	var azErr *AzCopyError
+	if errors.As(err, &azErr) {
+		return azErr.Code
+	}
+	return EErrorCode.Unknown()
+}
+
+// DescribeError says what went wrong and, for errors with a code, what can be done about it
+func DescribeError(err error) string {
+	code := ErrorCodeOf(err)
+	hint, ok := errorCodeHints[code]
+	if !ok {
+		return err.Error()
+	}
+	return Localize(EMessageKey.ErrorWithHint(), err, Localize(hint), code)
+}
@@ -58,7 +58,7 @@ func (c *cacheLimiter) TryAdd(count int64, useRelaxedLimit bool) (added bool) {
 	// for high-priority things (i.e. things we deem to be allowable under a relaxed (non-strict) limit)
 	strict := !useRelaxedLimit
 	if strict {
-		lim = int64(float32(lim) * 0.75)
+		lim = c.strictLimit()
 		// Rationale for the level of the strict limit: as at Jan 2018, we are using 0.75 of the total as the strict
 		// limit, leaving the other 0.25 of the total accessible under the "relaxed" limit.
 		// That last 25% gets use for two things: in downloads it is used for things where we KNOW there's
@@ -101,6 +101,11 @@ func (c *cacheLimiter) WaitUntilAdd(ctx context.Context, count int64, useRelaxed
 	}
 }
 
+// strictLimit is the part of the limit that's available to things that aren't allowed the relaxed limit
+func (c *cacheLimiter) strictLimit() int64 {
+	return int64(float32(c.limit) * 0.75)
+}
+
 func (c *cacheLimiter) Remove(count int64) {
 	negativeDelta := -count
 	atomic.AddInt64(&c.value, negativeDelta)
@@ -109,3 +114,38 @@ func (c *cacheLimiter) Remove(count int64) {
 func (c *cacheLimiter) Limit() int64 {
 	return c.limit
 }
+
+// hardCacheLimiter is used when the user has set a hard limit on memory. It counts each amount of RAM at the capacity of
+// the slice that the slice pool will really allocate for it, which can be up to twice as much, since capacities are powers of 2.
+// Without that, the limit would apply only to the requested lengths, not to the memory that is actually used
+type hardCacheLimiter struct {
+	cacheLimiter
+}
+
+func NewHardCacheLimiter(limit int64) CacheLimiter {
+	return &hardCacheLimiter{cacheLimiter{limit: limit}}
+}
+
+func (c *hardCacheLimiter) charge(count int64) int64 {
+	if count <= 0 {
+		return count
+	}
+	charge := SliceCapacityFor(count)
+	if strictLimit := c.strictLimit(); charge > strictLimit {
+		// else it could never be added under the strict limit, and anything that's waiting for that would wait forever
+		charge = strictLimit
+	}
+	return charge
+}
+
+func (c *hardCacheLimiter) TryAdd(count int64, useRelaxedLimit bool) (added bool) {
+	return c.cacheLimiter.TryAdd(c.charge(count), useRelaxedLimit)
+}
+
+func (c *hardCacheLimiter) WaitUntilAdd(ctx context.Context, count int64, useRelaxedLimit Predicate) error {
+	return c.cacheLimiter.WaitUntilAdd(ctx, c.charge(count), useRelaxedLimit)
+}
+
+func (c *hardCacheLimiter) Remove(count int64) {
+	c.cacheLimiter.Remove(c.charge(count))
+}
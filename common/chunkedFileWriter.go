@@ -24,7 +24,7 @@ import (
 	"context"
 	"crypto/md5"
 	"errors"
-	"hash"
+	"fmt"
 	"io"
 	"math"
 	"sync/atomic"
@@ -74,6 +74,9 @@ type chunkedFileWriter struct {
 	// for logging chunk state transitions
 	chunkLogger ChunkStatusLogger
 
+	// told about each chunk once it has been saved, so that progress only counts bytes that have reached the file
+	writtenBytesReporter WrittenBytesReporter
+
 	// file chunks that have arrived and not been sorted yet
 	newUnorderedChunks chan fileChunk
 
@@ -93,6 +96,23 @@ type chunkedFileWriter struct {
 	md5ValidationOption HashValidationOption
 
 	sourceMd5Exists bool
+
+	// if not nil, is also written with exactly the bytes of the file as saved (e.g. for a CRC64 check). Its caller reads
+	// the hashes from it after Flush
+	extraHasher io.Writer
+
+	// where the first chunk we are given goes. Non-zero when an earlier run of the job already saved the start of the file
+	startOffset int64
+
+	// the bytes that are already in the file, before startOffset. Read only to include them in the hashes
+	savedContent io.Reader
+}
+
+// WrittenBytesReporter is told when chunk data has been written to the file. Progress reporting counts bytes
+// at that point, rather than when they arrive off the wire, so that a failed write is never reported as progress.
+// The bytes have only been handed to the OS, and may not be on the disk yet, so they mustn't be relied on for resuming
+type WrittenBytesReporter interface {
+	ReportWrittenBytes(n int64)
 }
 
 type fileChunk struct {
@@ -100,7 +120,15 @@ type fileChunk struct {
 	data []byte
 }
 
-func NewChunkedFileWriter(ctx context.Context, slicePool ByteSlicePooler, cacheLimiter CacheLimiter, chunkLogger ChunkStatusLogger, file io.WriteCloser, numChunks uint32, maxBodyRetries int, md5ValidationOption HashValidationOption, sourceMd5Exists bool) ChunkedFileWriter {
+func NewChunkedFileWriter(ctx context.Context, slicePool ByteSlicePooler, cacheLimiter CacheLimiter, chunkLogger ChunkStatusLogger, writtenBytesReporter WrittenBytesReporter, file io.WriteCloser, numChunks uint32, maxBodyRetries int, md5ValidationOption HashValidationOption, sourceMd5Exists bool) ChunkedFileWriter {
+	return NewResumingChunkedFileWriter(ctx, slicePool, cacheLimiter, chunkLogger, writtenBytesReporter, file, numChunks, maxBodyRetries, md5ValidationOption, sourceMd5Exists, nil, 0, nil)
+}
+
+// NewResumingChunkedFileWriter is for a file whose first startOffset bytes were saved by an earlier run of the job.
+// The file must already be positioned at startOffset, and only the chunks from there on are expected. savedContent must
+// yield exactly the bytes before startOffset, so that they can be included in the hashes. It's only read if a hash is needed.
+// numChunks is the count of chunks that will be enqueued, not counting those that were already saved
+func NewResumingChunkedFileWriter(ctx context.Context, slicePool ByteSlicePooler, cacheLimiter CacheLimiter, chunkLogger ChunkStatusLogger, writtenBytesReporter WrittenBytesReporter, file io.WriteCloser, numChunks uint32, maxBodyRetries int, md5ValidationOption HashValidationOption, sourceMd5Exists bool, extraHasher io.Writer, startOffset int64, savedContent io.Reader) ChunkedFileWriter {
 	// Set max size for buffered channel. The upper limit here is believed to be generous, given worker routine drains it constantly.
 	// Use num chunks in file if lower than the upper limit, to prevent allocating RAM for lots of large channel buffers when dealing with
 	// very large numbers of very small files.
@@ -111,12 +139,16 @@ func NewChunkedFileWriter(ctx context.Context, slicePool ByteSlicePooler, cacheL
 		slicePool:               slicePool,
 		cacheLimiter:            cacheLimiter,
 		chunkLogger:             chunkLogger,
+		writtenBytesReporter:    writtenBytesReporter,
 		successMd5:              make(chan []byte),
 		failureError:            make(chan error, 1),
 		newUnorderedChunks:      make(chan fileChunk, chanBufferSize),
 		maxRetryPerDownloadBody: maxBodyRetries,
 		md5ValidationOption:     md5ValidationOption,
 		sourceMd5Exists:         sourceMd5Exists,
+		extraHasher:             extraHasher,
+		startOffset:             startOffset,
+		savedContent:            savedContent,
 	}
 	go w.workerRoutine(ctx)
 	return w
@@ -213,12 +245,27 @@ func (w *chunkedFileWriter) MaxRetryPerDownloadBody() int {
 // resorting to the likes of SetFileValidData (https://docs.microsoft.com/en-us/windows/desktop/api/fileapi/nf-fileapi-setfilevaliddata)
 // and (b) we can compute MD5 hashes - which can only be computed when moving through the data sequentially
 func (w *chunkedFileWriter) workerRoutine(ctx context.Context) {
-	nextOffsetToSave := int64(0)
+	nextOffsetToSave := w.startOffset
 	unsavedChunksByFileOffset := make(map[int64]fileChunk)
 	md5Hasher := md5.New()
+	needHash := true
 	if w.md5ValidationOption == EHashValidationOption.NoCheck() || !w.sourceMd5Exists {
 		// save CPU time by not even computing a hash, if we don't want to check it, or have nothing to check it against
 		md5Hasher = &nullHasher{}
+		needHash = false
+	}
+	hasher := io.Writer(md5Hasher)
+	if w.extraHasher != nil {
+		hasher = io.MultiWriter(md5Hasher, w.extraHasher)
+		needHash = true
+	}
+	if needHash && w.startOffset > 0 {
+		// the hashes must cover the whole file, including what an earlier run saved
+		if err := w.hashSavedContent(hasher); err != nil {
+			w.failureError <- err
+			close(w.failureError)
+			return
+		}
 	}
 
 	for {
@@ -246,7 +293,7 @@ func (w *chunkedFileWriter) workerRoutine(ctx context.Context) {
 
 		// Process all chunks that we can
 		w.setStatusForContiguousAvailableChunks(unsavedChunksByFileOffset, nextOffsetToSave, ctx) // update states of those that have all their prior ones already here
-		err := w.sequentiallyProcessAvailableChunks(unsavedChunksByFileOffset, &nextOffsetToSave, md5Hasher, ctx)
+		err := w.sequentiallyProcessAvailableChunks(unsavedChunksByFileOffset, &nextOffsetToSave, hasher, ctx)
 		if err != nil {
 			w.failureError <- err
 			close(w.failureError) // must close because many goroutines may be calling the public methods, and all need to be able to tell there's been an error, even tho only one will get the actual error
@@ -255,9 +302,24 @@ func (w *chunkedFileWriter) workerRoutine(ctx context.Context) {
 	}
 }
 
+// hashSavedContent adds the bytes that were already in the file, before startOffset, to the hashes
+func (w *chunkedFileWriter) hashSavedContent(hasher io.Writer) error {
+	if w.savedContent == nil {
+		return errors.New("the content saved by an earlier run is needed to compute the file's hashes, but was not supplied")
+	}
+	n, err := io.Copy(hasher, w.savedContent)
+	if err != nil {
+		return err
+	}
+	if n != w.startOffset {
+		return fmt.Errorf("expected %d bytes to have been saved by an earlier run, but found %d", w.startOffset, n)
+	}
+	return nil
+}
+
 // Hashes and saves available chunks that are sequential from nextOffsetToSave. Stops and returns as soon as it hits
 // a gap (i.e. the position of a chunk that hasn't arrived yet)
-func (w *chunkedFileWriter) sequentiallyProcessAvailableChunks(unsavedChunksByFileOffset map[int64]fileChunk, nextOffsetToSave *int64, md5Hasher hash.Hash, ctx context.Context) error {
+func (w *chunkedFileWriter) sequentiallyProcessAvailableChunks(unsavedChunksByFileOffset map[int64]fileChunk, nextOffsetToSave *int64, hasher io.Writer, ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
@@ -274,7 +336,7 @@ func (w *chunkedFileWriter) sequentiallyProcessAvailableChunks(unsavedChunksByFi
 		*nextOffsetToSave += int64(len(nextChunkInSequence.data)) // update immediately so we won't forget!
 
 		// Save it (hashing exactly what we save)
-		err := w.saveOneChunk(nextChunkInSequence, md5Hasher)
+		err := w.saveOneChunk(nextChunkInSequence, hasher)
 		if err != nil {
 			return err
 		}
@@ -302,7 +364,7 @@ func (w *chunkedFileWriter) setStatusForContiguousAvailableChunks(unsavedChunksB
 }
 
 // Saves one chunk to its destination
-func (w *chunkedFileWriter) saveOneChunk(chunk fileChunk, md5Hasher hash.Hash) error {
+func (w *chunkedFileWriter) saveOneChunk(chunk fileChunk, hasher io.Writer) error {
 	defer func() {
 		w.cacheLimiter.Remove(int64(len(chunk.data))) // remove this from the tally of scheduled-but-unsaved bytes
 		atomic.AddInt32(&w.activeChunkCount, -1)
@@ -322,13 +384,17 @@ func (w *chunkedFileWriter) saveOneChunk(chunk fileChunk, md5Hasher hash.Hash) e
 		}
 
 		// always hash exactly what we save
-		md5Hasher.Write(slice)
+		_, _ = hasher.Write(slice)
 		_, err := w.file.Write(slice) // unlike Read, Write must process ALL the data, or have an error.  It can't return "early".
 		if err != nil {
 			return err
 		}
 	}
 
+	if w.writtenBytesReporter != nil {
+		w.writtenBytesReporter.ReportWrittenBytes(int64(len(chunk.data)))
+	}
+
 	return nil
 }
 
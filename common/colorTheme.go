@@ -0,0 +1,111 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ColorTheme holds the ANSI SGR parameters for each kind of message that's colored, e.g. "31" for red, or "1;31" for bold red.
+// An empty one means that kind of message isn't colored
+type ColorTheme struct {
+	Error   string
+	Warning string
+	Success string // the summary of a job that succeeded
+}
+
+var DefaultColorTheme = ColorTheme{Error: "31", Warning: "33", Success: "32"}
+
+var sgrParameters = regexp.MustCompile(`^[0-9]+(;[0-9]+)*$`)
+
+// ParseColorTheme reads a theme in the form 'error=91,warning=93,success=none', where each value is either
+// SGR parameters or 'none'. Kinds of message that aren't mentioned keep their default colors
+func ParseColorTheme(s string) (ColorTheme, error) {
+	theme := DefaultColorTheme
+	for _, entry := range strings.Split(s, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return theme, fmt.Errorf("the color '%s' is not of the form kind=color, e.g. error=91", entry)
+		}
+		value := strings.TrimSpace(parts[1])
+		if strings.EqualFold(value, "none") {
+			value = ""
+		} else if !sgrParameters.MatchString(value) {
+			return theme, fmt.Errorf("'%s' is not a color. Use ANSI color numbers, e.g. 91 for bright red or 1;31 for bold red, or 'none'", value)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "error":
+			theme.Error = value
+		case "warning":
+			theme.Warning = value
+		case "success":
+			theme.Success = value
+		default:
+			return theme, fmt.Errorf("'%s' is not a kind of message that can be colored. The choices include: error, warning, success", parts[0])
+		}
+	}
+	return theme, nil
+}
+
+func (lcm *lifecycleMgr) SetColors(mode ColorMode, theme ColorTheme) {
+	lcm.colorMode = mode
+	lcm.colorTheme = theme
+}
+
+// whether messages written to w should be colored
+func (lcm *lifecycleMgr) useColorsFor(w io.Writer) bool {
+	if !lcm.console.supportsAnsi() {
+		return false // even when asked for, since they'd be shown as gibberish
+	}
+
+	switch lcm.colorMode {
+	case EColorMode.Always():
+		return true
+	case EColorMode.Never():
+		return false
+	default:
+		return lcm.GetEnvironmentVariable(EEnvironmentVariable.NoColor()) == "" && isTerminal(w)
+	}
+}
+
+// colors the text with the given SGR parameters, if w should be colored. Each line is colored separately,
+// so that nothing is left colored if the output is read a line at a time
+func (lcm *lifecycleMgr) paint(w io.Writer, color string, text string) string {
+	if color == "" || text == "" || !lcm.useColorsFor(w) {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "\x1b[" + color + "m" + line + "\x1b[0m"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,52 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"io"
+	"regexp"
+)
+
+// console hides the differences between platforms in how a line of output is rewritten in place
+type console interface {
+	returnToLineStart() // move the cursor back to the start of the current line, so it can be written over
+	supportsAnsi() bool // whether ANSI escape sequences are acted on, rather than shown as they are
+}
+
+// matches the CSI sequences (e.g. colors and cursor movement) that we might write
+var ansiEscapeSequence = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+func stripAnsiEscapeSequences(s string) string {
+	return ansiEscapeSequence.ReplaceAllString(s, "")
+}
+
+// the console of most terminals, and of anything that isn't a console at all (e.g. a file)
+type ansiConsole struct {
+	output io.Writer
+}
+
+func (c ansiConsole) returnToLineStart() {
+	_, _ = io.WriteString(c.output, "\r")
+}
+
+func (ansiConsole) supportsAnsi() bool {
+	return true
+}
@@ -0,0 +1,73 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var mSetConsoleCursorPosition = dKernel32.NewProc("SetConsoleCursorPosition")
+
+// Windows 10 consoles understand ANSI escape sequences once virtual terminal processing is turned on.
+// Older ones, and legacy mode, don't, and don't reliably handle a carriage return either,
+// so for those the cursor is moved through the console API instead.
+func newConsole(output io.Writer) console {
+	f, ok := output.(*os.File)
+	if !ok {
+		return ansiConsole{output: output}
+	}
+
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return ansiConsole{output: output} // redirected to a file or pipe, so it's not up to the console to interpret anything
+	}
+
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 ||
+		windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil {
+		return ansiConsole{output: output}
+	}
+
+	return legacyConsole{handle: handle}
+}
+
+// a console that doesn't support virtual terminal processing
+type legacyConsole struct {
+	handle windows.Handle
+}
+
+func (c legacyConsole) returnToLineStart() {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(c.handle, &info); err != nil {
+		return
+	}
+
+	// the COORD is passed by value, packed into a single argument
+	start := windows.Coord{X: 0, Y: info.CursorPosition.Y}
+	_, _, _ = mSetConsoleCursorPosition.Call(uintptr(c.handle), uintptr(uint16(start.X))|uintptr(uint16(start.Y))<<16)
+}
+
+func (legacyConsole) supportsAnsi() bool {
+	return false
+}
@@ -24,7 +24,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"net"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +36,9 @@ import (
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/minio/minio-go"
 	"github.com/minio/minio-go/pkg/credentials"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
 
 	"github.com/Azure/azure-storage-azcopy/azbfs"
 )
@@ -199,12 +205,16 @@ func CreateS3Credential(ctx context.Context, credInfo CredentialInfo, options Cr
 	glcm := GetLifecycleMgr()
 	switch credInfo.CredentialType {
 	case ECredentialType.S3AccessKey():
-		accessKeyID := glcm.GetEnvironmentVariable(EEnvironmentVariable.AWSAccessKeyID())
-		secretAccessKey := glcm.GetEnvironmentVariable(EEnvironmentVariable.AWSSecretAccessKey())
-		sessionToken := glcm.GetEnvironmentVariable(EEnvironmentVariable.AwsSessionToken())
+		accessKeyIDVar, secretAccessKeyVar := S3AccessKeyEnvironmentVariables(credInfo.S3CredentialInfo.Endpoint)
+		accessKeyID := glcm.GetEnvironmentVariable(accessKeyIDVar)
+		secretAccessKey := glcm.GetEnvironmentVariable(secretAccessKeyVar)
+		sessionToken := ""
+		if !IsGoogleCloudStorageEndpoint(credInfo.S3CredentialInfo.Endpoint) {
+			sessionToken = glcm.GetEnvironmentVariable(EEnvironmentVariable.AwsSessionToken())
+		}
 
 		if accessKeyID == "" || secretAccessKey == "" {
-			return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables must be set before creating the S3 AccessKey credential")
+			return nil, missingS3AccessKeyError(credInfo.S3CredentialInfo.Endpoint)
 		}
 
 		// create and return s3 credential
@@ -215,6 +225,18 @@ func CreateS3Credential(ctx context.Context, credInfo CredentialInfo, options Cr
 	panic("work around the compiling, logic wouldn't reach here")
 }
 
+// missingS3AccessKeyError says which variables must hold the access key. Google Cloud Storage is read through its
+// S3-compatible API, which only takes HMAC keys, so anyone who has set up OAuth for it is told to make a key instead
+func missingS3AccessKeyError(endpoint string) error {
+	accessKeyIDVar, secretAccessKeyVar := S3AccessKeyEnvironmentVariables(endpoint)
+	err := fmt.Errorf("%s and %s environment variables must be set before creating the S3 AccessKey credential", accessKeyIDVar.Name, secretAccessKeyVar.Name)
+	if IsGoogleCloudStorageEndpoint(endpoint) {
+		err = fmt.Errorf("%w. Google Cloud Storage can only be read with an HMAC key, not with OAuth credentials such as a service account's key file "+
+			"or application default credentials. Create an HMAC key for the service account instead", err)
+	}
+	return err
+}
+
 func refreshBlobFSToken(ctx context.Context, tokenInfo OAuthTokenInfo, tokenCredential azbfs.TokenCredential, options CredentialOpOptions) time.Duration {
 	newToken, err := tokenInfo.Refresh(ctx)
 	if err != nil {
@@ -246,7 +268,11 @@ func CreateS3Client(ctx context.Context, credInfo CredentialInfo, option Credent
 		return nil, err
 	}
 
-	return minio.NewWithCredentials(credInfo.S3CredentialInfo.Endpoint, credential, true, credInfo.S3CredentialInfo.Region)
+	region := credInfo.S3CredentialInfo.Region
+	if region == "" && IsGoogleCloudStorageEndpoint(credInfo.S3CredentialInfo.Endpoint) {
+		region = "auto" // what Google expects in V4 signatures, since its buckets aren't tied to S3 regions
+	}
+	return minio.NewWithCredentials(credInfo.S3CredentialInfo.Endpoint, credential, true, region)
 }
 
 type S3ClientFactory struct {
@@ -285,3 +311,150 @@ func (f *S3ClientFactory) GetS3Client(ctx context.Context, credInfo CredentialIn
 		return s3Client, nil
 	}
 }
+
+// ==============================================================================================
+// SFTP credential related factory methods
+// ==============================================================================================
+
+// CreateSFTPClient logs in to the SFTP server in the URL, with the key in AZCOPY_SFTP_KEY_FILE, the keys held by
+// ssh-agent, or the password in AZCOPY_SFTP_PASSWORD, and starts an SFTP session.
+// The server's host key must be in the known hosts file, since otherwise there's no telling who we're talking to
+func CreateSFTPClient(p SFTPURLParts) (*SFTPClient, error) {
+	config, err := createSSHClientConfig(p.User)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ssh.Dial("tcp", p.Address(), config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to SFTP server %s: %w", p.Address(), err)
+	}
+	session, err := conn.NewSession()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	closer := sshSessionCloser{session, conn}
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	if err = session.RequestSubsystem("sftp"); err != nil {
+		closer.Close()
+		return nil, fmt.Errorf("the server %s doesn't offer SFTP: %w", p.Address(), err)
+	}
+
+	client, err := NewSFTPClient(r, w, closer)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func createSSHClientConfig(user string) (*ssh.ClientConfig, error) {
+	lcm := GetLifecycleMgr()
+
+	knownHostsFile := lcm.GetEnvironmentVariable(EEnvironmentVariable.SFTPKnownHosts())
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(lcm.GetEnvironmentVariable(EEnvironmentVariable.UserDir()), ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the known hosts file, which is needed to check the identity of SFTP servers: %w. "+
+			"Set %s to use another file", err, EEnvironmentVariable.SFTPKnownHosts().Name)
+	}
+
+	auth := make([]ssh.AuthMethod, 0)
+	if keyFile := lcm.GetEnvironmentVariable(EEnvironmentVariable.SFTPKeyFile()); keyFile != "" {
+		key, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the SFTP key file: %w", err)
+		}
+		var signer ssh.Signer
+		if passphrase := lcm.GetEnvironmentVariable(EEnvironmentVariable.SFTPKeyPassphrase()); passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the SFTP key file %s: %w", keyFile, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if sock := lcm.GetEnvironmentVariable(EEnvironmentVariable.SSHAuthSock()); sock != "" {
+		if agentConn, err := net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+		}
+	}
+	if password := lcm.GetEnvironmentVariable(EEnvironmentVariable.SFTPPassword()); password != "" {
+		auth = append(auth, ssh.Password(password),
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no credentials for SFTP. Set %s or %s, or add your key to ssh-agent",
+			EEnvironmentVariable.SFTPKeyFile().Name, EEnvironmentVariable.SFTPPassword().Name)
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}, nil
+}
+
+type sshSessionCloser struct {
+	session *ssh.Session
+	conn    *ssh.Client
+}
+
+func (c sshSessionCloser) Close() error {
+	c.session.Close()
+	return c.conn.Close()
+}
+
+type SFTPClientFactory struct {
+	sftpClients map[string]*SFTPClient
+	lock        sync.Mutex
+}
+
+// NewSFTPClientFactory creates new SFTP client factory.
+func NewSFTPClientFactory() SFTPClientFactory {
+	return SFTPClientFactory{
+		sftpClients: make(map[string]*SFTPClient),
+	}
+}
+
+// GetSFTPClient gets an SFTP client from the pool, or creates one if there's none for the user and server yet.
+// One client can carry any number of requests at once, so there's one per server; but one whose connection has been
+// lost is replaced, rather than failing all the transfers that come after it
+func (f *SFTPClientFactory) GetSFTPClient(p SFTPURLParts) (*SFTPClient, error) {
+	key := p.User + "@" + p.Address()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if sftpClient, ok := f.sftpClients[key]; ok && sftpClient.Err() == nil {
+		return sftpClient, nil
+	}
+
+	sftpClient, err := CreateSFTPClient(p)
+	if err != nil {
+		return nil, err
+	}
+	f.sftpClients[key] = sftpClient
+	return sftpClient, nil
+}
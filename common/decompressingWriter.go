@@ -21,6 +21,8 @@
 package common
 
 import (
+	"bufio"
+	"compress/flate"
 	"compress/gzip"
 	"compress/zlib"
 	"errors"
@@ -59,7 +61,14 @@ func NewDecompressingWriter(destination io.WriteCloser, ct CompressionType) io.W
 func (d decompressingWriter) decompressorFactory(tp CompressionType, preader *io.PipeReader) (io.ReadCloser, error) {
 	switch tp {
 	case ECompressionType.ZLib():
-		return zlib.NewReader(preader)
+		// "deflate" should mean ZLib-wrapped, but some servers send raw deflate streams instead. Like browsers, we
+		// accept both, telling them apart by whether they start with a valid ZLib header
+		buffered := bufio.NewReader(preader)
+		header, err := buffered.Peek(2)
+		if err == nil && !isZLibHeader(header) {
+			return flate.NewReader(buffered), nil
+		}
+		return zlib.NewReader(buffered)
 	case ECompressionType.GZip():
 		return gzip.NewReader(preader)
 	default:
@@ -67,6 +76,12 @@ func (d decompressingWriter) decompressorFactory(tp CompressionType, preader *io
 	}
 }
 
+// isZLibHeader checks the first two bytes of a stream against RFC 1950: the compression method must be deflate, and
+// the two bytes, as a big-endian number, a multiple of 31
+func isZLibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
 func (d decompressingWriter) worker(tp CompressionType, preader *io.PipeReader, destination io.WriteCloser, workerError chan error) {
 
 	var err error
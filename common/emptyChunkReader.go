@@ -57,6 +57,10 @@ func (cr *emptyChunkReader) HasPrefetchedEntirelyZeros() bool {
 	return false // we don't have any zeros (or anything else for that matter)
 }
 
+func (cr *emptyChunkReader) PrefetchedDataRanges(unitSize int64, minGap int64) (ranges []DataRange, ok bool) {
+	return []DataRange{}, true // there's no data
+}
+
 func (cr *emptyChunkReader) Length() int64 {
 	return 0
 }
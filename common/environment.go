@@ -45,14 +45,34 @@ var VisibleEnvironmentVariables = []EnvironmentVariable{
 	EEnvironmentVariable.BufferGB(),
 	EEnvironmentVariable.AWSAccessKeyID(),
 	EEnvironmentVariable.AWSSecretAccessKey(),
+	EEnvironmentVariable.GoogleHMACAccessID(),
+	EEnvironmentVariable.GoogleHMACSecret(),
+	EEnvironmentVariable.SFTPPassword(),
+	EEnvironmentVariable.SFTPKeyFile(),
+	EEnvironmentVariable.SFTPKeyPassphrase(),
+	EEnvironmentVariable.SFTPKnownHosts(),
 	EEnvironmentVariable.ShowPerfStates(),
 	EEnvironmentVariable.PacePageBlobs(),
 	EEnvironmentVariable.DefaultServiceApiVersion(),
 	EEnvironmentVariable.ClientSecret(),
 	EEnvironmentVariable.CertificatePassword(),
 	EEnvironmentVariable.AutoTuneToCpu(),
+	EEnvironmentVariable.RetryMaxTries(),
+	EEnvironmentVariable.RetryTryTimeout(),
+	EEnvironmentVariable.RetryDelay(),
+	EEnvironmentVariable.RetryMaxDelay(),
+	EEnvironmentVariable.RetryJitter(),
+	EEnvironmentVariable.StallTimeout(),
+	EEnvironmentVariable.CheckpointInterval(),
+	EEnvironmentVariable.EncryptPlanFiles(),
+	EEnvironmentVariable.PlanFilePassphrase(),
 	EEnvironmentVariable.CacheProxyLookup(),
 	EEnvironmentVariable.UserAgentPrefix(),
+	EEnvironmentVariable.StatusInterval(),
+	EEnvironmentVariable.NotifySecret(),
+	EEnvironmentVariable.ColorTheme(),
+	EEnvironmentVariable.NoColor(),
+	EEnvironmentVariable.ControlToken(),
 }
 
 var EEnvironmentVariable = EnvironmentVariable{}
@@ -83,7 +103,7 @@ func (EnvironmentVariable) CertificatePassword() EnvironmentVariable {
 func (EnvironmentVariable) ConcurrencyValue() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_CONCURRENCY_VALUE",
-		Description: "Overrides how many HTTP connections work on transfers. By default, this number is determined based on the number of logical cores on the machine.",
+		Description: "Overrides how many HTTP connections work on transfers. By default, this number is determined based on the number of logical cores on the machine. Set to AUTO to have AzCopy start with a few connections and tune the number up or down, based on the observed throughput and on server busy (503) errors and timeouts.",
 	}
 }
 
@@ -95,6 +115,71 @@ func (EnvironmentVariable) AutoTuneToCpu() EnvironmentVariable {
 	}
 }
 
+// The retry settings only affect the transfers themselves, not the listing of the source and destination
+func (EnvironmentVariable) RetryMaxTries() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_RETRY_MAX_TRIES",
+		Description: "Overrides how many times each request is tried, including the first try, before it counts as failed. The default is 20.",
+	}
+}
+
+func (EnvironmentVariable) RetryTryTimeout() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_RETRY_TRY_TIMEOUT",
+		Description: "Overrides how long a single try of a request may take, e.g. 30s or 5m. The default is 15m.",
+	}
+}
+
+func (EnvironmentVariable) RetryDelay() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_RETRY_DELAY",
+		Description: "Overrides the delay before the first retry of a request, e.g. 200ms or 4s. Later retries wait exponentially longer. The default is 1s.",
+	}
+}
+
+func (EnvironmentVariable) RetryMaxDelay() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_RETRY_MAX_DELAY",
+		Description: "Overrides the longest delay between retries of a request, e.g. 10s or 2m. The default is 60s.",
+	}
+}
+
+func (EnvironmentVariable) RetryJitter() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_RETRY_JITTER",
+		Description: "Overrides how much each retry delay is randomly varied, as a fraction of the delay, e.g. 0.1 for up to 10% either way, or 0 for no variation. By default, delays vary between 80% and 130%, and after the service throttles a request the next delay is picked at random, up to three times the previous one, so that throttled connections don't all come back at once. 0 turns that off too.",
+	}
+}
+
+func (EnvironmentVariable) StallTimeout() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_STALL_TIMEOUT",
+		Description: "Overrides how long a transfer may go without making progress, e.g. 10m, before its requests are cancelled and retried on new connections. Set to 0 to turn this off. The default is 5m.",
+	}
+}
+
+func (EnvironmentVariable) CheckpointInterval() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CHECKPOINT_INTERVAL",
+		Description: "Overrides how often the status of transfers is flushed to the job plan files, which is what lets a job be resumed after the machine crashes. Set to a duration, e.g. 30s, to flush that often, to 0 to flush as each transfer finishes (safest, but slowest), or to 'never' to leave it to the OS (fastest). By default, each job part is flushed when it finishes.",
+	}
+}
+
+func (EnvironmentVariable) EncryptPlanFiles() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_ENCRYPT_PLAN_FILES",
+		Description: "Set to true to encrypt the job plan files, which hold the paths of everything a job transfers, with a key that is kept by the OS (DPAPI on Windows, the login keychain on macOS, and the session keyring on Linux, where the plan files can only be read until you log out). Encrypted plan files are only written at checkpoints (see AZCOPY_CHECKPOINT_INTERVAL), so with 'never' the status of a job's transfers isn't saved.",
+	}
+}
+
+func (EnvironmentVariable) PlanFilePassphrase() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_PLAN_FILE_PASSPHRASE",
+		Description: "Encrypts the job plan files with a key derived from this passphrase, rather than one kept by the OS. The same passphrase must be set to list, show or resume those jobs.",
+		Hidden:      true,
+	}
+}
+
 func (EnvironmentVariable) TransferInitiationPoolSize() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_CONCURRENT_FILES",
@@ -152,7 +237,7 @@ func (EnvironmentVariable) JobPlanLocation() EnvironmentVariable {
 func (EnvironmentVariable) BufferGB() EnvironmentVariable {
 	return EnvironmentVariable{
 		Name:        "AZCOPY_BUFFER_GB",
-		Description: "Max number of GB that AzCopy should use for buffering data between network and disk. May include decimal point, e.g. 0.5. The default is based on machine size.",
+		Description: "Max number of GB that AzCopy should use for buffering data between network and disk. May include decimal point, e.g. 0.5. The default is based on machine size. For a hard limit, use --memory-limit-gb instead.",
 	}
 }
 
@@ -204,6 +289,65 @@ func (EnvironmentVariable) AWSSecretAccessKey() EnvironmentVariable {
 	}
 }
 
+func (EnvironmentVariable) GoogleHMACAccessID() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "GOOGLE_HMAC_ACCESS_ID",
+		Description: "The access ID of a Google Cloud Storage HMAC key, for a Google Cloud Storage source used in service to service copy. OAuth credentials aren't supported.",
+	}
+}
+
+func (EnvironmentVariable) GoogleHMACSecret() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "GOOGLE_HMAC_SECRET",
+		Description: "The secret of a Google Cloud Storage HMAC key, for a Google Cloud Storage source used in service to service copy.",
+		Hidden:      true,
+	}
+}
+
+func (EnvironmentVariable) SFTPPassword() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_SFTP_PASSWORD",
+		Description: "The password of the user named in an SFTP URL. Not needed if the user logs in with a key.",
+		Hidden:      true,
+	}
+}
+
+func (EnvironmentVariable) SFTPKeyFile() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_SFTP_KEY_FILE",
+		Description: "The private key file with which to log in to SFTP servers, e.g. ~/.ssh/id_ed25519. Keys held by a running ssh-agent are also tried.",
+	}
+}
+
+func (EnvironmentVariable) SFTPKeyPassphrase() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_SFTP_KEY_PASSPHRASE",
+		Description: "The passphrase of the key in AZCOPY_SFTP_KEY_FILE, if it's encrypted.",
+		Hidden:      true,
+	}
+}
+
+func (EnvironmentVariable) SFTPKnownHosts() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_SFTP_KNOWN_HOSTS",
+		Description: "The known_hosts file holding the host keys of SFTP servers, against which their identity is checked. The default is ~/.ssh/known_hosts.",
+	}
+}
+
+// SSHAuthSock is where an ssh-agent listens. Only used internally, not listed in the environment variables.
+func (EnvironmentVariable) SSHAuthSock() EnvironmentVariable {
+	return EnvironmentVariable{Name: "SSH_AUTH_SOCK"}
+}
+
+// S3AccessKeyEnvironmentVariables gives the variables that hold the access key for the endpoint: Google's HMAC key
+// for Google Cloud Storage, and AWS's access key otherwise. The key for one is never sent to the other
+func S3AccessKeyEnvironmentVariables(endpoint string) (accessKeyID, secretAccessKey EnvironmentVariable) {
+	if IsGoogleCloudStorageEndpoint(endpoint) {
+		return EEnvironmentVariable.GoogleHMACAccessID(), EEnvironmentVariable.GoogleHMACSecret()
+	}
+	return EEnvironmentVariable.AWSAccessKeyID(), EEnvironmentVariable.AWSSecretAccessKey()
+}
+
 // AwsSessionToken is temporaily internally reserved, and not exposed to users.
 func (EnvironmentVariable) AwsSessionToken() EnvironmentVariable {
 	return EnvironmentVariable{Name: "AWS_SESSION_TOKEN"}
@@ -233,3 +377,42 @@ func (EnvironmentVariable) UserAgentPrefix() EnvironmentVariable {
 		Description: "Add a prefix to the default AzCopy User Agent, which is used for telemetry purposes. A space is automatically inserted.",
 	}
 }
+
+func (EnvironmentVariable) StatusInterval() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_STATUS_INTERVAL",
+		Description: "How often, in seconds, to fetch and report the job's progress. Equivalent to the status-interval flag, which takes precedence.",
+	}
+}
+
+func (EnvironmentVariable) NotifySecret() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_NOTIFY_SECRET",
+		Description: "If set, each notification sent to the notify-url is signed with this secret, using HMAC-SHA256. The signature is in the X-AzCopy-Signature header.",
+		Hidden:      true,
+	}
+}
+
+func (EnvironmentVariable) ColorTheme() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_COLOR_THEME",
+		Description: "The colors of errors, warnings and successful summaries, e.g. 'error=91,warning=93,success=none'. Equivalent to the color-theme flag, which takes precedence.",
+	}
+}
+
+// NoColor is the cross-application convention for turning colors off, see https://no-color.org
+func (EnvironmentVariable) NoColor() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "NO_COLOR",
+		Description: "If set to anything, output is not colored, unless the color flag is 'always'.",
+	}
+}
+
+// ControlToken is a secret, so it is not shown by the env command
+func (EnvironmentVariable) ControlToken() EnvironmentVariable {
+	return EnvironmentVariable{
+		Name:        "AZCOPY_CONTROL_TOKEN",
+		Description: "The token that requests to the control server (see the serve command) must send, in the header 'Authorization: Bearer <token>'. By default, a random one is created, and shown when the server starts.",
+		Hidden:      true,
+	}
+}
@@ -33,20 +33,21 @@ type ExclusiveStringMap struct {
 }
 
 func NewExclusiveStringMap(fromTo FromTo, goos string) *ExclusiveStringMap {
-
-	caseInsenstiveDownload := fromTo.IsDownload() &&
-		(strings.EqualFold(goos, "windows") || strings.EqualFold(goos, "darwin")) // download to case insensitive OS
-	caseSensitiveToRemote := fromTo.To() == ELocation.File() // upload to Windows-like cloud file system
-	insensitive := caseInsenstiveDownload || caseSensitiveToRemote
-	sensitive := !insensitive
-
 	return &ExclusiveStringMap{
 		lock:          &sync.Mutex{},
 		m:             make(map[string]struct{}),
-		caseSensitive: sensitive,
+		caseSensitive: !IsCaseInsensitiveDestination(fromTo, goos),
 	}
 }
 
+// IsCaseInsensitiveDestination says whether names at the destination that differ only in case refer to the same file
+func IsCaseInsensitiveDestination(fromTo FromTo, goos string) bool {
+	caseInsenstiveDownload := fromTo.IsDownload() &&
+		(strings.EqualFold(goos, "windows") || strings.EqualFold(goos, "darwin")) // download to case insensitive OS
+	caseSensitiveToRemote := fromTo.To() == ELocation.File() // upload to Windows-like cloud file system
+	return caseInsenstiveDownload || caseSensitiveToRemote
+}
+
 var exclusiveStringMapCollisionError = errors.New("cannot simultaneously send two files to same destination name")
 
 // Add succeeds if and only if key is not currently in the map
@@ -73,6 +74,17 @@ func (e *ExclusiveStringMap) Remove(key string) {
 	delete(e.m, key)
 }
 
+// Contains says whether key is currently in the map
+func (e *ExclusiveStringMap) Contains(key string) bool {
+	key = e.convertCase(key)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	_, alreadyThere := e.m[key]
+	return alreadyThere
+}
+
 func (e *ExclusiveStringMap) convertCase(s string) string {
 	if e.caseSensitive {
 		return s
@@ -24,6 +24,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"math"
+	"net/url"
+	"path"
 	"reflect"
 	"regexp"
 	"strings"
@@ -149,6 +151,110 @@ func (d DeleteSnapshotsOption) ToDeleteSnapshotsOptionType() azblob.DeleteSnapsh
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// ArchivedSourceOption says what copy does with source blobs in the archive tier, which can't be read until they are rehydrated
+var EArchivedSourceOption = ArchivedSourceOption(0)
+
+type ArchivedSourceOption uint8
+
+// Fail lets the transfers of archived blobs fail, as the service refuses to read them
+func (ArchivedSourceOption) Fail() ArchivedSourceOption { return ArchivedSourceOption(0) }
+
+// Skip leaves archived blobs out of the job, and counts them in its summary
+func (ArchivedSourceOption) Skip() ArchivedSourceOption { return ArchivedSourceOption(1) }
+
+// Rehydrate starts the rehydration of archived blobs, and transfers each one once it is back online
+func (ArchivedSourceOption) Rehydrate() ArchivedSourceOption { return ArchivedSourceOption(2) }
+
+func (o ArchivedSourceOption) String() string {
+	return enum.StringInt(o, reflect.TypeOf(o))
+}
+
+func (o *ArchivedSourceOption) Parse(s string) error {
+	// allow empty to mean "Fail"
+	if s == "" {
+		*o = EArchivedSourceOption.Fail()
+		return nil
+	}
+
+	val, err := enum.ParseInt(reflect.TypeOf(o), s, true, true)
+	if err == nil {
+		*o = val.(ArchivedSourceOption)
+	}
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// RehydratePriority is how quickly the service is asked to bring an archived blob back online
+var ERehydratePriority = RehydratePriority(0)
+
+type RehydratePriority uint8
+
+func (RehydratePriority) Standard() RehydratePriority { return RehydratePriority(0) }
+func (RehydratePriority) High() RehydratePriority     { return RehydratePriority(1) }
+
+func (p RehydratePriority) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
+func (p *RehydratePriority) Parse(s string) error {
+	// allow empty to mean "Standard"
+	if s == "" {
+		*p = ERehydratePriority.Standard()
+		return nil
+	}
+
+	val, err := enum.ParseInt(reflect.TypeOf(p), s, true, true)
+	if err == nil {
+		*p = val.(RehydratePriority)
+	}
+	return err
+}
+
+func (p RehydratePriority) ToRehydratePriorityType() azblob.RehydratePriorityType {
+	return azblob.RehydratePriorityType(p.String())
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// S2SCopyMethod is how the data of a block blob is copied from a remote source, during service to service copy
+var ES2SCopyMethod = S2SCopyMethod(0)
+
+type S2SCopyMethod uint8
+
+// Auto copies with Put Block From URL, but falls back to DownloadUpload if the service can't read the source
+func (S2SCopyMethod) Auto() S2SCopyMethod { return S2SCopyMethod(0) }
+
+// PutBlockFromURL has the service read each block from the source, while AzCopy waits
+func (S2SCopyMethod) PutBlockFromURL() S2SCopyMethod { return S2SCopyMethod(1) }
+
+// CopyBlob has the service copy the whole blob in the background, with the asynchronous Copy Blob, and AzCopy waits
+// until it's done
+func (S2SCopyMethod) CopyBlob() S2SCopyMethod { return S2SCopyMethod(2) }
+
+// DownloadUpload reads each block from the source into AzCopy, which sends it on to the destination
+func (S2SCopyMethod) DownloadUpload() S2SCopyMethod { return S2SCopyMethod(3) }
+
+func (m S2SCopyMethod) String() string {
+	return enum.StringInt(m, reflect.TypeOf(m))
+}
+
+func (m *S2SCopyMethod) Parse(s string) error {
+	// allow empty to mean "Auto"
+	if s == "" {
+		*m = ES2SCopyMethod.Auto()
+		return nil
+	}
+
+	val, err := enum.ParseInt(reflect.TypeOf(m), s, true, true)
+	if err == nil {
+		*m = val.(S2SCopyMethod)
+	}
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 type DeleteDestination uint32
 
 var EDeleteDestination = DeleteDestination(0)
@@ -171,6 +277,56 @@ func (dd DeleteDestination) String() string {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// SyncHashType is what sync compares, to decide whether a file has changed: its last-modified time (None), or the hash of its content
+type SyncHashType uint8
+
+var ESyncHashType = SyncHashType(0)
+
+func (SyncHashType) None() SyncHashType { return SyncHashType(0) }
+func (SyncHashType) MD5() SyncHashType  { return SyncHashType(1) }
+
+func (ht *SyncHashType) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(ht), s, true)
+	if err == nil {
+		*ht = val.(SyncHashType)
+	}
+	return err
+}
+
+func (ht SyncHashType) String() string {
+	return enum.StringInt(ht, reflect.TypeOf(ht))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// SyncConflictResolution decides what a two-way sync does with a file that has changed at both ends since the last sync
+type SyncConflictResolution uint8
+
+var ESyncConflictResolution = SyncConflictResolution(0)
+
+// NewestWins copies the version that was modified last over the other one
+func (SyncConflictResolution) NewestWins() SyncConflictResolution { return SyncConflictResolution(0) }
+
+// SourceWins copies the source's version over the destination's
+func (SyncConflictResolution) SourceWins() SyncConflictResolution { return SyncConflictResolution(1) }
+
+// RenameBoth overwrites neither version. Each end gets a copy of the other's version, under a name that says where it came from
+func (SyncConflictResolution) RenameBoth() SyncConflictResolution { return SyncConflictResolution(2) }
+
+func (cr *SyncConflictResolution) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(cr), s, true)
+	if err == nil {
+		*cr = val.(SyncConflictResolution)
+	}
+	return err
+}
+
+func (cr SyncConflictResolution) String() string {
+	return enum.StringInt(cr, reflect.TypeOf(cr))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 // represents one possible response
 var EResponseOption = ResponseOption{ResponseType: "", UserFriendlyResponseType: "", ResponseString: ""}
 
@@ -211,10 +367,11 @@ var EOverwriteOption = OverwriteOption(0)
 
 type OverwriteOption uint8
 
-func (OverwriteOption) True() OverwriteOption          { return OverwriteOption(0) }
-func (OverwriteOption) False() OverwriteOption         { return OverwriteOption(1) }
-func (OverwriteOption) Prompt() OverwriteOption        { return OverwriteOption(2) }
-func (OverwriteOption) IfSourceNewer() OverwriteOption { return OverwriteOption(3) }
+func (OverwriteOption) True() OverwriteOption            { return OverwriteOption(0) }
+func (OverwriteOption) False() OverwriteOption           { return OverwriteOption(1) }
+func (OverwriteOption) Prompt() OverwriteOption          { return OverwriteOption(2) }
+func (OverwriteOption) IfSourceNewer() OverwriteOption   { return OverwriteOption(3) }
+func (OverwriteOption) IfSizeDifferent() OverwriteOption { return OverwriteOption(4) }
 
 func (o *OverwriteOption) Parse(s string) error {
 	val, err := enum.Parse(reflect.TypeOf(o), s, true)
@@ -230,6 +387,92 @@ func (o OverwriteOption) String() string {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+var EDuplicateDestinationPolicy = DuplicateDestinationPolicy(0)
+
+// DuplicateDestinationPolicy says what to do when the source listing yields two files that would be copied to the same destination
+type DuplicateDestinationPolicy uint8
+
+func (DuplicateDestinationPolicy) Skip() DuplicateDestinationPolicy     { return DuplicateDestinationPolicy(0) }
+func (DuplicateDestinationPolicy) Fail() DuplicateDestinationPolicy     { return DuplicateDestinationPolicy(1) }
+func (DuplicateDestinationPolicy) LastWins() DuplicateDestinationPolicy { return DuplicateDestinationPolicy(2) }
+
+func (p *DuplicateDestinationPolicy) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(p), s, true)
+	if err == nil {
+		*p = val.(DuplicateDestinationPolicy)
+	}
+	return err
+}
+
+func (p DuplicateDestinationPolicy) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var EPlanLocation = PlanLocation(0)
+
+// PlanLocation says where a job's plan is kept. A plan on disk lets the job be resumed, listed and shown by later
+// AzCopy processes. A plan in memory avoids writing to disk, but goes when the process does
+type PlanLocation uint8
+
+func (PlanLocation) Disk() PlanLocation   { return PlanLocation(0) }
+func (PlanLocation) Memory() PlanLocation { return PlanLocation(1) }
+
+func (l *PlanLocation) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(l), s, true)
+	if err == nil {
+		*l = val.(PlanLocation)
+	}
+	return err
+}
+
+func (l PlanLocation) String() string {
+	return enum.StringInt(l, reflect.TypeOf(l))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var EPackFormat = PackFormat(0)
+
+// PackFormat says whether, and how, the files of each directory are packed into one archive when uploading, so that
+// trees of many small files don't take a request, or several, per file
+type PackFormat uint8
+
+func (PackFormat) None() PackFormat  { return PackFormat(0) }
+func (PackFormat) Tar() PackFormat   { return PackFormat(1) }
+func (PackFormat) TarGz() PackFormat { return PackFormat(2) }
+
+func (p *PackFormat) Parse(s string) error {
+	if s == "" {
+		*p = EPackFormat.None()
+		return nil
+	}
+	val, err := enum.Parse(reflect.TypeOf(p), s, true)
+	if err == nil {
+		*p = val.(PackFormat)
+	}
+	return err
+}
+
+func (p PackFormat) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
+// ArchiveName is the name of the archive that holds a directory's files, which is uploaded into that directory
+func (p PackFormat) ArchiveName() string {
+	switch p {
+	case EPackFormat.Tar():
+		return "azcopy-packed.tar"
+	case EPackFormat.TarGz():
+		return "azcopy-packed.tar.gz"
+	default:
+		return ""
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 type OutputFormat uint32
 
 var EOutputFormat = OutputFormat(0)
@@ -250,6 +493,135 @@ func (of OutputFormat) String() string {
 	return enum.StringInt(of, reflect.TypeOf(of))
 }
 
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// OutputVerbosity is the threshold below which the lifecycle manager drops messages, rather than showing them to the user.
+// It only affects the command's output. The verbosity of the log file is controlled separately, by log-level
+type OutputVerbosity uint8
+
+var EOutputVerbosity = OutputVerbosity(0)
+
+func (OutputVerbosity) Debug() OutputVerbosity   { return OutputVerbosity(0) }
+func (OutputVerbosity) Info() OutputVerbosity    { return OutputVerbosity(1) }
+func (OutputVerbosity) Warning() OutputVerbosity { return OutputVerbosity(2) }
+func (OutputVerbosity) Error() OutputVerbosity   { return OutputVerbosity(3) }
+
+func (ov *OutputVerbosity) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(ov), s, true)
+	if err == nil {
+		*ov = val.(OutputVerbosity)
+	}
+	return err
+}
+
+func (ov OutputVerbosity) String() string {
+	return enum.StringInt(ov, reflect.TypeOf(ov))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ProgressDisplay controls how progress is shown, when the output type is text
+type ProgressDisplay uint8
+
+var EProgressDisplay = ProgressDisplay(0)
+
+// Auto rewrites the progress line in place when stdout is a terminal, and prints separate lines otherwise
+func (ProgressDisplay) Auto() ProgressDisplay { return ProgressDisplay(0) }
+
+// InPlace rewrites the same line, using carriage returns, each time progress is reported
+func (ProgressDisplay) InPlace() ProgressDisplay { return ProgressDisplay(1) }
+
+// Lines prints progress as plain lines, and less often, which suits files and CI logs
+func (ProgressDisplay) Lines() ProgressDisplay { return ProgressDisplay(2) }
+
+func (pd *ProgressDisplay) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(pd), s, true)
+	if err == nil {
+		*pd = val.(ProgressDisplay)
+	}
+	return err
+}
+
+func (pd ProgressDisplay) String() string {
+	return enum.StringInt(pd, reflect.TypeOf(pd))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ErrorOutput controls where errors and warnings are printed, when the output type is text
+type ErrorOutput uint8
+
+var EErrorOutput = ErrorOutput(0)
+
+// Stderr keeps errors and warnings apart from the progress and summaries on stdout
+func (ErrorOutput) Stderr() ErrorOutput { return ErrorOutput(0) }
+
+// Stdout prints everything together, as older versions did
+func (ErrorOutput) Stdout() ErrorOutput { return ErrorOutput(1) }
+
+func (eo *ErrorOutput) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(eo), s, true)
+	if err == nil {
+		*eo = val.(ErrorOutput)
+	}
+	return err
+}
+
+func (eo ErrorOutput) String() string {
+	return enum.StringInt(eo, reflect.TypeOf(eo))
+}
+
+// ColorMode controls whether text output is colored
+type ColorMode uint8
+
+var EColorMode = ColorMode(0)
+
+// Auto colors the output if it's a terminal, and the NO_COLOR environment variable isn't set
+func (ColorMode) Auto() ColorMode   { return ColorMode(0) }
+func (ColorMode) Always() ColorMode { return ColorMode(1) }
+func (ColorMode) Never() ColorMode  { return ColorMode(2) }
+
+func (cm *ColorMode) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(cm), s, true)
+	if err == nil {
+		*cm = val.(ColorMode)
+	}
+	return err
+}
+
+func (cm ColorMode) String() string {
+	return enum.StringInt(cm, reflect.TypeOf(cm))
+}
+
+// QueueOverflowPolicy says what happens to messages when output can't keep up with them, and the message queue is full
+type QueueOverflowPolicy uint8
+
+var EQueueOverflowPolicy = QueueOverflowPolicy(0)
+
+// Block makes whoever is sending the message wait for room in the queue
+func (QueueOverflowPolicy) Block() QueueOverflowPolicy { return QueueOverflowPolicy(0) }
+
+// Coalesce drops progress reports, since they're out of date by the time there's room, and blocks for everything else
+func (QueueOverflowPolicy) Coalesce() QueueOverflowPolicy { return QueueOverflowPolicy(1) }
+
+// DropOldest holds messages until there's room, but once too many are held, the oldest Info and Debug messages are dropped
+func (QueueOverflowPolicy) DropOldest() QueueOverflowPolicy { return QueueOverflowPolicy(2) }
+
+// Grow holds as many messages as it takes, so nothing is dropped, and nobody waits
+func (QueueOverflowPolicy) Grow() QueueOverflowPolicy { return QueueOverflowPolicy(3) }
+
+func (p *QueueOverflowPolicy) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(p), s, true)
+	if err == nil {
+		*p = val.(QueueOverflowPolicy)
+	}
+	return err
+}
+
+func (p QueueOverflowPolicy) String() string {
+	return enum.StringInt(p, reflect.TypeOf(p))
+}
+
 var EExitCode = ExitCode(0)
 
 type ExitCode uint32
@@ -323,8 +695,18 @@ type JobPriority uint8
 
 func (JobPriority) Normal() JobPriority { return JobPriority(0) }
 func (JobPriority) Low() JobPriority    { return JobPriority(1) }
+func (JobPriority) High() JobPriority   { return JobPriority(2) }
+
+func (jp *JobPriority) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(jp), s, true, true)
+	if err == nil {
+		*jp = val.(JobPriority)
+	}
+	return err
+}
+
 func (jp JobPriority) String() string {
-	return enum.StringInt(uint8(jp), reflect.TypeOf(jp))
+	return enum.StringInt(jp, reflect.TypeOf(jp))
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -386,6 +768,12 @@ func (j *JobStatus) IsJobDone() bool {
 		*j == EJobStatus.Failed()
 }
 
+// HasStopped is true once the job won't make any more progress in this run of AzCopy: either it's done,
+// or it has been paused, in which case "jobs resume" carries on with it
+func (j *JobStatus) HasStopped() bool {
+	return j.IsJobDone() || *j == EJobStatus.Paused()
+}
+
 func (JobStatus) All() JobStatus                           { return JobStatus(100) }
 func (JobStatus) InProgress() JobStatus                    { return JobStatus(0) }
 func (JobStatus) Paused() JobStatus                        { return JobStatus(1) }
@@ -396,6 +784,7 @@ func (JobStatus) CompletedWithErrors() JobStatus           { return JobStatus(5)
 func (JobStatus) CompletedWithSkipped() JobStatus          { return JobStatus(6) }
 func (JobStatus) CompletedWithErrorsAndSkipped() JobStatus { return JobStatus(7) }
 func (JobStatus) Failed() JobStatus                        { return JobStatus(8) }
+func (JobStatus) Queued() JobStatus                        { return JobStatus(9) } // waiting, with --after-job, for another job
 func (js JobStatus) String() string {
 	return enum.StringInt(js, reflect.TypeOf(js))
 }
@@ -415,6 +804,8 @@ func (Location) File() Location      { return Location(4) }
 func (Location) BlobFS() Location    { return Location(5) }
 func (Location) S3() Location        { return Location(6) }
 func (Location) Benchmark() Location { return Location(7) }
+func (Location) SFTP() Location      { return Location(8) }
+func (Location) HTTP() Location      { return Location(9) }
 
 func (l Location) String() string {
 	return enum.StringInt(l, reflect.TypeOf(l))
@@ -441,10 +832,12 @@ func fromToValue(from Location, to Location) FromTo {
 
 func (l Location) IsRemote() bool {
 	switch l {
-	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3():
+	case ELocation.BlobFS(), ELocation.Blob(), ELocation.File(), ELocation.S3(), ELocation.HTTP():
 		return true
 	case ELocation.Local(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown():
 		return false
+	case ELocation.SFTP():
+		return false // since AzCopy reads and writes the files itself, as it does local ones
 	default:
 		panic("unexpected location, please specify if it is remote")
 	}
@@ -464,7 +857,7 @@ func (l Location) IsFolderAware() bool {
 	switch l {
 	case ELocation.BlobFS(), ELocation.File(), ELocation.Local():
 		return true
-	case ELocation.Blob(), ELocation.S3(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.Unknown():
+	case ELocation.Blob(), ELocation.S3(), ELocation.Benchmark(), ELocation.Pipe(), ELocation.SFTP(), ELocation.HTTP(), ELocation.Unknown():
 		return false
 	default:
 		panic("unexpected location, please specify if it is folder-aware")
@@ -501,6 +894,12 @@ func (FromTo) FileBlob() FromTo    { return FromTo(fromToValue(ELocation.File(),
 func (FromTo) BlobFile() FromTo    { return FromTo(fromToValue(ELocation.Blob(), ELocation.File())) }
 func (FromTo) FileFile() FromTo    { return FromTo(fromToValue(ELocation.File(), ELocation.File())) }
 func (FromTo) S3Blob() FromTo      { return FromTo(fromToValue(ELocation.S3(), ELocation.Blob())) }
+func (FromTo) S3File() FromTo      { return FromTo(fromToValue(ELocation.S3(), ELocation.File())) }
+func (FromTo) SFTPBlob() FromTo    { return FromTo(fromToValue(ELocation.SFTP(), ELocation.Blob())) }
+func (FromTo) SFTPFile() FromTo    { return FromTo(fromToValue(ELocation.SFTP(), ELocation.File())) }
+func (FromTo) LocalSFTP() FromTo   { return FromTo(fromToValue(ELocation.Local(), ELocation.SFTP())) }
+func (FromTo) HTTPBlob() FromTo    { return FromTo(fromToValue(ELocation.HTTP(), ELocation.Blob())) }
+func (FromTo) HTTPFile() FromTo    { return FromTo(fromToValue(ELocation.HTTP(), ELocation.File())) }
 
 // todo: to we really want these?  Starts to look like a bit of a combinatorial explosion
 func (FromTo) BenchmarkBlob() FromTo {
@@ -561,6 +960,11 @@ func (ft *FromTo) AreBothFolderAware() bool {
 	return ft.From().IsFolderAware() && ft.To().IsFolderAware()
 }
 
+// Reversed is the same pair of locations, the other way round
+func (ft *FromTo) Reversed() FromTo {
+	return fromToValue(ft.To(), ft.From())
+}
+
 // TODO: deletes are not covered by the above Is* routines
 
 var BenchmarkLmt = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -639,6 +1043,10 @@ func (TransferStatus) TierAvailabilityCheckFailure() TransferStatus { return Tra
 
 func (TransferStatus) Cancelled() TransferStatus { return TransferStatus(-6) }
 
+// Transfer was cancelled on its own, by the user, while the rest of the job carried on. Unlike the other
+// unsuccessful statuses, it's not tried again when the job is resumed
+func (TransferStatus) CancelledByUser() TransferStatus { return TransferStatus(-7) }
+
 func (ts TransferStatus) ShouldTransfer() bool {
 	return ts == ETransferStatus.NotStarted() || ts == ETransferStatus.Started()
 }
@@ -649,7 +1057,7 @@ func (ts TransferStatus) String() string {
 	return enum.StringInt(ts, reflect.TypeOf(ts))
 }
 func (ts *TransferStatus) Parse(s string) error {
-	val, err := enum.ParseInt(reflect.TypeOf(ts), s, false, true)
+	val, err := enum.ParseInt(reflect.TypeOf(ts), s, true, true)
 	if err == nil {
 		*ts = val.(TransferStatus)
 	}
@@ -720,6 +1128,60 @@ func (bbt *BlockBlobTier) UnmarshalJSON(b []byte) error {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// BlockBlobTierRule sets the access tier of the block blobs whose paths match a pattern. As for HeaderRule, a pattern
+// without a / is matched against the name of each file, and one with a / against its whole relative path
+type BlockBlobTierRule struct {
+	Pattern string
+	Tier    BlockBlobTier
+}
+
+// BlockBlobTierRules are given as pattern=tier pairs, separated by ';', e.g. "*.bak=Archive;logs/*=Cool".
+// Where several rules match a blob, the last one wins
+type BlockBlobTierRules []BlockBlobTierRule
+
+// ParseBlockBlobTierRules is the reverse of BlockBlobTierRules.ToString
+func ParseBlockBlobTierRules(s string) (BlockBlobTierRules, error) {
+	var rules BlockBlobTierRules
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("'%s' isn't of the form pattern=tier", pair)
+		}
+		r := BlockBlobTierRule{Pattern: pair[:i]}
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("'%s' isn't a valid pattern: %w", r.Pattern, err)
+		}
+		if err := r.Tier.Parse(pair[i+1:]); err != nil || r.Tier == EBlockBlobTier.None() {
+			return nil, fmt.Errorf("'%s', given for %s, isn't a block blob tier. The tiers are Hot, Cool and Archive", pair[i+1:], r.Pattern)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (rules BlockBlobTierRules) ToString() string {
+	pairs := make([]string, len(rules))
+	for i, r := range rules {
+		pairs[i] = r.Pattern + "=" + r.Tier.String()
+	}
+	return strings.Join(pairs, ";")
+}
+
+// TierFor gives the tier of the block blob with this relative path, which is tier unless a rule matches it
+func (rules BlockBlobTierRules) TierFor(relPath string, tier BlockBlobTier) BlockBlobTier {
+	for _, r := range rules {
+		if matchesPathPattern(r.Pattern, relPath) {
+			tier = r.Tier
+		}
+	}
+	return tier
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 var EPageBlobTier = PageBlobTier(0)
 
 type PageBlobTier uint8
@@ -883,6 +1345,61 @@ func (i *InvalidMetadataHandleOption) UnmarshalJSON(b []byte) error {
 	return i.Parse(s)
 }
 
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// MetadataMergeOption says how the metadata given with --metadata is combined with the source's, when copying
+// between services
+var EMetadataMergeOption = MetadataMergeOption(0)
+
+type MetadataMergeOption uint8
+
+// Overwrite keeps the source's metadata, and adds the given metadata to it, overwriting source values with the same key
+func (MetadataMergeOption) Overwrite() MetadataMergeOption { return MetadataMergeOption(0) }
+
+// KeepSource adds the given metadata, but keeps the source's values for keys that are in both
+func (MetadataMergeOption) KeepSource() MetadataMergeOption { return MetadataMergeOption(1) }
+
+// Replace drops the source's metadata, so that the destination only has the given metadata
+func (MetadataMergeOption) Replace() MetadataMergeOption { return MetadataMergeOption(2) }
+
+func (m MetadataMergeOption) String() string {
+	return enum.StringInt(m, reflect.TypeOf(m))
+}
+
+func (m *MetadataMergeOption) Parse(s string) error {
+	val, err := enum.ParseInt(reflect.TypeOf(m), s, true, true)
+	if err == nil {
+		*m = val.(MetadataMergeOption)
+	}
+	return err
+}
+
+// Merge combines the source's metadata with the given metadata
+func (m MetadataMergeOption) Merge(srcMetadata, givenMetadata Metadata) Metadata {
+	if len(givenMetadata) == 0 {
+		return srcMetadata
+	}
+
+	var first, second Metadata
+	switch m {
+	case EMetadataMergeOption.Replace():
+		return givenMetadata
+	case EMetadataMergeOption.KeepSource():
+		first, second = givenMetadata, srcMetadata
+	default:
+		first, second = srcMetadata, givenMetadata
+	}
+
+	merged := Metadata{}
+	for k, v := range first {
+		merged[k] = v
+	}
+	for k, v := range second {
+		merged[k] = v
+	}
+	return merged
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 const (
 	DefaultBlockBlobBlockSize      = 8 * 1024 * 1024
@@ -914,9 +1431,11 @@ type CopyTransfer struct {
 	Metadata           Metadata
 
 	// Properties for S2S blob copy
-	BlobType      azblob.BlobType
-	BlobTier      azblob.AccessTierType
-	BlobVersionID string
+	BlobType       azblob.BlobType
+	BlobTier       azblob.AccessTierType
+	BlobVersionID  string
+	BlobSnapshotID string
+	BlobDeleted    bool // the source blob is soft-deleted, so it must be undeleted before it's read
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -1077,6 +1596,164 @@ func (m Metadata) ConcatenatedKeys() string {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// BlobTags are the index tags of a blob
+type BlobTags map[string]string
+
+const maxBlobTags = 10
+
+var blobTagRegex = regexp.MustCompile(`^[A-Za-z0-9 +\-./:=_]*$`)
+
+// ToString gives the tags in the form that the x-ms-tags header takes, i.e. as a URL-encoded query string
+func (bt BlobTags) ToString() string {
+	values := url.Values{}
+	for k, v := range bt {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// ToCommonBlobTagsMap is the reverse of BlobTags.ToString
+func ToCommonBlobTagsMap(s string) (BlobTags, error) {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := BlobTags{}
+	for k := range values {
+		tags[k] = values.Get(k)
+	}
+	return tags, nil
+}
+
+// Validate checks the tags against the limits of the service: at most 10 tags, with keys of 1 to 128 characters and
+// values of up to 256, using only letters, digits, spaces and the characters + - . / : = _
+func (bt BlobTags) Validate() error {
+	if len(bt) > maxBlobTags {
+		return fmt.Errorf("a blob can have at most %d index tags, but %d were given", maxBlobTags, len(bt))
+	}
+	for k, v := range bt {
+		if len(k) == 0 || len(k) > 128 || !blobTagRegex.MatchString(k) {
+			return fmt.Errorf("'%s' isn't a valid index tag key. Keys must be 1 to 128 characters long, and may only contain letters, digits, spaces and + - . / : = _", k)
+		}
+		if len(v) > 256 || !blobTagRegex.MatchString(v) {
+			return fmt.Errorf("the value of index tag '%s' isn't valid. Values must be at most 256 characters long, and may only contain letters, digits, spaces and + - . / : = _", k)
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ContentTypeMap maps file extensions, in lower case and with their leading dot (e.g. ".md"), to the content types
+// that uploaded files with those extensions get
+type ContentTypeMap map[string]string
+
+// ToString gives the map as a URL-encoded query string, since content types can have parameters, separated by ';'
+func (m ContentTypeMap) ToString() string {
+	values := url.Values{}
+	for k, v := range m {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// ToContentTypeMap is the reverse of ContentTypeMap.ToString
+func ToContentTypeMap(s string) (ContentTypeMap, error) {
+	values, err := url.ParseQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	m := ContentTypeMap{}
+	for k := range values {
+		m[k] = values.Get(k)
+	}
+	return m, nil
+}
+
+// Lookup gives the content type for the extension of a file, if the map has one for it
+func (m ContentTypeMap) Lookup(fileExtension string) (string, bool) {
+	contentType, ok := m[strings.ToLower(fileExtension)]
+	return contentType, ok
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// HeaderRule sets HTTP headers on the uploaded files whose paths match a pattern. A pattern without a / is matched
+// against the name of each file, and one with a / against its whole path, relative to the source
+type HeaderRule struct {
+	Pattern            string `json:"pattern"`
+	CacheControl       string `json:"cacheControl,omitempty"`
+	ContentEncoding    string `json:"contentEncoding,omitempty"`
+	ContentLanguage    string `json:"contentLanguage,omitempty"`
+	ContentDisposition string `json:"contentDisposition,omitempty"`
+}
+
+// Matches says whether the rule applies to the file with this relative path, which uses / as its separator
+func (r HeaderRule) Matches(relPath string) bool {
+	return matchesPathPattern(r.Pattern, relPath)
+}
+
+// matchesPathPattern matches a pattern without a / against the name of a file, and one with a / against its whole
+// relative path
+func matchesPathPattern(pattern string, relPath string) bool {
+	if !strings.Contains(pattern, "/") {
+		relPath = path.Base(relPath)
+	}
+	matched, err := path.Match(pattern, relPath)
+	return err == nil && matched
+}
+
+// HeaderRules are applied in order, so where several rules set the same header on a file, the last one wins
+type HeaderRules []HeaderRule
+
+// ToString gives the rules as JSON, or "" if there are none
+func (rules HeaderRules) ToString() string {
+	if len(rules) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		panic(err) // can't happen, since the rules only have strings
+	}
+	return string(b)
+}
+
+// ToHeaderRules is the reverse of HeaderRules.ToString
+func ToHeaderRules(s string) (HeaderRules, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var rules HeaderRules
+	err := json.Unmarshal([]byte(s), &rules)
+	return rules, err
+}
+
+// Apply gives the headers for the file with this relative path
+func (rules HeaderRules) Apply(headers ResourceHTTPHeaders, relPath string) ResourceHTTPHeaders {
+	for _, r := range rules {
+		if !r.Matches(relPath) {
+			continue
+		}
+		if r.CacheControl != "" {
+			headers.CacheControl = r.CacheControl
+		}
+		if r.ContentEncoding != "" {
+			headers.ContentEncoding = r.ContentEncoding
+		}
+		if r.ContentLanguage != "" {
+			headers.ContentLanguage = r.ContentLanguage
+		}
+		if r.ContentDisposition != "" {
+			headers.ContentDisposition = r.ContentDisposition
+		}
+	}
+	return headers
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 // Common resource's HTTP headers stands for properties used in AzCopy.
 type ResourceHTTPHeaders struct {
 	ContentType        string
@@ -1244,10 +1921,10 @@ func (ct CompressionType) String() string {
 }
 
 func GetCompressionType(contentEncoding string) (CompressionType, error) {
-	switch strings.ToLower(contentEncoding) {
-	case "":
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
 		return ECompressionType.None(), nil
-	case "gzip":
+	case "gzip", "x-gzip": // x-gzip is the old name, which HTTP/1.1 says to treat the same
 		return ECompressionType.GZip(), nil
 	case "deflate":
 		return ECompressionType.ZLib(), nil
@@ -1265,10 +1942,65 @@ type EntityType uint8
 func (EntityType) File() EntityType   { return EntityType(0) }
 func (EntityType) Folder() EntityType { return EntityType(1) }
 
+// Symlink is a symbolic link that is copied as a link, rather than as the file it points to
+func (EntityType) Symlink() EntityType { return EntityType(2) }
+
 func (e EntityType) String() string {
 	return enum.StringInt(e, reflect.TypeOf(e))
 }
 
+// SymlinkMetadataKey marks a blob that holds a symbolic link. The content of such a blob is the target of the link
+const SymlinkMetadataKey = "is_symlink"
+
+// HardlinkMetadataKey marks an empty blob that stands for a hard link. Its value is the (escaped) path of the blob with
+// the content, relative to the directory of the link
+const HardlinkMetadataKey = "hardlink_target"
+
+// CreationTimeMetadataKey and LastWriteTimeMetadataKey hold the times of a local file that was uploaded with
+// --preserve-info, in RFC 3339 format, so that they can be put back when it's downloaded
+const CreationTimeMetadataKey = "creation_time"
+const LastWriteTimeMetadataKey = "last_write_time"
+
+// XattrsMetadataKey holds the extended attributes of a local file that was uploaded with --preserve-xattrs. Their
+// names aren't valid metadata keys, so they're all encoded into this one value
+const XattrsMetadataKey = "xattrs"
+
+// Crc64MetadataKey holds the CRC64 of a file that was uploaded with --put-crc64, base64-encoded in the same way as the
+// service's x-ms-content-crc64 header, so that the whole file can be checked against it when it's downloaded
+const Crc64MetadataKey = "content_crc64"
+
+// Sha256MetadataKey holds the SHA-256 of a file that was uploaded with --put-sha256, in lower case hex, so that the whole
+// file can be checked against it when it's downloaded
+const Sha256MetadataKey = "content_sha256"
+
+////////////////////////////////////////////////////////////////
+
+// SymlinkHandlingType says what to do with the symbolic links found in a local source
+type SymlinkHandlingType uint8
+
+var ESymlinkHandlingType = SymlinkHandlingType(0)
+
+// Skip leaves symbolic links out of the transfer
+func (SymlinkHandlingType) Skip() SymlinkHandlingType { return SymlinkHandlingType(0) }
+
+// Follow transfers what the links point to, as if it were at the location of the link
+func (SymlinkHandlingType) Follow() SymlinkHandlingType { return SymlinkHandlingType(1) }
+
+// Preserve transfers the links themselves, as blobs that hold their targets, and re-creates them on download
+func (SymlinkHandlingType) Preserve() SymlinkHandlingType { return SymlinkHandlingType(2) }
+
+func (sht *SymlinkHandlingType) Parse(s string) error {
+	val, err := enum.Parse(reflect.TypeOf(sht), s, true)
+	if err == nil {
+		*sht = val.(SymlinkHandlingType)
+	}
+	return err
+}
+
+func (sht SymlinkHandlingType) String() string {
+	return enum.StringInt(sht, reflect.TypeOf(sht))
+}
+
 ////////////////////////////////////////////////////////////////
 
 var EFolderPropertiesOption = FolderPropertyOption(0)
@@ -22,6 +22,7 @@ package common_test
 
 import (
 	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
 	chk "gopkg.in/check.v1"
 )
 
@@ -90,6 +91,24 @@ func (s *feSteModelsTestSuite) TestIsJobDone(c *chk.C) {
 	c.Assert(status.IsJobDone(), chk.Equals, true)
 }
 
+func (s *feSteModelsTestSuite) TestHasStopped(c *chk.C) {
+	status := common.EJobStatus.InProgress()
+	c.Assert(status.HasStopped(), chk.Equals, false)
+
+	status = status.Cancelling()
+	c.Assert(status.HasStopped(), chk.Equals, false)
+
+	// a paused job isn't done, but it has stopped
+	status = status.Paused()
+	c.Assert(status.HasStopped(), chk.Equals, true)
+
+	status = status.Cancelled()
+	c.Assert(status.HasStopped(), chk.Equals, true)
+
+	status = status.Completed()
+	c.Assert(status.HasStopped(), chk.Equals, true)
+}
+
 func getInvalidMetadataSample() common.Metadata {
 	m := make(map[string]string)
 
@@ -173,3 +192,46 @@ func (s *feSteModelsTestSuite) TestMetadataResolveInvalidKeyNegative(c *chk.C) {
 	_, err = mNegative3.ResolveInvalidKey()
 	c.Assert(err, chk.NotNil)
 }
+
+func (s *feSteModelsTestSuite) TestTransferStatusParseIgnoresCase(c *chk.C) {
+	var status common.TransferStatus
+	c.Assert(status.Parse("failed"), chk.IsNil)
+	c.Assert(status, chk.Equals, common.ETransferStatus.Failed())
+	c.Assert(status.Parse("Success"), chk.IsNil)
+	c.Assert(status, chk.Equals, common.ETransferStatus.Success())
+}
+
+func (s *feSteModelsTestSuite) TestArchivedSourceOptionParse(c *chk.C) {
+	var option common.ArchivedSourceOption
+	c.Assert(option.Parse(""), chk.IsNil)
+	c.Assert(option, chk.Equals, common.EArchivedSourceOption.Fail())
+	c.Assert(option.Parse("rehydrate"), chk.IsNil)
+	c.Assert(option, chk.Equals, common.EArchivedSourceOption.Rehydrate())
+	c.Assert(option.Parse("thaw"), chk.NotNil)
+
+	var priority common.RehydratePriority
+	c.Assert(priority.Parse(""), chk.IsNil)
+	c.Assert(priority.ToRehydratePriorityType(), chk.Equals, azblob.RehydratePriorityStandard)
+	c.Assert(priority.Parse("high"), chk.IsNil)
+	c.Assert(priority.ToRehydratePriorityType(), chk.Equals, azblob.RehydratePriorityHigh)
+}
+
+func (s *feSteModelsTestSuite) TestGetCompressionType(c *chk.C) {
+	cases := map[string]common.CompressionType{
+		"":         common.ECompressionType.None(),
+		"identity": common.ECompressionType.None(),
+		"gzip":     common.ECompressionType.GZip(),
+		" GZIP ":   common.ECompressionType.GZip(),
+		"x-gzip":   common.ECompressionType.GZip(),
+		"deflate":  common.ECompressionType.ZLib(),
+	}
+	for encoding, expected := range cases {
+		ct, err := common.GetCompressionType(encoding)
+		c.Assert(err, chk.IsNil)
+		c.Assert(ct, chk.Equals, expected)
+	}
+
+	ct, err := common.GetCompressionType("br")
+	c.Assert(err, chk.NotNil)
+	c.Assert(ct, chk.Equals, common.ECompressionType.Unsupported())
+}
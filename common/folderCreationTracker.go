@@ -74,7 +74,8 @@ func (f *simpleFolderTracker) ShouldSetProperties(folder string, overwrite Overw
 	case EOverwriteOption.True():
 		return true
 	case EOverwriteOption.Prompt(),
-		EOverwriteOption.IfSourceNewer(), // TODO discuss if this case should be treated differently than false
+		EOverwriteOption.IfSourceNewer(),   // TODO discuss if this case should be treated differently than false
+		EOverwriteOption.IfSizeDifferent(), // folders have no size to compare, so this is treated like false
 		EOverwriteOption.False():
 
 		f.mu.Lock()
@@ -0,0 +1,121 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+// HTTPResourceProperties holds the properties of a file at a plain HTTP(S) URL, as given by the headers
+// of the response to a HEAD request for it
+type HTTPResourceProperties struct {
+	Header http.Header
+}
+
+// GetHTTPResourceProperties sends a HEAD request for the URL through the given pipeline (which retries it, as it does
+// any other request), and returns the properties from the response
+func GetHTTPResourceProperties(ctx context.Context, p pipeline.Pipeline, u url.URL) (HTTPResourceProperties, error) {
+	request, err := pipeline.NewRequest(http.MethodHead, u, nil)
+	if err != nil {
+		return HTTPResourceProperties{}, err
+	}
+
+	response, err := p.Do(ctx, nil, request)
+	if err != nil {
+		return HTTPResourceProperties{}, err
+	}
+	r := response.Response()
+	_, _ = io.Copy(ioutil.Discard, r.Body)
+	_ = r.Body.Close()
+
+	if r.StatusCode < http.StatusOK || r.StatusCode >= http.StatusMultipleChoices {
+		// leave out the query, since it may hold a token
+		return HTTPResourceProperties{}, fmt.Errorf("cannot get the properties of %s://%s%s: %s", u.Scheme, u.Host, u.Path, r.Status)
+	}
+
+	return HTTPResourceProperties{Header: r.Header}, nil
+}
+
+// ContentLength returns the size of the file, or -1 if the server didn't give it.
+func (h HTTPResourceProperties) ContentLength() int64 {
+	size, err := strconv.ParseInt(h.Header.Get("Content-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return -1
+	}
+	return size
+}
+
+// LastModified returns the value for header Last-Modified, or the zero time if there's none.
+func (h HTTPResourceProperties) LastModified() time.Time {
+	t, err := http.ParseTime(h.Header.Get("Last-Modified"))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// ContentType returns the value for header Content-Type.
+func (h HTTPResourceProperties) ContentType() string {
+	return h.Header.Get("Content-Type")
+}
+
+// CacheControl returns the value for header Cache-Control.
+func (h HTTPResourceProperties) CacheControl() string {
+	return h.Header.Get("Cache-Control")
+}
+
+// ContentDisposition returns the value for header Content-Disposition.
+func (h HTTPResourceProperties) ContentDisposition() string {
+	return h.Header.Get("Content-Disposition")
+}
+
+// ContentEncoding returns the value for header Content-Encoding.
+func (h HTTPResourceProperties) ContentEncoding() string {
+	return h.Header.Get("Content-Encoding")
+}
+
+// ContentLanguage returns the value for header Content-Language.
+func (h HTTPResourceProperties) ContentLanguage() string {
+	return h.Header.Get("Content-Language")
+}
+
+// ContentMD5 returns the value for header Content-MD5.
+func (h HTTPResourceProperties) ContentMD5() []byte {
+	s := h.Header.Get("Content-MD5")
+	if s == "" {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		b = nil
+	}
+	return b
+}
@@ -0,0 +1,186 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// JobCompletionNotification is what is POSTed to the notify-url, when a job ends
+type JobCompletionNotification struct {
+	Outcome      string   // Succeeded, CompletedWithErrors or Failed
+	ExitCode     ExitCode // what AzCopy exits with, or NoExit if a followup job is about to start
+	TimeStamp    time.Time
+	JobSummary   json.RawMessage `json:",omitempty"` // the same as the final message of --output-type=json, for jobs that ran
+	ErrorMessage string          `json:",omitempty"` // for jobs that failed, why
+}
+
+const (
+	notificationSignatureHeader = "X-AzCopy-Signature"
+
+	// the most that notifying may delay AzCopy's exit by, retries included
+	notificationDeadline = 5 * time.Second
+)
+
+// jobCompletionNotifier sends notifications to a webhook, so that pipelines can act as soon as a job ends, rather than polling
+type jobCompletionNotifier struct {
+	url         string
+	secret      []byte // if non-empty, notifications are signed with it
+	client      *http.Client
+	deadline    time.Duration   // how long all attempts together may take
+	retryDelays []time.Duration // how long to wait before each retry. Its length is the number of retries
+}
+
+func newJobCompletionNotifier(notifyURL string, secret string) (*jobCompletionNotifier, error) {
+	u, err := url.Parse(notifyURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("the notify-url '%s' is not a valid http or https URL", notifyURL)
+	}
+
+	return &jobCompletionNotifier{
+		url:         notifyURL,
+		secret:      []byte(secret),
+		client:      &http.Client{},
+		deadline:    notificationDeadline,
+		retryDelays: []time.Duration{500 * time.Millisecond, time.Second},
+	}, nil
+}
+
+func (lcm *lifecycleMgr) SetNotifyURL(notifyURL string) error {
+	notifier, err := newJobCompletionNotifier(notifyURL, lcm.GetEnvironmentVariable(EEnvironmentVariable.NotifySecret()))
+	if err != nil {
+		return err
+	}
+
+	lcm.notifier = notifier
+	return nil
+}
+
+// the outcome is judged by the exit code, since that is what scripts already rely on
+func newJobCompletionNotification(o OutputBuilder, exitCode ExitCode, errorMessage string) JobCompletionNotification {
+	n := JobCompletionNotification{
+		Outcome:      "Succeeded",
+		ExitCode:     exitCode,
+		TimeStamp:    time.Now(),
+		ErrorMessage: errorMessage,
+	}
+
+	if errorMessage != "" {
+		n.Outcome = "Failed"
+	} else if exitCode == EExitCode.Error() {
+		n.Outcome = "CompletedWithErrors" // the job ran, but not all of its transfers succeeded
+	}
+
+	if o != nil {
+		if summary := o(EOutputFormat.Json()); json.Valid([]byte(summary)) {
+			n.JobSummary = json.RawMessage(summary)
+		}
+	}
+	return n
+}
+
+// notifyJobCompletion tells the webhook that a job has ended. Failures are only warned about, since the job itself is done
+func (lcm *lifecycleMgr) notifyJobCompletion(n JobCompletionNotification) {
+	if lcm.notifier == nil {
+		return
+	}
+
+	if err := lcm.notifier.send(n); err != nil {
+		lcm.Warn(fmt.Sprintf("Could not notify %s that the job has ended: %s", lcm.notifier.url, err))
+	}
+}
+
+func (n *jobCompletionNotifier) send(notification JobCompletionNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.deadline)
+	defer cancel()
+
+	err = n.post(ctx, body)
+	for _, delay := range n.retryDelays {
+		var permanent permanentNotificationError
+		if err == nil || errors.As(err, &permanent) {
+			break
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		err = n.post(ctx, body)
+	}
+	return err
+}
+
+// a failure that retrying won't fix, e.g. the URL doesn't exist
+type permanentNotificationError struct {
+	statusCode int
+}
+
+func (e permanentNotificationError) Error() string {
+	return fmt.Sprintf("the server responded with %d %s", e.statusCode, http.StatusText(e.statusCode))
+}
+
+func (n *jobCompletionNotifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		req.Header.Set(notificationSignatureHeader, "sha256="+signNotification(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout:
+		return fmt.Errorf("the server responded with %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	default:
+		return permanentNotificationError{statusCode: resp.StatusCode}
+	}
+}
+
+// the hex-encoded HMAC-SHA256 of the body, so that the receiver can check that the notification came from us
+func signNotification(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
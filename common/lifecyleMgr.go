@@ -2,27 +2,59 @@ package common
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
-// only one instance of the formatter should exist
+// how often progress is printed, when it is printed as separate lines rather than in place
+const progressLineInterval = 30 * time.Second
+
+// only one instance of the formatter should exist, for the console
 var lcm = func() (lcmgr *lifecycleMgr) {
-	lcmgr = &lifecycleMgr{
+	lcmgr = newLifecycleMgr(os.Stdout, os.Stderr, os.Stdin)
+
+	// Check if need to do CPU profiling, and do CPU profiling accordingly when azcopy life start.
+	lcmgr.checkAndStartCPUProfiling()
+
+	return
+}()
+
+// the instance returned by GetLifecycleMgr. It's the console one, unless an embedder has swapped it
+var activeLcm LifecycleMgr = lcm
+
+// if errorOutput is nil, errors and warnings are written to output, along with everything else
+func newLifecycleMgr(output io.Writer, errorOutput io.Writer, input io.Reader) *lifecycleMgr {
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	lcmgr := &lifecycleMgr{
+		shutdownCtx:          shutdownCtx,
+		shutdown:             shutdown,
 		msgQueue:             make(chan outputMessage, 1000),
 		progressCache:        "",
 		cancelChannel:        make(chan os.Signal, 1),
 		e2eContinueChannel:   make(chan struct{}),
 		e2eAllowOpenChannel:  make(chan struct{}),
 		outputFormat:         EOutputFormat.Text(), // output text by default
+		outputVerbosity:      EOutputVerbosity.Info(),
+		progressAsLines:      !isTerminal(output),
+		output:               output,
+		errorOutput:          errorOutput,
+		console:              newConsole(output),
+		colorTheme:           DefaultColorTheme,
+		input:                input,
 		logSanitizer:         NewAzCopyLogSanitizer(),
 		inputQueue:           make(chan userInput, 1000),
 		allowCancelFromStdIn: false,
@@ -35,37 +67,76 @@ var lcm = func() (lcmgr *lifecycleMgr) {
 	// and process input
 	go lcmgr.watchInputs()
 
-	// Check if need to do CPU profiling, and do CPU profiling accordingly when azcopy life start.
-	lcmgr.checkAndStartCPUProfiling()
+	return lcmgr
+}
 
-	return
-}()
+// NewLifecycleMgr creates a lifecycle manager that writes to output, and reads the user's answers from input,
+// instead of using the console. E.g. to capture the output of commands in tests, or when embedding AzCopy.
+// Note that the process still exits when a command finishes, as it does with the console lifecycle manager,
+// unless ReturnOnExit is called. Then, the command must be started with Run.
+func NewLifecycleMgr(output io.Writer, input io.Reader) LifecycleMgr {
+	return newLifecycleMgr(output, nil, input)
+}
+
+// SetLifecycleMgr replaces the instance returned by GetLifecycleMgr.
+// It must be called before any work starts, since it is not safe to call concurrently with GetLifecycleMgr.
+func SetLifecycleMgr(mgr LifecycleMgr) {
+	activeLcm = mgr
+}
 
 // create a public interface so that consumers outside of this package can refer to the lifecycle manager
-// but they would not be able to instantiate one
+// (and substitute their own, with SetLifecycleMgr)
 type LifecycleMgr interface {
 	Init(OutputBuilder)                                          // let the user know the job has started and initial information like log location
 	Progress(OutputBuilder)                                      // print on the same line over and over again, not allowed to float up
 	Exit(OutputBuilder, ExitCode)                                // indicates successful execution exit after printing, allow user to specify exit code
 	Info(string)                                                 // simple print, allowed to float up
+	Warn(string)                                                 // like Info, but for things the user should take notice of
+	Debug(string)                                                // like Info, but only shown when the verbosity is Debug
 	Error(string)                                                // indicates fatal error, exit after printing, exit code is always Failed (1)
+	ReportError(error)                                           // like Error, but for an AzCopyError, advice on fixing it is given too
 	Prompt(message string, details PromptDetails) ResponseOption // ask the user a question(after erasing the progress), then return the response
+	SetPromptTimeout(time.Duration)                              // make Prompt give up waiting after this long. Zero means wait forever
+	SetScriptedAnswers(map[PromptType]string)                    // answer prompts of the given types with the given answers, instead of asking the user
+	SetOutputFile(path string) error                             // also append everything except progress to the given file, with timestamps
+	EnableSystemLog() error                                      // also write job summaries and errors to syslog, or the Windows Event Log
+	SetNotifyURL(url string) error                               // POST a notification to the given URL when each job ends
+	SetQuiet(bool)                                               // only output warnings, errors, prompts and the final summary
 	SurrenderControl()                                           // give up control, this should never return
-	InitiateProgressReporting(WorkController)                    // start writing progress with another routine
-	AllowReinitiateProgressReporting()                           // allow re-initiation of progress reporting for followup job
+	ReturnOnExit()                                               // library mode: once Exit or Error has been output, return from Run, instead of exiting the process
+	Run(work func()) ExitCode                                    // run the work (e.g. a command) until it exits, and in library mode, return its exit code
+	Context() context.Context                                    // cancelled once the lifecycle manager has shut down, after the last of its output
+	InitiateProgressReporting(WorkController)                    // start writing progress with another routine. Several jobs may be reported at once
 	GetEnvironmentVariable(EnvironmentVariable) string           // get the environment variable or its default value
 	ClearEnvironmentVariable(EnvironmentVariable)                // clears the environment variable
 	SetOutputFormat(OutputFormat)                                // change the output format of the entire application
+	SetOutputVerbosity(OutputVerbosity)                          // drop Debug, Info and Warn messages that are below the given verbosity
+	EnableProgressEndpoint(endpoint string) error                // also publish progress events to local processes that connect to the given socket/pipe
+	RegisterCleanupHook(func())                                  // run the given func just before the process exits
+	SetProgressDisplay(ProgressDisplay)                          // choose whether text progress is rewritten in place, or printed as separate lines
+	SetErrorOutput(ErrorOutput)                                  // choose whether text errors and warnings go to stderr, or to stdout with everything else
+	SetColors(ColorMode, ColorTheme)                             // choose whether, and how, errors, warnings and successful summaries are colored
+	EnableTransferEvents()                                       // with JSON output, also output an event as each file starts and ends
+	TransferEventsEnabled() bool                                 // whether TransferEvent outputs anything, so the work of making events can be skipped
+	TransferEvent(TransferEvent)                                 // output what happened to one file, if transfer events are enabled
+	SetProgressInterval(time.Duration)                           // report progress at this interval, instead of the default. Zero restores the default
+	SetQueueOverflowPolicy(QueueOverflowPolicy)                  // choose what happens to messages when output can't keep up with them
+	OutputQueueStats() OutputQueueStats                          // how many messages were dropped or coalesced, because output couldn't keep up
 	EnableInputWatcher()                                         // depending on the command, we may allow user to give input through Stdin
 	EnableCancelFromStdIn()                                      // allow user to send in `cancel` to stop the job
 	AddUserAgentPrefix(string) string                            // append the global user agent prefix, if applicable
 	E2EAwaitContinue()                                           // used by E2E tests
 	E2EAwaitAllowOpenFiles()                                     // used by E2E tests
 	E2EEnableAwaitAllowOpenFiles(enable bool)                    // used by E2E tests
+
+	// like Prompt, but if there's no answer within the timeout (when non-zero), the default answer is used
+	PromptWithTimeout(message string, details PromptDetails, timeout time.Duration, defaultAnswer ResponseOption) ResponseOption
+	// like Prompt, but if the answer isn't one of the response options, the user is told so and asked again
+	PromptForChoice(message string, details PromptDetails) ResponseOption
 }
 
 func GetLifecycleMgr() LifecycleMgr {
-	return lcm
+	return activeLcm
 }
 
 // single point of control for all outputs
@@ -75,14 +146,44 @@ type lifecycleMgr struct {
 	cancelChannel         chan os.Signal
 	e2eContinueChannel    chan struct{}
 	e2eAllowOpenChannel   chan struct{}
-	waitEverCalled        int32
 	outputFormat          OutputFormat
+	output                io.Writer // where everything is printed, normally stdout
+	errorOutput           io.Writer // if non-nil, where errors and warnings are printed instead, with text output. Normally stderr
+	errorsToOutput        bool      // print errors and warnings to output, even though there is an errorOutput
+	console               console   // how output is rewritten in place, which depends on the platform and terminal
+	input                 io.Reader // where the user's input comes from, normally stdin
+	outputFile            io.Writer // if non-nil, gets a timestamped copy of everything except progress
+	systemLog             systemLog // if non-nil, gets a copy of job summaries and errors
+	colorMode             ColorMode
+	colorTheme            ColorTheme
+	transferEvents        bool // output a TransferEvent message for each file, with JSON output
+	outputVerbosity       OutputVerbosity
+	quiet                 bool                    // drop everything below Warning, including Init and progress, regardless of outputVerbosity
+	progressAsLines       bool                    // print each progress report on its own line, since stdout is not a terminal
+	lastProgressLineTime  time.Time               // when progressAsLines, the time we last printed progress
+	progressInterval      time.Duration           // zero, unless the user has chosen how often to report progress
+	promptTimeout         time.Duration           // zero means prompts wait forever for an answer
+	progressPublisher     *progressEventPublisher // nil unless progress is also being published to a local endpoint
+	notifier              *jobCompletionNotifier  // nil unless a webhook is to be told when each job ends
+	progressReportersLock sync.Mutex
+	progressReporters     []*jobProgressReporter // the jobs whose progress is being reported, in the order they started
+	jobsReported          int                    // how many jobs have ever been reported, to number them
+	cancelWatcherOnce     sync.Once
+	shutdownCtx           context.Context
+	shutdown              context.CancelFunc
+	returnOnExit          bool     // library mode: shut down without exiting the process
+	exitCode              ExitCode // once shut down, what the process would have exited with
+	cleanupHooksLock      sync.Mutex
+	cleanupHooks          []func()
+	queueOverflow         messageQueueOverflow
 	logSanitizer          pipeline.LogSanitizer
 	inputQueue            chan userInput // msgs from the user
-	allowWatchInput       bool           // accept user inputs and place then in the inputQueue
-	allowCancelFromStdIn  bool           // allow user to send in 'cancel' from the stdin to stop the current job
-	e2eAllowAwaitContinue bool           // allow the user to send 'continue' from stdin to start the current job
-	e2eAllowAwaitOpen     bool           // allow the user to send 'open' from stdin to allow the opening of the first file
+	inputEnded            int32          // set to 1 once there's no more input to read, e.g. because stdin was closed
+	scriptedAnswers       map[PromptType]string
+	allowWatchInput       bool // accept user inputs and place then in the inputQueue
+	allowCancelFromStdIn  bool // allow user to send in 'cancel' from the stdin to stop the current job
+	e2eAllowAwaitContinue bool // allow the user to send 'continue' from stdin to start the current job
+	e2eAllowAwaitOpen     bool // allow the user to send 'open' from stdin to allow the opening of the first file
 }
 
 type userInput struct {
@@ -92,10 +193,13 @@ type userInput struct {
 
 // should be started in a single go routine
 func (lcm *lifecycleMgr) watchInputs() {
-	consoleReader := bufio.NewReader(os.Stdin)
+	consoleReader := bufio.NewReader(lcm.input)
 	for {
 		// sleep for a bit, the option might be enabled later
 		if !lcm.allowWatchInput {
+			if lcm.shutdownCtx.Err() != nil {
+				return // library mode, and the option will never be enabled now
+			}
 			time.Sleep(time.Microsecond * 500)
 			continue
 		}
@@ -103,11 +207,19 @@ func (lcm *lifecycleMgr) watchInputs() {
 		// reads input until the first occurrence of \n in the input,
 		input, err := consoleReader.ReadString('\n')
 		timeReceived := time.Now()
-		if err != nil {
-			continue
+		if err != nil && input == "" {
+			// stdin is closed (e.g. we were started by a service, or a script has written all it's going to),
+			// so there will be no more answers. Tell anyone waiting for one, rather than leaving them to wait forever
+			if err != io.EOF {
+				lcm.Warn(DescribeError(NewAzCopyError(EErrorCode.Input(), "read from standard input", err)))
+			}
+			atomic.StoreInt32(&lcm.inputEnded, 1)
+			close(lcm.inputQueue)
+			return
 		}
+		// otherwise, it's the last line, without a line ending. We'll find out that there's no more on the next read
 
-		// remove spaces before/after the content
+		// remove spaces (and the \r of Windows line endings) before/after the content
 		msg := strings.TrimSpace(input)
 
 		if lcm.allowCancelFromStdIn && strings.EqualFold(msg, "cancel") {
@@ -125,19 +237,55 @@ func (lcm *lifecycleMgr) watchInputs() {
 // get the answer to a question that was asked at a certain time
 // only user input after the specified time is returned to make sure that we are getting the right answer to our question
 // NOTE: to ask a question, go through Prompt, to guarantee that only 1 question is asked at a time
-func (lcm *lifecycleMgr) getInputAfterTime(time time.Time) string {
+// If timeout is non-zero, gives up after that long, and returns false
+func (lcm *lifecycleMgr) getInputAfterTime(questionTime time.Time, timeout time.Duration) (string, bool) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
 	for {
-		msg := <-lcm.inputQueue
+		select {
+		case msg, ok := <-lcm.inputQueue:
+			if !ok {
+				return "", false // there's no more input
+			}
+			// keep reading until we find an input that came in after the user specified time
+			if msg.timeReceived.After(questionTime) {
+				return msg.content, true
+			}
+			// otherwise keep waiting as it's possible that the user has not typed it in yet
+		case <-expired:
+			return "", false
+		}
+	}
+}
 
-		// keep reading until we find an input that came in after the user specified time
-		if msg.timeReceived.After(time) {
-			return msg.content
+// sends the answer to a prompt back to whoever is waiting for it. The channel is closed if there was no answer in time
+func (lcm *lifecycleMgr) answerPrompt(msgToOutput outputMessage, questionTime time.Time) {
+	if msgToOutput.presetAnswer != "" {
+		if lcm.outputFormat == EOutputFormat.Text() {
+			fmt.Fprintln(lcm.output, msgToOutput.presetAnswer) // so that the output reads as if the user had typed it
 		}
+		msgToOutput.inputChannel <- msgToOutput.presetAnswer
+		return
+	}
 
-		// otherwise keep waiting as it's possible that the user has not typed it in yet
+	if answer, ok := lcm.getInputAfterTime(questionTime, msgToOutput.promptTimeout); ok {
+		msgToOutput.inputChannel <- answer
+	} else {
+		close(msgToOutput.inputChannel)
 	}
 }
 
+func (lcm *lifecycleMgr) SetPromptTimeout(timeout time.Duration) {
+	lcm.promptTimeout = timeout
+}
+
+func (lcm *lifecycleMgr) SetScriptedAnswers(answers map[PromptType]string) {
+	lcm.scriptedAnswers = answers
+}
+
 func (lcm *lifecycleMgr) EnableInputWatcher() {
 	lcm.allowWatchInput = true
 }
@@ -154,6 +302,76 @@ func (lcm *lifecycleMgr) SetOutputFormat(format OutputFormat) {
 	lcm.outputFormat = format
 }
 
+func (lcm *lifecycleMgr) SetErrorOutput(errorOutput ErrorOutput) {
+	lcm.errorsToOutput = errorOutput == EErrorOutput.Stdout()
+}
+
+// where errors and warnings are printed, if not to output with everything else
+func (lcm *lifecycleMgr) separateErrorOutput() io.Writer {
+	if lcm.errorsToOutput {
+		return nil
+	}
+	return lcm.errorOutput
+}
+
+func (lcm *lifecycleMgr) SetProgressDisplay(display ProgressDisplay) {
+	switch display {
+	case EProgressDisplay.InPlace():
+		lcm.progressAsLines = false
+	case EProgressDisplay.Lines():
+		lcm.progressAsLines = true
+	default:
+		lcm.progressAsLines = !isTerminal(lcm.output)
+	}
+}
+
+// when stdout is redirected to a file, or piped, carriage returns don't overwrite anything, they just make a mess
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && terminal.IsTerminal(int(f.Fd()))
+}
+
+func (lcm *lifecycleMgr) SetProgressInterval(interval time.Duration) {
+	lcm.progressInterval = interval
+}
+
+func (lcm *lifecycleMgr) SetOutputVerbosity(verbosity OutputVerbosity) {
+	lcm.outputVerbosity = verbosity
+}
+
+func (lcm *lifecycleMgr) EnableProgressEndpoint(endpoint string) error {
+	if lcm.progressPublisher != nil {
+		return errors.New("a progress endpoint has already been enabled")
+	}
+
+	publisher, err := newProgressEventPublisher(endpoint)
+	if err != nil {
+		return fmt.Errorf("cannot listen for progress subscribers on '%s': %w", endpoint, err)
+	}
+	lcm.progressPublisher = publisher
+	return nil
+}
+
+// publishes the event to the progress endpoint, if there is one. Subscribers always get JSON, whatever the output format
+func (lcm *lifecycleMgr) publishProgressEvent(msgType outputMessageType, o OutputBuilder) {
+	if lcm.progressPublisher == nil || o == nil {
+		return
+	}
+	lcm.progressPublisher.publish(msgType, o(EOutputFormat.Json()))
+}
+
+func (lcm *lifecycleMgr) SetQuiet(quiet bool) {
+	lcm.quiet = quiet
+}
+
+// Errors are always output, regardless of the verbosity, since they end the process
+func (lcm *lifecycleMgr) shouldOutput(verbosity OutputVerbosity) bool {
+	if lcm.quiet && verbosity < EOutputVerbosity.Warning() {
+		return false
+	}
+	return verbosity >= lcm.outputVerbosity
+}
+
 func (lcm *lifecycleMgr) checkAndStartCPUProfiling() {
 	// CPU Profiling add-on. Set AZCOPY_PROFILE_CPU to enable CPU profiling,
 	// the value AZCOPY_PROFILE_CPU indicates the path to save CPU profiling data.
@@ -200,10 +418,15 @@ func (lcm *lifecycleMgr) checkAndTriggerMemoryProfiling() {
 }
 
 func (lcm *lifecycleMgr) Init(o OutputBuilder) {
-	lcm.msgQueue <- outputMessage{
+	lcm.publishProgressEvent(eOutputMessageType.Init(), o)
+	if !lcm.shouldOutput(EOutputVerbosity.Info()) {
+		return
+	}
+
+	lcm.enqueue(outputMessage{
 		msgContent: o(lcm.outputFormat),
 		msgType:    eOutputMessageType.Init(),
-	}
+	})
 }
 
 func (lcm *lifecycleMgr) Progress(o OutputBuilder) {
@@ -211,50 +434,135 @@ func (lcm *lifecycleMgr) Progress(o OutputBuilder) {
 	if o != nil {
 		messageContent = o(lcm.outputFormat)
 	}
+	lcm.publishProgressEvent(eOutputMessageType.Progress(), o)
+	if lcm.quiet {
+		return
+	}
 
-	lcm.msgQueue <- outputMessage{
+	lcm.enqueue(outputMessage{
 		msgContent: messageContent,
 		msgType:    eOutputMessageType.Progress(),
-	}
+	})
 }
 
 func (lcm *lifecycleMgr) Info(msg string) {
+	lcm.outputSimpleMessage(EOutputVerbosity.Info(), eOutputMessageType.Info(), "INFO", msg)
+}
 
-	msg = lcm.logSanitizer.SanitizeLogMessage(msg) // sometimes error-like text comes through Info, before the final "we've failed, please stop now" signal comes to Error. So we sanitize in both places.
+func (lcm *lifecycleMgr) Warn(msg string) {
+	lcm.outputSimpleMessage(EOutputVerbosity.Warning(), eOutputMessageType.Warning(), "WARN", msg)
+}
 
-	infoMsg := fmt.Sprintf("INFO: %v", msg)
+func (lcm *lifecycleMgr) Debug(msg string) {
+	lcm.outputSimpleMessage(EOutputVerbosity.Debug(), eOutputMessageType.Debug(), "DEBUG", msg)
+}
 
-	lcm.msgQueue <- outputMessage{
-		msgContent: infoMsg,
-		msgType:    eOutputMessageType.Info(),
+func (lcm *lifecycleMgr) outputSimpleMessage(verbosity OutputVerbosity, msgType outputMessageType, prefix string, msg string) {
+	if !lcm.shouldOutput(verbosity) {
+		return
 	}
+
+	msg = lcm.logSanitizer.SanitizeLogMessage(msg) // sometimes error-like text comes through Info, before the final "we've failed, please stop now" signal comes to Error. So we sanitize in both places.
+
+	lcm.enqueue(outputMessage{
+		msgContent: fmt.Sprintf("%s: %v", prefix, msg),
+		msgType:    msgType,
+	})
 }
 
 func (lcm *lifecycleMgr) Prompt(message string, details PromptDetails) ResponseOption {
+	// callers already treat an unrecognized answer conservatively (e.g. by not overwriting), so that's the right default
+	return lcm.PromptWithTimeout(message, details, lcm.promptTimeout, EResponseOption.Default())
+}
+
+func (lcm *lifecycleMgr) PromptWithTimeout(message string, details PromptDetails, timeout time.Duration, defaultAnswer ResponseOption) ResponseOption {
+	rawResponse, answered := lcm.ask(message, details, timeout)
+	if !answered {
+		lcm.reportPromptTimeout(timeout, defaultAnswer)
+		return defaultAnswer
+	}
+
+	if option, ok := matchResponseOption(details.ResponseOptions, rawResponse); ok {
+		return option
+	}
+
+	// nothing matched our options, assume default behavior (up to whoever that called Prompt)
+	// we don't re-prompt the user since this makes the integration with Stg Exp more complex
+	return EResponseOption.Default()
+}
+
+// how many times PromptForChoice asks, before giving up and returning the default
+const maxPromptAttempts = 3
+
+func (lcm *lifecycleMgr) PromptForChoice(message string, details PromptDetails) ResponseOption {
+	for attempt := 1; ; attempt++ {
+		rawResponse, answered := lcm.ask(message, details, lcm.promptTimeout)
+		if !answered {
+			lcm.reportPromptTimeout(lcm.promptTimeout, EResponseOption.Default())
+			return EResponseOption.Default()
+		}
+
+		if option, ok := matchResponseOption(details.ResponseOptions, rawResponse); ok {
+			return option
+		}
+
+		// as with Prompt, applications that integrate through JSON get the default, rather than an unexpected second question
+		if lcm.outputFormat != EOutputFormat.Text() || attempt == maxPromptAttempts {
+			return EResponseOption.Default()
+		}
+
+		choices := make([]string, len(details.ResponseOptions))
+		for i, option := range details.ResponseOptions {
+			choices[i] = strings.ToUpper(option.ResponseString)
+		}
+		lcm.Info(Localize(EMessageKey.InvalidChoice(), rawResponse, strings.Join(choices, ", ")))
+	}
+}
+
+// ask the question, and block until the user answers, or until we give up waiting for an answer
+func (lcm *lifecycleMgr) ask(message string, details PromptDetails, timeout time.Duration) (rawResponse string, answered bool) {
 	expectedInputChannel := make(chan string, 1)
-	lcm.msgQueue <- outputMessage{
+	lcm.enqueue(outputMessage{
 		msgContent:    message,
 		msgType:       eOutputMessageType.Prompt(),
 		inputChannel:  expectedInputChannel,
 		promptDetails: details,
+		promptTimeout: timeout,
+		presetAnswer:  lcm.scriptedAnswers[details.PromptType],
+	})
+
+	rawResponse, answered = <-expectedInputChannel
+	return
+}
+
+func (lcm *lifecycleMgr) reportPromptTimeout(timeout time.Duration, defaultAnswer ResponseOption) {
+	answerDescription := defaultAnswer.UserFriendlyResponseType
+	if answerDescription == "" {
+		answerDescription = "the default"
 	}
 
-	// block until input comes from the user
-	rawResponse := <-expectedInputChannel
+	if atomic.LoadInt32(&lcm.inputEnded) == 1 {
+		lcm.Info(Localize(EMessageKey.PromptNoInput(), answerDescription))
+	} else {
+		lcm.Info(Localize(EMessageKey.PromptTimedOut(), timeout, answerDescription))
+	}
+}
 
-	// match the given response against one of the options we gave
-	for _, option := range details.ResponseOptions {
+// match the given response against one of the options we gave
+func matchResponseOption(options []ResponseOption, rawResponse string) (ResponseOption, bool) {
+	for _, option := range options {
 		// in case the user misunderstood and typed full response type instead, we still tolerate it
 		// e.g. instead of "y", user typed "Yes"
 		if strings.EqualFold(option.ResponseString, rawResponse) ||
 			strings.EqualFold(option.UserFriendlyResponseType, rawResponse) {
-			return option
+			return option, true
 		}
 	}
+	return EResponseOption.Default(), false
+}
 
-	// nothing matched our options, assume default behavior (up to whoever that called Prompt)
-	// we don't re-prompt the user since this makes the integration with Stg Exp more complex
-	return EResponseOption.Default()
+func (lcm *lifecycleMgr) ReportError(err error) {
+	lcm.Error(DescribeError(err))
 }
 
 // TODO minor: consider merging with Exit
@@ -268,11 +576,17 @@ func (lcm *lifecycleMgr) Error(msg string) {
 	// Check if there is ongoing CPU profiling, and stop CPU profiling.
 	lcm.checkAndStopCPUProfiling()
 
-	lcm.msgQueue <- outputMessage{
+	if lcm.progressPublisher != nil {
+		lcm.progressPublisher.publish(eOutputMessageType.Error(), msg)
+		lcm.progressPublisher.close()
+	}
+	lcm.notifyJobCompletion(newJobCompletionNotification(nil, EExitCode.Error(), msg))
+
+	lcm.enqueue(outputMessage{
 		msgContent: msg,
 		msgType:    eOutputMessageType.Error(),
 		exitCode:   EExitCode.Error(),
-	}
+	})
 
 	// stall forever until the success message is printed and program exits
 	lcm.SurrenderControl()
@@ -292,11 +606,17 @@ func (lcm *lifecycleMgr) Exit(o OutputBuilder, applicationExitCode ExitCode) {
 		messageContent = o(lcm.outputFormat)
 	}
 
-	lcm.msgQueue <- outputMessage{
+	lcm.publishProgressEvent(eOutputMessageType.EndOfJob(), o)
+	lcm.notifyJobCompletion(newJobCompletionNotification(o, applicationExitCode, ""))
+	if applicationExitCode != EExitCode.NoExit() && lcm.progressPublisher != nil {
+		lcm.progressPublisher.close() // so that subscribers get the final event before we exit
+	}
+
+	lcm.enqueue(outputMessage{
 		msgContent: messageContent,
 		msgType:    eOutputMessageType.EndOfJob(),
 		exitCode:   applicationExitCode,
-	}
+	})
 
 	if applicationExitCode != EExitCode.NoExit() {
 		// stall forever until the success message is printed and program exits
@@ -306,34 +626,135 @@ func (lcm *lifecycleMgr) Exit(o OutputBuilder, applicationExitCode ExitCode) {
 
 // this is used by commands that wish to stall forever to wait for the operations to complete
 func (lcm *lifecycleMgr) SurrenderControl() {
-	// stall forever
-	select {}
+	// stall until the lifecycle manager shuts down, which, unless in library mode, is when the process exits
+	<-lcm.shutdownCtx.Done()
+
+	// in library mode, the goroutine goes no further, since whatever called us doesn't expect to carry on.
+	// Run waits for the shutdown on another goroutine, and returns control from there
+	runtime.Goexit()
+}
+
+func (lcm *lifecycleMgr) ReturnOnExit() {
+	lcm.returnOnExit = true
+}
+
+// Run calls work on a goroutine of its own, and waits for the lifecycle manager to shut down.
+// E.g. when embedding AzCopy: mgr.Run(func() { cmd.Execute(...) }).
+// Since a lifecycle manager shuts down only once, it can only run one piece of work.
+func (lcm *lifecycleMgr) Run(work func()) ExitCode {
+	go func() {
+		work()
+
+		// work that returns without exiting has nothing left to report
+		lcm.Exit(nil, EExitCode.Success())
+	}()
+
+	<-lcm.shutdownCtx.Done()
+	return lcm.exitCode
+}
+
+func (lcm *lifecycleMgr) Context() context.Context {
+	return lcm.shutdownCtx
 }
 
 func (lcm *lifecycleMgr) processOutputMessage() {
 	// this function constantly pulls out message to output
 	// and pass them onto the right handler based on the output format
 	for {
-		msgToPrint := <-lcm.msgQueue
-
-		switch lcm.outputFormat {
-		case EOutputFormat.Json():
-			lcm.processJSONOutput(msgToPrint)
-		case EOutputFormat.Text():
-			lcm.processTextOutput(msgToPrint)
-		case EOutputFormat.None():
-			lcm.processNoneOutput(msgToPrint)
+		select {
+		case msgToPrint := <-lcm.msgQueue:
+			lcm.processMessage(msgToPrint)
+		case <-lcm.shutdownCtx.Done():
+			return // library mode, where the process carries on without us
+		}
+	}
+}
+
+func (lcm *lifecycleMgr) processMessage(msgToPrint outputMessage) {
+	// before it's printed, since printing it may exit the process
+	lcm.writeToOutputFile(msgToPrint)
+	lcm.writeToSystemLog(msgToPrint)
+
+	switch lcm.outputFormat {
+	case EOutputFormat.Json():
+		lcm.processJSONOutput(msgToPrint)
+	case EOutputFormat.Text():
+		lcm.processTextOutput(msgToPrint)
+	case EOutputFormat.None():
+		lcm.processNoneOutput(msgToPrint)
+	default:
+		panic("unimplemented output format")
+	}
+}
+
+// the shutdown barrier: messages that were queued by other goroutines while the final one was being output are not lost
+func (lcm *lifecycleMgr) drainMessageQueue() {
+	for {
+		var msg outputMessage
+		select {
+		case msg = <-lcm.msgQueue:
+		default:
+			if !lcm.overflowPending() {
+				return
+			}
+			msg = <-lcm.msgQueue // the rest are on their way in from the overflow
+		}
+
+		switch msg.msgType {
+		case eOutputMessageType.Prompt():
+			close(msg.inputChannel) // there's nobody left to answer, so the asker gets its default answer
+		case eOutputMessageType.Error(), eOutputMessageType.EndOfJob(), eOutputMessageType.Progress():
+			// we're already exiting, and the progress is out of date
 		default:
-			panic("unimplemented output format")
+			lcm.processMessage(msg)
 		}
 	}
 }
 
+// so that the user knows the output they've seen isn't everything
+func (lcm *lifecycleMgr) reportDroppedMessages() {
+	if dropped := lcm.OutputQueueStats().Dropped; dropped > 0 {
+		lcm.processMessage(outputMessage{
+			msgContent: "INFO: " + Localize(EMessageKey.MessagesDropped(), dropped),
+			msgType:    eOutputMessageType.Info(),
+		})
+	}
+}
+
+func (lcm *lifecycleMgr) SetOutputFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, DEFAULT_FILE_PERM)
+	if err != nil {
+		return fmt.Errorf("cannot open output file: %w", err)
+	}
+
+	lcm.outputFile = f
+	lcm.RegisterCleanupHook(func() { _ = f.Close() })
+	return nil
+}
+
+// progress is left out, since it's re-printed every couple of seconds, and only the latest one ever matters
+func (lcm *lifecycleMgr) writeToOutputFile(msg outputMessage) {
+	if lcm.outputFile == nil || msg.msgType == eOutputMessageType.Progress() || msg.msgContent == "" {
+		return
+	}
+
+	timestamp := time.Now().Format(time.RFC3339)
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(msg.msgContent, "\n"), "\n") {
+		b.WriteString(timestamp + " " + line + "\n")
+	}
+	_, _ = io.WriteString(lcm.outputFile, b.String()) // there's nowhere to report a failure to write the copy
+}
+
 func (lcm *lifecycleMgr) processNoneOutput(msgToOutput outputMessage) {
 	if msgToOutput.msgType == eOutputMessageType.Error() {
-		os.Exit(int(EExitCode.Error()))
+		// the output may be carrying data, such as a blob piped to stdout, but the reason for failing can still go to stderr
+		if lcm.errorOutput != nil && msgToOutput.msgContent != "" {
+			fmt.Fprintln(lcm.errorOutput, msgToOutput.msgContent)
+		}
+		lcm.exitProcess(EExitCode.Error())
 	} else if msgToOutput.shouldExitProcess() {
-		os.Exit(int(msgToOutput.exitCode))
+		lcm.exitProcess(msgToOutput.exitCode)
 	}
 
 	// ignore all other outputs
@@ -346,25 +767,30 @@ func (lcm *lifecycleMgr) processJSONOutput(msgToOutput outputMessage) {
 
 	// simply output the json message
 	// we assume the msgContent is already formatted correctly
-	fmt.Println(GetJsonStringFromTemplate(newJsonOutputTemplate(msgType, msgToOutput.msgContent,
+	fmt.Fprintln(lcm.output, GetJsonStringFromTemplate(newJsonOutputTemplate(msgType, msgToOutput.msgContent,
 		msgToOutput.promptDetails)))
 
 	// exit if needed
 	if msgToOutput.shouldExitProcess() {
-		os.Exit(int(msgToOutput.exitCode))
+		lcm.exitProcess(msgToOutput.exitCode)
 	} else if msgType == eOutputMessageType.Prompt() {
 		// read the response to the prompt and send it back through the channel
-		msgToOutput.inputChannel <- lcm.getInputAfterTime(questionTime)
+		lcm.answerPrompt(msgToOutput, questionTime)
 	}
 }
 
 func (lcm *lifecycleMgr) processTextOutput(msgToOutput outputMessage) {
+	if !lcm.console.supportsAnsi() {
+		// otherwise, they would be shown as they are, as gibberish
+		msgToOutput.msgContent = stripAnsiEscapeSequences(msgToOutput.msgContent)
+	}
+
 	// when a new line needs to overwrite the current line completely
 	// we need to make sure that if the new line is shorter, we properly erase everything from the current line
 	var matchLengthWithSpaces = func(curLineLength, newLineLength int) {
 		if dirtyLeftover := curLineLength - newLineLength; dirtyLeftover > 0 {
 			for i := 0; i < dirtyLeftover; i++ {
-				fmt.Print(" ")
+				fmt.Fprint(lcm.output, " ")
 			}
 		}
 	}
@@ -374,15 +800,40 @@ func (lcm *lifecycleMgr) processTextOutput(msgToOutput outputMessage) {
 		// simply print and quit
 		// if no message is intended, avoid adding new lines
 		if msgToOutput.msgContent != "" {
-			fmt.Println("\n" + msgToOutput.msgContent)
+			color := lcm.colorTheme.Error
+			if msgToOutput.msgType == eOutputMessageType.EndOfJob() {
+				color = IffString(msgToOutput.exitCode == EExitCode.Success(), lcm.colorTheme.Success, "")
+			}
+
+			if errorOutput := lcm.separateErrorOutput(); errorOutput != nil && msgToOutput.msgType == eOutputMessageType.Error() {
+				if lcm.progressCache != "" {
+					fmt.Fprintln(lcm.output) // leave the last progress status where it is
+				}
+				fmt.Fprintln(errorOutput, "\n"+lcm.paint(errorOutput, color, msgToOutput.msgContent))
+			} else {
+				fmt.Fprintln(lcm.output, "\n"+lcm.paint(lcm.output, color, msgToOutput.msgContent))
+			}
 		}
 		if msgToOutput.shouldExitProcess() {
-			os.Exit(int(msgToOutput.exitCode))
+			lcm.exitProcess(msgToOutput.exitCode)
 		}
 
 	case eOutputMessageType.Progress():
-		fmt.Print("\r")                   // return carriage back to start
-		fmt.Print(msgToOutput.msgContent) // print new progress
+		if lcm.progressAsLines {
+			// nothing reads these as they happen, so there's no point filling the log with one every couple of seconds
+			lineInterval := progressLineInterval
+			if lcm.progressInterval != 0 {
+				lineInterval = lcm.progressInterval // the user asked for this often, so give them what they asked for
+			}
+			if time.Since(lcm.lastProgressLineTime) >= lineInterval {
+				fmt.Fprintln(lcm.output, msgToOutput.msgContent)
+				lcm.lastProgressLineTime = time.Now()
+			}
+			break
+		}
+
+		lcm.console.returnToLineStart()                // return carriage back to start
+		fmt.Fprint(lcm.output, msgToOutput.msgContent) // print new progress
 
 		// it is possible that the new progress status is somehow shorter than the previous one
 		// in this case we must erase the left over characters from the previous progress
@@ -390,46 +841,58 @@ func (lcm *lifecycleMgr) processTextOutput(msgToOutput outputMessage) {
 
 		lcm.progressCache = msgToOutput.msgContent
 
-	case eOutputMessageType.Init(), eOutputMessageType.Info():
-		if lcm.progressCache != "" { // a progress status is already on the last line
+	case eOutputMessageType.Init(), eOutputMessageType.Info(), eOutputMessageType.Warning(), eOutputMessageType.Debug():
+		color := IffString(msgToOutput.msgType == eOutputMessageType.Warning(), lcm.colorTheme.Warning, "")
+		if errorOutput := lcm.separateErrorOutput(); errorOutput != nil && msgToOutput.msgType == eOutputMessageType.Warning() {
+			if lcm.progressCache != "" {
+				// erase the progress status, so the warning isn't printed on the end of it, then put it back afterwards
+				lcm.console.returnToLineStart()
+				matchLengthWithSpaces(len(lcm.progressCache), 0)
+				lcm.console.returnToLineStart()
+				fmt.Fprintln(errorOutput, lcm.paint(errorOutput, color, msgToOutput.msgContent))
+				fmt.Fprint(lcm.output, lcm.progressCache)
+			} else {
+				fmt.Fprintln(errorOutput, lcm.paint(errorOutput, color, msgToOutput.msgContent))
+			}
+		} else if lcm.progressCache != "" { // a progress status is already on the last line
 			// print the info from the beginning on current line
-			fmt.Print("\r")
-			fmt.Print(msgToOutput.msgContent)
+			lcm.console.returnToLineStart()
+			fmt.Fprint(lcm.output, lcm.paint(lcm.output, color, msgToOutput.msgContent))
 
 			// it is possible that the info is shorter than the progress status
 			// in this case we must erase the left over characters from the progress status
 			matchLengthWithSpaces(len(lcm.progressCache), len(msgToOutput.msgContent))
 
 			// print the previous progress status again, so that it's on the last line
-			fmt.Print("\n")
-			fmt.Print(lcm.progressCache)
+			fmt.Fprint(lcm.output, "\n")
+			fmt.Fprint(lcm.output, lcm.progressCache)
 		} else {
-			fmt.Println(msgToOutput.msgContent)
+			fmt.Fprintln(lcm.output, lcm.paint(lcm.output, color, msgToOutput.msgContent))
 		}
 	case eOutputMessageType.Prompt():
 		questionTime := time.Now()
 
 		if lcm.progressCache != "" { // a progress status is already on the last line
 			// print the prompt from the beginning on current line
-			fmt.Print("\r")
-			fmt.Print(msgToOutput.msgContent)
+			lcm.console.returnToLineStart()
+			fmt.Fprint(lcm.output, msgToOutput.msgContent)
 
 			// it is possible that the prompt is shorter than the progress status
 			// in this case we must erase the left over characters from the progress status
 			matchLengthWithSpaces(len(lcm.progressCache), len(msgToOutput.msgContent))
 
 		} else {
-			fmt.Print(msgToOutput.msgContent)
+			fmt.Fprint(lcm.output, msgToOutput.msgContent)
 		}
 
 		// example output: Please confirm with: [Y] Yes  [N] No  [A] Yes for all  [L] No for all
-		fmt.Print(" Please confirm with:")
+		fmt.Fprint(lcm.output, " Please confirm with:")
 		for _, option := range msgToOutput.promptDetails.ResponseOptions {
-			fmt.Printf(" [%s] %s ", strings.ToUpper(option.ResponseString), option.UserFriendlyResponseType)
+			fmt.Fprintf(lcm.output, " [%s] %s ", strings.ToUpper(option.ResponseString), option.UserFriendlyResponseType)
 		}
 
 		// read the response to the prompt and send it back through the channel
-		msgToOutput.inputChannel <- lcm.getInputAfterTime(questionTime)
+		lcm.answerPrompt(msgToOutput, questionTime)
 	}
 }
 
@@ -439,59 +902,63 @@ type WorkController interface {
 	ReportProgressOrExit(mgr LifecycleMgr) (totalKnownCount uint32) // print the progress status, optionally exit the application if work is done
 }
 
-// AllowReinitiateProgressReporting must be called before running an cleanup job, to allow the initiation of that job's
-// progress reporting to begin
-func (lcm *lifecycleMgr) AllowReinitiateProgressReporting() {
-	atomic.StoreInt32(&lcm.waitEverCalled, 0)
-}
-
-// isInteractive indicates whether the application was spawned by an actual user on the command
+// InitiateProgressReporting starts reporting the progress of a job.
+// Each job is reported by its own routine, so that one job waiting (e.g. on a prompt) doesn't hold up the others,
+// and the routine ends when the job calls Exit. If the job is the only one, this works just as it always has;
+// if there are several, their progress is combined.
 func (lcm *lifecycleMgr) InitiateProgressReporting(jc WorkController) {
-	if !atomic.CompareAndSwapInt32(&lcm.waitEverCalled, 0, 1) {
-		return
-	}
+	reporter := lcm.registerProgressReporter(jc)
+
+	lcm.cancelWatcherOnce.Do(func() {
+		// cancelChannel will be notified when os receives os.Interrupt and os.Kill signals,
+		// and SIGTERM, which is how container orchestrators ask us to stop
+		signal.Notify(lcm.cancelChannel, os.Interrupt, os.Kill, syscall.SIGTERM)
+		go lcm.watchCancellation()
+	})
 
-	// this go routine never returns
-	// it will terminate the whole process eventually when the work is complete
 	go func() {
 		const progressFrequencyThreshold = 1000000
 		var oldCount, newCount uint32
 
-		// cancelChannel will be notified when os receives os.Interrupt and os.Kill signals
-		signal.Notify(lcm.cancelChannel, os.Interrupt, os.Kill)
-
 		cancelCalled := false
 
 		doCancel := func() {
 			cancelCalled = true
-			lcm.Info("Cancellation requested. Beginning clean shutdown...")
-			jc.Cancel(lcm)
+			jc.Cancel(reporter)
 		}
 
 		for {
 			select {
-			case <-lcm.cancelChannel:
+			case <-reporter.cancelRequests:
 				doCancel()
 				continue // to exit on next pass through loop
 			default:
-				newCount = jc.ReportProgressOrExit(lcm)
+				newCount = jc.ReportProgressOrExit(reporter)
+			}
+
+			if reporter.isFinished() {
+				return // the job has ended, without ending the process
 			}
 
 			wait := 2 * time.Second
-			if newCount >= progressFrequencyThreshold && !cancelCalled {
+			if lcm.progressInterval != 0 {
+				wait = lcm.progressInterval // the user's choice overrides our own adjustments below
+			} else if newCount >= progressFrequencyThreshold && !cancelCalled {
 				// report less on progress  - to save on the CPU costs of doing so and because, if there are this many files,
 				// its going to be a long job anyway, so no need to report so often
 				wait = 2 * time.Minute
 				if oldCount < progressFrequencyThreshold {
-					lcm.Info(fmt.Sprintf("Reducing progress output frequency to %v, because there are over %d files", wait, progressFrequencyThreshold))
+					lcm.Info(Localize(EMessageKey.ReducingProgressFrequency(), wait, progressFrequencyThreshold))
 				}
 			}
 
 			// wait a bit before fetching job status again, as fetching has costs associated with it on the backend
 			select {
-			case <-lcm.cancelChannel:
+			case <-reporter.cancelRequests:
 				doCancel()
 			case <-time.After(wait):
+			case <-lcm.shutdownCtx.Done():
+				return // library mode, where the process carries on without us
 			}
 
 			oldCount = newCount
@@ -499,6 +966,22 @@ func (lcm *lifecycleMgr) InitiateProgressReporting(jc WorkController) {
 	}()
 }
 
+// a signal (or `cancel` from stdin) cancels every job that is running
+func (lcm *lifecycleMgr) watchCancellation() {
+	for range lcm.cancelChannel {
+		lcm.Info(Localize(EMessageKey.CancellationRequested()))
+
+		lcm.progressReportersLock.Lock()
+		for _, r := range lcm.progressReporters {
+			select {
+			case r.cancelRequests <- struct{}{}:
+			default: // it has yet to act on the last request
+			}
+		}
+		lcm.progressReportersLock.Unlock()
+	}
+}
+
 func (lcm *lifecycleMgr) GetEnvironmentVariable(env EnvironmentVariable) string {
 	value := os.Getenv(env.Name)
 	if value == "" {
@@ -547,7 +1030,47 @@ func (lcm *lifecycleMgr) E2EEnableAwaitAllowOpenFiles(enable bool) {
 	}
 }
 
-// captures the common logic of exiting if there's an expected error
+// RegisterCleanupHook adds a func that must run before the process exits, e.g. to flush logs.
+// Hooks run in the reverse order of their registration (like defers), on every path that exits through
+// the lifecycle manager. They cannot run if the process is killed outright (e.g. by SIGKILL).
+func (lcm *lifecycleMgr) RegisterCleanupHook(hook func()) {
+	lcm.cleanupHooksLock.Lock()
+	defer lcm.cleanupHooksLock.Unlock()
+	lcm.cleanupHooks = append(lcm.cleanupHooks, hook)
+}
+
+func (lcm *lifecycleMgr) runCleanupHooks() {
+	lcm.cleanupHooksLock.Lock()
+	hooks := lcm.cleanupHooks
+	lcm.cleanupHooks = nil // so that they only run once
+	lcm.cleanupHooksLock.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		func() {
+			// a failing hook must not stop the others, or stop us from exiting
+			defer func() { _ = recover() }()
+			hooks[i]()
+		}()
+	}
+}
+
+// all exits must come through here, so that the cleanup hooks get to run
+func (lcm *lifecycleMgr) exitProcess(exitCode ExitCode) {
+	lcm.drainMessageQueue()
+	lcm.reportDroppedMessages()
+	lcm.runCleanupHooks()
+
+	if lcm.returnOnExit {
+		lcm.exitCode = exitCode
+		lcm.shutdown()
+		return
+	}
+	os.Exit(int(exitCode))
+}
+
+// captures the common logic of exiting if there's an expected error.
+// It's only for errors that can't happen unless there's a bug. Errors from the environment (e.g. a full disk)
+// should be reported with ReportError instead, so that the user gets a message that they can act on, not a stack trace
 func PanicIfErr(err error) {
 	if err != nil {
 		panic(err)
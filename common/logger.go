@@ -28,6 +28,7 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -112,6 +113,7 @@ type jobLogger struct {
 	logger            *log.Logger       // The Job's logger
 	appLogger         ILogger
 	sanitizer         pipeline.LogSanitizer
+	writeFailure      *sync.Once // so that a failure to write to the log is only reported once
 }
 
 func NewJobLogger(jobID JobID, minimumLevelToLog LogLevel, appLogger ILogger, logFileFolder string) ILoggerResetable {
@@ -125,6 +127,7 @@ func NewJobLogger(jobID JobID, minimumLevelToLog LogLevel, appLogger ILogger, lo
 		minimumLevelToLog: minimumLevelToLog.ToPipelineLogLevel(),
 		logFileFolder:     logFileFolder,
 		sanitizer:         NewAzCopyLogSanitizer(),
+		writeFailure:      &sync.Once{},
 	}
 }
 
@@ -135,7 +138,10 @@ func (jl *jobLogger) OpenLog() {
 
 	file, err := os.OpenFile(path.Join(jl.logFileFolder, jl.jobID.String()+".log"),
 		os.O_RDWR|os.O_CREATE|os.O_APPEND, DEFAULT_FILE_PERM)
-	PanicIfErr(err)
+	if err != nil {
+		GetLifecycleMgr().ReportError(NewAzCopyError(EErrorCode.LogFile(), "open the log file", err))
+		return
+	}
 
 	jl.file = file
 
@@ -168,8 +174,10 @@ func (jl *jobLogger) CloseLog() {
 	}
 
 	jl.logger.Println("Closing Log")
-	err := jl.file.Close()
-	PanicIfErr(err)
+	if err := jl.file.Close(); err != nil {
+		// the job is already done, so this is only worth a warning. It usually means the last of the log didn't get written
+		GetLifecycleMgr().Warn(DescribeError(NewAzCopyError(EErrorCode.LogFile(), "close the log file", err)))
+	}
 }
 
 func (jl jobLogger) Log(loglevel pipeline.LogLevel, msg string) {
@@ -185,7 +193,16 @@ func (jl jobLogger) Log(loglevel pipeline.LogLevel, msg string) {
 		msg = strings.Replace(msg, "\n", lineEnding, -1)
 	}
 	if jl.ShouldLog(loglevel) {
-		jl.logger.Println(msg)
+		jl.println(msg)
+	}
+}
+
+// a failure to write is only reported the first time, since the later writes will most likely fail the same way (e.g. disk full)
+func (jl jobLogger) println(msg string) {
+	if err := jl.logger.Output(3, msg+"\n"); err != nil {
+		jl.writeFailure.Do(func() {
+			GetLifecycleMgr().Warn(DescribeError(NewAzCopyError(EErrorCode.LogFile(), "write to the log file", err)))
+		})
 	}
 }
 
@@ -0,0 +1,126 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MessageKey identifies a message that is shown to the user, so that it can be looked up in the catalog for their locale
+var EMessageKey = MessageKey("")
+
+type MessageKey string
+
+func (MessageKey) CancellationRequested() MessageKey { return MessageKey("CancellationRequested") }
+func (MessageKey) ReducingProgressFrequency() MessageKey {
+	return MessageKey("ReducingProgressFrequency")
+}
+func (MessageKey) PromptTimedOut() MessageKey         { return MessageKey("PromptTimedOut") }
+func (MessageKey) PromptNoInput() MessageKey          { return MessageKey("PromptNoInput") }
+func (MessageKey) InvalidChoice() MessageKey          { return MessageKey("InvalidChoice") }
+func (MessageKey) CopyJobSummary() MessageKey         { return MessageKey("CopyJobSummary") }
+func (MessageKey) SyncJobSummary() MessageKey         { return MessageKey("SyncJobSummary") }
+func (MessageKey) ResumeJobSummary() MessageKey       { return MessageKey("ResumeJobSummary") }
+func (MessageKey) CleanupNothingToDelete() MessageKey { return MessageKey("CleanupNothingToDelete") }
+func (MessageKey) ErrorWithHint() MessageKey          { return MessageKey("ErrorWithHint") }
+func (MessageKey) ErrorHintInput() MessageKey         { return MessageKey("ErrorHintInput") }
+func (MessageKey) ErrorHintLogFile() MessageKey       { return MessageKey("ErrorHintLogFile") }
+func (MessageKey) ErrorHintFileSystem() MessageKey    { return MessageKey("ErrorHintFileSystem") }
+func (MessageKey) MessagesDropped() MessageKey        { return MessageKey("MessagesDropped") }
+func (MessageKey) JobPaused() MessageKey              { return MessageKey("JobPaused") }
+
+// a message catalog maps each key to a format string, for fmt.Sprintf
+type messageCatalog map[MessageKey]string
+
+const DefaultLocale = "en-US"
+
+// every message must be in the default catalog. Other catalogs may leave messages out, and the default is used for those
+var messageCatalogs = map[string]messageCatalog{
+	DefaultLocale: enUSMessages,
+}
+
+var localeLock sync.RWMutex
+var activeCatalog = messageCatalogs[DefaultLocale]
+
+// SetLocale chooses the catalog that messages are taken from. If there's none for the exact locale (e.g. en-GB),
+// one for the same language is used (e.g. en-US). If there's none for the language either, the default locale is used,
+// and an error says so.
+func SetLocale(locale string) error {
+	catalog, found := findMessageCatalog(locale)
+
+	localeLock.Lock()
+	defer localeLock.Unlock()
+	activeCatalog = catalog
+
+	if !found {
+		return fmt.Errorf("there are no messages for locale '%s', so %s is used instead", locale, DefaultLocale)
+	}
+	return nil
+}
+
+func findMessageCatalog(locale string) (catalog messageCatalog, found bool) {
+	if locale == "" {
+		return messageCatalogs[DefaultLocale], true
+	}
+
+	// accept the forms used by POSIX (en_US.UTF-8) as well as by Windows and the web (en-US)
+	locale = strings.Replace(strings.SplitN(locale, ".", 2)[0], "_", "-", -1)
+	language := strings.SplitN(locale, "-", 2)[0]
+
+	// sorted, so that the choice of catalog for a language doesn't depend on map ordering
+	names := make([]string, 0, len(messageCatalogs))
+	for name := range messageCatalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.EqualFold(name, locale) {
+			return messageCatalogs[name], true
+		}
+	}
+	for _, name := range names {
+		if strings.EqualFold(strings.SplitN(name, "-", 2)[0], language) {
+			return messageCatalogs[name], true
+		}
+	}
+
+	return messageCatalogs[DefaultLocale], false
+}
+
+// Localize returns the message for the key, in the chosen locale, formatted with the given args
+func Localize(key MessageKey, args ...interface{}) string {
+	localeLock.RLock()
+	format, ok := activeCatalog[key]
+	localeLock.RUnlock()
+
+	if !ok {
+		format, ok = messageCatalogs[DefaultLocale][key]
+	}
+	if !ok {
+		return string(key) // a bug, but better that the user sees something than nothing at all
+	}
+
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,69 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+var enUSMessages = messageCatalog{
+	EMessageKey.CancellationRequested():     "Cancellation requested. Beginning clean shutdown...",
+	EMessageKey.ReducingProgressFrequency(): "Reducing progress output frequency to %v, because there are over %d files",
+	EMessageKey.PromptTimedOut():            "No answer was given within %v, so assuming %s",
+	EMessageKey.PromptNoInput():             "There is no input to read an answer from, so assuming %s",
+	EMessageKey.InvalidChoice():             "'%s' is not one of the choices. Please answer with one of: %s",
+	EMessageKey.CleanupNothingToDelete():    "Cleanup completed (nothing needed to be deleted)",
+	EMessageKey.MessagesDropped():           "%d messages were left out, because output could not keep up with them.",
+	EMessageKey.JobPaused():                 "Job %s has been paused. To carry on from where it stopped, run: azcopy jobs resume %s",
+
+	EMessageKey.ErrorWithHint():       "%v. %s (error code: %v)",
+	EMessageKey.ErrorHintInput():      "AzCopy will carry on as if nobody is there to answer. To answer questions ahead of time, use --prompt-answers",
+	EMessageKey.ErrorHintLogFile():    "Check that there is free space, and that you can write to the folder set by --log-location or AZCOPY_LOG_LOCATION",
+	EMessageKey.ErrorHintFileSystem(): "Check that you can create folders there, or use AZCOPY_LOG_LOCATION and AZCOPY_JOB_PLAN_LOCATION to choose other ones",
+
+	EMessageKey.CopyJobSummary(): `
+
+Job %s summary
+Elapsed Time (Minutes): %v
+Number of File Transfers: %v
+Number of Folder Property Transfers: %v
+Total Number of Transfers: %v
+Number of Transfers Completed: %v
+Number of Transfers Failed: %v
+Number of Transfers Skipped: %v%s
+TotalBytesTransferred: %v
+Final Job Status: %v%s%s
+`,
+
+	EMessageKey.SyncJobSummary(): `
+Job %s Summary
+Files Scanned at Source: %v
+Files Scanned at Destination: %v
+Elapsed Time (Minutes): %v
+Number of Copy Transfers for Files: %v
+Number of Copy Transfers for Folder Properties: %v 
+Total Number Of Copy Transfers: %v
+Number of Copy Transfers Completed: %v
+Number of Copy Transfers Failed: %v
+Number of Deletions at Destination: %v
+Total Number of Bytes Transferred: %v
+Total Number of Bytes Enumerated: %v
+Final Job Status: %v%s%s
+`,
+
+	EMessageKey.ResumeJobSummary(): "\n\nJob %s summary\nElapsed Time (Minutes): %v\nNumber of File Transfers: %v\nNumber of Folder Property Transfers: %v\nTotal Number Of Transfers: %v\nNumber of Transfers Completed: %v\nNumber of Transfers Failed: %v\nNumber of Transfers Skipped: %v\nTotalBytesTransferred: %v\nFinal Job Status: %v\n",
+}
@@ -0,0 +1,174 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// with DropOldest, this is how many messages can be held before the oldest Info and Debug ones are dropped
+const maxOverflowMessages = 10000
+
+// messages that are waiting for room in the message queue, with the DropOldest and Grow policies
+type messageQueueOverflow struct {
+	policy    QueueOverflowPolicy
+	lock      sync.Mutex
+	pending   []outputMessage // oldest first. The first is the one being moved into the queue
+	wake      chan struct{}   // tells the mover that there are messages pending
+	startOnce sync.Once
+	dropped   int64 // messages that were never output
+	coalesced int64 // progress reports that were dropped, since a later one replaced them
+	peak      int   // the most messages that were ever pending at once
+}
+
+// OutputQueueStats counts the messages that were lost because output couldn't keep up with them
+type OutputQueueStats struct {
+	Dropped        int64 // Info and Debug messages, dropped by the DropOldest policy
+	Coalesced      int64 // progress reports that weren't output, because there was a later one
+	PeakOverflowed int   // the most messages that were held at once, waiting for room in the queue
+}
+
+func (lcm *lifecycleMgr) SetQueueOverflowPolicy(policy QueueOverflowPolicy) {
+	lcm.queueOverflow.policy = policy
+}
+
+func (lcm *lifecycleMgr) OutputQueueStats() OutputQueueStats {
+	o := &lcm.queueOverflow
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return OutputQueueStats{
+		Dropped:        atomic.LoadInt64(&o.dropped),
+		Coalesced:      atomic.LoadInt64(&o.coalesced),
+		PeakOverflowed: o.peak,
+	}
+}
+
+// every message goes into the queue through here, so that the overflow policy applies to all of them
+func (lcm *lifecycleMgr) enqueue(msg outputMessage) {
+	o := &lcm.queueOverflow
+	if o.policy == EQueueOverflowPolicy.DropOldest() || o.policy == EQueueOverflowPolicy.Grow() {
+		lcm.enqueueWithOverflow(msg)
+		return
+	}
+
+	select {
+	case lcm.msgQueue <- msg:
+		return
+	default:
+	}
+
+	// the queue is full
+	if o.policy == EQueueOverflowPolicy.Coalesce() && msg.msgType == eOutputMessageType.Progress() {
+		atomic.AddInt64(&o.coalesced, 1) // there'll be another along in a moment
+		return
+	}
+	lcm.msgQueue <- msg
+}
+
+func (lcm *lifecycleMgr) enqueueWithOverflow(msg outputMessage) {
+	o := &lcm.queueOverflow
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	// while anything is pending, new messages go after it, so that they're still output in order
+	if len(o.pending) == 0 {
+		select {
+		case lcm.msgQueue <- msg:
+			return
+		default:
+		}
+	}
+
+	o.startOnce.Do(func() {
+		o.wake = make(chan struct{}, 1)
+		go lcm.moveOverflowIntoQueue()
+	})
+
+	if msg.msgType == eOutputMessageType.Progress() {
+		o.removeFirstPending(func(m outputMessage) bool { return m.msgType == eOutputMessageType.Progress() }, &o.coalesced)
+	} else if o.policy == EQueueOverflowPolicy.DropOldest() && len(o.pending) >= maxOverflowMessages {
+		// if everything pending matters more than these (e.g. it's all warnings), nothing is dropped, and the overflow grows
+		o.removeFirstPending(func(m outputMessage) bool {
+			return m.msgType == eOutputMessageType.Info() || m.msgType == eOutputMessageType.Debug()
+		}, &o.dropped)
+	}
+
+	o.pending = append(o.pending, msg)
+	if len(o.pending) > o.peak {
+		o.peak = len(o.pending)
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default: // the mover has already been told
+	}
+}
+
+// removes the oldest pending message that matches, and counts it. The first message is skipped, since it's being moved.
+// Must be called with the lock held
+func (o *messageQueueOverflow) removeFirstPending(matches func(outputMessage) bool, counter *int64) {
+	for i := 1; i < len(o.pending); i++ {
+		if matches(o.pending[i]) {
+			o.pending = append(o.pending[:i], o.pending[i+1:]...)
+			atomic.AddInt64(counter, 1)
+			return
+		}
+	}
+}
+
+// moves pending messages into the queue as room becomes available, oldest first
+func (lcm *lifecycleMgr) moveOverflowIntoQueue() {
+	o := &lcm.queueOverflow
+	for {
+		o.lock.Lock()
+		if len(o.pending) == 0 {
+			o.lock.Unlock()
+			select {
+			case <-o.wake:
+				continue
+			case <-lcm.shutdownCtx.Done():
+				return
+			}
+		}
+		next := o.pending[0]
+		o.lock.Unlock()
+
+		// the message stays pending until it's in the queue, so that nothing can overtake it
+		select {
+		case lcm.msgQueue <- next:
+		case <-lcm.shutdownCtx.Done():
+			return
+		}
+
+		o.lock.Lock()
+		o.pending = o.pending[1:]
+		o.lock.Unlock()
+	}
+}
+
+// whether there are messages that are still on their way into the queue
+func (lcm *lifecycleMgr) overflowPending() bool {
+	o := &lcm.queueOverflow
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return len(o.pending) > 0
+}
@@ -26,6 +26,8 @@ import (
 	"os"
 	"sync"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 const lineEnding = "\n"
@@ -46,6 +48,10 @@ type MMF struct {
 	// isMapped is false and gracefully fails the http request (avoiding the
 	// access violation panic).
 	lock sync.RWMutex
+	// inMemory is true if the slice is ordinary memory, rather than a mapped file (see NewInMemoryMMF)
+	inMemory bool
+	// persist, if set, is how an in-memory MMF is flushed (see NewPersistedInMemoryMMF)
+	persist func(data []byte) error
 }
 
 func NewMMF(file *os.File, writable bool, offset int64, length int64) (*MMF, error) {
@@ -65,6 +71,12 @@ func NewMMF(file *os.File, writable bool, offset int64, length int64) (*MMF, err
 // the MMF is unusable.
 func (m *MMF) Unmap() {
 	m.lock.Lock()
+	if m.inMemory {
+		m.slice = nil
+		m.isMapped = false
+		m.lock.Unlock()
+		return
+	}
 	err := syscall.Munmap(m.slice)
 	m.slice = nil
 	PanicIfErr(err)
@@ -72,6 +84,19 @@ func (m *MMF) Unmap() {
 	m.lock.Unlock()
 }
 
+// Flush writes any modified pages back to the file, and waits until they're on disk
+func (m *MMF) Flush() error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if !m.isMapped {
+		return nil
+	}
+	if m.inMemory {
+		return m.flushInMemory()
+	}
+	return unix.Msync(m.slice, unix.MS_SYNC)
+}
+
 func (m *MMF) UseMMF() bool {
 	m.lock.RLock()
 	if !m.isMapped {
@@ -0,0 +1,41 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+// NewInMemoryMMF wraps ordinary memory, so that it can be used where a memory-mapped file would be, e.g. for a job plan
+// that isn't to be written to disk. The data is lost when the process exits, so flushing it does nothing
+func NewInMemoryMMF(data []byte) *MMF {
+	return &MMF{slice: data, isMapped: true, inMemory: true}
+}
+
+// NewPersistedInMemoryMMF is like NewInMemoryMMF, except that flushing it hands the data to persist, which can save it
+// in a form that couldn't be mapped directly (e.g. encrypted)
+func NewPersistedInMemoryMMF(data []byte, persist func(data []byte) error) *MMF {
+	return &MMF{slice: data, isMapped: true, inMemory: true, persist: persist}
+}
+
+// flushInMemory is called by Flush, with the (read) lock held
+func (m *MMF) flushInMemory() error {
+	if m.persist == nil {
+		return nil
+	}
+	return m.persist(m.slice)
+}
@@ -48,6 +48,10 @@ type MMF struct {
 	// isMapped is false and gracefully fails the http request (avoiding the
 	// access violation panic).
 	lock sync.RWMutex
+	// inMemory is true if the slice is ordinary memory, rather than a mapped file (see NewInMemoryMMF)
+	inMemory bool
+	// persist, if set, is how an in-memory MMF is flushed (see NewPersistedInMemoryMMF)
+	persist func(data []byte) error
 }
 
 func NewMMF(file *os.File, writable bool, offset int64, length int64) (*MMF, error) {
@@ -84,6 +88,12 @@ func NewMMF(file *os.File, writable bool, offset int64, length int64) (*MMF, err
 // the MMF is unusable.
 func (m *MMF) Unmap() {
 	m.lock.Lock()
+	if m.inMemory {
+		m.slice = nil
+		m.isMapped = false
+		m.lock.Unlock()
+		return
+	}
 	addr := uintptr(unsafe.Pointer(&(([]byte)(m.slice)[0])))
 	m.slice = []byte{}
 	// Modified pages in the unmapped view are not written to disk until their share count
@@ -99,6 +109,23 @@ func (m *MMF) Unmap() {
 	m.lock.Unlock()
 }
 
+// Flush writes any modified pages back to the file
+func (m *MMF) Flush() error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if !m.isMapped {
+		return nil
+	}
+	if m.inMemory {
+		return m.flushInMemory()
+	}
+	if len(m.slice) == 0 {
+		return nil
+	}
+	addr := uintptr(unsafe.Pointer(&(([]byte)(m.slice)[0])))
+	return os.NewSyscallError("FlushViewOfFile", syscall.FlushViewOfFile(addr, uintptr(m.length)))
+}
+
 func (m *MMF) UseMMF() bool {
 	m.lock.RLock()
 	if !m.isMapped {
@@ -22,6 +22,7 @@ package common
 
 import (
 	"math/bits"
+	"sync/atomic"
 )
 
 // A pool of byte slices
@@ -62,12 +63,13 @@ func (p *simpleSlicePool) Get() []byte {
 	}
 }
 
-func (p *simpleSlicePool) Put(b []byte) {
+func (p *simpleSlicePool) Put(b []byte) (kept bool) {
 	select {
 	case p.c <- b:
-		return
+		return true
 	default:
 		// just throw b away and let it get GC'd if p.c is full
+		return false
 	}
 }
 
@@ -77,6 +79,14 @@ func (p *simpleSlicePool) Put(b []byte) {
 // (E.g. if only had one pool, holding really big slices, it would be wasteful when
 // we only need to put put small amounts of data into them).
 type multiSizeSlicePool struct {
+	// first in the struct, so that they are 64-bit aligned for atomic access on 32-bit platforms
+	atomicRentedBytes int64
+	atomicPooledBytes int64
+
+	// if non-zero, the most bytes that the slices from this pool may add up to, counting both the ones that are
+	// rented out and the ones that are sitting idle in the pool. Idle ones are thrown away to stay within it
+	maxTotalBytes int64
+
 	// It is safe for multiple readers to read this, once we have populated it
 	// See https://groups.google.com/forum/#!topic/golang-nuts/nL8z96SXcDs
 	poolsBySize []*simpleSlicePool
@@ -84,13 +94,26 @@ type multiSizeSlicePool struct {
 
 // Create new slice pool capable of pooling slices up to maxSliceLength in size
 func NewMultiSizeSlicePool(maxSliceLength int64) ByteSlicePooler {
+	return NewMultiSizeSlicePoolWithLimit(maxSliceLength, 0)
+}
+
+// NewMultiSizeSlicePoolWithLimit creates a pool which doesn't keep idle slices if they, plus the slices that are rented out,
+// would add up to more than maxTotalBytes. It does not block a rental. That's up to the caller (i.e. to a CacheLimiter)
+func NewMultiSizeSlicePoolWithLimit(maxSliceLength int64, maxTotalBytes int64) ByteSlicePooler {
 	maxSlotIndex, _ := getSlotInfo(maxSliceLength)
 	poolsBySize := make([]*simpleSlicePool, maxSlotIndex+1)
 	for i := 0; i <= maxSlotIndex; i++ {
 		maxCount := getMaxSliceCountInPool(i)
 		poolsBySize[i] = newSimpleSlicePool(maxCount)
 	}
-	return &multiSizeSlicePool{poolsBySize: poolsBySize}
+	return &multiSizeSlicePool{poolsBySize: poolsBySize, maxTotalBytes: maxTotalBytes}
+}
+
+// SliceCapacityFor returns the capacity of the slice that the pool gives out for the given length.
+// That's the amount of memory the slice really uses
+func SliceCapacityFor(length int64) int64 {
+	_, maxCapInSlot := getSlotInfo(length)
+	return int64(maxCapInSlot)
 }
 
 var indexOf32KSlot, _ = getSlotInfo(32 * 1024)
@@ -157,6 +180,9 @@ func (mp *multiSizeSlicePool) RentSlice(desiredSize int64) []byte {
 
 	// try to get a pooled slice
 	if typedSlice := pool.Get(); typedSlice != nil {
+		atomic.AddInt64(&mp.atomicPooledBytes, -int64(cap(typedSlice)))
+		atomic.AddInt64(&mp.atomicRentedBytes, int64(cap(typedSlice)))
+
 		// clear out the entire slice up to the capacity
 		// a zero-ing-out loop written in the right form in Go, will be automatically turned into a call to memclr,
 		// which is an optimized Go runtime routine written in assembler
@@ -171,10 +197,27 @@ func (mp *multiSizeSlicePool) RentSlice(desiredSize int64) []byte {
 		return typedSlice
 	}
 
-	// make a new slice if nothing pooled
+	// make a new slice if nothing pooled, first making room for it by throwing away idle ones of other sizes (if we have a limit)
+	rented := atomic.AddInt64(&mp.atomicRentedBytes, int64(maxCapInSlot))
+	if mp.maxTotalBytes > 0 {
+		mp.discardIdleSlices(mp.maxTotalBytes - rented)
+	}
 	return make([]byte, desiredSize, maxCapInSlot)
 }
 
+// discardIdleSlices throws away pooled slices, biggest first, until the pool holds no more than maxPooledBytes
+func (mp *multiSizeSlicePool) discardIdleSlices(maxPooledBytes int64) {
+	for index := len(mp.poolsBySize) - 1; index >= 0; index-- {
+		for atomic.LoadInt64(&mp.atomicPooledBytes) > maxPooledBytes {
+			typedSlice := mp.poolsBySize[index].Get()
+			if typedSlice == nil {
+				break // this one is empty, so move on to the next size down
+			}
+			atomic.AddInt64(&mp.atomicPooledBytes, -int64(cap(typedSlice)))
+		}
+	}
+}
+
 // returns the slice to its pool
 func (mp *multiSizeSlicePool) ReturnSlice(slice []byte) {
 	slotIndex, _ := getSlotInfo(int64(cap(slice))) // be sure to use capacity, not length, here
@@ -182,8 +225,16 @@ func (mp *multiSizeSlicePool) ReturnSlice(slice []byte) {
 	// get the pool that most closely corresponds to the desired size
 	pool := mp.poolsBySize[slotIndex]
 
+	rented := atomic.AddInt64(&mp.atomicRentedBytes, -int64(cap(slice)))
+	pooled := atomic.LoadInt64(&mp.atomicPooledBytes)
+	if mp.maxTotalBytes > 0 && rented+pooled+int64(cap(slice)) > mp.maxTotalBytes {
+		return // keeping it would take us over the limit, so let it get GC'd
+	}
+
 	// put the slice back into the pool
-	pool.Put(slice)
+	if pool.Put(slice) {
+		atomic.AddInt64(&mp.atomicPooledBytes, int64(cap(slice)))
+	}
 }
 
 // Prune inactive stuff in all the big slots if due (don't worry about the little ones, they don't eat much RAM)
@@ -202,7 +253,9 @@ func (mp *multiSizeSlicePool) Prune() {
 			// With repeated calls of Prune, this will gradually drain idle pools.
 			// But, since Prune is not called very often,
 			// it won't have much adverse impact on active pools.
-			_ = mp.poolsBySize[index].Get()
+			if typedSlice := mp.poolsBySize[index].Get(); typedSlice != nil {
+				atomic.AddInt64(&mp.atomicPooledBytes, -int64(cap(typedSlice)))
+			}
 		}
 	}
 }
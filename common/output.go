@@ -24,8 +24,13 @@ func (outputMessageType) EndOfJob() outputMessageType { return outputMessageType
 //   confirm whether we also need a separate exit code to signal process exit. For now, let's assume that anything listening to our stdout
 //   will detect process exit (if needs to) by detecting that we have closed our stdout.
 
-func (outputMessageType) Error() outputMessageType  { return outputMessageType(4) } // indicate fatal error, exit right after
-func (outputMessageType) Prompt() outputMessageType { return outputMessageType(5) } // ask the user a question after erasing the progress
+func (outputMessageType) Error() outputMessageType   { return outputMessageType(4) } // indicate fatal error, exit right after
+func (outputMessageType) Prompt() outputMessageType  { return outputMessageType(5) } // ask the user a question after erasing the progress
+func (outputMessageType) Warning() outputMessageType { return outputMessageType(6) } // simple print, allowed to float up
+func (outputMessageType) Debug() outputMessageType   { return outputMessageType(7) } // simple print, allowed to float up
+
+// TransferEvent says what happened to one file. It's only output as JSON
+func (outputMessageType) TransferEvent() outputMessageType { return outputMessageType(8) }
 
 func (o outputMessageType) String() string {
 	return enum.StringInt(o, reflect.TypeOf(o))
@@ -38,6 +43,8 @@ type outputMessage struct {
 	exitCode      ExitCode      // only for when the application is meant to exit after printing (i.e. Error or Final)
 	inputChannel  chan<- string // support getting a response from the user
 	promptDetails PromptDetails
+	promptTimeout time.Duration // zero means wait for the answer forever
+	presetAnswer  string        // if non-empty, the answer to the prompt, which was given ahead of time
 }
 
 func (m outputMessage) shouldExitProcess() bool {
@@ -74,16 +81,29 @@ func GetJsonStringFromTemplate(template interface{}) string {
 }
 
 // defines the general output template when the format is set to json
+// Each message is written as a single line, so consumers can read the output one line (i.e. one object) at a time
 type JsonOutputTemplate struct {
 	TimeStamp      time.Time
 	MessageType    string
 	MessageContent string // a simple string for INFO and ERROR, a serialized JSON for INIT, PROGRESS, EXIT
 	PromptDetails  PromptDetails
+
+	// the same content as MessageContent, but embedded as a JSON object rather than as a string,
+	// so that wrappers can consume it without a second round of parsing. Only present when MessageContent is JSON
+	Payload json.RawMessage `json:",omitempty"`
 }
 
 func newJsonOutputTemplate(messageType outputMessageType, messageContent string, promptDetails PromptDetails) *JsonOutputTemplate {
-	return &JsonOutputTemplate{TimeStamp: time.Now(), MessageType: messageType.String(),
+	t := &JsonOutputTemplate{TimeStamp: time.Now(), MessageType: messageType.String(),
 		MessageContent: messageContent, PromptDetails: promptDetails}
+
+	switch messageType {
+	case eOutputMessageType.Init(), eOutputMessageType.Progress(), eOutputMessageType.EndOfJob(), eOutputMessageType.TransferEvent():
+		if trimmed := strings.TrimSpace(messageContent); strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+			t.Payload = json.RawMessage(trimmed)
+		}
+	}
+	return t
 }
 
 type InitMsgJsonTemplate struct {
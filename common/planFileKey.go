@@ -0,0 +1,63 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+)
+
+// PlanFileKeySize is the size, in bytes, of the key that encrypts the job plan files (an AES-256 key)
+const PlanFileKeySize = 32
+
+var planFileKey = struct {
+	sync.Mutex
+	key []byte
+}{}
+
+// GetPlanFileKey gets the key that the job plan files are encrypted with, when they are encrypted with a key kept by the OS
+// rather than with a passphrase. The key is made the first time it's needed, and kept by the OS from then on, where only
+// the current user can get at it. On Windows, the key is kept, protected, in a file in planFolder
+func GetPlanFileKey(planFolder string) ([]byte, error) {
+	planFileKey.Lock()
+	defer planFileKey.Unlock()
+	if planFileKey.key != nil {
+		return planFileKey.key, nil
+	}
+	key, err := loadOrCreatePlanFileKey(planFolder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the key that the plan files are encrypted with: %v", err)
+	}
+	if len(key) != PlanFileKeySize {
+		return nil, fmt.Errorf("the key that the plan files are encrypted with is %d bytes long, rather than %d", len(key), PlanFileKeySize)
+	}
+	planFileKey.key = key
+	return key, nil
+}
+
+func newPlanFileKey() ([]byte, error) {
+	key := make([]byte, PlanFileKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jiacfan/keychain"
+)
+
+const planFileKeyServiceName = "AzCopyV10"
+const planFileKeyAccountName = "AzCopyPlanFileKey"
+
+// loadOrCreatePlanFileKey keeps the key in the user's default (login) keychain, with the same settings as the credential cache
+func loadOrCreatePlanFileKey(planFolder string) ([]byte, error) {
+	query := keychain.NewItem()
+	query.SetSecClass(keychain.SecClassGenericPassword)
+	query.SetService(planFileKeyServiceName)
+	query.SetAccount(planFileKeyAccountName)
+	query.SetMatchLimit(keychain.MatchLimitOne)
+	query.SetReturnData(true)
+	results, err := keychain.QueryItem(query)
+	if err != nil {
+		err = handleGenericKeyChainSecError(err)
+		return nil, fmt.Errorf("failed to load key, %v", err)
+	}
+	if len(results) > 1 {
+		return nil, errors.New("invalid state, more than one key found")
+	}
+	if len(results) == 1 {
+		return results[0].Data, nil
+	}
+
+	key, err := newPlanFileKey()
+	if err != nil {
+		return nil, err
+	}
+	item := keychain.NewItem()
+	item.SetSecClass(keychain.SecClassGenericPassword)
+	item.SetService(planFileKeyServiceName)
+	item.SetAccount(planFileKeyAccountName)
+	item.SetData(key)
+	item.SetSynchronizable(keychain.SynchronizableNo)
+	item.SetAccessible(keychain.AccessibleAfterFirstUnlockThisDeviceOnly)
+	if err = keychain.AddItem(item); err != nil {
+		err = handleGenericKeyChainSecError(err)
+		return nil, fmt.Errorf("failed to save key, %v", err)
+	}
+	return key, nil
+}
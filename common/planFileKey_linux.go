@@ -0,0 +1,56 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/jiacfan/keyctl"
+)
+
+const planFileKeyName = "AzCopyPlanFileKey"
+
+// loadOrCreatePlanFileKey keeps the key in the session keyring, like the credential cache does. So, like cached logins,
+// the key (and with it, anything encrypted with it) is lost when the user logs out
+func loadOrCreatePlanFileKey(planFolder string) ([]byte, error) {
+	keyring, err := keyctl.SessionKeyring()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keyring, %v", err)
+	}
+	if key, err := keyring.Search(planFileKeyName); err == nil {
+		return key.Get()
+	}
+
+	b, err := newPlanFileKey()
+	if err != nil {
+		return nil, err
+	}
+	k, err := keyring.Add(planFileKeyName, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save key, %v", err)
+	}
+	// Set permissions to only current user.
+	if err = keyctl.SetPerm(k, keyctl.PermUserAll); err != nil {
+		_ = k.Unlink()
+		return nil, fmt.Errorf("failed to set permission for key, %v", err)
+	}
+	return b, nil
+}
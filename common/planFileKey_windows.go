@@ -0,0 +1,60 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const planFileKeyFileName = "planFile.key"
+
+// loadOrCreatePlanFileKey keeps the key in a file in the plan folder, protected with DPAPI (like the credential cache),
+// so that only the current user, on this machine, can decrypt it
+func loadOrCreatePlanFileKey(planFolder string) ([]byte, error) {
+	keyFilePath := filepath.Join(planFolder, planFileKeyFileName)
+	entropy := newDataBlob([]byte(azcopyverbose))
+
+	b, err := ioutil.ReadFile(keyFilePath)
+	if err == nil {
+		key, err := decrypt(b, entropy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key file %q, %v", keyFilePath, err)
+		}
+		return key, nil
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read key file %q, %v", keyFilePath, err)
+	}
+
+	key, err := newPlanFileKey()
+	if err != nil {
+		return nil, err
+	}
+	if b, err = encrypt(key, entropy); err != nil {
+		return nil, fmt.Errorf("failed to encrypt key, %v", err)
+	}
+	if err = ioutil.WriteFile(keyFilePath, b, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file %q, %v", keyFilePath, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,51 @@
+// +build !windows
+
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"io"
+	"net"
+	"os"
+)
+
+type unixSocketProgressListener struct {
+	net.Listener
+}
+
+func (l unixSocketProgressListener) Accept() (io.WriteCloser, error) {
+	return l.Listener.Accept()
+}
+
+// listenProgressEndpoint listens on the Unix domain socket at the given path
+func listenProgressEndpoint(endpoint string) (progressEventListener, error) {
+	// a socket file left behind by an earlier run would otherwise make the listen fail
+	if info, err := os.Stat(endpoint); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(endpoint)
+	}
+
+	l, err := net.Listen("unix", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return unixSocketProgressListener{l}, nil
+}
@@ -0,0 +1,130 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Refer to https://docs.microsoft.com/en-us/windows/win32/api/winbase/nf-winbase-createnamedpipew for more details.
+var mCreateNamedPipe = dKernel32.NewProc("CreateNamedPipeW")
+
+// Refer to https://docs.microsoft.com/en-us/windows/win32/api/namedpipeapi/nf-namedpipeapi-connectnamedpipe for more details.
+var mConnectNamedPipe = dKernel32.NewProc("ConnectNamedPipe")
+
+const (
+	pipeAccessOutbound        = 0x00000002
+	pipeTypeByte              = 0x00000000
+	pipeRejectRemoteClients   = 0x00000008
+	pipeUnlimitedInstances    = 255
+	fileFlagFirstPipeInstance = 0x00080000
+	progressPipeBufferSize    = 64 * 1024
+	namedPipePrefix           = `\\.\pipe\`
+)
+
+// namedPipeProgressListener creates one instance of the pipe per subscriber, since that's how named pipes work
+type namedPipeProgressListener struct {
+	name     string
+	lock     sync.Mutex
+	closed   bool
+	isFirst  bool
+	pipeName *uint16
+}
+
+// listenProgressEndpoint creates the named pipe with the given name. Plain names, like "azcopy-progress",
+// are treated as shorthand for \\.\pipe\azcopy-progress
+func listenProgressEndpoint(endpoint string) (progressEventListener, error) {
+	if !strings.HasPrefix(strings.ToLower(endpoint), namedPipePrefix) {
+		endpoint = namedPipePrefix + endpoint
+	}
+
+	pipeName, err := syscall.UTF16PtrFromString(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &namedPipeProgressListener{name: endpoint, pipeName: pipeName, isFirst: true}, nil
+}
+
+func (l *namedPipeProgressListener) createInstance() (windows.Handle, error) {
+	openMode := uint32(pipeAccessOutbound)
+	if l.isFirst {
+		openMode |= fileFlagFirstPipeInstance // fail, rather than share, if someone else already owns this name
+	}
+
+	r1, _, e1 := mCreateNamedPipe.Call(uintptr(unsafe.Pointer(l.pipeName)), uintptr(openMode),
+		uintptr(pipeTypeByte|pipeRejectRemoteClients), pipeUnlimitedInstances,
+		progressPipeBufferSize, progressPipeBufferSize, 0, 0)
+	h := windows.Handle(r1)
+	if h == windows.InvalidHandle {
+		return h, e1
+	}
+	l.isFirst = false
+	return h, nil
+}
+
+func (l *namedPipeProgressListener) Accept() (io.WriteCloser, error) {
+	h, err := l.createInstance()
+	if err != nil {
+		return nil, err
+	}
+
+	// blocks until a subscriber connects (or until Close connects to us, to wake us up)
+	r1, _, e1 := mConnectNamedPipe.Call(uintptr(h), 0)
+	if r1 == 0 && e1 != windows.ERROR_PIPE_CONNECTED {
+		_ = windows.CloseHandle(h)
+		return nil, e1
+	}
+
+	l.lock.Lock()
+	closed := l.closed
+	l.lock.Unlock()
+	if closed {
+		_ = windows.CloseHandle(h)
+		return nil, errors.New("progress endpoint is closed")
+	}
+
+	return os.NewFile(uintptr(h), l.name), nil
+}
+
+func (l *namedPipeProgressListener) Close() error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.lock.Unlock()
+
+	// there's no way to interrupt a blocking ConnectNamedPipe, other than connecting to it
+	h, err := windows.CreateFile(l.pipeName, windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err == nil {
+		_ = windows.CloseHandle(h)
+	}
+	return nil
+}
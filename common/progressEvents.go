@@ -0,0 +1,163 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// progressEventListener accepts connections from local processes (e.g. GUI front-ends) that want to follow
+// the progress of a job. There is one implementation per platform, since the endpoint is a Unix domain socket on
+// Linux and macOS, and a named pipe on Windows
+type progressEventListener interface {
+	Accept() (io.WriteCloser, error)
+	Close() error
+}
+
+const progressSubscriberBufferSize = 100
+
+// how long we wait, at exit, for the final events to reach subscribers
+const progressSubscriberDrainTimeout = 2 * time.Second
+
+// progressEventPublisher pushes each progress event, as a line of JSON, to every connected subscriber.
+// The events are the same as those written to stdout by --output-type=json, regardless of what format is being
+// used for stdout, so subscribers need not parse human-readable text.
+// A subscriber that is too slow to keep up misses events, rather than slowing down the job.
+type progressEventPublisher struct {
+	listener    progressEventListener
+	lock        sync.Mutex
+	closed      bool
+	subscribers map[*progressSubscriber]struct{}
+	wg          sync.WaitGroup
+}
+
+type progressSubscriber struct {
+	conn   io.WriteCloser
+	events chan string
+}
+
+func newProgressEventPublisher(endpoint string) (*progressEventPublisher, error) {
+	listener, err := listenProgressEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &progressEventPublisher{
+		listener:    listener,
+		subscribers: make(map[*progressSubscriber]struct{}),
+	}
+	go p.acceptSubscribers()
+	return p, nil
+}
+
+func (p *progressEventPublisher) acceptSubscribers() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			p.lock.Lock()
+			closed := p.closed
+			p.lock.Unlock()
+			if closed {
+				return
+			}
+			time.Sleep(100 * time.Millisecond) // don't spin if something has gone wrong with the endpoint
+			continue
+		}
+
+		s := &progressSubscriber{conn: conn, events: make(chan string, progressSubscriberBufferSize)}
+		p.lock.Lock()
+		if p.closed {
+			p.lock.Unlock()
+			_ = conn.Close()
+			return
+		}
+		p.subscribers[s] = struct{}{}
+		p.wg.Add(1)
+		p.lock.Unlock()
+
+		go p.writeToSubscriber(s)
+	}
+}
+
+func (p *progressEventPublisher) writeToSubscriber(s *progressSubscriber) {
+	defer p.wg.Done()
+	defer s.conn.Close()
+
+	for event := range s.events {
+		if _, err := io.WriteString(s.conn, event+"\n"); err != nil {
+			// the subscriber has gone away
+			p.lock.Lock()
+			if _, present := p.subscribers[s]; present {
+				delete(p.subscribers, s)
+				close(s.events)
+			}
+			p.lock.Unlock()
+
+			// drain anything that was queued before we removed it, so that publish never blocks
+			for range s.events {
+			}
+			return
+		}
+	}
+}
+
+// publish queues the given event for every subscriber, without waiting for it to be written
+func (p *progressEventPublisher) publish(msgType outputMessageType, content string) {
+	event := GetJsonStringFromTemplate(newJsonOutputTemplate(msgType, content, PromptDetails{}))
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for s := range p.subscribers {
+		select {
+		case s.events <- event:
+		default: // subscriber is not keeping up, so it misses this one
+		}
+	}
+}
+
+// close stops accepting subscribers, and gives the existing ones a little time to receive everything that has been published
+func (p *progressEventPublisher) close() {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	p.closed = true
+	for s := range p.subscribers {
+		close(s.events)
+	}
+	p.subscribers = nil
+	p.lock.Unlock()
+
+	_ = p.listener.Close()
+
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(progressSubscriberDrainTimeout):
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// jobProgressReporter is the LifecycleMgr given to each WorkController whose progress is being reported.
+// It tells the lifecycle manager which job each progress update belongs to,
+// so that when several jobs run at once, their progress can be multiplexed onto the display.
+type jobProgressReporter struct {
+	LifecycleMgr
+	lcm            *lifecycleMgr
+	number         int           // 1 for the first job reported, 2 for the next, and so on
+	cancelRequests chan struct{} // the job should be cancelled
+	latestProgress string        // guarded by lcm.progressReportersLock
+	finished       bool          // guarded by lcm.progressReportersLock
+}
+
+func (lcm *lifecycleMgr) registerProgressReporter(jc WorkController) *jobProgressReporter {
+	lcm.progressReportersLock.Lock()
+	defer lcm.progressReportersLock.Unlock()
+
+	lcm.jobsReported++
+	reporter := &jobProgressReporter{
+		LifecycleMgr:   lcm,
+		lcm:            lcm,
+		number:         lcm.jobsReported,
+		cancelRequests: make(chan struct{}, 1),
+	}
+	lcm.progressReporters = append(lcm.progressReporters, reporter)
+	return reporter
+}
+
+func (lcm *lifecycleMgr) unregisterProgressReporter(reporter *jobProgressReporter) {
+	lcm.progressReportersLock.Lock()
+	defer lcm.progressReportersLock.Unlock()
+
+	reporter.finished = true
+	for i, r := range lcm.progressReporters {
+		if r == reporter {
+			lcm.progressReporters = append(lcm.progressReporters[:i], lcm.progressReporters[i+1:]...)
+			break
+		}
+	}
+}
+
+func (r *jobProgressReporter) isFinished() bool {
+	r.lcm.progressReportersLock.Lock()
+	defer r.lcm.progressReportersLock.Unlock()
+
+	return r.finished
+}
+
+func (r *jobProgressReporter) Progress(o OutputBuilder) {
+	if o == nil {
+		r.lcm.Progress(nil)
+		return
+	}
+
+	r.lcm.Progress(func(format OutputFormat) string {
+		if format != EOutputFormat.Text() {
+			return o(format) // each JSON message is about a single job, and identifies it
+		}
+		return r.lcm.multiplexProgress(r, o(format))
+	})
+}
+
+// Exit ends the reporting of the job's progress, as well as doing what Exit always does.
+// With EExitCode.NoExit(), the other jobs carry on.
+func (r *jobProgressReporter) Exit(o OutputBuilder, applicationExitCode ExitCode) {
+	r.lcm.unregisterProgressReporter(r)
+	r.lcm.Exit(o, applicationExitCode)
+}
+
+// multiplexProgress records the given job's latest progress, and returns the progress of all the jobs being reported.
+// A single job's progress is shown as it is. With more than one, each job's progress is labelled with its number,
+// and they are either combined onto the one line that is rewritten in place, or given a line each.
+func (lcm *lifecycleMgr) multiplexProgress(reporter *jobProgressReporter, progress string) string {
+	lcm.progressReportersLock.Lock()
+	defer lcm.progressReportersLock.Unlock()
+
+	reporter.latestProgress = progress
+	if len(lcm.progressReporters) < 2 {
+		return progress
+	}
+
+	all := make([]string, 0, len(lcm.progressReporters))
+	for _, r := range lcm.progressReporters {
+		if r.latestProgress != "" {
+			all = append(all, fmt.Sprintf("Job %d: %s", r.number, r.latestProgress))
+		}
+	}
+
+	separator := " | "
+	if lcm.progressAsLines {
+		separator = "\n"
+	}
+	return strings.Join(all, separator)
+}
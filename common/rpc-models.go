@@ -26,6 +26,13 @@ func (RpcCmd) CancelJob() RpcCmd          { return RpcCmd("Cancel") }
 func (RpcCmd) PauseJob() RpcCmd           { return RpcCmd("PauseJob") }
 func (RpcCmd) ResumeJob() RpcCmd          { return RpcCmd("ResumeJob") }
 func (RpcCmd) GetJobFromTo() RpcCmd       { return RpcCmd("GetJobFromTo") }
+func (RpcCmd) SetJobSettings() RpcCmd     { return RpcCmd("SetJobSettings") }
+func (RpcCmd) GetJobStatus() RpcCmd       { return RpcCmd("GetJobStatus") }
+func (RpcCmd) RetryJob() RpcCmd           { return RpcCmd("RetryJob") }
+func (RpcCmd) CancelTransfers() RpcCmd    { return RpcCmd("CancelTransfers") }
+func (RpcCmd) ExportJob() RpcCmd          { return RpcCmd("ExportJob") }
+func (RpcCmd) ImportJob() RpcCmd          { return RpcCmd("ImportJob") }
+func (RpcCmd) UpdateQueuedJob() RpcCmd    { return RpcCmd("UpdateQueuedJob") }
 
 func (c RpcCmd) String() string {
 	return enum.String(c, reflect.TypeOf(c))
@@ -116,7 +123,13 @@ type CopyJobPartOrderRequest struct {
 	ForceWrite      OverwriteOption // to determine if the existing needs to be overwritten or not. If set to true, existing blobs are overwritten
 	ForceIfReadOnly bool            // Supplements ForceWrite with addition setting for Azure Files objects with read-only attribute
 	AutoDecompress  bool            // if true, source data with encodings that represent compression are automatically decompressed when downloading
+	Compress        bool            // if true, files are gzipped when uploading, unless their extensions say they're compressed already
+	Pack            PackFormat      // if not None, the files of each directory are packed into one archive when uploading
+	Unpack          bool            // if true, tar archives are unpacked into the directory they are downloaded to
+	UploadVHD       bool            // if true, files are uploaded as the page blobs of disks, which must be fixed-size VHDs
+	AppendToBlob    bool            // if true, only the part of each file that isn't in its (append blob) destination yet is uploaded
 	Priority        JobPriority     // priority of the task
+	ActiveHours     ActiveHours     // the daily window in which the job's transfers may use the network
 	FromTo          FromTo
 	Fpo             FolderPropertyOption // passed in from front-end to ensure that front-end and STE agree on the desired behaviour for the job
 	// list of blobTypes to exclude.
@@ -130,13 +143,23 @@ type CopyJobPartOrderRequest struct {
 	BlobAttributes BlobTransferAttributes
 	CommandString  string // commandString hold the user given command which is logged to the Job log file
 	CredentialInfo CredentialInfo
+	PlanLocation   PlanLocation // where the job's plan is kept
 
 	PreserveSMBPermissions         PreservePermissionsOption
 	PreserveSMBInfo                bool
+	PreservePOSIXPermissions       bool // owner, group and mode, between local file systems and ADLS Gen2
+	PreserveFileTimes              bool // creation and last write times of local files, kept in blob metadata
+	PreserveXattrs                 bool // extended attributes of local files, kept in blob metadata
 	S2SGetPropertiesInBackend      bool
 	S2SSourceChangeValidation      bool
 	DestLengthValidation           bool
 	S2SInvalidMetadataHandleOption InvalidMetadataHandleOption
+	S2SPreserveBlobTags            bool // copy the index tags of source blobs to the destination blobs
+	S2SMetadataMerge               MetadataMergeOption
+	S2SCopyMethod                  S2SCopyMethod        // how the data of block blobs is copied, and whether to fall back to reading it through AzCopy
+	ArchivedSource                 ArchivedSourceOption // what to do with source blobs in the archive tier
+	RehydratePriority              RehydratePriority    // how quickly archived blobs are brought back online, when they are rehydrated
+	IncludeVersions                bool                 // every version of each blob is listed, and the versions must be written to the destination in order
 }
 
 // CredentialInfo contains essential credential info which need be transited between modules,
@@ -182,12 +205,21 @@ type BlobTransferAttributes struct {
 	ContentDisposition       string                // Specifies the content disposition
 	CacheControl             string                // Specifies the cache control header
 	BlockBlobTier            BlockBlobTier         // Specifies the tier to set on the block blobs.
+	BlockBlobTierRules       string                // pattern=tier pairs that override BlockBlobTier for the block blobs matching the patterns
 	PageBlobTier             PageBlobTier          // Specifies the tier to set on the page blobs.
 	Metadata                 string                // User-defined Name-value pairs associated with the blob
+	BlobTags                 string                // Index tags to set on the blobs, URL-encoded as in the x-ms-tags header
 	NoGuessMimeType          bool                  // represents user decision to interpret the content-encoding from source file
+	ContentTypeMap           string                // file extensions mapped to content types by the user, URL-encoded; they override the built-in ones
+	SniffContentType         bool                  // when uploading, detect the content type from the content of files whose extensions don't give it
+	HeaderRules              string                // JSON of the rules that set HTTP headers on the uploaded files whose paths match patterns
 	PreserveLastModifiedTime bool                  // when downloading, tell engine to set file's timestamp to timestamp of blob
 	PutMd5                   bool                  // when uploading, should we create and PUT Content-MD5 hashes
 	MD5ValidationOption      HashValidationOption  // when downloading, how strictly should we validate MD5 hashes?
+	PutCrc64                 bool                  // when uploading, should we send CRC64 checksums with each block, and store the file's CRC64 in its metadata
+	Crc64ValidationOption    HashValidationOption  // when downloading, how strictly should we validate CRC64 checksums?
+	PutSha256                bool                  // when uploading, should we store the file's SHA-256 in its metadata
+	Sha256ValidationOption   HashValidationOption  // when downloading, how strictly should we validate SHA-256 digests?
 	BlockSizeInBytes         int64                 // when uploading/downloading/copying, specify the size of each chunk
 	DeleteSnapshotsOption    DeleteSnapshotsOption // when deleting, specify what to do with the snapshots
 }
@@ -233,6 +265,17 @@ type ListJobSummaryResponse struct {
 	TransfersFailed    uint32 `json:",string"`
 	TransfersSkipped   uint32 `json:",string"`
 
+	// files that were never scheduled, because copy's skip-unchanged option found them to be already up to date
+	// at the destination. Not included in TotalTransfers
+	TransfersSkippedUnchanged uint32 `json:",string"`
+
+	// blobs that were never scheduled, because they were in the archive tier and copy was asked to skip them.
+	// Not included in TotalTransfers
+	TransfersSkippedArchived uint32 `json:",string"`
+
+	// how many times transfers were found making no progress, and were restarted. Will be zero if read outside the process running the job
+	TransfersStalled uint32 `json:",string"`
+
 	// includes bytes sent in retries (i.e. has double counting, if there are retries) and in failed transfers
 	BytesOverWire uint64 `json:",string"`
 
@@ -253,14 +296,58 @@ type ListJobSummaryResponse struct {
 	AverageE2EMilliseconds int     `json:",string"`
 	ServerBusyPercentage   float32 `json:",string"`
 	NetworkErrorPercentage float32 `json:",string"`
+	ThrottledRequests      uint64  `json:",string"` // responses with which the service asked us to slow down (500, 503 or 429)
 
 	FailedTransfers  []TransferDetail
 	SkippedTransfers []TransferDetail
 	PerfConstraint   PerfConstraint
 	PerfStrings      []string `json:"-"`
 
+	// downloads whose data didn't match the MD5 hash stored at the source, including those that were kept because
+	// check-md5 was LogOnly. Will be empty if read outside the process running the job
+	Md5Mismatches []TransferDetail
+
 	PerformanceAdvice []PerformanceAdvice
 	IsCleanupJob      bool
+
+	// only present when the job has ended
+	FinalJobSummary *FinalJobSummary `json:",omitempty"`
+}
+
+// FinalJobSummary is the outcome of a job, in a form that is easy for automation to check
+type FinalJobSummary struct {
+	JobID                 JobID
+	ExitCode              ExitCode
+	ElapsedTimeSeconds    float64
+	BytesTransferred      uint64  `json:",string"`
+	TransfersCompleted    uint32  `json:",string"`
+	TransfersFailed       uint32  `json:",string"`
+	TransfersSkipped      uint32  `json:",string"`
+	AverageThroughputMbps float64 // megabits per second, over the whole job
+	LogFileLocation       string  `json:",omitempty"`
+
+	// how many of the skipped files were never scheduled, because skip-unchanged found them already up to date
+	TransfersSkippedUnchanged uint32 `json:",string,omitempty"`
+}
+
+func NewFinalJobSummary(summary ListJobSummaryResponse, elapsed time.Duration, exitCode ExitCode) *FinalJobSummary {
+	var throughput float64
+	if elapsed > 0 {
+		throughput = float64(summary.TotalBytesTransferred) * 8 / (1000 * 1000) / elapsed.Seconds()
+	}
+
+	return &FinalJobSummary{
+		JobID:                 summary.JobID,
+		ExitCode:              exitCode,
+		ElapsedTimeSeconds:    elapsed.Seconds(),
+		BytesTransferred:      summary.TotalBytesTransferred,
+		TransfersCompleted:    summary.TransfersCompleted,
+		TransfersFailed:       summary.TransfersFailed,
+		TransfersSkipped:      summary.TransfersSkipped + summary.TransfersSkippedUnchanged + summary.TransfersSkippedArchived,
+		AverageThroughputMbps: throughput,
+
+		TransfersSkippedUnchanged: summary.TransfersSkippedUnchanged,
+	}
 }
 
 // wraps the standard ListJobSummaryResponse with sync-specific stats
@@ -290,7 +377,9 @@ type TransferDetail struct {
 	Dst                string
 	IsFolderProperties bool
 	TransferStatus     TransferStatus
-	ErrorCode          int32 `json:",string"`
+	ErrorCode          int32  `json:",string"`
+	ErrorMessage       string `json:",omitempty"` // only for failed transfers: why the transfer failed
+	RetryCount         int32  `json:",omitempty"` // only for failed transfers: how many times its requests were retried
 }
 
 type CancelPauseResumeResponse struct {
@@ -305,6 +394,28 @@ type ListJobTransfersResponse struct {
 	Details  []TransferDetail
 }
 
+// SetJobSettingsRequest changes the settings of a job, while it is running
+type SetJobSettingsRequest struct {
+	JobID   JobID
+	CapMbps float64 // the new throughput cap, in megabits per second. Zero removes the cap
+}
+
+type SetJobSettingsResponse struct {
+	ErrorMsg string
+}
+
+// CancelTransfersRequest cancels some of the transfers of a job, while it is running
+type CancelTransfersRequest struct {
+	JobID JobID
+	// the sources of the transfers, either in full or relative to the source of the job
+	Sources []string
+}
+
+type CancelTransfersResponse struct {
+	ErrorMsg           string
+	TransfersCancelled int // how many of the job's transfers will be cancelled
+}
+
 // GetJobFromToRequest indicates request to get job's FromTo info from job part plan header
 type GetJobFromToRequest struct {
 	JobID JobID
@@ -317,3 +428,72 @@ type GetJobFromToResponse struct {
 	Source      string
 	Destination string
 }
+
+// GetJobStatusResponse gives the status of a job, which may be running in another AzCopy process
+type GetJobStatusResponse struct {
+	ErrorMsg  string
+	JobStatus JobStatus
+	Abandoned bool // whether no process has been running the job, or holding it in the queue, for a while
+}
+
+// UpdateQueuedJobRequest records a job that's waiting, with --after-job, for another to complete, so that it can be
+// seen before it has any plan files. It's made again as the job's status changes
+type UpdateQueuedJobRequest struct {
+	JobID         JobID
+	AfterJob      JobID
+	CommandString string
+	JobStatus     JobStatus
+}
+
+type UpdateQueuedJobResponse struct {
+	ErrorMsg string
+}
+
+// RetryJobResponse gives the new job that retries the failed transfers of a job. The new job still has to be resumed, to run it
+type RetryJobResponse struct {
+	ErrorMsg         string
+	JobID            JobID
+	TransfersToRetry uint32
+}
+
+// JobBundle is a job, with all its transfers and their progress, as exported by "jobs export". Importing it into AzCopy
+// on another machine lets the job be carried on there. The fields before Parts only describe the job, for people to read
+type JobBundle struct {
+	DataSchemaVersion  Version // of the plans. A bundle can only be imported by an AzCopy whose plans have the same version
+	JobID              JobID
+	CommandString      string
+	FromTo             string
+	Source             string
+	Destination        string
+	JobStatus          JobStatus
+	TotalTransfers     uint32
+	TransfersCompleted uint32
+	TransfersFailed    uint32
+	Parts              []JobPartBundle
+}
+
+// JobPartBundle is one part of an exported job. Its plan is exactly what was in the part's plan file (decrypted, if the file
+// was encrypted), since that holds all the job's options, as well as its transfers and their status
+type JobPartBundle struct {
+	PartNum PartNumber
+	Plan    []byte
+}
+
+type ExportJobResponse struct {
+	ErrorMsg string
+	Bundle   JobBundle
+}
+
+// ImportJobRequest imports an exported job. Source and Destination, if they're given, replace the job's source and
+// destination, for when they have moved (e.g. a local folder that is somewhere else on the new machine)
+type ImportJobRequest struct {
+	Bundle      JobBundle
+	Source      string
+	Destination string
+}
+
+// ImportJobResponse says how many of the imported job's transfers are left to do. The job still has to be resumed, to do them
+type ImportJobResponse struct {
+	ErrorMsg      string
+	TransfersLeft uint32
+}
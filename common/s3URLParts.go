@@ -40,6 +40,8 @@ import (
 // b. http://s3-aws-region.amazonaws.com/bucket (Region-specific endpoint)
 // Dual stack endpoint(IPv6&IPv4) is also supported (https://docs.aws.amazon.com/AmazonS3/latest/dev/dual-stack-endpoints.html#dual-stack-endpoints-description)
 // i.e. the endpoint in http://bucketname.s3.dualstack.aws-region.amazonaws.com or http://s3.dualstack.aws-region.amazonaws.com/bucketname
+// Google Cloud Storage is parsed too, since its XML API is interoperable with S3: i.e. https://storage.googleapis.com/bucket
+// or https://bucket.storage.googleapis.com
 type S3URLParts struct {
 	Scheme         string // Ex: "https://", "s3://"
 	Host           string // Ex: "s3.amazonaws.com", "s3-eu-west-1.amazonaws.com", "bucket.s3-eu-west-1.amazonaws.com"
@@ -62,6 +64,9 @@ const s3KeywordAmazonAWS = "amazonaws"
 const s3KeywordDualStack = "dualstack"
 const s3EssentialHostPart = "amazonaws.com"
 
+// GoogleCloudStorageEndpoint is the endpoint of Google Cloud Storage's XML API, which accepts S3 requests signed with HMAC keys
+const GoogleCloudStorageEndpoint = "storage.googleapis.com"
+
 var s3HostRegex = regexp.MustCompile(s3HostPattern)
 
 // IsS3URL verfies if a given URL points to S3 URL supported by AzCopy-v10
@@ -69,7 +74,16 @@ func IsS3URL(u url.URL) bool {
 	if _, isS3URL := findS3URLMatches(strings.ToLower(u.Host)); isS3URL {
 		return true
 	}
-	return false
+	return isGoogleCloudStorageHost(strings.ToLower(u.Host))
+}
+
+func isGoogleCloudStorageHost(host string) bool {
+	return host == GoogleCloudStorageEndpoint || strings.HasSuffix(host, "."+GoogleCloudStorageEndpoint)
+}
+
+// IsGoogleCloudStorageEndpoint says whether the endpoint of S3URLParts is Google Cloud Storage, rather than S3
+func IsGoogleCloudStorageEndpoint(endpoint string) bool {
+	return strings.EqualFold(endpoint, GoogleCloudStorageEndpoint)
 }
 
 func findS3URLMatches(host string) (matches []string, isS3Host bool) {
@@ -86,7 +100,8 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 	host := strings.ToLower(u.Host)
 
 	matchSlices, isS3URL := findS3URLMatches(host)
-	if !isS3URL {
+	isGoogle := isGoogleCloudStorageHost(host)
+	if !isS3URL && !isGoogle {
 		return S3URLParts{}, errors.New(invalidS3URLErrorMessage)
 	}
 
@@ -102,7 +117,13 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 	}
 
 	// Check what's the path style, and parse accordingly.
-	if matchSlices[1] != "" { // Go's implementatoin is a bit strange, even if the first subexp fail to be matched, "" will be returned for that sub exp
+	if isGoogle && host != GoogleCloudStorageEndpoint {
+		// A virtual-hosted-style Google URL. Its bucket name may contain periods, so the endpoint is known by its suffix
+		up.BucketName = strings.TrimSuffix(host, "."+GoogleCloudStorageEndpoint)
+		up.ObjectKey = path
+
+		up.Endpoint = GoogleCloudStorageEndpoint
+	} else if !isGoogle && matchSlices[1] != "" { // Go's implementatoin is a bit strange, even if the first subexp fail to be matched, "" will be returned for that sub exp
 		// In this case, it would be in virtual-hosted-style URL, and has host prefix like bucket.s3[-.]
 		up.BucketName = matchSlices[1][:len(matchSlices[1])-1] // Removing the trailing '.' at the end
 		up.ObjectKey = path
@@ -121,8 +142,10 @@ func NewS3URLParts(u url.URL) (S3URLParts, error) {
 
 		up.Endpoint = host
 	}
-	// Check if dualstack is contained in host name
-	if matchSlices[2] == s3KeywordDualStack {
+	// Check if dualstack is contained in host name. Google has neither dual stack endpoints nor regional ones
+	if isGoogle {
+		// nothing to do
+	} else if matchSlices[2] == s3KeywordDualStack {
 		up.isDualStack = true
 		if matchSlices[3] != s3KeywordAmazonAWS {
 			up.Region = matchSlices[3]
@@ -176,6 +199,11 @@ func (p *S3URLParts) URL() url.URL {
 	return u
 }
 
+// IsGoogleCloudStorage says whether the URL is of Google Cloud Storage, rather than S3
+func (p *S3URLParts) IsGoogleCloudStorage() bool {
+	return IsGoogleCloudStorageEndpoint(p.Endpoint)
+}
+
 func (p *S3URLParts) String() string {
 	u := p.URL()
 	return u.String()
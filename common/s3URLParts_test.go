@@ -189,6 +189,49 @@ func (s *s3URLPartsTestSuite) TestS3URLParse(c *chk.C) {
 
 }
 
+func (s *s3URLPartsTestSuite) TestGoogleCloudStorageURLParse(c *chk.C) {
+	u, _ := url.Parse("https://storage.googleapis.com/bucket_name/dir/object.txt")
+	c.Assert(IsS3URL(*u), chk.Equals, true)
+	p, err := NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.Endpoint, chk.Equals, GoogleCloudStorageEndpoint)
+	c.Assert(p.BucketName, chk.Equals, "bucket_name")
+	c.Assert(p.ObjectKey, chk.Equals, "dir/object.txt")
+	c.Assert(p.Region, chk.Equals, "")
+	c.Assert(p.IsGoogleCloudStorage(), chk.Equals, true)
+	c.Assert(p.String(), chk.Equals, "https://storage.googleapis.com/bucket_name/dir/object.txt")
+
+	u, _ = url.Parse("https://my.bucket.storage.googleapis.com/dir/")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.Endpoint, chk.Equals, GoogleCloudStorageEndpoint)
+	c.Assert(p.BucketName, chk.Equals, "my.bucket")
+	c.Assert(p.IsDirectorySyntactically(), chk.Equals, true)
+	c.Assert(p.String(), chk.Equals, "https://my.bucket.storage.googleapis.com/dir/")
+
+	u, _ = url.Parse("https://storage.googleapis.com")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsServiceSyntactically(), chk.Equals, true)
+
+	u, _ = url.Parse("https://bucket.s3.amazonaws.com")
+	p, err = NewS3URLParts(*u)
+	c.Assert(err, chk.IsNil)
+	c.Assert(p.IsGoogleCloudStorage(), chk.Equals, false)
+
+	u, _ = url.Parse("https://storage.googleapis.com.example.com/bucket")
+	c.Assert(IsS3URL(*u), chk.Equals, false)
+}
+
+func (s *s3URLPartsTestSuite) TestGoogleCloudStorageNeedsAnHMACKey(c *chk.C) {
+	err := missingS3AccessKeyError(GoogleCloudStorageEndpoint)
+	c.Assert(err, chk.ErrorMatches, "GOOGLE_HMAC_ACCESS_ID and GOOGLE_HMAC_SECRET .* not with OAuth credentials .*")
+
+	err = missingS3AccessKeyError("s3.amazonaws.com")
+	c.Assert(err, chk.ErrorMatches, "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables must be set .*")
+	c.Assert(strings.Contains(err.Error(), "OAuth"), chk.Equals, false)
+}
+
 func (s *s3URLPartsTestSuite) TestS3URLParseNegative(c *chk.C) {
 	u, _ := url.Parse("http://bucket.amazonawstypo.com")
 	_, err := NewS3URLParts(*u)
@@ -0,0 +1,65 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the types of prompt that can be answered ahead of time
+var scriptablePromptTypes = []PromptType{EPromptType.Cancel(), EPromptType.Overwrite(), EPromptType.DeleteDestination()}
+
+// ParseScriptedAnswers parses answers to prompts that are given ahead of time, in the form PromptType=answer, e.g. Overwrite=n.
+// Blank entries, and those that start with #, are ignored, so that the lines of a response file can be passed in as they are.
+// If a type of prompt is answered more than once, the last answer wins.
+func ParseScriptedAnswers(entries []string) (map[PromptType]string, error) {
+	answers := make(map[PromptType]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("the answer '%s' is not of the form PromptType=answer, e.g. Overwrite=n", entry)
+		}
+
+		promptType, err := parseScriptablePromptType(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		answers[promptType] = strings.TrimSpace(parts[1])
+	}
+	return answers, nil
+}
+
+func parseScriptablePromptType(s string) (PromptType, error) {
+	names := make([]string, len(scriptablePromptTypes))
+	for i, promptType := range scriptablePromptTypes {
+		if strings.EqualFold(string(promptType), s) {
+			return promptType, nil
+		}
+		names[i] = string(promptType)
+	}
+	return EPromptType, fmt.Errorf("'%s' is not a type of prompt. The choices include: %s", s, strings.Join(names, ", "))
+}
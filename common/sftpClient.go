@@ -0,0 +1,628 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// SFTPClient is a minimal client for version 3 of the SSH File Transfer Protocol (draft-ietf-secsh-filexfer-02),
+// which is the version that OpenSSH, and nearly every other server, speaks. Only the requests that copying needs are
+// implemented: stat, listing directories, reading and writing files at given offsets, and making directories.
+// Requests are pipelined, each response being matched to its request by ID, so that many chunks of many files
+// can be read or written at once over the one connection
+type SFTPClient struct {
+	w       io.Writer
+	closer  io.Closer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan sftpResponse
+	err     error // once the connection has failed, every request fails with this
+
+	knownDirs sync.Map // directories that exist, so that MkdirAll needn't ask again for each file in them
+}
+
+const (
+	sftpProtocolVersion   = 3
+	sftpMaxReadLength     = 32 * 1024 // the largest read that servers are required to support
+	sftpMaxReadsInFlight  = 64
+	sftpMaxWriteLength    = 32 * 1024 // as for reads, since servers need only accept packets of about that size
+	sftpMaxWritesInFlight = 64
+	sftpMaxPacketLength   = 1024 * 1024
+)
+
+// packet types
+const (
+	sftpPacketInit       = 1
+	sftpPacketVersion    = 2
+	sftpPacketOpen       = 3
+	sftpPacketClose      = 4
+	sftpPacketRead       = 5
+	sftpPacketWrite      = 6
+	sftpPacketLstat      = 7
+	sftpPacketOpenDir    = 11
+	sftpPacketReadDir    = 12
+	sftpPacketRemove     = 13
+	sftpPacketMkdir      = 14
+	sftpPacketStat       = 17
+	sftpPacketStatus     = 101
+	sftpPacketHandle     = 102
+	sftpPacketData       = 103
+	sftpPacketName       = 104
+	sftpPacketAttrs      = 105
+	sftpOpenFlagRead     = 0x1
+	sftpOpenFlagWrite    = 0x2
+	sftpOpenFlagCreate   = 0x8
+	sftpOpenFlagTruncate = 0x10
+	sftpAttrSize         = 0x1
+	sftpAttrUIDGID       = 0x2
+	sftpAttrPerms        = 0x4
+	sftpAttrACModTime    = 0x8
+	sftpAttrExtended     = 0x80000000
+	sftpStatusOK         = 0
+	sftpStatusEOF        = 1
+	sftpStatusNoSuchFile = 2
+	sftpStatusFailure    = 4
+)
+
+// SFTPStatusError is an error reported by the server
+type SFTPStatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e SFTPStatusError) Error() string {
+	return fmt.Sprintf("SFTP server returned error %d: %s", e.Code, e.Message)
+}
+
+// IsSFTPNotExist says whether the error is the server saying that there's no such file
+func IsSFTPNotExist(err error) bool {
+	var statusErr SFTPStatusError
+	return errors.As(err, &statusErr) && statusErr.Code == sftpStatusNoSuchFile
+}
+
+// NewSFTPClient starts the SFTP session over the given streams, which are normally those of an SSH session's
+// "sftp" subsystem. The closer is closed along with the client
+func NewSFTPClient(r io.Reader, w io.Writer, closer io.Closer) (*SFTPClient, error) {
+	c := &SFTPClient{w: w, closer: closer, pending: make(map[uint32]chan sftpResponse)}
+
+	// the version exchange is the one request and response without an ID
+	init := sftpAppendUint32(sftpNewPacket(sftpPacketInit), sftpProtocolVersion)
+	if err := c.write(init); err != nil {
+		return nil, err
+	}
+	packetType, payload, err := sftpReadPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start the SFTP session: %w", err)
+	}
+	if packetType != sftpPacketVersion {
+		return nil, fmt.Errorf("cannot start the SFTP session: expected the server's version, but got packet type %d", packetType)
+	}
+	if version := (&sftpBuffer{b: payload}).uint32(); version != sftpProtocolVersion {
+		return nil, fmt.Errorf("the SFTP server speaks version %d of the protocol, but only version %d is supported", version, sftpProtocolVersion)
+	}
+
+	go c.receive(r)
+	return c, nil
+}
+
+// Err is the error that ended the connection, if it has ended
+func (c *SFTPClient) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close ends the session. Requests still waiting for their responses fail
+func (c *SFTPClient) Close() error {
+	c.fail(errors.New("the SFTP client has been closed"))
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+// Stat gets the attributes of the file or directory, following symbolic links
+func (c *SFTPClient) Stat(p string) (os.FileInfo, error) {
+	return c.stat(sftpPacketStat, p)
+}
+
+// Lstat gets the attributes of the file or directory, or of the symbolic link itself
+func (c *SFTPClient) Lstat(p string) (os.FileInfo, error) {
+	return c.stat(sftpPacketLstat, p)
+}
+
+func (c *SFTPClient) stat(packetType byte, p string) (os.FileInfo, error) {
+	resp := c.roundTrip(sftpAppendString(c.newRequest(packetType), p))
+	if err := resp.expect(sftpPacketAttrs); err != nil {
+		return nil, fmt.Errorf("cannot stat %s: %w", p, err)
+	}
+	info := resp.payload.attrs(path.Base(p))
+	return info, resp.payload.err
+}
+
+// ReadDir lists the directory, leaving out . and .. The attributes of symbolic links are those of the links themselves
+func (c *SFTPClient) ReadDir(p string) ([]os.FileInfo, error) {
+	handle, err := c.openHandle(sftpAppendString(c.newRequest(sftpPacketOpenDir), p))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open directory %s: %w", p, err)
+	}
+	defer c.closeHandle(handle)
+
+	entries := make([]os.FileInfo, 0)
+	for {
+		resp := c.roundTrip(sftpAppendString(c.newRequest(sftpPacketReadDir), handle))
+		if resp.isEOF() {
+			return entries, nil
+		}
+		if err := resp.expect(sftpPacketName); err != nil {
+			return nil, fmt.Errorf("cannot list directory %s: %w", p, err)
+		}
+		count := resp.payload.uint32()
+		for i := uint32(0); i < count && resp.payload.err == nil; i++ {
+			name := resp.payload.string()
+			resp.payload.string() // the "long name", as ls -l would show it
+			info := resp.payload.attrs(name)
+			if name != "." && name != ".." {
+				entries = append(entries, info)
+			}
+		}
+		if resp.payload.err != nil {
+			return nil, fmt.Errorf("cannot list directory %s: %w", p, resp.payload.err)
+		}
+	}
+}
+
+// Open opens the file for reading
+func (c *SFTPClient) Open(p string) (*SFTPFile, error) {
+	req := sftpAppendString(c.newRequest(sftpPacketOpen), p)
+	req = sftpAppendUint32(req, sftpOpenFlagRead)
+	req = sftpAppendUint32(req, 0) // no attributes, since the file isn't being created
+	handle, err := c.openHandle(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s: %w", p, err)
+	}
+	return &SFTPFile{client: c, handle: handle}, nil
+}
+
+// Create creates the file, or truncates it if it exists, and opens it for writing
+func (c *SFTPClient) Create(p string) (*SFTPFile, error) {
+	req := sftpAppendString(c.newRequest(sftpPacketOpen), p)
+	req = sftpAppendUint32(req, sftpOpenFlagWrite|sftpOpenFlagCreate|sftpOpenFlagTruncate)
+	req = sftpAppendUint32(req, 0) // no attributes, so the server gives the file its default permissions
+	handle, err := c.openHandle(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s: %w", p, err)
+	}
+	return &SFTPFile{client: c, handle: handle}, nil
+}
+
+// Remove deletes the file
+func (c *SFTPClient) Remove(p string) error {
+	if err := c.roundTrip(sftpAppendString(c.newRequest(sftpPacketRemove), p)).expect(sftpPacketStatus); err != nil {
+		return fmt.Errorf("cannot remove %s: %w", p, err)
+	}
+	return nil
+}
+
+// Mkdir makes the directory, whose parent must exist
+func (c *SFTPClient) Mkdir(p string) error {
+	req := sftpAppendUint32(sftpAppendString(c.newRequest(sftpPacketMkdir), p), 0) // no attributes
+	if err := c.roundTrip(req).expect(sftpPacketStatus); err != nil {
+		return fmt.Errorf("cannot make directory %s: %w", p, err)
+	}
+	c.knownDirs.Store(p, true)
+	return nil
+}
+
+// MkdirAll makes the directory, along with any of its parents that don't exist yet. It's called for each file that's
+// written, often at the same time for files in the same directory, so it's fine for another call to make the directory first
+func (c *SFTPClient) MkdirAll(p string) error {
+	p = path.Clean(p)
+	if p == "." || p == "/" {
+		return nil
+	}
+	if _, known := c.knownDirs.Load(p); known {
+		return nil
+	}
+
+	info, err := c.Stat(p)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("cannot make directory %s, since there's a file in the way", p)
+		}
+		c.knownDirs.Store(p, true)
+		return nil
+	} else if !IsSFTPNotExist(err) {
+		return err
+	}
+
+	if err = c.MkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+	if err = c.Mkdir(p); err != nil {
+		if info, statErr := c.Stat(p); statErr != nil || !info.IsDir() {
+			return err
+		}
+		c.knownDirs.Store(p, true) // made by someone else in the meantime
+	}
+	return nil
+}
+
+func (c *SFTPClient) openHandle(req []byte) (string, error) {
+	resp := c.roundTrip(req)
+	if err := resp.expect(sftpPacketHandle); err != nil {
+		return "", err
+	}
+	handle := resp.payload.string()
+	return handle, resp.payload.err
+}
+
+func (c *SFTPClient) closeHandle(handle string) error {
+	return c.roundTrip(sftpAppendString(c.newRequest(sftpPacketClose), handle)).expect(sftpPacketStatus)
+}
+
+// SFTPFile is a file opened for reading or for writing. It's safe to read or write from more than one goroutine at once
+type SFTPFile struct {
+	client *SFTPClient
+	handle string
+}
+
+// ReadAt reads len(p) bytes, unless the file ends first, in which case it returns io.EOF with the bytes it did read.
+// The reads are pipelined, since no server reads more than a little at a time
+func (f *SFTPFile) ReadAt(p []byte, off int64) (int, error) {
+	type pendingRead struct {
+		start, end int // of p
+		response   <-chan sftpResponse
+	}
+	queue := make([]pendingRead, 0, sftpMaxReadsInFlight)
+	requested := 0
+	for n := 0; n < len(p); {
+		for requested < len(p) && len(queue) < sftpMaxReadsInFlight {
+			end := requested + sftpMaxReadLength
+			if end > len(p) {
+				end = len(p)
+			}
+			queue = append(queue, pendingRead{requested, end, f.client.send(f.newReadRequest(off+int64(requested), end-requested))})
+			requested = end
+		}
+
+		r := queue[0]
+		queue = queue[1:]
+		read, err := f.readInto(p[r.start:r.end], off+int64(r.start), <-r.response)
+		n += read
+		if err != nil {
+			return n, err // any reads still in flight are simply left to arrive, and be dropped
+		}
+	}
+	return len(p), nil
+}
+
+// readInto fills the buffer from the response to a read, and if the server returned less than was asked for
+// (as it's allowed to), reads the rest
+func (f *SFTPFile) readInto(buf []byte, off int64, resp sftpResponse) (n int, err error) {
+	for {
+		if resp.isEOF() {
+			return n, io.EOF
+		}
+		if err = resp.expect(sftpPacketData); err != nil {
+			return n, err
+		}
+		data := resp.payload.bytes()
+		if resp.payload.err != nil {
+			return n, resp.payload.err
+		}
+		n += copy(buf[n:], data)
+		if n == len(buf) {
+			return n, nil
+		}
+		resp = f.client.roundTrip(f.newReadRequest(off+int64(n), len(buf)-n))
+	}
+}
+
+// WriteAt writes all of p at the offset. As with reads, the writes are pipelined, since no server takes more than a
+// little at a time
+func (f *SFTPFile) WriteAt(p []byte, off int64) (int, error) {
+	queue := make([]<-chan sftpResponse, 0, sftpMaxWritesInFlight)
+	sent := 0
+	for written := 0; written < len(p); {
+		for sent < len(p) && len(queue) < sftpMaxWritesInFlight {
+			end := sent + sftpMaxWriteLength
+			if end > len(p) {
+				end = len(p)
+			}
+			req := sftpAppendString(f.client.newRequest(sftpPacketWrite), f.handle)
+			req = sftpAppendUint64(req, uint64(off+int64(sent)))
+			queue = append(queue, f.client.send(sftpAppendString(req, string(p[sent:end]))))
+			sent = end
+		}
+
+		response := queue[0]
+		queue = queue[1:]
+		if err := (<-response).expect(sftpPacketStatus); err != nil {
+			return written, err // as with reads, any writes still in flight are left to finish by themselves
+		}
+		written += sftpMaxWriteLength
+		if written > len(p) {
+			written = len(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (f *SFTPFile) newReadRequest(off int64, length int) []byte {
+	req := sftpAppendString(f.client.newRequest(sftpPacketRead), f.handle)
+	req = sftpAppendUint64(req, uint64(off))
+	return sftpAppendUint32(req, uint32(length))
+}
+
+func (f *SFTPFile) Close() error {
+	return f.client.closeHandle(f.handle)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// requests and responses
+
+type sftpResponse struct {
+	packetType byte
+	payload    *sftpBuffer // after the ID
+	err        error
+}
+
+// expect checks that the response is of the expected type, or is a successful status if a status is expected
+func (r sftpResponse) expect(packetType byte) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.packetType == sftpPacketStatus {
+		code := r.payload.uint32()
+		if code == sftpStatusOK && packetType == sftpPacketStatus {
+			return nil
+		}
+		return SFTPStatusError{Code: code, Message: r.payload.string()}
+	}
+	if r.packetType != packetType {
+		return fmt.Errorf("expected an SFTP packet of type %d, but got type %d", packetType, r.packetType)
+	}
+	return nil
+}
+
+func (r sftpResponse) isEOF() bool {
+	return r.err == nil && r.packetType == sftpPacketStatus && (&sftpBuffer{b: r.payload.b}).uint32() == sftpStatusEOF
+}
+
+// newRequest starts a request packet, with the next ID
+func (c *SFTPClient) newRequest(packetType byte) []byte {
+	c.mu.Lock()
+	id := c.nextID
+	c.nextID++
+	c.mu.Unlock()
+	return sftpAppendUint32(sftpNewPacket(packetType), id)
+}
+
+func (c *SFTPClient) roundTrip(req []byte) sftpResponse {
+	return <-c.send(req)
+}
+
+// send sends the request, and returns the channel on which its response will arrive
+func (c *SFTPClient) send(req []byte) <-chan sftpResponse {
+	id := binary.BigEndian.Uint32(req[5:])
+	ch := make(chan sftpResponse, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		ch <- sftpResponse{err: c.err}
+		c.mu.Unlock()
+		return ch
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.write(req); err != nil {
+		c.fail(err)
+	}
+	return ch
+}
+
+func (c *SFTPClient) write(packet []byte) error {
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.w.Write(packet)
+	return err
+}
+
+// receive hands each response to the request that's waiting for it, until the connection fails
+func (c *SFTPClient) receive(r io.Reader) {
+	for {
+		packetType, payload, err := sftpReadPacket(r)
+		if err == io.EOF {
+			err = errors.New("the SFTP server closed the connection")
+		}
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		buf := &sftpBuffer{b: payload}
+		id := buf.uint32()
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ok {
+			ch <- sftpResponse{packetType: packetType, payload: buf, err: buf.err}
+		}
+	}
+}
+
+func (c *SFTPClient) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+	for id, ch := range c.pending {
+		ch <- sftpResponse{err: c.err}
+		delete(c.pending, id)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// encoding
+
+// sftpNewPacket starts a packet, leaving room for its length, which is filled in when it's written
+func sftpNewPacket(packetType byte) []byte {
+	return []byte{0, 0, 0, 0, packetType}
+}
+
+func sftpAppendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func sftpAppendUint64(b []byte, v uint64) []byte {
+	return sftpAppendUint32(sftpAppendUint32(b, uint32(v>>32)), uint32(v))
+}
+
+func sftpAppendString(b []byte, s string) []byte {
+	return append(sftpAppendUint32(b, uint32(len(s))), s...)
+}
+
+func sftpReadPacket(r io.Reader) (packetType byte, payload []byte, err error) {
+	var header [5]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacketLength {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", length)
+	}
+	payload = make([]byte, length-1)
+	if _, err = io.ReadFull(r, payload); err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return header[4], payload, err
+}
+
+// sftpBuffer reads the fields of a packet. A short packet sets err (and gives zero values), so that the
+// fields can be read one after another, and the error checked once at the end
+type sftpBuffer struct {
+	b   []byte
+	err error
+}
+
+func (s *sftpBuffer) next(n int) []byte {
+	if s.err != nil || n < 0 || len(s.b) < n {
+		if s.err == nil {
+			s.err = errors.New("the SFTP packet is too short")
+		}
+		return nil
+	}
+	v := s.b[:n]
+	s.b = s.b[n:]
+	return v
+}
+
+func (s *sftpBuffer) uint32() uint32 {
+	if v := s.next(4); v != nil {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (s *sftpBuffer) uint64() uint64 {
+	if v := s.next(8); v != nil {
+		return binary.BigEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (s *sftpBuffer) bytes() []byte {
+	return s.next(int(s.uint32()))
+}
+
+func (s *sftpBuffer) string() string {
+	return string(s.bytes())
+}
+
+func (s *sftpBuffer) attrs(name string) sftpFileInfo {
+	info := sftpFileInfo{name: name}
+	flags := s.uint32()
+	if flags&sftpAttrSize != 0 {
+		info.size = int64(s.uint64())
+	}
+	if flags&sftpAttrUIDGID != 0 {
+		s.uint32()
+		s.uint32()
+	}
+	if flags&sftpAttrPerms != 0 {
+		info.mode = sftpFileMode(s.uint32())
+	}
+	if flags&sftpAttrACModTime != 0 {
+		s.uint32() // access time
+		info.modTime = time.Unix(int64(s.uint32()), 0)
+	}
+	if flags&sftpAttrExtended != 0 {
+		for count := s.uint32(); count > 0 && s.err == nil; count-- {
+			s.string()
+			s.string()
+		}
+	}
+	return info
+}
+
+// sftpFileMode converts POSIX permissions, including the file type, to a FileMode
+func sftpFileMode(perms uint32) os.FileMode {
+	mode := os.FileMode(perms & 0777)
+	switch perms & 0170000 {
+	case 0100000: // regular file
+	case 0040000:
+		mode |= os.ModeDir
+	case 0120000:
+		mode |= os.ModeSymlink
+	default: // sockets, devices and pipes
+		mode |= os.ModeIrregular
+	}
+	return mode
+}
+
+type sftpFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (i sftpFileInfo) Name() string       { return i.name }
+func (i sftpFileInfo) Size() int64        { return i.size }
+func (i sftpFileInfo) Mode() os.FileMode  { return i.mode }
+func (i sftpFileInfo) ModTime() time.Time { return i.modTime }
+func (i sftpFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i sftpFileInfo) Sys() interface{}   { return nil }
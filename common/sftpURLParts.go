@@ -0,0 +1,114 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SFTPURLParts represents the components of an SFTP URL, e.g. sftp://user@host:2222/data/reports.
+// Unlike the HTTP URLs of the other locations, the path is used as it is, and is not URL-decoded, so that the
+// source root and the relative paths of the files (which are kept separately in the job plan, and joined back
+// together by the STE) never need escaping. Following the convention of other SFTP clients, a path starting
+// with /~/ is relative to the user's home directory, e.g. sftp://user@host/~/reports
+type SFTPURLParts struct {
+	User string
+	Host string
+	Port int
+	Path string // Ex: "/data/reports", or "reports" (relative to the home directory) for "/~/reports"
+}
+
+const SFTPScheme = "sftp://"
+const SFTPDefaultPort = 22
+const sftpHomePrefix = "/~/"
+
+// IsSFTPURL says whether the string is an sftp:// URL
+func IsSFTPURL(raw string) bool {
+	return len(raw) > len(SFTPScheme) && strings.EqualFold(raw[:len(SFTPScheme)], SFTPScheme)
+}
+
+// NewSFTPURLParts parses an sftp:// URL. Passwords aren't accepted in the URL, since it ends up in the job plan
+// and in the logs, so they must be given through the environment instead
+func NewSFTPURLParts(raw string) (SFTPURLParts, error) {
+	p := SFTPURLParts{Port: SFTPDefaultPort}
+	if !IsSFTPURL(raw) {
+		return p, fmt.Errorf("%s is not an SFTP URL. SFTP URLs look like sftp://user@host/path", raw)
+	}
+
+	authority := raw[len(SFTPScheme):]
+	p.Path = "/"
+	if i := strings.Index(authority, "/"); i >= 0 {
+		authority, p.Path = authority[:i], authority[i:]
+	}
+	if strings.HasPrefix(p.Path, sftpHomePrefix) {
+		p.Path = strings.TrimPrefix(p.Path, sftpHomePrefix)
+	} else if p.Path == "/~" {
+		p.Path = "."
+	}
+
+	if i := strings.LastIndex(authority, "@"); i >= 0 {
+		p.User, authority = authority[:i], authority[i+1:]
+		if strings.Contains(p.User, ":") {
+			return p, errors.New("SFTP URLs can't include a password, since the URL is kept in the job's plan and logs. " +
+				"Set the " + EEnvironmentVariable.SFTPPassword().Name + " environment variable instead")
+		}
+	}
+	if p.User == "" {
+		return p, fmt.Errorf("the SFTP URL %s has no user name. Give one in the URL, e.g. sftp://user@host/path", raw)
+	}
+
+	p.Host = authority
+	if host, port, err := net.SplitHostPort(authority); err == nil {
+		p.Host = host
+		if p.Port, err = strconv.Atoi(port); err != nil || p.Port <= 0 || p.Port > 65535 {
+			return p, fmt.Errorf("the SFTP URL %s has an invalid port", raw)
+		}
+	}
+	p.Host = strings.TrimSuffix(strings.TrimPrefix(p.Host, "["), "]")
+	if p.Host == "" {
+		return p, fmt.Errorf("the SFTP URL %s has no host", raw)
+	}
+	return p, nil
+}
+
+// Address is the host and port, for dialing
+func (p SFTPURLParts) Address() string {
+	return net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+}
+
+// String gives the URL back
+func (p SFTPURLParts) String() string {
+	authority := p.User + "@" + p.Host
+	if strings.Contains(p.Host, ":") || p.Port != SFTPDefaultPort {
+		authority = p.User + "@" + p.Address()
+	}
+	if strings.HasPrefix(p.Path, "/") {
+		return SFTPScheme + authority + p.Path
+	}
+	if p.Path == "." {
+		return SFTPScheme + authority + sftpHomePrefix
+	}
+	return SFTPScheme + authority + sftpHomePrefix + p.Path
+}
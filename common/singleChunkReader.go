@@ -66,11 +66,23 @@ type SingleChunkReader interface {
 	// we'll just treat it as a non-zero chunk. That's simpler (to code, to review and to test) than having this code force a prefetch.
 	HasPrefetchedEntirelyZeros() bool
 
+	// PrefetchedDataRanges gives the parts of the chunk that hold data, i.e. that aren't all zeros, looking at the chunk
+	// in units of unitSize bytes. Parts separated by less than minGap bytes of zeros are merged, so that a few scattered
+	// zero units don't split the chunk into many small ranges. If ok is false the chunk hasn't been prefetched, and
+	// (like HasPrefetchedEntirelyZeros) the caller must treat all of it as data.
+	PrefetchedDataRanges(unitSize int64, minGap int64) (ranges []DataRange, ok bool)
+
 	// WriteBufferTo writes the entire contents of the prefetched buffer to h
 	// Panics if the internal buffer has not been prefetched (or if its been discarded after a complete Read)
 	WriteBufferTo(h hash.Hash)
 }
 
+// DataRange is a range of bytes within a chunk
+type DataRange struct {
+	Offset int64 // from the start of the chunk
+	Length int64
+}
+
 // Simple aggregation of existing io interfaces
 type CloseableReaderAt interface {
 	io.ReaderAt
@@ -173,6 +185,47 @@ func (cr *singleChunkReader) HasPrefetchedEntirelyZeros() bool {
 	//       and (c) we would want to check whether it really did offer meaningful real-world performance gain, before introducing use of unsafe.
 }
 
+func (cr *singleChunkReader) PrefetchedDataRanges(unitSize int64, minGap int64) (ranges []DataRange, ok bool) {
+	cr.use()
+	defer cr.unuse()
+
+	if cr.buffer == nil {
+		return nil, false // not prefetched, for the same reason as in HasPrefetchedEntirelyZeros
+	}
+	return dataRanges(cr.buffer, unitSize, minGap), true
+}
+
+// dataRanges finds the units of buf that aren't all zeros, merging the ones that are close together.
+// The last unit may be shorter than unitSize, if the length of buf isn't a multiple of it
+func dataRanges(buf []byte, unitSize int64, minGap int64) []DataRange {
+	ranges := make([]DataRange, 0)
+	for start := int64(0); start < int64(len(buf)); start += unitSize {
+		end := start + unitSize
+		if end > int64(len(buf)) {
+			end = int64(len(buf))
+		}
+		if isAllZeros(buf[start:end]) {
+			continue
+		}
+
+		if n := len(ranges); n > 0 && start-(ranges[n-1].Offset+ranges[n-1].Length) < minGap {
+			ranges[n-1].Length = end - ranges[n-1].Offset // close enough to the last range to join it
+		} else {
+			ranges = append(ranges, DataRange{Offset: start, Length: end - start})
+		}
+	}
+	return ranges
+}
+
+func isAllZeros(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func (cr *singleChunkReader) BlockingPrefetch(fileReader io.ReaderAt, isRetry bool) error {
 	cr.use()
 	defer cr.unuse()
@@ -0,0 +1,63 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+// systemLog is somewhere outside of AzCopy's own logs that job summaries and errors are also written to,
+// so that scheduled runs across many machines can be monitored with the tools that already collect those logs
+type systemLog interface {
+	Info(msg string) error
+	Warning(msg string) error
+	Error(msg string) error
+	Close() error
+}
+
+// SystemLogSource is the name that AzCopy's messages are recorded under, in the system log
+const SystemLogSource = "AzCopy"
+
+func (lcm *lifecycleMgr) EnableSystemLog() error {
+	sysLog, err := openSystemLog()
+	if err != nil {
+		return err
+	}
+
+	lcm.systemLog = sysLog
+	lcm.RegisterCleanupHook(func() { _ = sysLog.Close() })
+	return nil
+}
+
+// only the end of each job, and errors, are of interest to whoever monitors the system log
+func (lcm *lifecycleMgr) writeToSystemLog(msg outputMessage) {
+	if lcm.systemLog == nil || msg.msgContent == "" {
+		return
+	}
+
+	// there's nowhere to report a failure to write to the system log, other than the output it's a copy of
+	switch {
+	case msg.msgType == eOutputMessageType.Error():
+		_ = lcm.systemLog.Error(msg.msgContent)
+	case msg.msgType != eOutputMessageType.EndOfJob():
+		return
+	case msg.exitCode == EExitCode.Error():
+		_ = lcm.systemLog.Warning(msg.msgContent) // the job ran, but not all of it succeeded
+	default:
+		_ = lcm.systemLog.Info(msg.msgContent)
+	}
+}
@@ -0,0 +1,45 @@
+// +build !windows
+
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func openSystemLog() (systemLog, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, SystemLogSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to syslog: %w", err)
+	}
+	return syslogWriter{w: w}, nil
+}
+
+func (s syslogWriter) Info(msg string) error    { return s.w.Info(msg) }
+func (s syslogWriter) Warning(msg string) error { return s.w.Warning(msg) }
+func (s syslogWriter) Error(msg string) error   { return s.w.Err(msg) }
+func (s syslogWriter) Close() error             { return s.w.Close() }
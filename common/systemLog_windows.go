@@ -0,0 +1,54 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// the event IDs that AzCopy's events are recorded with
+const (
+	eventIDJobSummary uint32 = 1
+	eventIDError      uint32 = 2
+)
+
+type eventLogWriter struct {
+	l *eventlog.Log
+}
+
+func openSystemLog() (systemLog, error) {
+	// registering the source needs administrator rights, and only has to be done once per machine.
+	// If it isn't registered, the events are still recorded, but Event Viewer notes that their source is unknown
+	_ = eventlog.InstallAsEventCreate(SystemLogSource, eventlog.Error|eventlog.Warning|eventlog.Info)
+
+	l, err := eventlog.Open(SystemLogSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the Windows Event Log: %w", err)
+	}
+	return eventLogWriter{l: l}, nil
+}
+
+func (e eventLogWriter) Info(msg string) error    { return e.l.Info(eventIDJobSummary, msg) }
+func (e eventLogWriter) Warning(msg string) error { return e.l.Warning(eventIDJobSummary, msg) }
+func (e eventLogWriter) Error(msg string) error   { return e.l.Error(eventIDError, msg) }
+func (e eventLogWriter) Close() error             { return e.l.Close() }
@@ -0,0 +1,92 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"time"
+)
+
+// TransferEventType says what happened to a file
+var ETransferEventType = TransferEventType("")
+
+type TransferEventType string
+
+func (TransferEventType) Started() TransferEventType   { return TransferEventType("Started") }
+func (TransferEventType) Completed() TransferEventType { return TransferEventType("Completed") }
+func (TransferEventType) Failed() TransferEventType    { return TransferEventType("Failed") }
+func (TransferEventType) Skipped() TransferEventType   { return TransferEventType("Skipped") }
+func (TransferEventType) Cancelled() TransferEventType { return TransferEventType("Cancelled") }
+
+// TransferEvent is output for each file, when transfer events are enabled, so that auditing systems can record
+// exactly which files were moved
+type TransferEvent struct {
+	JobID              JobID
+	EventType          TransferEventType
+	Source             string
+	Destination        string
+	Bytes              int64   // the size of the file when Started, and how many bytes were transferred otherwise
+	ElapsedTimeSeconds float64 `json:",omitempty"` // from Started until the transfer ended
+	ErrorMessage       string  `json:",omitempty"` // why the transfer failed
+	CopyMethod         string  `json:",omitempty"` // how the data of a block blob was copied from a remote source
+	TimeStamp          time.Time
+}
+
+// TransferEventTypeOf returns the event for a transfer that ended with the given status
+func TransferEventTypeOf(status TransferStatus) TransferEventType {
+	switch {
+	case status == ETransferStatus.Success():
+		return ETransferEventType.Completed()
+	case status == ETransferStatus.Cancelled() || status == ETransferStatus.CancelledByUser():
+		return ETransferEventType.Cancelled()
+	case status == ETransferStatus.SkippedEntityAlreadyExists() || status == ETransferStatus.SkippedBlobHasSnapshots():
+		return ETransferEventType.Skipped()
+	default:
+		return ETransferEventType.Failed()
+	}
+}
+
+func (lcm *lifecycleMgr) EnableTransferEvents() {
+	lcm.transferEvents = true
+}
+
+// the events are only worth the cost when someone's reading them, and only as JSON can they be
+func (lcm *lifecycleMgr) TransferEventsEnabled() bool {
+	return lcm.transferEvents && lcm.outputFormat == EOutputFormat.Json()
+}
+
+func (lcm *lifecycleMgr) TransferEvent(event TransferEvent) {
+	if !lcm.TransferEventsEnabled() {
+		return
+	}
+	if event.TimeStamp.IsZero() {
+		event.TimeStamp = time.Now()
+	}
+
+	// the URLs may have SAS tokens in them. They're redacted before marshalling, since redaction doesn't respect JSON quoting
+	event.Source = lcm.logSanitizer.SanitizeLogMessage(event.Source)
+	event.Destination = lcm.logSanitizer.SanitizeLogMessage(event.Destination)
+	event.ErrorMessage = lcm.logSanitizer.SanitizeLogMessage(event.ErrorMessage)
+
+	lcm.enqueue(outputMessage{
+		msgContent: GetJsonStringFromTemplate(event),
+		msgType:    eOutputMessageType.TransferEvent(),
+	})
+}
@@ -27,6 +27,7 @@ import (
 )
 
 const IncludeAfterFlagName = "include-after"
+const IncludeBeforeFlagName = "include-before"
 const BackupModeFlagName = "backup" // original name, backup mode, matches the name used for the same thing in Robocopy
 const PreserveOwnerFlagName = "preserve-owner"
 const PreserveOwnerDefault = true
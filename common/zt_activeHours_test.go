@@ -0,0 +1,88 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type activeHoursSuite struct{}
+
+var _ = chk.Suite(&activeHoursSuite{})
+
+func (s *activeHoursSuite) TestParse(c *chk.C) {
+	var a ActiveHours
+	c.Assert(a.Parse("22:00-06:30"), chk.IsNil)
+	c.Assert(a, chk.Equals, ActiveHours{IsSet: true, StartMinute: 22 * 60, EndMinute: 6*60 + 30})
+	c.Assert(a.String(), chk.Equals, "22:00-06:30")
+
+	c.Assert(a.Parse(" 9:05 - 17:00 "), chk.IsNil)
+	c.Assert(a.String(), chk.Equals, "09:05-17:00")
+
+	// empty means always active
+	c.Assert(a.Parse(""), chk.IsNil)
+	c.Assert(a.IsSet, chk.Equals, false)
+
+	for _, bad := range []string{"22:00", "22:00-06:00-07:00", "25:00-06:00", "22:00-6pm", "08:00-08:00"} {
+		c.Assert(a.Parse(bad), chk.NotNil, chk.Commentf(bad))
+	}
+}
+
+func (s *activeHoursSuite) TestIsActiveAt(c *chk.C) {
+	at := func(hour, minute int) time.Time { return time.Date(2020, 3, 10, hour, minute, 0, 0, time.Local) }
+
+	var daytime, overnight ActiveHours
+	c.Assert(daytime.Parse("09:00-17:00"), chk.IsNil)
+	c.Assert(overnight.Parse("22:00-06:00"), chk.IsNil)
+
+	c.Assert(daytime.IsActiveAt(at(8, 59)), chk.Equals, false)
+	c.Assert(daytime.IsActiveAt(at(9, 0)), chk.Equals, true)
+	c.Assert(daytime.IsActiveAt(at(16, 59)), chk.Equals, true)
+	c.Assert(daytime.IsActiveAt(at(17, 0)), chk.Equals, false)
+
+	c.Assert(overnight.IsActiveAt(at(21, 59)), chk.Equals, false)
+	c.Assert(overnight.IsActiveAt(at(22, 0)), chk.Equals, true)
+	c.Assert(overnight.IsActiveAt(at(0, 0)), chk.Equals, true)
+	c.Assert(overnight.IsActiveAt(at(5, 59)), chk.Equals, true)
+	c.Assert(overnight.IsActiveAt(at(6, 0)), chk.Equals, false)
+	c.Assert(overnight.IsActiveAt(at(12, 0)), chk.Equals, false)
+
+	c.Assert(ActiveHours{}.IsActiveAt(at(12, 0)), chk.Equals, true)
+}
+
+func (s *activeHoursSuite) TestNextStart(c *chk.C) {
+	at := func(day, hour, minute int) time.Time { return time.Date(2020, 3, day, hour, minute, 0, 0, time.UTC) }
+
+	var overnight ActiveHours
+	c.Assert(overnight.Parse("22:00-06:00"), chk.IsNil)
+
+	// inside the window, it's now
+	c.Assert(overnight.NextStart(at(10, 23, 0)), chk.Equals, at(10, 23, 0))
+	// before the start, it's later today
+	c.Assert(overnight.NextStart(at(10, 12, 30)), chk.Equals, at(10, 22, 0))
+
+	var morning ActiveHours
+	c.Assert(morning.Parse("01:00-05:00"), chk.IsNil)
+	// after the end, it's tomorrow
+	c.Assert(morning.NextStart(at(10, 6, 0)), chk.Equals, at(11, 1, 0))
+}
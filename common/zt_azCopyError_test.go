@@ -0,0 +1,47 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	chk "gopkg.in/check.v1"
+)
+
+type azCopyErrorSuite struct{}
+
+var _ = chk.Suite(&azCopyErrorSuite{})
+
+func (s *azCopyErrorSuite) TestDescribeErrorGivesAdvice(c *chk.C) {
+	err := fmt.Errorf("job setup failed: %w", NewAzCopyError(EErrorCode.LogFile(), "open the log file", os.ErrPermission))
+
+	c.Assert(ErrorCodeOf(err), chk.Equals, EErrorCode.LogFile())
+	c.Assert(errors.Is(err, os.ErrPermission), chk.Equals, true) // the original error can still be checked for
+	c.Assert(DescribeError(err), chk.Equals, "job setup failed: cannot open the log file: permission denied. "+
+		"Check that there is free space, and that you can write to the folder set by --log-location or AZCOPY_LOG_LOCATION (error code: LogFile)")
+
+	// errors without a code are left as they are
+	plain := errors.New("something else")
+	c.Assert(ErrorCodeOf(plain), chk.Equals, EErrorCode.Unknown())
+	c.Assert(DescribeError(plain), chk.Equals, "something else")
+}
@@ -0,0 +1,117 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"strings"
+
+	chk "gopkg.in/check.v1"
+)
+
+type blobPropertiesSuite struct{}
+
+var _ = chk.Suite(&blobPropertiesSuite{})
+
+func (s *blobPropertiesSuite) TestBlobTagsRoundTrip(c *chk.C) {
+	tags := BlobTags{"project": "alpha", "path": "/data/2020", "note": "a b+c=d"}
+
+	encoded := tags.ToString()
+	c.Assert(strings.Contains(encoded, "project=alpha"), chk.Equals, true)
+
+	decoded, err := ToCommonBlobTagsMap(encoded)
+	c.Assert(err, chk.IsNil)
+	c.Assert(decoded, chk.DeepEquals, tags)
+
+	empty, err := ToCommonBlobTagsMap(BlobTags{}.ToString())
+	c.Assert(err, chk.IsNil)
+	c.Assert(empty, chk.HasLen, 0)
+}
+
+func (s *blobPropertiesSuite) TestBlobTagsValidate(c *chk.C) {
+	c.Assert(BlobTags{"env": "prod", "owner": "team-a", "empty": ""}.Validate(), chk.IsNil)
+
+	tooMany := BlobTags{}
+	for i := 0; i <= maxBlobTags; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	c.Assert(tooMany.Validate(), chk.NotNil)
+
+	c.Assert(BlobTags{"": "v"}.Validate(), chk.NotNil)
+	c.Assert(BlobTags{strings.Repeat("k", 129): "v"}.Validate(), chk.NotNil)
+	c.Assert(BlobTags{"k": strings.Repeat("v", 257)}.Validate(), chk.NotNil)
+	c.Assert(BlobTags{"k?": "v"}.Validate(), chk.NotNil)
+	c.Assert(BlobTags{"k": "v&w"}.Validate(), chk.NotNil)
+}
+
+func (s *blobPropertiesSuite) TestMetadataMergeOptions(c *chk.C) {
+	src := Metadata{"a": "src", "b": "src"}
+	given := Metadata{"b": "given", "c": "given"}
+
+	c.Assert(EMetadataMergeOption.Overwrite().Merge(src, given), chk.DeepEquals, Metadata{"a": "src", "b": "given", "c": "given"})
+	c.Assert(EMetadataMergeOption.KeepSource().Merge(src, given), chk.DeepEquals, Metadata{"a": "src", "b": "src", "c": "given"})
+	c.Assert(EMetadataMergeOption.Replace().Merge(src, given), chk.DeepEquals, given)
+	c.Assert(EMetadataMergeOption.Replace().Merge(src, nil), chk.DeepEquals, src)
+
+	var m MetadataMergeOption
+	c.Assert(m.Parse("keepsource"), chk.IsNil)
+	c.Assert(m, chk.Equals, EMetadataMergeOption.KeepSource())
+	c.Assert(m.Parse("bogus"), chk.NotNil)
+}
+
+func (s *blobPropertiesSuite) TestHeaderRules(c *chk.C) {
+	rules := HeaderRules{
+		{Pattern: "*", CacheControl: "max-age=3600"},
+		{Pattern: "*.html", CacheControl: "no-cache", ContentLanguage: "en"},
+		{Pattern: "downloads/*", ContentDisposition: "attachment"},
+	}
+	given := ResourceHTTPHeaders{ContentType: "text/html", CacheControl: "max-age=60", ContentEncoding: "gzip"}
+
+	headers := rules.Apply(given, "docs/index.html")
+	c.Assert(headers, chk.DeepEquals, ResourceHTTPHeaders{ContentType: "text/html", CacheControl: "no-cache", ContentLanguage: "en", ContentEncoding: "gzip"})
+
+	// a pattern with a / is matched against the whole path, so it doesn't match deeper down
+	headers = rules.Apply(given, "downloads/tool.zip")
+	c.Assert(headers.ContentDisposition, chk.Equals, "attachment")
+	c.Assert(rules.Apply(given, "downloads/old/tool.zip").ContentDisposition, chk.Equals, "")
+
+	decoded, err := ToHeaderRules(rules.ToString())
+	c.Assert(err, chk.IsNil)
+	c.Assert(decoded, chk.DeepEquals, rules)
+	c.Assert(HeaderRules(nil).ToString(), chk.Equals, "")
+}
+
+func (s *blobPropertiesSuite) TestBlockBlobTierRules(c *chk.C) {
+	rules, err := ParseBlockBlobTierRules("*.bak=archive;logs/*=Cool;logs/keep.*=Hot")
+	c.Assert(err, chk.IsNil)
+	c.Assert(rules.ToString(), chk.Equals, "*.bak=Archive;logs/*=Cool;logs/keep.*=Hot")
+
+	c.Assert(rules.TierFor("db/daily.bak", EBlockBlobTier.None()), chk.Equals, EBlockBlobTier.Archive())
+	c.Assert(rules.TierFor("logs/app.log", EBlockBlobTier.Hot()), chk.Equals, EBlockBlobTier.Cool())
+	c.Assert(rules.TierFor("logs/keep.log", EBlockBlobTier.None()), chk.Equals, EBlockBlobTier.Hot()) // the last match wins
+	c.Assert(rules.TierFor("other/app.log", EBlockBlobTier.None()), chk.Equals, EBlockBlobTier.None())
+
+	_, err = ParseBlockBlobTierRules("*.bak")
+	c.Assert(err, chk.ErrorMatches, ".*isn't of the form pattern=tier")
+	_, err = ParseBlockBlobTierRules("*.bak=P10")
+	c.Assert(err, chk.ErrorMatches, ".*isn't a block blob tier.*")
+	_, err = ParseBlockBlobTierRules("[=Cool")
+	c.Assert(err, chk.ErrorMatches, ".*isn't a valid pattern.*")
+}
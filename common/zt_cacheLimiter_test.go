@@ -0,0 +1,72 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"context"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type cacheLimiterSuite struct{}
+
+var _ = chk.Suite(&cacheLimiterSuite{})
+
+func (s *cacheLimiterSuite) TestHardLimiterCountsRealCapacity(c *chk.C) {
+	const oneMB = 1024 * 1024
+	soft := NewCacheLimiter(16 * oneMB)
+	hard := NewHardCacheLimiter(16 * oneMB)
+
+	// 5 MB would be given an 8 MB slice, so only the soft one has room for 9 MB more
+	c.Assert(soft.TryAdd(5*oneMB, true), chk.Equals, true)
+	c.Assert(hard.TryAdd(5*oneMB, true), chk.Equals, true)
+	c.Assert(soft.TryAdd(9*oneMB, true), chk.Equals, true)
+	c.Assert(hard.TryAdd(9*oneMB, true), chk.Equals, false)
+
+	// removing gives back the same amount as was added
+	hard.Remove(5 * oneMB)
+	c.Assert(hard.TryAdd(2*oneMB, true), chk.Equals, true)
+	c.Assert(hard.TryAdd(4*oneMB, true), chk.Equals, true)
+	c.Assert(hard.TryAdd(8*oneMB, true), chk.Equals, true)
+	c.Assert(hard.TryAdd(3*oneMB, true), chk.Equals, false) // 14 MB in use, and this would be charged at 4
+
+	// something that's within the limit, but whose capacity would be beyond it, can still be added on its own
+	hard = NewHardCacheLimiter(6 * oneMB)
+	c.Assert(hard.TryAdd(5*oneMB, true), chk.Equals, true)
+	hard.Remove(5 * oneMB)
+	c.Assert(hard.TryAdd(6*oneMB, true), chk.Equals, true)
+}
+
+func (s *cacheLimiterSuite) TestHardLimiterAdmitsChunkBeyondStrictLimit(c *chk.C) {
+	const oneMB = 1024 * 1024
+	hard := NewHardCacheLimiter(8 * oneMB)
+
+	// 7 MB would be given an 8 MB slice, which is beyond the strict limit of 6 MB. It must still be added,
+	// rather than waiting forever for the relaxed limit to apply
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c.Assert(hard.WaitUntilAdd(ctx, 7*oneMB, func() bool { return false }), chk.IsNil)
+	c.Assert(hard.TryAdd(oneMB, false), chk.Equals, false)
+
+	hard.Remove(7 * oneMB)
+	c.Assert(hard.TryAdd(7*oneMB, false), chk.Equals, true)
+}
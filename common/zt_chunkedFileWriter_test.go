@@ -0,0 +1,165 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	chk "gopkg.in/check.v1"
+	"hash/crc64"
+	"math/rand"
+)
+
+type chunkedFileWriterSuite struct{}
+
+var _ = chk.Suite(&chunkedFileWriterSuite{})
+
+type nullChunkStatusLogger struct{}
+
+func (nullChunkStatusLogger) LogChunkStatus(id ChunkID, reason WaitReason) {}
+
+func (nullChunkStatusLogger) IsWaitingOnFinalBodyReads() bool { return false }
+
+// records the running total of committed bytes, each time it is told about some more
+type recordingBytesReporter struct {
+	total  int64
+	totals []int64
+}
+
+func (r *recordingBytesReporter) ReportWrittenBytes(n int64) {
+	r.total += n // only ever called from the writer's single worker goroutine
+	r.totals = append(r.totals, r.total)
+}
+
+func (s *chunkedFileWriterSuite) TestChunkedFileWriter_ReportsMonotonicByteProgressForSingleLargeFile(c *chk.C) {
+	const chunkSize = 64 * 1024
+	const numChunks = 50
+	const fileSize = chunkSize * numChunks
+
+	// given: one large file, whose chunks arrive out of order (as they do in real downloads)
+	ctx := context.Background()
+	dest := &closeableBuffer{Buffer: &bytes.Buffer{}}
+	reporter := &recordingBytesReporter{}
+	w := NewChunkedFileWriter(ctx, NewMultiSizeSlicePool(chunkSize), NewCacheLimiter(fileSize*2), nullChunkStatusLogger{},
+		reporter, dest, numChunks, 1, EHashValidationOption.NoCheck(), false)
+
+	data := make([]byte, fileSize)
+	rand.Read(data)
+	order := rand.Perm(numChunks)
+
+	// when: we download all the chunks
+	for _, i := range order {
+		offset := int64(i * chunkSize)
+		id := NewChunkID("bigfile", offset, chunkSize)
+		c.Assert(w.WaitToScheduleChunk(ctx, id, chunkSize), chk.IsNil)
+		c.Assert(w.EnqueueChunk(ctx, id, chunkSize, bytes.NewReader(data[offset:offset+chunkSize]), false), chk.IsNil)
+	}
+	_, err := w.Flush(ctx)
+	c.Assert(err, chk.IsNil)
+
+	// then: the file count only changes at the end, but byte progress was reported as each chunk was saved,
+	// and it went up every time
+	c.Assert(dest.Bytes(), chk.DeepEquals, data)
+	c.Assert(reporter.totals, chk.HasLen, numChunks)
+	for i := 1; i < len(reporter.totals); i++ {
+		c.Assert(reporter.totals[i] > reporter.totals[i-1], chk.Equals, true)
+	}
+	c.Assert(reporter.total, chk.Equals, int64(fileSize))
+}
+
+func (s *chunkedFileWriterSuite) TestChunkedFileWriter_ResumingIncludesSavedContentInHash(c *chk.C) {
+	const chunkSize = 16 * 1024
+	const numChunks = 8
+	const savedChunks = 3
+	const fileSize = chunkSize * numChunks
+	const startOffset = chunkSize * savedChunks
+
+	// given: a file whose first few chunks were saved by an earlier run
+	ctx := context.Background()
+	data := make([]byte, fileSize)
+	rand.Read(data)
+	dest := &closeableBuffer{Buffer: &bytes.Buffer{}}
+	reporter := &recordingBytesReporter{}
+	w := NewResumingChunkedFileWriter(ctx, NewMultiSizeSlicePool(chunkSize), NewCacheLimiter(fileSize*2), nullChunkStatusLogger{},
+		reporter, dest, numChunks-savedChunks, 1, EHashValidationOption.FailIfDifferent(), true, nil,
+		startOffset, bytes.NewReader(data[:startOffset]))
+
+	// when: we download the rest of it, out of order
+	for _, i := range rand.Perm(numChunks - savedChunks) {
+		offset := int64(startOffset + i*chunkSize)
+		id := NewChunkID("resumedfile", offset, chunkSize)
+		c.Assert(w.WaitToScheduleChunk(ctx, id, chunkSize), chk.IsNil)
+		c.Assert(w.EnqueueChunk(ctx, id, chunkSize, bytes.NewReader(data[offset:offset+chunkSize]), false), chk.IsNil)
+	}
+	hash, err := w.Flush(ctx)
+	c.Assert(err, chk.IsNil)
+
+	// then: only the rest was written and counted, but the hash is of the whole file
+	expectedHash := md5.Sum(data)
+	c.Assert(dest.Bytes(), chk.DeepEquals, data[startOffset:])
+	c.Assert(reporter.total, chk.Equals, int64(fileSize-startOffset))
+	c.Assert(hash, chk.DeepEquals, expectedHash[:])
+}
+
+func (s *chunkedFileWriterSuite) TestChunkedFileWriter_ExtraHasherCoversWholeFileWhenMd5IsNotChecked(c *chk.C) {
+	const chunkSize = 16 * 1024
+	const numChunks = 4
+	const startOffset = chunkSize
+
+	// given: a resumed file, whose MD5 isn't checked but whose CRC64 is wanted
+	ctx := context.Background()
+	data := make([]byte, chunkSize*numChunks)
+	rand.Read(data)
+	crc := crc64.New(crc64.MakeTable(crc64.ECMA))
+	w := NewResumingChunkedFileWriter(ctx, NewMultiSizeSlicePool(chunkSize), NewCacheLimiter(chunkSize*numChunks*2), nullChunkStatusLogger{},
+		nil, &closeableBuffer{Buffer: &bytes.Buffer{}}, numChunks-1, 1, EHashValidationOption.NoCheck(), false, crc,
+		startOffset, bytes.NewReader(data[:startOffset]))
+
+	// when: we download the rest of it, out of order
+	for _, i := range rand.Perm(numChunks - 1) {
+		offset := int64(startOffset + i*chunkSize)
+		id := NewChunkID("resumedfile", offset, chunkSize)
+		c.Assert(w.WaitToScheduleChunk(ctx, id, chunkSize), chk.IsNil)
+		c.Assert(w.EnqueueChunk(ctx, id, chunkSize, bytes.NewReader(data[offset:offset+chunkSize]), false), chk.IsNil)
+	}
+	_, err := w.Flush(ctx)
+	c.Assert(err, chk.IsNil)
+
+	// then: the extra hash is of the whole file
+	c.Assert(crc.Sum64(), chk.Equals, crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)))
+}
+
+func (s *chunkedFileWriterSuite) TestChunkedFileWriter_ResumingFailsIfSavedContentIsShort(c *chk.C) {
+	const chunkSize = 1024
+
+	ctx := context.Background()
+	dest := &closeableBuffer{Buffer: &bytes.Buffer{}}
+	w := NewResumingChunkedFileWriter(ctx, NewMultiSizeSlicePool(chunkSize), NewCacheLimiter(chunkSize*4), nullChunkStatusLogger{},
+		nil, dest, 1, 1, EHashValidationOption.FailIfDifferent(), true, nil,
+		chunkSize, bytes.NewReader(make([]byte, chunkSize/2)))
+
+	id := NewChunkID("resumedfile", chunkSize, chunkSize)
+	c.Assert(w.WaitToScheduleChunk(ctx, id, chunkSize), chk.IsNil)
+	_ = w.EnqueueChunk(ctx, id, chunkSize, bytes.NewReader(make([]byte, chunkSize)), false) // may or may not see the failure yet
+	_, err := w.Flush(ctx)
+	c.Assert(err, chk.NotNil)
+}
@@ -0,0 +1,74 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+
+	chk "gopkg.in/check.v1"
+)
+
+type colorThemeSuite struct{}
+
+var _ = chk.Suite(&colorThemeSuite{})
+
+func (s *colorThemeSuite) TestParseColorTheme(c *chk.C) {
+	theme, err := ParseColorTheme("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(theme, chk.Equals, DefaultColorTheme)
+
+	theme, err = ParseColorTheme("Error=1;91, success=none")
+	c.Assert(err, chk.IsNil)
+	c.Assert(theme, chk.Equals, ColorTheme{Error: "1;91", Warning: DefaultColorTheme.Warning, Success: ""})
+
+	_, err = ParseColorTheme("error=red")
+	c.Assert(err, chk.NotNil)
+	_, err = ParseColorTheme("info=32")
+	c.Assert(err, chk.ErrorMatches, ".*The choices include: error, warning, success")
+}
+
+func (s *colorThemeSuite) TestWarningsAndSuccessfulSummariesAreColored(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetColors(EColorMode.Always(), DefaultColorTheme)
+	lcm.processTextOutput(outputMessage{msgContent: "WARN: careful", msgType: eOutputMessageType.Warning()})
+	lcm.processTextOutput(outputMessage{msgContent: "INFO: plain", msgType: eOutputMessageType.Info()})
+	c.Assert(lcm.output.(*bytes.Buffer).String(), chk.Equals, "\x1b[33mWARN: careful\x1b[0m\nINFO: plain\n")
+
+	// each line on its own, so that a line read by itself doesn't leave the terminal colored
+	c.Assert(lcm.paint(lcm.output, "32", "Job done\n\nFinal Job Status: Completed"), chk.Equals,
+		"\x1b[32mJob done\x1b[0m\n\n\x1b[32mFinal Job Status: Completed\x1b[0m")
+}
+
+func (s *colorThemeSuite) TestColorsOnlyWhenWanted(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+
+	// a buffer isn't a terminal
+	lcm.SetColors(EColorMode.Auto(), DefaultColorTheme)
+	c.Assert(lcm.useColorsFor(lcm.output), chk.Equals, false)
+
+	lcm.SetColors(EColorMode.Never(), DefaultColorTheme)
+	c.Assert(lcm.useColorsFor(lcm.output), chk.Equals, false)
+
+	// and not even when asked for, if the console would show them as they are
+	lcm.console = noAnsiConsole{}
+	lcm.SetColors(EColorMode.Always(), DefaultColorTheme)
+	c.Assert(lcm.useColorsFor(lcm.output), chk.Equals, false)
+}
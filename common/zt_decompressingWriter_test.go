@@ -22,6 +22,7 @@ package common
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
 	"compress/zlib"
 	chk "gopkg.in/check.v1"
@@ -90,6 +91,27 @@ func (d *decompressingWriterSuite) TestDecompressingWriter_SuccessCases(c *chk.C
 	}
 }
 
+func (d *decompressingWriterSuite) TestDecompressingWriter_RawDeflate(c *chk.C) {
+	// given: data compressed with deflate, but without the ZLib header and checksum
+	originalData := d.genCompressibleTestData(100 * 1024)
+	compBuf := &bytes.Buffer{}
+	comp, err := flate.NewWriter(compBuf, flate.DefaultCompression)
+	c.Assert(err, chk.IsNil)
+	_, err = comp.Write(originalData)
+	c.Assert(err, chk.IsNil)
+	c.Assert(comp.Close(), chk.IsNil)
+
+	// when: it's decompressed as content encoding "deflate"
+	destFile := &closeableBuffer{Buffer: &bytes.Buffer{}}
+	decWriter := NewDecompressingWriter(destFile, ECompressionType.ZLib())
+	_, err = io.Copy(decWriter, compBuf)
+	c.Assert(err, chk.IsNil)
+	c.Assert(decWriter.Close(), chk.IsNil)
+
+	// then: the original data is written all the same
+	c.Assert(destFile.Bytes(), chk.DeepEquals, originalData)
+}
+
 func (d *decompressingWriterSuite) TestDecompressingWriter_EarlyClose(c *chk.C) {
 
 	cases := []CompressionType{
@@ -0,0 +1,48 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type folderCreationTrackerSuite struct{}
+
+var _ = chk.Suite(&folderCreationTrackerSuite{})
+
+func (s *folderCreationTrackerSuite) TestParseOverwriteOption(c *chk.C) {
+	var o OverwriteOption
+	c.Assert(o.Parse("ifSizeDifferent"), chk.IsNil)
+	c.Assert(o, chk.Equals, EOverwriteOption.IfSizeDifferent())
+	c.Assert(o.String(), chk.Equals, "IfSizeDifferent")
+	c.Assert(o.Parse("ifsourcenewer"), chk.IsNil)
+	c.Assert(o, chk.Equals, EOverwriteOption.IfSourceNewer())
+}
+
+func (s *folderCreationTrackerSuite) TestIfSizeDifferentOnlySetsPropertiesOfCreatedFolders(c *chk.C) {
+	f := NewFolderCreationTracker(EFolderPropertiesOption.AllFolders())
+	f.RecordCreation("created")
+
+	// folders have no size, so an existing one is left alone, like with overwrite=false
+	c.Assert(f.ShouldSetProperties("created", EOverwriteOption.IfSizeDifferent(), nil), chk.Equals, true)
+	c.Assert(f.ShouldSetProperties("existing", EOverwriteOption.IfSizeDifferent(), nil), chk.Equals, false)
+	c.Assert(f.ShouldSetProperties("existing", EOverwriteOption.True(), nil), chk.Equals, true)
+}
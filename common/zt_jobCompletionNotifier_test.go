@@ -0,0 +1,116 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type jobCompletionNotifierSuite struct{}
+
+var _ = chk.Suite(&jobCompletionNotifierSuite{})
+
+func (s *jobCompletionNotifierSuite) TestNotificationIsSignedAndRetried(c *chk.C) {
+	var attempts int32
+	var received JobCompletionNotification
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // worth trying again
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(notificationSignatureHeader)
+		c.Check(signature, chk.Equals, "sha256="+signNotification([]byte("secret"), body))
+		c.Check(json.Unmarshal(body, &received), chk.IsNil)
+	}))
+	defer server.Close()
+
+	notifier, err := newJobCompletionNotifier(server.URL, "secret")
+	c.Assert(err, chk.IsNil)
+	notifier.retryDelays = []time.Duration{0, 0}
+
+	summary := func(OutputFormat) string { return `{"TransfersFailed":1}` }
+	c.Assert(notifier.send(newJobCompletionNotification(summary, EExitCode.Error(), "")), chk.IsNil)
+	c.Assert(atomic.LoadInt32(&attempts), chk.Equals, int32(2))
+	c.Assert(received.Outcome, chk.Equals, "CompletedWithErrors")
+	c.Assert(string(received.JobSummary), chk.Equals, `{"TransfersFailed":1}`)
+	c.Assert(signature, chk.Not(chk.Equals), "")
+}
+
+func (s *jobCompletionNotifierSuite) TestClientErrorsAreNotRetried(c *chk.C) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		c.Check(r.Header.Get(notificationSignatureHeader), chk.Equals, "") // since there's no secret
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	notifier, err := newJobCompletionNotifier(server.URL, "")
+	c.Assert(err, chk.IsNil)
+	notifier.retryDelays = []time.Duration{0, 0}
+
+	c.Assert(notifier.send(newJobCompletionNotification(nil, EExitCode.Error(), "failed to list")), chk.NotNil)
+	c.Assert(atomic.LoadInt32(&attempts), chk.Equals, int32(1))
+}
+
+func (s *jobCompletionNotifierSuite) TestRetriesStopAtTheDeadline(c *chk.C) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		_, _ = ioutil.ReadAll(r.Body) // so that the server notices when the client gives up
+		select {
+		case <-time.After(5 * time.Second): // a server that hangs
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	notifier, err := newJobCompletionNotifier(server.URL, "")
+	c.Assert(err, chk.IsNil)
+	notifier.deadline = 200 * time.Millisecond
+	notifier.retryDelays = []time.Duration{0, time.Minute}
+
+	start := time.Now()
+	c.Assert(notifier.send(newJobCompletionNotification(nil, EExitCode.Success(), "")), chk.NotNil)
+	c.Assert(time.Since(start) < 2*time.Second, chk.Equals, true)
+	c.Assert(atomic.LoadInt32(&attempts) <= 2, chk.Equals, true)
+}
+
+func (s *jobCompletionNotifierSuite) TestNotificationOutcome(c *chk.C) {
+	c.Assert(newJobCompletionNotification(nil, EExitCode.Success(), "").Outcome, chk.Equals, "Succeeded")
+	c.Assert(newJobCompletionNotification(nil, EExitCode.Error(), "").Outcome, chk.Equals, "CompletedWithErrors")
+
+	failed := newJobCompletionNotification(func(OutputFormat) string { return "not json" }, EExitCode.Error(), "boom")
+	c.Assert(failed.Outcome, chk.Equals, "Failed")
+	c.Assert(failed.JobSummary, chk.IsNil)
+
+	_, err := newJobCompletionNotifier("ftp://example.com/hook", "")
+	c.Assert(err, chk.NotNil)
+}
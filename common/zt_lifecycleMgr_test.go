@@ -0,0 +1,573 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type lifecycleMgrSuite struct{}
+
+var _ = chk.Suite(&lifecycleMgrSuite{})
+
+// a lifecycle manager with nothing draining its queue, so the tests can see what would have been output
+func newTestLifecycleMgr() *lifecycleMgr {
+	output := &bytes.Buffer{}
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	return &lifecycleMgr{
+		shutdownCtx:     shutdownCtx,
+		shutdown:        shutdown,
+		msgQueue:        make(chan outputMessage, 100),
+		outputVerbosity: EOutputVerbosity.Info(),
+		output:          output,
+		console:         ansiConsole{output: output},
+		logSanitizer:    NewAzCopyLogSanitizer(),
+	}
+}
+
+func queuedMessages(lcm *lifecycleMgr) []outputMessage {
+	var msgs []outputMessage
+	for len(lcm.msgQueue) > 0 {
+		msgs = append(msgs, <-lcm.msgQueue)
+	}
+	return msgs
+}
+
+func (s *lifecycleMgrSuite) TestVerbosityFiltersMessages(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+
+	// by default, everything but debug is shown
+	lcm.Debug("d")
+	lcm.Info("i")
+	lcm.Warn("w")
+	msgs := queuedMessages(lcm)
+	c.Assert(msgs, chk.HasLen, 2)
+	c.Assert(msgs[0].msgContent, chk.Equals, "INFO: i")
+	c.Assert(msgs[0].msgType, chk.Equals, eOutputMessageType.Info())
+	c.Assert(msgs[1].msgContent, chk.Equals, "WARN: w")
+	c.Assert(msgs[1].msgType, chk.Equals, eOutputMessageType.Warning())
+
+	lcm.SetOutputVerbosity(EOutputVerbosity.Debug())
+	lcm.Debug("d")
+	msgs = queuedMessages(lcm)
+	c.Assert(msgs, chk.HasLen, 1)
+	c.Assert(msgs[0].msgContent, chk.Equals, "DEBUG: d")
+	c.Assert(msgs[0].msgType, chk.Equals, eOutputMessageType.Debug())
+
+	lcm.SetOutputVerbosity(EOutputVerbosity.Error())
+	lcm.Debug("d")
+	lcm.Info("i")
+	lcm.Warn("w")
+	c.Assert(queuedMessages(lcm), chk.HasLen, 0)
+}
+
+func (s *lifecycleMgrSuite) TestParseOutputVerbosity(c *chk.C) {
+	var v OutputVerbosity
+	c.Assert(v.Parse("warning"), chk.IsNil)
+	c.Assert(v, chk.Equals, EOutputVerbosity.Warning())
+	c.Assert(v.Parse("DEBUG"), chk.IsNil)
+	c.Assert(v, chk.Equals, EOutputVerbosity.Debug())
+	c.Assert(v.Parse("chatty"), chk.NotNil)
+}
+
+func (s *lifecycleMgrSuite) TestCleanupHooksRunOnceInReverseOrder(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+
+	var order []int
+	lcm.RegisterCleanupHook(func() { order = append(order, 1) })
+	lcm.RegisterCleanupHook(func() { panic("a broken hook must not stop the others") })
+	lcm.RegisterCleanupHook(func() { order = append(order, 3) })
+
+	lcm.runCleanupHooks()
+	c.Assert(order, chk.DeepEquals, []int{3, 1})
+
+	lcm.runCleanupHooks()
+	c.Assert(order, chk.DeepEquals, []int{3, 1})
+}
+
+func (s *lifecycleMgrSuite) TestProgressAsLines(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetProgressDisplay(EProgressDisplay.Lines())
+
+	lcm.processTextOutput(outputMessage{msgContent: "1 Done", msgType: eOutputMessageType.Progress()})
+	lcm.processTextOutput(outputMessage{msgContent: "2 Done", msgType: eOutputMessageType.Progress()}) // too soon to print again
+	lcm.processTextOutput(outputMessage{msgContent: "INFO: hi", msgType: eOutputMessageType.Info()})
+	out := lcm.output.(*bytes.Buffer).String()
+
+	// no carriage returns, and each message on a line of its own
+	c.Assert(strings.Contains(out, "\r"), chk.Equals, false)
+	c.Assert(out, chk.Equals, "1 Done\nINFO: hi\n")
+}
+
+func (s *lifecycleMgrSuite) TestProgressInPlace(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetProgressDisplay(EProgressDisplay.InPlace())
+
+	lcm.processTextOutput(outputMessage{msgContent: "10 Done", msgType: eOutputMessageType.Progress()})
+	lcm.processTextOutput(outputMessage{msgContent: "2 Done", msgType: eOutputMessageType.Progress()})
+	out := lcm.output.(*bytes.Buffer).String()
+
+	// the second line overwrites the first, including the extra character
+	c.Assert(out, chk.Equals, "\r10 Done\r2 Done ")
+}
+
+func (s *lifecycleMgrSuite) TestPromptTimeoutUsesDefaultAnswer(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.inputQueue = make(chan userInput, 10)
+	details := PromptDetails{ResponseOptions: []ResponseOption{EResponseOption.Yes(), EResponseOption.No()}}
+
+	// nobody answers, so we get the default
+	answer := make(chan ResponseOption)
+	go func() {
+		answer <- lcm.PromptWithTimeout("overwrite?", details, 50*time.Millisecond, EResponseOption.No())
+	}()
+	prompt := <-lcm.msgQueue
+	c.Assert(prompt.msgType, chk.Equals, eOutputMessageType.Prompt())
+	lcm.answerPrompt(prompt, time.Now())
+	c.Assert(<-answer, chk.Equals, EResponseOption.No())
+
+	// which was reported to the user
+	info := <-lcm.msgQueue
+	c.Assert(strings.Contains(info.msgContent, "assuming No"), chk.Equals, true)
+
+	// but an answer in time is used as normal
+	go func() { answer <- lcm.PromptWithTimeout("overwrite?", details, time.Minute, EResponseOption.No()) }()
+	prompt = <-lcm.msgQueue
+	questionTime := time.Now()
+	lcm.inputQueue <- userInput{timeReceived: questionTime.Add(time.Millisecond), content: "y"}
+	lcm.answerPrompt(prompt, questionTime)
+	c.Assert(<-answer, chk.Equals, EResponseOption.Yes())
+}
+
+func (s *lifecycleMgrSuite) TestNewLifecycleMgrUsesGivenOutputAndInput(c *chk.C) {
+	output := &syncBuffer{}
+	inputReader, inputWriter := io.Pipe()
+	mgr := NewLifecycleMgr(output, inputReader)
+	mgr.EnableInputWatcher()
+
+	mgr.Info("hello")
+	go func() {
+		// only answers given after the question has been asked are accepted, so wait until it has been
+		for !strings.Contains(output.String(), "Please confirm with:") {
+			time.Sleep(time.Millisecond)
+		}
+		_, _ = inputWriter.Write([]byte("y\n"))
+	}()
+	answer := mgr.PromptWithTimeout("proceed?", PromptDetails{ResponseOptions: []ResponseOption{EResponseOption.Yes(), EResponseOption.No()}},
+		time.Minute, EResponseOption.No())
+
+	c.Assert(answer, chk.Equals, EResponseOption.Yes())
+	c.Assert(strings.Contains(output.String(), "INFO: hello\n"), chk.Equals, true)
+	c.Assert(strings.Contains(output.String(), "proceed? Please confirm with:"), chk.Equals, true)
+}
+
+func (s *lifecycleMgrSuite) TestSetLifecycleMgr(c *chk.C) {
+	original := GetLifecycleMgr()
+	defer SetLifecycleMgr(original)
+
+	replacement := NewLifecycleMgr(&syncBuffer{}, strings.NewReader(""))
+	SetLifecycleMgr(replacement)
+	c.Assert(GetLifecycleMgr(), chk.Equals, replacement)
+}
+
+// the output is written by the lifecycle manager's own goroutine, so reading it in the test needs a lock
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func (s *lifecycleMgrSuite) TestOutputFileGetsEverythingButProgress(c *chk.C) {
+	outputFile := &bytes.Buffer{}
+	lcm := newTestLifecycleMgr()
+	lcm.outputFile = outputFile
+
+	lcm.writeToOutputFile(outputMessage{msgContent: "INFO: hi", msgType: eOutputMessageType.Info()})
+	lcm.writeToOutputFile(outputMessage{msgContent: "1 Done", msgType: eOutputMessageType.Progress()})
+	lcm.writeToOutputFile(outputMessage{msgContent: "Job summary\nFiles: 1\n", msgType: eOutputMessageType.EndOfJob()})
+
+	lines := strings.Split(strings.TrimSuffix(outputFile.String(), "\n"), "\n")
+	c.Assert(lines, chk.HasLen, 3)
+	for i, expected := range []string{"INFO: hi", "Job summary", "Files: 1"} {
+		timestamp := strings.SplitN(lines[i], " ", 2)[0]
+		_, err := time.Parse(time.RFC3339, timestamp)
+		c.Assert(err, chk.IsNil)
+		c.Assert(strings.TrimPrefix(lines[i], timestamp+" "), chk.Equals, expected)
+	}
+}
+
+func (s *lifecycleMgrSuite) TestQuietKeepsOnlyWhatMatters(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetQuiet(true)
+	builder := func(OutputFormat) string { return "x" }
+
+	lcm.Init(builder)
+	lcm.Info("i")
+	lcm.Progress(builder)
+	lcm.Warn("w")
+	lcm.Exit(builder, EExitCode.NoExit())
+
+	msgs := queuedMessages(lcm)
+	c.Assert(msgs, chk.HasLen, 2)
+	c.Assert(msgs[0].msgType, chk.Equals, eOutputMessageType.Warning())
+	c.Assert(msgs[1].msgType, chk.Equals, eOutputMessageType.EndOfJob())
+
+	// and quiet wins over the verbosity
+	lcm.SetOutputVerbosity(EOutputVerbosity.Debug())
+	lcm.Debug("d")
+	c.Assert(queuedMessages(lcm), chk.HasLen, 0)
+}
+
+func (s *lifecycleMgrSuite) TestProgressOfSeveralJobsIsMultiplexed(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetOutputFormat(EOutputFormat.Text())
+	progress := func(text string) OutputBuilder {
+		return func(OutputFormat) string { return text }
+	}
+
+	// a single job's progress is shown just as it is
+	first := lcm.registerProgressReporter(nil)
+	first.Progress(progress("10 %"))
+	c.Assert(queuedMessages(lcm)[0].msgContent, chk.Equals, "10 %")
+
+	second := lcm.registerProgressReporter(nil)
+	second.Progress(progress("50 %"))
+	c.Assert(queuedMessages(lcm)[0].msgContent, chk.Equals, "Job 1: 10 % | Job 2: 50 %")
+
+	lcm.progressAsLines = true
+	first.Progress(progress("20 %"))
+	c.Assert(queuedMessages(lcm)[0].msgContent, chk.Equals, "Job 1: 20 %\nJob 2: 50 %")
+
+	// once a job ends, the others carry on
+	first.Exit(nil, EExitCode.NoExit())
+	c.Assert(first.isFinished(), chk.Equals, true)
+	c.Assert(second.isFinished(), chk.Equals, false)
+	queuedMessages(lcm)
+	second.Progress(progress("60 %"))
+	c.Assert(queuedMessages(lcm)[0].msgContent, chk.Equals, "60 %")
+
+	// JSON progress is not combined, since each message identifies its job
+	third := lcm.registerProgressReporter(nil)
+	lcm.SetOutputFormat(EOutputFormat.Json())
+	third.Progress(progress("{}"))
+	c.Assert(queuedMessages(lcm)[0].msgContent, chk.Equals, "{}")
+}
+
+// like the legacy Windows console, which shows escape sequences as they are
+type noAnsiConsole struct {
+	ansiConsole
+}
+
+func (noAnsiConsole) supportsAnsi() bool {
+	return false
+}
+
+func (s *lifecycleMgrSuite) TestAnsiStrippedWhenConsoleDoesNotSupportIt(c *chk.C) {
+	c.Assert(stripAnsiEscapeSequences("\x1b[31mred\x1b[0m and \x1b[2Kplain"), chk.Equals, "red and plain")
+
+	lcm := newTestLifecycleMgr()
+	lcm.processTextOutput(outputMessage{msgContent: "\x1b[33mWARN: w\x1b[0m", msgType: eOutputMessageType.Warning()})
+	c.Assert(lcm.output.(*bytes.Buffer).String(), chk.Equals, "\x1b[33mWARN: w\x1b[0m\n")
+
+	lcm = newTestLifecycleMgr()
+	lcm.console = noAnsiConsole{ansiConsole{output: lcm.output}}
+	lcm.processTextOutput(outputMessage{msgContent: "\x1b[33mWARN: w\x1b[0m", msgType: eOutputMessageType.Warning()})
+	c.Assert(lcm.output.(*bytes.Buffer).String(), chk.Equals, "WARN: w\n")
+}
+
+func (s *lifecycleMgrSuite) TestPromptForChoiceAsksAgainOnInvalidAnswer(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetOutputFormat(EOutputFormat.Text())
+	lcm.inputQueue = make(chan userInput, 10)
+	details := PromptDetails{ResponseOptions: []ResponseOption{EResponseOption.Yes(), EResponseOption.No(),
+		EResponseOption.YesForAll(), EResponseOption.NoForAll()}}
+
+	answerWith := func(content string) {
+		prompt := <-lcm.msgQueue
+		c.Assert(prompt.msgType, chk.Equals, eOutputMessageType.Prompt())
+		questionTime := time.Now()
+		lcm.inputQueue <- userInput{timeReceived: questionTime.Add(time.Millisecond), content: content}
+		lcm.answerPrompt(prompt, questionTime)
+	}
+
+	answer := make(chan ResponseOption)
+	go func() { answer <- lcm.PromptForChoice("overwrite?", details) }()
+	answerWith("maybe")
+	info := <-lcm.msgQueue
+	c.Assert(info.msgContent, chk.Equals, "INFO: 'maybe' is not one of the choices. Please answer with one of: Y, N, A, L")
+	answerWith("Yes for all")
+	c.Assert(<-answer, chk.Equals, EResponseOption.YesForAll())
+
+	// but not forever
+	go func() { answer <- lcm.PromptForChoice("overwrite?", details) }()
+	for i := 0; i < maxPromptAttempts; i++ {
+		answerWith("?")
+		if i < maxPromptAttempts-1 {
+			<-lcm.msgQueue // what was wrong with the answer
+		}
+	}
+	c.Assert(<-answer, chk.Equals, EResponseOption.Default())
+
+	// and with JSON output, whatever is reading it gets the default straight away, as with Prompt
+	lcm.SetOutputFormat(EOutputFormat.Json())
+	go func() { answer <- lcm.PromptForChoice("overwrite?", details) }()
+	answerWith("?")
+	c.Assert(<-answer, chk.Equals, EResponseOption.Default())
+	c.Assert(queuedMessages(lcm), chk.HasLen, 0)
+}
+
+// records what would have gone to the system log
+type fakeSystemLog struct {
+	entries []string
+}
+
+func (f *fakeSystemLog) record(entry string) error {
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func (f *fakeSystemLog) Info(msg string) error    { return f.record("info: " + msg) }
+func (f *fakeSystemLog) Warning(msg string) error { return f.record("warning: " + msg) }
+func (f *fakeSystemLog) Error(msg string) error   { return f.record("error: " + msg) }
+func (f *fakeSystemLog) Close() error             { return nil }
+
+func (s *lifecycleMgrSuite) TestSystemLogGetsSummariesAndErrors(c *chk.C) {
+	sysLog := &fakeSystemLog{}
+	lcm := newTestLifecycleMgr()
+	lcm.systemLog = sysLog
+
+	lcm.writeToSystemLog(outputMessage{msgContent: "INFO: i", msgType: eOutputMessageType.Info()})
+	lcm.writeToSystemLog(outputMessage{msgContent: "50 %", msgType: eOutputMessageType.Progress()})
+	lcm.writeToSystemLog(outputMessage{msgContent: "all done", msgType: eOutputMessageType.EndOfJob(), exitCode: EExitCode.Success()})
+	lcm.writeToSystemLog(outputMessage{msgContent: "some failed", msgType: eOutputMessageType.EndOfJob(), exitCode: EExitCode.Error()})
+	lcm.writeToSystemLog(outputMessage{msgContent: "fatal", msgType: eOutputMessageType.Error(), exitCode: EExitCode.Error()})
+
+	c.Assert(sysLog.entries, chk.DeepEquals, []string{"info: all done", "warning: some failed", "error: fatal"})
+}
+
+func (s *lifecycleMgrSuite) TestErrorsAndWarningsGoToErrorOutput(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	errorOutput := &bytes.Buffer{}
+	lcm.errorOutput = errorOutput
+
+	lcm.processTextOutput(outputMessage{msgContent: "50 %", msgType: eOutputMessageType.Progress()})
+	lcm.processTextOutput(outputMessage{msgContent: "WARN: w", msgType: eOutputMessageType.Warning()})
+	lcm.processTextOutput(outputMessage{msgContent: "INFO: i", msgType: eOutputMessageType.Info()})
+
+	c.Assert(errorOutput.String(), chk.Equals, "WARN: w\n")
+	// the warning replaced the progress status, which was then put back, while the info pushed it down a line
+	c.Assert(lcm.output.(*bytes.Buffer).String(), chk.Equals, "\r50 %\r    \r50 %\rINFO: i\n50 %")
+
+	// unless they're wanted together
+	lcm = newTestLifecycleMgr()
+	errorOutput.Reset()
+	lcm.errorOutput = errorOutput
+	lcm.SetErrorOutput(EErrorOutput.Stdout())
+	lcm.processTextOutput(outputMessage{msgContent: "WARN: w", msgType: eOutputMessageType.Warning()})
+	c.Assert(errorOutput.String(), chk.Equals, "")
+	c.Assert(lcm.output.(*bytes.Buffer).String(), chk.Equals, "WARN: w\n")
+}
+
+func (s *lifecycleMgrSuite) TestNoneOutputStillReportsErrorsToErrorOutput(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	errorOutput := &bytes.Buffer{}
+	lcm.errorOutput = errorOutput
+	lcm.returnOnExit = true
+	lcm.SetOutputFormat(EOutputFormat.None())
+
+	lcm.processMessage(outputMessage{msgContent: "INFO: i", msgType: eOutputMessageType.Info()})
+	lcm.processMessage(outputMessage{msgContent: "50 %", msgType: eOutputMessageType.Progress()})
+	lcm.processMessage(outputMessage{msgContent: "failed", msgType: eOutputMessageType.Error()})
+
+	// nothing goes to the output, which may be carrying data
+	c.Assert(lcm.output.(*bytes.Buffer).String(), chk.Equals, "")
+	c.Assert(errorOutput.String(), chk.Equals, "failed\n")
+	c.Assert(lcm.exitCode, chk.Equals, EExitCode.Error())
+}
+
+func (s *lifecycleMgrSuite) TestRunReturnsInLibraryMode(c *chk.C) {
+	output := &syncBuffer{}
+	mgr := NewLifecycleMgr(output, strings.NewReader(""))
+	mgr.ReturnOnExit()
+	cleanedUp := false
+	mgr.RegisterCleanupHook(func() { cleanedUp = true })
+
+	afterExit := false
+	exitCode := mgr.Run(func() {
+		mgr.Info("working")
+		mgr.Exit(func(OutputFormat) string { return "some transfers failed" }, EExitCode.Error())
+		afterExit = true // Exit doesn't return, even in library mode
+	})
+
+	c.Assert(exitCode, chk.Equals, EExitCode.Error())
+	c.Assert(afterExit, chk.Equals, false)
+	c.Assert(cleanedUp, chk.Equals, true)
+	c.Assert(output.String(), chk.Equals, "INFO: working\n\nsome transfers failed\n")
+	c.Assert(mgr.Context().Err(), chk.NotNil)
+}
+
+func (s *lifecycleMgrSuite) TestRunTreatsReturningAsSuccess(c *chk.C) {
+	mgr := NewLifecycleMgr(&syncBuffer{}, strings.NewReader(""))
+	mgr.ReturnOnExit()
+	c.Assert(mgr.Run(func() {}), chk.Equals, EExitCode.Success())
+
+	mgr = NewLifecycleMgr(&syncBuffer{}, strings.NewReader(""))
+	mgr.ReturnOnExit()
+	c.Assert(mgr.Run(func() { mgr.Error("cannot start") }), chk.Equals, EExitCode.Error())
+}
+
+func (s *lifecycleMgrSuite) TestClosedInputEndsWaitingForAnswers(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.inputQueue = make(chan userInput, 10)
+	lcm.input = strings.NewReader("y\r\nlast line without an ending")
+	lcm.allowWatchInput = true
+	lcm.watchInputs() // returns, rather than spinning, once the input is used up
+
+	c.Assert((<-lcm.inputQueue).content, chk.Equals, "y")
+	c.Assert((<-lcm.inputQueue).content, chk.Equals, "last line without an ending")
+	_, ok := <-lcm.inputQueue
+	c.Assert(ok, chk.Equals, false)
+
+	// so that a prompt, even one without a timeout, gets the default straight away
+	details := PromptDetails{ResponseOptions: []ResponseOption{EResponseOption.Yes(), EResponseOption.No()}}
+	answer := make(chan ResponseOption)
+	go func() { answer <- lcm.PromptForChoice("overwrite?", details) }()
+	lcm.answerPrompt(<-lcm.msgQueue, time.Now())
+	c.Assert(<-answer, chk.Equals, EResponseOption.Default())
+	info := <-lcm.msgQueue
+	c.Assert(strings.Contains(info.msgContent, "no input"), chk.Equals, true)
+}
+
+func (s *lifecycleMgrSuite) TestScriptedAnswersAreUsedWithoutReadingInput(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetOutputFormat(EOutputFormat.Text())
+	lcm.SetScriptedAnswers(map[PromptType]string{EPromptType.Overwrite(): "n"})
+	details := PromptDetails{PromptType: EPromptType.Overwrite(),
+		ResponseOptions: []ResponseOption{EResponseOption.Yes(), EResponseOption.No()}}
+
+	answer := make(chan ResponseOption)
+	go func() { answer <- lcm.PromptForChoice("overwrite?", details) }()
+	lcm.answerPrompt(<-lcm.msgQueue, time.Now()) // there's no input queue, so this would block if it were read
+	c.Assert(<-answer, chk.Equals, EResponseOption.No())
+	c.Assert(lcm.output.(*bytes.Buffer).String(), chk.Equals, "n\n")
+}
+
+type brokenReader struct{}
+
+func (brokenReader) Read([]byte) (int, error) {
+	return 0, errors.New("the handle is invalid")
+}
+
+func (s *lifecycleMgrSuite) TestBrokenInputIsReportedAndEndsInput(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.inputQueue = make(chan userInput, 10)
+	lcm.input = brokenReader{}
+	lcm.allowWatchInput = true
+	lcm.watchInputs()
+
+	_, ok := <-lcm.inputQueue
+	c.Assert(ok, chk.Equals, false)
+	warning := <-lcm.msgQueue
+	c.Assert(warning.msgType, chk.Equals, eOutputMessageType.Warning())
+	c.Assert(strings.Contains(warning.msgContent, "cannot read from standard input: the handle is invalid. "), chk.Equals, true)
+	c.Assert(strings.HasSuffix(warning.msgContent, "(error code: Input)"), chk.Equals, true)
+}
+
+func fillMessageQueue(lcm *lifecycleMgr) {
+	for len(lcm.msgQueue) < cap(lcm.msgQueue) {
+		lcm.Info("filler")
+	}
+}
+
+func (s *lifecycleMgrSuite) TestCoalescePolicyDropsProgressWhenQueueIsFull(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	lcm.SetQueueOverflowPolicy(EQueueOverflowPolicy.Coalesce())
+	fillMessageQueue(lcm)
+
+	lcm.Progress(func(OutputFormat) string { return "50 %" }) // doesn't block
+	c.Assert(lcm.OutputQueueStats(), chk.Equals, OutputQueueStats{Coalesced: 1})
+}
+
+func (s *lifecycleMgrSuite) TestGrowPolicyKeepsMessagesInOrder(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	defer lcm.shutdown()
+	lcm.SetQueueOverflowPolicy(EQueueOverflowPolicy.Grow())
+	fillMessageQueue(lcm)
+
+	lcm.Info("first")
+	lcm.Progress(func(OutputFormat) string { return "10 %" })
+	lcm.Info("second")
+	lcm.Progress(func(OutputFormat) string { return "20 %" }) // replaces the out of date one
+	lcm.Warn("third")
+
+	var got []string
+	for len(got) < cap(lcm.msgQueue)+4 {
+		if msg := <-lcm.msgQueue; msg.msgContent != "INFO: filler" {
+			got = append(got, msg.msgContent)
+		} else {
+			got = append(got, "")
+		}
+	}
+	c.Assert(got[cap(lcm.msgQueue):], chk.DeepEquals, []string{"INFO: first", "INFO: second", "20 %", "WARN: third"})
+	c.Assert(lcm.overflowPending(), chk.Equals, false)
+	c.Assert(lcm.OutputQueueStats(), chk.Equals, OutputQueueStats{Coalesced: 1, PeakOverflowed: 4})
+}
+
+func (s *lifecycleMgrSuite) TestDropOldestPolicyDropsInfoButNotWarnings(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	defer lcm.shutdown()
+	lcm.SetQueueOverflowPolicy(EQueueOverflowPolicy.DropOldest())
+	fillMessageQueue(lcm)
+
+	// nobody is taking messages from the queue, so, apart from the one the mover is holding, everything stays pending
+	lcm.Warn("held")
+	for i := 0; i < maxOverflowMessages+9; i++ {
+		lcm.Info("overflow")
+	}
+	lcm.Warn("kept")
+
+	c.Assert(lcm.OutputQueueStats().Dropped, chk.Equals, int64(11)) // to make room for the last 10 Info messages, and the warning
+	lcm.queueOverflow.lock.Lock()
+	defer lcm.queueOverflow.lock.Unlock()
+	pending := lcm.queueOverflow.pending
+	c.Assert(pending, chk.HasLen, maxOverflowMessages)
+	c.Assert(pending[0].msgContent, chk.Equals, "WARN: held")
+	c.Assert(pending[len(pending)-1].msgContent, chk.Equals, "WARN: kept")
+}
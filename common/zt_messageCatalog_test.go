@@ -0,0 +1,74 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type messageCatalogSuite struct{}
+
+var _ = chk.Suite(&messageCatalogSuite{})
+
+func (s *messageCatalogSuite) TestLocalizeFallsThroughToDefault(c *chk.C) {
+	messageCatalogs["de-DE"] = messageCatalog{EMessageKey.CancellationRequested(): "Abbruch angefordert."}
+	defer func() {
+		delete(messageCatalogs, "de-DE")
+		c.Assert(SetLocale(""), chk.IsNil)
+	}()
+
+	c.Assert(SetLocale("de_AT.UTF-8"), chk.IsNil) // same language, so the German catalog is used
+	c.Assert(Localize(EMessageKey.CancellationRequested()), chk.Equals, "Abbruch angefordert.")
+
+	// messages that haven't been translated come from the default catalog
+	c.Assert(Localize(EMessageKey.PromptTimedOut(), "1m0s", "No"), chk.Equals, "No answer was given within 1m0s, so assuming No")
+
+	// an unknown language is reported, and the default is used
+	c.Assert(SetLocale("fr-FR"), chk.NotNil)
+	c.Assert(Localize(EMessageKey.CancellationRequested()), chk.Equals, "Cancellation requested. Beginning clean shutdown...")
+
+	c.Assert(Localize(MessageKey("NoSuchMessage")), chk.Equals, "NoSuchMessage")
+}
+
+func (s *messageCatalogSuite) TestDefaultCatalogIsComplete(c *chk.C) {
+	keys := []MessageKey{
+		EMessageKey.CancellationRequested(),
+		EMessageKey.ReducingProgressFrequency(),
+		EMessageKey.PromptTimedOut(),
+		EMessageKey.PromptNoInput(),
+		EMessageKey.InvalidChoice(),
+		EMessageKey.CopyJobSummary(),
+		EMessageKey.SyncJobSummary(),
+		EMessageKey.ResumeJobSummary(),
+		EMessageKey.CleanupNothingToDelete(),
+		EMessageKey.ErrorWithHint(),
+		EMessageKey.ErrorHintInput(),
+		EMessageKey.ErrorHintLogFile(),
+		EMessageKey.ErrorHintFileSystem(),
+		EMessageKey.MessagesDropped(),
+		EMessageKey.JobPaused(),
+	}
+	for _, key := range keys {
+		_, ok := messageCatalogs[DefaultLocale][key]
+		c.Assert(ok, chk.Equals, true, chk.Commentf(string(key)))
+	}
+	c.Assert(messageCatalogs[DefaultLocale], chk.HasLen, len(keys))
+}
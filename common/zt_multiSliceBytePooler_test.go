@@ -65,3 +65,35 @@ func (s *multiSliceBytePoolerSuite) TestMultiSliceSlotInfo(c *chk.C) {
 	}
 
 }
+
+func (s *multiSliceBytePoolerSuite) TestLimitedPoolKeepsWithinLimit(c *chk.C) {
+	const oneMB = 1024 * 1024
+	pool := NewMultiSizeSlicePoolWithLimit(16*oneMB, 4*oneMB).(*multiSizeSlicePool)
+
+	a := pool.RentSlice(oneMB)
+	b := pool.RentSlice(oneMB)
+	c.Assert(pool.atomicRentedBytes, chk.Equals, int64(2*oneMB))
+
+	// both fit, when returned
+	pool.ReturnSlice(a)
+	pool.ReturnSlice(b)
+	c.Assert(pool.atomicRentedBytes, chk.Equals, int64(0))
+	c.Assert(pool.atomicPooledBytes, chk.Equals, int64(2*oneMB))
+
+	// re-using a pooled slice moves it from pooled to rented
+	a = pool.RentSlice(oneMB)
+	c.Assert(pool.atomicRentedBytes, chk.Equals, int64(oneMB))
+	c.Assert(pool.atomicPooledBytes, chk.Equals, int64(oneMB))
+
+	// a new, bigger, slice forces the idle one out, to make room for it. (And the odd length is counted at its real capacity)
+	big := pool.RentSlice(2*oneMB + 1)
+	c.Assert(cap(big), chk.Equals, 4*oneMB)
+	c.Assert(pool.atomicPooledBytes, chk.Equals, int64(0))
+
+	// and with that over the limit, returned slices aren't kept
+	pool.ReturnSlice(a)
+	c.Assert(pool.atomicPooledBytes, chk.Equals, int64(0))
+	pool.ReturnSlice(big)
+	c.Assert(pool.atomicPooledBytes, chk.Equals, int64(4*oneMB))
+	c.Assert(pool.atomicRentedBytes, chk.Equals, int64(0))
+}
@@ -0,0 +1,84 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"encoding/json"
+	chk "gopkg.in/check.v1"
+	"strings"
+	"time"
+)
+
+type outputSuite struct{}
+
+var _ = chk.Suite(&outputSuite{})
+
+func (s *outputSuite) TestJsonOutputTemplate_EmbedsStructuredPayload(c *chk.C) {
+	summary := ListJobSummaryResponse{TotalTransfers: 3, TransfersCompleted: 2}
+	content := GetJsonStringFromTemplate(summary)
+
+	line := GetJsonStringFromTemplate(newJsonOutputTemplate(eOutputMessageType.Progress(), content, PromptDetails{}))
+	c.Assert(strings.Contains(line, "\n"), chk.Equals, false) // one message per line
+
+	// the payload can be read directly, without unwrapping a string
+	var parsed struct {
+		MessageType string
+		Payload     ListJobSummaryResponse
+	}
+	c.Assert(json.Unmarshal([]byte(line), &parsed), chk.IsNil)
+	c.Assert(parsed.MessageType, chk.Equals, "Progress")
+	c.Assert(parsed.Payload.TransfersCompleted, chk.Equals, uint32(2))
+
+	// and the original string form is still there, for existing consumers
+	var original JsonOutputTemplate
+	c.Assert(json.Unmarshal([]byte(line), &original), chk.IsNil)
+	c.Assert(original.MessageContent, chk.Equals, content)
+}
+
+func (s *outputSuite) TestJsonOutputTemplate_NoPayloadForPlainText(c *chk.C) {
+	line := GetJsonStringFromTemplate(newJsonOutputTemplate(eOutputMessageType.Info(), "INFO: hello", PromptDetails{}))
+	c.Assert(strings.Contains(line, "Payload"), chk.Equals, false)
+
+	line = GetJsonStringFromTemplate(newJsonOutputTemplate(eOutputMessageType.EndOfJob(), "not json {", PromptDetails{}))
+	c.Assert(strings.Contains(line, "Payload"), chk.Equals, false)
+}
+
+func (s *outputSuite) TestFinalJobSummary(c *chk.C) {
+	summary := ListJobSummaryResponse{TotalBytesTransferred: 10 * 1000 * 1000, TransfersCompleted: 4, TransfersFailed: 1,
+		TransfersSkipped: 2, TransfersSkippedUnchanged: 3}
+
+	final := NewFinalJobSummary(summary, 8*time.Second, EExitCode.Error())
+	c.Assert(final.ElapsedTimeSeconds, chk.Equals, float64(8))
+	c.Assert(final.AverageThroughputMbps, chk.Equals, float64(10))   // 80 megabits in 8 seconds
+	c.Assert(final.TransfersSkipped, chk.Equals, uint32(5))          // unchanged files count as skipped too
+	c.Assert(final.TransfersSkippedUnchanged, chk.Equals, uint32(3)) // and are also broken out
+	c.Assert(final.ExitCode, chk.Equals, EExitCode.Error())
+
+	// it's only in the JSON once the job has ended
+	c.Assert(strings.Contains(GetJsonStringFromTemplate(summary), "FinalJobSummary"), chk.Equals, false)
+	summary.FinalJobSummary = final
+	var parsed ListJobSummaryResponse
+	c.Assert(json.Unmarshal([]byte(GetJsonStringFromTemplate(summary)), &parsed), chk.IsNil)
+	c.Assert(*parsed.FinalJobSummary, chk.DeepEquals, *final)
+
+	// and no division by zero, for jobs that end instantly
+	c.Assert(NewFinalJobSummary(summary, 0, EExitCode.Success()).AverageThroughputMbps, chk.Equals, float64(0))
+}
@@ -0,0 +1,88 @@
+// +build !windows
+
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type progressEventsSuite struct{}
+
+var _ = chk.Suite(&progressEventsSuite{})
+
+func (s *progressEventsSuite) TestSubscribersReceiveJsonProgressEvents(c *chk.C) {
+	dir, err := ioutil.TempDir("", "progressEvents")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	endpoint := filepath.Join(dir, "azcopy.sock")
+
+	p, err := newProgressEventPublisher(endpoint)
+	c.Assert(err, chk.IsNil)
+
+	conn, err := net.Dial("unix", endpoint)
+	c.Assert(err, chk.IsNil)
+	defer conn.Close()
+
+	// the subscription is registered asynchronously, so keep publishing until the subscriber starts hearing about it
+	reader := bufio.NewReader(conn)
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		lines <- line
+	}()
+
+	var line string
+	deadline := time.After(10 * time.Second)
+	for line == "" {
+		p.publish(eOutputMessageType.Progress(), `{"PercentComplete":"50"}`)
+		select {
+		case line = <-lines:
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			c.Fatal("subscriber never received an event")
+		}
+	}
+
+	var event JsonOutputTemplate
+	c.Assert(json.Unmarshal([]byte(line), &event), chk.IsNil)
+	c.Assert(event.MessageType, chk.Equals, eOutputMessageType.Progress().String())
+	c.Assert(string(event.Payload), chk.Equals, `{"PercentComplete":"50"}`)
+
+	// after close, the subscriber is disconnected, once it has had everything
+	p.close()
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		if _, err = reader.ReadString('\n'); err != nil {
+			break
+		}
+	}
+	c.Assert(err.Error(), chk.Not(chk.Matches), ".*timeout.*")
+}
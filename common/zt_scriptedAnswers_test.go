@@ -0,0 +1,45 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type scriptedAnswersSuite struct{}
+
+var _ = chk.Suite(&scriptedAnswersSuite{})
+
+func (s *scriptedAnswersSuite) TestParseScriptedAnswers(c *chk.C) {
+	answers, err := ParseScriptedAnswers([]string{"# from a file", "", "overwrite=n\r", " DeleteDestination = a ", "Overwrite=y"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(answers, chk.DeepEquals, map[PromptType]string{
+		EPromptType.Overwrite():         "y", // the last one wins
+		EPromptType.DeleteDestination(): "a",
+	})
+
+	_, err = ParseScriptedAnswers([]string{"Overwrite"})
+	c.Assert(err, chk.NotNil)
+	_, err = ParseScriptedAnswers([]string{"Overwrite="})
+	c.Assert(err, chk.NotNil)
+	_, err = ParseScriptedAnswers([]string{"Reticulate=y"})
+	c.Assert(err, chk.ErrorMatches, ".*The choices include: Cancel, Overwrite, DeleteDestination")
+}
@@ -0,0 +1,47 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	chk "gopkg.in/check.v1"
+)
+
+type singleChunkReaderSuite struct{}
+
+var _ = chk.Suite(&singleChunkReaderSuite{})
+
+func (s *singleChunkReaderSuite) TestDataRanges(c *chk.C) {
+	buf := make([]byte, 10*512)
+	buf[0] = 1         // unit 0
+	buf[512*3+7] = 1   // unit 3
+	buf[512*9+511] = 1 // unit 9, the last
+
+	// units with fewer than four zero units between them are merged
+	c.Assert(dataRanges(buf, 512, 2048), chk.DeepEquals, []DataRange{{Offset: 0, Length: 4 * 512}, {Offset: 9 * 512, Length: 512}})
+	// but with no merging, each unit with data is a range of its own
+	c.Assert(dataRanges(buf, 512, 0), chk.DeepEquals, []DataRange{{Offset: 0, Length: 512}, {Offset: 3 * 512, Length: 512}, {Offset: 9 * 512, Length: 512}})
+
+	// a short last unit is kept short
+	c.Assert(dataRanges([]byte{0, 0, 0, 1, 0}, 4, 0), chk.DeepEquals, []DataRange{{Offset: 0, Length: 4}})
+	c.Assert(dataRanges([]byte{0, 0, 0, 0, 1}, 4, 0), chk.DeepEquals, []DataRange{{Offset: 4, Length: 1}})
+
+	c.Assert(dataRanges(make([]byte, 2048), 512, 0), chk.HasLen, 0)
+}
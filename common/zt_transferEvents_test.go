@@ -0,0 +1,73 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	chk "gopkg.in/check.v1"
+)
+
+type transferEventsSuite struct{}
+
+var _ = chk.Suite(&transferEventsSuite{})
+
+func (s *transferEventsSuite) TestTransferEventsAreOnlyOutputAsJson(c *chk.C) {
+	lcm := newTestLifecycleMgr()
+	event := TransferEvent{EventType: ETransferEventType.Completed(), Source: "/data/a.txt",
+		Destination: "https://acct.blob.core.windows.net/c/a.txt?sv=2019-12-12&sig=secret", Bytes: 10}
+
+	lcm.TransferEvent(event) // not enabled
+	lcm.EnableTransferEvents()
+	lcm.SetOutputFormat(EOutputFormat.Text())
+	lcm.TransferEvent(event) // enabled, but nothing can read them
+	c.Assert(queuedMessages(lcm), chk.HasLen, 0)
+
+	lcm.SetOutputFormat(EOutputFormat.Json())
+	lcm.TransferEvent(event)
+	msgs := queuedMessages(lcm)
+	c.Assert(msgs, chk.HasLen, 1)
+	c.Assert(strings.Contains(msgs[0].msgContent, "secret"), chk.Equals, false)
+
+	lcm.processJSONOutput(msgs[0])
+	var parsed struct {
+		MessageType string
+		Payload     TransferEvent
+	}
+	c.Assert(json.Unmarshal(lcm.output.(*bytes.Buffer).Bytes(), &parsed), chk.IsNil)
+	c.Assert(parsed.MessageType, chk.Equals, "TransferEvent")
+	c.Assert(parsed.Payload.EventType, chk.Equals, ETransferEventType.Completed())
+	c.Assert(parsed.Payload.Source, chk.Equals, "/data/a.txt")
+	c.Assert(parsed.Payload.Destination, chk.Equals, "https://acct.blob.core.windows.net/c/a.txt?sv=2019-12-12&sig=-REDACTED-")
+	c.Assert(parsed.Payload.Bytes, chk.Equals, int64(10))
+	c.Assert(parsed.Payload.TimeStamp.IsZero(), chk.Equals, false)
+}
+
+func (s *transferEventsSuite) TestTransferEventTypeOf(c *chk.C) {
+	c.Assert(TransferEventTypeOf(ETransferStatus.Success()), chk.Equals, ETransferEventType.Completed())
+	c.Assert(TransferEventTypeOf(ETransferStatus.SkippedEntityAlreadyExists()), chk.Equals, ETransferEventType.Skipped())
+	c.Assert(TransferEventTypeOf(ETransferStatus.SkippedBlobHasSnapshots()), chk.Equals, ETransferEventType.Skipped())
+	c.Assert(TransferEventTypeOf(ETransferStatus.Cancelled()), chk.Equals, ETransferEventType.Cancelled())
+	c.Assert(TransferEventTypeOf(ETransferStatus.Failed()), chk.Equals, ETransferEventType.Failed())
+	c.Assert(TransferEventTypeOf(ETransferStatus.BlobTierFailure()), chk.Equals, ETransferEventType.Failed())
+}
@@ -52,17 +52,15 @@ func main() {
 		azcopyLogPathFolder = azcopyAppPathFolder
 	}
 	if err := os.Mkdir(azcopyLogPathFolder, os.ModeDir|os.ModePerm); err != nil && !os.IsExist(err) {
-		common.PanicIfErr(err)
+		common.GetLifecycleMgr().ReportError(common.NewAzCopyError(common.EErrorCode.FileSystem(), "create the log folder", err))
 	}
 
-	// the user can optionally put the plan files somewhere else
+	// the user can optionally put the plan files somewhere else.
+	// The folder is created when the command starts, since a job that keeps its plan in memory doesn't need it
 	azcopyJobPlanFolder := common.GetLifecycleMgr().GetEnvironmentVariable(common.EEnvironmentVariable.JobPlanLocation())
 	if azcopyJobPlanFolder == "" {
 		azcopyJobPlanFolder = path.Join(azcopyAppPathFolder, "plans")
 	}
-	if err := os.Mkdir(azcopyJobPlanFolder, os.ModeDir|os.ModePerm); err != nil && !os.IsExist(err) {
-		common.PanicIfErr(err)
-	}
 
 	// If insufficient arguments, show usage & terminate
 	if len(os.Args) == 1 {
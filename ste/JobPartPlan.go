@@ -14,12 +14,16 @@ import (
 // dataSchemaVersion defines the data schema version of JobPart order files supported by
 // current version of azcopy
 // To be Incremented every time when we release azcopy with changed dataSchema
-const DataSchemaVersion common.Version = 15
+const DataSchemaVersion common.Version = 17
 
 const (
-	CustomHeaderMaxBytes = 256
-	MetadataMaxBytes     = 1000 // If > 65536, then jobPartPlanBlobData's MetadataLength field's type must change
-	BlobTierMaxBytes     = 10
+	CustomHeaderMaxBytes   = 256
+	MetadataMaxBytes       = 1000 // If > 65536, then jobPartPlanBlobData's MetadataLength field's type must change
+	BlobTierMaxBytes       = 10
+	BlobTagsMaxBytes       = 4000 // enough for 10 tags of the maximum size, even once URL-encoded
+	ContentTypeMapMaxBytes = 4000
+	HeaderRulesMaxBytes    = 4000
+	TierRulesMaxBytes      = 1000
 )
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -33,6 +37,9 @@ func (mmf *JobPartPlanMMF) Plan() *JobPartPlanHeader {
 }
 func (mmf *JobPartPlanMMF) Unmap() { (*common.MMF)(mmf).Unmap() }
 
+// Flush makes sure the plan's latest state is on disk, so that it survives a crash
+func (mmf *JobPartPlanMMF) Flush() error { return (*common.MMF)(mmf).Flush() }
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 // JobPartPlanHeader represents the header of Job Part's memory-mapped file
@@ -54,7 +61,13 @@ type JobPartPlanHeader struct {
 	ForceWrite             common.OverwriteOption      // True if the existing blobs needs to be overwritten.
 	ForceIfReadOnly        bool                        // Supplements ForceWrite with an additional setting for Azure Files. If true, the read-only attribute will be cleared before we overwrite
 	AutoDecompress         bool                        // if true, source data with encodings that represent compression are automatically decompressed when downloading
+	Compress               bool                        // if true, files are gzipped when uploading, unless their extensions say they're compressed already
+	Pack                   common.PackFormat           // if not None, the files of each directory are packed into one archive when uploading
+	Unpack                 bool                        // if true, tar archives are unpacked into the directory they are downloaded to
+	UploadVHD              bool                        // if true, files are uploaded as the page blobs of disks, which must be fixed-size VHDs
+	AppendToBlob           bool                        // if true, only the part of each file that isn't in its (append blob) destination yet is uploaded
 	Priority               common.JobPriority          // The Job Part's priority
+	ActiveHours            common.ActiveHours          // The daily window in which the Job Part's transfers may use the network
 	TTLAfterCompletion     uint32                      // Time to live after completion is used to persists the file on disk of specified time after the completion of JobPartOrder
 	FromTo                 common.FromTo               // The location of the transfer's source & destination
 	Fpo                    common.FolderPropertyOption // option specifying how folders will be handled
@@ -66,6 +79,14 @@ type JobPartPlanHeader struct {
 
 	PreserveSMBPermissions common.PreservePermissionsOption
 	PreserveSMBInfo        bool
+	// PreservePOSIXPermissions says whether the owner, group and mode of files are kept, between local file systems and ADLS Gen2
+	PreservePOSIXPermissions bool
+	// PreserveFileTimes says whether the creation and last write times of local files are kept in blob metadata on upload,
+	// and restored from it on download
+	PreserveFileTimes bool
+	// PreserveXattrs says whether the extended attributes of local files are kept in blob metadata on upload, and
+	// restored from it on download
+	PreserveXattrs bool
 	// S2SGetPropertiesInBackend represents whether to enable get S3 objects' or Azure files' properties during s2s copy in backend.
 	S2SGetPropertiesInBackend bool
 	// S2SSourceChangeValidation represents whether user wants to check if source has changed after enumerating.
@@ -74,6 +95,19 @@ type JobPartPlanHeader struct {
 	DestLengthValidation bool
 	// S2SInvalidMetadataHandleOption represents how user wants to handle invalid metadata.
 	S2SInvalidMetadataHandleOption common.InvalidMetadataHandleOption
+	// S2SPreserveBlobTags represents whether the index tags of source blobs are copied to the destination.
+	S2SPreserveBlobTags bool
+	// S2SMetadataMerge represents how the metadata given by the user is combined with the source's.
+	S2SMetadataMerge common.MetadataMergeOption
+	// S2SCopyMethod represents how the data of block blobs is copied from the source.
+	S2SCopyMethod common.S2SCopyMethod
+	// ArchivedSource represents what is done with source blobs in the archive tier.
+	ArchivedSource common.ArchivedSourceOption
+	// RehydratePriority represents how quickly archived source blobs are brought back online, when they are rehydrated.
+	RehydratePriority common.RehydratePriority
+	// IncludeVersions represents whether the transfers include every version of each blob, in which case
+	// the transfers to one destination must complete in the order in which they are listed.
+	IncludeVersions bool
 
 	// Any fields below this comment are NOT constants; they may change over as the job part is processed.
 	// Care must be taken to read/write to these fields in a thread-safe way!
@@ -109,6 +143,11 @@ func (jpph *JobPartPlanHeader) Transfer(transferIndex uint32) *JobPartPlanTransf
 	return (*JobPartPlanTransfer)(unsafe.Pointer((uintptr(unsafe.Pointer(jpph)) + unsafe.Sizeof(*jpph) + uintptr(jpph.CommandStringLength)) + (unsafe.Sizeof(JobPartPlanTransfer{}) * uintptr(transferIndex))))
 }
 
+// bytesAt returns the bytes of the plan file at the given offset. They are not copied, so they're only valid while it's mapped
+func (jpph *JobPartPlanHeader) bytesAt(offset int64, length int64) []byte {
+	return (*[1 << 30]byte)(unsafe.Pointer(uintptr(unsafe.Pointer(jpph)) + uintptr(offset)))[:length:length]
+}
+
 // CommandString returns the command string given by user when job was created
 func (jpph *JobPartPlanHeader) CommandString() string {
 	commandSlice := []byte{}
@@ -149,6 +188,13 @@ func (jpph *JobPartPlanHeader) TransferSrcDstStrings(transferIndex uint32) (sour
 		isFolder
 }
 
+// TransferSrcRelativePath returns the path of a transfer's source, relative to the source root, as it was given in the
+// JobPartOrder. Remote paths are URL-encoded
+func (jpph *JobPartPlanHeader) TransferSrcRelativePath(transferIndex uint32) string {
+	jppt := jpph.Transfer(transferIndex)
+	return jpph.getString(jppt.SrcOffset, jppt.SrcLength)
+}
+
 func (jpph *JobPartPlanHeader) getString(offset int64, length int16) string {
 	tempSlice := []byte{}
 	sh := (*reflect.SliceHeader)(unsafe.Pointer(&tempSlice))
@@ -162,7 +208,7 @@ func (jpph *JobPartPlanHeader) getString(offset int64, length int16) string {
 // TransferSrcPropertiesAndMetadata returns the SrcHTTPHeaders, properties and metadata for a transfer at given transferIndex in JobPartOrder
 // TODO: Refactor return type to an object
 func (jpph *JobPartPlanHeader) TransferSrcPropertiesAndMetadata(transferIndex uint32) (h common.ResourceHTTPHeaders, metadata common.Metadata, blobType azblob.BlobType, blobTier azblob.AccessTierType,
-	s2sGetPropertiesInBackend bool, DestLengthValidation bool, s2sSourceChangeValidation bool, s2sInvalidMetadataHandleOption common.InvalidMetadataHandleOption, entityType common.EntityType, blobVersionID string) {
+	s2sGetPropertiesInBackend bool, DestLengthValidation bool, s2sSourceChangeValidation bool, s2sInvalidMetadataHandleOption common.InvalidMetadataHandleOption, entityType common.EntityType, blobVersionID string, blobSnapshotID string) {
 	var err error
 	t := jpph.Transfer(transferIndex)
 
@@ -219,6 +265,10 @@ func (jpph *JobPartPlanHeader) TransferSrcPropertiesAndMetadata(transferIndex ui
 		blobVersionID = jpph.getString(offset, t.SrcBlobVersionIDLength)
 		offset += int64(t.SrcBlobVersionIDLength)
 	}
+	if t.SrcBlobSnapshotIDLength != 0 {
+		blobSnapshotID = jpph.getString(offset, t.SrcBlobSnapshotIDLength)
+		offset += int64(t.SrcBlobSnapshotIDLength)
+	}
 	return
 }
 
@@ -232,6 +282,13 @@ type JobPartPlanDstBlob struct {
 	// represents user decision to interpret the content-encoding from source file
 	NoGuessMimeType bool
 
+	// Specifies the content types that the user gave for file extensions, URL-encoded
+	ContentTypeMapLength uint16
+	ContentTypeMap       [ContentTypeMapMaxBytes]byte
+
+	// Specifies whether the content type of a file whose extension doesn't give it is detected from its content
+	SniffContentType bool
+
 	// Specifies the length of MIME content type of the blob
 	ContentTypeLength uint16
 
@@ -262,16 +319,34 @@ type JobPartPlanDstBlob struct {
 	// Specifies the cache control of the blob
 	CacheControl [CustomHeaderMaxBytes]byte
 
+	// Specifies the rules, in JSON, that override the headers above for the files whose paths match their patterns
+	HeaderRulesLength uint16
+	HeaderRules       [HeaderRulesMaxBytes]byte
+
 	// Specifies the tier if this is a block or page blob
 	BlockBlobTier common.BlockBlobTier
 	PageBlobTier  common.PageBlobTier
 
+	// Specifies the rules that override BlockBlobTier for the block blobs whose paths match their patterns
+	BlockBlobTierRulesLength uint16
+	BlockBlobTierRules       [TierRulesMaxBytes]byte
+
 	// Controls uploading of MD5 hashes
 	PutMd5 bool
 
+	// Controls the sending of CRC64 checksums, with each block and in the metadata of the blob
+	PutCrc64 bool
+
+	// Controls the storing of SHA-256 digests in the metadata of the blob
+	PutSha256 bool
+
 	MetadataLength uint16
 	Metadata       [MetadataMaxBytes]byte
 
+	// Specifies the index tags to set on the blobs, in the URL-encoded form of the x-ms-tags header
+	BlobTagsLength uint16
+	BlobTags       [BlobTagsMaxBytes]byte
+
 	// Specifies the maximum size of block which determines the number of chunks and chunk size of a transfer
 	BlockSize int64
 }
@@ -287,6 +362,12 @@ type JobPartPlanDstLocal struct {
 
 	// says how MD5 verification failures should be actioned
 	MD5VerificationOption common.HashValidationOption
+
+	// says how CRC64 verification failures should be actioned
+	Crc64VerificationOption common.HashValidationOption
+
+	// says how SHA-256 verification failures should be actioned
+	Sha256VerificationOption common.HashValidationOption
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -325,6 +406,9 @@ type JobPartPlanTransfer struct {
 	SrcBlobTypeLength           int16
 	SrcBlobTierLength           int16
 	SrcBlobVersionIDLength      int16
+	SrcBlobSnapshotIDLength     int16
+	// SrcBlobDeleted represents whether the source blob was soft-deleted when it was listed
+	SrcBlobDeleted bool
 
 	// Any fields below this comment are NOT constants; they may change over as the transfer is processed.
 	// Care must be taken to read/write to these fields in a thread-safe way!
@@ -337,6 +421,19 @@ type JobPartPlanTransfer struct {
 	// atomicErrorCode has a default value (0) which means either there was no error or transfer failed because some non storageError.
 	// atomicErrorCode should not be directly accessed anywhere except by transferStatus and setTransferStatus
 	atomicErrorCode int32
+
+	// atomicSavedBytes is how much of a download has been saved to the destination file, counting from its start.
+	// It lets a resumed job carry on from there, rather than downloading the whole file again
+	atomicSavedBytes int64
+}
+
+// stringsLength returns the total length of the transfer's strings, which are stored together, starting at SrcOffset
+func (jppt *JobPartPlanTransfer) stringsLength() int64 {
+	return int64(jppt.SrcLength) + int64(jppt.DstLength) + int64(jppt.SrcContentTypeLength) +
+		int64(jppt.SrcContentEncodingLength) + int64(jppt.SrcContentLanguageLength) + int64(jppt.SrcContentDispositionLength) +
+		int64(jppt.SrcCacheControlLength) + int64(jppt.SrcContentMD5Length) + int64(jppt.SrcMetadataLength) +
+		int64(jppt.SrcBlobTypeLength) + int64(jppt.SrcBlobTierLength) + int64(jppt.SrcBlobVersionIDLength) +
+		int64(jppt.SrcBlobSnapshotIDLength)
 }
 
 // TransferStatus returns the transfer's status
@@ -361,6 +458,34 @@ func (jppt *JobPartPlanTransfer) SetTransferStatus(status common.TransferStatus,
 	}
 }
 
+// cancelByUser marks the transfer as cancelled by the user, unless it has already finished. It says whether it did
+func (jppt *JobPartPlanTransfer) cancelByUser() bool {
+	cancelled := common.AtomicMorphInt32((*int32)(&jppt.atomicTransferStatus),
+		func(startVal int32) (val int32, morphResult interface{}) {
+			status := common.TransferStatus(startVal)
+			if status < 0 || status == common.ETransferStatus.Success() {
+				return startVal, false
+			}
+			return int32(common.ETransferStatus.CancelledByUser()), true
+		})
+	return cancelled.(bool)
+}
+
+// SavedBytes returns how much of the download has been saved to the destination file
+func (jppt *JobPartPlanTransfer) SavedBytes() int64 {
+	return atomic.LoadInt64(&jppt.atomicSavedBytes)
+}
+
+// SetSavedBytes records how much of the download has been saved to the destination file
+func (jppt *JobPartPlanTransfer) SetSavedBytes(n int64) {
+	atomic.StoreInt64(&jppt.atomicSavedBytes, n)
+}
+
+// addSavedBytes records that n more bytes of the download have been saved
+func (jppt *JobPartPlanTransfer) addSavedBytes(n int64) {
+	atomic.AddInt64(&jppt.atomicSavedBytes, n)
+}
+
 // ErrorCode returns the transfer's errorCode.
 func (jppt *JobPartPlanTransfer) ErrorCode() int32 {
 	return atomic.LoadInt32(&jppt.atomicErrorCode)
@@ -1,6 +1,7 @@
 package ste
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -23,6 +25,14 @@ func (jppfn *JobPartPlanFileName) GetJobPartPlanPath() string {
 
 const jobPartPlanFileNameFormat = "%v--%05d.steV%d"
 
+// planFileTempSuffix is added to the name of a plan file while it's being written. The file only gets its real name
+// once it's complete, so a crash part way through can never leave a half-written plan file under the real name
+const planFileTempSuffix = ".tmp"
+
+// planFileDamagedSuffix is added to the name of a plan file that fails validation. The file is kept, for investigation,
+// but it's no longer picked up when jobs are listed or resumed
+const planFileDamagedSuffix = ".damaged"
+
 // TODO: This needs testing
 func (jpfn JobPartPlanFileName) Parse() (jobID common.JobID, partNumber common.PartNumber, err error) {
 	var dataSchemaVersion common.Version
@@ -51,12 +61,42 @@ func (jpfn JobPartPlanFileName) Parse() (jobID common.JobID, partNumber common.P
 }
 
 func (jpfn JobPartPlanFileName) Delete() error {
+	inMemoryPlans.Lock()
+	_, inMemory := inMemoryPlans.plans[jpfn]
+	delete(inMemoryPlans.plans, jpfn)
+	inMemoryPlans.Unlock()
+	if inMemory {
+		return nil
+	}
 	return os.Remove(string(jpfn))
 }
 
+// inMemoryPlans holds the plans of the job parts whose plans are kept in memory (see common.PlanLocation),
+// by the names that their plan files would have had
+var inMemoryPlans = struct {
+	sync.Mutex
+	plans map[JobPartPlanFileName][]byte
+}{plans: make(map[JobPartPlanFileName][]byte)}
+
 func (jpfn JobPartPlanFileName) Map() *JobPartPlanMMF {
+	inMemoryPlans.Lock()
+	data, inMemory := inMemoryPlans.plans[jpfn]
+	inMemoryPlans.Unlock()
+	if inMemory {
+		return (*JobPartPlanMMF)(common.NewInMemoryMMF(data))
+	}
+
+	planPath := jpfn.GetJobPartPlanPath()
+	plan, sealer, err := readEncryptedPlanFile(planPath)
+	common.PanicIfErr(err)
+	if plan != nil {
+		return (*JobPartPlanMMF)(common.NewPersistedInMemoryMMF(plan, func(data []byte) error {
+			return sealer.writeFile(planPath, data)
+		}))
+	}
+
 	// opening the file with given filename
-	file, err := os.OpenFile(jpfn.GetJobPartPlanPath(), os.O_RDWR, common.DEFAULT_FILE_PERM)
+	file, err := os.OpenFile(planPath, os.O_RDWR, common.DEFAULT_FILE_PERM)
 	common.PanicIfErr(err)
 	// Ensure the file gets closed (although we can continue to use the MMF)
 	defer file.Close()
@@ -68,6 +108,100 @@ func (jpfn JobPartPlanFileName) Map() *JobPartPlanMMF {
 	return (*JobPartPlanMMF)(mmf)
 }
 
+// Validate checks that the plan file is complete, and belongs to the job part that its name says it does.
+// It should be called before Map, since mapping a truncated file would fail (or worse, fault) when it's read.
+// An encrypted plan file is decrypted first, so a planDecryptionError means that the file can't be read with the keys at hand
+func (jpfn JobPartPlanFileName) Validate() error {
+	return jpfn.validate(jpfn.GetJobPartPlanPath())
+}
+
+func (jpfn JobPartPlanFileName) validate(path string) error {
+	plan, _, err := readEncryptedPlanFile(path)
+	if err != nil {
+		return err
+	}
+	if plan != nil {
+		return jpfn.validatePlan(bytes.NewReader(plan), int64(len(plan)))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return jpfn.validatePlan(file, fileInfo.Size())
+}
+
+// validatePlan checks the plan, of the given size, that can be read from file
+func (jpfn JobPartPlanFileName) validatePlan(file io.ReaderAt, size int64) error {
+	jobID, partNum, err := jpfn.Parse()
+	if err != nil {
+		return err
+	}
+
+	var jpph JobPartPlanHeader
+	headerSize := int64(unsafe.Sizeof(jpph))
+	if size < headerSize {
+		return fmt.Errorf("the file is %d bytes long, which is too short to hold the plan header", size)
+	}
+	if err = readValue(file, 0, unsafe.Pointer(&jpph), headerSize); err != nil {
+		return err
+	}
+	if jpph.Version != DataSchemaVersion {
+		return fmt.Errorf("the plan header has data schema version %d, but %d was expected", jpph.Version, DataSchemaVersion)
+	}
+	if jpph.JobID != jobID || jpph.PartNum != partNum {
+		return fmt.Errorf("the plan header is for job %s part %d, which doesn't match the file name", jpph.JobID, jpph.PartNum)
+	}
+
+	transferSize := int64(unsafe.Sizeof(JobPartPlanTransfer{}))
+	transfersOffset := headerSize + int64(jpph.CommandStringLength)
+	if size < transfersOffset+transferSize*int64(jpph.NumTransfers) {
+		return fmt.Errorf("the file is %d bytes long, which is too short to hold its %d transfers", size, jpph.NumTransfers)
+	}
+	if jpph.NumTransfers == 0 {
+		return nil
+	}
+
+	// the strings of the last transfer are the last thing written to the file, so if they are all there, everything is
+	var last JobPartPlanTransfer
+	if err = readValue(file, transfersOffset+transferSize*int64(jpph.NumTransfers-1), unsafe.Pointer(&last), transferSize); err != nil {
+		return err
+	}
+	end := last.SrcOffset + int64(last.SrcLength) + int64(last.DstLength) + int64(last.SrcContentTypeLength) +
+		int64(last.SrcContentEncodingLength) + int64(last.SrcContentLanguageLength) + int64(last.SrcContentDispositionLength) +
+		int64(last.SrcCacheControlLength) + int64(last.SrcContentMD5Length) + int64(last.SrcMetadataLength) +
+		int64(last.SrcBlobTypeLength) + int64(last.SrcBlobTierLength) + int64(last.SrcBlobVersionIDLength) +
+		int64(last.SrcBlobSnapshotIDLength)
+	if last.SrcOffset < transfersOffset || size < end {
+		return fmt.Errorf("the file is %d bytes long, but its transfers' strings run to %d bytes", size, end)
+	}
+	return nil
+}
+
+// readValue reads length bytes, from the given offset of the file, straight into the structure at v
+func readValue(file io.ReaderAt, offset int64, v unsafe.Pointer, length int64) error {
+	byteSlice := (*[1 << 30]byte)(v)[:length:length]
+	_, err := file.ReadAt(byteSlice, offset)
+	return err
+}
+
+// writePlanValue writes a structure value to an io.Writer & returns the number of bytes written
+func writePlanValue(writer io.Writer, v interface{}) int64 {
+	rv := reflect.ValueOf(v)
+	structSize := reflect.TypeOf(v).Elem().Size()
+	slice := reflect.SliceHeader{Data: rv.Pointer(), Len: int(structSize), Cap: int(structSize)}
+	byteSlice := *(*[]byte)(unsafe.Pointer(&slice))
+	err := binary.Write(writer, binary.LittleEndian, byteSlice)
+	common.PanicIfErr(err)
+	return int64(structSize)
+}
+
 // createJobPartPlanFile creates the memory map JobPartPlanHeader using the given JobPartOrder and JobPartPlanBlobData
 func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	// Validate that the passed-in strings can fit in their respective fields
@@ -101,19 +235,46 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	if len(order.BlobAttributes.Metadata) > len(JobPartPlanDstBlob{}.Metadata) {
 		panic(fmt.Errorf("metadata string is too large: %q", order.BlobAttributes.Metadata))
 	}
+	if len(order.BlobAttributes.BlobTags) > len(JobPartPlanDstBlob{}.BlobTags) {
+		panic(fmt.Errorf("blob tags string is too large: %q", order.BlobAttributes.BlobTags))
+	}
+	if len(order.BlobAttributes.ContentTypeMap) > len(JobPartPlanDstBlob{}.ContentTypeMap) {
+		panic(fmt.Errorf("content type map string is too large: %q", order.BlobAttributes.ContentTypeMap))
+	}
+	if len(order.BlobAttributes.HeaderRules) > len(JobPartPlanDstBlob{}.HeaderRules) {
+		panic(fmt.Errorf("header rules string is too large: %q", order.BlobAttributes.HeaderRules))
+	}
+	if len(order.BlobAttributes.BlockBlobTierRules) > len(JobPartPlanDstBlob{}.BlockBlobTierRules) {
+		panic(fmt.Errorf("block blob tier rules string is too large: %q", order.BlobAttributes.BlockBlobTierRules))
+	}
 
-	// This nested function writes a structure value to an io.Writer & returns the number of bytes written
-	writeValue := func(writer io.Writer, v interface{}) int64 {
-		rv := reflect.ValueOf(v)
-		structSize := reflect.TypeOf(v).Elem().Size()
-		slice := reflect.SliceHeader{Data: rv.Pointer(), Len: int(structSize), Cap: int(structSize)}
-		byteSlice := *(*[]byte)(unsafe.Pointer(&slice))
-		err := binary.Write(writer, binary.LittleEndian, byteSlice)
-		common.PanicIfErr(err)
-		return int64(structSize)
+	if order.PlanLocation == common.EPlanLocation.Memory() {
+		var buffer bytes.Buffer
+		writePlan(&buffer, order)
+		inMemoryPlans.Lock()
+		inMemoryPlans.plans[jpfn] = buffer.Bytes()
+		inMemoryPlans.Unlock()
+		return
+	}
+
+	jpfn.writePlanFile(func(writer io.Writer) { writePlan(writer, order) })
+}
+
+// writePlanFile writes a new plan file, with what write writes. It's encrypted, if plan files are to be encrypted
+func (jpfn JobPartPlanFileName) writePlanFile(write func(writer io.Writer)) {
+	sealer, err := newPlanSealerFromEnvironment()
+	if err != nil {
+		panic(fmt.Errorf("couldn't encrypt job part plan file %q: %v", jpfn, err))
+	}
+	if sealer != nil {
+		var buffer bytes.Buffer
+		write(&buffer)
+		if err = sealer.writeFile(jpfn.GetJobPartPlanPath(), buffer.Bytes()); err != nil {
+			panic(fmt.Errorf("couldn't write encrypted job part plan file %q: %v", jpfn, err))
+		}
+		return
 	}
 
-	eof := int64(0)
 	/*
 	*       Following Steps are executed:
 	*		1. Get File Name from JobId and Part Number
@@ -124,14 +285,30 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	* 		6. Return File Name
 	 */
 
-	// create the Job Part Plan file
+	// create the Job Part Plan file. It's written under a temporary name, and only renamed once it's complete (see below)
 	//planPathname := planDir + "/" + string(jpfn)
-	file, err := os.Create(jpfn.GetJobPartPlanPath())
+	planPath := jpfn.GetJobPartPlanPath()
+	file, err := os.Create(planPath + planFileTempSuffix)
 	if err != nil {
 		panic(fmt.Errorf("couldn't create job part plan file %q: %v", jpfn, err))
 	}
 	defer file.Close()
 
+	write(file)
+
+	// Make sure everything is on disk before the file gets its real name. That way, if the machine crashes,
+	// the plan file is either all there or not there at all (in which case the leftover temp file is cleaned up later)
+	common.PanicIfErr(file.Sync())
+	common.PanicIfErr(file.Close()) // the defer above will then do nothing
+	if err = os.Rename(planPath+planFileTempSuffix, planPath); err != nil {
+		panic(fmt.Errorf("couldn't rename job part plan file %q into place: %v", jpfn, err))
+	}
+}
+
+// writePlan writes the plan for the job part order, in the layout that JobPartPlanMMF expects
+func writePlan(writer io.Writer, order common.CopyJobPartOrderRequest) {
+	eof := int64(0)
+
 	// If block size from the front-end is set to 0
 	// store the block-size as 0. While getting the transfer Info
 	// auto correction logic will apply. If the block-size stored is not 0
@@ -165,7 +342,13 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		ForceWrite:             order.ForceWrite,
 		ForceIfReadOnly:        order.ForceIfReadOnly,
 		AutoDecompress:         order.AutoDecompress,
+		Compress:               order.Compress,
+		Pack:                   order.Pack,
+		Unpack:                 order.Unpack,
+		UploadVHD:              order.UploadVHD,
+		AppendToBlob:           order.AppendToBlob,
 		Priority:               order.Priority,
+		ActiveHours:            order.ActiveHours,
 		TTLAfterCompletion:     uint32(time.Time{}.Nanosecond()),
 		FromTo:                 order.FromTo,
 		Fpo:                    order.Fpo,
@@ -175,27 +358,45 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		DstBlobData: JobPartPlanDstBlob{
 			BlobType:                 order.BlobAttributes.BlobType,
 			NoGuessMimeType:          order.BlobAttributes.NoGuessMimeType,
+			ContentTypeMapLength:     uint16(len(order.BlobAttributes.ContentTypeMap)),
+			SniffContentType:         order.BlobAttributes.SniffContentType,
 			ContentTypeLength:        uint16(len(order.BlobAttributes.ContentType)),
 			ContentEncodingLength:    uint16(len(order.BlobAttributes.ContentEncoding)),
 			ContentDispositionLength: uint16(len(order.BlobAttributes.ContentDisposition)),
 			ContentLanguageLength:    uint16(len(order.BlobAttributes.ContentLanguage)),
 			CacheControlLength:       uint16(len(order.BlobAttributes.CacheControl)),
+			HeaderRulesLength:        uint16(len(order.BlobAttributes.HeaderRules)),
 			PutMd5:                   order.BlobAttributes.PutMd5, // here because it relates to uploads (blob destination)
+			PutCrc64:                 order.BlobAttributes.PutCrc64,
+			PutSha256:                order.BlobAttributes.PutSha256,
 			BlockBlobTier:            order.BlobAttributes.BlockBlobTier,
+			BlockBlobTierRulesLength: uint16(len(order.BlobAttributes.BlockBlobTierRules)),
 			PageBlobTier:             order.BlobAttributes.PageBlobTier,
 			MetadataLength:           uint16(len(order.BlobAttributes.Metadata)),
+			BlobTagsLength:           uint16(len(order.BlobAttributes.BlobTags)),
 			BlockSize:                blockSize,
 		},
 		DstLocalData: JobPartPlanDstLocal{
 			PreserveLastModifiedTime: order.BlobAttributes.PreserveLastModifiedTime,
 			MD5VerificationOption:    order.BlobAttributes.MD5ValidationOption, // here because it relates to downloads (file destination)
+			Crc64VerificationOption:  order.BlobAttributes.Crc64ValidationOption,
+			Sha256VerificationOption: order.BlobAttributes.Sha256ValidationOption,
 		},
-		PreserveSMBPermissions: order.PreserveSMBPermissions,
-		PreserveSMBInfo:        order.PreserveSMBInfo,
+		PreserveSMBPermissions:   order.PreserveSMBPermissions,
+		PreserveSMBInfo:          order.PreserveSMBInfo,
+		PreservePOSIXPermissions: order.PreservePOSIXPermissions,
+		PreserveFileTimes:        order.PreserveFileTimes,
+		PreserveXattrs:           order.PreserveXattrs,
 		// For S2S copy, per JobPartPlan info
 		S2SGetPropertiesInBackend:      order.S2SGetPropertiesInBackend,
 		S2SSourceChangeValidation:      order.S2SSourceChangeValidation,
 		S2SInvalidMetadataHandleOption: order.S2SInvalidMetadataHandleOption,
+		S2SPreserveBlobTags:            order.S2SPreserveBlobTags,
+		S2SMetadataMerge:               order.S2SMetadataMerge,
+		S2SCopyMethod:                  order.S2SCopyMethod,
+		ArchivedSource:                 order.ArchivedSource,
+		RehydratePriority:              order.RehydratePriority,
+		IncludeVersions:                order.IncludeVersions,
 		DestLengthValidation:           order.DestLengthValidation,
 		atomicJobStatus:                common.EJobStatus.InProgress(), // We default to InProgress
 		DeleteSnapshotsOption:          order.BlobAttributes.DeleteSnapshotsOption,
@@ -212,15 +413,17 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 	copy(jpph.DstBlobData.ContentLanguage[:], order.BlobAttributes.ContentLanguage)
 	copy(jpph.DstBlobData.ContentDisposition[:], order.BlobAttributes.ContentDisposition)
 	copy(jpph.DstBlobData.CacheControl[:], order.BlobAttributes.CacheControl)
+	copy(jpph.DstBlobData.HeaderRules[:], order.BlobAttributes.HeaderRules)
+	copy(jpph.DstBlobData.BlockBlobTierRules[:], order.BlobAttributes.BlockBlobTierRules)
 	copy(jpph.DstBlobData.Metadata[:], order.BlobAttributes.Metadata)
+	copy(jpph.DstBlobData.BlobTags[:], order.BlobAttributes.BlobTags)
+	copy(jpph.DstBlobData.ContentTypeMap[:], order.BlobAttributes.ContentTypeMap)
 
-	eof += writeValue(file, &jpph)
+	eof += writePlanValue(writer, &jpph)
 
 	// write the command string in the JobPart Plan file
-	bytesWritten, err := file.WriteString(order.CommandString)
-	if err != nil {
-		panic(err)
-	}
+	bytesWritten, err := io.WriteString(writer, order.CommandString)
+	common.PanicIfErr(err)
 	eof += int64(bytesWritten)
 
 	// srcDstStringsOffset points to after the header & all the transfers; this is where the src/dst strings go for each transfer
@@ -269,11 +472,13 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			SrcBlobTypeLength:           int16(len(order.Transfers[t].BlobType)),
 			SrcBlobTierLength:           int16(len(order.Transfers[t].BlobTier)),
 			SrcBlobVersionIDLength:      int16(len(order.Transfers[t].BlobVersionID)),
+			SrcBlobSnapshotIDLength:     int16(len(order.Transfers[t].BlobSnapshotID)),
+			SrcBlobDeleted:              order.Transfers[t].BlobDeleted,
 
 			atomicTransferStatus: common.ETransferStatus.Started(), // Default
 			//ChunkNum:                getNumChunks(uint64(order.Transfers[t].SourceSize), uint64(data.BlockSize)),
 		}
-		eof += writeValue(file, &jppt) // Write the transfer entry
+		eof += writePlanValue(writer, &jppt) // Write the transfer entry
 
 		// The NEXT transfer's src/dst string come after THIS transfer's src/dst strings
 		srcDstStringsOffset[t] = currentSrcStringOffset
@@ -281,7 +486,7 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		currentSrcStringOffset += int64(jppt.SrcLength + jppt.DstLength + jppt.SrcContentTypeLength +
 			jppt.SrcContentEncodingLength + jppt.SrcContentLanguageLength + jppt.SrcContentDispositionLength +
 			jppt.SrcCacheControlLength + jppt.SrcContentMD5Length + jppt.SrcMetadataLength +
-			jppt.SrcBlobTypeLength + jppt.SrcBlobTierLength + jppt.SrcBlobVersionIDLength)
+			jppt.SrcBlobTypeLength + jppt.SrcBlobTierLength + jppt.SrcBlobVersionIDLength + jppt.SrcBlobSnapshotIDLength)
 	}
 
 	// All the transfers were written; now write each transfer's src/dst strings
@@ -292,42 +497,42 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 		}
 
 		// Write the src & dst strings to the job part plan file
-		bytesWritten, err := file.WriteString(order.Transfers[t].Source)
+		bytesWritten, err := io.WriteString(writer, order.Transfers[t].Source)
 		common.PanicIfErr(err)
 		eof += int64(bytesWritten)
 		// write the destination string in memory map file
-		bytesWritten, err = file.WriteString(order.Transfers[t].Destination)
+		bytesWritten, err = io.WriteString(writer, order.Transfers[t].Destination)
 		common.PanicIfErr(err)
 		eof += int64(bytesWritten)
 
 		// For S2S copy (and, in the case of Content-MD5, always), write the src properties
 		if len(order.Transfers[t].ContentType) != 0 {
-			bytesWritten, err = file.WriteString(order.Transfers[t].ContentType)
+			bytesWritten, err = io.WriteString(writer, order.Transfers[t].ContentType)
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if len(order.Transfers[t].ContentEncoding) != 0 {
-			bytesWritten, err = file.WriteString(order.Transfers[t].ContentEncoding)
+			bytesWritten, err = io.WriteString(writer, order.Transfers[t].ContentEncoding)
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if len(order.Transfers[t].ContentLanguage) != 0 {
-			bytesWritten, err = file.WriteString(order.Transfers[t].ContentLanguage)
+			bytesWritten, err = io.WriteString(writer, order.Transfers[t].ContentLanguage)
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if len(order.Transfers[t].ContentDisposition) != 0 {
-			bytesWritten, err = file.WriteString(order.Transfers[t].ContentDisposition)
+			bytesWritten, err = io.WriteString(writer, order.Transfers[t].ContentDisposition)
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if len(order.Transfers[t].CacheControl) != 0 {
-			bytesWritten, err = file.WriteString(order.Transfers[t].CacheControl)
+			bytesWritten, err = io.WriteString(writer, order.Transfers[t].CacheControl)
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if order.Transfers[t].ContentMD5 != nil { // if non-nil but 0 len, will simply not be read by the consumer (since length is zero)
-			bytesWritten, err = file.WriteString(string(order.Transfers[t].ContentMD5))
+			bytesWritten, err = io.WriteString(writer, string(order.Transfers[t].ContentMD5))
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
@@ -336,25 +541,66 @@ func (jpfn JobPartPlanFileName) Create(order common.CopyJobPartOrderRequest) {
 			metadataStr, err := order.Transfers[t].Metadata.Marshal()
 			common.PanicIfErr(err)
 
-			bytesWritten, err = file.WriteString(metadataStr)
+			bytesWritten, err = io.WriteString(writer, metadataStr)
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if len(order.Transfers[t].BlobType) != 0 {
-			bytesWritten, err = file.WriteString(string(order.Transfers[t].BlobType))
+			bytesWritten, err = io.WriteString(writer, string(order.Transfers[t].BlobType))
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if len(order.Transfers[t].BlobTier) != 0 {
-			bytesWritten, err = file.WriteString(string(order.Transfers[t].BlobTier))
+			bytesWritten, err = io.WriteString(writer, string(order.Transfers[t].BlobTier))
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 		if len(order.Transfers[t].BlobVersionID) != 0 {
-			bytesWritten, err = file.WriteString(order.Transfers[t].BlobVersionID)
+			bytesWritten, err = io.WriteString(writer, order.Transfers[t].BlobVersionID)
+			common.PanicIfErr(err)
+			eof += int64(bytesWritten)
+		}
+		if len(order.Transfers[t].BlobSnapshotID) != 0 {
+			bytesWritten, err = io.WriteString(writer, order.Transfers[t].BlobSnapshotID)
 			common.PanicIfErr(err)
 			eof += int64(bytesWritten)
 		}
 	}
-	// the file is closed to due to defer above
+}
+
+// CreateForRetry writes the plan file for a part of a new job, which retries some of the transfers of a part of an
+// existing job (e.g. the ones that failed). Everything else is copied from the existing part, so the new job has the
+// same options, and the transfers' source properties don't have to be fetched again
+func (jpfn JobPartPlanFileName) CreateForRetry(src *JobPartPlanHeader, jobID common.JobID, partNum common.PartNumber, transfers []uint32, isFinalPart bool) {
+	jpph := *src
+	jpph.StartTime = time.Now().UnixNano()
+	jpph.JobID = jobID
+	jpph.PartNum = partNum
+	jpph.IsFinalPart = isFinalPart
+	jpph.NumTransfers = uint32(len(transfers))
+	jpph.atomicJobStatus = common.EJobStatus.InProgress()
+
+	jpfn.writePlanFile(func(file io.Writer) {
+		eof := writePlanValue(file, &jpph)
+		bytesWritten, err := io.WriteString(file, src.CommandString())
+		common.PanicIfErr(err)
+		eof += int64(bytesWritten)
+
+		// each transfer's strings are copied as they are, so only their offset changes
+		currentSrcStringOffset := eof + int64(unsafe.Sizeof(JobPartPlanTransfer{}))*int64(len(transfers))
+		for _, t := range transfers {
+			jppt := *src.Transfer(t)
+			jppt.SrcOffset = currentSrcStringOffset
+			jppt.CompletionTime = 0
+			jppt.atomicTransferStatus = common.ETransferStatus.Started()
+			jppt.atomicErrorCode = 0
+			writePlanValue(file, &jppt)
+			currentSrcStringOffset += jppt.stringsLength()
+		}
+		for _, t := range transfers {
+			jppt := src.Transfer(t)
+			_, err = file.Write(src.bytesAt(jppt.SrcOffset, jppt.stringsLength()))
+			common.PanicIfErr(err)
+		}
+	})
 }
@@ -110,7 +110,7 @@ var JobsAdmin interface {
 	RequestTuneSlowly()
 }
 
-func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targetRateInMegaBitsPerSec float64, azcopyJobPlanFolder string, azcopyLogPathFolder string, providePerfAdvice bool) {
+func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, retry RetrySettings, checkpoint CheckpointInterval, targetRateInMegaBitsPerSec float64, opsPerSecond int, memoryLimitGB float64, azcopyJobPlanFolder string, azcopyLogPathFolder string, providePerfAdvice bool) {
 	if JobsAdmin != nil {
 		panic("initJobsAdmin was already called once")
 	}
@@ -137,50 +137,60 @@ func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targ
 	// from which each part is picked up one by one
 	// and transfers of that JobPart are scheduled
 	partsCh := make(chan IJobPartMgr, PartsChannelSize)
-	// Create normal & low transfer/chunk channels
+	// Create high, normal & low transfer/chunk channels
+	highTransferCh, highChunkCh := make(chan IJobPartTransferMgr, channelSize), make(chan chunkFunc, channelSize)
 	normalTransferCh, normalChunkCh := make(chan IJobPartTransferMgr, channelSize), make(chan chunkFunc, channelSize)
 	lowTransferCh, lowChunkCh := make(chan IJobPartTransferMgr, channelSize), make(chan chunkFunc, channelSize)
 
-	maxRamBytesToUse := getMaxRamForChunks()
-
-	// default to a pacer that doesn't actually control the rate
-	// (it just records total throughput, since for historical reasons we do that in the pacer)
-	var pacer pacerAdmin = newNullAutoPacer()
-	if targetRateInMegaBitsPerSec > 0 {
-		// use the "networking mega" (based on powers of 10, not powers of 2, since that's what mega means in networking context)
-		targetRateInBytesPerSec := int64(targetRateInMegaBitsPerSec * 1000 * 1000 / 8)
-		unusedExpectedCoarseRequestByteCount := int64(0)
-		pacer = newTokenBucketPacer(targetRateInBytesPerSec, unusedExpectedCoarseRequestByteCount)
-		// Note: as at July 2019, we don't currently have a shutdown method/event on JobsAdmin where this pacer
-		// could be shut down. But, it's global anyway, so we just leave it running until application exit.
+	// With a hard limit, both the slices that are in use and the ones that are kept for re-use are kept within it,
+	// and the slices are counted at their real size. Scheduling of new chunks blocks until they fit.
+	// Without one, only the in-use chunks are limited, so that the pool can keep more for re-use
+	maxRamBytesToUse := getMaxRamForChunks(memoryLimitGB)
+	slicePool := common.NewMultiSizeSlicePool(common.MaxBlockBlobBlockSize)
+	cacheLimiter := common.NewCacheLimiter(maxRamBytesToUse)
+	if memoryLimitGB > 0 {
+		slicePool = common.NewMultiSizeSlicePoolWithLimit(common.MaxBlockBlobBlockSize, maxRamBytesToUse)
+		cacheLimiter = common.NewHardCacheLimiter(maxRamBytesToUse)
 	}
 
+	// the pacer only controls the rate if there's a cap, which can be set (or changed, or removed) while jobs are running.
+	// Either way, it records total throughput, since for historical reasons we do that in the pacer
+	pacer := newAdjustablePacer(targetRateInMegaBitsPerSec)
+
 	ja := &jobsAdmin{
 		concurrency:             concurrency,
+		retrySettings:           retry,
+		stallWatchdog:           newStallWatchdog(retry.StallTimeout),
+		planCheckpointer:        newPlanCheckpointer(checkpoint),
 		logger:                  common.NewAppLogger(pipeline.LogInfo, azcopyLogPathFolder),
 		jobIDToJobMgr:           newJobIDToJobMgr(),
 		logDir:                  azcopyLogPathFolder,
 		planDir:                 azcopyJobPlanFolder,
 		pacer:                   pacer,
-		slicePool:               common.NewMultiSizeSlicePool(common.MaxBlockBlobBlockSize),
-		cacheLimiter:            common.NewCacheLimiter(maxRamBytesToUse),
+		opsLimiter:              newOpsRateLimiter(opsPerSecond),
+		slicePool:               slicePool,
+		cacheLimiter:            cacheLimiter,
+		isMemoryHardLimited:     memoryLimitGB > 0,
 		fileCountLimiter:        common.NewCacheLimiter(int64(concurrency.MaxOpenDownloadFiles)),
 		cpuMonitor:              cpuMon,
 		appCtx:                  appCtx,
-		commandLineMbpsCap:      targetRateInMegaBitsPerSec,
 		provideBenchmarkResults: providePerfAdvice,
 		coordinatorChannels: CoordinatorChannels{
 			partsChannel:     partsCh,
+			highTransferCh:   highTransferCh,
 			normalTransferCh: normalTransferCh,
 			lowTransferCh:    lowTransferCh,
 		},
 		xferChannels: XferChannels{
 			partsChannel:     partsCh,
+			highTransferCh:   highTransferCh,
 			normalTransferCh: normalTransferCh,
 			lowTransferCh:    lowTransferCh,
+			highChunkCh:      highChunkCh,
 			normalChunckCh:   normalChunkCh,
 			lowChunkCh:       lowChunkCh,
 		},
+		priorityRotation: defaultPriorityRotation,
 		poolSizingChannels: poolSizingChannels{ // all deliberately unbuffered, because pool sizer routine works in lock-step with these - processing them as they happen, never catching up on populated buffer later
 			entryNotificationCh: make(chan struct{}),
 			exitNotificationCh:  make(chan struct{}),
@@ -204,9 +214,26 @@ func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targ
 
 	JobsAdmin = ja
 
+	// if we are stopped mid-job (e.g. by SIGTERM), make sure that what we've logged so far is not lost.
+	// The plan files need no such care, since they are memory-mapped, and the OS persists them regardless of how we exit
+	// (flushing them, which planCheckpointer does, only matters if the machine itself goes down)
+	common.GetLifecycleMgr().RegisterCleanupHook(ja.flushJobLogs)
+
 	// Spin up slice pool pruner
 	go ja.slicePoolPruneLoop()
 
+	// Pick up changes that "jobs set" makes to the settings of running jobs
+	go ja.jobControlLoop()
+
+	// Show other processes that the jobs we're running, or holding in the queue, are still alive
+	go ja.jobHeartbeatLoop()
+
+	// Restart transfers whose requests have hung
+	go ja.stallWatchdog.run()
+
+	// Flush the plan files every so often, if the user has asked for that
+	go ja.planCheckpointer.run()
+
 	// One routine constantly monitors the partsChannel.  It takes the JobPartManager from
 	// the Channel and schedules the transfers of that JobPart.
 	go ja.scheduleJobParts()
@@ -226,7 +253,12 @@ func initJobsAdmin(appCtx context.Context, concurrency ConcurrencySettings, targ
 // currently-unused, re-usable slices, that is not tracked by cacheLimiter.
 // Also, block sizes that are not powers of two result in extra usage over and above this limit. (E.g. 100 MB blocks each
 // count 100 MB towards this limit, but actually consume 128 MB)
-func getMaxRamForChunks() int64 {
+func getMaxRamForChunks(memoryLimitGB float64) int64 {
+
+	// a hard limit, from the command line, takes precedence over everything else
+	if memoryLimitGB > 0 {
+		return int64(memoryLimitGB * 1024 * 1024 * 1024)
+	}
 
 	// return the user-specified override value, if any
 	envVar := common.EEnvironmentVariable.BufferGB()
@@ -423,25 +455,19 @@ func (ja *jobsAdmin) chunkProcessor(workerID int) {
 
 	for {
 		// We check for scalebacks first to shrink goroutine pool
-		// Then, we check chunks: normal & low priority
+		// Then, we check chunks, giving each priority its share of the workers
 		select {
 		case <-ja.poolSizingChannels.scalebackRequestCh:
 			return
 		default:
-			select {
-			case chunkFunc := <-ja.xferChannels.normalChunckCh:
+			if chunkFunc, ok := ja.nextChunkFunc(); ok {
 				chunkFunc(workerID)
-			default:
-				select {
-				case chunkFunc := <-ja.xferChannels.lowChunkCh:
-					chunkFunc(workerID)
-				default:
-					time.Sleep(100 * time.Millisecond) // Sleep before looping around
-					// TODO: Question: In order to safely support high goroutine counts,
-					// do we need to review sleep duration, or find an approach that does not require waking every x milliseconds
-					// For now, duration has been increased substantially from the previous 1 ms, to reduce cost of
-					// the wake-ups.
-				}
+			} else {
+				time.Sleep(100 * time.Millisecond) // Sleep before looping around
+				// TODO: Question: In order to safely support high goroutine counts,
+				// do we need to review sleep duration, or find an approach that does not require waking every x milliseconds
+				// For now, duration has been increased substantially from the previous 1 ms, to reduce cost of
+				// the wake-ups.
 			}
 		}
 	}
@@ -468,18 +494,43 @@ func (ja *jobsAdmin) transferProcessor(workerID int) {
 
 	for {
 		// No scaleback check here, because this routine runs only in a small number of goroutines, so no need to kill them off
-		select {
-		case jptm := <-ja.xferChannels.normalTransferCh:
+		if jptm, ok := ja.nextTransfer(); ok {
 			startTransfer(jptm)
+		} else {
+			time.Sleep(10 * time.Millisecond) // Sleep before looping around
+		}
+	}
+}
+
+// nextPriorities returns the order in which to look for work at each priority, this time round.
+// The turns are shared by all the workers, so that, between them, each priority gets its share
+func (ja *jobsAdmin) nextPriorities() []common.JobPriority {
+	turn := atomic.AddUint32(&ja.atomicPriorityTurn, 1)
+	return ja.priorityRotation[turn%uint32(len(ja.priorityRotation))]
+}
+
+// nextChunkFunc takes the next chunk func to run, if any are waiting
+func (ja *jobsAdmin) nextChunkFunc() (chunkFunc, bool) {
+	for _, priority := range ja.nextPriorities() {
+		select {
+		case chunkFunc := <-ja.xferChannels.chunkChannel(priority):
+			return chunkFunc, true
+		default:
+		}
+	}
+	return nil, false
+}
+
+// nextTransfer takes the next transfer to start, if any are waiting
+func (ja *jobsAdmin) nextTransfer() (IJobPartTransferMgr, bool) {
+	for _, priority := range ja.nextPriorities() {
+		select {
+		case jptm := <-ja.xferChannels.transferChannel(priority):
+			return jptm, true
 		default:
-			select {
-			case jptm := <-ja.xferChannels.lowTransferCh:
-				startTransfer(jptm)
-			default:
-				time.Sleep(10 * time.Millisecond) // Sleep before looping around
-			}
 		}
 	}
+	return nil, false
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -491,7 +542,11 @@ type jobsAdmin struct {
 	atomicBytesTransferredWhileTuning  int64
 	atomicTuningEndSeconds             int64
 	atomicCurrentMainPoolSize          int32 // align 64 bit integers for 32 bit arch
+	atomicPriorityTurn                 uint32
 	concurrency                        ConcurrencySettings
+	retrySettings                      RetrySettings
+	stallWatchdog                      *stallWatchdog
+	planCheckpointer                   *planCheckpointer
 	logger                             common.ILoggerCloser
 	jobIDToJobMgr                      jobIDToJobMgr // Thread-safe map from each JobID to its JobInfo
 	// Other global state can be stored in more fields here...
@@ -501,34 +556,62 @@ type jobsAdmin struct {
 	xferChannels                XferChannels
 	poolSizingChannels          poolSizingChannels
 	appCtx                      context.Context
-	pacer                       pacerAdmin
+	pacer                       *adjustablePacer
+	opsLimiter                  *opsRateLimiter
 	slicePool                   common.ByteSlicePooler
 	cacheLimiter                common.CacheLimiter
+	isMemoryHardLimited         bool // whether the cacheLimiter's limit was set by the user, as a hard limit
 	fileCountLimiter            common.CacheLimiter
 	workaroundJobLoggingChannel chan struct {
 		string
 		pipeline.LogLevel
 	}
 	concurrencyTuner        ConcurrencyTuner
-	commandLineMbpsCap      float64
 	provideBenchmarkResults bool
 	cpuMonitor              common.CPUMonitor
+	priorityRotation        priorityRotation
+	heldJobs                sync.Map // the jobs that this process is running, or holding in the queue, whose heartbeats it keeps
 }
 
 type CoordinatorChannels struct {
 	partsChannel     chan<- IJobPartMgr         // Write Only
+	highTransferCh   chan<- IJobPartTransferMgr // Write-only
 	normalTransferCh chan<- IJobPartTransferMgr // Write-only
 	lowTransferCh    chan<- IJobPartTransferMgr // Write-only
 }
 
 type XferChannels struct {
 	partsChannel     <-chan IJobPartMgr         // Read only
+	highTransferCh   <-chan IJobPartTransferMgr // Read-only
 	normalTransferCh <-chan IJobPartTransferMgr // Read-only
 	lowTransferCh    <-chan IJobPartTransferMgr // Read-only
+	highChunkCh      chan chunkFunc             // Read-write
 	normalChunckCh   chan chunkFunc             // Read-write
 	lowChunkCh       chan chunkFunc             // Read-write
 }
 
+func (c XferChannels) transferChannel(priority common.JobPriority) <-chan IJobPartTransferMgr {
+	switch priority {
+	case common.EJobPriority.High():
+		return c.highTransferCh
+	case common.EJobPriority.Low():
+		return c.lowTransferCh
+	default:
+		return c.normalTransferCh
+	}
+}
+
+func (c XferChannels) chunkChannel(priority common.JobPriority) chan chunkFunc {
+	switch priority {
+	case common.EJobPriority.High():
+		return c.highChunkCh
+	case common.EJobPriority.Low():
+		return c.lowChunkCh
+	default:
+		return c.normalChunckCh
+	}
+}
+
 type poolSizingChannels struct {
 	entryNotificationCh chan struct{}
 	exitNotificationCh  chan struct{}
@@ -580,6 +663,8 @@ func (ja *jobsAdmin) JobMgrEnsureExists(jobID common.JobID,
 
 func (ja *jobsAdmin) ScheduleTransfer(priority common.JobPriority, jptm IJobPartTransferMgr) {
 	switch priority { // priority determines which channel handles the job part's transfers
+	case common.EJobPriority.High():
+		ja.coordinatorChannels.highTransferCh <- jptm
 	case common.EJobPriority.Normal():
 		//jptm.SetChunkChannel(ja.xferChannels.normalChunckCh)
 		ja.coordinatorChannels.normalTransferCh <- jptm
@@ -593,6 +678,8 @@ func (ja *jobsAdmin) ScheduleTransfer(priority common.JobPriority, jptm IJobPart
 
 func (ja *jobsAdmin) ScheduleChunk(priority common.JobPriority, chunkFunc chunkFunc) {
 	switch priority { // priority determines which channel handles the job part's transfers
+	case common.EJobPriority.High():
+		ja.xferChannels.highChunkCh <- chunkFunc
 	case common.EJobPriority.Normal():
 		ja.xferChannels.normalChunckCh <- chunkFunc
 	case common.EJobPriority.Low():
@@ -622,6 +709,7 @@ func (ja *jobsAdmin) ResurrectJob(jobId common.JobID, sourceSAS string, destinat
 	// Search the existing plan files for the PartPlans for the given jobId
 	// only the files which have JobId has prefix and DataSchemaVersion as Suffix
 	// are include in the result
+	ja.removeAbandonedPlanFiles()
 	files := func(prefix, ext string) []os.FileInfo {
 		var files []os.FileInfo
 		filepath.Walk(ja.planDir, func(path string, fileInfo os.FileInfo, _ error) error {
@@ -644,6 +732,10 @@ func (ja *jobsAdmin) ResurrectJob(jobId common.JobID, sourceSAS string, destinat
 		if err != nil {
 			continue
 		}
+		if err = planFile.Validate(); err != nil {
+			ja.setAsideDamagedPlanFile(planFile, err)
+			continue
+		}
 		mmf := planFile.Map()
 		jm := ja.JobMgrEnsureExists(jobID, mmf.Plan().LogLevel, "")
 		jm.AddJobPart(partNum, planFile, mmf, sourceSAS, destinationSAS, false)
@@ -654,6 +746,7 @@ func (ja *jobsAdmin) ResurrectJob(jobId common.JobID, sourceSAS string, destinat
 // reconstructTheExistingJobParts reconstructs the in memory JobPartPlanInfo for existing memory map JobFile
 func (ja *jobsAdmin) ResurrectJobParts() {
 	// Get all the Job part plan files in the plan directory
+	ja.removeAbandonedPlanFiles()
 	files := func(ext string) []os.FileInfo {
 		var files []os.FileInfo
 		filepath.Walk(ja.planDir, func(path string, fileInfo os.FileInfo, _ error) error {
@@ -672,6 +765,10 @@ func (ja *jobsAdmin) ResurrectJobParts() {
 		if err != nil {
 			continue
 		}
+		if err = planFile.Validate(); err != nil {
+			ja.setAsideDamagedPlanFile(planFile, err)
+			continue
+		}
 		mmf := planFile.Map()
 		//todo : call the compute transfer function here for each job.
 		jm := ja.JobMgrEnsureExists(jobID, mmf.Plan().LogLevel, "")
@@ -679,6 +776,44 @@ func (ja *jobsAdmin) ResurrectJobParts() {
 	}
 }
 
+// abandonedPlanFileAge is how old a temporary plan file must be before we treat it as abandoned.
+// Plan files only take a moment to write, so anything this old can't still be in progress in another AzCopy process
+const abandonedPlanFileAge = time.Hour
+
+// removeAbandonedPlanFiles deletes the temporary files left behind when a process was killed, or the machine crashed,
+// while a plan file was being written. The job part they were for was never ordered, so there is nothing in them to resume
+func (ja *jobsAdmin) removeAbandonedPlanFiles() {
+	ext := fmt.Sprintf(".steV%d%s", DataSchemaVersion, planFileTempSuffix)
+	filepath.Walk(ja.planDir, func(path string, fileInfo os.FileInfo, _ error) error {
+		if fileInfo != nil && !fileInfo.IsDir() && strings.HasSuffix(fileInfo.Name(), ext) &&
+			time.Since(fileInfo.ModTime()) > abandonedPlanFileAge {
+			if err := os.Remove(path); err == nil {
+				ja.Log(pipeline.LogInfo, fmt.Sprintf("removed abandoned plan file %s", fileInfo.Name()))
+			}
+		}
+		return nil
+	})
+}
+
+// setAsideDamagedPlanFile renames a plan file that failed validation, so that it no longer stops the listing
+// (and resuming) of other jobs. Without this, mapping the file would panic, every time any job was resurrected
+func (ja *jobsAdmin) setAsideDamagedPlanFile(planFile JobPartPlanFileName, validationErr error) {
+	if _, ok := validationErr.(planDecryptionError); ok {
+		// the file may well be fine, and readable with the right key, so it's only skipped
+		msg := fmt.Sprintf("The plan file %s is skipped, so that part of its job cannot be resumed: %v", planFile, validationErr)
+		ja.Log(pipeline.LogWarning, msg)
+		common.GetLifecycleMgr().Info(msg)
+		return
+	}
+	planPath := planFile.GetJobPartPlanPath()
+	msg := fmt.Sprintf("The plan file %s is damaged, so that part of its job cannot be resumed: %v", planFile, validationErr)
+	if err := os.Rename(planPath, planPath+planFileDamagedSuffix); err == nil {
+		msg += fmt.Sprintf(". The file has been renamed to %s", string(planFile)+planFileDamagedSuffix)
+	}
+	ja.Log(pipeline.LogWarning, msg)
+	common.GetLifecycleMgr().Info(msg)
+}
+
 // TODO: I think something is wrong here: I think delete and cleanup should be merged together.
 // DeleteJobInfo api deletes an entry of given JobId the JobsInfo
 // TODO: add the clean up logic for all Jobparts.
@@ -723,6 +858,13 @@ func (ja *jobsAdmin) Log(level pipeline.LogLevel, msg string) { ja.logger.Log(le
 func (ja *jobsAdmin) Panic(err error)                         { ja.logger.Panic(err) }
 func (ja *jobsAdmin) CloseLog()                               { ja.logger.CloseLog() }
 
+func (ja *jobsAdmin) flushJobLogs() {
+	ja.jobIDToJobMgr.Iterate(false, func(k common.JobID, v IJobMgr) {
+		v.Log(pipeline.LogInfo, "AzCopy is exiting")
+		v.FlushLogs()
+	})
+}
+
 func (ja *jobsAdmin) CurrentMainPoolSize() int {
 	return int(atomic.LoadInt32(&ja.atomicCurrentMainPoolSize))
 }
@@ -0,0 +1,121 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Outside a job's active hours, its requests are held back before they are sent, so the job's transfers just wait
+// (keeping their state, and the chunks they have already read) until the window opens again.
+// Requests that are already in flight when the window closes are allowed to finish.
+
+// the longest we sleep before looking at the clock again, in case it is changed while we wait
+const activeHoursMaxSleep = time.Minute
+
+// activeHoursGate holds back the requests of one job while it is outside its active hours
+type activeHoursGate struct {
+	mu      sync.Mutex
+	window  common.ActiveHours
+	waiting bool // whether requests are being held back, so that we only say so once
+	now     func() time.Time
+	notify  func(msg string)
+}
+
+func newActiveHoursGate(notify func(msg string)) *activeHoursGate {
+	return &activeHoursGate{now: time.Now, notify: notify}
+}
+
+// setWindow is called as each part of the job is scheduled. All the parts have the same window
+func (g *activeHoursGate) setWindow(window common.ActiveHours) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.window = window
+}
+
+// wait returns once the job is inside its active hours, or when ctx is done
+func (g *activeHoursGate) wait(ctx context.Context) error {
+	for {
+		delay := g.delay()
+		if delay == 0 {
+			return nil
+		}
+		if delay > activeHoursMaxSleep {
+			delay = activeHoursMaxSleep
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// delay returns how long it is until the window opens, which is zero if it's open now
+func (g *activeHoursGate) delay() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	next := g.window.NextStart(now)
+	if !next.After(now) {
+		if g.waiting {
+			g.waiting = false
+			g.notify(fmt.Sprintf("The active hours %s have begun. Transfers are carrying on", g.window))
+		}
+		return 0
+	}
+	if !g.waiting {
+		g.waiting = true
+		g.notify(fmt.Sprintf("Outside the active hours %s. Transfers will wait until %s",
+			g.window, next.Format("15:04 on Jan 2")))
+	}
+	return next.Sub(now)
+}
+
+// withActiveHours returns a context whose requests are held back, by the activeHoursPolicy, outside the gate's window
+func withActiveHours(ctx context.Context, g *activeHoursGate) context.Context {
+	return context.WithValue(ctx, activeHoursContextKey, g)
+}
+
+var activeHoursContextKey = contextKey{"activeHours"}
+
+// newActiveHoursPolicyFactory holds back requests outside the active hours.
+// It goes before the retry policy in the pipeline, so that time spent waiting doesn't count against any try
+func newActiveHoursPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			if g, ok := ctx.Value(activeHoursContextKey).(*activeHoursGate); ok {
+				if err := g.wait(ctx); err != nil {
+					return nil, err
+				}
+			}
+			return next.Do(ctx, request)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Blobs in the archive tier can't be read until they have been rehydrated to an online tier, which takes hours.
+// When a job is asked to rehydrate them, the transfer of an archived blob starts its rehydration and then, rather
+// than holding on to a worker while it waits, puts itself back in the queue to be looked at again later.
+
+// how long a transfer waits before looking again at whether its source is back online
+var rehydrationPollInterval = 5 * time.Minute
+
+// sourceMayBeArchived says whether the source of a transfer must be checked before it's read. When the source's tier
+// was recorded as it was listed, we only need to check the ones that were archived then
+func sourceMayBeArchived(info TransferInfo, fromTo common.FromTo) bool {
+	return info.ArchivedSource == common.EArchivedSourceOption.Rehydrate() &&
+		fromTo.From() == common.ELocation.Blob() &&
+		info.SourceSize > 0 && // empty blobs are never read, so needn't be online
+		(info.S2SSrcBlobTier == azblob.AccessTierArchive || info.S2SSrcBlobTier == azblob.AccessTierNone)
+}
+
+// rehydrationNeeded looks at the properties of an archived blob. It's online unless it's in the archive tier,
+// and only needs its rehydration to be started if that hasn't happened already
+func rehydrationNeeded(accessTier string, archiveStatus string) (online bool, mustStart bool) {
+	if accessTier != string(azblob.AccessTierArchive) {
+		return true, false
+	}
+	return false, archiveStatus == ""
+}
+
+// archivedSourceIsOnline returns true if the source of the transfer can be read now. If it's in the archive tier, its
+// rehydration is started (unless that has already happened) and the transfer is put back in the queue, after a while.
+// So if this returns false without an error, the caller must just return, without reporting the transfer done
+func archivedSourceIsOnline(jptm IJobPartTransferMgr, p pipeline.Pipeline) (bool, error) {
+	info := jptm.Info()
+	if !sourceMayBeArchived(info, jptm.FromTo()) {
+		return true, nil
+	}
+
+	sourceURL, err := url.Parse(info.Source)
+	if err != nil {
+		return false, err
+	}
+	// the archive status is only returned by newer service versions
+	ctx := context.WithValue(jptm.Context(), ServiceAPIVersionOverride, azblob.ServiceVersion)
+	props, err := azblob.NewBlobURL(*sourceURL, p).GetProperties(ctx, azblob.BlobAccessConditions{})
+	if err != nil {
+		return false, err
+	}
+
+	online, mustStart := rehydrationNeeded(props.AccessTier(), props.ArchiveStatus())
+	if online {
+		return true, nil
+	}
+	if mustStart {
+		if err = startRehydration(ctx, p, *sourceURL, info.RehydratePriority); err != nil {
+			return false, err
+		}
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo,
+			fmt.Sprintf("Source is in the archive tier, so its rehydration to the Hot tier has been started, with %s priority", info.RehydratePriority))
+	}
+
+	rescheduleAfter(jptm, rehydrationPollInterval)
+	return false, nil
+}
+
+// startRehydration moves an archived blob to the Hot tier. The version of azblob that we use can't give a
+// rehydrate priority when it sets a blob's tier, so the request is made here instead
+func startRehydration(ctx context.Context, p pipeline.Pipeline, blobURL url.URL, priority common.RehydratePriority) error {
+	query := blobURL.Query()
+	query.Set("comp", "tier")
+	blobURL.RawQuery = query.Encode()
+
+	request, err := pipeline.NewRequest(http.MethodPut, blobURL, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("x-ms-access-tier", string(azblob.AccessTierHot))
+	request.Header.Set("x-ms-rehydrate-priority", string(priority.ToRehydratePriorityType()))
+
+	response, err := p.Do(ctx, nil, request)
+	if err != nil {
+		return err
+	}
+	r := response.Response()
+	_, _ = io.Copy(ioutil.Discard, r.Body)
+	_ = r.Body.Close()
+	if r.StatusCode != http.StatusOK && r.StatusCode != http.StatusAccepted {
+		// leave out the query, since it may hold a SAS
+		return fmt.Errorf("rehydrating %s://%s%s failed: %s", blobURL.Scheme, blobURL.Host, blobURL.Path, r.Status)
+	}
+	return nil
+}
+
+// rescheduleAfter puts the transfer back in the queue once the delay has passed, or straight away if the job is
+// cancelled, so that the cancellation is noticed without waiting
+func rescheduleAfter(jptm IJobPartTransferMgr, delay time.Duration) {
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-jptm.Context().Done():
+		}
+		jptm.RescheduleTransfer()
+	}()
+}
@@ -0,0 +1,80 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"hash"
+	"math"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// backgroundHasher hashes the chunks of a file, in order, on a goroutine of its own, so that a slow hash such as
+// SHA-256 holds up neither the reading of the file nor the sending of the chunks that have already been hashed.
+// A chunk mustn't be sent until it has been hashed, since sending it can release its buffer, so the func of each
+// chunk waits for that
+type backgroundHasher struct {
+	h       hash.Hash
+	pending chan backgroundHashItem
+	sum     chan []byte
+}
+
+type backgroundHashItem struct {
+	reader common.SingleChunkReader
+	hashed chan struct{}
+}
+
+func newBackgroundHasher(h hash.Hash, numChunks uint32) *backgroundHasher {
+	b := &backgroundHasher{
+		h:       h,
+		pending: make(chan backgroundHashItem, int(math.Min(float64(numChunks), 1000))),
+		sum:     make(chan []byte, 1),
+	}
+	go b.run()
+	return b
+}
+
+func (b *backgroundHasher) run() {
+	for item := range b.pending {
+		item.reader.WriteBufferTo(b.h)
+		close(item.hashed)
+	}
+	b.sum <- b.h.Sum(nil)
+}
+
+// add queues the prefetched chunk for hashing, and returns a version of its func that only runs once it's been hashed
+func (b *backgroundHasher) add(jptm IJobPartTransferMgr, reader common.SingleChunkReader, cf chunkFunc) chunkFunc {
+	hashed := make(chan struct{})
+	b.pending <- backgroundHashItem{reader: reader, hashed: hashed}
+	return func(workerId int) {
+		select {
+		case <-hashed:
+		case <-jptm.Context().Done(): // the func will see that the transfer has been cancelled
+		}
+		cf(workerId)
+	}
+}
+
+// finish waits for the chunks that have been added to be hashed, and returns the hash of them all
+func (b *backgroundHasher) finish() []byte {
+	close(b.pending)
+	return <-b.sum
+}
@@ -0,0 +1,107 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The version of azblob that we use can't set index tags when it creates a blob, so the request that creates it gets
+// them from its context instead. Setting them in the same request means that a blob never exists without its tags.
+
+// withBlobTags returns a context whose requests set the given index tags on the blobs they create
+func withBlobTags(ctx context.Context, tags common.BlobTags) context.Context {
+	if len(tags) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, blobTagsContextKey, tags.ToString())
+}
+
+var blobTagsContextKey = contextKey{"blobTags"}
+
+// newBlobTagsPolicyFactory adds the x-ms-tags header to requests whose context has tags.
+// It goes after the version policy, since the header needs a newer service version than we use by default
+func newBlobTagsPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			if tags, ok := ctx.Value(blobTagsContextKey).(string); ok {
+				request.Header.Set("x-ms-tags", tags)
+				request.Header.Set("x-ms-version", azblob.ServiceVersion)
+			}
+			return next.Do(ctx, request)
+		}
+	})
+}
+
+// getBlobTags gets the index tags of a blob. azblob has no method for that, in the version we use
+func getBlobTags(ctx context.Context, p pipeline.Pipeline, blobURL url.URL) (common.BlobTags, error) {
+	query := blobURL.Query()
+	query.Set("comp", "tags")
+	blobURL.RawQuery = query.Encode()
+
+	request, err := pipeline.NewRequest(http.MethodGet, blobURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := p.Do(context.WithValue(ctx, ServiceAPIVersionOverride, azblob.ServiceVersion), nil, request)
+	if err != nil {
+		return nil, err
+	}
+	r := response.Response()
+	body, err := ioutil.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		// leave out the query, since it may hold a SAS
+		return nil, fmt.Errorf("getting the index tags of %s://%s%s failed: %s", blobURL.Scheme, blobURL.Host, blobURL.Path, r.Status)
+	}
+
+	var tags azblob.BlobTags
+	if err = xml.Unmarshal(body, &tags); err != nil {
+		return nil, err
+	}
+	result := common.BlobTags{}
+	for _, tag := range tags.BlobTagSet {
+		result[tag.Key] = tag.Value
+	}
+	return result, nil
+}
+
+// blobTagsToApply gives the index tags for the destination blob: the ones the user gave, or else the source's,
+// when they were copied
+func blobTagsToApply(info TransferInfo, props *SrcProperties) common.BlobTags {
+	if len(info.BlobTags) > 0 {
+		return info.BlobTags
+	}
+	return props.SrcBlobTags
+}
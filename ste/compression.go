@@ -0,0 +1,116 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// alreadyCompressedExtensions are those of files whose content is compressed already, so gzipping them again would
+// cost time and save (almost) nothing
+var alreadyCompressedExtensions = map[string]bool{
+	".gz": true, ".tgz": true, ".gzip": true, ".zz": true, ".bz2": true, ".xz": true, ".zst": true, ".lz4": true,
+	".zip": true, ".7z": true, ".rar": true, ".br": true, ".jar": true, ".apk": true,
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".ods": true, ".odp": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".heic": true, ".avif": true,
+	".mp3": true, ".aac": true, ".m4a": true, ".ogg": true, ".opus": true, ".flac": true,
+	".mp4": true, ".m4v": true, ".mkv": true, ".mov": true, ".avi": true, ".webm": true,
+	".woff": true, ".woff2": true,
+}
+
+// isCompressible says whether a file is worth gzipping, judging by its extension
+func isCompressible(path string) bool {
+	return !alreadyCompressedExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// compressSourceIfWanted gzips the source of an upload with --compress into a temporary file, which is then uploaded
+// in its place. It's done before the transfer starts, since the senders must know the size of what they send. The
+// copy is kept only when it's smaller, and is removed when the transfer is done
+func compressSourceIfWanted(jptm IJobPartTransferMgr) error {
+	info := jptm.Info()
+	if !info.CompressOnUpload || info.EntityType != common.EEntityType.File() || info.SourceSize == 0 || !isCompressible(info.Source) {
+		return nil
+	}
+	if jptm.FromTo() == common.EFromTo.LocalBlob() && intendedBlobType(jptm) == azblob.BlobPageBlob {
+		return nil // the size of a page blob must be a multiple of 512 bytes, and a gzipped disk can't be used as one anyway
+	}
+
+	compressedPath, compressedSize, err := gzipToTempFile(info.Source)
+	if err != nil {
+		return fmt.Errorf("couldn't compress the file: %w", err)
+	}
+	if compressedSize >= info.SourceSize {
+		_ = os.Remove(compressedPath)
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "not compressed, since gzip doesn't make it smaller")
+		return nil
+	}
+
+	jptm.SetCompressedSource(compressedPath, compressedSize)
+	jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, fmt.Sprintf("compressed from %d to %d bytes", info.SourceSize, compressedSize))
+	return nil
+}
+
+func gzipToTempFile(path string) (compressedPath string, compressedSize int64, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer src.Close()
+
+	dst, err := ioutil.TempFile("", "azcopy-compress-")
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(dst.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return "", 0, err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return "", 0, err
+	}
+	if err = dst.Close(); err != nil {
+		return "", 0, err
+	}
+
+	fi, err := os.Stat(dst.Name())
+	if err != nil {
+		return "", 0, err
+	}
+	return dst.Name(), fi.Size(), nil
+}
@@ -179,9 +179,9 @@ func getMainPoolSize(numOfCPUs int, requestAutoTune bool) (initial int, max *Con
 
 	if common.GetLifecycleMgr().GetEnvironmentVariable(envVar) == "AUTO" {
 		// Allow user to force auto-tuning from the env var, even when not in benchmark mode
-		// Might be handy in some S2S cases, where we know that release 10.2.1 was using too few goroutines
-		// This feature will probably remain undocumented for at least one release cycle, while we consider
-		// whether to do more in this regard (e.g. make it the default behaviour)
+		// Might be handy in some S2S cases, where we know that release 10.2.1 was using too few goroutines.
+		// Outside of benchmark mode, the tuner also keeps backing off (and recovering) in response to
+		// 503s and timeouts for the rest of the job, so users don't need to hand-tune the value per environment
 		requestAutoTune = true
 	} else if c := tryNewConfiguredInt(envVar); c != nil {
 		if requestAutoTune {
@@ -22,6 +22,7 @@ package ste
 
 import (
 	"github.com/Azure/azure-storage-azcopy/common"
+	"math"
 	"sync"
 	"sync/atomic"
 )
@@ -39,6 +40,9 @@ type ConcurrencyTuner interface {
 
 	// recordRetry informs the concurrencyTuner that a retry has happened
 	recordRetry()
+
+	// recordTimeout informs the concurrencyTuner that a request timed out, or failed with a network error
+	recordTimeout()
 }
 
 type nullConcurrencyTuner struct {
@@ -61,9 +65,14 @@ func (n *nullConcurrencyTuner) recordRetry() {
 	// noop
 }
 
+func (n *nullConcurrencyTuner) recordTimeout() {
+	// noop
+}
+
 type autoConcurrencyTuner struct {
-	atomicRetryCount int64
-	observations     chan struct {
+	atomicRetryCount   int64
+	atomicTimeoutCount int64
+	observations       chan struct {
 		mbps      int
 		isHighCpu bool
 	}
@@ -126,6 +135,28 @@ func (t *autoConcurrencyTuner) recordRetry() {
 	atomic.AddInt64(&t.atomicRetryCount, 1)
 }
 
+func (t *autoConcurrencyTuner) recordTimeout() {
+	atomic.AddInt64(&t.atomicTimeoutCount, 1)
+}
+
+// takeErrorCounts returns the number of retries (503s) and timeouts seen since the last call
+func (t *autoConcurrencyTuner) takeErrorCounts() (retries int64, timeouts int64) {
+	return atomic.SwapInt64(&t.atomicRetryCount, 0), atomic.SwapInt64(&t.atomicTimeoutCount, 0)
+}
+
+// tooManyErrors says whether the number of 503s and timeouts seen in one measuring interval is high enough,
+// relative to the number of connections, that we should take it as a sign that we are pushing the service (or the network) too hard.
+// A handful of errors is normal, even at a sensible concurrency, so those alone are not enough
+func tooManyErrors(errorCount int64, concurrency float32) bool {
+	const minErrorsToBackOff = 3
+	const errorsPerConnectionToBackOff = 0.1
+	threshold := int64(concurrency * errorsPerConnectionToBackOff)
+	if threshold < minErrorsToBackOff {
+		threshold = minErrorsToBackOff
+	}
+	return errorCount >= threshold
+}
+
 const (
 	concurrencyReasonNone          = ""
 	concurrencyReasonTunerDisabled = "tuner disabled" // used as the final (non-finished) state for null tuner
@@ -135,6 +166,8 @@ const (
 	concurrencyReasonHitMax        = "hit max concurrency limit"
 	concurrencyReasonHighCpu       = "at optimum, but may be limited by CPU"
 	concurrencyReasonAtOptimum     = "at optimum"
+	concurrencyReasonThrottled     = "backing off due to server busy errors or timeouts"
+	concurrencyReasonRecovering    = "recovering after server busy errors or timeouts"
 	concurrencyReasonFinished      = "tuning already finished (or never started)"
 )
 
@@ -154,6 +187,7 @@ func (t *autoConcurrencyTuner) worker() {
 	sawHighMultiGbps := false
 	probeHigherRegardless := false
 	dontBackoffRegardless := false
+	sawTooManyErrors := false
 	multiplierReductionCount := 0
 	lastReason := concurrencyReasonNone
 
@@ -189,21 +223,25 @@ func (t *autoConcurrencyTuner) worker() {
 			everSawHighCpu = true // this doesn't stop us probing higher concurrency, since sometimes that works even when CPU looks high, but it does change the way we report the result
 		}
 
+		retries, timeouts := t.takeErrorCounts()
 		if t.isBenchmarking {
 			// Be a little more aggressive if we are tuning for benchmarking purposes (as opposed to day to day use)
 
 			// If we are seeing retries (within "normal" concurrency range) then for benchmarking purposes we don't want to back off.
 			// (Since if we back off the retries might stop and then they won't be reported on as a limiting factor.)
-			sawRetry := atomic.SwapInt64(&t.atomicRetryCount, 0) > 0
+			sawRetry := retries > 0
 			dontBackoffRegardless = sawRetry && concurrency <= 256
 
 			// Workaround for variable throughput when targeting 20 Gbps account limit (concurrency around 64 didn't seem to give stable throughput in some tests)
 			// TODO: review this, and look for root cause/better solution
 			probeHigherRegardless = sawHighMultiGbps && concurrency >= 32 && concurrency < 128 && multiplier >= standardMultiplier
+		} else {
+			// In day to day use, lots of 503s or timeouts mean the increase was too aggressive, even if throughput went up
+			sawTooManyErrors = tooManyErrors(retries+timeouts, concurrency)
 		}
 
 		// decide what to do based on the measurement
-		if lastSpeed > desiredNewSpeed || probeHigherRegardless {
+		if (lastSpeed > desiredNewSpeed || probeHigherRegardless) && !sawTooManyErrors {
 			// Our concurrency change gave the hoped-for speed increase, so loop around and see if another increase will also work,
 			// unless already at max
 			if atMax {
@@ -257,9 +295,44 @@ func (t *autoConcurrencyTuner) worker() {
 	t.storeFinalState(lastReason, concurrency)
 	t.signalStability()
 
-	// now just provide an "inactive" value for ever
+	t.adaptForever(concurrency)
+}
+
+// adaptForever runs after tuning has finished. Conditions can change during a long job (e.g. other
+// clients start using the same account) so we keep watching for 503s and timeouts.  If there are too many, we back off,
+// and once they have stopped for a while we grow back, step by step, to the concurrency that tuning found.
+// When benchmarking we never do that, because the point of benchmarking is to report on the throttling, not to avoid it.
+func (t *autoConcurrencyTuner) adaptForever(tunedConcurrency float32) {
+	const backoffFactor = 0.75
+	const recoveryMultiplier = 1.2
+	const quietIntervalsBeforeRecovery = 3
+
+	concurrency := tunedConcurrency
+	quietIntervals := 0
+	_, _ = t.takeErrorCounts() // the ones from tuning have already been acted on
+
 	for {
-		_ = t.setConcurrency(concurrency, concurrencyReasonFinished)
+		reason := concurrencyReasonFinished
+		if !t.isBenchmarking {
+			retries, timeouts := t.takeErrorCounts()
+			errorCount := retries + timeouts
+			if tooManyErrors(errorCount, concurrency) && concurrency > float32(t.initialConcurrency) {
+				concurrency = float32(math.Max(float64(concurrency*backoffFactor), float64(t.initialConcurrency)))
+				reason = concurrencyReasonThrottled
+				quietIntervals = 0
+			} else if errorCount == 0 && concurrency < tunedConcurrency {
+				quietIntervals++
+				if quietIntervals >= quietIntervalsBeforeRecovery {
+					concurrency = float32(math.Min(math.Ceil(float64(concurrency*recoveryMultiplier)), float64(tunedConcurrency)))
+					reason = concurrencyReasonRecovering
+					quietIntervals = 0
+				}
+			} else {
+				quietIntervals = 0
+			}
+		}
+
+		_ = t.setConcurrency(concurrency, reason)
 		_, _ = t.getCurrentSpeed() // read from the channel
 		t.signalStability()        // in case anyone new has "subscribed"
 	}
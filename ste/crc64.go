@@ -0,0 +1,162 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc64"
+	"io"
+	"net/http"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The Blob service can check a CRC64 of the data of each block, page or append that it's sent, and can return the CRC64
+// of each range that's read from it. The version of azblob that we use exposes neither, so, as with index tags, the
+// request gets its CRC64 (or its wish for one) from its context, and a policy puts that into its headers.
+// The CRC64 of the whole file is kept in its metadata (see storedChecksums.go).
+
+// crc64Table is for the polynomial that the Storage service uses
+var crc64Table = crc64.MakeTable(0x9A6C9329AC4BC9B5)
+
+// maxRangeCrc64Size is the largest range that the service will return a CRC64 for
+const maxRangeCrc64Size = 4 * 1024 * 1024
+
+func newCrc64Hasher() hash.Hash64 {
+	return crc64.New(crc64Table)
+}
+
+// encodeCrc64 formats a CRC64 in the way the service does: its bytes in little-endian order, base64-encoded
+func encodeCrc64(crc uint64) string {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, crc)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+var errRangeCrc64Mismatch = errors.New("the CRC64 of the data, as we received it, did not match the CRC64 that the Blob service computed for it. " +
+	"This means that the data was corrupted in transit")
+
+var errCrc64Mismatch = errors.New("the CRC64 of the data, as we received it, did not match the value stored in the metadata of the blob. " +
+	"This means that either there is a data integrity error OR the blob has been changed by a tool that didn't update its CRC64")
+
+const noCrc64Stored = "no CRC64 was stored in the metadata of this blob. So the downloaded data cannot be CRC64-validated."
+
+var errExpectedCrc64Missing = errors.New(noCrc64Stored + " This application is currently configured to treat missing CRC64 checksums as errors")
+
+// withUploadCrc64 returns a context whose request sends the CRC64 of the given part of the chunk, for the service to
+// check, if the job asks for that. The CRC64 is computed from the chunk's buffer, rather than by reading the chunk,
+// since reading it to the end would release the buffer
+func withUploadCrc64(jptm IJobPartTransferMgr, ctx context.Context, reader common.SingleChunkReader, offset int64, length int64) context.Context {
+	if !jptm.ShouldPutCrc64() || length == 0 {
+		return ctx
+	}
+	h := &rangeHasher{Hash64: newCrc64Hasher(), skip: offset, remaining: length}
+	reader.WriteBufferTo(h)
+	return context.WithValue(ctx, uploadCrc64ContextKey, encodeCrc64(h.Sum64()))
+}
+
+var uploadCrc64ContextKey = contextKey{"uploadCrc64"}
+
+// withDownloadCrc64 returns a context whose request asks the service for the CRC64 of the range it reads, and checks
+// the data against it, if the job asks for that. The service only computes it for ranges of up to 4 MiB
+func withDownloadCrc64(jptm IJobPartTransferMgr, ctx context.Context, length int64) context.Context {
+	if jptm.Crc64ValidationOption() == common.EHashValidationOption.NoCheck() || length == 0 || length > maxRangeCrc64Size {
+		return ctx
+	}
+	return context.WithValue(ctx, downloadCrc64ContextKey, true)
+}
+
+var downloadCrc64ContextKey = contextKey{"downloadCrc64"}
+
+// newTransactionalCrc64PolicyFactory adds the CRC64 headers to requests whose context has them, and wraps the bodies of
+// the responses that have a CRC64 so that they fail if the data doesn't match it.
+// Like the blob tags policy, it goes after the version policy, since the headers need a newer service version than we use by default
+func newTransactionalCrc64PolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			if crc, ok := ctx.Value(uploadCrc64ContextKey).(string); ok {
+				request.Header.Set("x-ms-content-crc64", crc)
+				request.Header.Set("x-ms-version", azblob.ServiceVersion)
+			}
+			wantRangeCrc64 := ctx.Value(downloadCrc64ContextKey) != nil
+			if wantRangeCrc64 {
+				request.Header.Set("x-ms-range-get-content-crc64", "true")
+				request.Header.Set("x-ms-version", azblob.ServiceVersion)
+			}
+
+			response, err := next.Do(ctx, request)
+			if wantRangeCrc64 && err == nil && response != nil {
+				r := response.Response()
+				if crc := r.Header.Get("x-ms-content-crc64"); crc != "" && (r.StatusCode == http.StatusOK || r.StatusCode == http.StatusPartialContent) {
+					r.Body = &crc64CheckingBody{ReadCloser: r.Body, hasher: newCrc64Hasher(), expected: crc}
+				}
+			}
+			return response, err
+		}
+	})
+}
+
+// crc64CheckingBody fails the read that reaches the end of a response body, if the body didn't match its CRC64.
+// If the body is read again, after a failure, that's a new response with its own CRC64, so each is checked separately
+type crc64CheckingBody struct {
+	io.ReadCloser
+	hasher   hash.Hash64
+	expected string
+}
+
+func (b *crc64CheckingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	_, _ = b.hasher.Write(p[:n])
+	if err == io.EOF && encodeCrc64(b.hasher.Sum64()) != b.expected {
+		return n, errRangeCrc64Mismatch
+	}
+	return n, err
+}
+
+// rangeHasher hashes only the given range of the bytes written to it
+type rangeHasher struct {
+	hash.Hash64
+	skip      int64
+	remaining int64
+}
+
+func (h *rangeHasher) Write(p []byte) (int, error) {
+	n := len(p)
+	if h.skip >= int64(len(p)) {
+		h.skip -= int64(len(p))
+		return n, nil
+	}
+	p = p[h.skip:]
+	h.skip = 0
+	if int64(len(p)) > h.remaining {
+		p = p[:h.remaining]
+	}
+	h.remaining -= int64(len(p))
+	_, _ = h.Hash64.Write(p)
+	return n, nil
+}
@@ -0,0 +1,52 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// undeleteSourceIfDeleted restores a source blob that was soft-deleted when it was listed, since it can't be read until
+// it's undeleted. Undeleting a blob restores its soft-deleted snapshots too, so it's the blob itself that is undeleted,
+// even if the source is one of its snapshots. It does nothing to a blob that has been undeleted already
+func undeleteSourceIfDeleted(jptm IJobPartTransferMgr, p pipeline.Pipeline) error {
+	info := jptm.Info()
+	if !info.SrcBlobDeleted {
+		return nil
+	}
+
+	sourceURL, err := url.Parse(info.Source)
+	if err != nil {
+		return err
+	}
+	blobURLParts := azblob.NewBlobURLParts(*sourceURL)
+	blobURLParts.Snapshot = ""
+	blobURLParts.VersionID = ""
+	if _, err = azblob.NewBlobURL(blobURLParts.URL(), p).Undelete(jptm.Context()); err != nil {
+		return err
+	}
+
+	jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "Source was soft-deleted, so it has been undeleted")
+	return nil
+}
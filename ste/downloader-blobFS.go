@@ -30,18 +30,51 @@ import (
 	"github.com/Azure/azure-storage-azcopy/common"
 )
 
-type blobFSDownloader struct{}
+type blobFSDownloader struct {
+	jptm        IJobPartTransferMgr
+	srcPipeline pipeline.Pipeline
+}
 
 func newBlobFSDownloader() downloader {
 	return &blobFSDownloader{}
 }
 
 func (bd *blobFSDownloader) Prologue(jptm IJobPartTransferMgr, srcPipeline pipeline.Pipeline) {
-	// noop
+	bd.jptm = jptm
+	bd.srcPipeline = srcPipeline
 }
 
 func (bd *blobFSDownloader) Epilogue() {
-	//noop
+	if bd.jptm == nil {
+		return // nothing we can do
+	}
+	if bd.jptm.IsLive() && bd.jptm.Info().PreservePOSIXPermissions {
+		err := bd.preservePOSIXOwnership()
+		if err != nil {
+			bd.jptm.FailActiveDownload("Setting POSIX permissions", err)
+		}
+	}
+}
+
+// preservePOSIXOwnership gives the downloaded file the owner, group and mode of its source path
+func (bd *blobFSDownloader) preservePOSIXOwnership() error {
+	info := bd.jptm.Info()
+	u, err := url.Parse(info.Source)
+	if err != nil {
+		return err
+	}
+
+	srcFileURL := azbfs.NewDirectoryURL(*u, bd.srcPipeline).NewFileUrl()
+	acl, err := srcFileURL.GetAccessControl(bd.jptm.Context())
+	if err != nil {
+		return err
+	}
+
+	return applyPOSIXOwnership(info.Destination, POSIXOwnership{
+		Owner:       acl.XMsOwner(),
+		Group:       acl.XMsGroup(),
+		Permissions: acl.XMsPermissions(),
+	})
 }
 
 // Returns a chunk-func for ADLS gen2 downloads
@@ -0,0 +1,73 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// fileTimesMetadata records the times of a local file in blob metadata. The creation time is left out where the
+// file system doesn't give it to us
+func fileTimesMetadata(path string) (common.Metadata, error) {
+	fileInfo, err := common.OSStat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := common.Metadata{common.LastWriteTimeMetadataKey: fileInfo.ModTime().UTC().Format(time.RFC3339Nano)}
+	if creationTime, ok := fileCreationTime(fileInfo); ok {
+		metadata[common.CreationTimeMetadataKey] = creationTime.UTC().Format(time.RFC3339Nano)
+	}
+	return metadata, nil
+}
+
+// restoreFileTimes puts back the times that were recorded in the metadata of the source blob. Like the preservation of
+// the last modified time, failing to do so is logged rather than failing the transfer
+func restoreFileTimes(jptm IJobPartTransferMgr) {
+	info := jptm.Info()
+
+	if value, ok := info.SrcMetadata[common.LastWriteTimeMetadataKey]; ok {
+		lastWriteTime, err := time.Parse(time.RFC3339Nano, value)
+		if err == nil {
+			err = os.Chtimes(info.Destination, lastWriteTime, lastWriteTime)
+		}
+		if err != nil {
+			jptm.LogError(info.Destination, "Restoring last write time ", err)
+		} else {
+			jptm.Log(pipeline.LogInfo, fmt.Sprintf(" Restored last write time for %s", info.Destination))
+		}
+	}
+
+	if value, ok := info.SrcMetadata[common.CreationTimeMetadataKey]; ok {
+		creationTime, err := time.Parse(time.RFC3339Nano, value)
+		if err == nil {
+			err = setFileCreationTime(info.Destination, creationTime)
+		}
+		if err != nil {
+			jptm.LogError(info.Destination, "Restoring creation time ", err)
+		}
+	}
+}
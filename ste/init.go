@@ -27,6 +27,8 @@ import (
 	"io/ioutil"
 	"math"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -50,9 +52,10 @@ func ToFixed(num float64, precision int) float64 {
 }
 
 // MainSTE initializes the Storage Transfer Engine
-func MainSTE(concurrency ConcurrencySettings, targetRateInMegaBitsPerSec float64, azcopyJobPlanFolder, azcopyLogPathFolder string, providePerfAdvice bool) error {
+// A memoryLimitGB of zero means there is no hard limit on the RAM used for buffering data (but there is a soft one, which depends on the machine)
+func MainSTE(concurrency ConcurrencySettings, retry RetrySettings, checkpoint CheckpointInterval, targetRateInMegaBitsPerSec float64, opsPerSecond int, memoryLimitGB float64, azcopyJobPlanFolder, azcopyLogPathFolder string, providePerfAdvice bool) error {
 	// Initialize the JobsAdmin, resurrect Job plan files
-	initJobsAdmin(steCtx, concurrency, targetRateInMegaBitsPerSec, azcopyJobPlanFolder, azcopyLogPathFolder, providePerfAdvice)
+	initJobsAdmin(steCtx, concurrency, retry, checkpoint, targetRateInMegaBitsPerSec, opsPerSecond, memoryLimitGB, azcopyJobPlanFolder, azcopyLogPathFolder, providePerfAdvice)
 	// No need to read the existing JobPartPlan files since Azcopy is running in process
 	//JobsAdmin.ResurrectJobParts()
 	// TODO: We may want to list listen first and terminate if there is already an instance listening
@@ -142,6 +145,11 @@ func ExecuteNewCopyJobPartOrder(order common.CopyJobPartOrderRequest) common.Cop
 	jppfn := JobsAdmin.NewJobPartPlanFileName(order.JobID, order.PartNum)
 	jppfn.Create(order)                                                                   // Convert the order to a plan file
 	jpm := JobsAdmin.JobMgrEnsureExists(order.JobID, order.LogLevel, order.CommandString) // Get a this job part's job manager (create it if it doesn't exist)
+	JobsAdmin.(*jobsAdmin).holdJob(order.JobID)
+	if order.PartNum == 0 {
+		// if the job was queued behind another, its plan file now says what its queue file did
+		_ = os.Remove(queuedJobPath(order.JobID))
+	}
 
 	if len(order.Transfers) == 0 && order.IsFinalPart {
 		/*
@@ -191,6 +199,14 @@ func CancelPauseJobOrder(jobID common.JobID, desiredJobStatus common.JobStatus)
 		}
 	}
 
+	// A paused job is carried on with "jobs resume", which can only resume a job that has been ordered completely
+	if desiredJobStatus == common.EJobStatus.Paused() && !completeJobOrdered(jm) {
+		return common.CancelPauseResumeResponse{
+			CancelledPauseResumed: false,
+			ErrorMsg:              fmt.Sprintf("cannot pause the job %s yet, since it hasn't been ordered completely", jobID),
+		}
+	}
+
 	jpp0 := jpm.Plan()
 	var jr common.CancelPauseResumeResponse
 	switch jpp0.JobStatus() { // Current status
@@ -237,6 +253,19 @@ func CancelPauseJobOrder(jobID common.JobID, desiredJobStatus common.JobStatus)
 	return jr
 }
 
+// completeJobOrdered determines whether final part for job with JobId has been ordered or not.
+func completeJobOrdered(jm IJobMgr) bool {
+	completeJobOrdered := false
+	for p := PartNumber(0); true; p++ {
+		jpm, found := jm.JobPartMgr(p)
+		if !found {
+			break
+		}
+		completeJobOrdered = completeJobOrdered || jpm.Plan().IsFinalPart
+	}
+	return completeJobOrdered
+}
+
 func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeResponse {
 	// Strip '?' if present as first character of the source sas / destination sas
 	if len(req.SourceSAS) > 0 && req.SourceSAS[0] == '?' {
@@ -257,19 +286,6 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 	// Get the Job manager again for given JobId
 	jm, _ := JobsAdmin.JobMgr(req.JobID)
 
-	// Check whether Job has been completely ordered or not
-	completeJobOrdered := func(jm IJobMgr) bool {
-		// completeJobOrdered determines whether final part for job with JobId has been ordered or not.
-		completeJobOrdered := false
-		for p := PartNumber(0); true; p++ {
-			jpm, found := jm.JobPartMgr(p)
-			if !found {
-				break
-			}
-			completeJobOrdered = completeJobOrdered || jpm.Plan().IsFinalPart
-		}
-		return completeJobOrdered
-	}
 	// If the job has not been ordered completely, then job cannot be resumed
 	if !completeJobOrdered(jm) {
 		return common.CancelPauseResumeResponse{
@@ -293,7 +309,12 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 		switch jpm.Plan().FromTo {
 		case common.EFromTo.LocalBlob(),
 			common.EFromTo.LocalFile(),
-			common.EFromTo.S3Blob():
+			common.EFromTo.S3Blob(),
+			common.EFromTo.S3File(),
+			common.EFromTo.SFTPBlob(),
+			common.EFromTo.SFTPFile(),
+			common.EFromTo.HTTPBlob(), // an HTTP(S) source's query string, if it had one, is given again with --source-sas
+			common.EFromTo.HTTPFile():
 			if len(req.DestinationSAS) == 0 {
 				errorMsg = "The destination-sas switch must be provided to resume the job"
 			}
@@ -369,6 +390,7 @@ func ResumeJobOrder(req common.ResumeJobRequest) common.CancelPauseResumeRespons
 			}
 		})
 
+		JobsAdmin.(*jobsAdmin).holdJob(req.JobID)
 		jm.ResumeTransfers(steCtx) // Reschedule all job part's transfers
 		//}()
 		jr = common.CancelPauseResumeResponse{
@@ -398,6 +420,15 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		// Search the plan files in Azcopy folder
 		// and resurrect the Job
 		if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+			// a job that's queued behind another has nothing to show but its status
+			if q, err := readQueuedJob(jobID); err == nil {
+				return common.ListJobSummaryResponse{
+					Timestamp:       time.Now().UTC(),
+					JobID:           jobID,
+					JobStatus:       q.JobStatus,
+					FailedTransfers: []common.TransferDetail{},
+				}
+			}
 			return common.ListJobSummaryResponse{
 				ErrorMsg: fmt.Sprintf("no job with JobId %v exists", jobID),
 			}
@@ -416,6 +447,15 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		FailedTransfers:    []common.TransferDetail{},
 	}
 
+	// the metrics file is only written by the process running the job, not by the likes of "jobs show"
+	if found {
+		defer func() {
+			if err := jm.(*jobMgr).metricsSnapshots.maybeWrite(js); err != nil {
+				jm.Log(pipeline.LogWarning, "Cannot write the job's metrics file: "+err.Error())
+			}
+		}()
+	}
+
 	// To avoid race condition: get overall status BEFORE we get counts of completed files)
 	// (if we get it afterwards, we can get a cases where the counts haven't reached 100% done, but by the time we
 	// get the status, the job IS finished - and so we report completion with a lower total file count than what the job really had).
@@ -438,7 +478,7 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 			jppt := jpp.Transfer(t)
 			js.TotalBytesEnumerated += uint64(jppt.SourceSize)
 
-			if jppt.EntityType == common.EEntityType.File() {
+			if jppt.EntityType != common.EEntityType.Folder() {
 				js.FileTransfers++
 			} else {
 				js.FolderPropertyTransfers++
@@ -468,7 +508,8 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 						TransferStatus:     common.ETransferStatus.Failed(),
 						ErrorCode:          jppt.ErrorCode()}) // TODO: Optimize
 			case common.ETransferStatus.SkippedEntityAlreadyExists(),
-				common.ETransferStatus.SkippedBlobHasSnapshots():
+				common.ETransferStatus.SkippedBlobHasSnapshots(),
+				common.ETransferStatus.CancelledByUser():
 				js.TransfersSkipped++
 				// getting the source and destination for skipped transfer at position - index
 				src, dst, isFolder := jpp.TransferSrcDstStrings(t)
@@ -499,6 +540,8 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 	js.CompleteJobOrdered = js.CompleteJobOrdered || jm.AllTransfersScheduled()
 
 	js.BytesOverWire = uint64(JobsAdmin.BytesOverWire())
+	js.TransfersStalled = JobsAdmin.(*jobsAdmin).stallWatchdog.stallCount(jobID)
+	js.Md5Mismatches = jm.(*jobMgr).md5Mismatches.list()
 
 	// Get the number of active go routines performing the transfer or executing the chunk Func
 	// TODO: added for debugging purpose. remove later (is covered by GetPerfInfo now anyway)
@@ -512,6 +555,7 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		js.AverageE2EMilliseconds = pipeStats.AverageE2EMilliseconds()
 		js.NetworkErrorPercentage = pipeStats.NetworkErrorPercentage()
 		js.ServerBusyPercentage = pipeStats.TotalServerBusyPercentage()
+		js.ThrottledRequests = uint64(pipeStats.ThrottledCount())
 	}
 
 	// If the status is cancelled, then no need to check for completerJobOrdered
@@ -522,6 +566,17 @@ func GetJobSummary(jobID common.JobID) common.ListJobSummaryResponse {
 		js.PerformanceAdvice = jm.TryGetPerformanceAdvice(js.TotalBytesExpected, js.TotalTransfers-js.TransfersSkipped, part0.Plan().FromTo)
 		return js
 	}
+	// If the job has been paused, its transfers must stop, and it's reported as paused once they all have
+	if part0PlanStatus == common.EJobStatus.Paused() {
+		if jm.(*jobMgr).stopForPause() {
+			js.JobStatus = part0PlanStatus
+		}
+		return js
+	}
+	// Likewise if it's being cancelled. It's reported as cancelled once its transfers have all stopped
+	if part0PlanStatus == common.EJobStatus.Cancelling() {
+		jm.(*jobMgr).stopForCancel()
+	}
 	// Job is completed if Job order is complete AND ALL transfers are completed/failed
 	// FIX: active or inactive state, then job order is said to be completed if final part of job has been ordered.
 	if (js.CompleteJobOrdered) && (part0PlanStatus.IsJobDone()) {
@@ -557,6 +612,7 @@ func ListJobTransfers(r common.ListJobTransfersRequest) common.ListJobTransfersR
 		JobID:   r.JobID,
 		Details: []common.TransferDetail{},
 	}
+	failures := readTransferFailures(r.JobID)
 	for partNum := PartNumber(0); true; partNum++ {
 		jpm, found := jm.JobPartMgr(partNum)
 		if !found {
@@ -584,16 +640,26 @@ func ListJobTransfers(r common.ListJobTransfersRequest) common.ListJobTransfersR
 			}
 			// getting source and destination of a transfer at index index for given jobId and part number.
 			src, dst, isFolder := jpp.TransferSrcDstStrings(t)
-			ljt.Details = append(ljt.Details,
-				common.TransferDetail{Src: src, Dst: dst, IsFolderProperties: isFolder, TransferStatus: transferEntry.TransferStatus(), ErrorCode: transferEntry.ErrorCode()})
+			detail := common.TransferDetail{Src: src, Dst: dst, IsFolderProperties: isFolder, TransferStatus: transferEntry.TransferStatus(), ErrorCode: transferEntry.ErrorCode()}
+			if detail.TransferStatus <= common.ETransferStatus.Failed() {
+				if f, ok := failures[transferFailureKey{partNum: partNum, transferIndex: t}]; ok {
+					detail.ErrorMessage = f.ErrorMessage
+					detail.RetryCount = f.RetryCount
+				}
+			}
+			ljt.Details = append(ljt.Details, detail)
 		}
 	}
 	return ljt
 }
 
-func GetJobLCMWrapper(jobID common.JobID) common.LifecycleMgr {
+// GetJobLCMWrapper wraps the given lifecycle manager (or the global one, if nil), so that the job's progress
+// is also written to the job's log
+func GetJobLCMWrapper(jobID common.JobID, lcm common.LifecycleMgr) common.LifecycleMgr {
 	jobmgr, found := JobsAdmin.JobMgr(jobID)
-	lcm := common.GetLifecycleMgr()
+	if lcm == nil {
+		lcm = common.GetLifecycleMgr()
+	}
 
 	if !found {
 		return lcm
@@ -611,10 +677,24 @@ func ListJobs(givenStatus common.JobStatus) common.ListJobsResponse {
 	JobsAdmin.ResurrectJobParts()
 	// building the ListJobsResponse for sending response back to front-end
 	jobIds := JobsAdmin.JobIDs()
-	if len(jobIds) == 0 {
+	queuedJobIds := queuedJobIDs()
+	if len(jobIds) == 0 && len(queuedJobIds) == 0 {
 		return common.ListJobsResponse{ErrorMessage: "no jobs exists in Azcopy history"}
 	}
 	listJobResponse := common.ListJobsResponse{JobIDDetails: []common.JobIDDetails{}}
+	for _, jobId := range queuedJobIds {
+		if _, found := JobsAdmin.JobMgr(jobId); found {
+			continue // its first plan file has been written, but its queue file hasn't been removed yet
+		}
+		q, err := readQueuedJob(jobId)
+		if err != nil {
+			continue
+		}
+		if givenStatus == common.EJobStatus.All() || givenStatus == q.JobStatus {
+			listJobResponse.JobIDDetails = append(listJobResponse.JobIDDetails,
+				common.JobIDDetails{JobId: jobId, CommandString: q.CommandString, StartTime: q.QueuedAt.UnixNano(), JobStatus: q.JobStatus})
+		}
+	}
 	for _, jobId := range jobIds {
 		jm, found := JobsAdmin.JobMgr(jobId)
 		if !found {
@@ -676,3 +756,207 @@ func GetJobFromTo(r common.GetJobFromToRequest) common.GetJobFromToResponse {
 		Destination: destination,
 	}
 }
+
+// GetJobStatus returns the status of a job, without resurrecting it. It's used to wait for a job that another
+// AzCopy process is running, so it just reads the status from the job's first plan file, which that process keeps up to date,
+// or from its queue file, if it's still waiting for a job of its own
+func GetJobStatus(jobID common.JobID) common.GetJobStatusResponse {
+	status, errMsg := jobStatusWithoutResurrecting(jobID)
+	if errMsg != "" {
+		return common.GetJobStatusResponse{ErrorMsg: errMsg}
+	}
+	// a job that's stopped doesn't need a process, while any other job does
+	abandoned := !status.HasStopped() && isJobAbandoned(jobID)
+	return common.GetJobStatusResponse{JobStatus: status, Abandoned: abandoned}
+}
+
+func jobStatusWithoutResurrecting(jobID common.JobID) (status common.JobStatus, errMsg string) {
+	if jm, found := JobsAdmin.JobMgr(jobID); found {
+		if jp0, ok := jm.JobPartMgr(0); ok {
+			return jp0.Plan().JobStatus(), ""
+		}
+	}
+
+	planFile := JobsAdmin.NewJobPartPlanFileName(jobID, 0)
+	if err := planFile.Validate(); err != nil {
+		if os.IsNotExist(err) {
+			if q, err := readQueuedJob(jobID); err == nil {
+				return q.JobStatus, ""
+			}
+			return status, fmt.Sprintf("no job with JobID %v exists", jobID)
+		}
+		return status, fmt.Sprintf("cannot read the plan file of job %v: %v", jobID, err)
+	}
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	return mmf.Plan().JobStatus(), ""
+}
+
+// UpdateQueuedJob records a job that's waiting for another to complete, in its queue file, and keeps its heartbeat
+// for as long as this process is holding it
+func UpdateQueuedJob(r common.UpdateQueuedJobRequest) common.UpdateQueuedJobResponse {
+	q := queuedJob{AfterJob: r.AfterJob, CommandString: r.CommandString, QueuedAt: time.Now().UTC(), JobStatus: r.JobStatus}
+	if existing, err := readQueuedJob(r.JobID); err == nil {
+		q.QueuedAt = existing.QueuedAt
+	}
+	if err := writeQueuedJob(r.JobID, q); err != nil {
+		return common.UpdateQueuedJobResponse{ErrorMsg: fmt.Sprintf("cannot save the queue file of job %v: %v", r.JobID, err)}
+	}
+	JobsAdmin.(*jobsAdmin).holdJob(r.JobID)
+	return common.UpdateQueuedJobResponse{}
+}
+
+// RetryJob creates a new job, to retry the transfers that failed in a job that has stopped. The new job's plan files are
+// copies of the old job's, with only the failed transfers in them, so it has the same options. It's run by resuming it
+func RetryJob(jobID common.JobID) common.RetryJobResponse {
+	if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+		return common.RetryJobResponse{ErrorMsg: fmt.Sprintf("no job with JobId %v exists", jobID)}
+	}
+	jm, _ := JobsAdmin.JobMgr(jobID)
+	if !completeJobOrdered(jm) {
+		return common.RetryJobResponse{ErrorMsg: fmt.Sprintf("cannot retry job %v. It hasn't been ordered completely", jobID)}
+	}
+	jpm0, _ := jm.JobPartMgr(0)
+	if status := jpm0.Plan().JobStatus(); !status.HasStopped() {
+		return common.RetryJobResponse{ErrorMsg: fmt.Sprintf("cannot retry job %v, because it hasn't finished (its status is %v). "+
+			"Wait for it to finish, or if its AzCopy process was stopped, resume it instead", jobID, status)}
+	}
+
+	// the parts that have failed transfers, in order
+	type retryPart struct {
+		plan      *JobPartPlanHeader
+		transfers []uint32
+	}
+	var parts []retryPart
+	var transfersToRetry uint32
+	for partNum := PartNumber(0); true; partNum++ {
+		jpm, found := jm.JobPartMgr(partNum)
+		if !found {
+			break
+		}
+		jpp := jpm.Plan()
+		part := retryPart{plan: jpp}
+		for t := uint32(0); t < jpp.NumTransfers; t++ {
+			// as when resuming, any negative status is a failure
+			if jpp.Transfer(t).TransferStatus() <= common.ETransferStatus.Failed() {
+				part.transfers = append(part.transfers, t)
+			}
+		}
+		if len(part.transfers) > 0 {
+			parts = append(parts, part)
+			transfersToRetry += uint32(len(part.transfers))
+		}
+	}
+	if len(parts) == 0 {
+		return common.RetryJobResponse{ErrorMsg: fmt.Sprintf("job %v has no failed transfers to retry", jobID)}
+	}
+
+	newJobID := common.NewJobID()
+	for i, part := range parts {
+		partNum := PartNumber(i)
+		JobsAdmin.NewJobPartPlanFileName(newJobID, partNum).CreateForRetry(part.plan, newJobID, partNum, part.transfers, i == len(parts)-1)
+	}
+	jm.Log(pipeline.LogInfo, fmt.Sprintf("The %d failed transfers of this job are being retried by job %v", transfersToRetry, newJobID))
+
+	return common.RetryJobResponse{JobID: newJobID, TransfersToRetry: transfersToRetry}
+}
+
+// SetJobSettings changes the settings of a running job. The job may be running in a different AzCopy process,
+// so the settings are passed on through the job's control file, which the running process checks regularly
+func SetJobSettings(r common.SetJobSettingsRequest) common.SetJobSettingsResponse {
+	if r.CapMbps < 0 {
+		return common.SetJobSettingsResponse{ErrorMsg: "the throughput cap cannot be negative"}
+	}
+
+	jm, found := JobsAdmin.JobMgr(r.JobID)
+	if !found {
+		// Job with JobId does not exists.
+		// Search the plan files in Azcopy folder and resurrect the Job.
+		if !JobsAdmin.ResurrectJob(r.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+			return common.SetJobSettingsResponse{
+				ErrorMsg: fmt.Sprintf("no job with JobID %v exists", r.JobID),
+			}
+		}
+		jm, _ = JobsAdmin.JobMgr(r.JobID)
+	}
+	jp0, ok := jm.JobPartMgr(0)
+	if !ok {
+		return common.SetJobSettingsResponse{ErrorMsg: fmt.Sprintf("JobID=%v, Part#=0 not found", r.JobID)}
+	}
+
+	// only a running job checks its control file. Anything else would just ignore the new settings, so say so
+	if status := jp0.Plan().JobStatus(); status != common.EJobStatus.InProgress() {
+		return common.SetJobSettingsResponse{
+			ErrorMsg: fmt.Sprintf("cannot change the settings of job %v, because its status is %v. "+
+				"To change the throughput cap when resuming a job, use the cap-mbps flag of the resume command", r.JobID, status),
+		}
+	}
+
+	if err := updateJobControl(r.JobID, func(control *jobControl) { control.CapMbps = &r.CapMbps }); err != nil {
+		return common.SetJobSettingsResponse{ErrorMsg: fmt.Sprintf("cannot save the new settings of job %v: %v", r.JobID, err)}
+	}
+	return common.SetJobSettingsResponse{}
+}
+
+// CancelTransfers cancels some of the transfers of a running job, leaving the rest of the job to carry on.
+// Like SetJobSettings, it passes the request on through the job's control file
+func CancelTransfers(r common.CancelTransfersRequest) common.CancelTransfersResponse {
+	if len(r.Sources) == 0 {
+		return common.CancelTransfersResponse{ErrorMsg: "no transfers were given to cancel"}
+	}
+
+	jm, found := JobsAdmin.JobMgr(r.JobID)
+	if !found {
+		if !JobsAdmin.ResurrectJob(r.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+			return common.CancelTransfersResponse{ErrorMsg: fmt.Sprintf("no job with JobID %v exists", r.JobID)}
+		}
+		jm, _ = JobsAdmin.JobMgr(r.JobID)
+	}
+	jp0, ok := jm.JobPartMgr(0)
+	if !ok {
+		return common.CancelTransfersResponse{ErrorMsg: fmt.Sprintf("JobID=%v, Part#=0 not found", r.JobID)}
+	}
+	if status := jp0.Plan().JobStatus(); status != common.EJobStatus.InProgress() {
+		return common.CancelTransfersResponse{
+			ErrorMsg: fmt.Sprintf("cannot cancel transfers of job %v, because its status is %v", r.JobID, status),
+		}
+	}
+
+	// check each source against the parts ordered so far, so that mistyped ones are reported,
+	// rather than silently cancelling nothing. Until the whole job has been ordered, they might just not be there yet
+	unmatched := make([]string, 0)
+	toCancel := 0
+	completelyOrdered := false
+	for _, source := range r.Sources {
+		matcher := newTransferSourceMatcher([]string{source})
+		matched := false
+		jm.(*jobMgr).jobPartMgrs.Iterate(true, func(_ common.PartNumber, jpm IJobPartMgr) {
+			plan := jpm.Plan()
+			completelyOrdered = completelyOrdered || plan.IsFinalPart
+			for t := uint32(0); t < plan.NumTransfers; t++ {
+				if matcher.matches(plan, t) {
+					matched = true
+					if plan.Transfer(t).TransferStatus().ShouldTransfer() {
+						toCancel++
+					}
+				}
+			}
+		})
+		if !matched {
+			unmatched = append(unmatched, source)
+		}
+	}
+	if len(unmatched) > 0 && completelyOrdered {
+		return common.CancelTransfersResponse{
+			ErrorMsg: fmt.Sprintf("job %v has no transfers from %s", r.JobID, strings.Join(unmatched, ", ")),
+		}
+	}
+
+	err := updateJobControl(r.JobID, func(control *jobControl) {
+		control.CancelledSources = append(control.CancelledSources, r.Sources...)
+	})
+	if err != nil {
+		return common.CancelTransfersResponse{ErrorMsg: fmt.Sprintf("cannot save the cancellations for job %v: %v", r.JobID, err)}
+	}
+	return common.CancelTransfersResponse{TransfersCancelled: toCancel}
+}
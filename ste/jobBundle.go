@@ -0,0 +1,147 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// ExportJob bundles up the job's plans, as they stand, so that the job can be imported into AzCopy on another machine,
+// and carried on there. The plans hold no SAS tokens, so neither does the bundle
+func ExportJob(jobID common.JobID) common.ExportJobResponse {
+	if !JobsAdmin.ResurrectJob(jobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+		return common.ExportJobResponse{ErrorMsg: fmt.Sprintf("no job with JobId %v exists", jobID)}
+	}
+	jm, _ := JobsAdmin.JobMgr(jobID)
+	if !completeJobOrdered(jm) {
+		return common.ExportJobResponse{ErrorMsg: fmt.Sprintf("cannot export job %v. It hasn't been ordered completely", jobID)}
+	}
+
+	bundle := common.JobBundle{DataSchemaVersion: DataSchemaVersion, JobID: jobID, Parts: make([]common.JobPartBundle, 0)}
+	for partNum := PartNumber(0); true; partNum++ {
+		jpm, found := jm.JobPartMgr(partNum)
+		if !found {
+			break
+		}
+		jpp := jpm.Plan()
+		if partNum == 0 {
+			bundle.CommandString = jpp.CommandString()
+			bundle.FromTo = jpp.FromTo.String()
+			bundle.Source = string(jpp.SourceRoot[:jpp.SourceRootLength])
+			bundle.Destination = string(jpp.DestinationRoot[:jpp.DestinationRootLength])
+			bundle.JobStatus = jpp.JobStatus()
+		}
+		for t := uint32(0); t < jpp.NumTransfers; t++ {
+			switch status := jpp.Transfer(t).TransferStatus(); {
+			case status == common.ETransferStatus.Success():
+				bundle.TransfersCompleted++
+			case status <= common.ETransferStatus.Failed() && status != common.ETransferStatus.CancelledByUser():
+				bundle.TransfersFailed++
+			}
+		}
+		bundle.TotalTransfers += jpp.NumTransfers
+
+		mmf := (*common.MMF)(jpm.(*jobPartMgr).planMMF)
+		bundle.Parts = append(bundle.Parts, common.JobPartBundle{PartNum: partNum, Plan: append([]byte{}, mmf.Slice()...)})
+	}
+	return common.ExportJobResponse{Bundle: bundle}
+}
+
+// ImportJob writes the plan files of an exported job, so that it can be resumed. The transfers that had finished stay finished
+func ImportJob(r common.ImportJobRequest) common.ImportJobResponse {
+	bundle := r.Bundle
+	if bundle.DataSchemaVersion != DataSchemaVersion {
+		return common.ImportJobResponse{ErrorMsg: fmt.Sprintf("the job was exported by a version of AzCopy whose plan files are version %d, "+
+			"but this version's are %d. Import it with the same version of AzCopy that exported it", bundle.DataSchemaVersion, DataSchemaVersion)}
+	}
+	if len(bundle.Parts) == 0 {
+		return common.ImportJobResponse{ErrorMsg: "the bundle has no job parts in it"}
+	}
+	if JobsAdmin.ResurrectJob(bundle.JobID, EMPTY_SAS_STRING, EMPTY_SAS_STRING) {
+		return common.ImportJobResponse{ErrorMsg: fmt.Sprintf("job %v is already here. To import it again, remove it first, with the jobs rm command", bundle.JobID)}
+	}
+
+	// check everything before writing anything, so that a bad bundle doesn't leave half a job behind
+	plans := make([][]byte, len(bundle.Parts))
+	var transfersLeft uint32
+	for i, part := range bundle.Parts {
+		planFile := JobsAdmin.NewJobPartPlanFileName(bundle.JobID, PartNumber(i))
+		if part.PartNum != PartNumber(i) {
+			return common.ImportJobResponse{ErrorMsg: fmt.Sprintf("part %d of the bundle is numbered %d", i, part.PartNum)}
+		}
+		if err := planFile.validatePlan(bytes.NewReader(part.Plan), int64(len(part.Plan))); err != nil {
+			return common.ImportJobResponse{ErrorMsg: fmt.Sprintf("part %d of the bundle is damaged: %v", i, err)}
+		}
+
+		// a copy, since the header is changed in place, and must be suitably aligned for that
+		plan := make([]byte, len(part.Plan))
+		copy(plan, part.Plan)
+		jpph := (*JobPartPlanHeader)(unsafe.Pointer(&plan[0]))
+		if jpph.IsFinalPart != (i == len(bundle.Parts)-1) {
+			return common.ImportJobResponse{ErrorMsg: "the bundle doesn't have all the parts of the job"}
+		}
+		if err := jpph.setRoots(r.Source, r.Destination); err != nil {
+			return common.ImportJobResponse{ErrorMsg: err.Error()}
+		}
+		for t := uint32(0); t < jpph.NumTransfers; t++ {
+			// as when resuming, everything but the transfers that succeeded, or that were cancelled by the user, is done again
+			if status := jpph.Transfer(t).TransferStatus(); status != common.ETransferStatus.Success() && status != common.ETransferStatus.CancelledByUser() {
+				transfersLeft++
+			}
+		}
+		plans[i] = plan
+	}
+
+	for i, plan := range plans {
+		JobsAdmin.NewJobPartPlanFileName(bundle.JobID, PartNumber(i)).writePlanFile(func(writer io.Writer) {
+			_, err := writer.Write(plan)
+			common.PanicIfErr(err)
+		})
+	}
+	JobsAdmin.LogToJobLog(fmt.Sprintf("Imported job %v, with %d transfers left to do", bundle.JobID, transfersLeft), pipeline.LogInfo)
+	return common.ImportJobResponse{TransfersLeft: transfersLeft}
+}
+
+// setRoots replaces the source and destination of the job part, where they're given
+func (jpph *JobPartPlanHeader) setRoots(source, destination string) error {
+	if source != "" {
+		if len(source) > len(jpph.SourceRoot) {
+			return fmt.Errorf("the new source is too long: %q", source)
+		}
+		jpph.SourceRoot = [len(jpph.SourceRoot)]byte{}
+		jpph.SourceRootLength = uint16(copy(jpph.SourceRoot[:], source))
+	}
+	if destination != "" {
+		if len(destination) > len(jpph.DestinationRoot) {
+			return fmt.Errorf("the new destination is too long: %q", destination)
+		}
+		jpph.DestinationRoot = [len(jpph.DestinationRoot)]byte{}
+		jpph.DestinationRootLength = uint16(copy(jpph.DestinationRoot[:], destination))
+	}
+	return nil
+}
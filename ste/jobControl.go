@@ -0,0 +1,196 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// A job's control file is how "jobs set" and "jobs cancel-transfers", run in one AzCopy process, change the job while it
+// is running in another. Like the job's failures file, its name contains ".steV" but doesn't end with it, so that it's removed
+// along with the plan files, but not mistaken for one of them
+const jobControlFileNameFormat = "%v.steV%d.control"
+
+// how often a running process checks the control files of its jobs
+const jobControlCheckInterval = 2 * time.Second
+
+// jobControl holds the settings that can be changed while a job runs
+type jobControl struct {
+	CapMbps *float64 `json:",omitempty"` // zero means no cap, and nil means it hasn't been set
+
+	// the sources of the transfers that are to be cancelled, as the user gave them
+	CancelledSources []string `json:",omitempty"`
+}
+
+func (c jobControl) capMbpsEquals(other jobControl) bool {
+	if c.CapMbps == nil || other.CapMbps == nil {
+		return c.CapMbps == other.CapMbps
+	}
+	return *c.CapMbps == *other.CapMbps
+}
+
+func jobControlPath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s"+jobControlFileNameFormat, JobsAdmin.AppPathFolder(), common.AZCOPY_PATH_SEPARATOR_STRING, jobID, DataSchemaVersion)
+}
+
+// writeJobControl saves the settings. The file is replaced in one go, so the running process never reads a half-written one
+func writeJobControl(jobID common.JobID, control jobControl) error {
+	content, err := json.Marshal(control)
+	if err != nil {
+		return err
+	}
+	path := jobControlPath(jobID)
+	if err = ioutil.WriteFile(path+planFileTempSuffix, content, common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	return os.Rename(path+planFileTempSuffix, path)
+}
+
+// updateJobControl changes the settings, starting from what was set before, if anything was
+func updateJobControl(jobID common.JobID, update func(control *jobControl)) error {
+	control, err := readJobControl(jobID)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	update(&control)
+	return writeJobControl(jobID, control)
+}
+
+func readJobControl(jobID common.JobID) (jobControl, error) {
+	var control jobControl
+	content, err := ioutil.ReadFile(jobControlPath(jobID))
+	if err != nil {
+		return control, err
+	}
+	err = json.Unmarshal(content, &control)
+	return control, err
+}
+
+// jobControlLoop applies the changes that "jobs set" and "jobs cancel-transfers" make to the control files of our jobs.
+// Only settings changed after we started working on a job count; when a job is resumed, the flags given to the
+// resume command take precedence over anything that was set for the job's earlier run.
+// Cancellations always count, since there's no flag to override them, and cancelling a transfer twice does no harm
+func (ja *jobsAdmin) jobControlLoop() {
+	ticker := time.NewTicker(jobControlCheckInterval)
+	defer ticker.Stop()
+
+	// what each job's control file said when we last looked, or nil if the job didn't have one
+	lastSeen := make(map[common.JobID]*jobControl)
+	for {
+		select {
+		case <-ticker.C:
+			ja.jobIDToJobMgr.Iterate(false, func(jobID common.JobID, jm IJobMgr) {
+				var current *jobControl
+				if control, err := readJobControl(jobID); err == nil {
+					current = &control
+				}
+				previous, seen := lastSeen[jobID]
+				lastSeen[jobID] = current
+				if current == nil {
+					return
+				}
+				if seen && (previous == nil || !previous.capMbpsEquals(*current)) {
+					ja.applyJobControl(jm, *current)
+				}
+				if previous == nil || len(previous.CancelledSources) != len(current.CancelledSources) {
+					ja.applyTransferCancellations(jm, current.CancelledSources)
+				}
+			})
+		case <-ja.appCtx.Done():
+			return
+		}
+	}
+}
+
+func (ja *jobsAdmin) applyJobControl(jm IJobMgr, control jobControl) {
+	// the pacer is shared by all the jobs in this process, so the cap applies to all of them
+	if control.CapMbps != nil && *control.CapMbps != ja.pacer.capMbps() {
+		capMbps := *control.CapMbps
+		ja.pacer.setCapMbps(capMbps)
+		msg := fmt.Sprintf("The throughput cap has been changed to %v Mbps", capMbps)
+		if capMbps == 0 {
+			msg = "The throughput cap has been removed"
+		}
+		jm.Log(pipeline.LogInfo, msg)
+		common.GetLifecycleMgr().Info(msg)
+	}
+}
+
+// applyTransferCancellations cancels the transfers with the given sources. Those in parts that haven't been
+// scheduled yet are cancelled when they are
+func (ja *jobsAdmin) applyTransferCancellations(jm IJobMgr, sources []string) {
+	matcher := newTransferSourceMatcher(sources)
+	jm.(*jobMgr).setCancelledSources(matcher)
+
+	cancelled := 0
+	jm.(*jobMgr).jobPartMgrs.Iterate(true, func(_ common.PartNumber, jpm IJobPartMgr) {
+		cancelled += jpm.(*jobPartMgr).cancelTransfersByUser(matcher)
+	})
+	if cancelled > 0 {
+		msg := fmt.Sprintf("%d transfers have been cancelled, at the user's request", cancelled)
+		jm.Log(pipeline.LogInfo, msg)
+		common.GetLifecycleMgr().Info(msg)
+	}
+}
+
+// transferSourceMatcher finds transfers by their sources, as the user gave them. A source can be given in full, or
+// relative to the source of the job (i.e. as it appears in the destination), and for a remote source, the SAS is ignored
+type transferSourceMatcher map[string]struct{}
+
+func newTransferSourceMatcher(sources []string) transferSourceMatcher {
+	m := make(transferSourceMatcher, len(sources))
+	for _, s := range sources {
+		m[normalizeSourceForMatching(s)] = struct{}{}
+	}
+	return m
+}
+
+func normalizeSourceForMatching(source string) string {
+	if strings.Contains(source, "://") {
+		if i := strings.Index(source, "?"); i >= 0 {
+			source = source[:i]
+		}
+	}
+	source = strings.ReplaceAll(source, `\`, common.AZCOPY_PATH_SEPARATOR_STRING)
+	return strings.Trim(source, common.AZCOPY_PATH_SEPARATOR_STRING)
+}
+
+func (m transferSourceMatcher) matches(plan *JobPartPlanHeader, transferIndex uint32) bool {
+	if len(m) == 0 {
+		return false
+	}
+	jppt := plan.Transfer(transferIndex)
+	relative := plan.getString(jppt.SrcOffset, jppt.SrcLength)
+	if _, ok := m[normalizeSourceForMatching(relative)]; ok {
+		return true
+	}
+	root := string(plan.SourceRoot[:plan.SourceRootLength])
+	_, ok := m[normalizeSourceForMatching(common.GenerateFullPath(root, relative))]
+	return ok
+}
@@ -0,0 +1,137 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// A job that was queued behind another, with --after-job, has no plan files until it starts, since what it will transfer
+// isn't known before then. So that "jobs list", "jobs show" and the jobs queued behind it can see it while it waits, the
+// process that's holding it back keeps a queue file for it, which is removed once its first plan file exists.
+// Like the control file, its name contains ".steV" but doesn't end with it
+const queuedJobFileNameFormat = "%v.steV%d.queued"
+
+// Each process touches the heartbeat file of every job that it's running, or holding in the queue, so that a job that's
+// waiting for another can tell when no process is running that one any more (e.g. because it was killed)
+const jobHeartbeatFileNameFormat = "%v.steV%d.heartbeat"
+
+const jobHeartbeatInterval = 5 * time.Second
+
+// a job whose heartbeat is older than this has been abandoned by the process that was running it
+const jobHeartbeatStaleAfter = time.Minute
+
+// queuedJob is what the queue file of a job says about it
+type queuedJob struct {
+	AfterJob      common.JobID
+	CommandString string
+	QueuedAt      time.Time
+	JobStatus     common.JobStatus // Queued while it waits, then InProgress until its first plan file exists, or Failed if it never starts
+}
+
+func queuedJobPath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s"+queuedJobFileNameFormat, JobsAdmin.AppPathFolder(), common.AZCOPY_PATH_SEPARATOR_STRING, jobID, DataSchemaVersion)
+}
+
+func jobHeartbeatPath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s"+jobHeartbeatFileNameFormat, JobsAdmin.AppPathFolder(), common.AZCOPY_PATH_SEPARATOR_STRING, jobID, DataSchemaVersion)
+}
+
+// writeQueuedJob saves the queue file in one go, as writeJobControl does, so that other processes never read a half-written one
+func writeQueuedJob(jobID common.JobID, q queuedJob) error {
+	content, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	path := queuedJobPath(jobID)
+	if err = ioutil.WriteFile(path+planFileTempSuffix, content, common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	return os.Rename(path+planFileTempSuffix, path)
+}
+
+func readQueuedJob(jobID common.JobID) (queuedJob, error) {
+	var q queuedJob
+	content, err := ioutil.ReadFile(queuedJobPath(jobID))
+	if err != nil {
+		return q, err
+	}
+	err = json.Unmarshal(content, &q)
+	return q, err
+}
+
+// queuedJobIDs returns the jobs that have queue files, i.e. those that haven't started yet, or have only just started
+func queuedJobIDs() []common.JobID {
+	suffix := fmt.Sprintf(".steV%d.queued", DataSchemaVersion)
+	var jobIDs []common.JobID
+	filepath.Walk(JobsAdmin.AppPathFolder(), func(path string, fileInfo os.FileInfo, _ error) error {
+		if fileInfo != nil && !fileInfo.IsDir() && strings.HasSuffix(fileInfo.Name(), suffix) {
+			if jobID, err := common.ParseJobID(strings.TrimSuffix(fileInfo.Name(), suffix)); err == nil {
+				jobIDs = append(jobIDs, jobID)
+			}
+		}
+		return nil
+	})
+	return jobIDs
+}
+
+// holdJob starts the heartbeat of a job that this process is running, or holding in the queue. It carries on until
+// the process exits, by when the job has either finished, or been stopped part-way through
+func (ja *jobsAdmin) holdJob(jobID common.JobID) {
+	if _, alreadyHeld := ja.heldJobs.LoadOrStore(jobID, struct{}{}); !alreadyHeld {
+		touchJobHeartbeat(jobID)
+	}
+}
+
+func (ja *jobsAdmin) jobHeartbeatLoop() {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ja.heldJobs.Range(func(jobID, _ interface{}) bool {
+				touchJobHeartbeat(jobID.(common.JobID))
+				return true
+			})
+		case <-ja.appCtx.Done():
+			return
+		}
+	}
+}
+
+func touchJobHeartbeat(jobID common.JobID) {
+	// the content doesn't matter, only when the file was last written
+	_ = ioutil.WriteFile(jobHeartbeatPath(jobID), []byte(time.Now().UTC().Format(time.RFC3339)), common.DEFAULT_FILE_PERM)
+}
+
+// isJobAbandoned says whether no process has been running, or holding, the job for a while
+func isJobAbandoned(jobID common.JobID) bool {
+	info, err := os.Stat(jobHeartbeatPath(jobID))
+	return err != nil || time.Since(info.ModTime()) > jobHeartbeatStaleAfter
+}
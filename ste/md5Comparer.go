@@ -96,6 +96,12 @@ func (c *md5Comparer) Check() error {
 	return nil
 }
 
+// mismatched says whether there were two MD5s to compare, and they differed, whatever the validation option says to do about it
+func (c *md5Comparer) mismatched() bool {
+	return c.validationOption != common.EHashValidationOption.NoCheck() &&
+		len(c.expected) != 0 && len(c.actualAsSaved) != 0 && !bytes.Equal(c.expected, c.actualAsSaved)
+}
+
 func (c *md5Comparer) logAsMissing() {
 	c.logger.LogAtLevelForCurrentTransfer(pipeline.LogWarning, noMD5Stored)
 }
@@ -0,0 +1,64 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"sync"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+const md5MismatchMessage = "the MD5 hash of the downloaded data didn't match the one stored at the source"
+
+// md5MismatchRecorder keeps the downloads of a job whose data didn't match the MD5 hash stored at the source, so that
+// the job summary can list them. That includes those that were kept, because check-md5 was LogOnly, which the failed
+// transfers don't show
+type md5MismatchRecorder struct {
+	mu        sync.Mutex
+	transfers []common.TransferDetail
+}
+
+func newMd5MismatchRecorder() *md5MismatchRecorder {
+	return &md5MismatchRecorder{}
+}
+
+func (r *md5MismatchRecorder) record(src, dst string, kept bool) {
+	if r == nil {
+		return
+	}
+	status := common.ETransferStatus.Failed()
+	if kept {
+		status = common.ETransferStatus.Success()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transfers = append(r.transfers, common.TransferDetail{Src: src, Dst: dst, TransferStatus: status, ErrorMessage: md5MismatchMessage})
+}
+
+func (r *md5MismatchRecorder) list() []common.TransferDetail {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]common.TransferDetail(nil), r.transfers...)
+}
@@ -0,0 +1,109 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The values given with --metadata may hold these placeholders, which are filled in for each file
+const (
+	metadataFileNamePlaceholder = "{filename}"
+	metadataRelPathPlaceholder  = "{relpath}"
+	metadataJobIDPlaceholder    = "{jobid}"
+)
+
+// expandMetadataPlaceholders fills in the placeholders in the values of the metadata. relPath is the path of the file
+// relative to the source root, using / as the separator
+func expandMetadataPlaceholders(metadata common.Metadata, relPath string, jobID common.JobID) common.Metadata {
+	hasPlaceholders := false
+	for _, v := range metadata {
+		if strings.Contains(v, "{") {
+			hasPlaceholders = true
+			break
+		}
+	}
+	if !hasPlaceholders {
+		return metadata
+	}
+
+	replacer := strings.NewReplacer(
+		metadataFileNamePlaceholder, toMetadataValue(path.Base(relPath)),
+		metadataRelPathPlaceholder, toMetadataValue(relPath),
+		metadataJobIDPlaceholder, jobID.String())
+
+	expanded := make(common.Metadata, len(metadata))
+	for k, v := range metadata {
+		expanded[k] = replacer.Replace(v)
+	}
+	return expanded
+}
+
+// toMetadataValue percent-encodes the characters of a name that can't be in a metadata value, since those are sent as
+// HTTP headers, which may only hold printable ASCII
+func toMetadataValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '%' {
+			b.WriteString(fmt.Sprintf("%%%02X", c))
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// sourceRelativePath gives the path of the transfer's source relative to the source root, unescaped, and using / as
+// the separator. When the source is a single file, that's its name
+func sourceRelativePath(plan *JobPartPlanHeader, transferIndex uint32, source string) string {
+	relPath := plan.TransferSrcRelativePath(transferIndex)
+	if plan.FromTo.From().IsRemote() {
+		if unescaped, err := url.PathUnescape(relPath); err == nil {
+			relPath = unescaped
+		}
+	}
+	relPath = strings.Trim(strings.ReplaceAll(relPath, `\`, "/"), "/")
+
+	if relPath == "" {
+		if u, err := url.Parse(source); err == nil && plan.FromTo.From().IsRemote() {
+			source = u.Path
+		}
+		relPath = path.Base(strings.ReplaceAll(source, `\`, "/"))
+	}
+	return relPath
+}
+
+// metadataToApply gives the metadata for the destination of a transfer. Uploads already have the metadata given with
+// --metadata, from their source info provider. When copying between services, that is combined with the source's
+// metadata here, as --s2s-metadata-merge says
+func metadataToApply(jptm IJobPartTransferMgr, sip ISourceInfoProvider, srcMetadata common.Metadata) common.Metadata {
+	if sip.IsLocal() {
+		return srcMetadata
+	}
+	_, givenMetadata := jptm.ResourceDstData(nil)
+	return jptm.Info().S2SMetadataMerge.Merge(srcMetadata, givenMetadata)
+}
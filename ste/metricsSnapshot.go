@@ -0,0 +1,140 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Every so often, a snapshot of the metrics of each job that is running is written to a file next to its log, so that
+// monitoring tools can follow a job that runs unattended, without needing its console output. The file is replaced each
+// time (so it's never more than one snapshot), and is left behind when the job ends, holding the job's final state.
+const metricsSnapshotFileNameFormat = "%v.metrics.json"
+
+const metricsSnapshotInterval = 10 * time.Second
+
+// JobMetricsSnapshot is the content of a job's metrics file
+type JobMetricsSnapshot struct {
+	Timestamp          time.Time
+	JobID              common.JobID
+	JobStatus          common.JobStatus
+	CompleteJobOrdered bool // false while the source is still being scanned, so the totals may still grow
+	PercentComplete    float32
+
+	TotalTransfers     uint32
+	TransfersCompleted uint32
+	TransfersFailed    uint32
+	TransfersSkipped   uint32
+	TransfersStalled   uint32
+
+	TotalBytesExpected    uint64
+	TotalBytesTransferred uint64
+	BytesOverWire         uint64
+
+	// the average since the previous snapshot
+	ThroughputMbps float64
+
+	AverageIOPS            int
+	ServerBusyPercentage   float32
+	NetworkErrorPercentage float32
+	ThrottledRequests      uint64
+}
+
+type metricsSnapshotWriter struct {
+	mu                sync.Mutex
+	path              string
+	lastWritten       time.Time
+	lastBytesOverWire uint64
+	failed            bool // so that we only log the first failure
+	now               func() time.Time
+}
+
+func newMetricsSnapshotWriter(jobID common.JobID, logFileFolder string) *metricsSnapshotWriter {
+	return &metricsSnapshotWriter{
+		path: fmt.Sprintf("%s%s"+metricsSnapshotFileNameFormat, logFileFolder, common.AZCOPY_PATH_SEPARATOR_STRING, jobID),
+		now:  time.Now,
+	}
+}
+
+// maybeWrite writes a snapshot of the job's summary, if enough time has passed since the last one, or if the job
+// has stopped. It returns an error only the first time that writing fails
+func (w *metricsSnapshotWriter) maybeWrite(js common.ListJobSummaryResponse) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	elapsed := now.Sub(w.lastWritten)
+	if elapsed < metricsSnapshotInterval && !js.JobStatus.HasStopped() {
+		return nil
+	}
+
+	snapshot := JobMetricsSnapshot{
+		Timestamp:              now.UTC(),
+		JobID:                  js.JobID,
+		JobStatus:              js.JobStatus,
+		CompleteJobOrdered:     js.CompleteJobOrdered,
+		PercentComplete:        js.PercentComplete,
+		TotalTransfers:         js.TotalTransfers,
+		TransfersCompleted:     js.TransfersCompleted,
+		TransfersFailed:        js.TransfersFailed,
+		TransfersSkipped:       js.TransfersSkipped,
+		TransfersStalled:       js.TransfersStalled,
+		TotalBytesExpected:     js.TotalBytesExpected,
+		TotalBytesTransferred:  js.TotalBytesTransferred,
+		BytesOverWire:          js.BytesOverWire,
+		AverageIOPS:            js.AverageIOPS,
+		ServerBusyPercentage:   js.ServerBusyPercentage,
+		NetworkErrorPercentage: js.NetworkErrorPercentage,
+		ThrottledRequests:      js.ThrottledRequests,
+	}
+	if !w.lastWritten.IsZero() && elapsed > 0 && js.BytesOverWire >= w.lastBytesOverWire {
+		snapshot.ThroughputMbps = float64(js.BytesOverWire-w.lastBytesOverWire) * 8 / (1000 * 1000) / elapsed.Seconds()
+	}
+	w.lastWritten = now
+	w.lastBytesOverWire = js.BytesOverWire
+
+	err := w.write(snapshot)
+	if err != nil && !w.failed {
+		w.failed = true
+		return err
+	}
+	return nil
+}
+
+// write replaces the file, by renaming a new one over it, so that readers never see a partly-written snapshot
+func (w *metricsSnapshotWriter) write(snapshot JobMetricsSnapshot) error {
+	b, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempPath := w.path + ".tmp"
+	if err = ioutil.WriteFile(tempPath, b, common.DEFAULT_FILE_PERM); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, w.path)
+}
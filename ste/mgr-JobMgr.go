@@ -77,9 +77,11 @@ type IJobMgr interface {
 	getInMemoryTransitJobState() InMemoryTransitJobState      // get in memory transit job state saved in this job.
 	setInMemoryTransitJobState(state InMemoryTransitJobState) // set in memory transit job state saved in this job.
 	ChunkStatusLogger() common.ChunkStatusLogger
+	FlushLogs() // persist what has been logged so far, without closing the logs
 	HttpClient() *http.Client
 	PipelineNetworkStats() *pipelineNetworkStats
 	getOverwritePrompter() *overwritePrompter
+	getActiveHoursGate() *activeHoursGate
 	common.ILoggerCloser
 }
 
@@ -93,6 +95,8 @@ func newJobMgr(concurrency ConcurrencySettings, appLogger common.ILogger, jobID
 		httpClient:                    NewAzcopyHTTPClient(concurrency.MaxIdleConnections),
 		logger:                        common.NewJobLogger(jobID, level, appLogger, logFileFolder),
 		chunkStatusLogger:             common.NewChunkStatusLogger(jobID, cpuMon, logFileFolder, enableChunkLogOutput),
+		metricsSnapshots:              newMetricsSnapshotWriter(jobID, logFileFolder),
+		md5Mismatches:                 newMd5MismatchRecorder(),
 		concurrency:                   concurrency,
 		overwritePrompter:             newOverwritePrompter(),
 		pipelineNetworkStats:          newPipelineNetworkStats(JobsAdmin.(*jobsAdmin).concurrencyTuner), // let the stats coordinate with the concurrency tuner
@@ -100,6 +104,10 @@ func newJobMgr(concurrency ConcurrencySettings, appLogger common.ILogger, jobID
 		initMu:                        &sync.Mutex{},
 		jobPartProgress:               jobPartProgressCh,
 		/*Other fields remain zero-value until this job is scheduled */}
+	jm.activeHoursGate = newActiveHoursGate(func(msg string) {
+		jm.Log(pipeline.LogInfo, msg)
+		common.GetLifecycleMgr().Info(msg)
+	})
 	jm.reset(appCtx, commandString)
 	jm.logJobsAdminMessages()
 	go jm.reportJobPartDoneHandler()
@@ -110,6 +118,19 @@ func (jm *jobMgr) getOverwritePrompter() *overwritePrompter {
 	return jm.overwritePrompter
 }
 
+func (jm *jobMgr) setCancelledSources(m transferSourceMatcher) {
+	jm.cancelledSources.Store(m)
+}
+
+func (jm *jobMgr) getCancelledSources() transferSourceMatcher {
+	m, _ := jm.cancelledSources.Load().(transferSourceMatcher)
+	return m
+}
+
+func (jm *jobMgr) getActiveHoursGate() *activeHoursGate {
+	return jm.activeHoursGate
+}
+
 func (jm *jobMgr) reset(appCtx context.Context, commandString string) IJobMgr {
 	jm.logger.OpenLog()
 	// log the user given command to the job log file.
@@ -123,6 +144,7 @@ func (jm *jobMgr) reset(appCtx context.Context, commandString string) IJobMgr {
 	atomic.StoreUint64(&jm.atomicNumberOfBytesCovered, 0)
 	atomic.StoreUint64(&jm.atomicTotalBytesToXfer, 0)
 	jm.partsDone = 0
+	atomic.StoreInt32(&jm.atomicPauseCompleteIndicator, 0)
 	return jm
 }
 
@@ -131,8 +153,16 @@ func (jm *jobMgr) logConcurrencyParameters() {
 
 	jm.logger.Log(level, fmt.Sprintf("Number of CPUs: %d", runtime.NumCPU()))
 	// TODO: label max file buffer ram with how we obtained it (env var or default)
-	jm.logger.Log(level, fmt.Sprintf("Max file buffer RAM %.3f GB",
-		float32(JobsAdmin.(*jobsAdmin).cacheLimiter.Limit())/(1024*1024*1024)))
+	hardLimitMessage := ""
+	if JobsAdmin.(*jobsAdmin).isMemoryHardLimited {
+		hardLimitMessage = " (hard limit)"
+	}
+	jm.logger.Log(level, fmt.Sprintf("Max file buffer RAM %.3f GB%s",
+		float32(JobsAdmin.(*jobsAdmin).cacheLimiter.Limit())/(1024*1024*1024), hardLimitMessage))
+
+	if opsPerSecond := JobsAdmin.(*jobsAdmin).opsLimiter.opsPerSecond; opsPerSecond > 0 {
+		jm.logger.Log(level, fmt.Sprintf("Max requests per second: %d", opsPerSecond))
+	}
 
 	dynamicMessage := ""
 	if jm.concurrency.AutoTuneMainPool() {
@@ -161,6 +191,11 @@ func (jm *jobMgr) logConcurrencyParameters() {
 
 	jm.logger.Log(level, fmt.Sprintf("Max open files when downloading: %d (auto-computed)",
 		jm.concurrency.MaxOpenDownloadFiles))
+
+	jm.logger.Log(level, fmt.Sprintf("Retries: %s", JobsAdmin.(*jobsAdmin).retrySettings.GetDescription()))
+
+	jm.logger.Log(level, fmt.Sprintf("Flush transfer status to plan files: %s",
+		JobsAdmin.(*jobsAdmin).planCheckpointer.interval.GetDescription()))
 }
 
 // jobMgrInitState holds one-time init structures (such as SIPM), that initialize when the first part is added.
@@ -184,6 +219,10 @@ type jobMgr struct {
 	atomicAllTransfersScheduled     int32
 	atomicFinalPartOrderedIndicator int32
 	atomicTransferDirection         common.TransferDirection
+	// atomicResumedIndicator is set when this run of the job was started by resuming it
+	atomicResumedIndicator int32
+	// atomicPauseCompleteIndicator is set once all the job's transfers have stopped, after it was paused
+	atomicPauseCompleteIndicator int32
 
 	concurrency          ConcurrencySettings
 	logger               common.ILoggerResetable
@@ -213,6 +252,18 @@ type jobMgr struct {
 	// only a single instance of the prompter is needed for all transfers
 	overwritePrompter *overwritePrompter
 
+	// holds back the job's requests outside its active hours
+	activeHoursGate *activeHoursGate
+
+	// writes the job's metrics file, for monitoring tools
+	metricsSnapshots *metricsSnapshotWriter
+
+	// the downloads whose data didn't match the MD5 stored at the source, for the job summary
+	md5Mismatches *md5MismatchRecorder
+
+	// the sources of the transfers that the user has cancelled, so that parts scheduled later can leave them out
+	cancelledSources atomic.Value // transferSourceMatcher
+
 	// must have a single instance of this, for the whole job
 	folderCreationTracker common.FolderCreationTracker
 
@@ -329,7 +380,7 @@ func (jm *jobMgr) TryGetPerformanceAdvice(bytesInJob uint64, filesInJob uint32,
 
 	dir := jm.atomicTransferDirection.AtomicLoad()
 	isToAzureFiles := fromTo.To() == common.ELocation.File()
-	a := NewPerformanceAdvisor(jm.pipelineNetworkStats, ja.commandLineMbpsCap, int64(megabitsPerSec), finalReason, finalConcurrency, dir, averageBytesPerFile, isToAzureFiles)
+	a := NewPerformanceAdvisor(jm.pipelineNetworkStats, ja.pacer.capMbps(), int64(megabitsPerSec), finalReason, finalConcurrency, dir, averageBytesPerFile, isToAzureFiles)
 	return a.GetAdvice()
 }
 
@@ -447,6 +498,7 @@ func (jm *jobMgr) IncludeExclude() (map[string]int, map[string]int) {
 // ScheduleTransfers schedules this job part's transfers. It is called when a new job part is ordered & is also called to resume a paused Job
 func (jm *jobMgr) ResumeTransfers(appCtx context.Context) {
 	jm.reset(appCtx, "")
+	atomic.StoreInt32(&jm.atomicResumedIndicator, 1)
 	// Since while creating the JobMgr, atomicAllTransfersScheduled is set to true
 	// reset it to false while resuming it
 	//jm.ResetAllTransfersScheduled()
@@ -456,6 +508,41 @@ func (jm *jobMgr) ResumeTransfers(appCtx context.Context) {
 	})
 }
 
+// wasResumed returns whether this run of the job was started by resuming it
+func (jm *jobMgr) wasResumed() bool {
+	return atomic.LoadInt32(&jm.atomicResumedIndicator) == 1
+}
+
+// isPaused returns whether the job has been paused, either by this process or, with "jobs pause", by another one
+func (jm *jobMgr) isPaused() bool {
+	part0, ok := jm.jobPartMgrs.Get(0)
+	return ok && part0.Plan().JobStatus() == common.EJobStatus.Paused()
+}
+
+// stopForPause stops the job's transfers, once it has been paused, and returns whether they have all stopped yet.
+// It's needed because "jobs pause" runs in a different process, so all it can do is record the new
+// status in the plan file. We find out about that when the front end next asks for the job's progress
+func (jm *jobMgr) stopForPause() bool {
+	if jm.ctx.Err() == nil {
+		if jm.ShouldLog(pipeline.LogInfo) {
+			jm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v has been paused. Stopping its transfers", jm.jobID))
+		}
+		jm.Cancel()
+	}
+	return atomic.LoadInt32(&jm.atomicPauseCompleteIndicator) == 1
+}
+
+// stopForCancel stops the job's transfers, once it is being cancelled. Like stopForPause, it's needed because the
+// job may have been cancelled by another process, which can only record the new status in the plan file
+func (jm *jobMgr) stopForCancel() {
+	if jm.ctx.Err() == nil {
+		if jm.ShouldLog(pipeline.LogInfo) {
+			jm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v is being cancelled. Stopping its transfers", jm.jobID))
+		}
+		jm.Cancel()
+	}
+}
+
 // AllTransfersScheduled returns whether Job has completely resumed or not
 func (jm *jobMgr) AllTransfersScheduled() bool {
 	return atomic.LoadInt32(&jm.atomicAllTransfersScheduled) == 1
@@ -498,8 +585,8 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 		// JobPart 0 status is not changed (unless we are cancelling)
 		haveFinalPart = atomic.LoadInt32(&jm.atomicFinalPartOrderedIndicator) == 1
 		allKnownPartsDone := partsDone == jm.jobPartMgrs.Count()
-		isCancelling := jobStatus == common.EJobStatus.Cancelling()
-		shouldComplete := allKnownPartsDone && (haveFinalPart || isCancelling)
+		isStopping := jobStatus == common.EJobStatus.Cancelling() || jobStatus == common.EJobStatus.Paused()
+		shouldComplete := allKnownPartsDone && (haveFinalPart || isStopping)
 		if shouldComplete {
 			break
 		} //Else log and wait for next part to complete
@@ -526,11 +613,19 @@ func (jm *jobMgr) reportJobPartDoneHandler() {
 		if shouldLog {
 			jm.Log(pipeline.LogInfo, fmt.Sprintf("%s %v successfully cancelled", partDescription, jm.jobID))
 		}
+	case common.EJobStatus.Paused():
+		// the status stays as it is, so that "jobs resume" can carry on from here
+		atomic.StoreInt32(&jm.atomicPauseCompleteIndicator, 1)
+		if shouldLog {
+			jm.Log(pipeline.LogInfo, fmt.Sprintf("%s %v successfully paused", partDescription, jm.jobID))
+		}
 	case common.EJobStatus.InProgress():
 		part0Plan.SetJobStatus((common.EJobStatus).EnhanceJobStatusInfo(jobProgressInfo.transfersSkipped > 0,
 			jobProgressInfo.transfersFailed > 0,
 			jobProgressInfo.transfersCompleted > 0))
 	}
+	// so that the job's final status is on disk, before we report it
+	JobsAdmin.(*jobsAdmin).planCheckpointer.partDone(jobPart0Mgr.(*jobPartMgr))
 
 	jm.chunkStatusLogger.FlushLog() // TODO: remove once we sort out what will be calling CloseLog (currently nothing)
 }
@@ -561,6 +656,10 @@ func (jm *jobMgr) CloseLog() {
 	jm.chunkStatusLogger.FlushLog()
 }
 
+func (jm *jobMgr) FlushLogs() {
+	jm.chunkStatusLogger.FlushLog() // the job log itself is unbuffered, so only the chunk log needs flushing
+}
+
 func (jm *jobMgr) ChunkStatusLogger() common.ChunkStatusLogger {
 	return jm.chunkStatusLogger
 }
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -35,6 +36,8 @@ type IJobPartMgr interface {
 	BlobTypeOverride() common.BlobType
 	BlobTiers() (blockBlobTier common.BlockBlobTier, pageBlobTier common.PageBlobTier)
 	ShouldPutMd5() bool
+	ShouldPutCrc64() bool
+	ShouldPutSha256() bool
 	SAS() (string, string)
 	//CancelJob()
 	Close()
@@ -157,12 +160,18 @@ func NewBlobPipeline(c azblob.Credential, o azblob.PipelineOptions, r XferRetryO
 	f := []pipeline.Factory{
 		azblob.NewTelemetryPolicyFactory(o.Telemetry),
 		azblob.NewUniqueRequestIDPolicyFactory(),
+		newActiveHoursPolicyFactory(),       // hold requests back outside the job's active hours
 		NewBlobXferRetryPolicyFactory(r),    // actually retry the operation
 		newRetryNotificationPolicyFactory(), // record that a retry status was returned
+		newRetryCountPolicyFactory(),        // count the retries, so they can be reported if the transfer fails
+		newOpsRateLimitPolicyFactory(),      // keep to the cap on requests per second, if there is one
+		newStallDetectionPolicyFactory(),    // let the stall watchdog cancel tries that have hung
 		c,
 		pipeline.MethodFactoryMarker(), // indicates at what stage in the pipeline the method factory is invoked
 		//NewPacerPolicyFactory(p),
 		NewVersionPolicyFactory(),
+		newBlobTagsPolicyFactory(),           // after the version policy, since it may need to raise the version
+		newTransactionalCrc64PolicyFactory(), // likewise
 		NewRequestLogPolicyFactory(RequestLogOptions{LogWarningIfTryOverThreshold: o.RequestLog.LogWarningIfTryOverThreshold}),
 		newXferStatsPolicyFactory(statsAcc),
 	}
@@ -179,8 +188,12 @@ func NewBlobFSPipeline(c azbfs.Credential, o azbfs.PipelineOptions, r XferRetryO
 	f := []pipeline.Factory{
 		azbfs.NewTelemetryPolicyFactory(o.Telemetry),
 		azbfs.NewUniqueRequestIDPolicyFactory(),
+		newActiveHoursPolicyFactory(),       // hold requests back outside the job's active hours
 		NewBFSXferRetryPolicyFactory(r),     // actually retry the operation
 		newRetryNotificationPolicyFactory(), // record that a retry status was returned
+		newRetryCountPolicyFactory(),        // count the retries, so they can be reported if the transfer fails
+		newOpsRateLimitPolicyFactory(),      // keep to the cap on requests per second, if there is one
+		newStallDetectionPolicyFactory(),    // let the stall watchdog cancel tries that have hung
 	}
 
 	f = append(f, c)
@@ -194,7 +207,7 @@ func NewBlobFSPipeline(c azbfs.Credential, o azbfs.PipelineOptions, r XferRetryO
 }
 
 // NewFilePipeline creates a Pipeline using the specified credentials and options.
-func NewFilePipeline(c azfile.Credential, o azfile.PipelineOptions, r azfile.RetryOptions, p pacer, client *http.Client, statsAcc *pipelineNetworkStats) pipeline.Pipeline {
+func NewFilePipeline(c azfile.Credential, o azfile.PipelineOptions, r XferRetryOptions, p pacer, client *http.Client, statsAcc *pipelineNetworkStats) pipeline.Pipeline {
 	if c == nil {
 		panic("c can't be nil")
 	}
@@ -202,8 +215,12 @@ func NewFilePipeline(c azfile.Credential, o azfile.PipelineOptions, r azfile.Ret
 	f := []pipeline.Factory{
 		azfile.NewTelemetryPolicyFactory(o.Telemetry),
 		azfile.NewUniqueRequestIDPolicyFactory(),
-		azfile.NewRetryPolicyFactory(r),     // actually retry the operation
+		newActiveHoursPolicyFactory(),       // hold requests back outside the job's active hours
+		NewFileXferRetryPolicyFactory(r),    // actually retry the operation
 		newRetryNotificationPolicyFactory(), // record that a retry status was returned
+		newRetryCountPolicyFactory(),        // count the retries, so they can be reported if the transfer fails
+		newOpsRateLimitPolicyFactory(),      // keep to the cap on requests per second, if there is one
+		newStallDetectionPolicyFactory(),    // let the stall watchdog cancel tries that have hung
 		c,
 		pipeline.MethodFactoryMarker(), // indicates at what stage in the pipeline the method factory is invoked
 		NewVersionPolicyFactory(),
@@ -248,11 +265,26 @@ type jobPartMgr struct {
 	// Additional data shared by all of this Job Part's transfers; initialized when this jobPartMgr is created
 	pageBlobTier common.PageBlobTier
 
+	// rules that set the tier of the block blobs whose paths match their patterns, over blockBlobTier
+	blockBlobTierRules common.BlockBlobTierRules
+
 	// Additional data shared by all of this Job Part's transfers; initialized when this jobPartMgr is created
 	putMd5 bool
 
+	// whether to send CRC64 checksums with each block, and store the CRC64 of the whole file in its metadata
+	putCrc64 bool
+
+	// whether to store the SHA-256 of the whole file in its metadata
+	putSha256 bool
+
 	metadata common.Metadata
 
+	// content types given by the user for file extensions, which take precedence over the built-in ones
+	contentTypeMap common.ContentTypeMap
+
+	// rules that set headers on the files whose paths match their patterns, over those in httpHeaders
+	headerRules common.HeaderRules
+
 	blobTypeOverride common.BlobType // User specified blob type
 
 	preserveLastModifiedTime bool
@@ -283,6 +315,10 @@ type jobPartMgr struct {
 	atomicTransfersCompleted uint32
 	atomicTransfersFailed    uint32
 	atomicTransfersSkipped   uint32
+
+	// the transfers that have been scheduled, and haven't finished yet, so that the user can cancel them
+	liveTransfersMu sync.Mutex
+	liveTransfers   map[uint32]*jobPartTransferMgr
 }
 
 func (jpm *jobPartMgr) getOverwritePrompter() *overwritePrompter {
@@ -329,6 +365,8 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 	}
 
 	jpm.putMd5 = dstData.PutMd5
+	jpm.putCrc64 = dstData.PutCrc64
+	jpm.putSha256 = dstData.PutSha256
 	jpm.blockBlobTier = dstData.BlockBlobTier
 	jpm.pageBlobTier = dstData.PageBlobTier
 
@@ -342,20 +380,38 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 		}
 	}
 
+	var err error
+	jpm.contentTypeMap, err = common.ToContentTypeMap(string(dstData.ContentTypeMap[:dstData.ContentTypeMapLength]))
+	if err != nil {
+		panic(err)
+	}
+	jpm.headerRules, err = common.ToHeaderRules(string(dstData.HeaderRules[:dstData.HeaderRulesLength]))
+	if err != nil {
+		panic(err)
+	}
+	jpm.blockBlobTierRules, err = common.ParseBlockBlobTierRules(string(dstData.BlockBlobTierRules[:dstData.BlockBlobTierRulesLength]))
+	if err != nil {
+		panic(err)
+	}
+
 	jpm.preserveLastModifiedTime = plan.DstLocalData.PreserveLastModifiedTime
 
 	jpm.blobTypeOverride = plan.DstBlobData.BlobType
 	jpm.newJobXfer = computeJobXfer(plan.FromTo, plan.DstBlobData.BlobType)
 
 	jpm.priority = plan.Priority
+	jpm.jobMgr.getActiveHoursGate().setWindow(plan.ActiveHours)
 
 	jpm.createPipelines(jobCtx) // pipeline is created per job part manager
 
+	// leave out the transfers that the user cancelled while this part was waiting to be scheduled
+	jpm.cancelTransfersByUser(jpm.jobMgr.(*jobMgr).getCancelledSources())
+
 	// *** Schedule this job part's transfers ***
 	for t := uint32(0); t < plan.NumTransfers; t++ {
 		jppt := plan.Transfer(t)
 		ts := jppt.TransferStatus()
-		if ts == common.ETransferStatus.Success() {
+		if ts == common.ETransferStatus.Success() || ts == common.ETransferStatus.CancelledByUser() {
 			jpm.ReportTransferDone(ts) // Don't schedule an already-completed/failed transfer
 			continue
 		}
@@ -377,6 +433,11 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 			//TODO: insert the factory func interface in jptm.
 			// numChunks will be set by the transfer's prologue method
 		}
+		jptm.ctx = withActiveHours(withStallWatching(withRetryCounting(transferCtx, jptm), jptm), jpm.jobMgr.getActiveHoursGate())
+		jpm.addLiveTransfer(jptm)
+		if jppt.TransferStatus() == common.ETransferStatus.CancelledByUser() {
+			jptm.Cancel() // it was cancelled just now, before it could be found in the live transfers
+		}
 		if jpm.ShouldLog(pipeline.LogInfo) {
 			jpm.Log(pipeline.LogInfo, fmt.Sprintf("scheduling JobID=%v, Part#=%d, Transfer#=%d, priority=%v", plan.JobID, plan.PartNum, t, plan.Priority))
 		}
@@ -397,6 +458,46 @@ func (jpm *jobPartMgr) ScheduleTransfers(jobCtx context.Context) {
 	}
 }
 
+func (jpm *jobPartMgr) addLiveTransfer(jptm *jobPartTransferMgr) {
+	jpm.liveTransfersMu.Lock()
+	defer jpm.liveTransfersMu.Unlock()
+	if jpm.liveTransfers == nil {
+		jpm.liveTransfers = make(map[uint32]*jobPartTransferMgr)
+	}
+	jpm.liveTransfers[jptm.transferIndex] = jptm
+}
+
+func (jpm *jobPartMgr) removeLiveTransfer(jptm *jobPartTransferMgr) {
+	jpm.liveTransfersMu.Lock()
+	defer jpm.liveTransfersMu.Unlock()
+	delete(jpm.liveTransfers, jptm.transferIndex)
+}
+
+// cancelTransfersByUser cancels the part's unfinished transfers that the matcher matches, and returns how many it cancelled.
+// They are marked as cancelled by the user in the plan, so that they stay cancelled if the job is resumed
+func (jpm *jobPartMgr) cancelTransfersByUser(matcher transferSourceMatcher) int {
+	if len(matcher) == 0 {
+		return 0
+	}
+	plan := jpm.Plan()
+	cancelled := 0
+	for t := uint32(0); t < plan.NumTransfers; t++ {
+		if !matcher.matches(plan, t) || !plan.Transfer(t).cancelByUser() {
+			continue
+		}
+		cancelled++
+
+		jpm.liveTransfersMu.Lock()
+		jptm, isLive := jpm.liveTransfers[t]
+		jpm.liveTransfersMu.Unlock()
+		if isLive {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "Cancelled at the user's request.")
+			jptm.Cancel()
+		}
+	}
+	return cancelled
+}
+
 func (jpm *jobPartMgr) ScheduleChunks(chunkFunc chunkFunc) {
 	JobsAdmin.ScheduleChunk(jpm.priority, chunkFunc)
 }
@@ -428,17 +529,16 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 		Cancel:   jpm.jobMgr.Cancel,
 	}
 	// TODO: Consider to remove XferRetryPolicy and Options?
-	xferRetryOption := XferRetryOptions{
-		Policy:        0,
-		MaxTries:      UploadMaxTries, // TODO: Consider to unify options.
-		TryTimeout:    UploadTryTimeout,
-		RetryDelay:    UploadRetryDelay,
-		MaxRetryDelay: UploadMaxRetryDelay}
+	retrySettings := JobsAdmin.(*jobsAdmin).retrySettings
+	xferRetryOption := retrySettings.xferRetryOptions()
 
 	var statsAccForSip *pipelineNetworkStats = nil // we don't accumulate stats on the source info provider
 
 	// Create source info provider's pipeline for S2S copy.
-	if fromTo == common.EFromTo.BlobBlob() || fromTo == common.EFromTo.BlobFile() {
+	// Plain HTTP(S) sources use it too, for their HEAD requests, but without the timeout parameter that only Azure Storage understands.
+	if fromTo == common.EFromTo.BlobBlob() || fromTo == common.EFromTo.BlobFile() || fromTo.From() == common.ELocation.HTTP() {
+		sipRetryOption := xferRetryOption
+		sipRetryOption.NoServerTimeout = fromTo.From() == common.ELocation.HTTP()
 		jpm.sourceProviderPipeline = NewBlobPipeline(
 			azblob.NewAnonymousCredential(),
 			azblob.PipelineOptions{
@@ -447,7 +547,7 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 					Value: userAgent,
 				},
 			},
-			xferRetryOption,
+			sipRetryOption,
 			jpm.pacer,
 			jpm.jobMgr.HttpClient(),
 			statsAccForSip)
@@ -462,13 +562,7 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 					Value: userAgent,
 				},
 			},
-			azfile.RetryOptions{
-				Policy:        azfile.RetryPolicyExponential,
-				MaxTries:      UploadMaxTries,
-				TryTimeout:    UploadTryTimeout,
-				RetryDelay:    UploadRetryDelay,
-				MaxRetryDelay: UploadMaxRetryDelay,
-			},
+			xferRetryOption,
 			jpm.pacer,
 			jpm.jobMgr.HttpClient(),
 			statsAccForSip)
@@ -477,7 +571,7 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 	// Create pipeline for data transfer.
 	switch fromTo {
 	case common.EFromTo.BlobTrash(), common.EFromTo.BlobLocal(), common.EFromTo.LocalBlob(), common.EFromTo.BenchmarkBlob(),
-		common.EFromTo.BlobBlob(), common.EFromTo.FileBlob(), common.EFromTo.S3Blob():
+		common.EFromTo.BlobBlob(), common.EFromTo.FileBlob(), common.EFromTo.S3Blob(), common.EFromTo.SFTPBlob(), common.EFromTo.HTTPBlob():
 		credential := common.CreateBlobCredential(ctx, credInfo, credOption)
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, credential type: %v", jpm.Plan().JobID, credInfo.CredentialType))
 		jpm.pipeline = NewBlobPipeline(
@@ -511,7 +605,7 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 			jpm.jobMgr.PipelineNetworkStats())
 	// Create pipeline for Azure File.
 	case common.EFromTo.FileTrash(), common.EFromTo.FileLocal(), common.EFromTo.LocalFile(), common.EFromTo.BenchmarkFile(),
-		common.EFromTo.FileFile(), common.EFromTo.BlobFile():
+		common.EFromTo.FileFile(), common.EFromTo.BlobFile(), common.EFromTo.S3File(), common.EFromTo.SFTPFile(), common.EFromTo.HTTPFile():
 		jpm.pipeline = NewFilePipeline(
 			azfile.NewAnonymousCredential(),
 			azfile.PipelineOptions{
@@ -520,16 +614,12 @@ func (jpm *jobPartMgr) createPipelines(ctx context.Context) {
 					Value: userAgent,
 				},
 			},
-			azfile.RetryOptions{
-				Policy:        azfile.RetryPolicyExponential,
-				MaxTries:      UploadMaxTries,
-				TryTimeout:    UploadTryTimeout,
-				RetryDelay:    UploadRetryDelay,
-				MaxRetryDelay: UploadMaxRetryDelay,
-			},
+			xferRetryOption,
 			jpm.pacer,
 			jpm.jobMgr.HttpClient(),
 			jpm.jobMgr.PipelineNetworkStats())
+	case common.EFromTo.LocalSFTP():
+		// SFTP is written to over SSH, with clients of its own, so there's no pipeline
 	default:
 		panic(fmt.Errorf("Unrecognized from-to: %q", fromTo.String()))
 	}
@@ -601,6 +691,11 @@ var builtinTypes = map[string]string{
 func (jpm *jobPartMgr) inferContentType(fullFilePath string, dataFileToXfer []byte) string {
 	fileExtension := filepath.Ext(fullFilePath)
 
+	// the user's own mapping comes first, since it's there to fix what the others get wrong
+	if contentType, ok := jpm.contentTypeMap.Lookup(fileExtension); ok {
+		return contentType
+	}
+
 	// short-circuit for common static website files
 	// mime.TypeByExtension takes the registry into account, which is most often undesirable in practice
 	if override, ok := builtinTypes[strings.ToLower(fileExtension)]; ok {
@@ -632,6 +727,14 @@ func (jpm *jobPartMgr) ShouldPutMd5() bool {
 	return jpm.putMd5
 }
 
+func (jpm *jobPartMgr) ShouldPutCrc64() bool {
+	return jpm.putCrc64
+}
+
+func (jpm *jobPartMgr) ShouldPutSha256() bool {
+	return jpm.putSha256
+}
+
 func (jpm *jobPartMgr) SAS() (string, string) {
 	return jpm.sourceSAS, jpm.destinationSAS
 }
@@ -666,7 +769,8 @@ func (jpm *jobPartMgr) updateJobPartProgress(status common.TransferStatus) {
 		atomic.AddUint32(&jpm.atomicTransfersCompleted, 1)
 	case common.ETransferStatus.Failed(), common.ETransferStatus.BlobTierFailure():
 		atomic.AddUint32(&jpm.atomicTransfersFailed, 1)
-	case common.ETransferStatus.SkippedEntityAlreadyExists(), common.ETransferStatus.SkippedBlobHasSnapshots():
+	case common.ETransferStatus.SkippedEntityAlreadyExists(), common.ETransferStatus.SkippedBlobHasSnapshots(),
+		common.ETransferStatus.CancelledByUser():
 		atomic.AddUint32(&jpm.atomicTransfersSkipped, 1)
 	case common.ETransferStatus.Cancelled():
 	default:
@@ -678,6 +782,7 @@ func (jpm *jobPartMgr) updateJobPartProgress(status common.TransferStatus) {
 func (jpm *jobPartMgr) ReportTransferDone(status common.TransferStatus) (transfersDone uint32) {
 	transfersDone = atomic.AddUint32(&jpm.atomicTransfersDone, 1)
 	jpm.updateJobPartProgress(status)
+	checkpointer := JobsAdmin.(*jobsAdmin).planCheckpointer
 
 	//Add a safety count-check
 
@@ -686,22 +791,35 @@ func (jpm *jobPartMgr) ReportTransferDone(status common.TransferStatus) (transfe
 		jpm.Log(pipeline.LogInfo, fmt.Sprintf("JobID=%v, Part#=%d, TransfersDone=%d of %d", plan.JobID, plan.PartNum, transfersDone, plan.NumTransfers))
 	}
 	if transfersDone == jpm.planMMF.Plan().NumTransfers {
+		checkpointer.partDone(jpm)
 		jppi := jobPartProgressInfo{
 			transfersCompleted: int(atomic.LoadUint32(&jpm.atomicTransfersCompleted)),
 			transfersSkipped:   int(atomic.LoadUint32(&jpm.atomicTransfersSkipped)),
 			transfersFailed:    int(atomic.LoadUint32(&jpm.atomicTransfersFailed)),
 		}
 		jpm.jobMgr.ReportJobPartDone(jppi)
+	} else {
+		checkpointer.transferDone(jpm)
 	}
 	return transfersDone
 }
 
+// flushPlan checkpoints the plan file, so that the status of every transfer so far would survive a crash
+func (jpm *jobPartMgr) flushPlan() {
+	if err := jpm.planMMF.Flush(); err != nil {
+		jpm.Log(pipeline.LogWarning, fmt.Sprintf("failed to flush the plan file %s: %v", jpm.filename, err))
+	}
+}
+
 //func (jpm *jobPartMgr) Cancel() { jpm.jobMgr.Cancel() }
 func (jpm *jobPartMgr) Close() {
 	jpm.planMMF.Unmap()
 	// Clear other fields to all for GC
 	jpm.httpHeaders = common.ResourceHTTPHeaders{}
 	jpm.metadata = common.Metadata{}
+	jpm.contentTypeMap = nil
+	jpm.headerRules = nil
+	jpm.blockBlobTierRules = nil
 	jpm.preserveLastModifiedTime = false
 	// TODO: Delete file?
 	/*if err := os.Remove(jpm.planFile.Name()); err != nil {
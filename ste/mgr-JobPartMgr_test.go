@@ -21,9 +21,13 @@
 package ste
 
 import (
-	chk "gopkg.in/check.v1"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
 )
 
 // Hookup to the testing framework
@@ -60,3 +64,30 @@ func (s *jobPartMgrTestSuite) TestInferContentType(c *chk.C) {
 		c.Assert(strings.Contains(contentType, expectedType), chk.Equals, true)
 	}
 }
+
+func (s *jobPartMgrTestSuite) TestInferContentTypeWithUserMap(c *chk.C) {
+	partMgr := jobPartMgr{contentTypeMap: common.ContentTypeMap{
+		".md":  "text/markdown; charset=utf-8",
+		".css": "text/css; charset=utf-8",
+	}}
+
+	// the user's types win, even over the built-in ones, and the extension's case doesn't matter
+	c.Assert(partMgr.inferContentType("/usr/foo/README.MD", nil), chk.Equals, "text/markdown; charset=utf-8")
+	c.Assert(partMgr.inferContentType("/usr/foo/site.css", nil), chk.Equals, "text/css; charset=utf-8")
+	c.Assert(partMgr.inferContentType("/usr/foo/index.html", nil), chk.Equals, "text/html")
+}
+
+func (s *jobPartMgrTestSuite) TestSniffContent(c *chk.C) {
+	partMgr := jobPartMgr{}
+	file, err := ioutil.TempFile("", "sniff")
+	c.Assert(err, chk.IsNil)
+	defer os.Remove(file.Name())
+	_, err = file.Write(append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), make([]byte, 1000)...))
+	c.Assert(err, chk.IsNil)
+	c.Assert(file.Close(), chk.IsNil)
+
+	sniffed, err := sniffContent(file.Name())
+	c.Assert(err, chk.IsNil)
+	c.Assert(sniffed, chk.HasLen, sniffLen)
+	c.Assert(partMgr.inferContentType("/usr/foo/no/extension", sniffed), chk.Equals, "image/png")
+}
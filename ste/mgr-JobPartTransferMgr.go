@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -25,6 +26,10 @@ type IJobPartTransferMgr interface {
 	PreserveLastModifiedTime() (time.Time, bool)
 	ShouldPutMd5() bool
 	MD5ValidationOption() common.HashValidationOption
+	ShouldPutCrc64() bool
+	Crc64ValidationOption() common.HashValidationOption
+	ShouldPutSha256() bool
+	Sha256ValidationOption() common.HashValidationOption
 	BlobTypeOverride() common.BlobType
 	BlobTiers() (blockBlobTier common.BlockBlobTier, pageBlobTier common.PageBlobTier)
 	JobHasLowFileCount() bool
@@ -41,11 +46,16 @@ type IJobPartTransferMgr interface {
 	ShouldDecompress() bool
 	GetSourceCompressionType() (common.CompressionType, error)
 	ReportChunkDone(id common.ChunkID) (lastChunk bool, chunksDone uint32)
+	ReportWrittenBytes(n int64)
+	SavedBytes() int64
+	ReportSavedBytes(n int64)
+	ResumeDownloadAt(offset int64)
 	TransferStatusIgnoringCancellation() common.TransferStatus
 	SetStatus(status common.TransferStatus)
 	SetErrorCode(errorCode int32)
 	SetNumberOfChunks(numChunks uint32)
 	SetActionAfterLastChunk(f func())
+	SetCompressedSource(path string, size int64)
 	ReportTransferDone() uint32
 	RescheduleTransfer()
 	ScheduleChunks(chunkFunc chunkFunc)
@@ -55,6 +65,9 @@ type IJobPartTransferMgr interface {
 	IsLive() bool
 	IsDeadBeforeStart() bool
 	IsDeadInflight() bool
+	JobWasResumed() bool
+	JobIsPaused() bool
+	TransferIdentity() (jobID common.JobID, partNum PartNumber, transferIndex uint32)
 	// TODO: added for debugging purpose. remove later
 	OccupyAConnection()
 	// TODO: added for debugging purpose. remove later
@@ -86,16 +99,32 @@ type IJobPartTransferMgr interface {
 	SecurityInfoPersistenceManager() *securityInfoPersistenceManager
 	FolderDeletionManager() common.FolderDeletionManager
 	GetDestinationRoot() string
+	PreviousTransferToSameDestination() (status common.TransferStatus, destinationLocked bool, exists bool)
+	SetS2SCopyMethod(method common.S2SCopyMethod)
+	RecordMd5Mismatch(kept bool)
 }
 
 type TransferInfo struct {
-	BlockSize              int64
-	Source                 string
-	SourceSize             int64
-	Destination            string
-	EntityType             common.EntityType
-	PreserveSMBPermissions common.PreservePermissionsOption
-	PreserveSMBInfo        bool
+	BlockSize                int64
+	Source                   string
+	SourceSize               int64
+	Destination              string
+	EntityType               common.EntityType
+	PreserveSMBPermissions   common.PreservePermissionsOption
+	PreserveSMBInfo          bool
+	PreservePOSIXPermissions bool
+	PreserveFileTimes        bool
+	PreserveXattrs           bool
+	SniffContentType         bool
+	CompressOnUpload         bool
+	PackOnUpload             common.PackFormat // the source is a directory, whose files are uploaded as one archive
+	UnpackOnDownload         bool
+	UploadVHD                bool
+	AppendToBlob             bool
+	// set once the source has been gzipped, or packed, for upload, after which SourceSize is the size of that copy
+	CompressedSource string
+	UncompressedSize int64
+	BlobTags         common.BlobTags // given by the user, for all the blobs of the job
 
 	// Transfer info for S2S copy
 	SrcProperties
@@ -103,11 +132,21 @@ type TransferInfo struct {
 	S2SSourceChangeValidation      bool
 	DestLengthValidation           bool
 	S2SInvalidMetadataHandleOption common.InvalidMetadataHandleOption
+	S2SPreserveBlobTags            bool
+	S2SMetadataMerge               common.MetadataMergeOption
+	S2SCopyMethod                  common.S2SCopyMethod
 
 	// Blob
 	SrcBlobType    azblob.BlobType       // used for both S2S and for downloads to local from blob
 	S2SSrcBlobTier azblob.AccessTierType // AccessTierType (string) is used to accommodate service-side support matrix change.
 
+	// Archived blob sources
+	ArchivedSource    common.ArchivedSourceOption
+	RehydratePriority common.RehydratePriority
+
+	// set if the source blob was soft-deleted when it was listed
+	SrcBlobDeleted bool
+
 	// NumChunks is the number of chunks in which transfer will be split into while uploading the transfer.
 	// NumChunks is not used in case of AppendBlob transfer.
 	NumChunks uint16
@@ -145,6 +184,7 @@ func (i TransferInfo) entityTypeLogIndicator() string {
 type SrcProperties struct {
 	SrcHTTPHeaders common.ResourceHTTPHeaders // User for S2S copy, where per transfer's src properties need be set in destination.
 	SrcMetadata    common.Metadata
+	SrcBlobTags    common.BlobTags // only got when they are to be copied
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -157,6 +197,9 @@ type jobPartTransferMgr struct {
 	// (hard to infer from atomicChunksDone because that counts both successes and failures)
 	atomicSuccessfulBytes int64
 
+	// when bytes last moved for this transfer, as Unix nanoseconds. Used by the stall watchdog
+	atomicLastActivityNanos int64
+
 	// NumberOfChunksDone represents the number of chunks of a transfer
 	// which are either completed or failed.
 	// NumberOfChunksDone determines the final cancellation or completion of a transfer
@@ -165,12 +208,22 @@ type jobPartTransferMgr struct {
 	// used defensively to protect against accidental double counting
 	atomicCompletionIndicator uint32
 
+	// set once our bytes have been removed from the job-wide count of bytes in active files,
+	// after which no more committed bytes may be added to it
+	atomicProgressClosedIndicator uint32
+
 	// used to show whether we have started doing things that may affect the destination
 	atomicDestModifiedIndicator uint32
 
 	// used to show whether THIS jptm holds the destination lock
 	atomicDestLockHeldIndicator uint32
 
+	// how many times requests for this transfer have been retried. Reported if the transfer fails
+	atomicRetryCount int32
+
+	// the requests in flight, so that the stall watchdog can cancel them
+	stallState transferStallState
+
 	jobPartMgr          IJobPartMgr // Refers to the "owning" Job Part
 	jobPartPlanTransfer *JobPartPlanTransfer
 	transferIndex       uint32
@@ -187,6 +240,13 @@ type jobPartTransferMgr struct {
 
 	actionAfterLastChunk func()
 
+	// for the transfer events: when the transfer started, and if it failed, why
+	startTime     time.Time
+	failureReason string
+
+	// how the data was copied, for service to service copies of block blobs, which have a choice
+	atomicS2SCopyMethod uint32
+
 	/*
 		@Parteek removed 3/23 morning, as jeff ad equivalent
 		// transfer chunks are put into this channel and execution engine takes chunk out of this channel.
@@ -206,9 +266,31 @@ func (jptm *jobPartTransferMgr) FromTo() common.FromTo {
 }
 
 func (jptm *jobPartTransferMgr) StartJobXfer() {
+	jptm.startTime = time.Now()
+	if common.GetLifecycleMgr().TransferEventsEnabled() {
+		jptm.reportTransferEvent(common.ETransferEventType.Started(), jptm.Info().SourceSize)
+	}
 	jptm.jobPartMgr.StartJobXfer(jptm)
 }
 
+func (jptm *jobPartTransferMgr) reportTransferEvent(eventType common.TransferEventType, bytes int64) {
+	event := common.TransferEvent{
+		JobID:       jptm.jobPartMgr.Plan().JobID,
+		EventType:   eventType,
+		Source:      jptm.Info().Source,
+		Destination: jptm.Info().Destination,
+		Bytes:       bytes,
+	}
+	if eventType != common.ETransferEventType.Started() {
+		event.ElapsedTimeSeconds = time.Since(jptm.startTime).Seconds()
+		event.ErrorMessage = jptm.failureReason
+		if method := common.S2SCopyMethod(atomic.LoadUint32(&jptm.atomicS2SCopyMethod)); method != common.ES2SCopyMethod.Auto() {
+			event.CopyMethod = method.String()
+		}
+	}
+	common.GetLifecycleMgr().TransferEvent(event)
+}
+
 func (jptm *jobPartTransferMgr) GetOverwriteOption() common.OverwriteOption {
 	return jptm.jobPartMgr.GetOverwriteOption()
 }
@@ -230,6 +312,25 @@ func (jptm *jobPartTransferMgr) GetSourceCompressionType() (common.CompressionTy
 	return common.GetCompressionType(encoding)
 }
 
+// autoBlockSize picks the block size for a file of the given size, when the user didn't choose one.
+// We need to set the blockSize in such way that number of blocks per blob
+// does not exceeds 50000 (max number of block per blob)
+func autoBlockSize(sourceSize int64) int64 {
+	blockSize := int64(common.DefaultBlockBlobBlockSize)
+	for ; int64(getNumChunks(sourceSize, blockSize)) > common.MaxNumberOfBlocksPerBlob; blockSize = 2 * blockSize {
+		if blockSize > common.BlockSizeThreshold {
+			/*
+			 * For a RAM usage of 0.5G/core, we would have 4G memory on typical 8 core device, meaning at a blockSize of 256M,
+			 * we can have 4 blocks in core, waiting for a disk or n/w operation. Any higher block size would *sort of*
+			 * serialize n/w and disk operations, and is better avoided.
+			 */
+			// round up, so that the last few bytes don't spill over into block number 50001
+			return (sourceSize + common.MaxNumberOfBlocksPerBlob - 1) / common.MaxNumberOfBlocksPerBlob
+		}
+	}
+	return blockSize
+}
+
 func (jptm *jobPartTransferMgr) Info() TransferInfo {
 	if jptm.transferInfo != nil {
 		return *jptm.transferInfo
@@ -239,9 +340,13 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 	src, dst, _ := plan.TransferSrcDstStrings(jptm.transferIndex)
 	dstBlobData := plan.DstBlobData
 
-	srcHTTPHeaders, srcMetadata, srcBlobType, srcBlobTier, s2sGetPropertiesInBackend, DestLengthValidation, s2sSourceChangeValidation, s2sInvalidMetadataHandleOption, entityType, versionID :=
+	srcHTTPHeaders, srcMetadata, srcBlobType, srcBlobTier, s2sGetPropertiesInBackend, DestLengthValidation, s2sSourceChangeValidation, s2sInvalidMetadataHandleOption, entityType, versionID, snapshotID :=
 		plan.TransferSrcPropertiesAndMetadata(jptm.transferIndex)
 	srcSAS, dstSAS := jptm.jobPartMgr.SAS()
+	blobTags, err := common.ToCommonBlobTagsMap(string(dstBlobData.BlobTags[:dstBlobData.BlobTagsLength]))
+	if err != nil {
+		panic(err)
+	}
 	// If the length of destination SAS is greater than 0
 	// it means the destination is remote url and destination SAS
 	// has been stripped from the destination before persisting it in
@@ -292,24 +397,25 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 		src = sURL.String()
 	}
 
+	if snapshotID != "" {
+		snapshotID = "snapshot=" + snapshotID
+		sURL, e := url.Parse(src)
+		if e != nil {
+			panic(e)
+		}
+		if len(sURL.RawQuery) > 0 {
+			sURL.RawQuery += "&" + snapshotID
+		} else {
+			sURL.RawQuery = snapshotID
+		}
+		src = sURL.String()
+	}
+
 	sourceSize := plan.Transfer(jptm.transferIndex).SourceSize
 	var blockSize = dstBlobData.BlockSize
-	// If the blockSize is 0, then User didn't provide any blockSize
-	// We need to set the blockSize in such way that number of blocks per blob
-	// does not exceeds 50000 (max number of block per blob)
+	// If the blockSize is 0, then User didn't provide any blockSize, so we pick one for this file
 	if blockSize == 0 {
-		blockSize = common.DefaultBlockBlobBlockSize
-		for ; uint32(sourceSize/blockSize) > common.MaxNumberOfBlocksPerBlob; blockSize = 2 * blockSize {
-			if blockSize > common.BlockSizeThreshold {
-				/*
-				 * For a RAM usage of 0.5G/core, we would have 4G memory on typical 8 core device, meaning at a blockSize of 256M,
-				 * we can have 4 blocks in core, waiting for a disk or n/w operation. Any higher block size would *sort of*
-				 * serialize n/w and disk operations, and is better avoided.
-				 */
-				blockSize = sourceSize / common.MaxNumberOfBlocksPerBlob
-				break
-			}
-		}
+		blockSize = autoBlockSize(sourceSize)
 	}
 	blockSize = common.Iffint64(blockSize > common.MaxBlockBlobBlockSize, common.MaxBlockBlobBlockSize, blockSize)
 
@@ -321,16 +427,32 @@ func (jptm *jobPartTransferMgr) Info() TransferInfo {
 		EntityType:                     entityType,
 		PreserveSMBPermissions:         plan.PreserveSMBPermissions,
 		PreserveSMBInfo:                plan.PreserveSMBInfo,
+		PreservePOSIXPermissions:       plan.PreservePOSIXPermissions,
+		PreserveFileTimes:              plan.PreserveFileTimes,
+		PreserveXattrs:                 plan.PreserveXattrs,
+		SniffContentType:               dstBlobData.SniffContentType,
+		CompressOnUpload:               plan.Compress,
+		PackOnUpload:                   plan.Pack,
+		UnpackOnDownload:               plan.Unpack,
+		UploadVHD:                      plan.UploadVHD,
+		AppendToBlob:                   plan.AppendToBlob,
 		S2SGetPropertiesInBackend:      s2sGetPropertiesInBackend,
 		S2SSourceChangeValidation:      s2sSourceChangeValidation,
 		S2SInvalidMetadataHandleOption: s2sInvalidMetadataHandleOption,
 		DestLengthValidation:           DestLengthValidation,
+		S2SPreserveBlobTags:            plan.S2SPreserveBlobTags,
+		S2SMetadataMerge:               plan.S2SMetadataMerge,
+		S2SCopyMethod:                  plan.S2SCopyMethod,
+		BlobTags:                       blobTags,
 		SrcProperties: SrcProperties{
 			SrcHTTPHeaders: srcHTTPHeaders,
 			SrcMetadata:    srcMetadata,
 		},
-		SrcBlobType:    srcBlobType,
-		S2SSrcBlobTier: srcBlobTier,
+		SrcBlobType:       srcBlobType,
+		S2SSrcBlobTier:    srcBlobTier,
+		ArchivedSource:    plan.ArchivedSource,
+		RehydratePriority: plan.RehydratePriority,
+		SrcBlobDeleted:    plan.Transfer(jptm.transferIndex).SrcBlobDeleted,
 	}
 
 	return *jptm.transferInfo
@@ -429,7 +551,12 @@ func (jptm *jobPartTransferMgr) ScheduleChunks(chunkFunc chunkFunc) {
 }
 
 func (jptm *jobPartTransferMgr) ResourceDstData(dataFileToXfer []byte) (headers common.ResourceHTTPHeaders, metadata common.Metadata) {
-	return jptm.jobPartMgr.(*jobPartMgr).resourceDstData(jptm.Info().Source, dataFileToXfer)
+	headers, metadata = jptm.jobPartMgr.(*jobPartMgr).resourceDstData(jptm.Info().Source, dataFileToXfer)
+
+	plan := jptm.jobPartMgr.Plan()
+	relPath := sourceRelativePath(plan, jptm.transferIndex, jptm.Info().Source)
+	headers = jptm.jobPartMgr.(*jobPartMgr).headerRules.Apply(headers, relPath)
+	return headers, expandMetadataPlaceholders(metadata, relPath, plan.JobID)
 }
 
 // TODO refactor into something like jptm.IsLastModifiedTimeEqual() so that there is NO LastModifiedTime method and people therefore CAN'T do it wrong due to time zone
@@ -455,6 +582,22 @@ func (jptm *jobPartTransferMgr) MD5ValidationOption() common.HashValidationOptio
 	return jptm.jobPartMgr.(*jobPartMgr).localDstData().MD5VerificationOption
 }
 
+func (jptm *jobPartTransferMgr) ShouldPutCrc64() bool {
+	return jptm.jobPartMgr.ShouldPutCrc64()
+}
+
+func (jptm *jobPartTransferMgr) Crc64ValidationOption() common.HashValidationOption {
+	return jptm.jobPartMgr.(*jobPartMgr).localDstData().Crc64VerificationOption
+}
+
+func (jptm *jobPartTransferMgr) ShouldPutSha256() bool {
+	return jptm.jobPartMgr.ShouldPutSha256()
+}
+
+func (jptm *jobPartTransferMgr) Sha256ValidationOption() common.HashValidationOption {
+	return jptm.jobPartMgr.(*jobPartMgr).localDstData().Sha256VerificationOption
+}
+
 func (jptm *jobPartTransferMgr) DeleteSnapshotsOption() common.DeleteSnapshotsOption {
 	return jptm.jobPartMgr.(*jobPartMgr).deleteSnapshotsOption()
 }
@@ -464,7 +607,12 @@ func (jptm *jobPartTransferMgr) BlobTypeOverride() common.BlobType {
 }
 
 func (jptm *jobPartTransferMgr) BlobTiers() (blockBlobTier common.BlockBlobTier, pageBlobTier common.PageBlobTier) {
-	return jptm.jobPartMgr.BlobTiers()
+	blockBlobTier, pageBlobTier = jptm.jobPartMgr.BlobTiers()
+	if jpm, ok := jptm.jobPartMgr.(*jobPartMgr); ok && len(jpm.blockBlobTierRules) > 0 {
+		relPath := sourceRelativePath(jpm.Plan(), jptm.transferIndex, jptm.Info().Source)
+		blockBlobTier = jpm.blockBlobTierRules.TierFor(relPath, blockBlobTier)
+	}
+	return blockBlobTier, pageBlobTier
 }
 
 // JobHasLowFileCount returns an estimate of whether we only have a very small number of files in the overall job
@@ -486,6 +634,26 @@ func (jptm *jobPartTransferMgr) SetActionAfterLastChunk(f func()) {
 	jptm.actionAfterLastChunk = f
 }
 
+// SetCompressedSource records that the file is uploaded from a gzipped copy of it, or the directory from an archive
+// of its files, at path. From then on, the size of the transfer is that of the copy, which is removed when the
+// transfer is done
+func (jptm *jobPartTransferMgr) SetCompressedSource(path string, size int64) {
+	info := jptm.Info() // makes sure that jptm.transferInfo is there to change
+	jptm.transferInfo.CompressedSource = path
+	jptm.transferInfo.UncompressedSize = info.SourceSize
+	jptm.transferInfo.SourceSize = size
+}
+
+// uncompressedLength turns a length in the gzipped copy of a file being uploaded compressed into the corresponding
+// length of the file itself, since the progress of the job is measured against the sizes of the source files
+func (jptm *jobPartTransferMgr) uncompressedLength(n int64) int64 {
+	info := jptm.transferInfo
+	if info == nil || info.CompressedSource == "" || info.SourceSize == 0 {
+		return n
+	}
+	return int64(float64(n) * float64(info.UncompressedSize) / float64(info.SourceSize))
+}
+
 // Call Done when a chunk has completed its transfer; this method returns the number of chunks completed so far
 func (jptm *jobPartTransferMgr) ReportChunkDone(id common.ChunkID) (lastChunk bool, chunksDone uint32) {
 
@@ -496,21 +664,56 @@ func (jptm *jobPartTransferMgr) ReportChunkDone(id common.ChunkID) (lastChunk bo
 	id.SetCompletionNotificationSent()
 
 	// track progress
-	if jptm.IsLive() {
-		atomic.AddInt64(&jptm.atomicSuccessfulBytes, id.Length())
-		JobsAdmin.AddSuccessfulBytesInActiveFiles(id.Length())
+	// For downloads, a chunk being done only means it has been handed to the ChunkedFileWriter, so its bytes are
+	// counted later, when the file is synced (see savedBytesRecorder). For uploads, done means the block has been staged.
+	if fromTo := jptm.FromTo(); !fromTo.IsDownload() {
+		jptm.ReportWrittenBytes(jptm.uncompressedLength(id.Length()))
 	}
 
 	// Do our actual processing
 	chunksDone = atomic.AddUint32(&jptm.atomicChunksDone, 1)
 	lastChunk = chunksDone == jptm.numChunks
 	if lastChunk {
-		jptm.runActionAfterLastChunk()
+		jptm.runActionAfterLastChunk() // for downloads, this flushes the writer, so all written bytes have been reported by the time it returns
+		atomic.StoreUint32(&jptm.atomicProgressClosedIndicator, 1)
 		JobsAdmin.AddSuccessfulBytesInActiveFiles(-atomic.LoadInt64(&jptm.atomicSuccessfulBytes)) // subtract our bytes from the active files bytes, because we are done now
 	}
 	return lastChunk, chunksDone
 }
 
+// ReportWrittenBytes adds n to the count of bytes that have been transferred for this file, so that progress
+// can be reported within (very large) files rather than only in terms of whole files
+func (jptm *jobPartTransferMgr) ReportWrittenBytes(n int64) {
+	if !jptm.IsLive() || atomic.LoadUint32(&jptm.atomicProgressClosedIndicator) != 0 {
+		return
+	}
+	atomic.AddInt64(&jptm.atomicSuccessfulBytes, n)
+	JobsAdmin.AddSuccessfulBytesInActiveFiles(n)
+	jptm.noteActivity()
+}
+
+// SavedBytes returns how much of a download, counting from the start of the file, has been saved to the destination.
+// After a resume, that's what earlier runs of the job saved
+func (jptm *jobPartTransferMgr) SavedBytes() int64 {
+	return jptm.jobPartPlanTransfer.SavedBytes()
+}
+
+// ReportSavedBytes records that n more bytes of a download have reached the disk. It's recorded even if the transfer
+// has failed, since the bytes are in the file all the same
+func (jptm *jobPartTransferMgr) ReportSavedBytes(n int64) {
+	jptm.jobPartPlanTransfer.addSavedBytes(n)
+}
+
+// ResumeDownloadAt is called before a download's chunks are scheduled. The bytes before offset were saved by an
+// earlier run of the job (so offset is zero when starting afresh), and are counted as transferred now
+func (jptm *jobPartTransferMgr) ResumeDownloadAt(offset int64) {
+	jptm.jobPartPlanTransfer.SetSavedBytes(offset)
+	if offset > 0 && jptm.IsLive() {
+		atomic.AddInt64(&jptm.atomicSuccessfulBytes, offset)
+		JobsAdmin.AddSuccessfulBytesInActiveFiles(offset)
+	}
+}
+
 // If an automatic action has been specified for after the last chunk, run it now
 // (Prior to introduction of this routine, individual chunkfuncs had to check the return values
 // of ReportChunkDone and then implement their own versions of the necessary transfer epilogue code.
@@ -601,6 +804,24 @@ func (jptm *jobPartTransferMgr) IsLive() bool {
 	return !jptm.isDead()
 }
 
+// JobWasResumed is true if this run of the job was started by "jobs resume", in which case an earlier run
+// may already have sent part of this transfer
+func (jptm *jobPartTransferMgr) JobWasResumed() bool {
+	return jptm.jobPartMgr.(*jobPartMgr).jobMgr.(*jobMgr).wasResumed()
+}
+
+// JobIsPaused is true once the job has been paused. Transfers stopped by a pause are picked up again by
+// "jobs resume", so anything they have already sent to the destination should be left there
+func (jptm *jobPartTransferMgr) JobIsPaused() bool {
+	return jptm.jobPartMgr.(*jobPartMgr).jobMgr.(*jobMgr).isPaused()
+}
+
+// TransferIdentity returns where this transfer is in the job's plan files, which stays the same when the job is resumed
+func (jptm *jobPartTransferMgr) TransferIdentity() (jobID common.JobID, partNum PartNumber, transferIndex uint32) {
+	plan := jptm.jobPartMgr.Plan()
+	return plan.JobID, plan.PartNum, jptm.transferIndex
+}
+
 func (jptm *jobPartTransferMgr) ShouldLog(level pipeline.LogLevel) bool {
 	return jptm.jobPartMgr.ShouldLog(level)
 }
@@ -708,6 +929,7 @@ func (jptm *jobPartTransferMgr) failActiveTransfer(typ transferErrorCode, descri
 		requestID := ErrorEx{err}.MSRequestID()
 		fullMsg := fmt.Sprintf("%s. When %s. X-Ms-Request-Id: %s\n", msg, descriptionOfWhereErrorOccurred, requestID) // trailing \n to separate it better from any later, unrelated, log lines
 		jptm.logTransferError(typ, jptm.Info().Source, jptm.Info().Destination, fullMsg, status)
+		jptm.failureReason = strings.TrimSpace(fullMsg)
 		jptm.SetStatus(failureStatus)
 		jptm.SetErrorCode(int32(status)) // TODO: what are the rules about when this needs to be set, and doesn't need to be (e.g. for earlier failures)?
 		// If the status code was 403, it means there was an authentication error and we exit.
@@ -849,8 +1071,44 @@ func (jptm *jobPartTransferMgr) ReportTransferDone() uint32 {
 	if atomic.SwapUint32(&jptm.atomicCompletionIndicator, 1) != 0 {
 		panic("cannot report the same transfer done twice")
 	}
+	jptm.stopStallWatching()
+	if jpm, ok := jptm.jobPartMgr.(*jobPartMgr); ok {
+		jpm.removeLiveTransfer(jptm)
+	}
+	if jptm.transferInfo != nil && jptm.transferInfo.CompressedSource != "" {
+		if err := os.Remove(jptm.transferInfo.CompressedSource); err != nil {
+			jptm.Log(pipeline.LogWarning, fmt.Sprintf("Couldn't remove the compressed copy of %s: %v", jptm.transferInfo.Source, err))
+		}
+	}
+
+	status := jptm.jobPartPlanTransfer.TransferStatus()
+	if status <= common.ETransferStatus.Failed() && status != common.ETransferStatus.CancelledByUser() {
+		jptm.recordFailure()
+	}
+	if common.GetLifecycleMgr().TransferEventsEnabled() {
+		jptm.reportTransferEvent(common.TransferEventTypeOf(status), atomic.LoadInt64(&jptm.atomicSuccessfulBytes))
+	}
+	return jptm.jobPartMgr.ReportTransferDone(status)
+}
+
+// CountRetry is called, via the transfer's context, each time one of the transfer's requests is retried
+func (jptm *jobPartTransferMgr) CountRetry() {
+	atomic.AddInt32(&jptm.atomicRetryCount, 1)
+}
 
-	return jptm.jobPartMgr.ReportTransferDone(jptm.jobPartPlanTransfer.TransferStatus())
+// recordFailure saves the details of this transfer's failure, so that "jobs show" can list them later
+func (jptm *jobPartTransferMgr) recordFailure() {
+	plan := jptm.jobPartMgr.Plan()
+	err := recordTransferFailure(plan.JobID, transferFailure{
+		PartNum:       plan.PartNum,
+		TransferIndex: jptm.transferIndex,
+		ErrorCode:     jptm.ErrorCode(),
+		ErrorMessage:  jptm.failureReason,
+		RetryCount:    atomic.LoadInt32(&jptm.atomicRetryCount),
+	})
+	if err != nil {
+		jptm.Log(pipeline.LogWarning, fmt.Sprintf("failed to record the details of the failure: %v", err))
+	}
 }
 
 func (jptm *jobPartTransferMgr) SourceProviderPipeline() pipeline.Pipeline {
@@ -869,3 +1127,50 @@ func (jptm *jobPartTransferMgr) GetDestinationRoot() string {
 	p := jptm.jobPartMgr.Plan()
 	return string(p.DestinationRoot[:p.DestinationRootLength])
 }
+
+// SetS2SCopyMethod records how the data of the transfer is being copied, so that it can be reported when the transfer ends
+func (jptm *jobPartTransferMgr) SetS2SCopyMethod(method common.S2SCopyMethod) {
+	atomic.StoreUint32(&jptm.atomicS2SCopyMethod, uint32(method))
+}
+
+// RecordMd5Mismatch records that the downloaded data didn't match the MD5 stored at the source, so that the job summary
+// can list the transfer. kept says whether the file was kept anyway
+func (jptm *jobPartTransferMgr) RecordMd5Mismatch(kept bool) {
+	jpm, ok := jptm.jobPartMgr.(*jobPartMgr)
+	if !ok {
+		return
+	}
+	if jm, ok := jpm.jobMgr.(*jobMgr); ok {
+		src, dst, _ := jpm.Plan().TransferSrcDstStrings(jptm.transferIndex)
+		jm.md5Mismatches.record(src, dst, kept)
+	}
+}
+
+// PreviousTransferToSameDestination looks, when every version of each blob is being copied, at the transfer that was
+// listed just before this one, which may be in the previous part. If it writes to the same destination, it's of an
+// earlier version of the same blob, and we say what has become of it, and whether it still holds the destination's lock
+func (jptm *jobPartTransferMgr) PreviousTransferToSameDestination() (status common.TransferStatus, destinationLocked bool, exists bool) {
+	plan := jptm.jobPartMgr.Plan()
+	if !plan.IncludeVersions {
+		return common.ETransferStatus.NotStarted(), false, false
+	}
+
+	prevPlan, prevIndex := plan, jptm.transferIndex
+	if prevIndex == 0 {
+		if plan.PartNum == 0 {
+			return common.ETransferStatus.NotStarted(), false, false
+		}
+		prevPart, found := jptm.jobPartMgr.(*jobPartMgr).jobMgr.JobPartMgr(plan.PartNum - 1)
+		if !found || prevPart.Plan().NumTransfers == 0 {
+			return common.ETransferStatus.NotStarted(), false, false
+		}
+		prevPlan, prevIndex = prevPart.Plan(), prevPart.Plan().NumTransfers
+	}
+	prevIndex--
+
+	_, dst, _ := plan.TransferSrcDstStrings(jptm.transferIndex)
+	if _, prevDst, _ := prevPlan.TransferSrcDstStrings(prevIndex); prevDst != dst {
+		return common.ETransferStatus.NotStarted(), false, false
+	}
+	return prevPlan.Transfer(prevIndex).TransferStatus(), jptm.jobPartMgr.ExclusiveDestinationMap().Contains(jptm.Info().Destination), true
+}
@@ -0,0 +1,95 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+)
+
+// opsRateLimiter applies the --cap-ops limit, on the number of requests sent per second. It's shared by every pipeline
+// in the process, including the ones used for enumeration, since the service's limits apply to all requests alike.
+// The requests are spaced out evenly, rather than allowed in bursts, so that the rate never exceeds the cap even briefly
+type opsRateLimiter struct {
+	mu           sync.Mutex
+	opsPerSecond int
+	next         time.Time // when the next request may be sent
+	now          func() time.Time
+}
+
+func newOpsRateLimiter(opsPerSecond int) *opsRateLimiter {
+	return &opsRateLimiter{opsPerSecond: opsPerSecond, now: time.Now}
+}
+
+// reserve takes the next free slot, and returns how long it is until that slot
+func (l *opsRateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.opsPerSecond <= 0 {
+		return 0
+	}
+	now := l.now()
+	if l.next.Before(now) {
+		l.next = now // the slots that went unused are not saved up
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Second / time.Duration(l.opsPerSecond))
+	return delay
+}
+
+// wait returns when the request may be sent, or when ctx is done
+func (l *opsRateLimiter) wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newOpsRateLimitPolicyFactory holds back requests that would exceed the cap. It goes after the retry policy in the
+// pipeline, so that retries count towards the cap too (otherwise a throttled account could be sent a storm of them)
+func newOpsRateLimitPolicyFactory() pipeline.Factory {
+	var limiter *opsRateLimiter
+	if JobsAdmin != nil {
+		limiter = JobsAdmin.(*jobsAdmin).opsLimiter
+	}
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			if limiter != nil {
+				if err := limiter.wait(ctx); err != nil {
+					return nil, err
+				}
+			}
+			return next.Do(ctx, request)
+		}
+	})
+}
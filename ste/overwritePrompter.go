@@ -63,7 +63,7 @@ func (o *overwritePrompter) promptForConfirmation(objectPath string, objectType
 			"Do you wish to overwrite its properties?", objectPath)
 	}
 
-	answer := common.GetLifecycleMgr().Prompt(question,
+	answer := common.GetLifecycleMgr().PromptForChoice(question,
 		common.PromptDetails{
 			PromptType:   common.EPromptType.Overwrite(),
 			PromptTarget: objectPath,
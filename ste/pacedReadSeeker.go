@@ -36,20 +36,25 @@ type pacedReadSeeker struct {
 
 	body io.Reader // Seeking is required to support retries
 	p    pacer
+
+	// told when bytes are read, if the transfer is watched for stalls
+	activity stallWatchable
 }
 
 func newPacedRequestBody(ctx context.Context, requestBody io.ReadSeeker, p pacer) io.ReadSeeker {
 	if p == nil {
 		panic("p must not be nil")
 	}
-	return &pacedReadSeeker{ctx: ctx, body: requestBody, p: p}
+	activity, _ := ctx.Value(stallWatchContextKey).(stallWatchable)
+	return &pacedReadSeeker{ctx: ctx, body: requestBody, p: p, activity: activity}
 }
 
 func newPacedResponseBody(ctx context.Context, responseBody io.ReadCloser, p pacer) io.ReadCloser {
 	if p == nil {
 		panic("p must not be nil")
 	}
-	return &pacedReadSeeker{ctx: ctx, body: responseBody, p: p}
+	activity, _ := ctx.Value(stallWatchContextKey).(stallWatchable)
+	return &pacedReadSeeker{ctx: ctx, body: responseBody, p: p, activity: activity}
 }
 
 func (prs *pacedReadSeeker) Read(p []byte) (int, error) {
@@ -68,6 +73,10 @@ func (prs *pacedReadSeeker) Read(p []byte) (int, error) {
 	excess := requestedCount - n
 	prs.p.UndoRequest(int64(excess))
 
+	if n > 0 && prs.activity != nil {
+		prs.activity.noteActivity()
+	}
+
 	return n, err
 }
 
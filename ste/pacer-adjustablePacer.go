@@ -0,0 +1,92 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+)
+
+// adjustablePacer is the pacer that applies the --cap-mbps limit. Unlike a plain tokenBucketPacer,
+// the cap can be changed while jobs are running (see "jobs set"), including being removed altogether,
+// in which case there's no pacing at all
+type adjustablePacer struct {
+	tokenBucket *tokenBucketPacer
+
+	// the cap, as the bits of a float64 (so that it can be read and written atomically). Zero means not capped
+	atomicCapMbpsBits uint64
+	atomicGrandTotal  int64
+}
+
+func newAdjustablePacer(capMbps float64) *adjustablePacer {
+	unusedExpectedCoarseRequestByteCount := int64(0)
+	p := &adjustablePacer{tokenBucket: newTokenBucketPacer(mbpsToBytesPerSecond(capMbps), unusedExpectedCoarseRequestByteCount)}
+	p.setCapMbps(capMbps)
+	// Note: as at July 2019, we don't currently have a shutdown method/event on JobsAdmin where this pacer
+	// could be shut down. But, it's global anyway, so we just leave it running until application exit.
+	return p
+}
+
+// use the "networking mega" (based on powers of 10, not powers of 2, since that's what mega means in networking context)
+func mbpsToBytesPerSecond(mbps float64) int64 {
+	return int64(mbps * 1000 * 1000 / 8)
+}
+
+func (p *adjustablePacer) capMbps() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&p.atomicCapMbpsBits))
+}
+
+// setCapMbps changes the cap. It takes effect straight away, for all requests not yet allocated
+func (p *adjustablePacer) setCapMbps(capMbps float64) {
+	if capMbps < 0 {
+		capMbps = 0
+	}
+	p.tokenBucket.setTargetBytesPerSecond(mbpsToBytesPerSecond(capMbps))
+	atomic.StoreUint64(&p.atomicCapMbpsBits, math.Float64bits(capMbps))
+}
+
+func (p *adjustablePacer) RequestTrafficAllocation(ctx context.Context, byteCount int64) error {
+	if p.capMbps() > 0 {
+		if err := p.tokenBucket.RequestTrafficAllocation(ctx, byteCount); err != nil {
+			return err
+		}
+	}
+	atomic.AddInt64(&p.atomicGrandTotal, byteCount) // we track total aggregate throughput, whether or not we are pacing
+	return nil
+}
+
+func (p *adjustablePacer) UndoRequest(byteCount int64) {
+	if byteCount > 0 {
+		// if the cap was removed since the request, this puts back tokens that weren't taken. That does no harm,
+		// because the token bucket is trimmed back to size as soon as it has a cap again
+		p.tokenBucket.UndoRequest(byteCount)
+		atomic.AddInt64(&p.atomicGrandTotal, -byteCount)
+	}
+}
+
+func (p *adjustablePacer) Close() error {
+	return p.tokenBucket.Close()
+}
+
+func (p *adjustablePacer) GetTotalTraffic() int64 {
+	return atomic.LoadInt64(&p.atomicGrandTotal)
+}
@@ -0,0 +1,212 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// packSourceIfWanted packs the files of the directory that is the source of an upload with --pack into a temporary
+// archive, which is then uploaded in its place. As with --compress, it's done before the transfer starts, since the
+// senders must know the size of what they send, and the archive is removed when the transfer is done.
+// Only the regular files directly in the directory are packed. Its subdirectories have archives of their own
+func packSourceIfWanted(jptm IJobPartTransferMgr) error {
+	info := jptm.Info()
+	if info.PackOnUpload == common.EPackFormat.None() {
+		return nil
+	}
+
+	archivePath, archiveSize, count, err := packDirectoryToTempFile(info.Source, info.PackOnUpload == common.EPackFormat.TarGz())
+	if err != nil {
+		return fmt.Errorf("couldn't pack the files of the directory: %w", err)
+	}
+
+	jptm.SetCompressedSource(archivePath, archiveSize)
+	jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, fmt.Sprintf("packed %d files, of %d bytes, into %d bytes", count, info.SourceSize, archiveSize))
+	return nil
+}
+
+func packDirectoryToTempFile(dir string, gzipped bool) (archivePath string, archiveSize int64, count int, err error) {
+	entries, err := ioutil.ReadDir(dir) // uses Lstat, so symlinks aren't regular files, and are left out
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	dst, err := ioutil.TempFile("", "azcopy-pack-")
+	if err != nil {
+		return "", 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(dst.Name())
+		}
+	}()
+
+	var w io.Writer = dst
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(dst)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		if err = addFileToTar(tw, filepath.Join(dir, entry.Name()), entry); err != nil {
+			dst.Close()
+			return "", 0, 0, err
+		}
+		count++
+	}
+
+	if err = tw.Close(); err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	fi, err := os.Stat(dst.Name())
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return dst.Name(), fi.Size(), count, nil
+}
+
+func addFileToTar(tw *tar.Writer, path string, fi os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	header.Name = fi.Name()
+	if err = tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	// the header says how long the file is, so the file mustn't have changed size since it was listed
+	n, err := io.Copy(tw, io.LimitReader(f, fi.Size()))
+	if err == nil && n != fi.Size() {
+		err = fmt.Errorf("%s got shorter while it was being packed", path)
+	}
+	return err
+}
+
+// isArchiveToUnpack says whether a file downloaded with --unpack is an archive, judging by its name
+func isArchiveToUnpack(path string) bool {
+	name := strings.ToLower(path)
+	return strings.HasSuffix(name, ".tar") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+var errUnsafeArchiveEntry = errors.New("the archive holds a path that would be outside the directory it's unpacked into")
+
+// unpackArchive unpacks the regular files and directories in a tar, or gzipped tar, archive into dir. Files that
+// exist already are only replaced if overwrite is true. Other kinds of entries, such as links, are left out
+func unpackArchive(archivePath string, dir string, overwrite bool) (count int, err error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if lower := strings.ToLower(archivePath); strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return count, errUnsafeArchiveEntry
+		}
+		target := filepath.Join(dir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, os.ModePerm); err != nil {
+				return count, err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if !overwrite {
+				if _, err := os.Lstat(target); err == nil {
+					continue
+				}
+			}
+			if err = unpackFile(tr, header, target); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+}
+
+func unpackFile(tr *tar.Reader, header *tar.Header, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, tr)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
@@ -0,0 +1,144 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// CheckpointInterval says how often the status of transfers is flushed to the plan files.
+// Since the plan files are memory-mapped, what we write to them survives AzCopy itself crashing, whether we flush or not.
+// Flushing is about the machine crashing (or, for plan files on a network share, the connection to it being lost),
+// so it's a trade of safety for speed: each flush is a synchronous write, and with lots of small files those writes
+// can take longer than the transfers themselves.
+type CheckpointInterval time.Duration
+
+const (
+	// CheckpointEachTransfer flushes as each transfer finishes
+	CheckpointEachTransfer CheckpointInterval = 0
+	// CheckpointEachPart flushes as each job part finishes (and at the end of the job). It's the default
+	CheckpointEachPart CheckpointInterval = -1
+	// CheckpointNever leaves it to the OS to write the plan files back in its own time
+	CheckpointNever CheckpointInterval = -2
+)
+
+// NewCheckpointInterval gets the interval from the AZCOPY_CHECKPOINT_INTERVAL environment variable, if it's set
+func NewCheckpointInterval() (CheckpointInterval, error) {
+	envVar := common.EEnvironmentVariable.CheckpointInterval()
+	value := strings.TrimSpace(common.GetLifecycleMgr().GetEnvironmentVariable(envVar))
+	switch strings.ToLower(value) {
+	case "":
+		return CheckpointEachPart, nil
+	case "never":
+		return CheckpointNever, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return CheckpointEachPart, fmt.Errorf("invalid value %q for environment variable %s: it must be a duration such as 30s or 5m, 0, or 'never'",
+			value, envVar.Name)
+	}
+	return CheckpointInterval(d), nil
+}
+
+// GetDescription summarizes the interval, for the log
+func (i CheckpointInterval) GetDescription() string {
+	switch i {
+	case CheckpointEachTransfer:
+		return "as each transfer finishes"
+	case CheckpointEachPart:
+		return "as each job part finishes"
+	case CheckpointNever:
+		return "never (left to the OS)"
+	default:
+		return fmt.Sprintf("every %v, and as each job part finishes", time.Duration(i))
+	}
+}
+
+func (i CheckpointInterval) isPeriodic() bool {
+	return i > 0
+}
+
+// planCheckpointer decides when the plan files are flushed. With a periodic interval, it keeps track of which
+// job parts have had transfers finish since they were last flushed, so that the idle ones are left alone
+type planCheckpointer struct {
+	interval CheckpointInterval
+	flush    func(jpm *jobPartMgr)
+	mu       sync.Mutex
+	dirty    map[*jobPartMgr]struct{}
+}
+
+func newPlanCheckpointer(interval CheckpointInterval) *planCheckpointer {
+	return &planCheckpointer{
+		interval: interval,
+		flush:    func(jpm *jobPartMgr) { jpm.flushPlan() },
+		dirty:    make(map[*jobPartMgr]struct{}),
+	}
+}
+
+// transferDone is called when one of the part's transfers has finished, and its status has been written to the plan
+func (c *planCheckpointer) transferDone(jpm *jobPartMgr) {
+	switch {
+	case c.interval == CheckpointEachTransfer:
+		c.flush(jpm)
+	case c.interval.isPeriodic():
+		c.mu.Lock()
+		c.dirty[jpm] = struct{}{}
+		c.mu.Unlock()
+	}
+}
+
+// partDone is called when all of the part's transfers have finished, or when the job's final status has been set
+func (c *planCheckpointer) partDone(jpm *jobPartMgr) {
+	if c.interval == CheckpointNever {
+		return
+	}
+	c.mu.Lock()
+	delete(c.dirty, jpm)
+	c.mu.Unlock()
+	c.flush(jpm)
+}
+
+func (c *planCheckpointer) run() {
+	if !c.interval.isPeriodic() {
+		return
+	}
+	for range time.Tick(time.Duration(c.interval)) {
+		c.flushDirty()
+	}
+}
+
+// flushDirty flushes the parts that have had transfers finish since they were last flushed, and returns how many there were
+func (c *planCheckpointer) flushDirty() int {
+	c.mu.Lock()
+	dirty := c.dirty
+	c.dirty = make(map[*jobPartMgr]struct{})
+	c.mu.Unlock() // since flushing is slow
+
+	for jpm := range dirty {
+		c.flush(jpm)
+	}
+	return len(dirty)
+}
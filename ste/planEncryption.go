@@ -0,0 +1,276 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Plan files hold the paths of everything a job transfers, its command line, and the extra query parameters of its URLs,
+// so they can be encrypted at rest (see AZCOPY_ENCRYPT_PLAN_FILES and AZCOPY_PLAN_FILE_PASSPHRASE), with AES-256-GCM.
+// An encrypted plan can't be memory-mapped, so it's decrypted into memory when it's mapped, and the whole plan is
+// encrypted again, and written over the file, each time it's flushed.
+//
+// An encrypted plan file is laid out as:
+//   magic (8 bytes) | key source (1 byte) | salt (16 bytes) | nonce (12 bytes) | encrypted plan, with the GCM tag
+// Everything before the nonce is authenticated along with the plan. The salt is only used when the key comes from a passphrase.
+
+// encryptedPlanMagic starts every encrypted plan file. A plain plan file starts with its data schema version,
+// which is nowhere near this big
+const encryptedPlanMagic = "AZCPLAN\x01"
+
+const planSaltSize = 16
+
+const encryptedPlanHeaderSize = len(encryptedPlanMagic) + 1 + planSaltSize
+
+// scrypt's recommended settings for interactive logins, as of 2017
+const planPassphraseScryptN, planPassphraseScryptR, planPassphraseScryptP = 1 << 15, 8, 1
+
+type planKeySource byte
+
+const (
+	planKeyFromOS         planKeySource = 1
+	planKeyFromPassphrase planKeySource = 2
+)
+
+// planDecryptionError means that an encrypted plan file couldn't be decrypted. That might be because it's damaged,
+// but it's as likely to be because the right key isn't available (e.g. the passphrase isn't set), so the file is left alone
+type planDecryptionError struct {
+	err error
+}
+
+func (e planDecryptionError) Error() string {
+	return "cannot decrypt the plan file: " + e.err.Error()
+}
+
+// planSealer encrypts plans with one key, and writes them to their files
+type planSealer struct {
+	header []byte
+	aead   cipher.AEAD
+	mu     sync.Mutex // so that two flushes of the same plan can't write the file at the same time
+}
+
+// processPlanSalt is used for every plan this process encrypts with a passphrase, so that the (deliberately slow)
+// derivation of the key from the passphrase is only done once
+var processPlanSalt = struct {
+	sync.Once
+	salt []byte
+}{}
+
+// derivedPlanKeys caches the keys derived from passphrases, by salt and passphrase
+var derivedPlanKeys = struct {
+	sync.Mutex
+	keys map[string][]byte
+}{keys: make(map[string][]byte)}
+
+// ValidatePlanEncryption checks the environment variables that say whether new plan files are to be encrypted
+func ValidatePlanEncryption() error {
+	_, err := planKeySourceFromEnvironment()
+	return err
+}
+
+// planKeySourceFromEnvironment says where the key for new plan files is to come from. It's 0 if they aren't to be encrypted
+func planKeySourceFromEnvironment() (planKeySource, error) {
+	lcm := common.GetLifecycleMgr()
+	if lcm.GetEnvironmentVariable(common.EEnvironmentVariable.PlanFilePassphrase()) != "" {
+		return planKeyFromPassphrase, nil
+	}
+	envVar := common.EEnvironmentVariable.EncryptPlanFiles()
+	switch value := strings.ToLower(strings.TrimSpace(lcm.GetEnvironmentVariable(envVar))); value {
+	case "", "false":
+		return 0, nil
+	case "true":
+		return planKeyFromOS, nil
+	default:
+		return 0, fmt.Errorf("invalid value %q for environment variable %s: it must be true or false", value, envVar.Name)
+	}
+}
+
+// newPlanSealerFromEnvironment gets the sealer for new plan files. It's nil if they aren't to be encrypted
+func newPlanSealerFromEnvironment() (*planSealer, error) {
+	source, err := planKeySourceFromEnvironment()
+	if err != nil || source == 0 {
+		return nil, err
+	}
+	salt := make([]byte, planSaltSize)
+	if source == planKeyFromPassphrase {
+		processPlanSalt.Do(func() {
+			processPlanSalt.salt = make([]byte, planSaltSize)
+			_, err := rand.Read(processPlanSalt.salt)
+			common.PanicIfErr(err)
+		})
+		salt = processPlanSalt.salt
+	}
+	return newPlanSealer(source, salt)
+}
+
+func newPlanSealer(source planKeySource, salt []byte) (*planSealer, error) {
+	var key []byte
+	var err error
+	switch source {
+	case planKeyFromOS:
+		key, err = common.GetPlanFileKey(JobsAdmin.AppPathFolder())
+	case planKeyFromPassphrase:
+		key, err = planKeyFromPassphraseAndSalt(salt)
+	default:
+		err = fmt.Errorf("unknown key source %d", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, encryptedPlanHeaderSize)
+	header = append(header, encryptedPlanMagic...)
+	header = append(header, byte(source))
+	header = append(header, salt...)
+	return &planSealer{header: header, aead: aead}, nil
+}
+
+func planKeyFromPassphraseAndSalt(salt []byte) ([]byte, error) {
+	envVar := common.EEnvironmentVariable.PlanFilePassphrase()
+	passphrase := common.GetLifecycleMgr().GetEnvironmentVariable(envVar)
+	if passphrase == "" {
+		return nil, fmt.Errorf("the plan file is encrypted with a passphrase. Set it in environment variable %s", envVar.Name)
+	}
+
+	derivedPlanKeys.Lock()
+	defer derivedPlanKeys.Unlock()
+	cacheKey := string(salt) + passphrase
+	if key, ok := derivedPlanKeys.keys[cacheKey]; ok {
+		return key, nil
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, planPassphraseScryptN, planPassphraseScryptR, planPassphraseScryptP, common.PlanFileKeySize)
+	if err != nil {
+		return nil, err
+	}
+	derivedPlanKeys.keys[cacheKey] = key
+	return key, nil
+}
+
+func isEncryptedPlan(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(encryptedPlanMagic))
+}
+
+// seal encrypts the plan, and returns the content of its file
+func (s *planSealer) seal(plan []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	content := make([]byte, 0, len(s.header)+len(nonce)+len(plan)+s.aead.Overhead())
+	content = append(content, s.header...)
+	content = append(content, nonce...)
+	return s.aead.Seal(content, nonce, plan, s.header), nil
+}
+
+// openPlan decrypts the content of an encrypted plan file. It also returns the sealer that the plan should be written back
+// with, which uses the same key, so that the file can still be read by whoever could read it before
+func openPlan(content []byte) ([]byte, *planSealer, error) {
+	if len(content) < encryptedPlanHeaderSize || !isEncryptedPlan(content) {
+		return nil, nil, planDecryptionError{errors.New("the file is not an encrypted plan file")}
+	}
+	salt := content[len(encryptedPlanMagic)+1 : encryptedPlanHeaderSize]
+	s, err := newPlanSealer(planKeySource(content[len(encryptedPlanMagic)]), append([]byte{}, salt...))
+	if err != nil {
+		return nil, nil, planDecryptionError{err}
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(content) < encryptedPlanHeaderSize+nonceSize {
+		return nil, nil, planDecryptionError{errors.New("the file is too short")}
+	}
+	nonce := content[encryptedPlanHeaderSize : encryptedPlanHeaderSize+nonceSize]
+	plan, err := s.aead.Open(nil, nonce, content[encryptedPlanHeaderSize+nonceSize:], content[:encryptedPlanHeaderSize])
+	if err != nil {
+		return nil, nil, planDecryptionError{errors.New("the key is wrong, or the file has been changed")}
+	}
+	return plan, s, nil
+}
+
+// readEncryptedPlanFile reads and decrypts the file, if it's encrypted. If it isn't, it returns a nil plan
+func readEncryptedPlanFile(path string) ([]byte, *planSealer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(encryptedPlanMagic))
+	if _, err = io.ReadFull(file, magic); err != nil || !isEncryptedPlan(magic) {
+		return nil, nil, nil // too short to be encrypted, so leave it to the plain plan file's checks
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	var content bytes.Buffer
+	if _, err = content.ReadFrom(file); err != nil {
+		return nil, nil, err
+	}
+	return openPlan(content.Bytes())
+}
+
+// writeFile encrypts the plan, and writes it to the file at path. Like a new plan file, it's written under a temporary
+// name, and renamed over the old one when it's complete, so a crash part way through can't leave the plan half written
+func (s *planSealer) writeFile(path string, plan []byte) error {
+	content, err := s.seal(plan)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(path+planFileTempSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(content); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+planFileTempSuffix, path)
+}
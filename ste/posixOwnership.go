@@ -0,0 +1,70 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parsePOSIXPermissions reads permissions as ADLS Gen2 gives them: symbolic (rwxr-x---), with a trailing + when there
+// is an extended ACL, and t or T in the last place for the sticky bit. 4-digit octal is accepted too
+func parsePOSIXPermissions(s string) (os.FileMode, error) {
+	s = strings.TrimSuffix(s, "+")
+
+	if octal, err := strconv.ParseUint(s, 8, 32); err == nil && len(s) <= 4 {
+		mode := os.FileMode(octal & 0777)
+		if octal&01000 != 0 {
+			mode |= os.ModeSticky
+		}
+		return mode, nil
+	}
+
+	if len(s) != 9 {
+		return 0, fmt.Errorf("'%s' aren't POSIX permissions", s)
+	}
+	var mode os.FileMode
+	for i, c := range s {
+		bit := os.FileMode(1) << uint(8-i)
+		switch {
+		case c == rune("rwxrwxrwx"[i]):
+			mode |= bit
+		case i == 8 && c == 't':
+			mode |= bit | os.ModeSticky
+		case i == 8 && c == 'T':
+			mode |= os.ModeSticky
+		case c != '-':
+			return 0, fmt.Errorf("'%s' aren't POSIX permissions", s)
+		}
+	}
+	return mode, nil
+}
+
+// formatPOSIXPermissions gives the permissions in the 4-digit octal that ADLS Gen2 takes
+func formatPOSIXPermissions(mode os.FileMode) string {
+	octal := uint32(mode.Perm())
+	if mode&os.ModeSticky != 0 {
+		octal |= 01000
+	}
+	return fmt.Sprintf("%04o", octal)
+}
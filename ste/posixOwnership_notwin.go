@@ -0,0 +1,74 @@
+// +build !windows
+
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+func (f localFileSourceInfoProvider) GetPOSIXOwnership() (POSIXOwnership, error) {
+	fileInfo, err := os.Stat(f.jptm.Info().Source)
+	if err != nil {
+		return POSIXOwnership{}, err
+	}
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return POSIXOwnership{}, fmt.Errorf("the owner of %s isn't known", f.jptm.Info().Source)
+	}
+
+	return POSIXOwnership{
+		Owner:       strconv.FormatUint(uint64(stat.Uid), 10),
+		Group:       strconv.FormatUint(uint64(stat.Gid), 10),
+		Permissions: formatPOSIXPermissions(fileInfo.Mode()),
+	}, nil
+}
+
+// applyPOSIXOwnership sets the mode of the local file, and its owner and group. The owner and group are only set when
+// they are numeric IDs, since the names ADLS Gen2 may use instead (such as Azure AD object IDs) mean nothing locally.
+// Changing the owner usually needs root.
+func applyPOSIXOwnership(path string, ownership POSIXOwnership) error {
+	mode, err := parsePOSIXPermissions(ownership.Permissions)
+	if err != nil {
+		return err
+	}
+
+	uid, uidErr := strconv.Atoi(ownership.Owner)
+	gid, gidErr := strconv.Atoi(ownership.Group)
+	if uidErr != nil {
+		uid = -1 // leave it as it is
+	}
+	if gidErr != nil {
+		gid = -1
+	}
+	if uid != -1 || gid != -1 {
+		if err = os.Lchown(path, uid, gid); err != nil {
+			return err
+		}
+	}
+
+	// after chown, since that may clear the setuid and setgid bits
+	return os.Chmod(path, mode)
+}
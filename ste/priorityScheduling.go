@@ -0,0 +1,79 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type priorityShare struct {
+	priority common.JobPriority
+	share    int
+}
+
+// priorityShares says how the workers divide their time between jobs of different priorities, when jobs of
+// more than one priority have work waiting. E.g. a high priority job gets 6 of every 10 chunks that are picked up.
+// Workers never sit idle while there is work at any priority, so a job that is running on its own
+// gets all of them, whatever its priority. The workers, and so the shares, belong to one process: a high priority job
+// doesn't take anything from jobs that are running in other AzCopy processes. Listed from highest priority to lowest
+var priorityShares = []priorityShare{
+	{common.EJobPriority.High(), 6},
+	{common.EJobPriority.Normal(), 3},
+	{common.EJobPriority.Low(), 1},
+}
+
+// priorityRotation lists, for each turn in a rotation, the order in which to look for work at each priority.
+// Each priority is looked at first in as many turns as its share
+type priorityRotation [][]common.JobPriority
+
+// newPriorityRotation spreads out each priority's turns (by smooth weighted round robin), rather than bunching
+// them together, so that the shares hold over short periods too. After the priority whose turn it is,
+// the others are looked at from highest to lowest
+func newPriorityRotation(shares []priorityShare) priorityRotation {
+	total := 0
+	for _, s := range shares {
+		total += s.share
+	}
+
+	current := make([]int, len(shares))
+	rotation := make(priorityRotation, 0, total)
+	for turn := 0; turn < total; turn++ {
+		chosen := 0
+		for i, s := range shares {
+			current[i] += s.share
+			if current[i] > current[chosen] {
+				chosen = i
+			}
+		}
+		current[chosen] -= total
+
+		order := []common.JobPriority{shares[chosen].priority}
+		for i, s := range shares {
+			if i != chosen {
+				order = append(order, s.priority)
+			}
+		}
+		rotation = append(rotation, order)
+	}
+	return rotation
+}
+
+var defaultPriorityRotation = newPriorityRotation(priorityShares)
@@ -0,0 +1,161 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// RetrySettings stores the retry behaviour of the pipelines that do the transfers.
+// The defaults suit most links, but users can override them through environment variables: e.g. to have more patience
+// with a flaky link, or to retry sooner within a region, where round trips are short
+type RetrySettings struct {
+	MaxTries      int32
+	TryTimeout    time.Duration
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	Jitter        float32 // as in XferRetryOptions, so zero means the default jitter
+
+	// StallTimeout is how long a transfer may have requests in flight, but no bytes moving, before the
+	// stall watchdog cancels those requests, so that they are retried. Zero turns the watchdog off
+	StallTimeout time.Duration
+
+	// IsUserSpecified says whether any of the values came from the environment
+	IsUserSpecified bool
+}
+
+// DefaultRetrySettings returns the settings that apply when none of the environment variables are set
+func DefaultRetrySettings() RetrySettings {
+	return RetrySettings{
+		MaxTries:      UploadMaxTries,
+		TryTimeout:    UploadTryTimeout,
+		RetryDelay:    UploadRetryDelay,
+		MaxRetryDelay: UploadMaxRetryDelay,
+		StallTimeout:  defaultStallTimeout,
+	}
+}
+
+const defaultStallTimeout = 5 * time.Minute
+
+// NewRetrySettings gets the retry settings by referring to the AZCOPY_RETRY_* environment variables, if they are set
+func NewRetrySettings() (RetrySettings, error) {
+	s := DefaultRetrySettings()
+	lcm := common.GetLifecycleMgr()
+	get := func(envVar common.EnvironmentVariable) string {
+		value := strings.TrimSpace(lcm.GetEnvironmentVariable(envVar))
+		if value != "" {
+			s.IsUserSpecified = true
+		}
+		return value
+	}
+	badValue := func(envVar common.EnvironmentVariable, value string, reason string) error {
+		return fmt.Errorf("invalid value %q for environment variable %s: %s", value, envVar.Name, reason)
+	}
+	getDuration := func(envVar common.EnvironmentVariable, target *time.Duration, allowZero bool) error {
+		if value := get(envVar); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return badValue(envVar, value, "it must be a duration such as 500ms, 30s or 5m")
+			}
+			if d < 0 || (d == 0 && !allowZero) {
+				return badValue(envVar, value, "it must be greater than zero")
+			}
+			*target = d
+		}
+		return nil
+	}
+
+	maxTriesEnv := common.EEnvironmentVariable.RetryMaxTries()
+	if value := get(maxTriesEnv); value != "" {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < 1 {
+			return s, badValue(maxTriesEnv, value, "it must be a whole number, of at least 1")
+		}
+		s.MaxTries = int32(n)
+	}
+	if err := getDuration(common.EEnvironmentVariable.RetryTryTimeout(), &s.TryTimeout, false); err != nil {
+		return s, err
+	}
+	if err := getDuration(common.EEnvironmentVariable.RetryDelay(), &s.RetryDelay, false); err != nil {
+		return s, err
+	}
+	if err := getDuration(common.EEnvironmentVariable.RetryMaxDelay(), &s.MaxRetryDelay, false); err != nil {
+		return s, err
+	}
+	if s.RetryDelay > s.MaxRetryDelay {
+		return s, fmt.Errorf("the retry delay (%v) cannot be longer than the maximum retry delay (%v). Check the environment variables %s and %s",
+			s.RetryDelay, s.MaxRetryDelay, common.EEnvironmentVariable.RetryDelay().Name, common.EEnvironmentVariable.RetryMaxDelay().Name)
+	}
+
+	if err := getDuration(common.EEnvironmentVariable.StallTimeout(), &s.StallTimeout, true); err != nil {
+		return s, err
+	}
+
+	jitterEnv := common.EEnvironmentVariable.RetryJitter()
+	if value := get(jitterEnv); value != "" {
+		j, err := strconv.ParseFloat(value, 32)
+		if err != nil || j < 0 || j >= 1 {
+			return s, badValue(jitterEnv, value, "it must be a fraction, of at least 0 and less than 1")
+		}
+		s.Jitter = float32(j)
+		if j == 0 {
+			s.Jitter = -1 // the user really wants none, as opposed to our default
+		}
+	}
+
+	return s, nil
+}
+
+func (s RetrySettings) xferRetryOptions() XferRetryOptions {
+	return XferRetryOptions{
+		Policy:        RetryPolicyExponential,
+		MaxTries:      s.MaxTries,
+		TryTimeout:    s.TryTimeout,
+		RetryDelay:    s.RetryDelay,
+		MaxRetryDelay: s.MaxRetryDelay,
+		Jitter:        s.Jitter,
+	}
+}
+
+// GetDescription summarizes the settings, for the log
+func (s RetrySettings) GetDescription() string {
+	jitter := "default jitter"
+	if s.Jitter < 0 {
+		jitter = "no jitter"
+	} else if s.Jitter > 0 {
+		jitter = fmt.Sprintf("jitter of %.0f%%", s.Jitter*100)
+	}
+	source := "hard-coded defaults. Set the AZCOPY_RETRY_* environment variables to override"
+	if s.IsUserSpecified {
+		source = "AZCOPY_RETRY_* environment variables"
+	}
+	stalls := "stalled transfers are not restarted"
+	if s.StallTimeout > 0 {
+		stalls = fmt.Sprintf("transfers are restarted after %v without progress", s.StallTimeout)
+	}
+	return fmt.Sprintf("up to %d tries of up to %v each, with delays from %v to %v and %s; %s (based on %s)",
+		s.MaxTries, s.TryTimeout, s.RetryDelay, s.MaxRetryDelay, jitter, stalls, source)
+}
@@ -49,7 +49,9 @@ func newURLToBlobCopier(jptm IJobPartTransferMgr, destination string, p pipeline
 				fmt.Sprintf("BlobType has been explictly set to %q for destination blob.", blobTypeOverride))
 		}
 	} else {
-		if blobSrcInfoProvider, ok := srcInfoProvider.(IBlobSourceInfoProvider); ok { // If source is a blob, detect the source blob type.
+		if destinationIsManagedDisk(destination) { // Managed disks can only be page blobs.
+			targetBlobType = azblob.BlobPageBlob
+		} else if blobSrcInfoProvider, ok := srcInfoProvider.(IBlobSourceInfoProvider); ok { // If source is a blob, detect the source blob type.
 			targetBlobType = blobSrcInfoProvider.BlobType()
 		} else { // If source is not a blob, infer the blob type from the extension.
 			srcURL, err := url.Parse(jptm.Info().Source)
@@ -0,0 +1,79 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// how often the destination is looked at, while the service copies a blob in the background
+var asyncCopyPollInterval = 5 * time.Second
+
+// copyMethodUsed says how the data of a block blob will be copied, to begin with, when the given method was asked for
+func copyMethodUsed(method common.S2SCopyMethod) common.S2SCopyMethod {
+	if method == common.ES2SCopyMethod.Auto() {
+		return common.ES2SCopyMethod.PutBlockFromURL()
+	}
+	return method
+}
+
+// canFallBackToDownloadUpload says whether a copy that the service couldn't make, because it couldn't read the source
+// (typically because of the source account's firewall or private endpoints), can be made by reading the data through
+// AzCopy instead. Only blob sources are read that way
+func canFallBackToDownloadUpload(method common.S2SCopyMethod, fromTo common.FromTo, err error) bool {
+	if method != common.ES2SCopyMethod.Auto() || fromTo.From() != common.ELocation.Blob() {
+		return false
+	}
+	stgErr, ok := err.(azblob.StorageError)
+	return ok && stgErr.ServiceCode() == azblob.ServiceCodeType(azblob.StorageErrorCodeCannotVerifyCopySource)
+}
+
+// waitForAsyncCopy waits until the service has finished copying a blob in the background. If the transfer is
+// cancelled first, the copy is aborted, so that it doesn't carry on without us
+func waitForAsyncCopy(ctx context.Context, blobURL azblob.BlobURL, copyID string) error {
+	for {
+		props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{})
+		switch {
+		case ctx.Err() != nil:
+			abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			_, _ = blobURL.AbortCopyFromURL(abortCtx, copyID, azblob.LeaseAccessConditions{})
+			return ctx.Err()
+		case err != nil:
+			return err
+		case props.CopyStatus() == azblob.CopyStatusSuccess:
+			return nil
+		case props.CopyStatus() != azblob.CopyStatusPending:
+			return fmt.Errorf("the service's copy of the blob ended with status %q: %s", props.CopyStatus(), props.CopyStatusDescription())
+		}
+
+		select {
+		case <-time.After(asyncCopyPollInterval):
+		case <-ctx.Done():
+		}
+	}
+}
@@ -22,6 +22,7 @@ package ste
 
 import (
 	"context"
+	"fmt"
 	"net/url"
 	"time"
 
@@ -44,8 +45,14 @@ type appendBlobSenderBase struct {
 	// the properties of the local file
 	headersToApply  azblob.BlobHTTPHeaders
 	metadataToApply azblob.Metadata
+	blobTagsToApply common.BlobTags
 
 	soleChunkFuncSemaphore *semaphore.Weighted
+
+	// With --append, the length the destination had before the transfer. The part of the source before it is in the blob
+	// already, so it isn't sent again, and the blob isn't deleted if the transfer fails
+	appendOffset        int64
+	appendingToExisting bool
 }
 
 type appendBlockFunc = func()
@@ -84,7 +91,8 @@ func newAppendBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pip
 		numChunks:              numChunks,
 		pacer:                  pacer,
 		headersToApply:         props.SrcHTTPHeaders.ToAzBlobHTTPHeaders(),
-		metadataToApply:        props.SrcMetadata.ToAzBlobMetadata(),
+		metadataToApply:        metadataToApply(jptm, srcInfoProvider, props.SrcMetadata).ToAzBlobMetadata(),
+		blobTagsToApply:        blobTagsToApply(transferInfo, props),
 		soleChunkFuncSemaphore: semaphore.NewWeighted(1)}, nil
 }
 
@@ -138,8 +146,19 @@ func (s *appendBlobSenderBase) Prologue(ps common.PrologueState) (destinationMod
 	// about the file type at this time than what we had before
 	s.headersToApply.ContentType = ps.GetInferredContentType(s.jptm)
 
+	if s.jptm.Info().AppendToBlob {
+		exists, err := s.findAppendOffset()
+		if err != nil {
+			s.jptm.FailActiveSend("Checking blob to append to", err)
+			return
+		}
+		if exists {
+			return s.appendOffset < s.jptm.Info().SourceSize
+		}
+	}
+
 	destinationModified = true
-	_, err := s.destAppendBlobURL.Create(s.jptm.Context(), s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{})
+	_, err := s.destAppendBlobURL.Create(withBlobTags(s.jptm.Context(), s.blobTagsToApply), s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{})
 	if err != nil {
 		s.jptm.FailActiveSend("Creating blob", err)
 		return
@@ -147,6 +166,35 @@ func (s *appendBlobSenderBase) Prologue(ps common.PrologueState) (destinationMod
 	return
 }
 
+// findAppendOffset looks for an existing blob to append the source to, and if there is one, notes how much of the
+// source it holds already. Since the source must be a continuation of the blob, the blob can't be longer than it
+func (s *appendBlobSenderBase) findAppendOffset() (exists bool, err error) {
+	props, err := s.destAppendBlobURL.GetProperties(s.jptm.Context(), azblob.BlobAccessConditions{})
+	if exists, _, err = remoteObjectExists(props, err); !exists || err != nil {
+		return false, err
+	}
+	if props.BlobType() != azblob.BlobAppendBlob {
+		return false, fmt.Errorf("the destination is a %s, so it can't be appended to", props.BlobType())
+	}
+	srcSize := s.jptm.Info().SourceSize
+	if props.ContentLength() > srcSize {
+		return false, fmt.Errorf("the destination has %d bytes but the source only has %d, so the source must have been truncated or replaced", props.ContentLength(), srcSize)
+	}
+
+	s.appendOffset = props.ContentLength()
+	s.appendingToExisting = true
+	if s.appendOffset == srcSize {
+		s.jptm.Log(pipeline.LogInfo, "Destination already holds all of the source, so there's nothing to append")
+	} else {
+		s.jptm.Log(pipeline.LogInfo, fmt.Sprintf("Appending the %d bytes after offset %d to the existing destination", srcSize-s.appendOffset, s.appendOffset))
+	}
+	return true, nil
+}
+
+func (s *appendBlobSenderBase) SendOffset() int64 {
+	return s.appendOffset
+}
+
 func (s *appendBlobSenderBase) Epilogue() {
 	// Empty function because you don't have to commit on an append blob
 }
@@ -154,7 +202,7 @@ func (s *appendBlobSenderBase) Epilogue() {
 func (s *appendBlobSenderBase) Cleanup() {
 	jptm := s.jptm
 	// Cleanup
-	if jptm.IsDeadInflight() {
+	if jptm.IsDeadInflight() && !s.appendingToExisting {
 		// There is a possibility that some uncommitted blocks will be there
 		// Delete the uncommitted blobs
 		// TODO: particularly, given that this is an APPEND blob, do we really need to delete it?  But if we don't delete it,
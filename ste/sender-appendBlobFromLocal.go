@@ -21,6 +21,8 @@
 package ste
 
 import (
+	"io"
+
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -47,11 +49,28 @@ func (u *appendBlobUploader) Md5Channel() chan<- []byte {
 
 func (u *appendBlobUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader, chunkIsWholeFile bool) chunkFunc {
 	appendBlockFromLocal := func() {
+		// With --append the chunk may already be in the blob, in which case it's never read to the end, so close it here
+		defer reader.Close()
+
+		offset := id.OffsetInFile()
+		var data io.ReadSeeker = reader
+		skipped := int64(0)
+		if u.appendOffset > offset {
+			chunkEnd := offset + reader.Length()
+			if u.appendOffset >= chunkEnd {
+				return
+			}
+			skipped = u.appendOffset - offset
+			data = newRangeReadSeeker(reader, skipped, chunkEnd-u.appendOffset)
+			offset = u.appendOffset
+		}
+		ctx := withUploadCrc64(u.jptm, u.jptm.Context(), reader, skipped, reader.Length()-skipped)
+
 		u.jptm.LogChunkStatus(id, common.EWaitReason.Body())
-		body := newPacedRequestBody(u.jptm.Context(), reader, u.pacer)
-		_, err := u.destAppendBlobURL.AppendBlock(u.jptm.Context(), body,
+		body := newPacedRequestBody(u.jptm.Context(), data, u.pacer)
+		_, err := u.destAppendBlobURL.AppendBlock(ctx, body,
 			azblob.AppendBlobAccessConditions{
-				AppendPositionAccessConditions: azblob.AppendPositionAccessConditions{IfAppendPositionEqual: id.OffsetInFile()},
+				AppendPositionAccessConditions: azblob.AppendPositionAccessConditions{IfAppendPositionEqual: offset},
 			}, nil)
 		if err != nil {
 			u.jptm.FailActiveUpload("Appending block", err)
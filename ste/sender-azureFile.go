@@ -115,7 +115,7 @@ func newAzureFileSenderBase(jptm IJobPartTransferMgr, destination string, p pipe
 		ctx:             ctx,
 		headersToApply:  props.SrcHTTPHeaders.ToAzFileHTTPHeaders(),
 		sip:             sip,
-		metadataToApply: props.SrcMetadata.ToAzFileMetadata(),
+		metadataToApply: metadataToApply(jptm, sip, props.SrcMetadata).ToAzFileMetadata(),
 	}, nil
 }
 
@@ -21,6 +21,8 @@
 package ste
 
 import (
+	"errors"
+
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"math"
@@ -29,6 +31,7 @@ import (
 type blobFSUploader struct {
 	blobFSSenderBase
 	md5Channel chan []byte
+	sip        ISourceInfoProvider
 }
 
 func newBlobFSUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
@@ -37,7 +40,7 @@ func newBlobFSUploader(jptm IJobPartTransferMgr, destination string, p pipeline.
 		return nil, err
 	}
 
-	return &blobFSUploader{blobFSSenderBase: *senderBase, md5Channel: newMd5Channel()}, nil
+	return &blobFSUploader{blobFSSenderBase: *senderBase, md5Channel: newMd5Channel(), sip: sip}, nil
 
 }
 
@@ -91,4 +94,29 @@ func (u *blobFSUploader) Epilogue() {
 			jptm.FailActiveUpload("Getting hash", errNoHash) // don't return, since need cleanup below
 		}
 	}
+
+	// the owner can only be set once the file exists, so this is done after the flush
+	if jptm.IsLive() && jptm.Info().PreservePOSIXPermissions {
+		u.setPOSIXOwnership()
+	}
+}
+
+func (u *blobFSUploader) setPOSIXOwnership() {
+	jptm := u.jptm
+
+	posixSIP, ok := u.sip.(IPOSIXPropertyBearingSourceInfoProvider)
+	if !ok {
+		jptm.FailActiveUpload("Getting POSIX permissions", errors.New("the source has no POSIX permissions"))
+		return
+	}
+	ownership, err := posixSIP.GetPOSIXOwnership()
+	if err != nil {
+		jptm.FailActiveUpload("Getting POSIX permissions", err)
+		return
+	}
+
+	_, err = u.fileURL().SetAccessControl(jptm.Context(), ownership.Owner, ownership.Group, ownership.Permissions)
+	if err != nil {
+		jptm.FailActiveUpload("Setting POSIX permissions", err)
+	}
 }
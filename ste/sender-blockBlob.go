@@ -22,7 +22,9 @@ package ste
 
 import (
 	"context"
+	"crypto/md5"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/url"
@@ -52,9 +54,15 @@ type blockBlobSenderBase struct {
 	// the properties of the local file
 	headersToApply  azblob.BlobHTTPHeaders
 	metadataToApply azblob.Metadata
+	blobTagsToApply common.BlobTags
 
 	atomicPutListIndicator int32
 	muBlockIDs             *sync.Mutex
+
+	// block IDs start with this, so that they are the same each time the job is run
+	blockIDPrefix string
+	// when the job has been resumed, the blocks that an earlier run already staged, and their sizes
+	stagedBlocks map[string]int64
 }
 
 func getVerifiedChunkParams(transferInfo TransferInfo, memLimit int64) (chunkSize int64, numChunks uint32, err error) {
@@ -69,7 +77,7 @@ func getVerifiedChunkParams(transferInfo TransferInfo, memLimit int64) (chunkSiz
 	if common.MinParallelChunkCountThreshold >= memLimit/chunkSize {
 		glcm := common.GetLifecycleMgr()
 		msg := fmt.Sprintf("Using a blocksize of %.2fGiB for file %s. AzCopy is limited to use %.2fGiB of memory."+
-			"Consider providing at least %.2fGiB to AzCopy, using environment variable %s.",
+			"Consider providing at least %.2fGiB to AzCopy, using --memory-limit-gb or environment variable %s.",
 			toGiB(chunkSize), transferInfo.Source, toGiB(memLimit),
 			toGiB(common.MinParallelChunkCountThreshold*chunkSize),
 			common.EEnvironmentVariable.BufferGB().Name)
@@ -91,7 +99,12 @@ func getVerifiedChunkParams(transferInfo TransferInfo, memLimit int64) (chunkSiz
 	}
 
 	if numChunks > common.MaxNumberOfBlocksPerBlob {
-		err = fmt.Errorf("Block size %d for source of size %d is not correct. Number of blocks will exceed the limit", chunkSize, srcSize)
+		const MiB = 1024 * 1024
+		minBlockSize := (srcSize + common.MaxNumberOfBlocksPerBlob - 1) / common.MaxNumberOfBlocksPerBlob
+		minBlockSizeMiB := (minBlockSize + MiB - 1) / MiB
+		err = fmt.Errorf("block size of %d bytes is too small for file %s of size %.2fGiB. It would need %d blocks, but "+
+			"a BlockBlob can have at most %d. Use a block size of at least %dMiB, or leave out --block-size-mb so that AzCopy picks one for each file",
+			chunkSize, transferInfo.Source, toGiB(srcSize), numChunks, common.MaxNumberOfBlocksPerBlob, minBlockSizeMiB)
 		return
 	}
 
@@ -125,6 +138,8 @@ func newBlockBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipe
 		destBlobTier = blockBlobTierOverride.ToAccessTierType()
 	}
 
+	jobID, partNum, transferIndex := jptm.TransferIdentity()
+
 	return &blockBlobSenderBase{
 		jptm:             jptm,
 		destBlockBlobURL: destBlockBlobURL,
@@ -133,9 +148,11 @@ func newBlockBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipe
 		pacer:            pacer,
 		blockIDs:         make([]string, numChunks),
 		headersToApply:   props.SrcHTTPHeaders.ToAzBlobHTTPHeaders(),
-		metadataToApply:  props.SrcMetadata.ToAzBlobMetadata(),
+		metadataToApply:  metadataToApply(jptm, srcInfoProvider, props.SrcMetadata).ToAzBlobMetadata(),
+		blobTagsToApply:  blobTagsToApply(jptm.Info(), props),
 		destBlobTier:     destBlobTier,
-		muBlockIDs:       &sync.Mutex{}}, nil
+		muBlockIDs:       &sync.Mutex{},
+		blockIDPrefix:    getBlockIDPrefix(jobID, partNum, transferIndex, chunkSize)}, nil
 }
 
 func (s *blockBlobSenderBase) SendableEntityType() common.EntityType {
@@ -158,9 +175,37 @@ func (s *blockBlobSenderBase) Prologue(ps common.PrologueState) (destinationModi
 	// sometimes, specifically when reading local files, we have more info
 	// about the file type at this time than what we had before
 	s.headersToApply.ContentType = ps.GetInferredContentType(s.jptm)
+
+	// a file that fits in one chunk is sent as a whole blob, rather than as blocks
+	if s.jptm.JobWasResumed() && s.numChunks > 1 {
+		s.findStagedBlocks()
+	}
 	return false
 }
 
+// findStagedBlocks lists the blocks that an earlier run of the job staged before it was paused or failed, so that they
+// don't have to be sent again. It only saves time, so if the list can't be retrieved, every block is sent
+func (s *blockBlobSenderBase) findStagedBlocks() {
+	blockList, err := s.destBlockBlobURL.GetBlockList(s.jptm.Context(), azblob.BlockListUncommitted, azblob.LeaseAccessConditions{})
+	if err != nil || len(blockList.UncommittedBlocks) == 0 {
+		return // usually because nothing was staged, so the blob doesn't exist
+	}
+
+	s.stagedBlocks = make(map[string]int64, len(blockList.UncommittedBlocks))
+	for _, block := range blockList.UncommittedBlocks {
+		s.stagedBlocks[block.Name] = block.Size
+	}
+	s.jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo,
+		fmt.Sprintf("Found %d blocks already staged by an earlier run of the job", len(s.stagedBlocks)))
+}
+
+// isAlreadyStaged says whether the given block was staged by an earlier run of the job.
+// The size is checked too, in case the source has changed since then
+func (s *blockBlobSenderBase) isAlreadyStaged(encodedBlockID string, size int64) bool {
+	stagedSize, ok := s.stagedBlocks[encodedBlockID]
+	return ok && stagedSize == size
+}
+
 func (s *blockBlobSenderBase) Epilogue() {
 	jptm := s.jptm
 
@@ -179,7 +224,7 @@ func (s *blockBlobSenderBase) Epilogue() {
 		jptm.Log(pipeline.LogDebug, fmt.Sprintf("Conclude Transfer with BlockList %s", blockIDs))
 
 		// commit the blocks.
-		if _, err := s.destBlockBlobURL.CommitBlockList(jptm.Context(), blockIDs, s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}); err != nil {
+		if _, err := s.destBlockBlobURL.CommitBlockList(withBlobTags(jptm.Context(), s.blobTagsToApply), blockIDs, s.headersToApply, s.metadataToApply, azblob.BlobAccessConditions{}); err != nil {
 			jptm.FailActiveSend("Committing block list", err)
 			return
 		}
@@ -201,6 +246,11 @@ func (s *blockBlobSenderBase) Cleanup() {
 		deletionContext, cancelFn := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancelFn()
 		if jptm.WasCanceled() {
+			if jptm.JobIsPaused() {
+				// Keep what has been staged, so that it doesn't have to be sent again when the job is resumed
+				jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "Keeping uncommitted blocks, since the job has been paused")
+				return
+			}
 			// If we cancelled, and the only blocks that exist are uncommitted, then clean them up.
 			// This prevents customer paying for their storage for a week until they get garbage collected, and it
 			// also prevents any issues with "too many uncommitted blocks" if user tries to upload the blob again in future.
@@ -212,6 +262,10 @@ func (s *blockBlobSenderBase) Cleanup() {
 				// Delete can delete uncommitted blobs.
 				_, _ = s.destBlockBlobURL.Delete(deletionContext, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
 			}
+		} else if s.numChunks > 1 {
+			// Keep what has been staged, since resuming the job retries failed transfers, and can re-use the blocks.
+			// If the job is never resumed, the service removes the uncommitted blocks after a week
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "Keeping uncommitted blocks, so that they can be re-used if the job is resumed")
 		} else {
 			// TODO: review (one last time) should we really do this?  Or should we just give better error messages on "too many uncommitted blocks" errors
 			jptm.LogAtLevelForCurrentTransfer(pipeline.LogDebug, "Deleting destination blob due to failure")
@@ -229,7 +283,17 @@ func (s *blockBlobSenderBase) setBlockID(index int32, value string) {
 	s.blockIDs[index] = value
 }
 
-func (s *blockBlobSenderBase) generateEncodedBlockID() string {
-	blockID := common.NewUUID().String()
+// generateEncodedBlockID returns the ID of the block at the given index. It is the same each time the job is run, so that
+// a resumed job can tell which blocks were already staged before it was paused or failed
+func (s *blockBlobSenderBase) generateEncodedBlockID(index int32) string {
+	blockID := fmt.Sprintf("%s%05d", s.blockIDPrefix, index)
 	return base64.StdEncoding.EncodeToString([]byte(blockID))
 }
+
+// getBlockIDPrefix returns a block ID prefix that is unique to the transfer and its block size.
+// Its 31 characters, plus a five digit block index, make IDs as long as the UUIDs that we used to use,
+// because the service requires all of a blob's uncommitted blocks to have IDs of the same length
+func getBlockIDPrefix(jobID common.JobID, partNum PartNumber, transferIndex uint32, blockSize int64) string {
+	hash := md5.Sum([]byte(fmt.Sprintf("%s/%d/%d/%d", jobID.String(), partNum, transferIndex, blockSize)))
+	return hex.EncodeToString(hash[:])[:31]
+}
@@ -22,6 +22,7 @@ package ste
 
 import (
 	"bytes"
+	"crypto/sha256"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -66,15 +67,20 @@ func (u *blockBlobUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int
 func (u *blockBlobUploader) generatePutBlock(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader) chunkFunc {
 	return createSendToRemoteChunkFunc(u.jptm, id, func() {
 		// step 1: generate block ID
-		encodedBlockID := u.generateEncodedBlockID()
+		encodedBlockID := u.generateEncodedBlockID(blockIndex)
 
 		// step 2: save the block ID into the list of block IDs
 		u.setBlockID(blockIndex, encodedBlockID)
 
-		// step 3: put block to remote
+		// step 3: put block to remote, unless it was already put there before the job was paused
+		if u.isAlreadyStaged(encodedBlockID, reader.Length()) {
+			_ = reader.Close() // release its buffer, since it won't be sent
+			return
+		}
 		u.jptm.LogChunkStatus(id, common.EWaitReason.Body())
 		body := newPacedRequestBody(u.jptm.Context(), reader, u.pacer)
-		_, err := u.destBlockBlobURL.StageBlock(u.jptm.Context(), encodedBlockID, body, azblob.LeaseAccessConditions{}, nil)
+		ctx := withUploadCrc64(u.jptm, u.jptm.Context(), reader, 0, reader.Length())
+		_, err := u.destBlockBlobURL.StageBlock(ctx, encodedBlockID, body, azblob.LeaseAccessConditions{}, nil)
 		if err != nil {
 			u.jptm.FailActiveUpload("Staging block", err)
 			return
@@ -92,7 +98,9 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 		jptm.LogChunkStatus(id, common.EWaitReason.Body())
 		var err error
 		if jptm.Info().SourceSize == 0 {
-			_, err = u.destBlockBlobURL.Upload(jptm.Context(), bytes.NewReader(nil), u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{})
+			// the hashes of an empty file aren't waited for, so its checksums are set here
+			u.setChecksumMetadataOfEmptyFile()
+			_, err = u.destBlockBlobURL.Upload(withBlobTags(jptm.Context(), u.blobTagsToApply), bytes.NewReader(nil), u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{})
 		} else {
 			// File with content
 
@@ -106,7 +114,8 @@ func (u *blockBlobUploader) generatePutWholeBlob(id common.ChunkID, blockIndex i
 
 			// Upload the file
 			body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
-			_, err = u.destBlockBlobURL.Upload(jptm.Context(), body, u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{})
+			ctx := withUploadCrc64(jptm, withBlobTags(jptm.Context(), u.blobTagsToApply), reader, 0, reader.Length())
+			_, err = u.destBlockBlobURL.Upload(ctx, body, u.headersToApply, u.metadataToApply, azblob.BlobAccessConditions{})
 		}
 
 		// if the put blob is a failure, update the transfer status to failed
@@ -136,6 +145,23 @@ func (u *blockBlobUploader) Epilogue() {
 	u.blockBlobSenderBase.Epilogue()
 }
 
+// SetChecksumMetadata adds a checksum of the whole file to the metadata that the blob is committed with
+func (u *blockBlobUploader) SetChecksumMetadata(key string, value string) {
+	if u.metadataToApply == nil {
+		u.metadataToApply = azblob.Metadata{}
+	}
+	u.metadataToApply[key] = value
+}
+
+func (u *blockBlobUploader) setChecksumMetadataOfEmptyFile() {
+	if u.jptm.ShouldPutCrc64() {
+		u.SetChecksumMetadata(common.Crc64MetadataKey, encodeCrc64(0))
+	}
+	if u.jptm.ShouldPutSha256() {
+		u.SetChecksumMetadata(common.Sha256MetadataKey, encodeSha256(sha256.New().Sum(nil)))
+	}
+}
+
 func (u *blockBlobUploader) GetDestinationLength() (int64, error) {
 	prop, err := u.destBlockBlobURL.GetProperties(u.jptm.Context(), azblob.BlobAccessConditions{})
 
@@ -23,7 +23,10 @@ package ste
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"net/url"
+	"sync/atomic"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
@@ -35,6 +38,11 @@ type urlToBlockBlobCopier struct {
 	blockBlobSenderBase
 
 	srcURL url.URL
+
+	// how the data is copied. With Auto, it's copied by Put Block From URL, unless the service can't read the source,
+	// in which case atomicFellBack is set, and the blocks are read through AzCopy instead
+	copyMethod     common.S2SCopyMethod
+	atomicFellBack int32
 }
 
 func newURLToBlockBlobCopier(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, srcInfoProvider IRemoteSourceInfoProvider) (s2sCopier, error) {
@@ -57,9 +65,22 @@ func newURLToBlockBlobCopier(jptm IJobPartTransferMgr, destination string, p pip
 		return nil, err
 	}
 
+	copyMethod := jptm.Info().S2SCopyMethod
+	if copyMethod == common.ES2SCopyMethod.CopyBlob() && jptm.Info().SourceSize > 0 {
+		// the service copies the whole blob at once
+		senderBase.chunkSize = jptm.Info().SourceSize
+		senderBase.numChunks = 1
+		senderBase.blockIDs = make([]string, 1)
+	}
+	jptm.SetS2SCopyMethod(copyMethodUsed(copyMethod))
+	if jptm.ShouldLog(pipeline.LogInfo) {
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, fmt.Sprintf("Copying data with %s.", copyMethodUsed(copyMethod)))
+	}
+
 	return &urlToBlockBlobCopier{
 		blockBlobSenderBase: *senderBase,
-		srcURL:              *srcURL}, nil
+		srcURL:              *srcURL,
+		copyMethod:          copyMethod}, nil
 }
 
 // Returns a chunk-func for blob copies
@@ -68,6 +89,10 @@ func (c *urlToBlockBlobCopier) GenerateCopyFunc(id common.ChunkID, blockIndex in
 		setPutListNeed(&c.atomicPutListIndicator, putListNotNeeded)
 		return c.generateCreateEmptyBlob(id)
 	}
+	if c.copyMethod == common.ES2SCopyMethod.CopyBlob() {
+		setPutListNeed(&c.atomicPutListIndicator, putListNotNeeded)
+		return c.generateAsyncCopyBlob(id)
+	}
 
 	setPutListNeed(&c.atomicPutListIndicator, putListNeeded)
 	return c.generatePutBlockFromURL(id, blockIndex, adjustedChunkSize)
@@ -96,7 +121,7 @@ func (c *urlToBlockBlobCopier) generateCreateEmptyBlob(id common.ChunkID) chunkF
 
 		jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
 		// Create blob and finish.
-		if _, err := c.destBlockBlobURL.Upload(c.jptm.Context(), bytes.NewReader(nil), c.headersToApply, c.metadataToApply, azblob.BlobAccessConditions{}); err != nil {
+		if _, err := c.destBlockBlobURL.Upload(withBlobTags(c.jptm.Context(), c.blobTagsToApply), bytes.NewReader(nil), c.headersToApply, c.metadataToApply, azblob.BlobAccessConditions{}); err != nil {
 			jptm.FailActiveSend("Creating empty blob", err)
 			return
 		}
@@ -125,12 +150,19 @@ func (c *urlToBlockBlobCopier) generateCreateEmptyBlob(id common.ChunkID) chunkF
 func (c *urlToBlockBlobCopier) generatePutBlockFromURL(id common.ChunkID, blockIndex int32, adjustedChunkSize int64) chunkFunc {
 	return createSendToRemoteChunkFunc(c.jptm, id, func() {
 		// step 1: generate block ID
-		encodedBlockID := c.generateEncodedBlockID()
+		encodedBlockID := c.generateEncodedBlockID(blockIndex)
 
 		// step 2: save the block ID into the list of block IDs
 		c.setBlockID(blockIndex, encodedBlockID)
 
-		// step 3: put block to remote
+		// step 3: put block to remote, unless it was already put there before the job was paused
+		if c.isAlreadyStaged(encodedBlockID, adjustedChunkSize) {
+			return
+		}
+		if c.copyMethod == common.ES2SCopyMethod.DownloadUpload() || atomic.LoadInt32(&c.atomicFellBack) == 1 {
+			c.stageDownloadedBlock(id, encodedBlockID, adjustedChunkSize)
+			return
+		}
 		c.jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
 
 		// Set the latest service version from sdk as service version in the context, to use StageBlockFromURL API
@@ -141,6 +173,15 @@ func (c *urlToBlockBlobCopier) generatePutBlockFromURL(id common.ChunkID, blockI
 		}
 		_, err := c.destBlockBlobURL.StageBlockFromURL(ctxWithLatestServiceVersion, encodedBlockID, c.srcURL,
 			id.OffsetInFile(), adjustedChunkSize, azblob.LeaseAccessConditions{}, azblob.ModifiedAccessConditions{})
+		if err != nil && canFallBackToDownloadUpload(c.copyMethod, c.jptm.FromTo(), err) {
+			if atomic.CompareAndSwapInt32(&c.atomicFellBack, 0, 1) {
+				c.jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning,
+					fmt.Sprintf("The service could not read the source, so its data will be read through AzCopy instead: %s", err))
+				c.jptm.SetS2SCopyMethod(common.ES2SCopyMethod.DownloadUpload())
+			}
+			c.stageDownloadedBlock(id, encodedBlockID, adjustedChunkSize)
+			return
+		}
 		if err != nil {
 			c.jptm.FailActiveSend("Staging block from URL", err)
 			return
@@ -148,6 +189,64 @@ func (c *urlToBlockBlobCopier) generatePutBlockFromURL(id common.ChunkID, blockI
 	})
 }
 
+// stageDownloadedBlock reads a block from the source into memory, the way that a download would, and sends it on to
+// the destination, the way that an upload would
+func (c *urlToBlockBlobCopier) stageDownloadedBlock(id common.ChunkID, encodedBlockID string, adjustedChunkSize int64) {
+	jptm := c.jptm
+
+	// the block counts against the memory limit, just like the blocks of uploads and downloads
+	if err := jptm.CacheLimiter().WaitUntilAdd(jptm.Context(), adjustedChunkSize, func() bool { return false }); err != nil {
+		jptm.FailActiveSend("Waiting for memory to read block", err)
+		return
+	}
+	defer jptm.CacheLimiter().Remove(adjustedChunkSize)
+	buffer := jptm.SlicePool().RentSlice(adjustedChunkSize)
+	defer jptm.SlicePool().ReturnSlice(buffer)
+
+	jptm.LogChunkStatus(id, common.EWaitReason.HeaderResponse())
+	get, err := azblob.NewBlobURL(c.srcURL, jptm.SourceProviderPipeline()).Download(jptm.Context(), id.OffsetInFile(), adjustedChunkSize, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		jptm.FailActiveSend("Reading block from source", err)
+		return
+	}
+	jptm.LogChunkStatus(id, common.EWaitReason.Body())
+	body := get.Body(azblob.RetryReaderOptions{
+		MaxRetryRequests: MaxRetryPerDownloadBody,
+		NotifyFailedRead: common.NewReadLogFunc(jptm, &c.srcURL),
+	})
+	defer body.Close()
+	if _, err = io.ReadFull(body, buffer); err != nil {
+		jptm.FailActiveSend("Reading block from source", err)
+		return
+	}
+
+	if err = c.pacer.RequestTrafficAllocation(jptm.Context(), adjustedChunkSize); err != nil {
+		jptm.FailActiveSend("Pacing block", err)
+		return
+	}
+	if _, err = c.destBlockBlobURL.StageBlock(jptm.Context(), encodedBlockID, bytes.NewReader(buffer), azblob.LeaseAccessConditions{}, nil); err != nil {
+		jptm.FailActiveSend("Staging block", err)
+	}
+}
+
+// generateAsyncCopyBlob generates a func that has the service copy the whole blob in the background, and waits until it's done.
+// The service copies the source's properties, and the metadata that we give
+func (c *urlToBlockBlobCopier) generateAsyncCopyBlob(id common.ChunkID) chunkFunc {
+	return createSendToRemoteChunkFunc(c.jptm, id, func() {
+		jptm := c.jptm
+
+		jptm.LogChunkStatus(id, common.EWaitReason.S2SCopyOnWire())
+		resp, err := c.destBlockBlobURL.StartCopyFromURL(jptm.Context(), c.srcURL, c.metadataToApply, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{})
+		if err != nil {
+			jptm.FailActiveSend("Starting copy of blob", err)
+			return
+		}
+		if err = waitForAsyncCopy(jptm.Context(), c.destBlockBlobURL.BlobURL, resp.CopyID()); err != nil {
+			jptm.FailActiveSend("Copying blob", err)
+		}
+	})
+}
+
 // GetDestinationLength gets the destination length.
 func (c *urlToBlockBlobCopier) GetDestinationLength() (int64, error) {
 	ctxWithLatestServiceVersion := context.WithValue(c.jptm.Context(), ServiceAPIVersionOverride, azblob.ServiceVersion)
@@ -48,6 +48,7 @@ type pageBlobSenderBase struct {
 	// the properties of the local file
 	headersToApply  azblob.BlobHTTPHeaders
 	metadataToApply azblob.Metadata
+	blobTagsToApply common.BlobTags
 	destBlobTier    azblob.AccessTierType
 	// filePacer is necessary because page blobs have per-blob throughput limits. The limits depend on
 	// what type of page blob it is (e.g. premium) and can be significantly lower than the blob account limit.
@@ -127,7 +128,8 @@ func newPageBlobSenderBase(jptm IJobPartTransferMgr, destination string, p pipel
 		numChunks:              numChunks,
 		pacer:                  pacer,
 		headersToApply:         props.SrcHTTPHeaders.ToAzBlobHTTPHeaders(),
-		metadataToApply:        props.SrcMetadata.ToAzBlobMetadata(),
+		metadataToApply:        metadataToApply(jptm, srcInfoProvider, props.SrcMetadata).ToAzBlobMetadata(),
+		blobTagsToApply:        blobTagsToApply(jptm.Info(), props),
 		destBlobTier:           destBlobTier,
 		filePacer:              newNullAutoPacer(), // defer creation of real one to Prologue
 		destPageRangeOptimizer: destRangeOptimizer,
@@ -154,6 +156,23 @@ func isInLegacyDiskExportAccount(u url.URL) bool {
 	return strings.HasPrefix(u.Host, legacyDiskExportPrefix) // md-....
 }
 
+// IsManagedDiskURL tells whether u is one of the SAS URLs that managed disks give for importing or exporting their data.
+// Storage account names can't have hyphens, so nothing else has the md- prefix
+func IsManagedDiskURL(u url.URL) bool {
+	return strings.HasPrefix(u.Host, legacyDiskExportPrefix)
+}
+
+// IsManagedDiskImportURL tells whether u is the URL of a managed disk's page blob that is open for uploading to
+func IsManagedDiskImportURL(u url.URL) bool {
+	return isInManagedDiskImportExportAccount(u)
+}
+
+// managed disks can only be page blobs, so that's what is sent to them, whatever the type or name of the source
+func destinationIsManagedDisk(destination string) bool {
+	u, err := url.Parse(destination)
+	return err == nil && isInManagedDiskImportExportAccount(*u)
+}
+
 func (s *pageBlobSenderBase) isInManagedDiskImportExportAccount() bool {
 	return isInManagedDiskImportExportAccount(s.destPageBlobURL.URL())
 }
@@ -227,7 +246,7 @@ func (s *pageBlobSenderBase) Prologue(ps common.PrologueState) (destinationModif
 	// about the file type at this time than what we had before
 	s.headersToApply.ContentType = ps.GetInferredContentType(s.jptm)
 
-	if _, err := s.destPageBlobURL.Create(s.jptm.Context(),
+	if _, err := s.destPageBlobURL.Create(withBlobTags(s.jptm.Context(), s.blobTagsToApply),
 		s.srcSize,
 		0,
 		s.headersToApply,
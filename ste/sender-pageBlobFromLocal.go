@@ -81,19 +81,40 @@ func (u *pageBlobUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int3
 			}
 		}
 
+		// when uploading a VHD, only the parts of the chunk that hold data are sent
+		ranges, sparse := u.vhdDataRanges(reader)
+		sendLength := reader.Length()
+		if sparse {
+			sendLength = 0
+			for _, r := range ranges {
+				sendLength += r.Length
+			}
+		}
+
 		// control rate of sending (since page blobs can effectively have per-blob throughput limits)
 		// Note that this level of control here is specific to the individual page blob, and is additional
 		// to the application-wide pacing that we (optionally) do below when writing the response body.
 		jptm.LogChunkStatus(id, common.EWaitReason.FilePacer())
-		if err := u.filePacer.RequestTrafficAllocation(jptm.Context(), reader.Length()); err != nil {
+		if err := u.filePacer.RequestTrafficAllocation(jptm.Context(), sendLength); err != nil {
 			jptm.FailActiveUpload("Pacing block", err)
 		}
 
 		// send it
 		jptm.LogChunkStatus(id, common.EWaitReason.Body())
-		body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
 		enrichedContext := withRetryNotification(jptm.Context(), u.filePacer)
-		_, err := u.destPageBlobURL.UploadPages(enrichedContext, id.OffsetInFile(), body, azblob.PageBlobAccessConditions{}, nil)
+		if sparse {
+			for _, r := range ranges {
+				body := newPacedRequestBody(jptm.Context(), newRangeReadSeeker(reader, r.Offset, r.Length), u.pacer)
+				_, err := u.destPageBlobURL.UploadPages(withUploadCrc64(jptm, enrichedContext, reader, r.Offset, r.Length), id.OffsetInFile()+r.Offset, body, azblob.PageBlobAccessConditions{}, nil)
+				if err != nil {
+					jptm.FailActiveUpload("Uploading page", err)
+					return
+				}
+			}
+			return
+		}
+		body := newPacedRequestBody(jptm.Context(), reader, u.pacer)
+		_, err := u.destPageBlobURL.UploadPages(withUploadCrc64(jptm, enrichedContext, reader, 0, reader.Length()), id.OffsetInFile(), body, azblob.PageBlobAccessConditions{}, nil)
 		if err != nil {
 			jptm.FailActiveUpload("Uploading page", err)
 			return
@@ -101,6 +122,20 @@ func (u *pageBlobUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int3
 	})
 }
 
+// vhdDataRanges gives the parts of a chunk of a VHD that aren't zero pages, which are all that need to be sent, since
+// a new page blob is all zeros. sparse is false if the whole chunk must be sent, including when the destination is a
+// managed disk import/export blob, where the zero pages may be replacing data that's already there
+func (u *pageBlobUploader) vhdDataRanges(reader common.SingleChunkReader) (ranges []common.DataRange, sparse bool) {
+	if !u.jptm.Info().UploadVHD || u.destPageRangeOptimizer != nil {
+		return nil, false
+	}
+	ranges, ok := reader.PrefetchedDataRanges(azblob.PageBlobPageBytes, vhdMinZeroGap)
+	if !ok || len(ranges) == 1 && ranges[0].Length == reader.Length() {
+		return nil, false
+	}
+	return ranges, true
+}
+
 func (u *pageBlobUploader) Epilogue() {
 	jptm := u.jptm
 
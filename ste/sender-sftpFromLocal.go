@@ -0,0 +1,165 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// sftpUploader writes local files to an SFTP server, each chunk being written at its offset in the file. SFTP has
+// nowhere to keep headers, metadata or hashes, so only the content is sent
+type sftpUploader struct {
+	jptm       IJobPartTransferMgr
+	ctx        context.Context
+	pacer      pacer
+	chunkSize  int64
+	numChunks  uint32
+	md5Channel chan []byte
+
+	path       string // on the server
+	sftpClient *common.SFTPClient
+	file       *common.SFTPFile // opened by the prologue
+}
+
+func newSFTPUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
+	info := jptm.Info()
+
+	dstURLParts, err := common.NewSFTPURLParts(destination)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftpClientFactory.GetSFTPClient(dstURLParts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sftpUploader{
+		jptm:       jptm,
+		ctx:        jptm.Context(),
+		pacer:      pacer,
+		chunkSize:  info.BlockSize,
+		numChunks:  getNumChunks(info.SourceSize, info.BlockSize),
+		md5Channel: newMd5Channel(),
+		path:       dstURLParts.Path,
+		sftpClient: client,
+	}, nil
+}
+
+func (u *sftpUploader) ChunkSize() int64 {
+	return u.chunkSize
+}
+
+func (u *sftpUploader) NumChunks() uint32 {
+	return u.numChunks
+}
+
+func (u *sftpUploader) Md5Channel() chan<- []byte {
+	return u.md5Channel
+}
+
+func (u *sftpUploader) RemoteFileExists() (bool, time.Time, error) {
+	info, err := u.sftpClient.Stat(u.path)
+	if common.IsSFTPNotExist(err) {
+		return false, time.Time{}, nil
+	} else if err != nil {
+		return false, time.Time{}, err
+	}
+	return true, info.ModTime(), nil
+}
+
+func (u *sftpUploader) Prologue(state common.PrologueState) (destinationModified bool) {
+	// SFTP servers have real directories, which must exist before files can be written in them
+	if err := u.sftpClient.MkdirAll(path.Dir(u.path)); err != nil {
+		u.jptm.FailActiveUpload("Creating parent directory", err)
+		return true
+	}
+
+	file, err := u.sftpClient.Create(u.path)
+	if err != nil {
+		u.jptm.FailActiveUpload("Creating file", err)
+		return true
+	}
+	u.file = file
+	return true
+}
+
+func (u *sftpUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader, chunkIsWholeFile bool) chunkFunc {
+	return createSendToRemoteChunkFunc(u.jptm, id, func() {
+		jptm := u.jptm
+
+		defer reader.Close()
+
+		if jptm.Info().SourceSize == 0 || u.file == nil {
+			// nothing to do, since this is a dummy chunk in a zero-size file (which the prologue has created), or
+			// the prologue failed
+			return
+		}
+
+		jptm.LogChunkStatus(id, common.EWaitReason.Body())
+		data := jptm.SlicePool().RentSlice(reader.Length())
+		defer jptm.SlicePool().ReturnSlice(data)
+		if _, err := io.ReadFull(newPacedRequestBody(u.ctx, reader, u.pacer), data); err != nil {
+			jptm.FailActiveUpload("Reading chunk", err)
+			return
+		}
+		if _, err := u.file.WriteAt(data, id.OffsetInFile()); err != nil {
+			jptm.FailActiveUpload("Writing chunk", err)
+			return
+		}
+	})
+}
+
+func (u *sftpUploader) Epilogue() {
+	if u.file == nil {
+		return
+	}
+	// the server may not report a failure to write the data until the file is closed
+	if err := u.file.Close(); err != nil && u.jptm.IsLive() {
+		u.jptm.FailActiveUpload("Closing file", err)
+	}
+}
+
+func (u *sftpUploader) Cleanup() {
+	jptm := u.jptm
+
+	if jptm.IsDeadInflight() && u.file != nil {
+		// the file's contents will be at an unknown stage of partial completeness
+		if err := u.sftpClient.Remove(u.path); err != nil {
+			jptm.Log(pipeline.LogError, fmt.Sprintf("error deleting the (incomplete) file %s. Failed with error %s", u.path, err.Error()))
+		}
+	}
+}
+
+func (u *sftpUploader) GetDestinationLength() (int64, error) {
+	info, err := u.sftpClient.Stat(u.path)
+	if err != nil {
+		return -1, err
+	}
+	return info.Size(), nil
+}
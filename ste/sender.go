@@ -107,6 +107,21 @@ type uploader interface {
 	Md5Channel() chan<- []byte
 }
 
+// checksumMetadataUploader is implemented by the uploaders that can store checksums of the whole file, such as its
+// CRC64, in its metadata. anyToRemote sets them before it sends the MD5, so the uploader has them by the time it has
+// received the MD5
+type checksumMetadataUploader interface {
+	SetChecksumMetadata(key string, value string)
+}
+
+// prefixSkippingUploader is implemented by the uploaders that may find, in their prologue, that the start of the file
+// is at the destination already. anyToRemote doesn't read the chunks that lie wholly before the offset they return,
+// unless it needs them for the checksums of the whole file
+type prefixSkippingUploader interface {
+	// SendOffset returns the offset in the file of the first byte that must be sent
+	SendOffset() int64
+}
+
 func newMd5Channel() chan []byte {
 	return make(chan []byte, 1) // must be buffered, so as not to hold up the goroutine running anyToRemote (which needs to start on the NEXT file after finishing its current one)
 }
@@ -182,17 +197,7 @@ func createChunkFunc(setDoneStatusOnExit bool, jptm IJobPartTransferMgr, id comm
 
 // newBlobUploader detects blob type and creates a uploader manually
 func newBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pipeline, pacer pacer, sip ISourceInfoProvider) (sender, error) {
-	override := jptm.BlobTypeOverride()
-	intendedType := override.ToAzBlobType()
-
-	if override == common.EBlobType.Detect() {
-		intendedType = inferBlobType(jptm.Info().Source, azblob.BlobBlockBlob)
-		// jptm.LogTransferInfo(fmt.Sprintf("Autodetected %s blob type as %s.", jptm.Info().Source , intendedType))
-		// TODO: Log these? @JohnRusk and @zezha-msft this creates quite a bit of spam in the logs but is important info.
-		// TODO: Perhaps we should log it only if it isn't a block blob?
-	}
-
-	switch intendedType {
+	switch intendedBlobType(jptm) {
 	case azblob.BlobBlockBlob:
 		return newBlockBlobUploader(jptm, destination, p, pacer, sip)
 	case azblob.BlobPageBlob:
@@ -203,3 +208,18 @@ func newBlobUploader(jptm IJobPartTransferMgr, destination string, p pipeline.Pi
 		return newBlockBlobUploader(jptm, destination, p, pacer, sip) // If no blob type was inferred, assume block blob.
 	}
 }
+
+// intendedBlobType gives the type of blob that an upload creates
+func intendedBlobType(jptm IJobPartTransferMgr) azblob.BlobType {
+	override := jptm.BlobTypeOverride()
+	if override == common.EBlobType.Detect() {
+		// jptm.LogTransferInfo(fmt.Sprintf("Autodetected %s blob type as %s.", jptm.Info().Source , intendedType))
+		// TODO: Log these? @JohnRusk and @zezha-msft this creates quite a bit of spam in the logs but is important info.
+		// TODO: Perhaps we should log it only if it isn't a block blob?
+		if destinationIsManagedDisk(jptm.Info().Destination) {
+			return azblob.BlobPageBlob
+		}
+		return inferBlobType(jptm.Info().Source, azblob.BlobBlockBlob)
+	}
+	return override.ToAzBlobType()
+}
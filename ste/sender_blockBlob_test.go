@@ -21,8 +21,10 @@
 package ste
 
 import (
+	"encoding/base64"
 	"fmt"
 
+	"github.com/Azure/azure-storage-azcopy/common"
 	chk "gopkg.in/check.v1"
 )
 
@@ -53,8 +55,70 @@ func (s *blockBlobSuite) TestGetVerifiedChunkParams(c *chk.C) {
 	// High block count
 	transferInfo.SourceSize = 2147483648 //16GiB
 	transferInfo.BlockSize = 2048        // 2KiB
-	expectedErr = fmt.Sprintf("Block size 2048 for source of size 2147483648 is not correct. Number of blocks will exceed the limit")
+	expectedErr = fmt.Sprintf("block size of 2048 bytes is too small for file tmpSrc of size 2.00GiB. It would need 1048576 blocks, but " +
+		"a BlockBlob can have at most 50000. Use a block size of at least 1MiB, or leave out --block-size-mb so that AzCopy picks one for each file")
 	_, _, err = getVerifiedChunkParams(transferInfo, memLimit)
 	c.Assert(err.Error(), chk.Equals, expectedErr)
 
 }
+
+func (s *blockBlobSuite) TestAutoBlockSizeFitsInBlockLimit(c *chk.C) {
+	const MiB = 1024 * 1024
+	c.Assert(autoBlockSize(0), chk.Equals, int64(8*MiB))
+	c.Assert(autoBlockSize(100*MiB), chk.Equals, int64(8*MiB))
+
+	// exactly 50000 blocks still fits, but one more byte needs a bigger block
+	c.Assert(autoBlockSize(common.MaxNumberOfBlocksPerBlob*8*MiB), chk.Equals, int64(8*MiB))
+	c.Assert(autoBlockSize(common.MaxNumberOfBlocksPerBlob*8*MiB+1), chk.Equals, int64(16*MiB))
+
+	// beyond the doubling threshold, the size is computed directly, and must still fit
+	for _, size := range []int64{600 * 1024 * MiB * 50, 30*1024*1024*MiB + 1, 190 * 1024 * 1024 * MiB} {
+		blockSize := autoBlockSize(size)
+		c.Assert(blockSize > common.BlockSizeThreshold, chk.Equals, true)
+		c.Assert(int64(getNumChunks(size, blockSize)) <= common.MaxNumberOfBlocksPerBlob, chk.Equals, true, chk.Commentf("size %d", size))
+	}
+}
+
+func (s *blockBlobSuite) TestBlockIDsAreTheSameEachRun(c *chk.C) {
+	jobID := common.NewJobID()
+	sender := &blockBlobSenderBase{blockIDPrefix: getBlockIDPrefix(jobID, 2, 7, 8*1024*1024)}
+	resumedSender := &blockBlobSenderBase{blockIDPrefix: getBlockIDPrefix(jobID, 2, 7, 8*1024*1024)}
+
+	for _, index := range []int32{0, 1, common.MaxNumberOfBlocksPerBlob - 1} {
+		encodedBlockID := sender.generateEncodedBlockID(index)
+		c.Assert(resumedSender.generateEncodedBlockID(index), chk.Equals, encodedBlockID)
+
+		// the IDs must stay as long as the UUIDs that were used before, because all of a blob's blocks must have IDs of the same length
+		blockID, err := base64.StdEncoding.DecodeString(encodedBlockID)
+		c.Assert(err, chk.IsNil)
+		c.Assert(blockID, chk.HasLen, len(common.NewUUID().String()))
+	}
+	c.Assert(sender.generateEncodedBlockID(1), chk.Not(chk.Equals), sender.generateEncodedBlockID(2))
+}
+
+func (s *blockBlobSuite) TestBlockIDsAreUniqueToTheTransfer(c *chk.C) {
+	jobID := common.NewJobID()
+	prefix := getBlockIDPrefix(jobID, 0, 1, 4*1024*1024)
+
+	c.Assert(getBlockIDPrefix(common.NewJobID(), 0, 1, 4*1024*1024), chk.Not(chk.Equals), prefix)
+	c.Assert(getBlockIDPrefix(jobID, 1, 1, 4*1024*1024), chk.Not(chk.Equals), prefix)
+	c.Assert(getBlockIDPrefix(jobID, 0, 2, 4*1024*1024), chk.Not(chk.Equals), prefix)
+
+	// so that blocks aren't reused if the block size is different
+	c.Assert(getBlockIDPrefix(jobID, 0, 1, 8*1024*1024), chk.Not(chk.Equals), prefix)
+}
+
+func (s *blockBlobSuite) TestOnlyBlocksOfTheRightSizeAreAlreadyStaged(c *chk.C) {
+	sender := &blockBlobSenderBase{blockIDPrefix: getBlockIDPrefix(common.NewJobID(), 0, 0, 1024)}
+	staged := sender.generateEncodedBlockID(0)
+	changed := sender.generateEncodedBlockID(1)
+	sender.stagedBlocks = map[string]int64{staged: 1024, changed: 512}
+
+	c.Assert(sender.isAlreadyStaged(staged, 1024), chk.Equals, true)
+	c.Assert(sender.isAlreadyStaged(changed, 1024), chk.Equals, false)
+	c.Assert(sender.isAlreadyStaged(sender.generateEncodedBlockID(2), 1024), chk.Equals, false)
+
+	// nothing is staged when the job hasn't been resumed
+	sender.stagedBlocks = nil
+	c.Assert(sender.isAlreadyStaged(staged, 1024), chk.Equals, false)
+}
@@ -40,6 +40,24 @@ func newBlobSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, e
 	return &blobSourceInfoProvider{defaultRemoteSourceInfoProvider: *base}, nil
 }
 
+func (p *blobSourceInfoProvider) Properties() (*SrcProperties, error) {
+	props, err := p.defaultRemoteSourceInfoProvider.Properties()
+	if err != nil || !p.transferInfo.S2SPreserveBlobTags {
+		return props, err
+	}
+
+	// index tags aren't part of the properties we get when listing, so they're fetched here
+	presignedURL, err := p.PreSignedSourceURL()
+	if err != nil {
+		return nil, err
+	}
+	props.SrcBlobTags, err = getBlobTags(p.jptm.Context(), p.jptm.SourceProviderPipeline(), *presignedURL)
+	if err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
 func (p *blobSourceInfoProvider) BlobTier() azblob.AccessTierType {
 	return p.transferInfo.S2SSrcBlobTier
 }
@@ -0,0 +1,57 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Source info provider for plain HTTP(S) URLs. The service reads the source itself, range by range, as it does for S3,
+// so the properties that the enumerator got from the HEAD request are all we need, until we check for changes
+type httpSourceInfoProvider struct {
+	defaultRemoteSourceInfoProvider
+}
+
+func newHTTPSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	base, err := newDefaultRemoteSourceInfoProvider(jptm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpSourceInfoProvider{defaultRemoteSourceInfoProvider: *base}, nil
+}
+
+func (p *httpSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
+	presigned, err := p.PreSignedSourceURL()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	props, err := common.GetHTTPResourceProperties(p.jptm.Context(), p.jptm.SourceProviderPipeline(), *presigned)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// a server that gives no Last-Modified gives the zero time, here and to the enumerator alike, so the two still match
+	return props.LastModified(), nil
+}
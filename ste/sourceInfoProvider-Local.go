@@ -21,9 +21,13 @@
 package ste
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-azcopy/common"
 )
 
@@ -41,7 +45,70 @@ func (f localFileSourceInfoProvider) Properties() (*SrcProperties, error) {
 	// create simulated headers, to represent what we want to propagate to the destination based on
 	// this file
 
-	headers, metadata := f.jptm.ResourceDstData(nil) // we don't have a known MIME type yet, so pass nil for the sniffed content of the file
+	var sniffed []byte
+	if f.transferInfo.SniffContentType && f.transferInfo.EntityType == common.EEntityType.File() {
+		var err error
+		if sniffed, err = sniffContent(f.transferInfo.Source); err != nil {
+			return nil, err
+		}
+	}
+	headers, metadata := f.jptm.ResourceDstData(sniffed) // unless sniffing was asked for, this is nil, and the content type comes from the extension
+
+	if len(f.transferInfo.SrcMetadata) > 0 {
+		// the enumerator has recorded something about this particular file, such as the hard link it stands for
+		fileMetadata := common.Metadata{}
+		for k, v := range metadata {
+			fileMetadata[k] = v
+		}
+		for k, v := range f.transferInfo.SrcMetadata {
+			fileMetadata[k] = v
+		}
+		metadata = fileMetadata
+	}
+
+	if f.transferInfo.PreserveFileTimes && f.transferInfo.EntityType == common.EEntityType.File() {
+		// record the times of the file, so that a download with --preserve-info can put them back
+		timesMetadata, err := fileTimesMetadata(f.transferInfo.Source)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range metadata {
+			timesMetadata[k] = v
+		}
+		metadata = timesMetadata
+	}
+
+	if f.transferInfo.PreserveXattrs && f.transferInfo.EntityType == common.EEntityType.File() {
+		xattrs, err := readXattrs(f.transferInfo.Source)
+		if err != nil {
+			return nil, err
+		}
+		if len(xattrs) > 0 {
+			encoded, dropped := encodeXattrs(xattrs)
+			if len(dropped) > 0 {
+				f.jptm.Log(pipeline.LogWarning, fmt.Sprintf("Extended attributes %s of %s don't fit in the blob's metadata, and won't be preserved",
+					strings.Join(dropped, ", "), f.transferInfo.Source))
+			}
+			xattrsMetadata := common.Metadata{common.XattrsMetadataKey: encoded}
+			for k, v := range metadata {
+				xattrsMetadata[k] = v
+			}
+			metadata = xattrsMetadata
+		}
+	}
+
+	if f.transferInfo.EntityType == common.EEntityType.Symlink() {
+		// mark the blob, so that a download with --preserve-symlinks knows to turn it back into a link
+		linkMetadata := common.Metadata{common.SymlinkMetadataKey: "true"}
+		for k, v := range metadata {
+			linkMetadata[k] = v
+		}
+		metadata = linkMetadata
+	}
+
+	if f.transferInfo.CompressedSource != "" && f.transferInfo.PackOnUpload == common.EPackFormat.None() {
+		headers.ContentEncoding = "gzip" // what's uploaded is the compressed copy of the file
+	}
 
 	return &SrcProperties{
 		SrcHTTPHeaders: common.ResourceHTTPHeaders{
@@ -62,6 +129,19 @@ func (f localFileSourceInfoProvider) IsLocal() bool {
 func (f localFileSourceInfoProvider) OpenSourceFile() (common.CloseableReaderAt, error) {
 	path := f.jptm.Info().Source
 
+	if f.transferInfo.EntityType == common.EEntityType.Symlink() {
+		// the content of a preserved symlink is its target, not the content of the file it points to
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return symlinkTargetReader{strings.NewReader(target)}, nil
+	}
+
+	if f.transferInfo.CompressedSource != "" {
+		return os.Open(f.transferInfo.CompressedSource)
+	}
+
 	if custom, ok := interface{}(f).(ICustomLocalOpener); ok {
 		return custom.Open(path)
 	}
@@ -69,7 +149,11 @@ func (f localFileSourceInfoProvider) OpenSourceFile() (common.CloseableReaderAt,
 }
 
 func (f localFileSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
-	i, err := common.OSStat(f.jptm.Info().Source)
+	stat := common.OSStat
+	if f.transferInfo.EntityType == common.EEntityType.Symlink() {
+		stat = os.Lstat // the time of the link, as enumerated, rather than of its target
+	}
+	i, err := stat(f.jptm.Info().Source)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -79,3 +163,31 @@ func (f localFileSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time,
 func (f localFileSourceInfoProvider) EntityType() common.EntityType {
 	return f.transferInfo.EntityType
 }
+
+// symlinkTargetReader serves the target of a symlink as the content of the file
+type symlinkTargetReader struct {
+	*strings.Reader
+}
+
+func (symlinkTargetReader) Close() error {
+	return nil
+}
+
+// sniffLen is how many bytes http.DetectContentType considers
+const sniffLen = 512
+
+// sniffContent reads the start of a file, which is all that http.DetectContentType looks at
+func sniffContent(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
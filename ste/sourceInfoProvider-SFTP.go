@@ -0,0 +1,91 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// Source info provider for SFTP. The files are read through SFTP, and uploaded, just as local ones are
+type sftpSourceInfoProvider struct {
+	jptm         IJobPartTransferMgr
+	transferInfo TransferInfo
+
+	sftpURLParts common.SFTPURLParts
+	sftpClient   *common.SFTPClient
+}
+
+var sftpClientFactory = common.NewSFTPClientFactory()
+
+func newSFTPSourceInfoProvider(jptm IJobPartTransferMgr) (ISourceInfoProvider, error) {
+	var err error
+	p := sftpSourceInfoProvider{jptm: jptm, transferInfo: jptm.Info()}
+
+	p.sftpURLParts, err = common.NewSFTPURLParts(p.transferInfo.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	p.sftpClient, err = sftpClientFactory.GetSFTPClient(p.sftpURLParts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (p *sftpSourceInfoProvider) Properties() (*SrcProperties, error) {
+	// as for local files, the headers are those we want the destination to have, since SFTP has none of its own
+	headers, metadata := p.jptm.ResourceDstData(nil)
+
+	return &SrcProperties{
+		SrcHTTPHeaders: common.ResourceHTTPHeaders{
+			ContentType:        headers.ContentType,
+			ContentEncoding:    headers.ContentEncoding,
+			ContentLanguage:    headers.ContentLanguage,
+			ContentDisposition: headers.ContentDisposition,
+			CacheControl:       headers.CacheControl,
+		},
+		SrcMetadata: metadata,
+	}, nil
+}
+
+func (p *sftpSourceInfoProvider) IsLocal() bool {
+	return true
+}
+
+func (p *sftpSourceInfoProvider) OpenSourceFile() (common.CloseableReaderAt, error) {
+	return p.sftpClient.Open(p.sftpURLParts.Path)
+}
+
+func (p *sftpSourceInfoProvider) GetFreshFileLastModifiedTime() (time.Time, error) {
+	i, err := p.sftpClient.Stat(p.sftpURLParts.Path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return i.ModTime(), nil
+}
+
+func (p *sftpSourceInfoProvider) EntityType() common.EntityType {
+	return p.transferInfo.EntityType
+}
@@ -91,6 +91,20 @@ type ISMBPropertyBearingSourceInfoProvider interface {
 	GetSMBProperties() (TypedSMBPropertyHolder, error)
 }
 
+// POSIXOwnership is the owner, group and permissions of a file, in the form ADLS Gen2 takes them:
+// the user and group IDs as strings, and the permissions in 4-digit octal
+type POSIXOwnership struct {
+	Owner       string
+	Group       string
+	Permissions string
+}
+
+type IPOSIXPropertyBearingSourceInfoProvider interface {
+	ISourceInfoProvider
+
+	GetPOSIXOwnership() (POSIXOwnership, error)
+}
+
 type ICustomLocalOpener interface {
 	ISourceInfoProvider
 	Open(path string) (*os.File, error)
@@ -0,0 +1,245 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// A transfer is stalled if it has requests in flight, but no bytes have moved for it for a while (e.g. because a connection
+// has hung). The stallWatchdog finds such transfers, and cancels their tries, so that the retry policy sends them again,
+// on fresh connections. Without it, one hung connection can hold an otherwise-finished job open until the try timeout.
+// Response bodies of downloads are not covered here, because the chunkedFileWriter already forces retries of slow body reads.
+
+// stallWatchable is implemented by transfers that the stallWatchdog looks after.
+// They register themselves into the context, using withStallWatching, so that the stallDetectionPolicy can tell them about each try
+type stallWatchable interface {
+	// noteActivity is called whenever bytes move for the transfer
+	noteActivity()
+
+	// startTry is called when a request is sent. The cancel func cancels only that one try. The returned func must be
+	// called when the response (or an error) is received
+	startTry(cancel context.CancelFunc) (endTry func())
+}
+
+// withStallWatching returns a context that lets the stall watchdog know about the requests that are sent with it
+func withStallWatching(ctx context.Context, w stallWatchable) context.Context {
+	return context.WithValue(ctx, stallWatchContextKey, w)
+}
+
+var stallWatchContextKey = contextKey{"stallWatch"}
+
+// newStallDetectionPolicyFactory gives each try its own cancellable context.
+// It must go after the retry policy in the pipeline, so that it sees each try
+func newStallDetectionPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			w, ok := ctx.Value(stallWatchContextKey).(stallWatchable)
+			if !ok {
+				return next.Do(ctx, request)
+			}
+
+			// We can't cancel tryCtx when we return, since the response body may not have been read yet.
+			// But it ends, and is released, along with the retry policy's per-try context
+			tryCtx, cancel := context.WithCancel(ctx)
+			endTry := w.startTry(cancel)
+			defer endTry()
+			return next.Do(tryCtx, request)
+		}
+	})
+}
+
+// transferStallState is the per-transfer state that the stall watchdog needs
+type transferStallState struct {
+	mu        sync.Mutex
+	nextTryID int64
+	tries     map[int64]context.CancelFunc // the tries that are currently in flight
+	isWatched bool
+}
+
+func (jptm *jobPartTransferMgr) noteActivity() {
+	atomic.StoreInt64(&jptm.atomicLastActivityNanos, time.Now().UnixNano())
+}
+
+func (jptm *jobPartTransferMgr) startTry(cancel context.CancelFunc) (endTry func()) {
+	s := &jptm.stallState
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.tries) == 0 {
+		// the clock only runs while we have something in flight. (E.g. the transfer may have spent
+		// a long time waiting for its next chunk to be scheduled, and that doesn't count)
+		jptm.noteActivity()
+	}
+	if s.tries == nil {
+		s.tries = make(map[int64]context.CancelFunc)
+	}
+	id := s.nextTryID
+	s.nextTryID++
+	s.tries[id] = cancel
+
+	if !s.isWatched && atomic.LoadUint32(&jptm.atomicCompletionIndicator) == 0 {
+		s.isWatched = true
+		JobsAdmin.(*jobsAdmin).stallWatchdog.watch(jptm)
+	}
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.tries, id)
+	}
+}
+
+// isStalled says whether the transfer has had requests in flight, but no activity, for at least the given time
+func (jptm *jobPartTransferMgr) isStalled(now time.Time, timeout time.Duration) bool {
+	s := &jptm.stallState
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastActivity := time.Unix(0, atomic.LoadInt64(&jptm.atomicLastActivityNanos))
+	return len(s.tries) > 0 && now.Sub(lastActivity) >= timeout
+}
+
+// restartStalledTries cancels all the tries that are in flight, so that they will be retried. Returns how many there were
+func (jptm *jobPartTransferMgr) restartStalledTries() int {
+	s := &jptm.stallState
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.tries)
+	for id, cancel := range s.tries {
+		cancel()
+		delete(s.tries, id)
+	}
+	return count
+}
+
+// stopStallWatching is called when the transfer is done
+func (jptm *jobPartTransferMgr) stopStallWatching() {
+	s := &jptm.stallState
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isWatched {
+		JobsAdmin.(*jobsAdmin).stallWatchdog.unwatch(jptm)
+	}
+}
+
+// stallWatchdog periodically checks all the transfers that have requests in flight
+type stallWatchdog struct {
+	timeout       time.Duration
+	mu            sync.Mutex
+	transfers     map[*jobPartTransferMgr]struct{}
+	stallsByJobID map[common.JobID]uint32
+}
+
+// newStallWatchdog returns nil if the timeout is zero, since that means the watchdog is turned off
+func newStallWatchdog(timeout time.Duration) *stallWatchdog {
+	if timeout <= 0 {
+		return nil
+	}
+	return &stallWatchdog{
+		timeout:       timeout,
+		transfers:     make(map[*jobPartTransferMgr]struct{}),
+		stallsByJobID: make(map[common.JobID]uint32),
+	}
+}
+
+// stallCount says how many times the job's transfers have been found stalled, and restarted
+func (w *stallWatchdog) stallCount(jobID common.JobID) uint32 {
+	if w == nil {
+		return 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stallsByJobID[jobID]
+}
+
+func (w *stallWatchdog) watch(jptm *jobPartTransferMgr) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.transfers[jptm] = struct{}{}
+}
+
+func (w *stallWatchdog) unwatch(jptm *jobPartTransferMgr) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.transfers, jptm)
+}
+
+func (w *stallWatchdog) run() {
+	if w == nil {
+		return
+	}
+
+	// check often enough that a stall is acted on soon after it reaches the timeout
+	interval := w.timeout / 5
+	if interval < time.Second {
+		interval = time.Second
+	} else if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+	for range time.Tick(interval) {
+		w.check(time.Now())
+	}
+}
+
+// check restarts the transfers that are stalled, and returns how many it restarted
+func (w *stallWatchdog) check(now time.Time) int {
+	w.mu.Lock()
+	stalled := make([]*jobPartTransferMgr, 0)
+	for jptm := range w.transfers {
+		if jptm.isStalled(now, w.timeout) {
+			stalled = append(stalled, jptm)
+		}
+	}
+	w.mu.Unlock() // since restarting calls back into the jptm
+
+	restartedTransfers := 0
+	for _, jptm := range stalled {
+		jptm.noteActivity() // give the retries the full timeout, before they could count as stalled
+		restarted := jptm.restartStalledTries()
+		if restarted == 0 {
+			continue // it finished its tries in the meantime
+		}
+		restartedTransfers++
+		jobID, _, _ := jptm.TransferIdentity()
+		w.mu.Lock()
+		w.stallsByJobID[jobID]++
+		w.mu.Unlock()
+		jptm.Log(pipeline.LogWarning, fmt.Sprintf("No progress for %v, so cancelling %d request(s) in flight, which will be retried", w.timeout, restarted))
+	}
+	return restartedTransfers
+}
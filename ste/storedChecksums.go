@@ -0,0 +1,159 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The service has no properties for checksums other than Content-MD5, so --put-crc64 and --put-sha256 store the
+// checksums of each uploaded file in the metadata of its blob, and --check-crc64 and --check-sha256 check downloads
+// against them.
+
+// storedChecksum is a kind of checksum that's stored in the metadata of blobs
+type storedChecksum struct {
+	metadataKey string
+	errMismatch error
+	errMissing  error
+	noneStored  string
+}
+
+var crc64Checksum = storedChecksum{common.Crc64MetadataKey, errCrc64Mismatch, errExpectedCrc64Missing, noCrc64Stored}
+
+var sha256Checksum = storedChecksum{common.Sha256MetadataKey, errSha256Mismatch, errExpectedSha256Missing, noSha256Stored}
+
+var errSha256Mismatch = errors.New("the SHA-256 of the data, as we received it, did not match the value stored in the metadata of the blob. " +
+	"This means that either there is a data integrity error OR the blob has been changed by a tool that didn't update its SHA-256")
+
+const noSha256Stored = "no SHA-256 was stored in the metadata of this blob. So the downloaded data cannot be SHA-256-validated."
+
+var errExpectedSha256Missing = errors.New(noSha256Stored + " This application is currently configured to treat missing SHA-256 digests as errors")
+
+// encodeSha256 formats a SHA-256 in lower case hex, as tools such as sha256sum do, so that stored digests are easy to compare
+func encodeSha256(sum []byte) string {
+	return hex.EncodeToString(sum)
+}
+
+// check compares the checksum of the file, as we saved it, with the one stored in the metadata of the blob. As with MD5,
+// the validation option says whether a missing or different checksum fails the transfer or is just logged
+func (c storedChecksum) check(option common.HashValidationOption, srcMetadata common.Metadata, actual string, logger transferSpecificLogger) error {
+	if option == common.EHashValidationOption.NoCheck() {
+		return nil
+	}
+
+	expected, ok := srcMetadata[c.metadataKey]
+	if !ok {
+		if option == common.EHashValidationOption.FailIfDifferentOrMissing() {
+			return c.errMissing
+		}
+		logger.LogAtLevelForCurrentTransfer(pipeline.LogWarning, c.noneStored)
+		return nil
+	}
+
+	if expected != actual {
+		if option == common.EHashValidationOption.LogOnly() {
+			logger.LogAtLevelForCurrentTransfer(pipeline.LogWarning, c.errMismatch.Error())
+			return nil
+		}
+		return c.errMismatch
+	}
+	return nil
+}
+
+// missing returns the error to fail a download with, before it starts, if the checksum must be stored and isn't
+func (c storedChecksum) missing(option common.HashValidationOption, srcMetadata common.Metadata) error {
+	if _, ok := srcMetadata[c.metadataKey]; !ok && option == common.EHashValidationOption.FailIfDifferentOrMissing() {
+		return c.errMissing
+	}
+	return nil
+}
+
+// expectedChecksumMissing returns the error to fail a download with, before it starts, if its blob lacks a checksum
+// that must be stored
+func expectedChecksumMissing(jptm IJobPartTransferMgr) error {
+	srcMetadata := jptm.Info().SrcMetadata
+	if err := crc64Checksum.missing(jptm.Crc64ValidationOption(), srcMetadata); err != nil {
+		return err
+	}
+	return sha256Checksum.missing(jptm.Sha256ValidationOption(), srcMetadata)
+}
+
+// downloadChecksums computes, as a file is saved, the checksums that are to be checked against those stored in the
+// metadata of its blob
+type downloadChecksums struct {
+	crc64  hash.Hash64
+	sha256 hash.Hash
+}
+
+// newDownloadChecksums returns nil if no checksums are to be checked
+func newDownloadChecksums(jptm IJobPartTransferMgr) *downloadChecksums {
+	c := &downloadChecksums{}
+	if jptm.Crc64ValidationOption() != common.EHashValidationOption.NoCheck() {
+		c.crc64 = newCrc64Hasher()
+	}
+	if jptm.Sha256ValidationOption() != common.EHashValidationOption.NoCheck() {
+		c.sha256 = sha256.New()
+	}
+	if c.crc64 == nil && c.sha256 == nil {
+		return nil
+	}
+	return c
+}
+
+// hasher is to be written with exactly the bytes of the file as saved
+func (c *downloadChecksums) hasher() io.Writer {
+	switch {
+	case c == nil:
+		return nil
+	case c.crc64 == nil:
+		return c.sha256
+	case c.sha256 == nil:
+		return c.crc64
+	default:
+		return io.MultiWriter(c.crc64, c.sha256)
+	}
+}
+
+// check fails the download if the file, as saved, doesn't match the checksums stored in the metadata of its blob
+func (c *downloadChecksums) check(jptm IJobPartTransferMgr) {
+	if c == nil {
+		return
+	}
+	srcMetadata := jptm.Info().SrcMetadata
+	if c.crc64 != nil {
+		if err := crc64Checksum.check(jptm.Crc64ValidationOption(), srcMetadata, encodeCrc64(c.crc64.Sum64()), jptm); err != nil {
+			jptm.FailActiveDownload("Checking CRC64", err)
+		}
+	}
+	if c.sha256 != nil {
+		if err := sha256Checksum.check(jptm.Sha256ValidationOption(), srcMetadata, encodeSha256(c.sha256.Sum(nil)), jptm); err != nil {
+			jptm.FailActiveDownload("Checking SHA-256", err)
+		}
+	}
+}
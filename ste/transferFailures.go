@@ -0,0 +1,103 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// The details of a job's failed transfers are kept in a file alongside its plan files, so that "jobs show" can say why
+// each transfer failed, even when it's run from a different process. (The plan files themselves can't hold this, because
+// everything in them has a fixed size.) The name contains ".steV", so that "jobs rm" and "jobs clean" remove the file too,
+// but doesn't end with it, so that it isn't mistaken for a plan file.
+const transferFailuresFileNameFormat = "%v.steV%d.failures"
+
+// transferFailure records one failure of one transfer. The file holds one of these, as JSON, per line
+type transferFailure struct {
+	PartNum       common.PartNumber
+	TransferIndex uint32
+	ErrorCode     int32
+	ErrorMessage  string
+	RetryCount    int32
+}
+
+type transferFailureKey struct {
+	partNum       common.PartNumber
+	transferIndex uint32
+}
+
+// serializes the writes to the failures files, so that lines from different transfers can't get interleaved
+var transferFailuresLock sync.Mutex
+
+func transferFailuresPath(jobID common.JobID) string {
+	return fmt.Sprintf("%s%s"+transferFailuresFileNameFormat, JobsAdmin.AppPathFolder(), common.AZCOPY_PATH_SEPARATOR_STRING, jobID, DataSchemaVersion)
+}
+
+// recordTransferFailure appends the failure to the job's failures file
+func recordTransferFailure(jobID common.JobID, failure transferFailure) error {
+	line, err := json.Marshal(failure)
+	if err != nil {
+		return err
+	}
+
+	transferFailuresLock.Lock()
+	defer transferFailuresLock.Unlock()
+
+	file, err := os.OpenFile(transferFailuresPath(jobID), os.O_WRONLY|os.O_CREATE|os.O_APPEND, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// readTransferFailures returns the most recent failure of each transfer of the job. A transfer can fail more
+// than once, if the job was resumed, so only the last failure is kept.
+// A missing file just means nothing has failed yet (or the job ran in a version of AzCopy without this file)
+func readTransferFailures(jobID common.JobID) map[transferFailureKey]transferFailure {
+	failures := make(map[transferFailureKey]transferFailure)
+
+	file, err := os.Open(transferFailuresPath(jobID))
+	if err != nil {
+		return failures
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var f transferFailure
+		if json.Unmarshal(scanner.Bytes(), &f) != nil {
+			continue // e.g. a line that was only partly written, when AzCopy was killed
+		}
+		failures[transferFailureKey{partNum: f.PartNum, transferIndex: f.TransferIndex}] = f
+	}
+	return failures
+}
@@ -0,0 +1,71 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+// When every version of each blob is copied, the versions of a blob are listed oldest first, one after another, and
+// all go to the same destination, which must get them in that order, so that its own versions follow the source's.
+// Since transfers run concurrently, a transfer that finds the one before it still under way puts itself back in the
+// queue, rather than holding on to a worker while it waits.
+
+// how long a transfer waits before looking again at whether the earlier version of its blob has been written
+var versionOrderPollInterval = time.Second
+
+var errEarlierVersionNotCopied = errors.New("an earlier version of this blob was not copied, so the later ones can't be copied in order")
+
+// earlierVersionState says what the transfer of a later version must do, given what has become of the transfer of the
+// version before it. The lock on the destination is released just after the status is set, so that must be waited for too
+func earlierVersionState(status common.TransferStatus, destinationLocked bool) (written bool, err error) {
+	switch {
+	case status.ShouldTransfer():
+		return false, nil
+	case status == common.ETransferStatus.Success() ||
+		status == common.ETransferStatus.SkippedEntityAlreadyExists() ||
+		status == common.ETransferStatus.SkippedBlobHasSnapshots():
+		return !destinationLocked, nil
+	default:
+		return false, errEarlierVersionNotCopied
+	}
+}
+
+// earlierVersionWritten returns true if the transfer can go ahead, because it isn't of a later version of a blob, or
+// the versions before it have been written. If they haven't been written yet, the transfer is put back in the queue,
+// to be looked at again later, so if this returns false without an error, the caller must just return, without
+// reporting the transfer done
+func earlierVersionWritten(jptm IJobPartTransferMgr) (bool, error) {
+	status, destinationLocked, exists := jptm.PreviousTransferToSameDestination()
+	if !exists {
+		return true, nil
+	}
+
+	written, err := earlierVersionState(status, destinationLocked)
+	if err != nil || written {
+		return written, err
+	}
+	rescheduleAfter(jptm, versionOrderPollInterval)
+	return false, nil
+}
@@ -0,0 +1,151 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// A fixed-size VHD is the raw content of the disk, followed by a 512 byte footer that describes it. Azure disks must
+// be fixed-size VHDs whose virtual size is a whole number of MiB, so a VHD upload checks that before anything is sent.
+// The footer is always sent, since it's never all zeros, while the zero pages of the disk are left out.
+
+const (
+	vhdFooterSize      = 512
+	vhdFooterCookie    = "conectix"
+	vhdDiskTypeFixed   = 2
+	vhdSizeGranularity = 1024 * 1024
+
+	// zero runs shorter than this are uploaded anyway, rather than splitting a chunk into many small requests
+	vhdMinZeroGap = 64 * 1024
+)
+
+// vhdFooter holds the fields of a VHD footer that we check
+type vhdFooter struct {
+	currentSize uint64 // the virtual size of the disk
+	diskType    uint32
+}
+
+// parseVHDFooter reads the footer at the end of a VHD, checking that it really is one
+func parseVHDFooter(b []byte) (vhdFooter, error) {
+	if len(b) != vhdFooterSize || !bytes.Equal(b[:len(vhdFooterCookie)], []byte(vhdFooterCookie)) {
+		return vhdFooter{}, errors.New("the file doesn't end with a VHD footer, so it isn't a VHD (VHDX files can't be uploaded as disks)")
+	}
+
+	// the checksum is the one's complement of the sum of the footer's bytes, leaving out the checksum itself
+	var sum uint32
+	for i, v := range b {
+		if i < 64 || i >= 68 {
+			sum += uint32(v)
+		}
+	}
+	if ^sum != binary.BigEndian.Uint32(b[64:68]) {
+		return vhdFooter{}, errors.New("the checksum of the VHD footer is wrong, so the VHD is corrupt")
+	}
+
+	return vhdFooter{
+		currentSize: binary.BigEndian.Uint64(b[48:56]),
+		diskType:    binary.BigEndian.Uint32(b[60:64]),
+	}, nil
+}
+
+// validateFixedVHD checks that a file can be uploaded as the page blob of a disk
+func validateFixedVHD(f io.ReaderAt, size int64) error {
+	if size < vhdFooterSize || size%azblob.PageBlobPageBytes != 0 {
+		return fmt.Errorf("the size of the file, %d bytes, is not a multiple of %d bytes, so it can't be a page blob", size, azblob.PageBlobPageBytes)
+	}
+
+	b := make([]byte, vhdFooterSize)
+	if _, err := f.ReadAt(b, size-vhdFooterSize); err != nil {
+		return fmt.Errorf("could not read the VHD footer: %w", err)
+	}
+	footer, err := parseVHDFooter(b)
+	if err != nil {
+		return err
+	}
+
+	if footer.diskType != vhdDiskTypeFixed {
+		return errors.New("the VHD is dynamically expanding or differencing, but only fixed-size VHDs can be used as disks. Convert it first, e.g. with Convert-VHD -VHDType Fixed")
+	}
+	if int64(footer.currentSize)+vhdFooterSize != size {
+		return fmt.Errorf("the VHD footer gives a size of %d bytes, which doesn't match the size of the file, %d bytes", footer.currentSize, size)
+	}
+	if footer.currentSize%vhdSizeGranularity != 0 {
+		return fmt.Errorf("the virtual size of the VHD, %d bytes, is not a whole number of MiB, which Azure requires of disks. Resize it first, e.g. with Resize-VHD", footer.currentSize)
+	}
+	return nil
+}
+
+// rangeReadSeeker reads one range of a chunk, so that the range can be sent on its own
+type rangeReadSeeker struct {
+	chunk    io.ReadSeeker
+	offset   int64
+	length   int64
+	position int64
+}
+
+func newRangeReadSeeker(chunk io.ReadSeeker, offset int64, length int64) *rangeReadSeeker {
+	return &rangeReadSeeker{chunk: chunk, offset: offset, length: length}
+}
+
+func (r *rangeReadSeeker) Read(p []byte) (int, error) {
+	if r.position >= r.length {
+		return 0, io.EOF
+	}
+	if _, err := r.chunk.Seek(r.offset+r.position, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if remaining := r.length - r.position; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.chunk.Read(p)
+	r.position += int64(n)
+	if err == io.EOF && r.position < r.length {
+		return n, io.ErrUnexpectedEOF
+	}
+	if r.position >= r.length {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *rangeReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	newPosition := r.position
+	switch whence {
+	case io.SeekStart:
+		newPosition = offset
+	case io.SeekCurrent:
+		newPosition += offset
+	case io.SeekEnd:
+		newPosition = r.length + offset
+	}
+	if newPosition < 0 {
+		return 0, errors.New("cannot seek to before beginning")
+	}
+	r.position = newPosition
+	return r.position, nil
+}
@@ -0,0 +1,68 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/base64"
+	"net/url"
+	"sort"
+)
+
+// Blob Storage allows 8 KiB of metadata in all, so the extended attributes of a file may use half of that
+const maxXattrsMetadataLength = 4 * 1024
+
+// encodeXattrs turns extended attributes into a metadata value, as a query string of their names and base64-encoded
+// values. Attributes that would make it longer than maxXattrsMetadataLength are left out, and their names returned
+func encodeXattrs(xattrs map[string][]byte) (encoded string, dropped []string) {
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	kept := url.Values{}
+	for _, name := range names {
+		kept.Set(name, base64.RawURLEncoding.EncodeToString(xattrs[name]))
+		if len(kept.Encode()) > maxXattrsMetadataLength {
+			kept.Del(name)
+			dropped = append(dropped, name)
+		}
+	}
+	return kept.Encode(), dropped
+}
+
+// decodeXattrs is the reverse of encodeXattrs
+func decodeXattrs(encoded string) (map[string][]byte, error) {
+	values, err := url.ParseQuery(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	xattrs := make(map[string][]byte, len(values))
+	for name := range values {
+		value, err := base64.RawURLEncoding.DecodeString(values.Get(name))
+		if err != nil {
+			return nil, err
+		}
+		xattrs[name] = value
+	}
+	return xattrs, nil
+}
@@ -23,6 +23,7 @@ package ste
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"hash"
@@ -200,8 +201,6 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 	jptm.LogChunkStatus(pseudoId, common.EWaitReason.XferStart())
 	defer jptm.LogChunkStatus(pseudoId, common.EWaitReason.ChunkDone())
 
-	srcSize := info.SourceSize
-
 	// step 1. perform initial checks
 	if jptm.WasCanceled() {
 		/* This is the earliest we detect jptm has been cancelled before scheduling chunks */
@@ -210,6 +209,31 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 		return
 	}
 
+	// step 1b. with --compress, gzip the file first, and with --pack, pack the files of the directory into an archive.
+	// After which the transfer is of that copy
+	err := compressSourceIfWanted(jptm)
+	if err == nil {
+		err = packSourceIfWanted(jptm)
+	}
+	if err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+	info = jptm.Info()
+	srcSize := info.SourceSize
+
+	// step 1c. the versions of a blob are written in order, so a later one waits until the one before it has been written
+	if written, err := earlierVersionWritten(jptm); err != nil {
+		jptm.LogSendError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	} else if !written {
+		return
+	}
+
 	// step 2a. Create sender
 	srcInfoProvider, err := sipf(jptm)
 	if err != nil {
@@ -218,8 +242,8 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 		jptm.ReportTransferDone()
 		return
 	}
-	if srcInfoProvider.EntityType() != common.EEntityType.File() {
-		panic("configuration error. Source Info Provider does not have File entity type")
+	if srcInfoProvider.EntityType() != common.EEntityType.File() && srcInfoProvider.EntityType() != common.EEntityType.Symlink() {
+		panic("configuration error. Source Info Provider does not have File or Symlink entity type") // a preserved symlink is uploaded as a small file holding its target
 	}
 
 	s, err := senderFactory(jptm, info.Destination, p, pacer, srcInfoProvider)
@@ -265,6 +289,16 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 				if jptm.LastModifiedTime().After(dstLmt) {
 					shouldOverwrite = true
 				}
+			} else if jptm.GetOverwriteOption() == common.EOverwriteOption.IfSizeDifferent() {
+				// only overwrite if the sizes differ, e.g. because an earlier run stopped part way through the file
+				dstLength, err := s.GetDestinationLength()
+				if err != nil {
+					jptm.LogSendError(info.Source, info.Destination, "Could not get destination file length. "+err.Error(), 0)
+					jptm.SetStatus(common.ETransferStatus.Failed())
+					jptm.ReportTransferDone()
+					return
+				}
+				shouldOverwrite = dstLength != srcSize
 			}
 
 			if !shouldOverwrite {
@@ -277,6 +311,24 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 		}
 	}
 
+	// step 3a: a soft-deleted source can't be read until it's undeleted
+	if err := undeleteSourceIfDeleted(jptm, jptm.SourceProviderPipeline()); err != nil {
+		jptm.LogSendError(info.Source, info.Destination, "Could not undelete soft-deleted source. "+err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// step 3b: an archived source can't be read until it's rehydrated. If it isn't ready, the transfer is looked at again later
+	if online, err := archivedSourceIsOnline(jptm, jptm.SourceProviderPipeline()); err != nil {
+		jptm.LogSendError(info.Source, info.Destination, "Could not rehydrate archived source. "+err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	} else if !online {
+		return
+	}
+
 	// step 4: Open the local Source File (if any)
 	common.GetLifecycleMgr().E2EAwaitAllowOpenFiles()
 	jptm.LogChunkStatus(pseudoId, common.EWaitReason.OpenLocalSource())
@@ -296,6 +348,16 @@ func anyToRemote_file(jptm IJobPartTransferMgr, info TransferInfo, p pipeline.Pi
 			return
 		}
 		defer srcFile.Close() // we read all the chunks in this routine, so can close the file at the end
+
+		// a disk image must be a fixed-size VHD, which is worth checking before hours are spent uploading it
+		if info.UploadVHD {
+			if err = validateFixedVHD(srcFile, srcSize); err != nil {
+				jptm.LogSendError(info.Source, info.Destination, "Not a valid disk image. "+err.Error(), 0)
+				jptm.SetStatus(common.ETransferStatus.Failed())
+				jptm.ReportTransferDone()
+				return
+			}
+		}
 	}
 
 	// We always to LMT verification after the transfer. Also do it here, before transfer, when:
@@ -384,11 +446,30 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 	}
 	safeToUseHash := true
 
+	// the checksums of the whole file that go in its metadata are only computed if the uploader can store them. (Those
+	// of an empty file are set by the uploader itself, since it doesn't wait for the hashes of one.)
+	// SHA-256 is slow enough that it's computed in the background, rather than holding up the reading of the file
+	var crc64Hasher hash.Hash64
+	var sha256Hasher *backgroundHasher
+	checksumUploader, canStoreChecksums := s.(checksumMetadataUploader)
+	if canStoreChecksums && srcSize > 0 {
+		if jptm.ShouldPutCrc64() {
+			crc64Hasher = newCrc64Hasher()
+		}
+		if jptm.ShouldPutSha256() {
+			sha256Hasher = newBackgroundHasher(sha256.New(), numChunks)
+		}
+	}
+
 	if srcInfoProvider.IsLocal() {
 		md5Channel = s.(uploader).Md5Channel()
 		defer close(md5Channel)
 	}
 
+	// the part of the file that an uploader found at the destination in its prologue. It's only known once the first
+	// chunk has been read
+	var alreadySent int64
+
 	chunkIDCount := int32(0)
 	for startIndex := int64(0); startIndex < srcSize || isDummyChunkInEmptyFile(startIndex, srcSize); startIndex += int64(chunkSize) {
 
@@ -400,8 +481,9 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 		}
 
 		id := common.NewChunkID(srcPath, startIndex, adjustedChunkSize) // TODO: stop using adjustedChunkSize, below, and use the size that's in the ID
+		isAlreadySent := alreadySent > 0 && startIndex+adjustedChunkSize <= alreadySent
 
-		if srcInfoProvider.IsLocal() {
+		if srcInfoProvider.IsLocal() && !isAlreadySent {
 			if jptm.WasCanceled() {
 				prefetchErr = jobCancelledLocalPrefetchErr
 			} else {
@@ -417,6 +499,9 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 					prefetchErr = chunkReader.BlockingPrefetch(srcFile, false)
 					if prefetchErr == nil {
 						chunkReader.WriteBufferTo(md5Hasher)
+						if crc64Hasher != nil {
+							chunkReader.WriteBufferTo(crc64Hasher)
+						}
 						ps = chunkReader.GetPrologueState()
 					} else {
 						safeToUseHash = false // because we've missed a chunk
@@ -434,15 +519,24 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 			if modified {
 				jptm.SetDestinationIsModified()
 			}
+			if skipper, ok := s.(prefixSkippingUploader); ok && srcInfoProvider.IsLocal() && !jptm.ShouldPutMd5() && crc64Hasher == nil && sha256Hasher == nil {
+				alreadySent = skipper.SendOffset()
+			}
 		}
 
 		// schedule the chunk job/msg
 		jptm.LogChunkStatus(id, common.EWaitReason.WorkerGR())
 		isWholeFile := numChunks == 1
 		var cf chunkFunc
-		if srcInfoProvider.IsLocal() {
+		if isAlreadySent {
+			// every chunk must still be scheduled, but there's nothing to send for this one
+			cf = createSendToRemoteChunkFunc(jptm, id, func() {})
+		} else if srcInfoProvider.IsLocal() {
 			if prefetchErr == nil {
 				cf = s.(uploader).GenerateUploadFunc(id, chunkIDCount, chunkReader, isWholeFile)
+				if sha256Hasher != nil {
+					cf = sha256Hasher.add(jptm, chunkReader, cf)
+				}
 			} else {
 				if chunkReader != nil {
 					_ = chunkReader.Close()
@@ -465,7 +559,16 @@ func scheduleSendChunks(jptm IJobPartTransferMgr, srcPath string, srcFile common
 		panic(fmt.Errorf("difference in the number of chunk calculated %v and actual chunks scheduled %v for src %s of size %v", numChunks, chunkIDCount, srcPath, srcSize))
 	}
 
+	if sha256Hasher != nil {
+		sha256Sum := sha256Hasher.finish()
+		if safeToUseHash {
+			checksumUploader.SetChecksumMetadata(common.Sha256MetadataKey, encodeSha256(sha256Sum))
+		}
+	}
 	if srcInfoProvider.IsLocal() && safeToUseHash {
+		if crc64Hasher != nil {
+			checksumUploader.SetChecksumMetadata(common.Crc64MetadataKey, encodeCrc64(crc64Hasher.Sum64()))
+		}
 		md5Channel <- md5Hasher.Sum(nil)
 	}
 }
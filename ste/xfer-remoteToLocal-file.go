@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
@@ -37,6 +38,8 @@ func remoteToLocal(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pacer, d
 	info := jptm.Info()
 	if info.IsFolderPropertiesTransfer() {
 		remoteToLocal_folder(jptm, p, pacer, df)
+	} else if info.EntityType == common.EEntityType.Symlink() {
+		remoteToLocal_symlink(jptm, p)
 	} else {
 		remoteToLocal_file(jptm, p, pacer, df)
 	}
@@ -64,10 +67,14 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		jptm.ReportTransferDone()
 		return
 	}
+	// if an earlier run of the job saved the start of the file, carry on from there
+	resumeOffset := downloadResumeOffset(jptm, info, downloadChunkSize)
+
 	// if the force Write flags is set to false or prompt
 	// then check the file exists at the remote location
 	// if it does, react accordingly
-	if jptm.GetOverwriteOption() != common.EOverwriteOption.True() {
+	// (but not if it's our own partially-downloaded file, which the earlier run already decided to overwrite)
+	if jptm.GetOverwriteOption() != common.EOverwriteOption.True() && resumeOffset == 0 {
 		dstProps, err := common.OSStat(info.Destination)
 		if err == nil {
 			// if the error is nil, then file exists locally
@@ -81,6 +88,9 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 				if jptm.LastModifiedTime().After(dstProps.ModTime()) {
 					shouldOverwrite = true
 				}
+			} else if jptm.GetOverwriteOption() == common.EOverwriteOption.IfSizeDifferent() {
+				// only overwrite if the sizes differ, e.g. because an earlier run stopped part way through the file
+				shouldOverwrite = dstProps.Size() != info.SourceSize
 			}
 
 			if !shouldOverwrite {
@@ -103,6 +113,31 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 			return
 		}
 	}
+	// likewise for the checksums that are stored in the metadata of the blob
+	if err := expectedChecksumMissing(jptm); err != nil {
+		jptm.LogDownloadError(info.Source, info.Destination, err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// step 3a: a soft-deleted source can't be read until it's undeleted
+	if err := undeleteSourceIfDeleted(jptm, p); err != nil {
+		jptm.LogDownloadError(info.Source, info.Destination, "Could not undelete soft-deleted source. "+err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// step 3b: an archived source can't be read until it's rehydrated. If it isn't ready, the transfer is looked at again later
+	if online, err := archivedSourceIsOnline(jptm, p); err != nil {
+		jptm.LogDownloadError(info.Source, info.Destination, "Could not rehydrate archived source. "+err.Error(), 0)
+		jptm.SetStatus(common.ETransferStatus.Failed())
+		jptm.ReportTransferDone()
+		return
+	} else if !online {
+		return
+	}
 
 	// step 4a: mark destination as modified before we take our first action there (which is to create the destination file)
 	jptm.SetDestinationIsModified()
@@ -127,7 +162,7 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		// For blobs, it sets up a page blob pacer if it's a page blob.
 		// For blobFS, it's a noop.
 		dl.Prologue(jptm, p)
-		epilogueWithCleanupDownload(jptm, dl, nil, nil) // need standard epilogue, rather than a quick exit, so we can preserve modification dates
+		epilogueWithCleanupDownload(jptm, dl, nil, nil, nil) // need standard epilogue, rather than a quick exit, so we can preserve modification dates
 		return
 	}
 
@@ -146,7 +181,7 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 		jptm.LogDownloadError(info.Source, info.Destination, "File Creation Error "+err.Error(), 0)
 		jptm.SetStatus(common.ETransferStatus.Failed())
 		// use standard epilogue for consistency, but force release of file count (without an actual file) if necessary
-		epilogueWithCleanupDownload(jptm, dl, nil, nil)
+		epilogueWithCleanupDownload(jptm, dl, nil, nil, nil)
 	}
 	// block until we can safely use a file handle
 	err := jptm.WaitUntilLockDestination(jptm.Context())
@@ -156,9 +191,22 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 	}
 
 	var dstFile io.WriteCloser
+	var savedContent io.Reader
 	if strings.EqualFold(info.Destination, common.Dev_Null) {
 		// the user wants to discard the downloaded data
 		dstFile = devNullWriter{}
+	} else if resumeOffset > 0 {
+		// carry on writing the file that the earlier run created
+		var file *os.File
+		file, err = openDestinationFileForResume(info.Destination, resumeOffset)
+		if err != nil {
+			failFileCreation(err)
+			return
+		}
+		dstFile = file
+		savedContent = io.NewSectionReader(file, 0, resumeOffset) // reading at an offset doesn't move the write position
+		jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo,
+			fmt.Sprintf("Resuming the download at byte %d, which an earlier run of the job had saved up to", resumeOffset))
 	} else {
 		// Normal scenario, create the destination file as expected
 		// Use pseudo chunk id to allow our usual state tracking mechanism to keep count of how many
@@ -190,27 +238,35 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 			return
 		}*/
 
-	// step 5a: compute num chunks
+	// step 5a: compute num chunks, leaving out those that were saved by an earlier run
 	numChunks := uint32(0)
 	if rem := fileSize % downloadChunkSize; rem == 0 {
 		numChunks = uint32(fileSize / downloadChunkSize)
 	} else {
 		numChunks = uint32(fileSize/downloadChunkSize + 1)
 	}
+	numChunks -= uint32(resumeOffset / downloadChunkSize)
+	jptm.ResumeDownloadAt(resumeOffset)
+	dstFile = newSavedBytesRecorder(jptm, dstFile)
 
 	// step 5b: create destination writer
 	chunkLogger := jptm.ChunkStatusLogger()
 	sourceMd5Exists := len(info.SrcHTTPHeaders.ContentMD5) > 0
-	dstWriter := common.NewChunkedFileWriter(
+	checksums := newDownloadChecksums(jptm)
+	dstWriter := common.NewResumingChunkedFileWriter(
 		jptm.Context(),
 		jptm.SlicePool(),
 		jptm.CacheLimiter(),
 		chunkLogger,
+		writtenBytesReporterFor(dstFile, jptm),
 		dstFile,
 		numChunks,
 		MaxRetryPerDownloadBody,
 		jptm.MD5ValidationOption(),
-		sourceMd5Exists)
+		sourceMd5Exists,
+		checksums.hasher(),
+		resumeOffset,
+		savedContent)
 
 	// step 5c: run prologue in downloader (here it can, for example, create things that will require cleanup in the epilogue)
 	common.GetLifecycleMgr().E2EAwaitAllowOpenFiles()
@@ -218,7 +274,7 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 
 	// step 5d: tell jptm what to expect, and how to clean up at the end
 	jptm.SetNumberOfChunks(numChunks)
-	jptm.SetActionAfterLastChunk(func() { epilogueWithCleanupDownload(jptm, dl, dstFile, dstWriter) })
+	jptm.SetActionAfterLastChunk(func() { epilogueWithCleanupDownload(jptm, dl, dstFile, dstWriter, checksums) })
 
 	// step 6: go through the blob range and schedule download chunk jobs
 	// TODO: currently, the epilogue will only run if the number of completed chunks = numChunks.
@@ -230,7 +286,7 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 	// eventually reach numChunks, since we have no better short-term alternative.
 
 	chunkCount := uint32(0)
-	for startIndex := int64(0); startIndex < fileSize; startIndex += downloadChunkSize {
+	for startIndex := resumeOffset; startIndex < fileSize; startIndex += downloadChunkSize {
 		adjustedChunkSize := downloadChunkSize
 
 		// compute exact size of the chunk
@@ -263,6 +319,114 @@ func remoteToLocal_file(jptm IJobPartTransferMgr, p pipeline.Pipeline, pacer pac
 
 }
 
+// downloadResumeOffset returns where to carry on downloading the file from, if an earlier run of the job saved the
+// start of it and left the partially-downloaded file in place. It's always a whole number of chunks, and is zero
+// if the download must start afresh
+func downloadResumeOffset(jptm IJobPartTransferMgr, info TransferInfo, chunkSize int64) int64 {
+	saved := jptm.SavedBytes()
+	if !jptm.JobWasResumed() || saved <= 0 || chunkSize <= 0 ||
+		strings.EqualFold(info.Destination, common.Dev_Null) || jptm.ShouldDecompress() {
+		return 0
+	}
+
+	// we create files at their full size, so if the size is different, the file isn't the one we were writing
+	fi, err := common.OSStat(info.Destination)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() != info.SourceSize {
+		return 0
+	}
+
+	offset := saved - saved%chunkSize
+	if offset >= info.SourceSize {
+		// the whole file was saved, so something went wrong after that (e.g. with the MD5 check). Start again
+		return 0
+	}
+	return offset
+}
+
+// savedBytesSyncInterval is how much of a download is written between the syncs that make it durable. Only what has been
+// synced is recorded as saved, since resuming trusts what's recorded, and after the OS crashes, whatever was written
+// but hadn't reached the disk would be left as zeros
+const savedBytesSyncInterval = 64 * 1024 * 1024
+
+// savedBytesRecorder passes the writes to a destination file on, and records them as saved once the file has been synced.
+// Those syncs are also when the download's progress is reported, so that progress only counts bytes that are on the disk
+type savedBytesRecorder struct {
+	io.WriteCloser
+	sync        func() error
+	record      func(n int64)
+	keepOnClose func() bool // whether what's been written is to be kept for a resume
+	unsynced    int64
+}
+
+// newSavedBytesRecorder wraps the destination file, unless it can't be synced, in which case the download can't be
+// resumed anyway (e.g. it's to /dev/null, or decompressed as it's written)
+func newSavedBytesRecorder(jptm IJobPartTransferMgr, file io.WriteCloser) io.WriteCloser {
+	syncer, ok := file.(interface{ Sync() error })
+	if !ok {
+		return file
+	}
+	return &savedBytesRecorder{
+		WriteCloser: file,
+		sync:        syncer.Sync,
+		record: func(n int64) {
+			jptm.ReportSavedBytes(n)
+			jptm.ReportWrittenBytes(n)
+		},
+		keepOnClose: func() bool { return !jptm.IsLive() || jptm.WasCanceled() },
+	}
+}
+
+// Write is called with the chunks in order, so the bytes written always follow on from those saved before
+func (r *savedBytesRecorder) Write(p []byte) (int, error) {
+	n, err := r.WriteCloser.Write(p)
+	r.unsynced += int64(n)
+	if err == nil && r.unsynced >= savedBytesSyncInterval {
+		err = r.syncAndRecord()
+	}
+	return n, err
+}
+
+// Close syncs what has been written since the last sync only if the download has stopped part-way, when a resume may
+// carry on from it, so that downloads that succeed don't pay for a sync of every file
+func (r *savedBytesRecorder) Close() error {
+	if r.unsynced > 0 && r.keepOnClose() {
+		_ = r.syncAndRecord() // if it can't be synced, the resume just starts a little earlier
+	}
+	return r.WriteCloser.Close()
+}
+
+func (r *savedBytesRecorder) syncAndRecord() error {
+	if err := r.sync(); err != nil {
+		return err
+	}
+	r.record(r.unsynced)
+	r.unsynced = 0
+	return nil
+}
+
+// writtenBytesReporterFor returns what the chunked file writer should tell as it writes each chunk. That's nobody
+// when the file is wrapped in a savedBytesRecorder, since it reports progress itself, once the bytes have been synced.
+// Files that can't be synced (e.g. /dev/null) have nothing more durable to wait for, so they report as they're written
+func writtenBytesReporterFor(file io.WriteCloser, reporter common.WrittenBytesReporter) common.WrittenBytesReporter {
+	if _, ok := file.(*savedBytesRecorder); ok {
+		return nil
+	}
+	return reporter
+}
+
+// openDestinationFileForResume opens a partially-downloaded file, ready to carry on writing it at offset
+func openDestinationFileForResume(destination string, offset int64) (*os.File, error) {
+	file, err := common.OSOpenFile(destination, os.O_RDWR, common.DEFAULT_FILE_PERM)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
 func createDestinationFile(jptm IJobPartTransferMgr, destination string, size int64, writeThrough bool) (file io.WriteCloser, err error) {
 	ct := common.ECompressionType.None()
 	if jptm.ShouldDecompress() {
@@ -294,7 +458,8 @@ func createDestinationFile(jptm IJobPartTransferMgr, destination string, size in
 }
 
 // complete epilogue. Handles both success and failure
-func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, activeDstFile io.WriteCloser, cw common.ChunkedFileWriter) {
+// checksums, if not nil, were given to cw, so they're those of the file once cw has been flushed
+func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, activeDstFile io.WriteCloser, cw common.ChunkedFileWriter, checksums *downloadChecksums) {
 	info := jptm.Info()
 
 	// allow our usual state tracking mechanism to keep count of how many epilogues are running at any given instant, for perf diagnostics
@@ -330,10 +495,16 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 				validationOption: jptm.MD5ValidationOption(),
 				logger:           jptm}
 			err := comparison.Check()
+			if comparison.mismatched() {
+				jptm.RecordMd5Mismatch(err == nil)
+			}
 			if err != nil {
 				jptm.FailActiveDownload("Checking MD5 hash", err)
 			}
 		}
+		if jptm.IsLive() {
+			checksums.check(jptm)
+		}
 	}
 
 	if dl != nil {
@@ -368,6 +539,36 @@ func epilogueWithCleanupDownload(jptm IJobPartTransferMgr, dl downloader, active
 				jptm.Log(pipeline.LogInfo, fmt.Sprintf(" Preserved Modified Time for %s", info.Destination))
 			}
 		}
+
+		// the times recorded when the file was uploaded, if any, take precedence over the last modified time of the blob
+		if info.PreserveFileTimes {
+			restoreFileTimes(jptm)
+		}
+	}
+
+	if jptm.IsLive() && info.PreserveXattrs {
+		if encoded, ok := info.SrcMetadata[common.XattrsMetadataKey]; ok {
+			xattrs, err := decodeXattrs(encoded)
+			if err == nil {
+				err = writeXattrs(info.Destination, xattrs)
+			}
+			if err != nil {
+				jptm.FailActiveDownload("Restoring extended attributes", err)
+			}
+		}
+	}
+
+	// with --unpack, an archive is unpacked into the directory it was downloaded to, and then removed
+	if jptm.IsLive() && info.UnpackOnDownload && info.Destination != common.Dev_Null && isArchiveToUnpack(info.Destination) {
+		count, err := unpackArchive(info.Destination, filepath.Dir(info.Destination), jptm.GetOverwriteOption() == common.EOverwriteOption.True())
+		if err != nil {
+			jptm.FailActiveDownload("Unpacking archive", err)
+		} else {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, fmt.Sprintf("unpacked %d files", count))
+			if err = os.Remove(info.Destination); err != nil {
+				jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "Couldn't remove the unpacked archive: "+err.Error())
+			}
+		}
 	}
 
 	commonDownloaderCompletion(jptm, info, common.EEntityType.File())
@@ -387,10 +588,15 @@ func commonDownloaderCompletion(jptm IJobPartTransferMgr, info TransferInfo, ent
 		}
 		// for files only, cleanup local file if applicable
 		if entityType == entityType.File() && jptm.IsDeadInflight() && jptm.HoldsDestinationLock() {
-			jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "Deleting incomplete destination file")
+			if shouldKeepPartialDownload(jptm, info) {
+				jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo,
+					fmt.Sprintf("Keeping the incomplete destination file, so that resuming the job can carry on from byte %d", jptm.SavedBytes()))
+			} else {
+				jptm.LogAtLevelForCurrentTransfer(pipeline.LogInfo, "Deleting incomplete destination file")
 
-			// the file created locally should be deleted
-			tryDeleteFile(info, jptm)
+				// the file created locally should be deleted
+				tryDeleteFile(info, jptm)
+			}
 		}
 	} else {
 		if !jptm.IsLive() {
@@ -432,6 +638,19 @@ func createEmptyFile(jptm IJobPartTransferMgr, destinationPath string) error {
 	return nil
 }
 
+// shouldKeepPartialDownload says whether a download that has stopped part-way should leave what it saved, for
+// "jobs resume" to carry on from. That's so if the job was paused, or the transfer failed (since resuming the
+// job retries failed transfers), but not if the user cancelled the job
+func shouldKeepPartialDownload(jptm IJobPartTransferMgr, info TransferInfo) bool {
+	saved := jptm.SavedBytes()
+	if saved <= 0 || saved >= info.SourceSize || jptm.ShouldDecompress() {
+		return false // nothing to carry on from, or the whole file was saved and then found to be bad
+	}
+	status := jptm.TransferStatusIgnoringCancellation()
+	failed := status < 0 && status != common.ETransferStatus.CancelledByUser() // the user doesn't want the file any more
+	return failed || jptm.JobIsPaused()
+}
+
 // deletes the file
 func deleteFile(destinationPath string) error {
 	return os.Remove(destinationPath)
@@ -0,0 +1,116 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// the target of a link is a path, so anything much bigger than this isn't a preserved symlink
+const maxSymlinkTargetLength = 64 * 1024
+
+// blob to local, for blobs that hold symlinks preserved by an upload with --preserve-symlinks.
+// The content of such a blob is the target of the link, so the link is re-created from it, rather than written out as a file
+func remoteToLocal_symlink(jptm IJobPartTransferMgr, p pipeline.Pipeline) {
+	if jptm.WasCanceled() {
+		jptm.SetStatus(common.ETransferStatus.Cancelled())
+		jptm.ReportTransferDone()
+		return
+	}
+
+	// schedule the work as a chunk, so the read of the target runs on the main goroutine pool
+	id := common.NewChunkID(jptm.Info().Source, 0, 0)
+	cf := createChunkFunc(true, jptm, id, func() { doCreateSymlink(jptm, p) })
+	jptm.ScheduleChunks(cf)
+}
+
+func doCreateSymlink(jptm IJobPartTransferMgr, p pipeline.Pipeline) {
+	info := jptm.Info()
+
+	// an existing link or file at the destination is subject to the overwrite option, in the same way as a file
+	if dstProps, err := os.Lstat(info.Destination); err == nil {
+		shouldOverwrite := false
+		switch jptm.GetOverwriteOption() {
+		case common.EOverwriteOption.True():
+			shouldOverwrite = true
+		case common.EOverwriteOption.Prompt():
+			shouldOverwrite = jptm.GetOverwritePrompter().ShouldOverwrite(info.Destination, common.EEntityType.File())
+		case common.EOverwriteOption.IfSourceNewer():
+			shouldOverwrite = jptm.LastModifiedTime().After(dstProps.ModTime())
+		case common.EOverwriteOption.IfSizeDifferent():
+			shouldOverwrite = dstProps.Size() != info.SourceSize
+		}
+
+		if !shouldOverwrite {
+			jptm.LogAtLevelForCurrentTransfer(pipeline.LogWarning, "File already exists, so will be skipped")
+			jptm.SetStatus(common.ETransferStatus.SkippedEntityAlreadyExists())
+			jptm.ReportTransferDone()
+			return
+		}
+	}
+
+	target, err := readSymlinkTarget(jptm, p)
+	if err != nil {
+		jptm.FailActiveDownload("reading the target of the symlink", err)
+		commonDownloaderCompletion(jptm, info, common.EEntityType.Symlink())
+		return
+	}
+
+	jptm.SetDestinationIsModified()
+	err = common.CreateParentDirectoryIfNotExist(info.Destination, jptm.GetFolderCreationTracker())
+	if err == nil {
+		// os.Symlink won't replace what's already there
+		if err = os.Remove(info.Destination); os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = os.Symlink(target, info.Destination)
+	}
+	if err != nil {
+		jptm.FailActiveDownload("creating the symlink", err)
+	}
+	commonDownloaderCompletion(jptm, info, common.EEntityType.Symlink())
+}
+
+// readSymlinkTarget reads the whole of the blob, which is the target of the link
+func readSymlinkTarget(jptm IJobPartTransferMgr, p pipeline.Pipeline) (string, error) {
+	u, err := url.Parse(jptm.Info().Source)
+	if err != nil {
+		return "", err
+	}
+
+	get, err := azblob.NewBlobURL(*u, p).Download(jptm.Context(), 0, maxSymlinkTargetLength, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return "", err
+	}
+	body := get.Body(azblob.RetryReaderOptions{MaxRetryRequests: MaxRetryPerDownloadBody})
+	defer body.Close()
+
+	target, err := ioutil.ReadAll(body)
+	return string(target), err
+}
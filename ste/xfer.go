@@ -122,6 +122,8 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 				return newAzureFilesUploader
 			case common.ELocation.BlobFS():
 				return newBlobFSUploader
+			case common.ELocation.SFTP():
+				return newSFTPUploader
 			default:
 				panic("unexpected target location type")
 			}
@@ -142,6 +144,10 @@ func computeJobXfer(fromTo common.FromTo, blobType common.BlobType) newJobXfer {
 			panic(blobFSNotS2S)
 		case common.ELocation.S3():
 			return newS3SourceInfoProvider
+		case common.ELocation.SFTP():
+			return newSFTPSourceInfoProvider
+		case common.ELocation.HTTP():
+			return newHTTPSourceInfoProvider
 		default:
 			panic("unexpected source type")
 		}
@@ -77,3 +77,35 @@ func newRetryNotificationPolicyFactory() pipeline.Factory {
 		return r.Do
 	})
 }
+
+// retryCountReceiver should be implemented by code that wishes to know how many retries its requests needed.
+// Such code must register itself into the context, using withRetryCounting, so that the retryCountPolicy
+// can invoke the callback for every try after the first
+type retryCountReceiver interface {
+	CountRetry()
+}
+
+// withRetryCounting returns a context that contains a retry counter
+func withRetryCounting(ctx context.Context, r retryCountReceiver) context.Context {
+	return context.WithValue(ctx, retryCountContextKey, r)
+}
+
+var retryCountContextKey = contextKey{"retryCount"}
+
+// newRetryCountPolicyFactory counts retries of any kind (unlike the retryNotificationPolicy, which is only about 503s).
+// It must go after the retry policy in the pipeline, so that it sees each try
+func newRetryCountPolicyFactory() pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		// This is per-policy, and so shared by all the tries of one operation
+		var try int32
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			try++
+			if try > 1 {
+				if counter, ok := ctx.Value(retryCountContextKey).(retryCountReceiver); ok {
+					counter.CountRetry()
+				}
+			}
+			return next.Do(ctx, request)
+		}
+	})
+}
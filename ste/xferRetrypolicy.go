@@ -14,6 +14,7 @@ import (
 	"github.com/Azure/azure-storage-azcopy/azbfs"
 	"github.com/Azure/azure-storage-azcopy/common"
 	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/azure-storage-file-go/azfile"
 )
 
 // XferRetryPolicy tells the pipeline what kind of retry policy to use. See the XferRetryPolicy* constants.
@@ -56,11 +57,21 @@ type XferRetryOptions struct {
 	// If you specify 0, then you must also specify 0 for RetryDelay.
 	MaxRetryDelay time.Duration
 
+	// Jitter is the fraction by which each delay is randomly made longer or shorter, so that the retries from
+	// many connections don't all happen at the same moment. A value of zero means that you accept our default,
+	// which varies the delay between 80% and 130%. A negative value means no jitter.
+	Jitter float32
+
 	// RetryReadsFromSecondaryHost specifies whether the retry policy should retry a read operation against another host.
 	// If RetryReadsFromSecondaryHost is "" (the default) then operations are not retried against another host.
 	// NOTE: Before setting this field, make sure you understand the issues around reading stale & potentially-inconsistent
 	// data at this webpage: https://docs.microsoft.com/en-us/azure/storage/common/storage-designing-ha-apps-with-ragrs
 	RetryReadsFromSecondaryHost string // Comment this our for non-Blob SDKs
+
+	// NoServerTimeout leaves out the server-side timeout query parameter, which only Azure Storage understands.
+	// Set it when sending requests to other servers, whose URLs may be pre-signed, since the extra parameter
+	// would spoil their signatures.
+	NoServerTimeout bool
 }
 
 func (o XferRetryOptions) retryReadsFromSecondaryHost() string {
@@ -84,6 +95,9 @@ func (o XferRetryOptions) defaults() XferRetryOptions {
 	if (o.RetryDelay == 0 && o.MaxRetryDelay != 0) || (o.RetryDelay != 0 && o.MaxRetryDelay == 0) {
 		panic("Both RetryDelay and MaxRetryDelay must be 0 or neither can be 0")
 	}
+	if o.Jitter >= 1 {
+		panic("Jitter must be < 1")
+	}
 
 	IfDefault := func(current *time.Duration, desired time.Duration) {
 		if *current == time.Duration(0) {
@@ -130,14 +144,112 @@ func (o XferRetryOptions) calcDelay(try int32) time.Duration { // try is >=1; ne
 	}
 
 	// Introduce some jitter:  [0.0, 1.0) / 2 = [0.0, 0.5) + 0.8 = [0.8, 1.3)
+	// or, if configured: [0.0, 1.0) * 2 * jitter = [0, 2 * jitter) + 1 - jitter = [1 - jitter, 1 + jitter)
 	// For casts and rounding - be careful, as per https://github.com/golang/go/issues/20757
-	delay = time.Duration(float32(delay) * (rand.Float32()/2 + 0.8)) // NOTE: We want math/rand; not crypto/rand
+	if o.Jitter == 0 {
+		delay = time.Duration(float32(delay) * (rand.Float32()/2 + 0.8)) // NOTE: We want math/rand; not crypto/rand
+	} else if o.Jitter > 0 {
+		delay = time.Duration(float32(delay) * (rand.Float32()*2*o.Jitter + 1 - o.Jitter))
+	}
+	if delay > o.MaxRetryDelay {
+		delay = o.MaxRetryDelay
+	}
+	return delay
+}
+
+// decorrelatedDelay picks the delay after a throttled try, at random between the base delay and three times the
+// previous delay ("decorrelated jitter"). Unlike calcDelay, successive delays don't march in step, so the many
+// connections of a large job that were throttled at the same moment spread their retries out, instead of coming
+// back together and being throttled again
+func (o XferRetryOptions) decorrelatedDelay(previous time.Duration) time.Duration {
+	if previous < o.RetryDelay {
+		previous = o.RetryDelay
+	}
+	delay := o.RetryDelay
+	if spread := 3*previous - o.RetryDelay; spread > 0 {
+		delay += time.Duration(rand.Int63n(int64(spread))) // NOTE: We want math/rand; not crypto/rand
+	}
 	if delay > o.MaxRetryDelay {
 		delay = o.MaxRetryDelay
 	}
 	return delay
 }
 
+// isThrottlingStatus says whether the status is one with which the service asks us to slow down. Storage says 503
+// when an account is over its limits, and 500 when it is timing out, which for our purposes is the same thing
+func isThrottlingStatus(statusCode int) bool {
+	return statusCode == http.StatusServiceUnavailable || statusCode == http.StatusInternalServerError || statusCode == http.StatusTooManyRequests
+}
+
+// parseRetryAfter gets how long the response asks us to wait before trying again, or zero if it doesn't say.
+// Besides the standard Retry-After header (seconds, or an HTTP date), Azure services may give it in milliseconds
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	for _, name := range []string{"x-ms-retry-after-ms", "retry-after-ms"} {
+		if ms, err := strconv.ParseInt(header.Get(name), 10, 64); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		return 0
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// retryBackoff works out the delay before each try against the primary, for one operation.
+// It backs off exponentially, as calcDelay says, unless the previous try was throttled. Then it uses decorrelated
+// jitter, and waits at least as long as the service asked in Retry-After (up to MaxRetryDelay, so that a wayward
+// header can't hold the job up indefinitely)
+type retryBackoff struct {
+	o          XferRetryOptions
+	lastDelay  time.Duration
+	throttled  bool
+	retryAfter time.Duration
+}
+
+// recordTry notes whether the try was throttled, and for how long the service asked us to wait
+func (b *retryBackoff) recordTry(response pipeline.Response, err error) {
+	var rawResponse *http.Response
+	if response != nil {
+		rawResponse = response.Response()
+	}
+	if rawResponse == nil {
+		if withResponse, ok := err.(interface{ Response() *http.Response }); ok {
+			rawResponse = withResponse.Response() // StorageErrors carry the response, in case we weren't given it
+		}
+	}
+
+	b.throttled = rawResponse != nil && isThrottlingStatus(rawResponse.StatusCode)
+	b.retryAfter = 0
+	if b.throttled {
+		b.retryAfter = parseRetryAfter(rawResponse.Header, time.Now())
+	}
+}
+
+func (b *retryBackoff) delay(primaryTry int32) time.Duration {
+	delay := b.o.calcDelay(primaryTry)
+	if b.throttled && b.o.Policy == RetryPolicyExponential && b.o.Jitter >= 0 {
+		delay = b.o.decorrelatedDelay(b.lastDelay)
+	}
+	if b.retryAfter > delay {
+		delay = b.retryAfter
+		if delay > b.o.MaxRetryDelay {
+			delay = b.o.MaxRetryDelay
+		}
+	}
+	b.lastDelay = delay
+	return delay
+}
+
 // TODO fix the separate retry policies
 // NewBFSXferRetryPolicyFactory creates a RetryPolicyFactory object configured using the specified options.
 func NewBFSXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
@@ -146,12 +258,14 @@ func NewBFSXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 		return func(ctx context.Context, request pipeline.Request) (response pipeline.Response, err error) {
 			// Before each try, we'll select either the primary or secondary URL.
 			primaryTry := int32(0) // This indicates how many tries we've attempted against the primary DC
+			backoff := retryBackoff{o: o}
 
 			// We only consider retrying against a secondary if we have a read request (GET/HEAD) AND this policy has a Secondary URL it can use
 			considerSecondary := (request.Method == http.MethodGet || request.Method == http.MethodHead) && o.retryReadsFromSecondaryHost() != ""
 
 			// Exponential retry algorithm: ((2 ^ attempt) - 1) * delay * random(0.8, 1.2)
 			// When to retry: connection failure or temporary/timeout. NOTE: StorageError considers HTTP 500/503 as temporary & is therefore retryable
+			// After a throttled try (500/503/429) the delay is decorrelated and honors Retry-After instead; see retryBackoff
 			// If using a secondary:
 			//    Even tries go against primary; odd tries go against the secondary
 			//    For a primary wait ((2 ^ primaryTries - 1) * delay * random(0.8, 1.2)
@@ -165,7 +279,7 @@ func NewBFSXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 				// Select the correct host and delay
 				if tryingPrimary {
 					primaryTry++
-					delay := o.calcDelay(primaryTry)
+					delay := backoff.delay(primaryTry)
 					logf("Primary try=%d, Delay=%v\n", primaryTry, delay)
 					time.Sleep(delay) // The 1st try returns 0 delay
 				} else {
@@ -216,6 +330,7 @@ func NewBFSXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 					response.Response().body = &deadlineExceededReadCloser{r: response.Response().body}
 				}*/
 				logf("Err=%v, response=%v\n", err, response)
+				backoff.recordTry(response, err)
 
 				action := "" // This MUST get changed within the switch code below
 				switch {
@@ -313,12 +428,14 @@ func NewBlobXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 		return func(ctx context.Context, request pipeline.Request) (response pipeline.Response, err error) {
 			// Before each try, we'll select either the primary or secondary URL.
 			primaryTry := int32(0) // This indicates how many tries we've attempted against the primary DC
+			backoff := retryBackoff{o: o}
 
 			// We only consider retrying against a secondary if we have a read request (GET/HEAD) AND this policy has a Secondary URL it can use
 			considerSecondary := (request.Method == http.MethodGet || request.Method == http.MethodHead) && o.retryReadsFromSecondaryHost() != ""
 
 			// Exponential retry algorithm: ((2 ^ attempt) - 1) * delay * random(0.8, 1.2)
 			// When to retry: connection failure or temporary/timeout. NOTE: StorageError considers HTTP 500/503 as temporary & is therefore retryable
+			// After a throttled try (500/503/429) the delay is decorrelated and honors Retry-After instead; see retryBackoff
 			// If using a secondary:
 			//    Even tries go against primary; odd tries go against the secondary
 			//    For a primary wait ((2 ^ primaryTries - 1) * delay * random(0.8, 1.2)
@@ -336,7 +453,7 @@ func NewBlobXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 				// Select the correct host and delay
 				if tryingPrimary {
 					primaryTry++
-					delay := o.calcDelay(primaryTry)
+					delay := backoff.delay(primaryTry)
 					logf("Primary try=%d, Delay=%f s\n", primaryTry, delay.Seconds())
 					time.Sleep(delay) // The 1st try returns 0 delay
 				} else {
@@ -373,9 +490,11 @@ func NewBlobXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 					}
 					logf("TryTimeout adjusted to=%d sec\n", timeout)
 				}
-				q := requestCopy.Request.URL.Query()
-				q.Set("timeout", strconv.Itoa(int(timeout+1))) // Add 1 to "round up"
-				requestCopy.Request.URL.RawQuery = q.Encode()
+				if !o.NoServerTimeout {
+					q := requestCopy.Request.URL.Query()
+					q.Set("timeout", strconv.Itoa(int(timeout+1))) // Add 1 to "round up"
+					requestCopy.Request.URL.RawQuery = q.Encode()
+				}
 				logf("Url=%s\n", requestCopy.Request.URL.String())
 
 				// Set the time for this particular retry operation and then Do the operation.
@@ -387,6 +506,7 @@ func NewBlobXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 					response.Response().body = &deadlineExceededReadCloser{r: response.Response().body}
 				}*/
 				logf("Err=%v, response=%v\n", err, response)
+				backoff.recordTry(response, err)
 
 				action := "" // This MUST get changed within the switch code below
 				switch {
@@ -413,14 +533,9 @@ func NewBlobXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 					// TODO make sure Storage error can be cast to different package's error object
 					// TODO: Discuss the error handling of Go Blob SDK.
 					if stErr, ok := err.(azblob.StorageError); ok {
-						// retry only in case of temporary storage errors.
-						if stErr.Temporary() {
-							action = "Retry: StorageError with error service code and Temporary()"
-						} else if stErr.Response() != nil && isSuccessStatusCode(stErr.Response()) { // This is a temporarily work around.
-							action = "Retry: StorageError with success status code"
-						} else {
-							action = "NoRetry: StorageError not Temporary() and without retriable status code"
-						}
+						action = storageErrorRetryAction(stErr.Temporary(), stErr.Response())
+					} else if stErr, ok := err.(azfile.StorageError); ok { // since Azure Files requests are retried by this policy too
+						action = storageErrorRetryAction(stErr.Temporary(), stErr.Response())
 					} else if _, ok := err.(net.Error); ok {
 						action = "Retry: net.Error"
 					} else if err == io.ErrUnexpectedEOF {
@@ -466,6 +581,23 @@ func NewBlobXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
 	})
 }
 
+// NewFileXferRetryPolicyFactory creates the retry policy for Azure Files, which is the Blob one, so that Files requests
+// also honor Retry-After and get our jitter. The Azure Files SDK's own policy does neither
+func NewFileXferRetryPolicyFactory(o XferRetryOptions) pipeline.Factory {
+	return NewBlobXferRetryPolicyFactory(o)
+}
+
+// storageErrorRetryAction decides whether to retry after a StorageError, from either SDK
+func storageErrorRetryAction(temporary bool, response *http.Response) string {
+	// retry only in case of temporary storage errors.
+	if temporary {
+		return "Retry: StorageError with error service code and Temporary()"
+	} else if response != nil && isSuccessStatusCode(response) { // This is a temporarily work around.
+		return "Retry: StorageError with success status code"
+	}
+	return "NoRetry: StorageError not Temporary() and without retriable status code"
+}
+
 var successStatusCodes = []int{http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent, http.StatusPartialContent}
 
 func isSuccessStatusCode(resp *http.Response) bool {
@@ -40,6 +40,7 @@ type pipelineNetworkStats struct {
 	atomic503CountUnknown      int64 // counts 503's when we don't know the reason
 	atomicE2ETotalMilliseconds int64 // should this be nanoseconds?  Not really needed, given typical minimum operation lengths that we observe
 	atomicStartSeconds         int64
+	atomicThrottledCount       int64 // counts 500's, 503's and 429's, from the start of the job (not just once stats have started)
 	nocopy                     common.NoCopy
 	tunerInterface             ConcurrencyTuner
 }
@@ -119,6 +120,12 @@ func (s *pipelineNetworkStats) GetTotalRetries() int64 {
 		atomic.LoadInt64(&s.atomic503CountUnknown)
 }
 
+// ThrottledCount is how many responses, so far, have asked us to slow down
+func (s *pipelineNetworkStats) ThrottledCount() int64 {
+	s.nocopy.Check()
+	return atomic.LoadInt64(&s.atomicThrottledCount)
+}
+
 func (s *pipelineNetworkStats) IOPSServerBusyPercentage() float32 {
 	s.nocopy.Check()
 	ops := float32(atomic.LoadInt64(&s.atomicOperationCount))
@@ -171,6 +178,10 @@ func (p *xferStatsPolicy) Do(ctx context.Context, request pipeline.Request) (pip
 	resp, err := p.next.Do(ctx, request)
 
 	if p.stats != nil {
+		if err != nil && !isContextCancelledError(err) {
+			p.stats.tunerInterface.recordTimeout() // no response from server, which (for the tuner's purposes) is as bad as a timeout
+		}
+
 		if p.stats.IsStarted() {
 			atomic.AddInt64(&p.stats.atomicOperationCount, 1)
 			atomic.AddInt64(&p.stats.atomicE2ETotalMilliseconds, int64(time.Since(start).Seconds()*1000))
@@ -183,8 +194,15 @@ func (p *xferStatsPolicy) Do(ctx context.Context, request pipeline.Request) (pip
 
 		// always look at retries, even if not started, because concurrency tuner needs to know about them
 		if resp != nil {
+			if rr := resp.Response(); rr != nil && isThrottlingStatus(rr.StatusCode) {
+				atomic.AddInt64(&p.stats.atomicThrottledCount, 1)
+			}
+
 			// TODO should we also count status 500?  It is mentioned here as timeout:https://docs.microsoft.com/en-us/azure/storage/common/storage-scalability-targets
-			if rr := resp.Response(); rr != nil && rr.StatusCode == http.StatusServiceUnavailable {
+			// For now, we only tell the tuner about those, so that it can back off when the service is timing out
+			if rr := resp.Response(); rr != nil && rr.StatusCode == http.StatusInternalServerError {
+				p.stats.tunerInterface.recordTimeout()
+			} else if rr != nil && rr.StatusCode == http.StatusServiceUnavailable {
 				p.stats.tunerInterface.recordRetry() // always tell the tuner
 				if p.stats.IsStarted() {             // but only count it here, if we have started
 					// To find out why the server was busy we need to look at the response
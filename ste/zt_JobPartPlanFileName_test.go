@@ -0,0 +1,286 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type planFileSuite struct{}
+
+var _ = chk.Suite(&planFileSuite{})
+
+// createTestPlanFile writes a plan file, with a couple of transfers, into a fresh plan directory
+func createTestPlanFile(c *chk.C) (planFile JobPartPlanFileName, cleanup func()) {
+	dir, err := ioutil.TempDir("", "planfiletest")
+	c.Assert(err, chk.IsNil)
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{planDir: dir, logger: common.NewAppLogger(pipeline.LogNone, "")}
+
+	order := common.CopyJobPartOrderRequest{
+		JobID:   common.NewJobID(),
+		PartNum: 0,
+		FromTo:  common.EFromTo.LocalBlob(),
+		Transfers: []common.CopyTransfer{
+			{Source: "/a.txt", Destination: "/a.txt", SourceSize: 1},
+			{Source: "/dir/b.txt", Destination: "/dir/b.txt", SourceSize: 2, ContentType: "text/plain"},
+		},
+	}
+	planFile = JobPartPlanFileName(fmt.Sprintf(jobPartPlanFileNameFormat, order.JobID.String(), order.PartNum, DataSchemaVersion))
+	planFile.Create(order)
+
+	return planFile, func() {
+		JobsAdmin = oldJobsAdmin
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func (s *planFileSuite) TestCreateLeavesOnlyTheFinishedFile(c *chk.C) {
+	planFile, cleanup := createTestPlanFile(c)
+	defer cleanup()
+
+	entries, err := ioutil.ReadDir(JobsAdmin.AppPathFolder())
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 1)
+	c.Assert(entries[0].Name(), chk.Equals, string(planFile))
+	c.Assert(planFile.Validate(), chk.IsNil)
+}
+
+func (s *planFileSuite) TestValidateRejectsTruncatedFile(c *chk.C) {
+	planFile, cleanup := createTestPlanFile(c)
+	defer cleanup()
+	path := planFile.GetJobPartPlanPath()
+	info, err := os.Stat(path)
+	c.Assert(err, chk.IsNil)
+
+	// losing just the end of the last transfer's strings must be noticed, as must losing most (or all) of the file
+	for _, size := range []int64{info.Size() - 1, 100, 0} {
+		c.Assert(os.Truncate(path, size), chk.IsNil)
+		c.Assert(planFile.Validate(), chk.NotNil)
+	}
+}
+
+func (s *planFileSuite) TestValidateRejectsMismatchedName(c *chk.C) {
+	planFile, cleanup := createTestPlanFile(c)
+	defer cleanup()
+	jobID, _, err := planFile.Parse()
+	c.Assert(err, chk.IsNil)
+
+	otherJob := JobPartPlanFileName(fmt.Sprintf(jobPartPlanFileNameFormat, common.NewJobID().String(), 0, DataSchemaVersion))
+	c.Assert(otherJob.validate(planFile.GetJobPartPlanPath()), chk.NotNil)
+	otherPart := JobPartPlanFileName(fmt.Sprintf(jobPartPlanFileNameFormat, jobID.String(), 1, DataSchemaVersion))
+	c.Assert(otherPart.validate(planFile.GetJobPartPlanPath()), chk.NotNil)
+}
+
+func (s *planFileSuite) TestOnlyAbandonedTempFilesAreRemoved(c *chk.C) {
+	planFile, cleanup := createTestPlanFile(c)
+	defer cleanup()
+	dir := JobsAdmin.AppPathFolder()
+
+	// one left over from a crash, and one that might still be being written by another process
+	abandoned := filepath.Join(dir, "abandoned--00000.steV"+fmt.Sprint(DataSchemaVersion)+planFileTempSuffix)
+	recent := filepath.Join(dir, "recent--00000.steV"+fmt.Sprint(DataSchemaVersion)+planFileTempSuffix)
+	c.Assert(ioutil.WriteFile(abandoned, []byte("x"), 0644), chk.IsNil)
+	c.Assert(ioutil.WriteFile(recent, []byte("x"), 0644), chk.IsNil)
+	old := time.Now().Add(-2 * abandonedPlanFileAge)
+	c.Assert(os.Chtimes(abandoned, old, old), chk.IsNil)
+
+	JobsAdmin.(*jobsAdmin).removeAbandonedPlanFiles()
+
+	_, err := os.Stat(abandoned)
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+	_, err = os.Stat(recent)
+	c.Assert(err, chk.IsNil)
+	_, err = os.Stat(planFile.GetJobPartPlanPath())
+	c.Assert(err, chk.IsNil)
+}
+
+func (s *planFileSuite) TestCreateForRetryKeepsOnlyGivenTransfers(c *chk.C) {
+	planFile, cleanup := createTestPlanFile(c)
+	defer cleanup()
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	src := mmf.Plan()
+	src.Transfer(1).SetTransferStatus(common.ETransferStatus.Failed(), true)
+	src.Transfer(1).SetErrorCode(403, true)
+
+	retryJobID := common.NewJobID()
+	retryFile := JobsAdmin.NewJobPartPlanFileName(retryJobID, 0)
+	retryFile.CreateForRetry(src, retryJobID, 0, []uint32{1}, true)
+	c.Assert(retryFile.Validate(), chk.IsNil)
+
+	retryMMF := retryFile.Map()
+	defer retryMMF.Unmap()
+	retry := retryMMF.Plan()
+	c.Assert(retry.JobID, chk.Equals, retryJobID)
+	c.Assert(retry.IsFinalPart, chk.Equals, true)
+	c.Assert(retry.FromTo, chk.Equals, src.FromTo)
+	c.Assert(retry.NumTransfers, chk.Equals, uint32(1))
+
+	srcPath, dstPath, _ := retry.TransferSrcDstStrings(0)
+	origSrcPath, origDstPath, _ := src.TransferSrcDstStrings(1)
+	c.Assert(srcPath, chk.Equals, origSrcPath)
+	c.Assert(dstPath, chk.Equals, origDstPath)
+	c.Assert(retry.Transfer(0).SourceSize, chk.Equals, int64(2))
+	c.Assert(retry.Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Started())
+	c.Assert(retry.Transfer(0).ErrorCode(), chk.Equals, int32(0))
+
+	// the source properties come along too
+	headers, _, _, _, _, _, _, _, _, _, _ := retry.TransferSrcPropertiesAndMetadata(0)
+	c.Assert(headers.ContentType, chk.Equals, "text/plain")
+}
+
+func (s *planFileSuite) TestPlanInMemoryWritesNothingToDisk(c *chk.C) {
+	dir, err := ioutil.TempDir("", "planfiletest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{planDir: dir, logger: common.NewAppLogger(pipeline.LogNone, "")}
+	defer func() { JobsAdmin = oldJobsAdmin }()
+
+	order := common.CopyJobPartOrderRequest{
+		JobID:        common.NewJobID(),
+		FromTo:       common.EFromTo.LocalBlob(),
+		PlanLocation: common.EPlanLocation.Memory(),
+		Transfers: []common.CopyTransfer{
+			{Source: "/a.txt", Destination: "/a.txt", SourceSize: 1, ContentType: "text/plain"},
+		},
+	}
+	planFile := JobsAdmin.NewJobPartPlanFileName(order.JobID, 0)
+	planFile.Create(order)
+
+	entries, err := ioutil.ReadDir(dir)
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 0)
+
+	mmf := planFile.Map()
+	plan := mmf.Plan()
+	c.Assert(plan.JobID, chk.Equals, order.JobID)
+	c.Assert(plan.NumTransfers, chk.Equals, uint32(1))
+	srcPath, _, _ := plan.TransferSrcDstStrings(0)
+	c.Assert(srcPath, chk.Equals, "a.txt") // relative to the (empty) source root
+	headers, _, _, _, _, _, _, _, _, _, _ := plan.TransferSrcPropertiesAndMetadata(0)
+	c.Assert(headers.ContentType, chk.Equals, "text/plain")
+
+	// the status is kept, just as it would be in a file
+	plan.Transfer(0).SetTransferStatus(common.ETransferStatus.Success(), false)
+	c.Assert(mmf.Flush(), chk.IsNil)
+	mmf.Unmap()
+	c.Assert(planFile.Map().Plan().Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Success())
+
+	c.Assert(planFile.Delete(), chk.IsNil)
+	_, stillInMemory := inMemoryPlans.plans[planFile]
+	c.Assert(stillInMemory, chk.Equals, false)
+}
+
+func (s *planFileSuite) TestPlanEncryptedWithPassphrase(c *chk.C) {
+	dir, err := ioutil.TempDir("", "planfiletest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{planDir: dir, logger: common.NewAppLogger(pipeline.LogNone, "")}
+	defer func() { JobsAdmin = oldJobsAdmin }()
+	passphraseEnvVar := common.EEnvironmentVariable.PlanFilePassphrase().Name
+	os.Setenv(passphraseEnvVar, "correct horse battery staple")
+	defer os.Unsetenv(passphraseEnvVar)
+
+	order := common.CopyJobPartOrderRequest{
+		JobID:  common.NewJobID(),
+		FromTo: common.EFromTo.LocalBlob(),
+		Transfers: []common.CopyTransfer{
+			{Source: "/secret-folder/a.txt", Destination: "/secret-folder/a.txt", SourceSize: 1},
+		},
+	}
+	planFile := JobsAdmin.NewJobPartPlanFileName(order.JobID, 0)
+	planFile.Create(order)
+
+	content, err := ioutil.ReadFile(planFile.GetJobPartPlanPath())
+	c.Assert(err, chk.IsNil)
+	c.Assert(isEncryptedPlan(content), chk.Equals, true)
+	c.Assert(strings.Contains(string(content), "secret-folder"), chk.Equals, false)
+
+	c.Assert(planFile.Validate(), chk.IsNil)
+	mmf := planFile.Map()
+	c.Assert(mmf.Plan().JobID, chk.Equals, order.JobID)
+	srcPath, _, _ := mmf.Plan().TransferSrcDstStrings(0)
+	c.Assert(srcPath, chk.Equals, "secret-folder/a.txt")
+
+	// the status only reaches the file when the plan is flushed
+	mmf.Plan().Transfer(0).SetTransferStatus(common.ETransferStatus.Success(), false)
+	c.Assert(mmf.Flush(), chk.IsNil)
+	mmf.Unmap()
+	c.Assert(planFile.Map().Plan().Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Success())
+
+	// without the right passphrase, the file can't be read, but isn't taken to be damaged either
+	os.Setenv(passphraseEnvVar, "wrong")
+	err = planFile.Validate()
+	c.Assert(err, chk.FitsTypeOf, planDecryptionError{})
+	os.Unsetenv(passphraseEnvVar)
+	c.Assert(planFile.Validate(), chk.ErrorMatches, ".*"+passphraseEnvVar+".*")
+
+	// and neither can a file that has been changed
+	os.Setenv(passphraseEnvVar, "correct horse battery staple")
+	content[len(content)-1] ^= 1
+	c.Assert(ioutil.WriteFile(planFile.GetJobPartPlanPath(), content, 0600), chk.IsNil)
+	c.Assert(planFile.Validate(), chk.FitsTypeOf, planDecryptionError{})
+}
+
+func (s *planFileSuite) TestPlanKeepsSnapshotIDs(c *chk.C) {
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{logger: common.NewAppLogger(pipeline.LogNone, "")}
+	defer func() { JobsAdmin = oldJobsAdmin }()
+
+	snapshot := "2021-03-04T05:06:07.1234567Z"
+	order := common.CopyJobPartOrderRequest{
+		JobID:        common.NewJobID(),
+		FromTo:       common.EFromTo.BlobLocal(),
+		PlanLocation: common.EPlanLocation.Memory(),
+		Transfers: []common.CopyTransfer{
+			{Source: "/a.txt", Destination: "/a.txt", SourceSize: 1},
+			{Source: "/a.txt", Destination: "/a.2021-03-04T05-06-07.1234567Z.txt", SourceSize: 1, BlobSnapshotID: snapshot},
+			{Source: "/b.txt", Destination: "/b.txt", SourceSize: 1, ContentType: "text/plain"},
+		},
+	}
+	planFile := JobsAdmin.NewJobPartPlanFileName(order.JobID, 0)
+	planFile.Create(order)
+	defer planFile.Delete()
+
+	plan := planFile.Map().Plan()
+	_, _, _, _, _, _, _, _, _, _, snapshotID := plan.TransferSrcPropertiesAndMetadata(0)
+	c.Assert(snapshotID, chk.Equals, "")
+	_, _, _, _, _, _, _, _, _, _, snapshotID = plan.TransferSrcPropertiesAndMetadata(1)
+	c.Assert(snapshotID, chk.Equals, snapshot)
+
+	// the strings of the transfers after it are where they should be
+	_, dstPath, _ := plan.TransferSrcDstStrings(2)
+	c.Assert(dstPath, chk.Equals, "b.txt")
+	headers, _, _, _, _, _, _, _, _, _, _ := plan.TransferSrcPropertiesAndMetadata(2)
+	c.Assert(headers.ContentType, chk.Equals, "text/plain")
+}
@@ -0,0 +1,71 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type activeHoursGateSuite struct{}
+
+var _ = chk.Suite(&activeHoursGateSuite{})
+
+func (s *activeHoursGateSuite) newGate(now time.Time, window string, c *chk.C) (*activeHoursGate, *[]string) {
+	messages := &[]string{}
+	g := newActiveHoursGate(func(msg string) { *messages = append(*messages, msg) })
+	g.now = func() time.Time { return now }
+	var a common.ActiveHours
+	c.Assert(a.Parse(window), chk.IsNil)
+	g.setWindow(a)
+	return g, messages
+}
+
+func (s *activeHoursGateSuite) TestOpenWindowDoesNotWait(c *chk.C) {
+	now := time.Date(2020, 3, 10, 23, 0, 0, 0, time.UTC)
+	g, messages := s.newGate(now, "22:00-06:00", c)
+	c.Assert(g.wait(context.Background()), chk.IsNil)
+	c.Assert(*messages, chk.HasLen, 0)
+
+	// no window at all
+	g, _ = s.newGate(now, "", c)
+	c.Assert(g.wait(context.Background()), chk.IsNil)
+}
+
+func (s *activeHoursGateSuite) TestClosedWindowWaitsUntilCancelled(c *chk.C) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	g, messages := s.newGate(now, "22:00-06:00", c)
+	c.Assert(g.delay(), chk.Equals, 10*time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.Assert(g.wait(ctx), chk.Equals, context.DeadlineExceeded)
+	c.Assert(g.wait(ctx), chk.Equals, context.DeadlineExceeded)
+
+	// it's only announced once, however many requests are waiting, and again when the window opens
+	c.Assert(*messages, chk.HasLen, 1)
+	g.now = func() time.Time { return now.Add(10 * time.Hour) }
+	c.Assert(g.wait(context.Background()), chk.IsNil)
+	c.Assert(*messages, chk.HasLen, 2)
+}
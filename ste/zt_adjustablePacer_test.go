@@ -0,0 +1,93 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type adjustablePacerSuite struct{}
+
+var _ = chk.Suite(&adjustablePacerSuite{})
+
+func (s *adjustablePacerSuite) TestUncappedDoesNotWait(c *chk.C) {
+	p := newAdjustablePacer(0)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.Assert(p.RequestTrafficAllocation(ctx, 1000*1000*1000), chk.IsNil)
+	c.Assert(p.GetTotalTraffic(), chk.Equals, int64(1000*1000*1000))
+}
+
+func (s *adjustablePacerSuite) TestCapCanBeAddedAndRemoved(c *chk.C) {
+	p := newAdjustablePacer(0)
+	defer p.Close()
+
+	// 8 Mbps is a million bytes per second, so asking for ten million must wait (far longer than our timeout)
+	p.setCapMbps(8)
+	c.Assert(p.capMbps(), chk.Equals, float64(8))
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	c.Assert(p.RequestTrafficAllocation(ctx, 10*1000*1000), chk.NotNil)
+	c.Assert(p.GetTotalTraffic(), chk.Equals, int64(0))
+
+	// once the cap is gone, the same request is allowed straight away
+	p.setCapMbps(0)
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel2()
+	c.Assert(p.RequestTrafficAllocation(ctx2, 10*1000*1000), chk.IsNil)
+	p.UndoRequest(1000)
+	c.Assert(p.GetTotalTraffic(), chk.Equals, int64(10*1000*1000-1000))
+}
+
+func (s *adjustablePacerSuite) TestJobControlRoundTrip(c *chk.C) {
+	dir, err := ioutil.TempDir("", "jobcontroltest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{planDir: dir, logger: common.NewAppLogger(pipeline.LogNone, "")}
+	defer func() { JobsAdmin = oldJobsAdmin }()
+	jobID := common.NewJobID()
+
+	_, err = readJobControl(jobID)
+	c.Assert(err, chk.NotNil) // nothing has been set
+
+	capMbps := 50.0
+	c.Assert(writeJobControl(jobID, jobControl{CapMbps: &capMbps}), chk.IsNil)
+	capMbps = 12.5
+	c.Assert(writeJobControl(jobID, jobControl{CapMbps: &capMbps}), chk.IsNil)
+	control, err := readJobControl(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(*control.CapMbps, chk.Equals, 12.5)
+
+	// only the control file itself is left behind, no temp file
+	entries, err := ioutil.ReadDir(dir)
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 1)
+}
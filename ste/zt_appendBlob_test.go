@@ -0,0 +1,269 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"golang.org/x/sync/semaphore"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type appendBlobSuite struct{}
+
+var _ = chk.Suite(&appendBlobSuite{})
+
+// fakeAppendBlobService answers Get Blob Properties with the blob given, or 404 if there isn't one, and records the
+// blocks appended to it
+type fakeAppendBlobService struct {
+	blobType azblob.BlobType
+	length   int64
+
+	mu       sync.Mutex
+	appended []appendedBlock
+}
+
+type appendedBlock struct {
+	position string
+	data     string
+}
+
+func (f *fakeAppendBlobService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodHead && f.blobType == "":
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodHead:
+		w.Header().Set("x-ms-blob-type", string(f.blobType))
+		w.Header().Set("Content-Length", strconv.FormatInt(f.length, 10))
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "appendblock":
+		data, _ := ioutil.ReadAll(r.Body)
+		f.mu.Lock()
+		f.appended = append(f.appended, appendedBlock{position: r.Header.Get("x-ms-blob-condition-appendpos"), data: string(data)})
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// appendTransferMgr is a transfer manager with just enough in it to find the offset to append at, run the chunk funcs
+// of an append blob uploader, and schedule the chunks of a local file
+type appendTransferMgr struct {
+	IJobPartTransferMgr
+	info      TransferInfo
+	putMd5    bool
+	failure   error
+	scheduled int
+}
+
+func (t *appendTransferMgr) Context() context.Context                              { return context.Background() }
+func (t *appendTransferMgr) Info() TransferInfo                                    { return t.info }
+func (t *appendTransferMgr) Log(level pipeline.LogLevel, msg string)               {}
+func (t *appendTransferMgr) ShouldPutCrc64() bool                                  { return false }
+func (t *appendTransferMgr) WasCanceled() bool                                     { return false }
+func (t *appendTransferMgr) OccupyAConnection()                                    {}
+func (t *appendTransferMgr) ReleaseAConnection()                                   {}
+func (t *appendTransferMgr) SetDestinationIsModified()                             {}
+func (t *appendTransferMgr) LogChunkStatus(id common.ChunkID, r common.WaitReason) {}
+func (t *appendTransferMgr) ReportChunkDone(id common.ChunkID) (bool, uint32)      { return false, 0 }
+func (t *appendTransferMgr) FailActiveUpload(where string, err error)              { t.failure = err }
+func (t *appendTransferMgr) ShouldPutMd5() bool                                    { return t.putMd5 }
+func (t *appendTransferMgr) ShouldLog(level pipeline.LogLevel) bool                { return false }
+func (t *appendTransferMgr) IsWaitingOnFinalBodyReads() bool                       { return false }
+func (t *appendTransferMgr) ChunkStatusLogger() common.ChunkStatusLogger           { return t }
+func (t *appendTransferMgr) SlicePool() common.ByteSlicePooler {
+	return common.NewMultiSizeSlicePool(1024)
+}
+func (t *appendTransferMgr) CacheLimiter() common.CacheLimiter  { return common.NewCacheLimiter(1024) }
+func (t *appendTransferMgr) ScheduleChunks(chunkFunc chunkFunc) { t.scheduled++ }
+
+// bytesChunkReader is a chunk reader over data that's in memory already
+type bytesChunkReader struct {
+	common.SingleChunkReader
+	data *bytes.Reader
+}
+
+func (r bytesChunkReader) Read(p []byte) (int, error) { return r.data.Read(p) }
+func (r bytesChunkReader) Seek(offset int64, whence int) (int64, error) {
+	return r.data.Seek(offset, whence)
+}
+func (r bytesChunkReader) Length() int64 { return r.data.Size() }
+func (r bytesChunkReader) Close() error  { return nil }
+
+func (s *appendBlobSuite) newUploader(c *chk.C, server *httptest.Server, srcSize int64) *appendBlobUploader {
+	u, err := url.Parse(server.URL + "/container/blob")
+	c.Assert(err, chk.IsNil)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{MaxTries: 1}})
+	return &appendBlobUploader{appendBlobSenderBase: appendBlobSenderBase{
+		jptm:                   &appendTransferMgr{info: TransferInfo{SourceSize: srcSize, AppendToBlob: true}},
+		destAppendBlobURL:      azblob.NewAppendBlobURL(*u, p),
+		pacer:                  newNullAutoPacer(),
+		soleChunkFuncSemaphore: semaphore.NewWeighted(1),
+	}}
+}
+
+func (s *appendBlobSuite) TestFindAppendOffset(c *chk.C) {
+	service := &fakeAppendBlobService{blobType: azblob.BlobAppendBlob}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	// a destination shorter than the source holds the start of it, so the rest is appended after it
+	service.length = 4
+	u := s.newUploader(c, server, 10)
+	exists, err := u.findAppendOffset()
+	c.Assert(err, chk.IsNil)
+	c.Assert(exists, chk.Equals, true)
+	c.Assert(u.SendOffset(), chk.Equals, int64(4))
+	c.Assert(u.appendingToExisting, chk.Equals, true)
+
+	// one as long as the source holds all of it, so there's nothing to send
+	service.length = 10
+	u = s.newUploader(c, server, 10)
+	exists, err = u.findAppendOffset()
+	c.Assert(err, chk.IsNil)
+	c.Assert(exists, chk.Equals, true)
+	c.Assert(u.SendOffset(), chk.Equals, int64(10))
+
+	// the source can't continue one longer than it
+	service.length = 11
+	u = s.newUploader(c, server, 10)
+	_, err = u.findAppendOffset()
+	c.Assert(err, chk.ErrorMatches, "the destination has 11 bytes but the source only has 10.*")
+	c.Assert(u.SendOffset(), chk.Equals, int64(0))
+	c.Assert(u.appendingToExisting, chk.Equals, false)
+
+	service.blobType = azblob.BlobBlockBlob
+	service.length = 4
+	_, err = s.newUploader(c, server, 10).findAppendOffset()
+	c.Assert(err, chk.ErrorMatches, "the destination is a BlockBlob, so it can't be appended to")
+
+	// with no destination, the whole source is sent to a new blob
+	service.blobType = ""
+	u = s.newUploader(c, server, 10)
+	exists, err = u.findAppendOffset()
+	c.Assert(err, chk.IsNil)
+	c.Assert(exists, chk.Equals, false)
+	c.Assert(u.appendingToExisting, chk.Equals, false)
+}
+
+func (s *appendBlobSuite) TestOnlyThePartAfterTheAppendOffsetIsSent(c *chk.C) {
+	service := &fakeAppendBlobService{}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	source := "0123456789abcdef"
+	u := s.newUploader(c, server, int64(len(source)))
+	u.appendOffset = 6
+	for offset := 0; offset < len(source); offset += 4 {
+		chunk := source[offset : offset+4]
+		id := common.NewChunkID("source", int64(offset), int64(len(chunk)))
+		reader := bytesChunkReader{data: bytes.NewReader([]byte(chunk))}
+		u.GenerateUploadFunc(id, int32(offset/4), reader, false)(0)
+	}
+
+	c.Assert(u.jptm.(*appendTransferMgr).failure, chk.IsNil)
+	// the first chunk is in the blob already, and the second only partly, so what's left of it is sent at the end
+	// of the blob
+	c.Assert(service.appended, chk.DeepEquals, []appendedBlock{
+		{position: "6", data: "67"},
+		{position: "8", data: "89ab"},
+		{position: "12", data: "cdef"},
+	})
+}
+
+// offsetRecordingFile is a local file that records the offsets it's read at
+type offsetRecordingFile struct {
+	*bytes.Reader
+	offsets []int64
+}
+
+func (f *offsetRecordingFile) ReadAt(p []byte, off int64) (int, error) {
+	f.offsets = append(f.offsets, off)
+	return f.Reader.ReadAt(p, off)
+}
+
+func (f *offsetRecordingFile) Close() error {
+	return nil
+}
+
+// prefixSkippingTestUploader is an uploader that finds the given offset in its prologue, and records the chunks it's
+// given to send
+type prefixSkippingTestUploader struct {
+	uploader
+	chunkSize  int64
+	numChunks  uint32
+	sendOffset int64
+	md5Channel chan []byte
+	uploaded   []int64
+}
+
+func (u *prefixSkippingTestUploader) ChunkSize() int64                         { return u.chunkSize }
+func (u *prefixSkippingTestUploader) NumChunks() uint32                        { return u.numChunks }
+func (u *prefixSkippingTestUploader) Prologue(state common.PrologueState) bool { return true }
+func (u *prefixSkippingTestUploader) Md5Channel() chan<- []byte                { return u.md5Channel }
+func (u *prefixSkippingTestUploader) SendOffset() int64                        { return u.sendOffset }
+func (u *prefixSkippingTestUploader) GenerateUploadFunc(id common.ChunkID, blockIndex int32, reader common.SingleChunkReader, chunkIsWholeFile bool) chunkFunc {
+	u.uploaded = append(u.uploaded, id.OffsetInFile())
+	_ = reader.Close()
+	return func(int) {}
+}
+
+type localSourceInfoProvider struct {
+	ISourceInfoProvider
+}
+
+func (localSourceInfoProvider) IsLocal() bool { return true }
+
+func (s *appendBlobSuite) TestChunksAlreadyInTheBlobAreNotRead(c *chk.C) {
+	source := []byte("0123456789abcdef")
+	schedule := func(putMd5 bool) (*offsetRecordingFile, *prefixSkippingTestUploader, *appendTransferMgr) {
+		file := &offsetRecordingFile{Reader: bytes.NewReader(source)}
+		u := &prefixSkippingTestUploader{chunkSize: 4, numChunks: 4, sendOffset: 9, md5Channel: newMd5Channel()}
+		jptm := &appendTransferMgr{putMd5: putMd5}
+		factory := func() (common.CloseableReaderAt, error) { return file, nil }
+		scheduleSendChunks(jptm, "source", file, int64(len(source)), u, factory, localSourceInfoProvider{})
+		return file, u, jptm
+	}
+
+	// the first chunk is read before the prologue finds the offset, and the second lies wholly before it, so isn't
+	// read or sent. The third holds the offset, so it's given to the uploader, to send the part of it after the offset
+	file, u, jptm := schedule(false)
+	c.Assert(file.offsets, chk.DeepEquals, []int64{0, 8, 12})
+	c.Assert(u.uploaded, chk.DeepEquals, []int64{0, 8, 12})
+	c.Assert(jptm.scheduled, chk.Equals, 4)
+
+	// the MD5 of the whole file needs all of it
+	file, u, jptm = schedule(true)
+	c.Assert(file.offsets, chk.DeepEquals, []int64{0, 4, 8, 12})
+	c.Assert(u.uploaded, chk.DeepEquals, []int64{0, 4, 8, 12})
+	c.Assert(jptm.scheduled, chk.Equals, 4)
+}
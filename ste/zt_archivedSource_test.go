@@ -0,0 +1,68 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type archivedSourceSuite struct{}
+
+var _ = chk.Suite(&archivedSourceSuite{})
+
+func (s *archivedSourceSuite) TestSourceMayBeArchived(c *chk.C) {
+	info := TransferInfo{SourceSize: 10, ArchivedSource: common.EArchivedSourceOption.Rehydrate()}
+	c.Assert(sourceMayBeArchived(info, common.EFromTo.BlobLocal()), chk.Equals, true) // tier not known, so must be checked
+
+	info.S2SSrcBlobTier = azblob.AccessTierArchive
+	c.Assert(sourceMayBeArchived(info, common.EFromTo.BlobBlob()), chk.Equals, true)
+
+	info.S2SSrcBlobTier = azblob.AccessTierHot
+	c.Assert(sourceMayBeArchived(info, common.EFromTo.BlobBlob()), chk.Equals, false)
+
+	// only blobs are archived, and empty ones are never read
+	info.S2SSrcBlobTier = azblob.AccessTierNone
+	c.Assert(sourceMayBeArchived(info, common.EFromTo.FileLocal()), chk.Equals, false)
+	info.SourceSize = 0
+	c.Assert(sourceMayBeArchived(info, common.EFromTo.BlobLocal()), chk.Equals, false)
+
+	info.SourceSize = 10
+	info.ArchivedSource = common.EArchivedSourceOption.Fail()
+	c.Assert(sourceMayBeArchived(info, common.EFromTo.BlobLocal()), chk.Equals, false)
+}
+
+func (s *archivedSourceSuite) TestRehydrationNeeded(c *chk.C) {
+	online, mustStart := rehydrationNeeded("Hot", "")
+	c.Assert(online, chk.Equals, true)
+	c.Assert(mustStart, chk.Equals, false)
+
+	online, mustStart = rehydrationNeeded("Archive", "")
+	c.Assert(online, chk.Equals, false)
+	c.Assert(mustStart, chk.Equals, true)
+
+	// already being rehydrated, whether by us or by someone else
+	online, mustStart = rehydrationNeeded("Archive", string(azblob.ArchiveStatusRehydratePendingToCool))
+	c.Assert(online, chk.Equals, false)
+	c.Assert(mustStart, chk.Equals, false)
+}
@@ -0,0 +1,66 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	chk "gopkg.in/check.v1"
+)
+
+type compressionSuite struct{}
+
+var _ = chk.Suite(&compressionSuite{})
+
+func (s *compressionSuite) TestIsCompressible(c *chk.C) {
+	c.Assert(isCompressible("/data/log.txt"), chk.Equals, true)
+	c.Assert(isCompressible("/data/no-extension"), chk.Equals, true)
+	c.Assert(isCompressible("/data/archive.tar.gz"), chk.Equals, false)
+	c.Assert(isCompressible("/data/photo.JPG"), chk.Equals, false)
+}
+
+func (s *compressionSuite) TestGzipToTempFile(c *chk.C) {
+	content := []byte(strings.Repeat("the same line, over and over\n", 1000))
+	src, err := ioutil.TempFile("", "compress")
+	c.Assert(err, chk.IsNil)
+	defer os.Remove(src.Name())
+	_, err = src.Write(content)
+	c.Assert(err, chk.IsNil)
+	c.Assert(src.Close(), chk.IsNil)
+
+	compressedPath, compressedSize, err := gzipToTempFile(src.Name())
+	c.Assert(err, chk.IsNil)
+	defer os.Remove(compressedPath)
+	c.Assert(compressedSize < int64(len(content)), chk.Equals, true)
+
+	compressed, err := ioutil.ReadFile(compressedPath)
+	c.Assert(err, chk.IsNil)
+	c.Assert(int64(len(compressed)), chk.Equals, compressedSize)
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	c.Assert(err, chk.IsNil)
+	decompressed, err := ioutil.ReadAll(gz)
+	c.Assert(err, chk.IsNil)
+	c.Assert(decompressed, chk.DeepEquals, content)
+}
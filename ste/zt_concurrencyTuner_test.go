@@ -170,6 +170,90 @@ func (s *concurrencyTunerSuite) TestConcurrencyTuner__HighBandwidthWorkaround_St
 	s.runTest(c, steps, s.noMax(), true, true)
 }
 
+func (s *concurrencyTunerSuite) TestConcurrencyTuner_BacksOffFromIncreaseThatCausesErrors(c *chk.C) {
+	steps := []tunerStep{
+		{4, concurrencyReasonInitial, 40, false},
+		{16, concurrencyReasonSeeking, 100, false},
+		{4, concurrencyReasonBackoff, 40, false}, // would have tried 64, if not for the 503s and timeouts seen at 16
+		{8, concurrencyReasonSeeking, 100, false},
+	}
+	errors := []int{0, 0, 5, 0}
+
+	s.runTestWithErrors(c, steps, 100, errors)
+}
+
+func (s *concurrencyTunerSuite) TestConcurrencyTuner_BacksOffAndRecoversAfterTuning(c *chk.C) {
+	steps := []tunerStep{
+		{4, concurrencyReasonInitial, 400, false},
+		{16, concurrencyReasonSeeking, 1000, false},
+		{64, concurrencyReasonSeeking, 4000, false},
+		{100, concurrencyReasonHitMax, 8000, false},
+		{100, concurrencyReasonFinished, 8000, false},
+		{75, concurrencyReasonThrottled, 8000, false}, // lots of errors, so back off...
+		{56, concurrencyReasonThrottled, 8000, false}, // ... and again, since they haven't stopped
+		{56, concurrencyReasonFinished, 8000, false},  // a few errors are tolerated, but they do delay the recovery
+		{56, concurrencyReasonFinished, 8000, false},
+		{56, concurrencyReasonFinished, 8000, false},
+		{68, concurrencyReasonRecovering, 8000, false}, // three quiet intervals in a row, so start growing back
+		{68, concurrencyReasonFinished, 8000, false},
+		{68, concurrencyReasonFinished, 8000, false},
+		{82, concurrencyReasonRecovering, 8000, false},
+		{82, concurrencyReasonFinished, 8000, false},
+		{82, concurrencyReasonFinished, 8000, false},
+		{99, concurrencyReasonRecovering, 8000, false},
+		{99, concurrencyReasonFinished, 8000, false},
+		{99, concurrencyReasonFinished, 8000, false},
+		{100, concurrencyReasonRecovering, 8000, false}, // never more than the value found by tuning
+		{100, concurrencyReasonFinished, 8000, false},
+		{100, concurrencyReasonFinished, 8000, false},
+		{100, concurrencyReasonFinished, 8000, false},
+		{100, concurrencyReasonFinished, 8000, false},
+	}
+	errors := []int{0, 0, 0, 0, 0, 10, 10, 1, 0, 0, 0}
+
+	s.runTestWithErrors(c, steps, 100, errors)
+}
+
+func (s *concurrencyTunerSuite) TestConcurrencyTuner_DoesntBackOffAfterTuningWhenBenchmarking(c *chk.C) {
+	t := NewAutoConcurrencyTuner(4, 16, true)
+	t.GetRecommendedConcurrency(-1, false)
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 20; j++ {
+			t.recordRetry()
+			t.recordTimeout()
+		}
+		conc, _ := t.GetRecommendedConcurrency(1000, false)
+		c.Assert(conc, chk.Equals, 16)
+	}
+}
+
+// runTestWithErrors is like runTest, but for non-benchmark tuning, with the given number of 503s and timeouts
+// reported to the tuner before each step (the number doesn't matter, only the total)
+func (s *concurrencyTunerSuite) runTestWithErrors(c *chk.C, steps []tunerStep, maxConcurrency int, errorsBeforeStep []int) {
+	t := NewAutoConcurrencyTuner(4, maxConcurrency, false)
+	observedMbps := -1
+	observedHighCpu := false
+
+	for i, x := range steps {
+		if i < len(errorsBeforeStep) {
+			for e := 0; e < errorsBeforeStep[i]; e++ {
+				if e%2 == 0 {
+					t.recordRetry()
+				} else {
+					t.recordTimeout()
+				}
+			}
+		}
+		conc, reason := t.GetRecommendedConcurrency(observedMbps, observedHighCpu)
+
+		c.Assert(conc, chk.Equals, x.concurrency, chk.Commentf("step %d", i))
+		c.Assert(reason, chk.Equals, x.reason, chk.Commentf("step %d", i))
+
+		observedMbps = x.mbpsObserved
+		observedHighCpu = x.highCpuObserved
+	}
+}
+
 func (s *concurrencyTunerSuite) runTest(c *chk.C, steps []tunerStep, maxConcurrency int, isBenchmarking bool, simulateRetries bool) {
 	t := NewAutoConcurrencyTuner(4, maxConcurrency, isBenchmarking)
 	observedMbps := -1 // there's no observation at first
@@ -0,0 +1,124 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"context"
+	"hash/crc64"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type crc64Suite struct{}
+
+var _ = chk.Suite(&crc64Suite{})
+
+// crc64TestPipeline sends requests through the CRC64 policy to a fake service that records the request, and replies
+// with the given body and CRC64 header
+func crc64TestPipeline(sent *http.Header, body []byte, crcHeader string) pipeline.Pipeline {
+	fakeService := pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			*sent = request.Header
+			header := http.Header{}
+			if crcHeader != "" && request.Header.Get("x-ms-range-get-content-crc64") == "true" {
+				header.Set("x-ms-content-crc64", crcHeader)
+			}
+			return pipeline.NewHTTPResponse(&http.Response{StatusCode: http.StatusPartialContent, Header: header, Body: ioutil.NopCloser(bytes.NewReader(body))}), nil
+		}
+	})
+	return pipeline.NewPipeline([]pipeline.Factory{newTransactionalCrc64PolicyFactory()}, pipeline.Options{HTTPSender: fakeService})
+}
+
+func (s *crc64Suite) doRequest(c *chk.C, ctx context.Context, p pipeline.Pipeline) ([]byte, error) {
+	u, _ := url.Parse("https://account.blob.core.windows.net/container/blob")
+	request, err := pipeline.NewRequest(http.MethodGet, *u, nil)
+	c.Assert(err, chk.IsNil)
+	response, err := p.Do(ctx, nil, request)
+	c.Assert(err, chk.IsNil)
+	return ioutil.ReadAll(response.Response().Body)
+}
+
+func (s *crc64Suite) TestEncodingMatchesService(c *chk.C) {
+	// the service sends the bytes in little-endian order
+	c.Assert(encodeCrc64(1), chk.Equals, "AQAAAAAAAAA=")
+}
+
+func (s *crc64Suite) TestRangeHasherHashesOnlyTheRange(c *chk.C) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	h := &rangeHasher{Hash64: newCrc64Hasher(), skip: 5, remaining: 20}
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		n, _ := h.Write(data[i:end])
+		c.Assert(n, chk.Equals, end-i)
+	}
+	c.Assert(h.Sum64(), chk.Equals, crc64.Checksum(data[5:25], crc64Table))
+}
+
+func (s *crc64Suite) TestUploadCrc64IsSentOnlyWhenInContext(c *chk.C) {
+	var sent http.Header
+	p := crc64TestPipeline(&sent, nil, "")
+
+	_, err := s.doRequest(c, context.Background(), p)
+	c.Assert(err, chk.IsNil)
+	c.Assert(sent.Get("x-ms-content-crc64"), chk.Equals, "")
+
+	_, err = s.doRequest(c, context.WithValue(context.Background(), uploadCrc64ContextKey, "AQAAAAAAAAA="), p)
+	c.Assert(err, chk.IsNil)
+	c.Assert(sent.Get("x-ms-content-crc64"), chk.Equals, "AQAAAAAAAAA=")
+	c.Assert(sent.Get("x-ms-range-get-content-crc64"), chk.Equals, "")
+}
+
+func (s *crc64Suite) TestDownloadedRangeIsChecked(c *chk.C) {
+	data := []byte("some data that was read from a blob")
+	ctx := context.WithValue(context.Background(), downloadCrc64ContextKey, true)
+	var sent http.Header
+
+	body, err := s.doRequest(c, ctx, crc64TestPipeline(&sent, data, encodeCrc64(crc64.Checksum(data, crc64Table))))
+	c.Assert(err, chk.IsNil)
+	c.Assert(body, chk.DeepEquals, data)
+	c.Assert(sent.Get("x-ms-range-get-content-crc64"), chk.Equals, "true")
+
+	_, err = s.doRequest(c, ctx, crc64TestPipeline(&sent, data, encodeCrc64(crc64.Checksum(data[1:], crc64Table))))
+	c.Assert(err, chk.Equals, errRangeCrc64Mismatch)
+}
+
+func (s *crc64Suite) TestWholeFileCheckFollowsValidationOption(c *chk.C) {
+	stored := common.Metadata{common.Crc64MetadataKey: encodeCrc64(42)}
+	o := common.EHashValidationOption
+
+	c.Assert(crc64Checksum.check(o.FailIfDifferent(), stored, encodeCrc64(42), nullTransferLogger{}), chk.IsNil)
+	c.Assert(crc64Checksum.check(o.FailIfDifferent(), stored, encodeCrc64(43), nullTransferLogger{}), chk.Equals, errCrc64Mismatch)
+	c.Assert(crc64Checksum.check(o.LogOnly(), stored, encodeCrc64(43), nullTransferLogger{}), chk.IsNil)
+	c.Assert(crc64Checksum.check(o.NoCheck(), stored, encodeCrc64(43), nullTransferLogger{}), chk.IsNil)
+
+	c.Assert(crc64Checksum.check(o.FailIfDifferent(), common.Metadata{}, encodeCrc64(43), nullTransferLogger{}), chk.IsNil)
+	c.Assert(crc64Checksum.check(o.FailIfDifferentOrMissing(), common.Metadata{}, encodeCrc64(43), nullTransferLogger{}), chk.Equals, errExpectedCrc64Missing)
+}
@@ -0,0 +1,55 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type fileTimesSuite struct{}
+
+var _ = chk.Suite(&fileTimesSuite{})
+
+func (s *fileTimesSuite) TestFileTimesMetadataRecordsLastWriteTime(c *chk.C) {
+	dir, err := ioutil.TempDir("", "fileTimes")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file.txt")
+	c.Assert(ioutil.WriteFile(path, []byte("content"), 0644), chk.IsNil)
+	lastWriteTime := time.Date(2015, 10, 21, 16, 29, 0, 0, time.UTC)
+	c.Assert(os.Chtimes(path, lastWriteTime, lastWriteTime), chk.IsNil)
+
+	metadata, err := fileTimesMetadata(path)
+	c.Assert(err, chk.IsNil)
+	c.Assert(metadata[common.LastWriteTimeMetadataKey], chk.Equals, "2015-10-21T16:29:00Z")
+
+	if creationTime, ok := metadata[common.CreationTimeMetadataKey]; ok {
+		_, err = time.Parse(time.RFC3339Nano, creationTime)
+		c.Assert(err, chk.IsNil)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type jobBundleSuite struct{}
+
+var _ = chk.Suite(&jobBundleSuite{})
+
+// exportTestJob makes a bundle of a one-part job, whose first transfer has succeeded, as ExportJob would
+func exportTestJob(c *chk.C) common.JobBundle {
+	order := common.CopyJobPartOrderRequest{
+		JobID:        common.NewJobID(),
+		FromTo:       common.EFromTo.LocalBlob(),
+		SourceRoot:   common.ResourceString{Value: "/old/data"},
+		IsFinalPart:  true,
+		PlanLocation: common.EPlanLocation.Memory(),
+		Transfers: []common.CopyTransfer{
+			{Source: "/a.txt", Destination: "/a.txt", SourceSize: 1},
+			{Source: "/b.txt", Destination: "/b.txt", SourceSize: 2},
+		},
+	}
+	planFile := JobsAdmin.NewJobPartPlanFileName(order.JobID, 0)
+	planFile.Create(order)
+	defer planFile.Delete()
+	mmf := planFile.Map()
+	mmf.Plan().Transfer(0).SetTransferStatus(common.ETransferStatus.Success(), false)
+
+	plan := append([]byte{}, (*common.MMF)(mmf).Slice()...)
+	return common.JobBundle{DataSchemaVersion: DataSchemaVersion, JobID: order.JobID, Parts: []common.JobPartBundle{{PartNum: 0, Plan: plan}}}
+}
+
+func (s *jobBundleSuite) TestImportJobKeepsProgressAndMovesRoot(c *chk.C) {
+	dir, err := ioutil.TempDir("", "jobbundletest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{planDir: dir, logger: common.NewAppLogger(pipeline.LogNone, "")}
+	defer func() { JobsAdmin = oldJobsAdmin }()
+
+	bundle := exportTestJob(c)
+	resp := ImportJob(common.ImportJobRequest{Bundle: bundle, Source: "/new/data"})
+	c.Assert(resp.ErrorMsg, chk.Equals, "")
+	c.Assert(resp.TransfersLeft, chk.Equals, uint32(1))
+
+	planFile := JobsAdmin.NewJobPartPlanFileName(bundle.JobID, 0)
+	c.Assert(planFile.Validate(), chk.IsNil)
+	plan := planFile.Map().Plan()
+	c.Assert(string(plan.SourceRoot[:plan.SourceRootLength]), chk.Equals, "/new/data")
+	c.Assert(plan.Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Success())
+	c.Assert(plan.Transfer(1).TransferStatus(), chk.Equals, common.ETransferStatus.Started())
+	src, _, _ := plan.TransferSrcDstStrings(1)
+	c.Assert(src, chk.Equals, "/new/data/b.txt")
+}
+
+func (s *jobBundleSuite) TestImportJobRejectsBadBundles(c *chk.C) {
+	dir, err := ioutil.TempDir("", "jobbundletest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{planDir: dir, logger: common.NewAppLogger(pipeline.LogNone, "")}
+	defer func() { JobsAdmin = oldJobsAdmin }()
+
+	bundle := exportTestJob(c)
+	bundle.DataSchemaVersion--
+	c.Assert(ImportJob(common.ImportJobRequest{Bundle: bundle}).ErrorMsg, chk.Matches, ".*version.*")
+
+	bundle = exportTestJob(c)
+	bundle.Parts[0].Plan = bundle.Parts[0].Plan[:len(bundle.Parts[0].Plan)-1]
+	c.Assert(ImportJob(common.ImportJobRequest{Bundle: bundle}).ErrorMsg, chk.Matches, "part 0 of the bundle is damaged.*")
+
+	// the plan must belong to the job that the bundle says it's for
+	other := exportTestJob(c)
+	bundle = exportTestJob(c)
+	bundle.Parts = other.Parts
+	c.Assert(ImportJob(common.ImportJobRequest{Bundle: bundle}).ErrorMsg, chk.Matches, "part 0 of the bundle is damaged.*")
+
+	entries, err := ioutil.ReadDir(dir)
+	c.Assert(err, chk.IsNil)
+	c.Assert(entries, chk.HasLen, 0)
+}
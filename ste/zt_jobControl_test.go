@@ -0,0 +1,98 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type jobControlSuite struct{}
+
+var _ = chk.Suite(&jobControlSuite{})
+
+func (s *jobControlSuite) TestUpdateKeepsEarlierSettings(c *chk.C) {
+	_, cleanup := createTestPlanFile(c) // for the plan directory
+	defer cleanup()
+	jobID := common.NewJobID()
+
+	c.Assert(updateJobControl(jobID, func(control *jobControl) { control.CancelledSources = []string{"a.txt"} }), chk.IsNil)
+	control, err := readJobControl(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(control.CapMbps, chk.IsNil) // so the cap is left alone
+
+	capMbps := 20.0
+	c.Assert(updateJobControl(jobID, func(control *jobControl) { control.CapMbps = &capMbps }), chk.IsNil)
+	c.Assert(updateJobControl(jobID, func(control *jobControl) {
+		control.CancelledSources = append(control.CancelledSources, "b.txt")
+	}), chk.IsNil)
+	control, err = readJobControl(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(*control.CapMbps, chk.Equals, 20.0)
+	c.Assert(control.CancelledSources, chk.DeepEquals, []string{"a.txt", "b.txt"})
+}
+
+func (s *jobControlSuite) TestSourceMatching(c *chk.C) {
+	planFile, cleanup := createTestPlanFile(c)
+	defer cleanup()
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	plan := mmf.Plan()
+
+	// transfer 1 is /dir/b.txt
+	for _, given := range []string{"dir/b.txt", "/dir/b.txt", `dir\b.txt`, "dir/b.txt/"} {
+		c.Assert(newTransferSourceMatcher([]string{given}).matches(plan, 1), chk.Equals, true, chk.Commentf("given %q", given))
+	}
+	for _, given := range []string{"b.txt", "dir", "a.txt"} {
+		c.Assert(newTransferSourceMatcher([]string{given}).matches(plan, 1), chk.Equals, false, chk.Commentf("given %q", given))
+	}
+	c.Assert(newTransferSourceMatcher(nil).matches(plan, 1), chk.Equals, false)
+
+	// the SAS of a remote source doesn't matter
+	c.Assert(normalizeSourceForMatching("https://acct.blob.core.windows.net/c/dir/b.txt?sv=x&sig=y"), chk.Equals,
+		normalizeSourceForMatching("https://acct.blob.core.windows.net/c/dir/b.txt"))
+}
+
+func (s *jobControlSuite) TestOnlyUnfinishedTransfersAreCancelled(c *chk.C) {
+	planFile, cleanup := createTestPlanFile(c)
+	defer cleanup()
+	mmf := planFile.Map()
+	defer mmf.Unmap()
+	jpm := &jobPartMgr{planMMF: mmf}
+	plan := mmf.Plan()
+	both := newTransferSourceMatcher([]string{"a.txt", "dir/b.txt"})
+
+	plan.Transfer(0).SetTransferStatus(common.ETransferStatus.Success(), true)
+	plan.Transfer(1).SetTransferStatus(common.ETransferStatus.Started(), true)
+	c.Assert(jpm.cancelTransfersByUser(both), chk.Equals, 1)
+	c.Assert(plan.Transfer(0).TransferStatus(), chk.Equals, common.ETransferStatus.Success())
+	c.Assert(plan.Transfer(1).TransferStatus(), chk.Equals, common.ETransferStatus.CancelledByUser())
+
+	// cancelling again does nothing, and nor does anything else overwrite the cancellation
+	c.Assert(jpm.cancelTransfersByUser(both), chk.Equals, 0)
+	plan.Transfer(1).SetTransferStatus(common.ETransferStatus.Cancelled(), false)
+	c.Assert(plan.Transfer(1).TransferStatus(), chk.Equals, common.ETransferStatus.CancelledByUser())
+
+	// nor is a failure turned into a cancellation
+	plan.Transfer(1).SetTransferStatus(common.ETransferStatus.Failed(), true)
+	c.Assert(jpm.cancelTransfersByUser(both), chk.Equals, 0)
+	c.Assert(plan.Transfer(1).TransferStatus(), chk.Equals, common.ETransferStatus.Failed())
+}
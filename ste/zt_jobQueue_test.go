@@ -0,0 +1,82 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"os"
+	"time"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type jobQueueSuite struct{}
+
+var _ = chk.Suite(&jobQueueSuite{})
+
+func (s *jobQueueSuite) TestQueuedJobIsVisibleBeforeItHasPlanFiles(c *chk.C) {
+	_, cleanup := createTestPlanFile(c) // for the plan directory
+	defer cleanup()
+	jobID, afterJob := common.NewJobID(), common.NewJobID()
+
+	resp := UpdateQueuedJob(common.UpdateQueuedJobRequest{JobID: jobID, AfterJob: afterJob, CommandString: "copy a b", JobStatus: common.EJobStatus.Queued()})
+	c.Assert(resp.ErrorMsg, chk.Equals, "")
+	c.Assert(queuedJobIDs(), chk.DeepEquals, []common.JobID{jobID})
+
+	status := GetJobStatus(jobID)
+	c.Assert(status.ErrorMsg, chk.Equals, "")
+	c.Assert(status.JobStatus, chk.Equals, common.EJobStatus.Queued())
+	c.Assert(status.Abandoned, chk.Equals, false)
+
+	summary := GetJobSummary(jobID)
+	c.Assert(summary.ErrorMsg, chk.Equals, "")
+	c.Assert(summary.JobStatus, chk.Equals, common.EJobStatus.Queued())
+
+	// the status changes, but the job was queued when it was first recorded
+	first, err := readQueuedJob(jobID)
+	c.Assert(err, chk.IsNil)
+	UpdateQueuedJob(common.UpdateQueuedJobRequest{JobID: jobID, AfterJob: afterJob, CommandString: "copy a b", JobStatus: common.EJobStatus.Failed()})
+	second, err := readQueuedJob(jobID)
+	c.Assert(err, chk.IsNil)
+	c.Assert(second.JobStatus, chk.Equals, common.EJobStatus.Failed())
+	c.Assert(second.QueuedAt.Equal(first.QueuedAt), chk.Equals, true)
+	c.Assert(second.AfterJob, chk.Equals, afterJob)
+
+	c.Assert(GetJobStatus(common.NewJobID()).ErrorMsg, chk.Matches, "no job with JobID .* exists")
+}
+
+func (s *jobQueueSuite) TestJobWithStaleHeartbeatIsAbandoned(c *chk.C) {
+	_, cleanup := createTestPlanFile(c)
+	defer cleanup()
+	jobID := common.NewJobID()
+	UpdateQueuedJob(common.UpdateQueuedJobRequest{JobID: jobID, JobStatus: common.EJobStatus.Queued()})
+	c.Assert(GetJobStatus(jobID).Abandoned, chk.Equals, false)
+
+	// as if the process holding the job had been killed a while ago
+	longAgo := time.Now().Add(-2 * jobHeartbeatStaleAfter)
+	c.Assert(os.Chtimes(jobHeartbeatPath(jobID), longAgo, longAgo), chk.IsNil)
+	c.Assert(GetJobStatus(jobID).Abandoned, chk.Equals, true)
+
+	// but a job that has stopped doesn't need a process
+	UpdateQueuedJob(common.UpdateQueuedJobRequest{JobID: jobID, JobStatus: common.EJobStatus.Failed()})
+	c.Assert(GetJobStatus(jobID).Abandoned, chk.Equals, false)
+}
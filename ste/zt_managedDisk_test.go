@@ -0,0 +1,54 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"net/url"
+
+	chk "gopkg.in/check.v1"
+)
+
+type managedDiskSuite struct{}
+
+var _ = chk.Suite(&managedDiskSuite{})
+
+func (s *managedDiskSuite) TestManagedDiskURLs(c *chk.C) {
+	parse := func(raw string) url.URL {
+		u, err := url.Parse(raw)
+		c.Assert(err, chk.IsNil)
+		return *u
+	}
+
+	importURL := parse("https://md-impexp-t0abc.z1.blob.storage.azure.net/xyz/abcd?sv=2018-03-28&sr=b")
+	exportURL := parse("https://md-hdd-t0abc.z1.blob.storage.azure.net/xyz/abcd?sv=2018-03-28&sr=b")
+	blobURL := parse("https://mdaccount.blob.core.windows.net/container/disk.vhd")
+
+	c.Assert(IsManagedDiskURL(importURL), chk.Equals, true)
+	c.Assert(IsManagedDiskURL(exportURL), chk.Equals, true)
+	c.Assert(IsManagedDiskURL(blobURL), chk.Equals, false)
+
+	// only the import URLs can be written to
+	c.Assert(IsManagedDiskImportURL(importURL), chk.Equals, true)
+	c.Assert(IsManagedDiskImportURL(exportURL), chk.Equals, false)
+	c.Assert(IsManagedDiskImportURL(blobURL), chk.Equals, false)
+	c.Assert(destinationIsManagedDisk(importURL.String()), chk.Equals, true)
+	c.Assert(destinationIsManagedDisk(blobURL.String()), chk.Equals, false)
+}
@@ -0,0 +1,82 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type md5MismatchSuite struct{}
+
+var _ = chk.Suite(&md5MismatchSuite{})
+
+type nullTransferLogger struct{}
+
+func (nullTransferLogger) LogAtLevelForCurrentTransfer(level pipeline.LogLevel, msg string) {}
+
+func (s *md5MismatchSuite) TestMismatchIsSeenWhateverTheOption(c *chk.C) {
+	a, b := []byte{1, 2, 3}, []byte{1, 2, 4}
+
+	for _, option := range []common.HashValidationOption{
+		common.EHashValidationOption.LogOnly(),
+		common.EHashValidationOption.FailIfDifferent(),
+		common.EHashValidationOption.FailIfDifferentOrMissing(),
+	} {
+		comparer := md5Comparer{expected: a, actualAsSaved: b, validationOption: option, logger: nullTransferLogger{}}
+		c.Assert(comparer.mismatched(), chk.Equals, true)
+		err := comparer.Check()
+		c.Assert(err == nil, chk.Equals, option == common.EHashValidationOption.LogOnly())
+
+		comparer.actualAsSaved = a
+		c.Assert(comparer.mismatched(), chk.Equals, false)
+	}
+
+	// nothing is compared when there's nothing to compare, or when the check is off
+	missing := md5Comparer{actualAsSaved: b, validationOption: common.EHashValidationOption.LogOnly(), logger: nullTransferLogger{}}
+	c.Assert(missing.mismatched(), chk.Equals, false)
+	off := md5Comparer{expected: a, actualAsSaved: b, validationOption: common.EHashValidationOption.NoCheck()}
+	c.Assert(off.mismatched(), chk.Equals, false)
+}
+
+func (s *md5MismatchSuite) TestRecorder(c *chk.C) {
+	var none *md5MismatchRecorder
+	none.record("src", "dst", true)
+	c.Assert(none.list(), chk.HasLen, 0)
+
+	r := newMd5MismatchRecorder()
+	r.record("https://account.blob.core.windows.net/c/kept", "/d/kept", true)
+	r.record("https://account.blob.core.windows.net/c/failed", "/d/failed", false)
+
+	list := r.list()
+	c.Assert(list, chk.HasLen, 2)
+	c.Assert(list[0].Dst, chk.Equals, "/d/kept")
+	c.Assert(list[0].TransferStatus, chk.Equals, common.ETransferStatus.Success())
+	c.Assert(list[1].Src, chk.Equals, "https://account.blob.core.windows.net/c/failed")
+	c.Assert(list[1].TransferStatus, chk.Equals, common.ETransferStatus.Failed())
+	c.Assert(list[1].ErrorMessage, chk.Equals, md5MismatchMessage)
+
+	// the list handed out is a copy
+	list[0].Dst = "changed"
+	c.Assert(r.list()[0].Dst, chk.Equals, "/d/kept")
+}
@@ -0,0 +1,56 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type metadataPlaceholdersSuite struct{}
+
+var _ = chk.Suite(&metadataPlaceholdersSuite{})
+
+func (s *metadataPlaceholdersSuite) TestExpandMetadataPlaceholders(c *chk.C) {
+	jobID := common.NewJobID()
+	metadata := common.Metadata{
+		"source":   "ingest",
+		"name":     "{filename}",
+		"original": "share/{relpath}",
+		"job":      "{jobid}",
+	}
+
+	expanded := expandMetadataPlaceholders(metadata, "dir/sub/file.txt", jobID)
+	c.Assert(expanded, chk.DeepEquals, common.Metadata{
+		"source":   "ingest",
+		"name":     "file.txt",
+		"original": "share/dir/sub/file.txt",
+		"job":      jobID.String(),
+	})
+
+	// the metadata of the job isn't changed, since it's shared by all the transfers
+	c.Assert(metadata["name"], chk.Equals, "{filename}")
+}
+
+func (s *metadataPlaceholdersSuite) TestExpandedValuesAreASCII(c *chk.C) {
+	expanded := expandMetadataPlaceholders(common.Metadata{"name": "{filename}"}, "dir/café 100%.txt", common.NewJobID())
+	c.Assert(expanded["name"], chk.Equals, "caf%C3%A9 100%25.txt")
+}
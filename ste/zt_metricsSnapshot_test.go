@@ -0,0 +1,100 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type metricsSnapshotSuite struct{}
+
+var _ = chk.Suite(&metricsSnapshotSuite{})
+
+func (s *metricsSnapshotSuite) readSnapshot(w *metricsSnapshotWriter, c *chk.C) JobMetricsSnapshot {
+	b, err := ioutil.ReadFile(w.path)
+	c.Assert(err, chk.IsNil)
+	var snapshot JobMetricsSnapshot
+	c.Assert(json.Unmarshal(b, &snapshot), chk.IsNil)
+	return snapshot
+}
+
+func (s *metricsSnapshotSuite) TestSnapshotsAreWrittenPeriodically(c *chk.C) {
+	dir, err := ioutil.TempDir("", "metrics")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	jobID := common.NewJobID()
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	w := newMetricsSnapshotWriter(jobID, dir)
+	w.now = func() time.Time { return now }
+
+	js := common.ListJobSummaryResponse{JobID: jobID, TotalTransfers: 10, TransfersCompleted: 2, BytesOverWire: 1000 * 1000}
+	c.Assert(w.maybeWrite(js), chk.IsNil)
+	snapshot := s.readSnapshot(w, c)
+	c.Assert(snapshot.JobID, chk.Equals, jobID)
+	c.Assert(snapshot.TransfersCompleted, chk.Equals, uint32(2))
+	c.Assert(snapshot.ThroughputMbps, chk.Equals, float64(0)) // nothing to measure it against yet
+
+	// too soon for the next one
+	now = now.Add(time.Second)
+	js.TransfersCompleted = 5
+	c.Assert(w.maybeWrite(js), chk.IsNil)
+	c.Assert(s.readSnapshot(w, c).TransfersCompleted, chk.Equals, uint32(2))
+
+	// 10 MB in the 10 seconds since the first snapshot is 8 Mb/s
+	now = now.Add(metricsSnapshotInterval - time.Second)
+	js.BytesOverWire += 10 * 1000 * 1000
+	c.Assert(w.maybeWrite(js), chk.IsNil)
+	snapshot = s.readSnapshot(w, c)
+	c.Assert(snapshot.TransfersCompleted, chk.Equals, uint32(5))
+	c.Assert(snapshot.ThroughputMbps, chk.Equals, float64(8))
+	c.Assert(snapshot.Timestamp.Equal(now), chk.Equals, true)
+}
+
+func (s *metricsSnapshotSuite) TestFinalSnapshotIsAlwaysWritten(c *chk.C) {
+	dir, err := ioutil.TempDir("", "metrics")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	w := newMetricsSnapshotWriter(common.NewJobID(), dir)
+	w.now = func() time.Time { return now }
+
+	js := common.ListJobSummaryResponse{JobStatus: common.EJobStatus.InProgress()}
+	c.Assert(w.maybeWrite(js), chk.IsNil)
+
+	js.JobStatus = common.EJobStatus.CompletedWithErrors()
+	c.Assert(w.maybeWrite(js), chk.IsNil)
+	c.Assert(s.readSnapshot(w, c).JobStatus, chk.Equals, common.EJobStatus.CompletedWithErrors())
+}
+
+func (s *metricsSnapshotSuite) TestOnlyFirstFailureIsReported(c *chk.C) {
+	w := newMetricsSnapshotWriter(common.NewJobID(), "/no/such/folder")
+	done := common.ListJobSummaryResponse{JobStatus: common.EJobStatus.Completed()}
+	c.Assert(w.maybeWrite(done), chk.NotNil)
+	c.Assert(w.maybeWrite(done), chk.IsNil)
+}
@@ -0,0 +1,63 @@
+// Copyright © Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type opsRateLimiterSuite struct{}
+
+var _ = chk.Suite(&opsRateLimiterSuite{})
+
+func (s *opsRateLimiterSuite) TestRequestsAreSpacedOut(c *chk.C) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+	l := newOpsRateLimiter(4)
+	l.now = func() time.Time { return now }
+
+	c.Assert(l.reserve(), chk.Equals, time.Duration(0))
+	c.Assert(l.reserve(), chk.Equals, 250*time.Millisecond)
+	c.Assert(l.reserve(), chk.Equals, 500*time.Millisecond)
+
+	// slots that went unused are not saved up, so there's no burst after a quiet spell
+	now = now.Add(10 * time.Second)
+	c.Assert(l.reserve(), chk.Equals, time.Duration(0))
+	c.Assert(l.reserve(), chk.Equals, 250*time.Millisecond)
+}
+
+func (s *opsRateLimiterSuite) TestNoCap(c *chk.C) {
+	l := newOpsRateLimiter(0)
+	for i := 0; i < 100; i++ {
+		c.Assert(l.reserve(), chk.Equals, time.Duration(0))
+	}
+}
+
+func (s *opsRateLimiterSuite) TestWaitEndsWhenCancelled(c *chk.C) {
+	l := newOpsRateLimiter(1)
+	c.Assert(l.wait(context.Background()), chk.IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Assert(l.wait(ctx), chk.Equals, context.Canceled)
+}
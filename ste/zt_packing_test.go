@@ -0,0 +1,149 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	chk "gopkg.in/check.v1"
+)
+
+type packingSuite struct{}
+
+var _ = chk.Suite(&packingSuite{})
+
+func (s *packingSuite) TestPackAndUnpack(c *chk.C) {
+	src, err := ioutil.TempDir("", "packtest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(src)
+
+	mtime := time.Date(2020, time.May, 1, 2, 3, 4, 0, time.UTC)
+	c.Assert(ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("aaa"), 0600), chk.IsNil)
+	c.Assert(os.Chtimes(filepath.Join(src, "a.txt"), mtime, mtime), chk.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(src, "empty"), nil, 0600), chk.IsNil)
+	c.Assert(os.Mkdir(filepath.Join(src, "sub"), 0700), chk.IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"), 0600), chk.IsNil)
+	if runtime.GOOS != "windows" {
+		c.Assert(os.Symlink("a.txt", filepath.Join(src, "link")), chk.IsNil)
+	}
+
+	for _, gzipped := range []bool{false, true} {
+		archive, size, count, err := packDirectoryToTempFile(src, gzipped)
+		c.Assert(err, chk.IsNil)
+		c.Assert(count, chk.Equals, 2) // not the subdirectory, its file, or the link
+		fi, err := os.Stat(archive)
+		c.Assert(err, chk.IsNil)
+		c.Assert(fi.Size(), chk.Equals, size)
+
+		// the name says whether it's gzipped
+		named := archive + ".tar"
+		if gzipped {
+			named += ".gz"
+		}
+		c.Assert(os.Rename(archive, named), chk.IsNil)
+		c.Assert(isArchiveToUnpack(named), chk.Equals, true)
+
+		dst, err := ioutil.TempDir("", "packtest")
+		c.Assert(err, chk.IsNil)
+		count, err = unpackArchive(named, dst, true)
+		c.Assert(err, chk.IsNil)
+		c.Assert(count, chk.Equals, 2)
+		_ = os.Remove(named)
+
+		content, err := ioutil.ReadFile(filepath.Join(dst, "a.txt"))
+		c.Assert(err, chk.IsNil)
+		c.Assert(string(content), chk.Equals, "aaa")
+		fi, err = os.Stat(filepath.Join(dst, "a.txt"))
+		c.Assert(err, chk.IsNil)
+		c.Assert(fi.ModTime().Equal(mtime), chk.Equals, true)
+		fi, err = os.Stat(filepath.Join(dst, "empty"))
+		c.Assert(err, chk.IsNil)
+		c.Assert(fi.Size(), chk.Equals, int64(0))
+		_, err = os.Stat(filepath.Join(dst, "sub"))
+		c.Assert(os.IsNotExist(err), chk.Equals, true)
+		os.RemoveAll(dst)
+	}
+}
+
+func writeTestTar(c *chk.C, entries map[string]string) string {
+	f, err := ioutil.TempFile("", "packtest-*.tar")
+	c.Assert(err, chk.IsNil)
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for name, content := range entries {
+		c.Assert(tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}), chk.IsNil)
+		_, err = tw.Write([]byte(content))
+		c.Assert(err, chk.IsNil)
+	}
+	c.Assert(tw.Close(), chk.IsNil)
+	return f.Name()
+}
+
+func (s *packingSuite) TestUnpackKeepsExistingFilesUnlessOverwriting(c *chk.C) {
+	archive := writeTestTar(c, map[string]string{"x": "new", "d/y": "new"})
+	defer os.Remove(archive)
+	dst, err := ioutil.TempDir("", "packtest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dst)
+	c.Assert(ioutil.WriteFile(filepath.Join(dst, "x"), []byte("old"), 0600), chk.IsNil)
+
+	count, err := unpackArchive(archive, dst, false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(count, chk.Equals, 1)
+	content, _ := ioutil.ReadFile(filepath.Join(dst, "x"))
+	c.Assert(string(content), chk.Equals, "old")
+	content, _ = ioutil.ReadFile(filepath.Join(dst, "d", "y"))
+	c.Assert(string(content), chk.Equals, "new")
+
+	count, err = unpackArchive(archive, dst, true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(count, chk.Equals, 2)
+	content, _ = ioutil.ReadFile(filepath.Join(dst, "x"))
+	c.Assert(string(content), chk.Equals, "new")
+}
+
+func (s *packingSuite) TestUnpackRejectsPathsOutsideTheDirectory(c *chk.C) {
+	dst, err := ioutil.TempDir("", "packtest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dst)
+
+	for _, name := range []string{"../escaped", "a/../../escaped", "/abs"} {
+		archive := writeTestTar(c, map[string]string{name: "x"})
+		_, err = unpackArchive(archive, filepath.Join(dst, "in"), true)
+		c.Assert(err, chk.Equals, errUnsafeArchiveEntry, chk.Commentf(name))
+		_ = os.Remove(archive)
+	}
+	_, err = os.Stat(filepath.Join(dst, "escaped"))
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *packingSuite) TestIsArchiveToUnpack(c *chk.C) {
+	c.Assert(isArchiveToUnpack("/d/azcopy-packed.tar"), chk.Equals, true)
+	c.Assert(isArchiveToUnpack("/d/x.TGZ"), chk.Equals, true)
+	c.Assert(isArchiveToUnpack("/d/x.gz"), chk.Equals, false)
+	c.Assert(isArchiveToUnpack("/d/tar"), chk.Equals, false)
+}
@@ -0,0 +1,106 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"os"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type planCheckpointerSuite struct{}
+
+var _ = chk.Suite(&planCheckpointerSuite{})
+
+func (s *planCheckpointerSuite) TestParseInterval(c *chk.C) {
+	envName := common.EEnvironmentVariable.CheckpointInterval().Name
+	defer os.Unsetenv(envName)
+
+	cases := map[string]CheckpointInterval{
+		"":      CheckpointEachPart,
+		"0":     CheckpointEachTransfer,
+		"never": CheckpointNever,
+		"NEVER": CheckpointNever,
+		"30s":   CheckpointInterval(30 * time.Second),
+	}
+	for value, expected := range cases {
+		c.Assert(os.Setenv(envName, value), chk.IsNil)
+		interval, err := NewCheckpointInterval()
+		c.Assert(err, chk.IsNil)
+		c.Assert(interval, chk.Equals, expected, chk.Commentf("value %q", value))
+	}
+
+	for _, bad := range []string{"-1m", "often", "30"} {
+		c.Assert(os.Setenv(envName, bad), chk.IsNil)
+		_, err := NewCheckpointInterval()
+		c.Assert(err, chk.NotNil, chk.Commentf("value %q", bad))
+	}
+}
+
+func (s *planCheckpointerSuite) newCheckpointer(interval CheckpointInterval) (*planCheckpointer, map[*jobPartMgr]int) {
+	flushes := make(map[*jobPartMgr]int)
+	cp := newPlanCheckpointer(interval)
+	cp.flush = func(jpm *jobPartMgr) { flushes[jpm]++ }
+	return cp, flushes
+}
+
+func (s *planCheckpointerSuite) TestWhenEachKindFlushes(c *chk.C) {
+	part := &jobPartMgr{}
+
+	cp, flushes := s.newCheckpointer(CheckpointEachTransfer)
+	cp.transferDone(part)
+	cp.transferDone(part)
+	cp.partDone(part)
+	c.Assert(flushes[part], chk.Equals, 3)
+
+	cp, flushes = s.newCheckpointer(CheckpointEachPart)
+	cp.transferDone(part)
+	cp.transferDone(part)
+	c.Assert(flushes[part], chk.Equals, 0)
+	cp.partDone(part)
+	c.Assert(flushes[part], chk.Equals, 1)
+
+	cp, flushes = s.newCheckpointer(CheckpointNever)
+	cp.transferDone(part)
+	cp.partDone(part)
+	c.Assert(flushes[part], chk.Equals, 0)
+}
+
+func (s *planCheckpointerSuite) TestPeriodicFlushesOnlyDirtyParts(c *chk.C) {
+	busy, idle, finished := &jobPartMgr{}, &jobPartMgr{}, &jobPartMgr{}
+	cp, flushes := s.newCheckpointer(CheckpointInterval(time.Minute))
+
+	cp.transferDone(busy)
+	cp.transferDone(busy)
+	cp.transferDone(finished)
+	cp.partDone(finished) // flushed now, so no need to flush it again later
+	c.Assert(flushes[busy], chk.Equals, 0)
+	c.Assert(flushes[finished], chk.Equals, 1)
+
+	c.Assert(cp.flushDirty(), chk.Equals, 1)
+	c.Assert(flushes[busy], chk.Equals, 1)
+	c.Assert(flushes[idle], chk.Equals, 0)
+	c.Assert(flushes[finished], chk.Equals, 1)
+
+	c.Assert(cp.flushDirty(), chk.Equals, 0) // nothing has happened since
+}
@@ -0,0 +1,64 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"os"
+
+	chk "gopkg.in/check.v1"
+)
+
+type posixOwnershipSuite struct{}
+
+var _ = chk.Suite(&posixOwnershipSuite{})
+
+func (s *posixOwnershipSuite) TestParsePOSIXPermissions(c *chk.C) {
+	cases := map[string]os.FileMode{
+		"rwxr-x---":  0750,
+		"rw-r--r--+": 0644,
+		"rwxrwxrwt":  0777 | os.ModeSticky,
+		"rwxrwxrwT":  0776 | os.ModeSticky,
+		"0640":       0640,
+		"1777":       0777 | os.ModeSticky,
+	}
+	for permissions, expected := range cases {
+		mode, err := parsePOSIXPermissions(permissions)
+		c.Assert(err, chk.IsNil, chk.Commentf(permissions))
+		c.Assert(mode, chk.Equals, expected, chk.Commentf(permissions))
+	}
+
+	for _, bad := range []string{"", "rwxr-x", "rwzr-x---", "t--------", "99999"} {
+		_, err := parsePOSIXPermissions(bad)
+		c.Assert(err, chk.NotNil, chk.Commentf(bad))
+	}
+}
+
+func (s *posixOwnershipSuite) TestFormatPOSIXPermissionsRoundTrips(c *chk.C) {
+	for _, mode := range []os.FileMode{0, 0644, 0750, 0777 | os.ModeSticky} {
+		formatted := formatPOSIXPermissions(mode)
+		c.Assert(formatted, chk.HasLen, 4)
+
+		parsed, err := parsePOSIXPermissions(formatted)
+		c.Assert(err, chk.IsNil)
+		c.Assert(parsed, chk.Equals, mode)
+	}
+	c.Assert(formatPOSIXPermissions(0755|os.ModeDir), chk.Equals, "0755")
+}
@@ -0,0 +1,99 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type prioritySchedulingSuite struct{}
+
+var _ = chk.Suite(&prioritySchedulingSuite{})
+
+func (s *prioritySchedulingSuite) TestRotationGivesEachPriorityItsShare(c *chk.C) {
+	rotation := newPriorityRotation(priorityShares)
+	c.Assert(rotation, chk.HasLen, 10)
+
+	firsts := map[common.JobPriority]int{}
+	for _, order := range rotation {
+		c.Assert(order, chk.HasLen, len(priorityShares))
+		firsts[order[0]]++
+	}
+	c.Assert(firsts[common.EJobPriority.High()], chk.Equals, 6)
+	c.Assert(firsts[common.EJobPriority.Normal()], chk.Equals, 3)
+	c.Assert(firsts[common.EJobPriority.Low()], chk.Equals, 1)
+
+	// the turns are spread out, so High never gets more than two in a row
+	for i := range rotation {
+		runOfThree := rotation[i][0] == rotation[(i+1)%10][0] && rotation[i][0] == rotation[(i+2)%10][0]
+		c.Assert(runOfThree, chk.Equals, false)
+	}
+}
+
+func (s *prioritySchedulingSuite) TestChunksArePickedInProportion(c *chk.C) {
+	ja := newJobsAdminWithChunkChannelsForTest()
+	picked := map[common.JobPriority]int{}
+	for _, priority := range []common.JobPriority{common.EJobPriority.High(), common.EJobPriority.Normal(), common.EJobPriority.Low()} {
+		p := priority
+		for i := 0; i < 100; i++ {
+			ja.ScheduleChunk(p, func(int) { picked[p]++ })
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		chunkFunc, ok := ja.nextChunkFunc()
+		c.Assert(ok, chk.Equals, true)
+		chunkFunc(0)
+	}
+	c.Assert(picked[common.EJobPriority.High()], chk.Equals, 60)
+	c.Assert(picked[common.EJobPriority.Normal()], chk.Equals, 30)
+	c.Assert(picked[common.EJobPriority.Low()], chk.Equals, 10)
+}
+
+func (s *prioritySchedulingSuite) TestJobRunningAloneGetsAllTheWorkers(c *chk.C) {
+	ja := newJobsAdminWithChunkChannelsForTest()
+	picked := 0
+	for i := 0; i < 10; i++ {
+		ja.ScheduleChunk(common.EJobPriority.Low(), func(int) { picked++ })
+	}
+
+	for i := 0; i < 10; i++ {
+		chunkFunc, ok := ja.nextChunkFunc()
+		c.Assert(ok, chk.Equals, true)
+		chunkFunc(0)
+	}
+	c.Assert(picked, chk.Equals, 10)
+
+	_, ok := ja.nextChunkFunc()
+	c.Assert(ok, chk.Equals, false)
+}
+
+func newJobsAdminWithChunkChannelsForTest() *jobsAdmin {
+	return &jobsAdmin{
+		xferChannels: XferChannels{
+			highChunkCh:    make(chan chunkFunc, 100),
+			normalChunckCh: make(chan chunkFunc, 100),
+			lowChunkCh:     make(chan chunkFunc, 100),
+		},
+		priorityRotation: defaultPriorityRotation,
+	}
+}
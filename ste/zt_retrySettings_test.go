@@ -0,0 +1,112 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"os"
+	"time"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type retrySettingsSuite struct{}
+
+var _ = chk.Suite(&retrySettingsSuite{})
+
+func (s *retrySettingsSuite) withEnv(c *chk.C, values map[common.EnvironmentVariable]string, test func()) {
+	for envVar, value := range values {
+		c.Assert(os.Setenv(envVar.Name, value), chk.IsNil)
+	}
+	defer func() {
+		for envVar := range values {
+			_ = os.Unsetenv(envVar.Name)
+		}
+	}()
+	test()
+}
+
+func (s *retrySettingsSuite) TestDefaultsWhenNothingIsSet(c *chk.C) {
+	settings, err := NewRetrySettings()
+	c.Assert(err, chk.IsNil)
+	c.Assert(settings, chk.DeepEquals, DefaultRetrySettings())
+	c.Assert(settings.xferRetryOptions().MaxTries, chk.Equals, int32(UploadMaxTries))
+}
+
+func (s *retrySettingsSuite) TestValuesFromEnvironment(c *chk.C) {
+	env := map[common.EnvironmentVariable]string{
+		common.EEnvironmentVariable.RetryMaxTries():   "5",
+		common.EEnvironmentVariable.RetryTryTimeout(): "30s",
+		common.EEnvironmentVariable.RetryDelay():      "200ms",
+		common.EEnvironmentVariable.RetryMaxDelay():   "2s",
+		common.EEnvironmentVariable.RetryJitter():     "0",
+		common.EEnvironmentVariable.StallTimeout():    "0",
+	}
+	s.withEnv(c, env, func() {
+		settings, err := NewRetrySettings()
+		c.Assert(err, chk.IsNil)
+		c.Assert(settings, chk.DeepEquals, RetrySettings{
+			MaxTries:        5,
+			TryTimeout:      30 * time.Second,
+			RetryDelay:      200 * time.Millisecond,
+			MaxRetryDelay:   2 * time.Second,
+			Jitter:          -1, // zero from the user means none at all
+			StallTimeout:    0,  // and here, it turns stall detection off
+			IsUserSpecified: true,
+		})
+		c.Assert(settings.xferRetryOptions().TryTimeout, chk.Equals, 30*time.Second)
+		c.Assert(settings.xferRetryOptions().Jitter, chk.Equals, float32(-1))
+	})
+}
+
+func (s *retrySettingsSuite) TestInvalidValues(c *chk.C) {
+	cases := []map[common.EnvironmentVariable]string{
+		{common.EEnvironmentVariable.RetryMaxTries(): "0"},
+		{common.EEnvironmentVariable.RetryMaxTries(): "lots"},
+		{common.EEnvironmentVariable.RetryTryTimeout(): "30"}, // no unit
+		{common.EEnvironmentVariable.RetryDelay(): "-1s"},
+		{common.EEnvironmentVariable.RetryDelay(): "2m"}, // longer than the default max delay
+		{common.EEnvironmentVariable.RetryJitter(): "1"},
+		{common.EEnvironmentVariable.StallTimeout(): "-1m"},
+	}
+	for _, env := range cases {
+		s.withEnv(c, env, func() {
+			_, err := NewRetrySettings()
+			c.Assert(err, chk.NotNil, chk.Commentf("%v", env))
+		})
+	}
+}
+
+func (s *retrySettingsSuite) TestJitterIsApplied(c *chk.C) {
+	o := XferRetryOptions{RetryDelay: time.Second, MaxRetryDelay: time.Minute, Jitter: -1}
+	c.Assert(o.calcDelay(2), chk.Equals, time.Second)
+	c.Assert(o.calcDelay(3), chk.Equals, 3*time.Second)
+
+	o.Jitter = 0.1
+	for i := 0; i < 100; i++ {
+		delay := o.calcDelay(2)
+		c.Assert(delay >= 900*time.Millisecond && delay <= 1100*time.Millisecond, chk.Equals, true, chk.Commentf("%v", delay))
+	}
+
+	// the cap still applies, after the jitter
+	o.MaxRetryDelay = 2 * time.Second
+	c.Assert(o.calcDelay(5), chk.Equals, 2*time.Second)
+}
@@ -0,0 +1,130 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type s2sCopyMethodSuite struct{}
+
+var _ = chk.Suite(&s2sCopyMethodSuite{})
+
+// fakeCopyService answers Get Blob Properties with the copy statuses given, one after another, and records whether
+// the copy was aborted. Any other request fails with the error code given
+type fakeCopyService struct {
+	statuses    []azblob.CopyStatusType
+	errorCode   string
+	atomicCalls int32
+	aborted     int32
+}
+
+func (f *fakeCopyService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut && r.URL.Query().Get("comp") == "copy" {
+		atomic.StoreInt32(&f.aborted, 1)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method == http.MethodHead && f.errorCode == "" {
+		call := int(atomic.AddInt32(&f.atomicCalls, 1)) - 1
+		if call >= len(f.statuses) {
+			call = len(f.statuses) - 1
+		}
+		w.Header().Set("x-ms-copy-status", string(f.statuses[call]))
+		w.Header().Set("x-ms-copy-status-description", "source changed")
+		return
+	}
+	w.Header().Set("x-ms-error-code", f.errorCode)
+	w.WriteHeader(http.StatusForbidden)
+}
+
+func (s *s2sCopyMethodSuite) blobURL(c *chk.C, server *httptest.Server) azblob.BlobURL {
+	u, err := url.Parse(server.URL + "/container/blob")
+	c.Assert(err, chk.IsNil)
+	p := azblob.NewPipeline(azblob.NewAnonymousCredential(), azblob.PipelineOptions{Retry: azblob.RetryOptions{MaxTries: 1}})
+	return azblob.NewBlobURL(*u, p)
+}
+
+func (s *s2sCopyMethodSuite) TestCopyMethodUsed(c *chk.C) {
+	c.Assert(copyMethodUsed(common.ES2SCopyMethod.Auto()), chk.Equals, common.ES2SCopyMethod.PutBlockFromURL())
+	c.Assert(copyMethodUsed(common.ES2SCopyMethod.CopyBlob()), chk.Equals, common.ES2SCopyMethod.CopyBlob())
+	c.Assert(copyMethodUsed(common.ES2SCopyMethod.DownloadUpload()), chk.Equals, common.ES2SCopyMethod.DownloadUpload())
+}
+
+func (s *s2sCopyMethodSuite) TestCanFallBackToDownloadUpload(c *chk.C) {
+	storageError := func(code string) error {
+		server := httptest.NewServer(&fakeCopyService{errorCode: code})
+		defer server.Close()
+		_, err := s.blobURL(c, server).GetProperties(context.Background(), azblob.BlobAccessConditions{})
+		c.Assert(err, chk.NotNil)
+		return err
+	}
+	cannotVerify := storageError("CannotVerifyCopySource")
+
+	c.Assert(canFallBackToDownloadUpload(common.ES2SCopyMethod.Auto(), common.EFromTo.BlobBlob(), cannotVerify), chk.Equals, true)
+
+	// only when asked to, only for blob sources, and only when the service couldn't read the source
+	c.Assert(canFallBackToDownloadUpload(common.ES2SCopyMethod.PutBlockFromURL(), common.EFromTo.BlobBlob(), cannotVerify), chk.Equals, false)
+	c.Assert(canFallBackToDownloadUpload(common.ES2SCopyMethod.Auto(), common.EFromTo.S3Blob(), cannotVerify), chk.Equals, false)
+	c.Assert(canFallBackToDownloadUpload(common.ES2SCopyMethod.Auto(), common.EFromTo.BlobBlob(), storageError("AuthorizationFailure")), chk.Equals, false)
+	c.Assert(canFallBackToDownloadUpload(common.ES2SCopyMethod.Auto(), common.EFromTo.BlobBlob(), errors.New("connection reset")), chk.Equals, false)
+}
+
+func (s *s2sCopyMethodSuite) TestWaitForAsyncCopy(c *chk.C) {
+	defer func(interval time.Duration) { asyncCopyPollInterval = interval }(asyncCopyPollInterval)
+	asyncCopyPollInterval = time.Millisecond
+
+	service := &fakeCopyService{statuses: []azblob.CopyStatusType{azblob.CopyStatusPending, azblob.CopyStatusPending, azblob.CopyStatusSuccess}}
+	server := httptest.NewServer(service)
+	c.Assert(waitForAsyncCopy(context.Background(), s.blobURL(c, server), "id"), chk.IsNil)
+	c.Assert(atomic.LoadInt32(&service.atomicCalls), chk.Equals, int32(3))
+	server.Close()
+
+	service = &fakeCopyService{statuses: []azblob.CopyStatusType{azblob.CopyStatusPending, azblob.CopyStatusFailed}}
+	server = httptest.NewServer(service)
+	c.Assert(waitForAsyncCopy(context.Background(), s.blobURL(c, server), "id"), chk.ErrorMatches, `.*ended with status "failed": source changed`)
+	server.Close()
+}
+
+func (s *s2sCopyMethodSuite) TestAsyncCopyIsAbortedWhenCancelled(c *chk.C) {
+	service := &fakeCopyService{statuses: []azblob.CopyStatusType{azblob.CopyStatusPending}}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	defer func(interval time.Duration) { asyncCopyPollInterval = interval }(asyncCopyPollInterval)
+	asyncCopyPollInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	c.Assert(waitForAsyncCopy(ctx, s.blobURL(c, server), "id"), chk.Equals, context.Canceled)
+	c.Assert(atomic.LoadInt32(&service.aborted), chk.Equals, int32(1))
+}
@@ -0,0 +1,112 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+
+	chk "gopkg.in/check.v1"
+)
+
+type savedBytesRecorderSuite struct{}
+
+var _ = chk.Suite(&savedBytesRecorderSuite{})
+
+// syncRecordingFile is a destination file that notes how much had been written to it each time it was synced
+type syncRecordingFile struct {
+	bytes.Buffer
+	syncedAt []int
+	closed   bool
+}
+
+func (f *syncRecordingFile) Sync() error {
+	f.syncedAt = append(f.syncedAt, f.Len())
+	return nil
+}
+
+func (f *syncRecordingFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newTestSavedBytesRecorder(file *syncRecordingFile, saved *int64, keepOnClose bool) *savedBytesRecorder {
+	return &savedBytesRecorder{
+		WriteCloser: file,
+		sync:        file.Sync,
+		record:      func(n int64) { *saved += n },
+		keepOnClose: func() bool { return keepOnClose },
+	}
+}
+
+func (s *savedBytesRecorderSuite) TestBytesAreOnlySavedOnceSynced(c *chk.C) {
+	file := &syncRecordingFile{}
+	var saved int64
+	r := newTestSavedBytesRecorder(file, &saved, false)
+
+	chunk := make([]byte, savedBytesSyncInterval/2)
+	_, err := r.Write(chunk)
+	c.Assert(err, chk.IsNil)
+	c.Assert(saved, chk.Equals, int64(0)) // written, but maybe not yet on the disk
+	c.Assert(file.syncedAt, chk.HasLen, 0)
+
+	_, err = r.Write(chunk)
+	c.Assert(err, chk.IsNil)
+	c.Assert(file.syncedAt, chk.DeepEquals, []int{savedBytesSyncInterval})
+	c.Assert(saved, chk.Equals, int64(savedBytesSyncInterval))
+
+	_, err = r.Write(chunk[:10])
+	c.Assert(err, chk.IsNil)
+	c.Assert(r.Close(), chk.IsNil)
+	c.Assert(file.closed, chk.Equals, true)
+	c.Assert(file.syncedAt, chk.HasLen, 1) // nothing to resume, so the rest needn't be synced
+	c.Assert(saved, chk.Equals, int64(savedBytesSyncInterval))
+}
+
+func (s *savedBytesRecorderSuite) TestStoppedDownloadIsSyncedOnClose(c *chk.C) {
+	file := &syncRecordingFile{}
+	var saved int64
+	r := newTestSavedBytesRecorder(file, &saved, true)
+
+	_, err := r.Write(make([]byte, 100))
+	c.Assert(err, chk.IsNil)
+	c.Assert(saved, chk.Equals, int64(0))
+
+	c.Assert(r.Close(), chk.IsNil)
+	c.Assert(file.syncedAt, chk.DeepEquals, []int{100})
+	c.Assert(saved, chk.Equals, int64(100))
+}
+
+func (s *savedBytesRecorderSuite) TestFilesThatCannotBeSyncedAreNotWrapped(c *chk.C) {
+	c.Assert(newSavedBytesRecorder(nil, devNullWriter{}), chk.Equals, devNullWriter{})
+}
+
+type countingWrittenBytesReporter struct{ written int64 }
+
+func (r *countingWrittenBytesReporter) ReportWrittenBytes(n int64) { r.written += n }
+
+func (s *savedBytesRecorderSuite) TestProgressIsOnlyReportedByTheWriterWhenTheFileCannotBeSynced(c *chk.C) {
+	reporter := &countingWrittenBytesReporter{}
+	var saved int64
+
+	// the recorder reports the progress when it syncs, so the chunked file writer mustn't report it as well
+	c.Assert(writtenBytesReporterFor(newTestSavedBytesRecorder(&syncRecordingFile{}, &saved, false), reporter), chk.IsNil)
+	c.Assert(writtenBytesReporterFor(devNullWriter{}, reporter), chk.Equals, reporter)
+}
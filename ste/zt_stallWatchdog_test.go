@@ -0,0 +1,125 @@
+// Copyright Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type stallWatchdogSuite struct{}
+
+var _ = chk.Suite(&stallWatchdogSuite{})
+
+// stallTestJobPart provides just enough of a job part for the watchdog to identify, and log for, its transfers
+type stallTestJobPart struct {
+	IJobPartMgr
+	plan JobPartPlanHeader
+}
+
+func (p *stallTestJobPart) Plan() *JobPartPlanHeader                { return &p.plan }
+func (p *stallTestJobPart) Log(level pipeline.LogLevel, msg string) {}
+
+func (s *stallWatchdogSuite) withWatchdog(timeout time.Duration, test func(w *stallWatchdog)) {
+	oldJobsAdmin := JobsAdmin
+	defer func() { JobsAdmin = oldJobsAdmin }()
+
+	w := newStallWatchdog(timeout)
+	JobsAdmin = &jobsAdmin{stallWatchdog: w}
+	test(w)
+}
+
+func (s *stallWatchdogSuite) newTransfer(jobID common.JobID) *jobPartTransferMgr {
+	return &jobPartTransferMgr{jobPartMgr: &stallTestJobPart{plan: JobPartPlanHeader{JobID: jobID}}}
+}
+
+func (s *stallWatchdogSuite) TestStalledTriesAreCancelled(c *chk.C) {
+	s.withWatchdog(time.Minute, func(w *stallWatchdog) {
+		jobID := common.NewJobID()
+		jptm := s.newTransfer(jobID)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		endTry := jptm.startTry(cancel)
+		defer endTry()
+
+		// not yet stalled
+		c.Assert(w.check(time.Now()), chk.Equals, 0)
+		c.Assert(ctx.Err(), chk.IsNil)
+
+		// stalled
+		c.Assert(w.check(time.Now().Add(2*time.Minute)), chk.Equals, 1)
+		c.Assert(ctx.Err(), chk.Equals, context.Canceled)
+		c.Assert(w.stallCount(jobID), chk.Equals, uint32(1))
+		c.Assert(w.stallCount(common.NewJobID()), chk.Equals, uint32(0))
+
+		// nothing left in flight, so nothing more to restart
+		c.Assert(w.check(time.Now().Add(4*time.Minute)), chk.Equals, 0)
+		c.Assert(w.stallCount(jobID), chk.Equals, uint32(1))
+	})
+}
+
+func (s *stallWatchdogSuite) TestActivityPreventsStall(c *chk.C) {
+	s.withWatchdog(time.Minute, func(w *stallWatchdog) {
+		jptm := s.newTransfer(common.NewJobID())
+		_, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		endTry := jptm.startTry(cancel)
+
+		c.Assert(jptm.isStalled(time.Now().Add(59*time.Second), time.Minute), chk.Equals, false)
+		c.Assert(jptm.isStalled(time.Now().Add(61*time.Second), time.Minute), chk.Equals, true)
+
+		// the clock is reset by activity, and stops when there's nothing in flight
+		time.Sleep(10 * time.Millisecond)
+		jptm.noteActivity()
+		c.Assert(jptm.isStalled(time.Now().Add(59*time.Second), time.Minute), chk.Equals, false)
+		endTry()
+		c.Assert(jptm.isStalled(time.Now().Add(time.Hour), time.Minute), chk.Equals, false)
+	})
+}
+
+func (s *stallWatchdogSuite) TestDoneTransfersAreNotWatched(c *chk.C) {
+	s.withWatchdog(time.Minute, func(w *stallWatchdog) {
+		jptm := s.newTransfer(common.NewJobID())
+		_, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		jptm.startTry(cancel)
+		c.Assert(w.transfers, chk.HasLen, 1)
+
+		jptm.stopStallWatching()
+		c.Assert(w.transfers, chk.HasLen, 0)
+	})
+}
+
+func (s *stallWatchdogSuite) TestZeroTimeoutTurnsWatchdogOff(c *chk.C) {
+	s.withWatchdog(0, func(w *stallWatchdog) {
+		c.Assert(w, chk.IsNil)
+		jptm := s.newTransfer(common.NewJobID())
+		_, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		jptm.startTry(cancel)() // must be safe, even with no watchdog
+		jptm.stopStallWatching()
+		c.Assert(w.stallCount(common.NewJobID()), chk.Equals, uint32(0))
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"crypto/sha256"
+	"hash"
+
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type storedChecksumsSuite struct{}
+
+var _ = chk.Suite(&storedChecksumsSuite{})
+
+// contextOnlyTransferMgr is a transfer manager that only has a context, which is all that backgroundHasher needs of one
+type contextOnlyTransferMgr struct {
+	IJobPartTransferMgr
+	ctx context.Context
+}
+
+func (t contextOnlyTransferMgr) Context() context.Context {
+	return t.ctx
+}
+
+// bufferOnlyChunkReader is a chunk reader that only has a buffer, which is all that backgroundHasher needs of one
+type bufferOnlyChunkReader struct {
+	common.SingleChunkReader
+	buffer []byte
+}
+
+func (r bufferOnlyChunkReader) WriteBufferTo(h hash.Hash) {
+	_, _ = h.Write(r.buffer)
+}
+
+func (s *storedChecksumsSuite) TestBackgroundHasherHashesChunksInOrder(c *chk.C) {
+	chunks := []string{"the quick ", "brown fox ", "jumps over ", "the lazy dog"}
+	jptm := contextOnlyTransferMgr{ctx: context.Background()}
+	b := newBackgroundHasher(sha256.New(), uint32(len(chunks)))
+
+	ran := 0
+	funcs := make([]chunkFunc, 0, len(chunks))
+	for _, chunk := range chunks {
+		funcs = append(funcs, b.add(jptm, bufferOnlyChunkReader{buffer: []byte(chunk)}, func(int) { ran++ }))
+	}
+	for _, cf := range funcs {
+		cf(0)
+	}
+
+	expected := sha256.Sum256([]byte("the quick brown fox jumps over the lazy dog"))
+	c.Assert(b.finish(), chk.DeepEquals, expected[:])
+	c.Assert(ran, chk.Equals, len(chunks))
+}
+
+func (s *storedChecksumsSuite) TestEncodingIsLowerCaseHex(c *chk.C) {
+	empty := sha256.Sum256(nil)
+	c.Assert(encodeSha256(empty[:]), chk.Equals, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+}
+
+func (s *storedChecksumsSuite) TestSha256CheckFollowsValidationOption(c *chk.C) {
+	stored := common.Metadata{common.Sha256MetadataKey: "aa"}
+	o := common.EHashValidationOption
+
+	c.Assert(sha256Checksum.check(o.FailIfDifferent(), stored, "aa", nullTransferLogger{}), chk.IsNil)
+	c.Assert(sha256Checksum.check(o.FailIfDifferent(), stored, "bb", nullTransferLogger{}), chk.Equals, errSha256Mismatch)
+	c.Assert(sha256Checksum.check(o.LogOnly(), stored, "bb", nullTransferLogger{}), chk.IsNil)
+	c.Assert(sha256Checksum.check(o.NoCheck(), stored, "bb", nullTransferLogger{}), chk.IsNil)
+
+	c.Assert(sha256Checksum.check(o.FailIfDifferent(), common.Metadata{}, "bb", nullTransferLogger{}), chk.IsNil)
+	c.Assert(sha256Checksum.check(o.FailIfDifferentOrMissing(), common.Metadata{}, "bb", nullTransferLogger{}), chk.Equals, errExpectedSha256Missing)
+}
+
+func (s *storedChecksumsSuite) TestMissingOnlyFailsWhenChecksumIsRequired(c *chk.C) {
+	o := common.EHashValidationOption
+	crc64Only := common.Metadata{common.Crc64MetadataKey: encodeCrc64(1)}
+
+	c.Assert(crc64Checksum.missing(o.FailIfDifferentOrMissing(), crc64Only), chk.IsNil)
+	c.Assert(sha256Checksum.missing(o.FailIfDifferentOrMissing(), crc64Only), chk.Equals, errExpectedSha256Missing)
+	c.Assert(sha256Checksum.missing(o.FailIfDifferent(), crc64Only), chk.IsNil)
+}
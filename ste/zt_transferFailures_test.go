@@ -0,0 +1,65 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-azcopy/common"
+	chk "gopkg.in/check.v1"
+)
+
+type transferFailuresSuite struct{}
+
+var _ = chk.Suite(&transferFailuresSuite{})
+
+func (s *transferFailuresSuite) TestLatestFailureOfEachTransferIsKept(c *chk.C) {
+	dir, err := ioutil.TempDir("", "failurestest")
+	c.Assert(err, chk.IsNil)
+	defer os.RemoveAll(dir)
+	oldJobsAdmin := JobsAdmin
+	JobsAdmin = &jobsAdmin{planDir: dir, logger: common.NewAppLogger(pipeline.LogNone, "")}
+	defer func() { JobsAdmin = oldJobsAdmin }()
+	jobID := common.NewJobID()
+
+	c.Assert(readTransferFailures(jobID), chk.HasLen, 0) // nothing has failed yet
+
+	c.Assert(recordTransferFailure(jobID, transferFailure{PartNum: 0, TransferIndex: 1, ErrorCode: 503, ErrorMessage: "first", RetryCount: 20}), chk.IsNil)
+	c.Assert(recordTransferFailure(jobID, transferFailure{PartNum: 1, TransferIndex: 1, ErrorCode: 404, ErrorMessage: "other part"}), chk.IsNil)
+	c.Assert(recordTransferFailure(jobID, transferFailure{PartNum: 0, TransferIndex: 1, ErrorCode: 403, ErrorMessage: "after resume", RetryCount: 1}), chk.IsNil)
+
+	// a line that was cut short, by the process being killed, is ignored
+	file, err := os.OpenFile(transferFailuresPath(jobID), os.O_WRONLY|os.O_APPEND, 0644)
+	c.Assert(err, chk.IsNil)
+	_, err = file.WriteString(`{"PartNum":0,"TransferIndex":2,"Err`)
+	c.Assert(err, chk.IsNil)
+	c.Assert(file.Close(), chk.IsNil)
+
+	failures := readTransferFailures(jobID)
+	c.Assert(failures, chk.HasLen, 2)
+	latest := failures[transferFailureKey{partNum: 0, transferIndex: 1}]
+	c.Assert(latest.ErrorCode, chk.Equals, int32(403))
+	c.Assert(latest.ErrorMessage, chk.Equals, "after resume")
+	c.Assert(latest.RetryCount, chk.Equals, int32(1))
+	c.Assert(failures[transferFailureKey{partNum: 1, transferIndex: 1}].ErrorMessage, chk.Equals, "other part")
+}
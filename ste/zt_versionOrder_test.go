@@ -0,0 +1,54 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	chk "gopkg.in/check.v1"
+
+	"github.com/Azure/azure-storage-azcopy/common"
+)
+
+type versionOrderSuite struct{}
+
+var _ = chk.Suite(&versionOrderSuite{})
+
+func (s *versionOrderSuite) TestEarlierVersionState(c *chk.C) {
+	// still to be written, or being written
+	for _, status := range []common.TransferStatus{common.ETransferStatus.NotStarted(), common.ETransferStatus.Started()} {
+		written, err := earlierVersionState(status, false)
+		c.Assert(err, chk.IsNil)
+		c.Assert(written, chk.Equals, false)
+	}
+
+	written, err := earlierVersionState(common.ETransferStatus.Success(), false)
+	c.Assert(err, chk.IsNil)
+	c.Assert(written, chk.Equals, true)
+
+	// finished, but hasn't let go of the destination yet
+	written, err = earlierVersionState(common.ETransferStatus.Success(), true)
+	c.Assert(err, chk.IsNil)
+	c.Assert(written, chk.Equals, false)
+
+	for _, status := range []common.TransferStatus{common.ETransferStatus.Failed(), common.ETransferStatus.Cancelled(), common.ETransferStatus.BlobTierFailure()} {
+		_, err = earlierVersionState(status, false)
+		c.Assert(err, chk.Equals, errEarlierVersionNotCopied)
+	}
+}
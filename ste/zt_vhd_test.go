@@ -0,0 +1,135 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	chk "gopkg.in/check.v1"
+)
+
+type vhdSuite struct{}
+
+var _ = chk.Suite(&vhdSuite{})
+
+// makeVHD makes the content of a VHD of the given virtual size, with some data at its start
+func makeVHD(virtualSize uint64, diskType uint32) []byte {
+	content := make([]byte, int(virtualSize)+vhdFooterSize)
+	copy(content, "some data")
+
+	footer := content[virtualSize:]
+	copy(footer, vhdFooterCookie)
+	binary.BigEndian.PutUint64(footer[40:48], virtualSize) // original size
+	binary.BigEndian.PutUint64(footer[48:56], virtualSize) // current size
+	binary.BigEndian.PutUint32(footer[60:64], diskType)
+	var sum uint32
+	for _, v := range footer {
+		sum += uint32(v)
+	}
+	binary.BigEndian.PutUint32(footer[64:68], ^sum)
+	return content
+}
+
+func (s *vhdSuite) TestValidateFixedVHD(c *chk.C) {
+	vhd := makeVHD(vhdSizeGranularity, vhdDiskTypeFixed)
+	c.Assert(validateFixedVHD(bytes.NewReader(vhd), int64(len(vhd))), chk.IsNil)
+
+	dynamic := makeVHD(vhdSizeGranularity, 3)
+	c.Assert(validateFixedVHD(bytes.NewReader(dynamic), int64(len(dynamic))), chk.ErrorMatches, "the VHD is dynamically expanding or differencing.*")
+
+	odd := makeVHD(vhdSizeGranularity+512, vhdDiskTypeFixed)
+	c.Assert(validateFixedVHD(bytes.NewReader(odd), int64(len(odd))), chk.ErrorMatches, ".*is not a whole number of MiB.*")
+
+	c.Assert(validateFixedVHD(bytes.NewReader(vhd), int64(len(vhd)-1)), chk.ErrorMatches, ".*is not a multiple of 512 bytes.*")
+
+	// the footer gives the size of the disk, so a VHD with extra data after it isn't valid
+	padded := append(make([]byte, 512), vhd...)
+	c.Assert(validateFixedVHD(bytes.NewReader(padded), int64(len(padded))), chk.ErrorMatches, "the VHD footer gives a size of .*")
+
+	corrupt := makeVHD(vhdSizeGranularity, vhdDiskTypeFixed)
+	corrupt[len(corrupt)-1] = 1
+	c.Assert(validateFixedVHD(bytes.NewReader(corrupt), int64(len(corrupt))), chk.ErrorMatches, "the checksum of the VHD footer is wrong.*")
+
+	notVHD := make([]byte, 4096)
+	c.Assert(validateFixedVHD(bytes.NewReader(notVHD), int64(len(notVHD))), chk.ErrorMatches, "the file doesn't end with a VHD footer.*")
+}
+
+func (s *vhdSuite) TestRangeReadSeeker(c *chk.C) {
+	chunk := bytes.NewReader([]byte("0123456789"))
+	r := newRangeReadSeeker(chunk, 3, 4)
+
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "3456")
+
+	// a retry seeks back to the start of the range
+	n, err := r.Seek(0, io.SeekEnd)
+	c.Assert(err, chk.IsNil)
+	c.Assert(n, chk.Equals, int64(4))
+	_, err = r.Seek(1, io.SeekStart)
+	c.Assert(err, chk.IsNil)
+	data, err = ioutil.ReadAll(r)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "456")
+}
+
+func (s *vhdSuite) TestRangeReadSeekerOffsets(c *chk.C) {
+	chunk := bytes.NewReader([]byte("0123456789"))
+
+	// a range that ends at the end of the chunk
+	r := newRangeReadSeeker(chunk, 6, 4)
+	buf := make([]byte, 3)
+	n, err := r.Read(buf)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(buf[:n]), chk.Equals, "678")
+	n, err = r.Read(buf)
+	c.Assert(err, chk.Equals, io.EOF)
+	c.Assert(string(buf[:n]), chk.Equals, "9")
+
+	// seeks are relative to the start of the range, not of the chunk
+	pos, err := r.Seek(-3, io.SeekEnd)
+	c.Assert(err, chk.IsNil)
+	c.Assert(pos, chk.Equals, int64(1))
+	pos, err = r.Seek(1, io.SeekCurrent)
+	c.Assert(err, chk.IsNil)
+	c.Assert(pos, chk.Equals, int64(2))
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "89")
+
+	_, err = r.Seek(-1, io.SeekStart)
+	c.Assert(err, chk.NotNil)
+
+	// the other reads of the chunk may have moved it, but the range is read from its own offset all the same
+	r = newRangeReadSeeker(chunk, 2, 3)
+	_, _ = chunk.Seek(0, io.SeekStart)
+	data, err = ioutil.ReadAll(r)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(data), chk.Equals, "234")
+
+	// a range that runs past the end of the chunk is an error, rather than a short body
+	r = newRangeReadSeeker(chunk, 8, 4)
+	_, err = ioutil.ReadAll(r)
+	c.Assert(err, chk.Equals, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,70 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"strings"
+
+	chk "gopkg.in/check.v1"
+)
+
+type xattrsSuite struct{}
+
+var _ = chk.Suite(&xattrsSuite{})
+
+func (s *xattrsSuite) TestXattrsRoundTrip(c *chk.C) {
+	xattrs := map[string][]byte{
+		"user.comment":         []byte("hello, world"),
+		"user.binary":          {0, 1, 2, 255},
+		"com.apple.quarantine": []byte("0083;5f1d2a3b;Safari;"),
+		"user.empty":           {},
+	}
+
+	encoded, dropped := encodeXattrs(xattrs)
+	c.Assert(dropped, chk.HasLen, 0)
+	for _, r := range encoded {
+		c.Assert(r < 128, chk.Equals, true) // metadata values must be ASCII
+	}
+
+	decoded, err := decodeXattrs(encoded)
+	c.Assert(err, chk.IsNil)
+	c.Assert(decoded, chk.HasLen, len(xattrs))
+	for name, value := range xattrs {
+		c.Assert(string(decoded[name]), chk.Equals, string(value))
+	}
+}
+
+func (s *xattrsSuite) TestXattrsThatDontFitAreDropped(c *chk.C) {
+	xattrs := map[string][]byte{
+		"user.a": []byte("small"),
+		"user.b": []byte(strings.Repeat("x", maxXattrsMetadataLength)),
+		"user.c": []byte("also small"),
+	}
+
+	encoded, dropped := encodeXattrs(xattrs)
+	c.Assert(dropped, chk.DeepEquals, []string{"user.b"})
+	c.Assert(len(encoded) <= maxXattrsMetadataLength, chk.Equals, true)
+
+	decoded, err := decodeXattrs(encoded)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(decoded["user.a"]), chk.Equals, "small")
+	c.Assert(string(decoded["user.c"]), chk.Equals, "also small")
+}
@@ -0,0 +1,145 @@
+// Copyright © 2017 Microsoft <wastore@microsoft.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ste
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-pipeline-go/pipeline"
+	"github.com/Azure/azure-storage-file-go/azfile"
+	chk "gopkg.in/check.v1"
+)
+
+type xferRetryPolicySuite struct{}
+
+var _ = chk.Suite(&xferRetryPolicySuite{})
+
+func (s *xferRetryPolicySuite) TestParseRetryAfter(c *chk.C) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	header := func(name, value string) http.Header {
+		h := http.Header{}
+		h.Set(name, value)
+		return h
+	}
+
+	c.Assert(parseRetryAfter(http.Header{}, now), chk.Equals, time.Duration(0))
+	c.Assert(parseRetryAfter(header("Retry-After", "7"), now), chk.Equals, 7*time.Second)
+	c.Assert(parseRetryAfter(header("Retry-After", "0"), now), chk.Equals, time.Duration(0))
+	c.Assert(parseRetryAfter(header("Retry-After", now.Add(90*time.Second).Format(http.TimeFormat)), now), chk.Equals, 90*time.Second)
+	c.Assert(parseRetryAfter(header("Retry-After", now.Add(-time.Minute).Format(http.TimeFormat)), now), chk.Equals, time.Duration(0))
+	c.Assert(parseRetryAfter(header("Retry-After", "soon"), now), chk.Equals, time.Duration(0))
+	c.Assert(parseRetryAfter(header("x-ms-retry-after-ms", "250"), now), chk.Equals, 250*time.Millisecond)
+}
+
+func (s *xferRetryPolicySuite) TestBackoffAfterThrottling(c *chk.C) {
+	o := XferRetryOptions{RetryDelay: time.Second, MaxRetryDelay: 30 * time.Second, Jitter: -1}.defaults()
+	throttled := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+
+	// without throttling, it's the usual exponential backoff
+	b := retryBackoff{o: o}
+	c.Assert(b.delay(1), chk.Equals, time.Duration(0))
+	b.recordTry(pipeline.NewHTTPResponse(&http.Response{StatusCode: http.StatusRequestTimeout}), nil)
+	c.Assert(b.delay(2), chk.Equals, time.Second)
+
+	// Retry-After makes it wait longer, but never longer than the maximum
+	throttled.Header.Set("Retry-After", "10")
+	b.recordTry(pipeline.NewHTTPResponse(throttled), nil)
+	c.Assert(b.delay(3), chk.Equals, 10*time.Second)
+	throttled.Header.Set("Retry-After", "3600")
+	b.recordTry(pipeline.NewHTTPResponse(throttled), nil)
+	c.Assert(b.delay(4), chk.Equals, 30*time.Second)
+
+	// with jitter, throttled tries get decorrelated delays, from the base delay up to three times the previous one
+	o.Jitter = 0
+	throttled.Header.Del("Retry-After")
+	b = retryBackoff{o: o, lastDelay: 4 * time.Second}
+	for i := 0; i < 100; i++ {
+		b.recordTry(pipeline.NewHTTPResponse(throttled), nil)
+		previous := b.lastDelay
+		delay := b.delay(2)
+		c.Assert(delay >= o.RetryDelay, chk.Equals, true)
+		c.Assert(delay < 3*previous || delay == o.MaxRetryDelay, chk.Equals, true)
+		c.Assert(delay <= o.MaxRetryDelay, chk.Equals, true)
+	}
+}
+
+func (s *xferRetryPolicySuite) TestRetryPolicyWaitsAsLongAsTheServiceAsks(c *chk.C) {
+	tries := 0
+	sender := pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			tries++
+			if tries == 1 {
+				h := http.Header{}
+				h.Set("x-ms-retry-after-ms", "300")
+				return pipeline.NewHTTPResponse(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: h, Body: http.NoBody}),
+					&fakeThrottlingError{}
+			}
+			return pipeline.NewHTTPResponse(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}), nil
+		}
+	})
+	o := XferRetryOptions{MaxTries: 3, RetryDelay: time.Millisecond, MaxRetryDelay: time.Second}
+	p := pipeline.NewPipeline([]pipeline.Factory{NewBlobXferRetryPolicyFactory(o)}, pipeline.Options{HTTPSender: sender})
+
+	u, _ := url.Parse("https://account.blob.core.windows.net/container/blob")
+	request, err := pipeline.NewRequest(http.MethodPut, *u, nil)
+	c.Assert(err, chk.IsNil)
+	start := time.Now()
+	_, err = p.Do(context.Background(), nil, request)
+	c.Assert(err, chk.IsNil)
+	c.Assert(tries, chk.Equals, 2)
+	c.Assert(time.Since(start) >= 300*time.Millisecond, chk.Equals, true)
+}
+
+func (s *xferRetryPolicySuite) TestFileRequestsWaitAsLongAsTheServiceAsks(c *chk.C) {
+	tries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tries++
+		if tries == 1 {
+			w.Header().Set("x-ms-error-code", "ServerBusy")
+			w.Header().Set("x-ms-retry-after-ms", "300")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("x-ms-type", "File")
+	}))
+	defer server.Close()
+
+	o := XferRetryOptions{MaxTries: 3, RetryDelay: time.Millisecond, MaxRetryDelay: time.Second, NoServerTimeout: true}
+	p := pipeline.NewPipeline([]pipeline.Factory{NewFileXferRetryPolicyFactory(o), azfile.NewAnonymousCredential(), pipeline.MethodFactoryMarker()}, pipeline.Options{})
+
+	u, _ := url.Parse(server.URL + "/share/file")
+	start := time.Now()
+	_, err := azfile.NewFileURL(*u, p).GetProperties(context.Background())
+	c.Assert(err, chk.IsNil)
+	c.Assert(tries, chk.Equals, 2) // the Files SDK's StorageError is retried
+	c.Assert(time.Since(start) >= 300*time.Millisecond, chk.Equals, true)
+}
+
+// fakeThrottlingError is retried, as a network error would be
+type fakeThrottlingError struct{}
+
+func (e *fakeThrottlingError) Error() string   { return "server busy" }
+func (e *fakeThrottlingError) Timeout() bool   { return false }
+func (e *fakeThrottlingError) Temporary() bool { return true }